@@ -44,6 +44,8 @@ type BashTool struct {
 	// Env holds the conversation context exposed to invoked commands as
 	// SHELLEY_* environment variables.
 	Env ShelleyEnv
+	// Sandbox, if set, confines the commands run.
+	Sandbox Sandbox
 }
 
 const (
@@ -164,11 +166,8 @@ func (i *bashInput) timeout(t *Timeouts) time.Duration {
 func (b *BashTool) run(ctx context.Context, req bashInput) llm.ToolOut {
 	// Check that the working directory exists
 	wd := b.getWorkingDir()
-	if _, err := os.Stat(wd); err != nil {
-		if os.IsNotExist(err) {
-			return llm.ErrorfToolOut("working directory does not exist: %s (use change_dir to switch to a valid directory)", wd)
-		}
-		return llm.ErrorfToolOut("cannot access working directory %s: %w", wd, err)
+	if err := checkWorkingDir(wd, b.Sandbox); err != nil {
+		return llm.ErrorToolOut(err)
 	}
 
 	// do a quick permissions check (NOT a security barrier)
@@ -241,8 +240,20 @@ func (b *BashTool) makeBashCommand(ctx context.Context, command string, out io.W
 	env := stripShelleyEnv(os.Environ())
 	env = append(env, "SKETCH=1")          // signal that this has been run by Sketch, sometimes useful for scripts
 	env = append(env, "EDITOR=/bin/false") // interactive editors won't work
+	env = append(env, `GIT_SEQUENCE_EDITOR=echo "To do an interactive rebase, run it in a tmux session." && exit 1`)
 	env = append(env, b.Env.Environ(cmd.Dir)...)
 	cmd.Env = env
+	sandboxCommand(cmd, b.Sandbox)
+	return cmd
+}
+
+// makeArgvCommand is like makeBashCommand, but runs args verbatim instead of
+// a shell command line, so callers needn't quote. It still goes through a
+// login shell so PATH matches what the bash tool sees.
+func (b *BashTool) makeArgvCommand(ctx context.Context, args []string, out io.Writer) *exec.Cmd {
+	cmd := b.makeBashCommand(ctx, `exec "$@"`, out)
+	cmd.Args = append(cmd.Args, args[0])
+	cmd.Args = append(cmd.Args, args...)
 	return cmd
 }
 
@@ -371,7 +382,6 @@ func (b *BashTool) executeBash(ctx context.Context, req bashInput, timeout time.
 	}
 
 	cmd := b.makeBashCommand(execCtx, req.Command, output)
-	cmd.Env = append(cmd.Env, `GIT_SEQUENCE_EDITOR=echo "To do an interactive rebase, run it in a tmux session." && exit 1`)
 	if err := cmd.Start(); err != nil {
 		return "", fmt.Errorf("command failed: %w", err)
 	}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -44,7 +45,7 @@ const ConsoleLogsDir = "/tmp/shelley-console-logs"
 // ConsoleLogSizeThreshold is the size in bytes above which console logs are written to a file
 const ConsoleLogSizeThreshold = 1024
 
-// DefaultIdleTimeout is how long to wait before shutting down an idle browser
+// DefaultIdleTimeout is how long to wait before closing an idle browser tab
 const DefaultIdleTimeout = 30 * time.Minute
 
 // DownloadInfo tracks information about a completed download
@@ -59,12 +60,10 @@ type DownloadInfo struct {
 
 // BrowseTools contains all browser tools and manages a shared browser instance
 type BrowseTools struct {
-	ctx              context.Context
-	allocCtx         context.Context
-	allocCancel      context.CancelFunc
-	browserCtx       context.Context
-	browserCtxCancel context.CancelFunc
-	mux              sync.Mutex
+	pool *Pool
+	// tab is this tool set's tab in a pooled browser; nil until first use.
+	tab *Tab
+	mux sync.Mutex
 	// Map to track screenshots by ID and their creation time
 	screenshots      map[string]time.Time
 	screenshotsMutex sync.Mutex
@@ -92,14 +91,12 @@ type BrowseTools struct {
 	traceMutex      sync.Mutex
 	// Screencast state
 	screencast screencastState
-	// browserCmd is the headless-shell *exec.Cmd, captured via
-	// chromedp.ModifyCmdFunc so we can kill its process group on shutdown.
-	browserCmd *exec.Cmd
 }
 
-// NewBrowseTools creates a new set of browser automation tools.
-// idleTimeout is how long to wait before shutting down an idle browser (0 uses default).
-func NewBrowseTools(ctx context.Context, idleTimeout time.Duration) *BrowseTools {
+// NewBrowseTools creates a new set of browser automation tools backed by a tab
+// in pool.
+// idleTimeout is how long to wait before closing an idle tab (0 uses default).
+func NewBrowseTools(ctx context.Context, pool *Pool, idleTimeout time.Duration) *BrowseTools {
 	if idleTimeout <= 0 {
 		idleTimeout = DefaultIdleTimeout
 	}
@@ -110,7 +107,7 @@ func NewBrowseTools(ctx context.Context, idleTimeout time.Duration) *BrowseTools
 	}
 
 	bt := &BrowseTools{
-		ctx:            ctx,
+		pool:           pool,
 		screenshots:    make(map[string]time.Time),
 		consoleLogs:    make([]*runtime.EventConsoleAPICalled, 0),
 		maxConsoleLogs: 100,
@@ -118,6 +115,8 @@ func NewBrowseTools(ctx context.Context, idleTimeout time.Duration) *BrowseTools
 		downloads:      make(map[string]*DownloadInfo),
 	}
 	bt.downloadCond = sync.NewCond(&bt.downloadsMutex)
+	// The tab outlives individual tool calls but not the conversation.
+	context.AfterFunc(ctx, bt.Close)
 	return bt
 }
 
@@ -126,103 +125,39 @@ func (b *BrowseTools) GetBrowserContext() (context.Context, error) {
 	b.mux.Lock()
 	defer b.mux.Unlock()
 
-	// If browser exists, check if it's still alive
-	if b.browserCtx != nil {
-		// Check if the browser context has been cancelled (e.g., due to crash)
-		if b.browserCtx.Err() != nil {
-			log.Printf("Browser context is dead (err: %v), restarting browser", b.browserCtx.Err())
+	// If we hold a tab, check if it's still alive
+	if b.tab != nil {
+		// Check if the tab context has been cancelled (e.g., due to a crash)
+		if err := b.tab.Context().Err(); err != nil {
+			log.Printf("Browser tab is dead (err: %v), opening a new one", err)
 			b.closeBrowserLocked()
-			// Fall through to create a new browser
+			// Fall through to open a new tab
 		} else {
 			b.resetIdleTimerLocked()
-			return b.browserCtx, nil
+			return b.tab.Context(), nil
 		}
 	}
 
-	// Initialize a new browser
-	opts := chromedp.DefaultExecAllocatorOptions[:]
-	opts = append(opts, chromedp.NoSandbox)
-	opts = append(opts, chromedp.Flag("--disable-dbus", true))
-	opts = append(opts, chromedp.WSURLReadTimeout(60*time.Second))
-	// Disable WebAuthn to prevent segfaults on FIDO/WebAuthn sites (issue #78)
-	// Must include all default disabled features plus WebAuthentication
-	// (chromedp v0.14.1 defaults: site-per-process,Translate,BlinkGenPropertyTrees)
-	opts = append(opts, chromedp.Flag("disable-features",
-		"site-per-process,Translate,BlinkGenPropertyTrees,WebAuthentication"))
-
-	// Capture the *exec.Cmd headless-shell is launched with so closeBrowserLocked
-	// can kill the whole process group. headless-shell forks zygote, renderers,
-	// GPU and utility processes; chromedp's default cancel only SIGKILLs the
-	// direct child, leaving descendants orphaned to PID 1. ModifyCmdFunc also
-	// replaces chromedp's default cmd setup, so configureBrowserCmd re-applies
-	// Pdeathsig and adds Setpgid for clean group kill.
-	// ModifyCmdFunc runs synchronously on the chromedp.Run goroutine before
-	// cmd.Start, so a plain pointer assignment is enough — Run returns after
-	// the browser is up, so by the time we read capturedCmd below the function
-	// has already finished.
-	var capturedCmd *exec.Cmd
-	opts = append(opts, chromedp.ModifyCmdFunc(func(cmd *exec.Cmd) {
-		configureBrowserCmd(cmd)
-		capturedCmd = cmd
-	}))
-
-	// killCapturedGroup is shared between error paths and the success path so
-	// every exit from this function reaps the headless-shell process group.
-	killCapturedGroup := func() {
-		if capturedCmd != nil && capturedCmd.Process != nil {
-			killBrowserProcessGroup(capturedCmd.Process.Pid)
-		}
+	tab, err := b.pool.Acquire()
+	if err != nil {
+		return nil, err
 	}
-
-	allocCtx, allocCancel := chromedp.NewExecAllocator(b.ctx, opts...)
-	browserCtx, browserCancel := chromedp.NewContext(
-		allocCtx,
-		chromedp.WithLogf(log.Printf),
-		chromedp.WithErrorf(log.Printf),
-		chromedp.WithBrowserOption(chromedp.WithDialTimeout(60*time.Second)),
-	)
+	tabCtx := tab.Context()
 
 	// Set up event listeners for console logs, downloads, network, and tracing.
-	// All listeners are registered once at browser startup and gated by enable flags.
-	chromedp.ListenTarget(browserCtx, b.handleBrowserEvent)
-
-	// Start the browser
-	if err := chromedp.Run(browserCtx); err != nil {
-		allocCancel()
-		killCapturedGroup()
-		return nil, fmt.Errorf("failed to start browser (please apt install chromium or equivalent): %w", err)
-	}
+	// All listeners are registered once per tab and gated by enable flags.
+	chromedp.ListenTarget(tabCtx, b.handleBrowserEvent)
 
 	// Set default viewport size to 1280x720 (16:9 widescreen)
-	if err := chromedp.Run(browserCtx, chromedp.EmulateViewport(1280, 720)); err != nil {
-		browserCancel()
-		allocCancel()
-		killCapturedGroup()
+	if err := chromedp.Run(tabCtx, chromedp.EmulateViewport(1280, 720)); err != nil {
+		tab.Close()
 		return nil, fmt.Errorf("failed to set default viewport: %w", err)
 	}
 
-	// Configure download behavior to allow downloads and emit events
-	if err := chromedp.Run(
-		browserCtx,
-		browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).
-			WithDownloadPath(DownloadDir).
-			WithEventsEnabled(true),
-	); err != nil {
-		browserCancel()
-		allocCancel()
-		killCapturedGroup()
-		return nil, fmt.Errorf("failed to configure download behavior: %w", err)
-	}
-
-	b.allocCtx = allocCtx
-	b.allocCancel = allocCancel
-	b.browserCtx = browserCtx
-	b.browserCtxCancel = browserCancel
-	b.browserCmd = capturedCmd
-
+	b.tab = tab
 	b.resetIdleTimerLocked()
 
-	return b.browserCtx, nil
+	return tabCtx, nil
 }
 
 // resetIdleTimerLocked resets or starts the idle timer. Caller must hold b.mux.
@@ -238,18 +173,17 @@ func (b *BrowseTools) idleShutdown() {
 	b.mux.Lock()
 	defer b.mux.Unlock()
 
-	if b.browserCtx == nil {
+	if b.tab == nil {
 		return
 	}
 
-	log.Printf("Browser idle for %v, shutting down", b.idleTimeout)
+	log.Printf("Browser tab idle for %v, closing", b.idleTimeout)
 	b.closeBrowserLocked()
 }
 
-// closeBrowserLocked shuts down the browser. Caller must hold b.mux.
-// It extracts the cancel functions and clears state under the lock,
-// then releases the lock to call the cancel functions (which may block
-// waiting for the chrome process to exit).
+// closeBrowserLocked closes this tool set's browser tab and returns it to the
+// pool. Caller must hold b.mux. It clears state under the lock, then releases
+// the lock to close the tab.
 func (b *BrowseTools) closeBrowserLocked() {
 	// Stop any active screencast before tearing down the browser.
 	// Extract state under lock, then do cleanup without holding it.
@@ -295,39 +229,21 @@ func (b *BrowseTools) closeBrowserLocked() {
 		b.idleTimer = nil
 	}
 
-	browserCancel := b.browserCtxCancel
-	allocCancel := b.allocCancel
-	browserCmd := b.browserCmd
-	b.browserCtxCancel = nil
-	b.allocCancel = nil
-	b.browserCtx = nil
-	b.allocCtx = nil
-	b.browserCmd = nil
-
-	// Release the lock before calling cancel functions. allocCancel in
-	// particular can block waiting for the chrome process to exit, and
-	// holding the mux would prevent GetBrowserContext from proceeding
-	// (it would see browserCtx == nil and start a new browser).
-	b.mux.Unlock()
-	defer b.mux.Lock()
-
-	if browserCancel != nil {
-		browserCancel()
-	}
-	if allocCancel != nil {
-		allocCancel()
-	}
-	// chromedp's allocCancel relies on context cancellation propagating SIGKILL
-	// only to headless-shell's direct process. Renderers, GPU, utility, and
-	// zygote children get reparented to PID 1 and continue running. Since we
-	// launched headless-shell in its own process group (Setpgid), we can
-	// SIGKILL the entire group to guarantee no leaks.
-	if browserCmd != nil && browserCmd.Process != nil {
-		killBrowserProcessGroup(browserCmd.Process.Pid)
+	tab := b.tab
+	b.tab = nil
+
+	// Release the lock before closing the tab. Closing can block waiting for
+	// the browser, and holding the mux would prevent GetBrowserContext from
+	// proceeding (it would see tab == nil and open a new one).
+	if tab != nil {
+		b.mux.Unlock()
+		defer b.mux.Lock()
+		tab.Close()
 	}
 }
 
-// Close shuts down the browser
+// Close closes the browser tab. The shared browser itself is shut down by
+// the pool once it has no tabs left.
 func (b *BrowseTools) Close() {
 	b.mux.Lock()
 	defer b.mux.Unlock()
@@ -538,6 +454,8 @@ func (b *BrowseTools) evalRun(ctx context.Context, input evalInput) llm.ToolOut
 type screenshotInput struct {
 	Selector string `json:"selector,omitempty"`
 	Timeout  string `json:"timeout,omitempty"`
+	FullPage bool   `json:"full_page,omitempty"`
+	Tile     bool   `json:"tile,omitempty"`
 }
 
 func (b *BrowseTools) screenshotRun(ctx context.Context, input screenshotInput) llm.ToolOut {
@@ -561,8 +479,11 @@ func (b *BrowseTools) screenshotRun(ctx context.Context, input screenshotInput)
 			chromedp.WaitReady(input.Selector),
 			chromedp.Screenshot(input.Selector, &buf, chromedp.NodeVisible),
 		)
+	} else if input.FullPage {
+		// Take a screenshot of the whole page, beyond the viewport
+		actions = append(actions, chromedp.FullScreenshot(&buf, 100))
 	} else {
-		// Take full page screenshot
+		// Take a screenshot of the viewport
 		actions = append(actions, chromedp.CaptureScreenshot(&buf))
 	}
 
@@ -604,35 +525,68 @@ func (b *BrowseTools) screenshotRun(ctx context.Context, input screenshotInput)
 
 	// Fit the screenshot inside the model's per-image limits. The full-size
 	// PNG stays on disk at screenshotPath; only the LLM-facing copy is
-	// (potentially) downscaled. A byte-overflow that can't be fixed by
-	// downscaling produces an error so we never send a request the API will
-	// reject.
-	maxDimension, maxBytes := imageLimits(ctx)
-	prepared, err := imageutil.Prepare(buf, screenshotPath, maxDimension, maxBytes)
+	// (potentially) downscaled or tiled. A byte-overflow that can't be fixed
+	// by downscaling produces an error so we never send a request the API
+	// will reject.
+	tiles, err := prepareTiles(ctx, buf, screenshotPath, input.Tile)
 	if err != nil {
 		return llm.ErrorToolOut(err)
 	}
 
-	base64Data := base64.StdEncoding.EncodeToString(prepared.Data)
+	description := fmt.Sprintf("Screenshot taken (saved as %s)", screenshotPath) + tilesNote(tiles)
+	return llm.ToolOut{LLMContent: imageContents(description, tiles), Display: display}
+}
 
-	description := fmt.Sprintf("Screenshot taken (saved as %s)", screenshotPath)
-	if prepared.Resized {
-		description += " [resized to fit model limits]"
+// prepareTiles fits image data inside the model's per-image limits with
+// imageutil.Prepare. If tile is set, an image larger than the model's
+// maximum dimension is first split into overlapping tiles, so that it keeps
+// more detail than one downscaled image would.
+func prepareTiles(ctx context.Context, data []byte, source string, tile bool) ([]imageutil.Prepared, error) {
+	maxDimension, maxBytes := imageLimits(ctx)
+	parts := [][]byte{data}
+	if tile {
+		// Leave images that can't be tiled whole, for Prepare to convert or
+		// reject.
+		if tiles, err := imageutil.Tile(data, maxDimension); err == nil {
+			parts = tiles
+		}
+	}
+	prepared := make([]imageutil.Prepared, len(parts))
+	for i, part := range parts {
+		p, err := imageutil.Prepare(part, source, maxDimension, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		prepared[i] = p
 	}
+	return prepared, nil
+}
 
-	return llm.ToolOut{LLMContent: []llm.Content{
-		{
-			Type: llm.ContentTypeText,
-			Text: description,
-		},
-		{
+// tilesNote describes how images from prepareTiles were fitted to the model.
+func tilesNote(tiles []imageutil.Prepared) string {
+	var note string
+	if len(tiles) > 1 {
+		note += fmt.Sprintf(" [split into %d overlapping tiles, in order]", len(tiles))
+	}
+	if slices.ContainsFunc(tiles, func(p imageutil.Prepared) bool { return p.Resized }) {
+		note += " [resized to fit model limits]"
+	}
+	return note
+}
+
+// imageContents returns a text block with description followed by images.
+func imageContents(description string, images []imageutil.Prepared) []llm.Content {
+	contents := []llm.Content{{Type: llm.ContentTypeText, Text: description}}
+	for _, img := range images {
+		contents = append(contents, llm.Content{
 			Type:          llm.ContentTypeText,
-			MediaType:     prepared.MediaType,
-			Data:          base64Data,
-			DisplayWidth:  prepared.Width,
-			DisplayHeight: prepared.Height,
-		},
-	}, Display: display}
+			MediaType:     img.MediaType,
+			Data:          base64.StdEncoding.EncodeToString(img.Data),
+			DisplayWidth:  img.Width,
+			DisplayHeight: img.Height,
+		})
+	}
+	return contents
 }
 
 // GetTools returns all browser tools. Emulation, network, accessibility, and
@@ -663,8 +617,9 @@ func (b *BrowseTools) CombinedTool() *llm.Tool {
   Parameters: width (integer, required), height (integer, required), timeout (string, optional)
 
 - action: "screenshot"
-  Take a screenshot of the page or a specific element.
-  Parameters: selector (string, optional), timeout (string, optional)
+  Take a screenshot of the viewport, the whole page, or a specific element.
+  Parameters: selector (string, optional), full_page (boolean, optional), tile (boolean, optional), timeout (string, optional)
+  full_page captures the page beyond the viewport. tile splits an image too large for the model into overlapping tiles instead of downscaling it, keeping detail in tall pages.
 
 - action: "console_logs"
   Get recent browser console logs.
@@ -753,6 +708,14 @@ Performance profiling (profile_* actions):
 				"type": "string",
 				"description": "CSS selector for element to screenshot (screenshot action)"
 			},
+			"full_page": {
+				"type": "boolean",
+				"description": "Capture the whole page, beyond the viewport (screenshot action)"
+			},
+			"tile": {
+				"type": "boolean",
+				"description": "Split an image too large for the model into overlapping tiles rather than downscale it (screenshot action)"
+			},
 			"timeout": {
 				"type": "string",
 				"description": "Timeout as a Go duration string (default: 15s)"
@@ -837,22 +800,31 @@ Performance profiling (profile_* actions):
 func (b *BrowseTools) ReadImageTool() *llm.Tool {
 	return &llm.Tool{
 		Name:        "read_image",
-		Description: "Read an image file (such as a screenshot) and encode it for sending to the LLM",
+		Description: "Read an image file (such as a screenshot in the repo) and encode it for sending to the LLM. Relative paths are resolved against the working directory. Large images are resized to fit model limits, or split into tiles with tile.",
 		InputSchema: json.RawMessage(`{
 			"type": "object",
 			"properties": {
 				"path": {
 					"type": "string",
-					"description": "Path to the image file to read"
+					"description": "Path to the image file to read, absolute or relative to the working directory"
 				},
 				"timeout": {
 					"type": "string",
 					"description": "Timeout as a Go duration string (default: 15s)"
+				},
+				"tile": {
+					"type": "boolean",
+					"description": "Split an image too large for the model into overlapping tiles rather than downscale it, to keep detail in tall screenshots"
+				},
+				"frames": {
+					"type": "integer",
+					"description": "For an animated GIF, how many frames to show, spread evenly through the animation and tiled left to right, top to bottom (default: 1, the first frame; at most 16)"
 				}
 			},
 			"required": ["path"]
 		}`),
-		Run: llm.RunJSON(b.readImageRun),
+		Run:      llm.RunJSON(b.readImageRun),
+		ReadOnly: true,
 	}
 }
 
@@ -866,6 +838,8 @@ type combinedInput struct {
 	Height        int    `json:"height,omitempty"`
 	Limit         int    `json:"limit,omitempty"`
 	Selector      string `json:"selector,omitempty"`
+	FullPage      bool   `json:"full_page,omitempty"`
+	Tile          bool   `json:"tile,omitempty"`
 	Timeout       string `json:"timeout,omitempty"`
 	Format        string `json:"format,omitempty"`
 	Quality       int64  `json:"quality,omitempty"`
@@ -902,7 +876,7 @@ func (b *BrowseTools) runCombined(ctx context.Context, input combinedInput) llm.
 	case "resize":
 		return b.resizeRun(ctx, resizeInput{Width: input.Width, Height: input.Height, Timeout: input.Timeout})
 	case "screenshot":
-		return b.screenshotRun(ctx, screenshotInput{Selector: input.Selector, Timeout: input.Timeout})
+		return b.screenshotRun(ctx, screenshotInput{Selector: input.Selector, Timeout: input.Timeout, FullPage: input.FullPage, Tile: input.Tile})
 	case "console_logs":
 		return b.recentConsoleLogsRun(ctx, recentConsoleLogsInput{Limit: input.Limit})
 	case "clear_console_logs":
@@ -1037,9 +1011,15 @@ func GetScreenshotPath(id string) string {
 type readImageInput struct {
 	Path    string `json:"path"`
 	Timeout string `json:"timeout,omitempty"`
+	Tile    bool   `json:"tile,omitempty"`
+	Frames  int    `json:"frames,omitempty"`
 }
 
 func (b *BrowseTools) readImageRun(ctx context.Context, input readImageInput) llm.ToolOut {
+	if wd := llm.WorkingDir(ctx); wd != "" && !filepath.IsAbs(input.Path) {
+		input.Path = filepath.Join(wd, input.Path)
+	}
+
 	// Check if the path exists
 	if _, err := os.Stat(input.Path); os.IsNotExist(err) {
 		return llm.ErrorfToolOut("image file not found: %s", input.Path)
@@ -1051,35 +1031,33 @@ func (b *BrowseTools) readImageRun(ctx context.Context, input readImageInput) ll
 		return llm.ErrorfToolOut("failed to read image file: %w", err)
 	}
 
-	maxDimension, maxBytes := imageLimits(ctx)
-	prepared, err := imageutil.Prepare(imageData, input.Path, maxDimension, maxBytes)
+	frames := imageutil.FrameCount(imageData)
+	sheetFrames := min(input.Frames, frames, imageutil.MaxContactSheetFrames)
+	if sheetFrames > 1 {
+		imageData, err = imageutil.ExtractFrames(imageData, sheetFrames)
+		if err != nil {
+			return llm.ErrorfToolOut("failed to extract frames from %s: %w", input.Path, err)
+		}
+	}
+
+	tiles, err := prepareTiles(ctx, imageData, input.Path, input.Tile)
 	if err != nil {
 		return llm.ErrorToolOut(err)
 	}
-
-	base64Data := base64.StdEncoding.EncodeToString(prepared.Data)
+	prepared := tiles[0]
 
 	description := fmt.Sprintf("Image from %s (type: %s)", input.Path, prepared.MediaType)
-	if prepared.Converted {
-		description += " [converted from HEIC]"
-	}
-	if prepared.Resized {
-		description += " [resized to fit model limits]"
+	switch {
+	case sheetFrames > 1:
+		description += fmt.Sprintf(" [%d of %d animation frames, tiled left to right, top to bottom]", sheetFrames, frames)
+	case frames > 1:
+		description += fmt.Sprintf(" [first of %d animation frames; pass frames for more]", frames)
+	case prepared.Converted:
+		description += " [converted to " + prepared.MediaType + "]"
 	}
+	description += tilesNote(tiles)
 
-	return llm.ToolOut{LLMContent: []llm.Content{
-		{
-			Type: llm.ContentTypeText,
-			Text: description,
-		},
-		{
-			Type:          llm.ContentTypeText,
-			MediaType:     prepared.MediaType,
-			Data:          base64Data,
-			DisplayWidth:  prepared.Width,
-			DisplayHeight: prepared.Height,
-		},
-	}}
+	return llm.ToolOut{LLMContent: imageContents(description, tiles)}
 }
 
 func imageLimits(ctx context.Context) (maxDimension, maxBytes int) {
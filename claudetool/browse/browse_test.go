@@ -7,6 +7,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"image"
+	"image/color"
+	"image/gif"
 	"image/png"
 	"net"
 	"net/http"
@@ -24,8 +26,12 @@ import (
 	"shelley.exe.dev/llm"
 )
 
+// testPool is the browser pool the tests share, as conversations share the
+// server's.
+var testPool = NewPool(DefaultTabsPerBrowser, DefaultPoolIdleTimeout)
+
 func TestCombinedTool(t *testing.T) {
-	tools := NewBrowseTools(context.Background(), 0)
+	tools := NewBrowseTools(context.Background(), testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -76,7 +82,7 @@ func TestCombinedTool(t *testing.T) {
 // families dispatch through the combined browser tool without needing a live
 // browser (help actions are pure text).
 func TestCombinedToolFoldedActions(t *testing.T) {
-	tools := NewBrowseTools(context.Background(), 0)
+	tools := NewBrowseTools(context.Background(), testPool, 0)
 	t.Cleanup(func() { tools.Close() })
 
 	tool := tools.CombinedTool()
@@ -92,7 +98,7 @@ func TestCombinedToolFoldedActions(t *testing.T) {
 }
 
 func TestCombinedToolUnknownAction(t *testing.T) {
-	tools := NewBrowseTools(context.Background(), 0)
+	tools := NewBrowseTools(context.Background(), testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -105,7 +111,7 @@ func TestCombinedToolUnknownAction(t *testing.T) {
 }
 
 func TestGetTools(t *testing.T) {
-	tools := NewBrowseTools(context.Background(), 0)
+	tools := NewBrowseTools(context.Background(), testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -133,7 +139,7 @@ func TestBrowserInitialization(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -170,7 +176,7 @@ func TestNavigateTool(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -214,7 +220,7 @@ func TestNavigateTool(t *testing.T) {
 func TestScreenshotTool(t *testing.T) {
 	// Create browser tools instance
 	ctx := context.Background()
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -271,7 +277,7 @@ func TestScreenshotRunGatesOnImageSupport(t *testing.T) {
 	baseCtx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	tools := NewBrowseTools(baseCtx, 0)
+	tools := NewBrowseTools(baseCtx, testPool, 0)
 	t.Cleanup(func() { tools.Close() })
 
 	// Navigate somewhere so a screenshot can be captured.
@@ -322,7 +328,7 @@ func TestScreenshotRunGatesOnImageSupport(t *testing.T) {
 
 func TestReadImageTool(t *testing.T) {
 	ctx := context.Background()
-	browseTools := NewBrowseTools(ctx, 0)
+	browseTools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		browseTools.Close()
 	})
@@ -365,6 +371,12 @@ func TestReadImageTool(t *testing.T) {
 	if contents[1].Data == "" {
 		t.Errorf("Expected Data in second content")
 	}
+
+	// Relative paths resolve against the working directory.
+	toolOut = tool.Run(llm.WithWorkingDir(ctx, testDir), []byte(`{"path": "test_image.png"}`))
+	if toolOut.Error != nil {
+		t.Fatalf("Read image tool with relative path failed: %v", toolOut.Error)
+	}
 }
 
 // TestDefaultViewportSize verifies that the browser starts with the correct default viewport size
@@ -376,7 +388,7 @@ func TestDefaultViewportSize(t *testing.T) {
 		t.Skip("Skipping browser test in CI/headless environment")
 	}
 
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -428,7 +440,7 @@ func TestBrowserIdleShutdownAndRestart(t *testing.T) {
 	defer cancel()
 
 	idleTimeout := 100 * time.Millisecond
-	tools := NewBrowseTools(ctx, idleTimeout)
+	tools := NewBrowseTools(ctx, testPool, idleTimeout)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -471,7 +483,7 @@ func TestBrowserCrashRecovery(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	tools := NewBrowseTools(ctx, 30*time.Minute)
+	tools := NewBrowseTools(ctx, testPool, 30*time.Minute)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -491,8 +503,8 @@ func TestBrowserCrashRecovery(t *testing.T) {
 	// Simulate a crash by canceling the browser context
 	// This mimics what chromedp does when Chrome segfaults
 	tools.mux.Lock()
-	if tools.browserCtxCancel != nil {
-		tools.browserCtxCancel()
+	if tools.tab != nil {
+		tools.tab.cancel()
 	}
 	tools.mux.Unlock()
 
@@ -542,7 +554,7 @@ func (s limitedService) Provider() string        { return "test" }
 func (s limitedService) SupportsImages() bool    { return true }
 
 func TestReadImageToolResizesOversizedImage(t *testing.T) {
-	browseTools := NewBrowseTools(context.Background(), 0)
+	browseTools := NewBrowseTools(context.Background(), testPool, 0)
 	t.Cleanup(func() {
 		browseTools.Close()
 	})
@@ -589,7 +601,7 @@ func TestReadImageToolResizesOversizedImage(t *testing.T) {
 }
 
 func TestReadImageToolRejectsOversizedBytes(t *testing.T) {
-	browseTools := NewBrowseTools(context.Background(), 0)
+	browseTools := NewBrowseTools(context.Background(), testPool, 0)
 	t.Cleanup(func() {
 		browseTools.Close()
 	})
@@ -622,7 +634,7 @@ func TestReadImageToolRejectsOversizedBytes(t *testing.T) {
 func TestReadImageToolNoServicePassesThrough(t *testing.T) {
 	// When no service is attached to the context (e.g. driven from tests or
 	// non-loop callers) the size checks should be skipped.
-	browseTools := NewBrowseTools(context.Background(), 0)
+	browseTools := NewBrowseTools(context.Background(), testPool, 0)
 	t.Cleanup(func() {
 		browseTools.Close()
 	})
@@ -677,7 +689,7 @@ func TestIsPort80(t *testing.T) {
 // TestResizeRunErrorPaths tests error paths in resize action
 func TestResizeRunErrorPaths(t *testing.T) {
 	ctx := context.Background()
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -706,7 +718,7 @@ func TestResizeRunErrorPaths(t *testing.T) {
 // TestScreenshotRunErrorPaths tests error paths in screenshot action
 func TestScreenshotRunErrorPaths(t *testing.T) {
 	ctx := context.Background()
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -722,7 +734,7 @@ func TestScreenshotRunErrorPaths(t *testing.T) {
 
 func TestRecentConsoleLogsRunErrorPaths(t *testing.T) {
 	ctx := context.Background()
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -764,7 +776,7 @@ func TestParseTimeout(t *testing.T) {
 func TestRegisterBrowserTools(t *testing.T) {
 	ctx := context.Background()
 
-	tools, cleanup := RegisterBrowserTools(ctx)
+	tools, cleanup := RegisterBrowserTools(ctx, testPool)
 	t.Cleanup(cleanup)
 
 	if len(tools) != 2 {
@@ -793,7 +805,7 @@ func TestGetScreenshotPath(t *testing.T) {
 // TestSaveScreenshotErrorPath tests error paths in SaveScreenshot
 func TestSaveScreenshotErrorPath(t *testing.T) {
 	ctx := context.Background()
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -812,7 +824,7 @@ func TestSaveScreenshotErrorPath(t *testing.T) {
 // TestConsoleLogsWriteToFile tests that large console logs are written to file
 func TestConsoleLogsWriteToFile(t *testing.T) {
 	ctx := context.Background()
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -828,9 +840,13 @@ func TestConsoleLogsWriteToFile(t *testing.T) {
 	}
 	tools.consoleLogsMutex.Unlock()
 
-	// Mock browser context to avoid actual browser initialization
+	// Mock the browser tab to avoid actual browser initialization
 	tools.mux.Lock()
-	tools.browserCtx = ctx
+	tools.tab = &Tab{
+		ctx:     ctx,
+		cancel:  func() {},
+		browser: &pooledBrowser{pool: NewPool(1, time.Hour), cancel: func() {}, allocCancel: func() {}, tabs: 1},
+	}
 	tools.mux.Unlock()
 
 	tool := tools.CombinedTool()
@@ -862,7 +878,7 @@ func TestConsoleLogsWriteToFile(t *testing.T) {
 // TestGenerateDownloadFilename tests filename generation with randomness
 func TestGenerateDownloadFilename(t *testing.T) {
 	ctx := context.Background()
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -908,7 +924,7 @@ func TestGenerateDownloadFilename(t *testing.T) {
 // TestDownloadTracking tests the download event handling
 func TestDownloadTracking(t *testing.T) {
 	ctx := context.Background()
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -957,7 +973,7 @@ func TestDownloadTracking(t *testing.T) {
 // TestToolOutWithDownloads tests the download info appending to tool output
 func TestToolOutWithDownloads(t *testing.T) {
 	ctx := context.Background()
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -1044,7 +1060,7 @@ func TestBrowserDownload(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -1132,7 +1148,7 @@ func TestBrowserDownloadReported(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -1180,7 +1196,7 @@ func TestLargeJSOutputWriteToFile(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -1244,7 +1260,7 @@ func TestSmallJSOutputInline(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() {
 		tools.Close()
 	})
@@ -1278,3 +1294,82 @@ func TestSmallJSOutputInline(t *testing.T) {
 		t.Errorf("Small result should not be written to file, got: %s", result)
 	}
 }
+
+func TestReadImageToolAnimatedGIF(t *testing.T) {
+	browseTools := NewBrowseTools(context.Background(), testPool, 0)
+	t.Cleanup(func() {
+		browseTools.Close()
+	})
+
+	p := color.Palette{color.Black, color.White}
+	g := &gif.GIF{Config: image.Config{ColorModel: p, Width: 30, Height: 20}}
+	for i := range 6 {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 30, 20), p))
+		g.Image[i].Pix[0] = uint8(i % 2)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	testImagePath := filepath.Join(t.TempDir(), "recording.gif")
+	if err := os.WriteFile(testImagePath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := browseTools.ReadImageTool()
+	tests := []struct {
+		input         string
+		description   string
+		width, height int
+	}{
+		{fmt.Sprintf(`{"path": %q}`, testImagePath), "first of 6 animation frames", 30, 20},
+		{fmt.Sprintf(`{"path": %q, "frames": 4}`, testImagePath), "4 of 6 animation frames", 60, 40},
+	}
+	for _, tt := range tests {
+		toolOut := tool.Run(context.Background(), []byte(tt.input))
+		if toolOut.Error != nil {
+			t.Fatalf("unexpected error: %v", toolOut.Error)
+		}
+		if !strings.Contains(toolOut.LLMContent[0].Text, tt.description) {
+			t.Errorf("description = %q, want it to mention %q", toolOut.LLMContent[0].Text, tt.description)
+		}
+		if c := toolOut.LLMContent[1]; c.MediaType != "image/png" || c.DisplayWidth != tt.width || c.DisplayHeight != tt.height {
+			t.Errorf("image = %s %dx%d, want image/png %dx%d", c.MediaType, c.DisplayWidth, c.DisplayHeight, tt.width, tt.height)
+		}
+	}
+}
+
+func TestReadImageToolTiles(t *testing.T) {
+	browseTools := NewBrowseTools(context.Background(), testPool, 0)
+	t.Cleanup(func() {
+		browseTools.Close()
+	})
+	ctx := llm.WithLLMService(context.Background(), limitedService{maxDim: 300})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 100, 1000))); err != nil {
+		t.Fatal(err)
+	}
+	testImagePath := filepath.Join(t.TempDir(), "full_page.png")
+	if err := os.WriteFile(testImagePath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := browseTools.ReadImageTool()
+	toolOut := tool.Run(ctx, []byte(fmt.Sprintf(`{"path": %q, "tile": true}`, testImagePath)))
+	if toolOut.Error != nil {
+		t.Fatalf("unexpected error: %v", toolOut.Error)
+	}
+	if len(toolOut.LLMContent) != 5 {
+		t.Fatalf("got %d content blocks, want a description and 4 tiles", len(toolOut.LLMContent))
+	}
+	if !strings.Contains(toolOut.LLMContent[0].Text, "4 overlapping tiles") || strings.Contains(toolOut.LLMContent[0].Text, "resized") {
+		t.Errorf("description = %q, want it to mention 4 tiles and no resizing", toolOut.LLMContent[0].Text)
+	}
+	for _, c := range toolOut.LLMContent[1:] {
+		if c.DisplayWidth != 100 || c.DisplayHeight != 300 {
+			t.Errorf("tile is %dx%d, want 100x300", c.DisplayWidth, c.DisplayHeight)
+		}
+	}
+}
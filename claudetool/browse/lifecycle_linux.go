@@ -2,8 +2,51 @@
 
 package browse
 
-import "syscall"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
 
 func setPdeathsig(attr *syscall.SysProcAttr) {
 	attr.Pdeathsig = syscall.SIGKILL
 }
+
+// processGroupRSS sums the resident set size of every process in the process
+// group pgid, read from /proc. Processes that vanish mid-scan are skipped.
+func processGroupRSS(pgid int) int64 {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0
+	}
+	pageSize := int64(os.Getpagesize())
+	var total int64
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		data, err := os.ReadFile("/proc/" + e.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// The command name (field 2) may contain spaces; fields after the
+		// closing paren are space-separated: state(3) ppid(4) pgrp(5) ... rss(24).
+		s := string(data)
+		i := strings.LastIndexByte(s, ')')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(s[i+1:])
+		if len(fields) < 22 {
+			continue
+		}
+		if pg, err := strconv.Atoi(fields[2]); err != nil || pg != pgid {
+			continue
+		}
+		if rss, err := strconv.ParseInt(fields[21], 10, 64); err == nil {
+			total += rss * pageSize
+		}
+	}
+	return total
+}
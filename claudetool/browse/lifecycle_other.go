@@ -7,3 +7,5 @@ import "os/exec"
 func configureBrowserCmd(cmd *exec.Cmd) {}
 
 func killBrowserProcessGroup(int) {}
+
+func processGroupRSS(int) int64 { return 0 }
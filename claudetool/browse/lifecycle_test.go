@@ -12,7 +12,7 @@ import (
 	"time"
 )
 
-// TestBrowserProcessGroupCleanup verifies that closing a BrowseTools instance
+// TestBrowserProcessGroupCleanup verifies that closing a browser pool
 // kills not just headless-shell but its entire process group (zygote, GPU,
 // renderer, utility processes). Without Setpgid + killpg, descendants
 // reparent to PID 1 and live on past Shelley shutdown — the bug this guards
@@ -25,7 +25,8 @@ func TestBrowserProcessGroupCleanup(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	tools := NewBrowseTools(ctx, 0)
+	pool := NewPool(DefaultTabsPerBrowser, DefaultPoolIdleTimeout)
+	tools := NewBrowseTools(ctx, pool, 0)
 
 	if _, err := tools.GetBrowserContext(); err != nil {
 		if strings.Contains(err.Error(), "failed to start browser") {
@@ -35,7 +36,7 @@ func TestBrowserProcessGroupCleanup(t *testing.T) {
 	}
 
 	tools.mux.Lock()
-	cmd := tools.browserCmd
+	cmd := tools.tab.browser.cmd
 	tools.mux.Unlock()
 	if cmd == nil || cmd.Process == nil {
 		t.Fatal("browserCmd not captured; ModifyCmdFunc wiring is broken")
@@ -61,6 +62,7 @@ func TestBrowserProcessGroupCleanup(t *testing.T) {
 	}
 
 	tools.Close()
+	pool.Close()
 
 	// Give the kernel a moment to reap.
 	deadline := time.Now().Add(5 * time.Second)
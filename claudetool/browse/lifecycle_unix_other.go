@@ -5,3 +5,5 @@ package browse
 import "syscall"
 
 func setPdeathsig(*syscall.SysProcAttr) {}
+
+func processGroupRSS(int) int64 { return 0 }
@@ -43,7 +43,7 @@ func TestBrowserNetworkClearCache(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	tools := NewBrowseTools(ctx, 0)
+	tools := NewBrowseTools(ctx, testPool, 0)
 	t.Cleanup(func() { tools.Close() })
 	t.Cleanup(func() { server.Close() })
 
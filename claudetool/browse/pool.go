@@ -0,0 +1,279 @@
+package browse
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultTabsPerBrowser is how many conversations share one headless browser
+// process before the pool launches another.
+const DefaultTabsPerBrowser = 8
+
+// DefaultPoolIdleTimeout is how long a browser with no open tabs is kept
+// around before the pool kills it.
+const DefaultPoolIdleTimeout = 5 * time.Minute
+
+// Pool lazily launches headless browsers and hands out tabs in them, so
+// conversations share a few Chrome processes instead of each spawning its
+// own. Each tab has its own browser context, isolating its cookies, storage
+// and cache. A browser is killed once it has had no tabs for the idle timeout.
+type Pool struct {
+	ctx            context.Context
+	tabsPerBrowser int
+	idleTimeout    time.Duration
+
+	mu       sync.Mutex
+	browsers []*pooledBrowser
+}
+
+type pooledBrowser struct {
+	pool        *Pool
+	ctx         context.Context
+	cancel      context.CancelFunc
+	allocCancel context.CancelFunc
+	cmd         *exec.Cmd
+	tabs        int // guarded by pool.mu
+	idleTimer   *time.Timer
+}
+
+// PoolStats describes the pool's current footprint.
+type PoolStats struct {
+	Browsers int `json:"browsers"`
+	Tabs     int `json:"tabs"`
+	// RSSBytes is the resident memory of all browser process groups.
+	// Only measured on Linux; 0 elsewhere.
+	RSSBytes int64 `json:"rss_bytes"`
+}
+
+// NewPool creates a browser pool. Browsers live until they have been tab-less
+// for idleTimeout.
+func NewPool(tabsPerBrowser int, idleTimeout time.Duration) *Pool {
+	return &Pool{
+		ctx:            context.Background(),
+		tabsPerBrowser: tabsPerBrowser,
+		idleTimeout:    idleTimeout,
+	}
+}
+
+// Tab is a browser tab leased from a Pool. Close it to return the slot.
+type Tab struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	browser *pooledBrowser
+	once    sync.Once
+}
+
+// Context returns the chromedp context for the tab.
+func (t *Tab) Context() context.Context { return t.ctx }
+
+// BrowserContextID returns the ID of the tab's browser context.
+func (t *Tab) BrowserContextID() cdp.BrowserContextID {
+	return chromedp.FromContext(t.ctx).BrowserContextID
+}
+
+// Close closes the tab and releases its slot in the browser.
+func (t *Tab) Close() {
+	t.once.Do(func() {
+		t.cancel()
+		t.browser.pool.release(t.browser)
+	})
+}
+
+// Acquire opens a new tab, reusing a running browser with spare capacity or
+// launching a new one. Each tab gets its own browser context, so cookies,
+// storage and cache aren't shared with other conversations' tabs.
+func (p *Pool) Acquire() (*Tab, error) {
+	pb, dead := p.reserve()
+	// Launch and shut down browsers without holding p.mu: both can take a
+	// long time, and other conversations' Acquire and Close need the lock.
+	for _, b := range dead {
+		b.shutdown()
+	}
+	if pb == nil {
+		var err error
+		if pb, err = p.launch(); err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		pb.tabs = 1
+		p.browsers = append(p.browsers, pb)
+		p.mu.Unlock()
+	}
+
+	tabCtx, tabCancel := chromedp.NewContext(pb.ctx, chromedp.WithNewBrowserContext())
+	if err := chromedp.Run(tabCtx); err != nil {
+		tabCancel()
+		p.release(pb)
+		return nil, fmt.Errorf("failed to open browser tab: %w", err)
+	}
+	tab := &Tab{ctx: tabCtx, cancel: tabCancel, browser: pb}
+	// Allow downloads and emit their events to the tab.
+	if err := chromedp.Run(tabCtx,
+		browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).
+			WithBrowserContextID(tab.BrowserContextID()).
+			WithDownloadPath(DownloadDir).
+			WithEventsEnabled(true),
+	); err != nil {
+		tab.Close()
+		return nil, fmt.Errorf("failed to configure download behavior: %w", err)
+	}
+	return tab, nil
+}
+
+// reserve takes a tab slot in a running browser with spare capacity, or
+// returns nil if none has one. It also drops dead browsers from the pool,
+// returning them for the caller to shut down.
+func (p *Pool) reserve() (pb *pooledBrowser, dead []*pooledBrowser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	live := p.browsers[:0]
+	for _, b := range p.browsers {
+		if b.ctx.Err() != nil {
+			log.Printf("browse: pooled browser died (err: %v), discarding", b.ctx.Err())
+			dead = append(dead, b)
+			continue
+		}
+		live = append(live, b)
+		if pb == nil && b.tabs < p.tabsPerBrowser {
+			pb = b
+		}
+	}
+	p.browsers = live
+	if pb != nil {
+		pb.tabs++
+		if pb.idleTimer != nil {
+			pb.idleTimer.Stop()
+			pb.idleTimer = nil
+		}
+	}
+	return pb, dead
+}
+
+func (p *Pool) release(pb *pooledBrowser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pb.tabs--
+	if pb.tabs > 0 {
+		return
+	}
+	pb.idleTimer = time.AfterFunc(p.idleTimeout, func() {
+		p.mu.Lock()
+		if pb.tabs > 0 {
+			p.mu.Unlock()
+			return
+		}
+		p.removeLocked(pb)
+		p.mu.Unlock()
+		log.Printf("browse: browser idle for %v, shutting down", p.idleTimeout)
+		// Shut down without holding p.mu: allocCancel can block waiting
+		// for the chrome process to exit.
+		pb.shutdown()
+	})
+}
+
+func (p *Pool) removeLocked(pb *pooledBrowser) {
+	for i, b := range p.browsers {
+		if b == pb {
+			p.browsers = append(p.browsers[:i], p.browsers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Stats reports the number of running browsers, open tabs, and their memory.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var st PoolStats
+	for _, b := range p.browsers {
+		st.Browsers++
+		st.Tabs += b.tabs
+		if b.cmd != nil && b.cmd.Process != nil {
+			st.RSSBytes += processGroupRSS(b.cmd.Process.Pid)
+		}
+	}
+	return st
+}
+
+// Close kills every browser in the pool, including ones with open tabs.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	browsers := p.browsers
+	p.browsers = nil
+	p.mu.Unlock()
+	for _, b := range browsers {
+		b.shutdown()
+	}
+}
+
+// launch starts a new headless browser.
+func (p *Pool) launch() (*pooledBrowser, error) {
+	opts := chromedp.DefaultExecAllocatorOptions[:]
+	opts = append(opts, chromedp.NoSandbox)
+	opts = append(opts, chromedp.Flag("--disable-dbus", true))
+	opts = append(opts, chromedp.WSURLReadTimeout(60*time.Second))
+	// Disable WebAuthn to prevent segfaults on FIDO/WebAuthn sites (issue #78)
+	// Must include all default disabled features plus WebAuthentication
+	// (chromedp v0.14.1 defaults: site-per-process,Translate,BlinkGenPropertyTrees)
+	opts = append(opts, chromedp.Flag("disable-features",
+		"site-per-process,Translate,BlinkGenPropertyTrees,WebAuthentication"))
+
+	// Capture the *exec.Cmd headless-shell is launched with so shutdown
+	// can kill the whole process group. headless-shell forks zygote, renderers,
+	// GPU and utility processes; chromedp's default cancel only SIGKILLs the
+	// direct child, leaving descendants orphaned to PID 1. ModifyCmdFunc also
+	// replaces chromedp's default cmd setup, so configureBrowserCmd re-applies
+	// Pdeathsig and adds Setpgid for clean group kill.
+	// ModifyCmdFunc runs synchronously on the chromedp.Run goroutine before
+	// cmd.Start, so a plain pointer assignment is enough — Run returns after
+	// the browser is up, so by the time we read capturedCmd below the function
+	// has already finished.
+	var capturedCmd *exec.Cmd
+	opts = append(opts, chromedp.ModifyCmdFunc(func(cmd *exec.Cmd) {
+		configureBrowserCmd(cmd)
+		capturedCmd = cmd
+	}))
+
+	allocCtx, allocCancel := chromedp.NewExecAllocator(p.ctx, opts...)
+	browserCtx, browserCancel := chromedp.NewContext(
+		allocCtx,
+		chromedp.WithLogf(log.Printf),
+		chromedp.WithErrorf(log.Printf),
+		chromedp.WithBrowserOption(chromedp.WithDialTimeout(60*time.Second)),
+	)
+	pb := &pooledBrowser{pool: p, ctx: browserCtx, cancel: browserCancel, allocCancel: allocCancel}
+
+	if err := chromedp.Run(browserCtx); err != nil {
+		pb.cmd = capturedCmd
+		pb.shutdown()
+		return nil, fmt.Errorf("failed to start browser (please apt install chromium or equivalent): %w", err)
+	}
+	pb.cmd = capturedCmd
+	return pb, nil
+}
+
+// shutdown kills the browser and its whole process group.
+func (pb *pooledBrowser) shutdown() {
+	if pb.idleTimer != nil {
+		pb.idleTimer.Stop()
+		pb.idleTimer = nil
+	}
+	pb.cancel()
+	pb.allocCancel()
+	// chromedp's allocCancel relies on context cancellation propagating SIGKILL
+	// only to headless-shell's direct process. Renderers, GPU, utility, and
+	// zygote children get reparented to PID 1 and continue running. Since we
+	// launched headless-shell in its own process group (Setpgid), we can
+	// SIGKILL the entire group to guarantee no leaks.
+	if pb.cmd != nil && pb.cmd.Process != nil {
+		killBrowserProcessGroup(pb.cmd.Process.Pid)
+	}
+}
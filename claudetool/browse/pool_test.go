@@ -0,0 +1,54 @@
+package browse
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPoolSharesBrowser verifies that tool sets share a browser process until
+// the per-browser tab limit is reached, each in its own browser context, and
+// that Stats reflects it.
+func TestPoolSharesBrowser(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pool := NewPool(2, time.Minute)
+	t.Cleanup(pool.Close)
+
+	var tools []*BrowseTools
+	for range 3 {
+		bt := NewBrowseTools(ctx, pool, 0)
+		t.Cleanup(bt.Close)
+		if _, err := bt.GetBrowserContext(); err != nil {
+			if strings.Contains(err.Error(), "failed to start browser") {
+				t.Skip("Browser automation not available in this environment")
+			}
+			t.Fatalf("GetBrowserContext: %v", err)
+		}
+		tools = append(tools, bt)
+	}
+
+	if tools[0].tab.browser != tools[1].tab.browser {
+		t.Error("expected first two tool sets to share a browser")
+	}
+	if tools[1].tab.browser == tools[2].tab.browser {
+		t.Error("expected third tool set to get a new browser")
+	}
+	if tools[0].tab.BrowserContextID() == tools[1].tab.BrowserContextID() {
+		t.Error("expected tabs in a shared browser to have their own browser contexts")
+	}
+	st := pool.Stats()
+	if st.Browsers != 2 || st.Tabs != 3 {
+		t.Errorf("Stats() = %+v, want 2 browsers and 3 tabs", st)
+	}
+
+	tools[2].Close()
+	if st := pool.Stats(); st.Tabs != 2 {
+		t.Errorf("after Close, Stats().Tabs = %d, want 2", st.Tabs)
+	}
+}
@@ -10,9 +10,9 @@ import (
 // It also returns a cleanup function that should be called when done to properly close the browser.
 // The browser will be initialized lazily when a browser tool is first used.
 // Per-image size limits are looked up from the llm.Service in the tool call
-// context at run time, not configured here.
-func RegisterBrowserTools(ctx context.Context) ([]*llm.Tool, func()) {
-	browserTools := NewBrowseTools(ctx, 0)
+// context at run time, not configured here. The tools take their tab from pool.
+func RegisterBrowserTools(ctx context.Context, pool *Pool) ([]*llm.Tool, func()) {
+	browserTools := NewBrowseTools(ctx, pool, 0)
 
 	return browserTools.GetTools(), func() {
 		browserTools.Close()
@@ -0,0 +1,82 @@
+package claudetool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+
+	"shelley.exe.dev/llm"
+)
+
+// cacheableTools are the tools whose results depend only on their input,
+// the working directory, and the files on disk.
+var cacheableTools = map[string]bool{
+	"keyword_search": true,
+	"outline":        true,
+	"read_image":     true,
+}
+
+// toolCache remembers successful results of cacheable tools within one
+// conversation. Any other tool may change files, so running one clears it,
+// as does the start of a user turn, since the user may have changed them.
+type toolCache struct {
+	wd      *MutableWorkingDir
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]llm.ToolOut
+	// gen counts clears, so a result computed across one isn't stored.
+	gen int
+}
+
+// cacheTools returns tools wrapped to share one cache, and a function that
+// clears it.
+func cacheTools(tools []*llm.Tool, wd *MutableWorkingDir) ([]*llm.Tool, func()) {
+	c := &toolCache{wd: wd, entries: make(map[[sha256.Size]byte]llm.ToolOut)}
+	out := make([]*llm.Tool, len(tools))
+	for i, t := range tools {
+		out[i] = c.wrap(t)
+	}
+	return out, c.clear
+}
+
+func (c *toolCache) wrap(t *llm.Tool) *llm.Tool {
+	if t.Run == nil {
+		return t
+	}
+	run := t.Run
+	wrapped := *t
+	if !cacheableTools[t.Name] {
+		wrapped.Run = func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+			defer c.clear()
+			return run(ctx, input)
+		}
+		return &wrapped
+	}
+	wrapped.Run = func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+		key := sha256.Sum256([]byte(t.Name + "\x00" + c.wd.Get() + "\x00" + string(input)))
+		c.mu.Lock()
+		out, ok := c.entries[key]
+		gen := c.gen
+		c.mu.Unlock()
+		if ok {
+			return out
+		}
+		out = run(ctx, input)
+		if out.Error == nil {
+			c.mu.Lock()
+			if c.gen == gen {
+				c.entries[key] = out
+			}
+			c.mu.Unlock()
+		}
+		return out
+	}
+	return &wrapped
+}
+
+func (c *toolCache) clear() {
+	c.mu.Lock()
+	clear(c.entries)
+	c.gen++
+	c.mu.Unlock()
+}
@@ -0,0 +1,49 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"shelley.exe.dev/llm"
+)
+
+func TestToolCache(t *testing.T) {
+	searches := 0
+	search := &llm.Tool{Name: "keyword_search", Run: func(context.Context, json.RawMessage) llm.ToolOut {
+		searches++
+		return llm.ToolOut{LLMContent: llm.TextContent("found")}
+	}}
+	patch := &llm.Tool{Name: "patch", Run: func(context.Context, json.RawMessage) llm.ToolOut {
+		return llm.ToolOut{}
+	}}
+	wd := NewMutableWorkingDir("/a")
+	tools, clearCache := cacheTools([]*llm.Tool{search, patch}, wd)
+	search, patch = tools[0], tools[1]
+
+	ctx := context.Background()
+	search.Run(ctx, json.RawMessage(`{"query":"x"}`))
+	search.Run(ctx, json.RawMessage(`{"query":"x"}`))
+	if searches != 1 {
+		t.Fatalf("identical call was not cached: %d runs", searches)
+	}
+	search.Run(ctx, json.RawMessage(`{"query":"y"}`))
+	if searches != 2 {
+		t.Fatalf("different input hit the cache: %d runs", searches)
+	}
+	wd.Set("/b")
+	search.Run(ctx, json.RawMessage(`{"query":"x"}`))
+	if searches != 3 {
+		t.Fatalf("different working dir hit the cache: %d runs", searches)
+	}
+	patch.Run(ctx, nil)
+	search.Run(ctx, json.RawMessage(`{"query":"x"}`))
+	if searches != 4 {
+		t.Fatalf("patch did not invalidate the cache: %d runs", searches)
+	}
+	clearCache()
+	search.Run(ctx, json.RawMessage(`{"query":"x"}`))
+	if searches != 5 {
+		t.Fatalf("clearing did not invalidate the cache: %d runs", searches)
+	}
+}
@@ -0,0 +1,290 @@
+package claudetool
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"shelley.exe.dev/claudetool/onstart"
+	"shelley.exe.dev/llm"
+)
+
+// DiagnosticsTool runs the project's linters and type checkers and reports
+// their findings in one format, grouped by file.
+type DiagnosticsTool struct {
+	// WorkingDir is the shared mutable working directory.
+	WorkingDir *MutableWorkingDir
+	// Env is exposed to the linters as SHELLEY_* variables.
+	Env ShelleyEnv
+	// Sandbox, if set, confines the linters.
+	Sandbox Sandbox
+}
+
+const (
+	diagnosticsName        = "diagnostics"
+	diagnosticsDescription = `Run linters and type checkers and return their issues grouped by file.
+
+Linters are chosen from the repository's manifests: go vet (go.mod), eslint (eslint config), tsc (tsconfig.json), ruff (Python projects).
+Pass "linters" to choose explicitly. Pass "path" to narrow the check (a Go package pattern, or a file/directory for eslint and ruff; tsc always checks the whole project).
+
+Use this instead of running linters via bash when fixing lint or type errors.
+`
+	diagnosticsInputSchema = `{
+  "type": "object",
+  "properties": {
+    "linters": {
+      "type": "array",
+      "items": {"type": "string", "enum": ["go vet", "eslint", "tsc", "ruff"]},
+      "description": "Linters to run; detected from the codebase if omitted"
+    },
+    "path": {
+      "type": "string",
+      "description": "Package pattern, file, or directory to check"
+    }
+  }
+}`
+)
+
+// maxDiagnostics caps how many issues are reported; the rest are counted.
+const maxDiagnostics = 200
+
+type diagnosticsInput struct {
+	Linters []string `json:"linters"`
+	Path    string   `json:"path"`
+}
+
+// Diagnostic is a single issue reported by a linter.
+type Diagnostic struct {
+	Linter   string `json:"linter"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Tool returns an llm.Tool for running diagnostics.
+func (d *DiagnosticsTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        diagnosticsName,
+		Description: diagnosticsDescription,
+		InputSchema: llm.MustSchema(diagnosticsInputSchema),
+		Run:         llm.RunJSON(d.run),
+	}
+}
+
+func (d *DiagnosticsTool) run(ctx context.Context, req diagnosticsInput) llm.ToolOut {
+	wd := d.WorkingDir.Get()
+	linters := req.Linters
+	if len(linters) == 0 {
+		cb, err := onstart.AnalyzeCodebase(ctx, wd)
+		if err != nil {
+			return llm.ErrorfToolOut("failed to detect linters (pass linters explicitly): %w", err)
+		}
+		linters = cb.Linters()
+		if len(linters) == 0 {
+			return llm.ErrorfToolOut("no linters detected in %s; pass linters explicitly", wd)
+		}
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, DefaultSlowTimeout)
+	defer cancel()
+
+	var diags []Diagnostic
+	for _, linter := range linters {
+		args, err := lintCommand(linter, req.Path)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := (&BashTool{WorkingDir: d.WorkingDir, Env: d.Env, Sandbox: d.Sandbox}).makeArgvCommand(execCtx, args, &stdout)
+		cmd.Stderr = &stderr
+		runErr := cmd.Run()
+		if execCtx.Err() == context.DeadlineExceeded {
+			return llm.ErrorfToolOut("%s timed out after %s", linter, DefaultSlowTimeout)
+		}
+		found, err := parseDiagnostics(linter, stdout.Bytes(), stderr.Bytes())
+		if err != nil {
+			return llm.ErrorfToolOut("failed to parse %s output: %w\n%s", linter, err, tail(stdout.String()+stderr.String(), maxFailureOutput))
+		}
+		if runErr != nil && len(found) == 0 {
+			// Linters exit non-zero when they find issues; failing without
+			// any means the linter itself didn't run.
+			return llm.ErrorfToolOut("[%s failed: %w]\n%s", strings.Join(args, " "), runErr, tail(stdout.String()+stderr.String(), maxFailureOutput))
+		}
+		for i := range found {
+			found[i].Linter = linter
+			if filepath.IsAbs(found[i].File) {
+				if rel, err := filepath.Rel(wd, found[i].File); err == nil {
+					found[i].File = rel
+				}
+			}
+		}
+		diags = append(diags, found...)
+	}
+
+	slices.SortStableFunc(diags, func(a, b Diagnostic) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return llm.ToolOut{LLMContent: llm.TextContent(formatDiagnostics(linters, diags)), Display: diags}
+}
+
+// lintCommand returns the argv for running linter over path.
+func lintCommand(linter, path string) ([]string, error) {
+	switch linter {
+	case "go vet":
+		return []string{"go", "vet", cmp.Or(path, "./...")}, nil
+	case "eslint":
+		return []string{"npx", "eslint", "--format", "json", cmp.Or(path, ".")}, nil
+	case "tsc":
+		return []string{"npx", "tsc", "--noEmit", "--pretty", "false"}, nil
+	case "ruff":
+		return []string{"ruff", "check", "--output-format", "json", cmp.Or(path, ".")}, nil
+	}
+	return nil, fmt.Errorf("unsupported linter %q", linter)
+}
+
+func parseDiagnostics(linter string, stdout, stderr []byte) ([]Diagnostic, error) {
+	switch linter {
+	case "go vet":
+		return parseGoVet(stderr), nil
+	case "eslint":
+		return parseESLintJSON(stdout)
+	case "tsc":
+		return parseTSC(stdout), nil
+	case "ruff":
+		return parseRuffJSON(stdout)
+	}
+	return nil, fmt.Errorf("unsupported linter %q", linter)
+}
+
+// goVetRe matches both vet findings and compiler errors, e.g.
+// "vet: ./a.go:3:2: unreachable code" or "./a.go:3:2: undefined: x".
+var goVetRe = regexp.MustCompile(`^(?:vet: )?([^\s:][^:]*\.go):(\d+):(\d+): (.*)$`)
+
+func parseGoVet(out []byte) []Diagnostic {
+	var diags []Diagnostic
+	for line := range strings.Lines(string(out)) {
+		m := goVetRe.FindStringSubmatch(strings.TrimRight(line, "\n"))
+		if m == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		diags = append(diags, Diagnostic{File: filepath.Clean(m[1]), Line: lineNo, Column: col, Severity: "error", Message: m[4]})
+	}
+	return diags
+}
+
+// tscRe matches tsc's non-pretty output: "src/a.ts(3,5): error TS2322: message".
+var tscRe = regexp.MustCompile(`^(.+)\((\d+),(\d+)\): (error|warning) (TS\d+): (.*)$`)
+
+func parseTSC(out []byte) []Diagnostic {
+	var diags []Diagnostic
+	for line := range strings.Lines(string(out)) {
+		m := tscRe.FindStringSubmatch(strings.TrimRight(line, "\n"))
+		if m == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		diags = append(diags, Diagnostic{File: m[1], Line: lineNo, Column: col, Severity: m[4], Code: m[5], Message: m[6]})
+	}
+	return diags
+}
+
+type eslintFile struct {
+	FilePath string `json:"filePath"`
+	Messages []struct {
+		RuleID   string `json:"ruleId"`
+		Severity int    `json:"severity"` // 1 = warning, 2 = error
+		Message  string `json:"message"`
+		Line     int    `json:"line"`
+		Column   int    `json:"column"`
+	} `json:"messages"`
+}
+
+func parseESLintJSON(out []byte) ([]Diagnostic, error) {
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var files []eslintFile
+	if err := json.Unmarshal(out, &files); err != nil {
+		return nil, err
+	}
+	var diags []Diagnostic
+	for _, f := range files {
+		for _, m := range f.Messages {
+			sev := "warning"
+			if m.Severity == 2 {
+				sev = "error"
+			}
+			diags = append(diags, Diagnostic{File: f.FilePath, Line: m.Line, Column: m.Column, Severity: sev, Code: m.RuleID, Message: m.Message})
+		}
+	}
+	return diags, nil
+}
+
+type ruffIssue struct {
+	Filename string `json:"filename"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Location struct {
+		Row    int `json:"row"`
+		Column int `json:"column"`
+	} `json:"location"`
+}
+
+func parseRuffJSON(out []byte) ([]Diagnostic, error) {
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var issues []ruffIssue
+	if err := json.Unmarshal(out, &issues); err != nil {
+		return nil, err
+	}
+	diags := make([]Diagnostic, 0, len(issues))
+	for _, i := range issues {
+		diags = append(diags, Diagnostic{File: i.Filename, Line: i.Location.Row, Column: i.Location.Column, Severity: "error", Code: i.Code, Message: i.Message})
+	}
+	return diags, nil
+}
+
+// formatDiagnostics renders diags (sorted by file) for the model.
+func formatDiagnostics(linters []string, diags []Diagnostic) string {
+	ran := strings.Join(linters, ", ")
+	if len(diags) == 0 {
+		return fmt.Sprintf("%s: no issues", ran)
+	}
+	files := 0
+	for i := range diags {
+		if i == 0 || diags[i].File != diags[i-1].File {
+			files++
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %d issues in %d files\n", ran, len(diags), files)
+	for i, d := range diags {
+		if i == maxDiagnostics {
+			fmt.Fprintf(&sb, "\n[%d more issues not shown]\n", len(diags)-i)
+			break
+		}
+		if i == 0 || d.File != diags[i-1].File {
+			fmt.Fprintf(&sb, "\n%s\n", d.File)
+		}
+		fmt.Fprintf(&sb, "  %d:%d %s", d.Line, d.Column, d.Severity)
+		if d.Code != "" {
+			fmt.Fprintf(&sb, " [%s]", d.Code)
+		}
+		fmt.Fprintf(&sb, " %s (%s)\n", d.Message, d.Linter)
+	}
+	return sb.String()
+}
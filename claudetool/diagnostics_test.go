@@ -0,0 +1,107 @@
+package claudetool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGoVet(t *testing.T) {
+	out := `# ex
+vet: ./a.go:7:2: unreachable code
+./sub/b.go:3:9: undefined: x
+`
+	diags := parseGoVet([]byte(out))
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %+v", len(diags), diags)
+	}
+	if d := diags[0]; d.File != "a.go" || d.Line != 7 || d.Column != 2 || d.Message != "unreachable code" {
+		t.Errorf("first = %+v", d)
+	}
+	if d := diags[1]; d.File != "sub/b.go" || d.Message != "undefined: x" {
+		t.Errorf("second = %+v", d)
+	}
+}
+
+func TestParseTSC(t *testing.T) {
+	out := "src/a.ts(3,5): error TS2322: Type 'string' is not assignable to type 'number'.\nFound 1 error.\n"
+	diags := parseTSC([]byte(out))
+	if len(diags) != 1 {
+		t.Fatalf("got %+v", diags)
+	}
+	if d := diags[0]; d.File != "src/a.ts" || d.Line != 3 || d.Code != "TS2322" || d.Severity != "error" {
+		t.Errorf("got %+v", d)
+	}
+}
+
+func TestParseESLintJSON(t *testing.T) {
+	out := `[{"filePath":"/p/a.js","messages":[{"ruleId":"no-unused-vars","severity":1,"message":"'x' is unused","line":2,"column":7},{"ruleId":"semi","severity":2,"message":"Missing semicolon","line":4,"column":1}]},{"filePath":"/p/b.js","messages":[]}]`
+	diags, err := parseESLintJSON([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 2 || diags[0].Severity != "warning" || diags[1].Severity != "error" || diags[1].Code != "semi" {
+		t.Errorf("got %+v", diags)
+	}
+}
+
+func TestParseRuffJSON(t *testing.T) {
+	out := `[{"filename":"/p/m.py","code":"F401","message":"'os' imported but unused","location":{"row":1,"column":8}}]`
+	diags, err := parseRuffJSON([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Code != "F401" || diags[0].Line != 1 || diags[0].Column != 8 {
+		t.Errorf("got %+v", diags)
+	}
+}
+
+func TestFormatDiagnostics(t *testing.T) {
+	got := formatDiagnostics([]string{"go vet"}, []Diagnostic{
+		{Linter: "go vet", File: "a.go", Line: 1, Column: 2, Severity: "error", Message: "m1"},
+		{Linter: "go vet", File: "a.go", Line: 5, Column: 1, Severity: "error", Message: "m2"},
+		{Linter: "go vet", File: "b.go", Line: 3, Column: 4, Severity: "error", Message: "m3"},
+	})
+	want := `go vet: 3 issues in 2 files
+
+a.go
+  1:2 error m1 (go vet)
+  5:1 error m2 (go vet)
+
+b.go
+  3:4 error m3 (go vet)
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := formatDiagnostics([]string{"ruff"}, nil); got != "ruff: no issues" {
+		t.Errorf("empty = %q", got)
+	}
+}
+
+func TestDiagnosticsGoVet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go vet")
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module ex\n\ngo 1.21\n",
+		"ex.go":  "package ex\n\nimport \"fmt\"\n\nfunc F() { fmt.Printf(\"%d\", \"s\") }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := &DiagnosticsTool{WorkingDir: NewMutableWorkingDir(dir)}
+	out := tool.run(context.Background(), diagnosticsInput{Linters: []string{"go vet"}})
+	if out.Error != nil {
+		t.Fatalf("unexpected error: %v", out.Error)
+	}
+	text := out.LLMContent[0].Text
+	if !strings.Contains(text, "1 issues in 1 files") || !strings.Contains(text, "ex.go\n  5:") {
+		t.Errorf("unexpected output:\n%s", text)
+	}
+}
@@ -8,13 +8,20 @@
 package editbuf
 
 import (
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"sort"
+	"sync"
 )
 
 // A Buffer is a queue of edits to apply to a given byte slice.
 type Buffer struct {
 	old []byte
+	sum [sha256.Size]byte // of old, to detect changes to its source
 	q   edits
 }
 
@@ -41,7 +48,7 @@ func (x edits) Less(i, j int) bool {
 // The returned buffer maintains a reference to the data, so the caller must ensure
 // the data is not modified until after the Buffer is done being used.
 func NewBuffer(old []byte) *Buffer {
-	return &Buffer{old: old}
+	return &Buffer{old: old, sum: sha256.Sum256(old)}
 }
 
 // Insert inserts the new string at old[pos:pos].
@@ -90,3 +97,182 @@ func (b *Buffer) Bytes() ([]byte, error) {
 	new = append(new, b.old[offset:]...)
 	return new, nil
 }
+
+// A ConflictError reports that a file changed on disk after it was read
+// into a Buffer, so the Buffer's edits were not written.
+type ConflictError struct {
+	Path    string
+	Current []byte // the file's contents now; nil if it no longer exists
+}
+
+func (e *ConflictError) Error() string {
+	if e.Current == nil {
+		return fmt.Sprintf("%s was deleted after it was read", e.Path)
+	}
+	return fmt.Sprintf("%s changed on disk after it was read", e.Path)
+}
+
+// writeMu makes a transaction's checks and writes atomic with respect to
+// other transactions, so that of two buffers read from the same contents,
+// only the first is written and the second gets a ConflictError.
+var writeMu sync.Mutex
+
+// WriteFile writes the buffer's data with its edits applied to the file
+// named path, creating it with perm if needed, and returns the data written.
+// It is a Tx of one buffer.
+func (b *Buffer) WriteFile(path string, perm fs.FileMode) ([]byte, error) {
+	var tx Tx
+	tx.Add(path, b, perm)
+	data, err := tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return data[0], nil
+}
+
+// A Tx writes the edits of several buffers to their files together:
+// if any file can't be written, none are.
+type Tx struct {
+	files []txFile
+}
+
+type txFile struct {
+	path    string // as added
+	target  string // path with symbolic links resolved
+	buf     *Buffer
+	perm    fs.FileMode
+	existed bool   // whether the file existed when checked
+	temp    string // the new contents, until renamed into place
+}
+
+// Add adds b, read from the file named path, to the transaction.
+// If the file doesn't exist, it is created with mode perm.
+func (tx *Tx) Add(path string, b *Buffer, perm fs.FileMode) {
+	tx.files = append(tx.files, txFile{path: path, buf: b, perm: perm})
+}
+
+// Commit writes each buffer's data with its edits applied to its file and
+// returns the data written, in the order the buffers were added.
+//
+// Every file must still hold the data its buffer was created with; a missing
+// file counts as empty. If one doesn't, Commit returns a *ConflictError.
+//
+// The new contents are written to temporary files beside the originals,
+// synced, and then renamed into place, so each file is always either old
+// or new. If a rename fails, the files already renamed are restored.
+// Symbolic links are followed, so the files they point to are written.
+func (tx *Tx) Commit() ([][]byte, error) {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	defer func() {
+		for _, f := range tx.files {
+			if f.temp != "" {
+				os.Remove(f.temp)
+			}
+		}
+	}()
+
+	data := make([][]byte, len(tx.files))
+	seen := make(map[string]bool)
+	for i := range tx.files {
+		f := &tx.files[i]
+		f.target = f.path
+		if resolved, err := filepath.EvalSymlinks(f.path); err == nil {
+			f.target = resolved
+		}
+		if seen[f.target] {
+			return nil, fmt.Errorf("%s: added to the transaction twice", f.path)
+		}
+		seen[f.target] = true
+		var err error
+		if data[i], err = f.buf.Bytes(); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.path, err)
+		}
+		if err := f.check(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range tx.files {
+		f := &tx.files[i]
+		var err error
+		if f.temp, err = writeTemp(f.target, data[i], f.perm); err != nil {
+			return nil, err
+		}
+	}
+	for i := range tx.files {
+		f := &tx.files[i]
+		if err := os.Rename(f.temp, f.target); err != nil {
+			return nil, errors.Join(err, tx.restore(i))
+		}
+		f.temp = ""
+		syncDir(filepath.Dir(f.target))
+	}
+	return data, nil
+}
+
+// check returns a *ConflictError if f's file no longer holds its buffer's original data.
+func (f *txFile) check() error {
+	cur, err := os.ReadFile(f.target)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if len(f.buf.old) > 0 {
+			return &ConflictError{Path: f.path}
+		}
+	case err != nil:
+		return err
+	case sha256.Sum256(cur) != f.buf.sum:
+		return &ConflictError{Path: f.path, Current: cur}
+	default:
+		f.existed = true
+	}
+	return nil
+}
+
+// restore puts back the original contents of the first n files,
+// which have been renamed into place.
+func (tx *Tx) restore(n int) error {
+	var errs []error
+	for _, f := range tx.files[:n] {
+		if !f.existed {
+			errs = append(errs, os.Remove(f.target))
+			continue
+		}
+		temp, err := writeTemp(f.target, f.buf.old, f.perm)
+		if err == nil {
+			err = os.Rename(temp, f.target)
+		}
+		if err != nil {
+			os.Remove(temp)
+			errs = append(errs, fmt.Errorf("restoring %s: %w", f.path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeTemp writes data to a new temporary file beside path and syncs it.
+// The file gets the mode of path if it exists, and perm otherwise.
+func writeTemp(path string, data []byte, perm fs.FileMode) (string, error) {
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	err = errors.Join(err, f.Chmod(perm), f.Sync(), f.Close())
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// syncDir syncs a directory so that renames in it are durable.
+// Not every file system supports this, so errors are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,122 @@
+package editbuf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileConflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(path, []byte("hello world\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := NewBuffer(orig), NewBuffer(orig)
+	first.Replace(0, 5, "howdy")
+	second.Replace(6, 11, "there")
+	got, err := first.WriteFile(path, 0o600)
+	if err != nil {
+		t.Fatalf("first WriteFile: %v", err)
+	}
+	if string(got) != "howdy world\n" {
+		t.Errorf("first WriteFile wrote %q", got)
+	}
+
+	_, err = second.WriteFile(path, 0o600)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("second WriteFile: got %v, want a ConflictError", err)
+	}
+	if string(conflict.Current) != "howdy world\n" {
+		t.Errorf("conflict.Current = %q", conflict.Current)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "howdy world\n" {
+		t.Errorf("file clobbered: %q", data)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewBuffer([]byte("howdy world\n")).WriteFile(path, 0o600)
+	if !errors.As(err, &conflict) || conflict.Current != nil {
+		t.Errorf("WriteFile of deleted file: got %v, want a ConflictError with no contents", err)
+	}
+
+	// A file that didn't exist may be created.
+	buf := NewBuffer(nil)
+	buf.Insert(0, "new\n")
+	if _, err := buf.WriteFile(path, 0o600); err != nil {
+		t.Errorf("WriteFile of new file: %v", err)
+	}
+}
+
+func TestTxCommit(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(a, []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("b\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink("a.txt", link); err != nil {
+		t.Fatal(err)
+	}
+
+	// A conflict in one file leaves every file alone.
+	bufA, bufB := NewBuffer([]byte("a\n")), NewBuffer([]byte("stale\n"))
+	bufA.Insert(0, "1")
+	bufB.Insert(0, "2")
+	var tx Tx
+	tx.Add(link, bufA, 0o600)
+	tx.Add(b, bufB, 0o600)
+	var conflict *ConflictError
+	if _, err := tx.Commit(); !errors.As(err, &conflict) || conflict.Path != b {
+		t.Fatalf("Commit() error = %v, want a conflict in %s", err, b)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "a\n" {
+		t.Errorf("a.txt = %q after failed commit", data)
+	}
+
+	bufB = NewBuffer([]byte("b\n"))
+	bufB.Insert(0, "2")
+	bufC := NewBuffer(nil)
+	bufC.Insert(0, "c\n")
+	tx = Tx{}
+	tx.Add(link, bufA, 0o600)
+	tx.Add(b, bufB, 0o600)
+	tx.Add(filepath.Join(dir, "c.txt"), bufC, 0o640)
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	for name, want := range map[string]string{"a.txt": "1a\n", "b.txt": "2b\n", "c.txt": "c\n"} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link.txt is no longer a symbolic link")
+	}
+	for name, want := range map[string]os.FileMode{"a.txt": 0o644, "c.txt": 0o640} {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err != nil || fi.Mode().Perm() != want {
+			t.Errorf("%s mode = %v, want %v", name, fi.Mode().Perm(), want)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 4 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	tx = Tx{}
+	tx.Add(a, NewBuffer([]byte("1a\n")), 0o600)
+	tx.Add(link, NewBuffer([]byte("1a\n")), 0o600)
+	if _, err := tx.Commit(); err == nil {
+		t.Error("Commit() of one file added twice succeeded")
+	}
+}
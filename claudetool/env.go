@@ -2,6 +2,7 @@ package claudetool
 
 import (
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
 
@@ -31,6 +32,9 @@ type ShelleyEnv struct {
 	// SHELLEY_PORT and SHELLEY_URL (http://localhost:<port>) are exported so
 	// scripts on the VM can reach the shelley API without the auth proxy.
 	Port int
+	// Vars are more variables to set, such as a project's env settings.
+	// They cannot override the SHELLEY_* variables.
+	Vars map[string]string
 }
 
 // shelleyEnvKeys lists every environment variable name ShelleyEnv may set. We
@@ -53,6 +57,11 @@ var shelleyEnvKeys = []string{
 // "is it set?" tests.
 func (e ShelleyEnv) Environ(cwd string) []string {
 	var out []string
+	for _, k := range slices.Sorted(maps.Keys(e.Vars)) {
+		if !slices.Contains(shelleyEnvKeys, k) {
+			out = append(out, k+"="+e.Vars[k])
+		}
+	}
 	add := func(k, v string) {
 		if v != "" {
 			out = append(out, k+"="+v)
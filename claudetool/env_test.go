@@ -36,6 +36,13 @@ func TestShelleyEnvOmitsEmpty(t *testing.T) {
 	}
 }
 
+func TestShelleyEnvVars(t *testing.T) {
+	got := ShelleyEnv{ConversationID: "x", Vars: map[string]string{"B": "2", "A": "1", "SHELLEY_CONVERSATION_ID": "y"}}.Environ("")
+	if !slices.Equal(got, []string{"A=1", "B=2", "SHELLEY_CONVERSATION_ID=x"}) {
+		t.Fatalf("Environ() = %v", got)
+	}
+}
+
 func TestShelleyEnvCwdAndGitRoot(t *testing.T) {
 	// Use the repo itself as a known git worktree.
 	root, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
@@ -0,0 +1,78 @@
+package claudetool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"shelley.exe.dev/llm"
+)
+
+// ConversationMessenger delivers messages from one conversation into another.
+type ConversationMessenger interface {
+	// SendToConversation posts message into the conversation identified by
+	// to (an id or slug) on behalf of fromID. The message is queued behind
+	// any turn already in progress. It returns the target conversation's id.
+	SendToConversation(ctx context.Context, fromID, to, message string) (string, error)
+}
+
+// HandoffTool posts a message into another existing conversation, e.g. so a
+// triage conversation can dispatch work to per-component conversations.
+type HandoffTool struct {
+	Messenger      ConversationMessenger
+	ConversationID string
+}
+
+const (
+	handoffName        = "handoff"
+	handoffDescription = `Post a message into another existing conversation, identified by its id or slug.
+
+The message is delivered as a user message, marked as coming from this conversation, and is processed after that conversation's current turn (if any). It does not wait for a reply.
+
+Use this to hand work off to conversations that own a component or task. Include everything the recipient needs; it cannot see this conversation.
+`
+	handoffInputSchema = `{
+  "type": "object",
+  "required": ["conversation", "message"],
+  "properties": {
+    "conversation": {
+      "type": "string",
+      "description": "Id or slug of the conversation to post to"
+    },
+    "message": {
+      "type": "string",
+      "description": "The message to deliver"
+    }
+  }
+}`
+)
+
+type handoffInput struct {
+	Conversation string `json:"conversation"`
+	Message      string `json:"message"`
+}
+
+// Tool returns an llm.Tool for posting into other conversations.
+func (h *HandoffTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        handoffName,
+		Description: handoffDescription,
+		InputSchema: llm.MustSchema(handoffInputSchema),
+		Run:         llm.RunJSON(h.run),
+	}
+}
+
+func (h *HandoffTool) run(ctx context.Context, req handoffInput) llm.ToolOut {
+	to := strings.TrimSpace(req.Conversation)
+	if to == "" {
+		return llm.ErrorfToolOut("conversation is required")
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		return llm.ErrorfToolOut("message is required")
+	}
+	id, err := h.Messenger.SendToConversation(ctx, h.ConversationID, to, req.Message)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("message delivered to conversation %s", id))}
+}
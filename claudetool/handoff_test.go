@@ -0,0 +1,53 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type fakeMessenger struct {
+	from, to, message string
+}
+
+func (f *fakeMessenger) SendToConversation(ctx context.Context, fromID, to, message string) (string, error) {
+	if to == "missing" {
+		return "", errors.New("conversation not found")
+	}
+	f.from, f.to, f.message = fromID, to, message
+	return "c-" + to, nil
+}
+
+func TestHandoffTool(t *testing.T) {
+	m := &fakeMessenger{}
+	tool := (&HandoffTool{Messenger: m, ConversationID: "triage"}).Tool()
+	run := func(input string) (string, error) {
+		out := tool.Run(context.Background(), json.RawMessage(input))
+		if out.Error != nil {
+			return "", out.Error
+		}
+		return out.LLMContent[0].Text, nil
+	}
+
+	text, err := run(`{"conversation":" frontend ","message":"fix the button"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "message delivered to conversation c-frontend" {
+		t.Fatalf("unexpected output %q", text)
+	}
+	if m.from != "triage" || m.to != "frontend" || m.message != "fix the button" {
+		t.Fatalf("unexpected send: %+v", m)
+	}
+
+	for _, bad := range []string{
+		`{"conversation":"","message":"x"}`,
+		`{"conversation":"frontend","message":" "}`,
+		`{"conversation":"missing","message":"x"}`,
+	} {
+		if _, err := run(bad); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
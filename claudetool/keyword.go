@@ -6,10 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"shelley.exe.dev/claudetool/onstart"
 	"shelley.exe.dev/llm"
 )
 
@@ -42,6 +45,7 @@ func (k *KeywordTool) Tool() *llm.Tool {
 		Description: keywordDescription,
 		InputSchema: llm.MustSchema(keywordInputSchema),
 		Run:         llm.RunJSON(k.keywordRun),
+		ReadOnly:    true,
 	}
 }
 
@@ -232,6 +236,9 @@ func ripgrep(ctx context.Context, wd string, terms []string) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	args := []string{"-C", "10", "-i", "--line-number", "--with-filename"}
+	if _, err := os.Stat(filepath.Join(wd, onstart.ShelleyIgnoreFile)); err == nil {
+		args = append(args, "--ignore-file", onstart.ShelleyIgnoreFile)
+	}
 	for _, term := range terms {
 		args = append(args, "-e", term)
 	}
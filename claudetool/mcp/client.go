@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"shelley.exe.dev/version"
+)
+
+// ServerConfig describes how to reach an MCP server. Exactly one of Command
+// (the stdio transport) or URL (the streamable HTTP transport) is set.
+type ServerConfig struct {
+	// Command and Args start a server that speaks MCP on stdin/stdout.
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	// Env is added to the command's environment.
+	Env map[string]string `json:"env,omitempty"`
+	// Dir is the command's working directory.
+	Dir string `json:"dir,omitempty"`
+	// URL is the endpoint of a server using the streamable HTTP transport.
+	URL string `json:"url,omitempty"`
+	// Headers are sent with every HTTP request, e.g. Authorization.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Validate reports whether the config names exactly one transport.
+func (c ServerConfig) Validate() error {
+	switch {
+	case c.Command == "" && c.URL == "":
+		return fmt.Errorf("one of command or url is required")
+	case c.Command != "" && c.URL != "":
+		return fmt.Errorf("command and url are mutually exclusive")
+	case c.Command == "" && (len(c.Args) > 0 || len(c.Env) > 0 || c.Dir != ""):
+		return fmt.Errorf("args, env, and dir require command")
+	case c.URL == "" && len(c.Headers) > 0:
+		return fmt.Errorf("headers require url")
+	}
+	return nil
+}
+
+// Client is a connection to one MCP server. It is safe for concurrent use.
+type Client struct {
+	t      transport
+	nextID atomic.Int64
+}
+
+// Connect starts or dials the server described by cfg and performs the MCP
+// initialization handshake.
+func Connect(ctx context.Context, cfg ServerConfig) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	var t transport
+	if cfg.Command != "" {
+		st, err := newStdioTransport(cfg)
+		if err != nil {
+			return nil, err
+		}
+		t = st
+	} else {
+		t = newHTTPTransport(cfg)
+	}
+	c := &Client{t: t}
+	var res initializeResult
+	err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo:      implementation{Name: "shelley", Version: version.Version},
+	}, &res)
+	if err == nil {
+		err = t.notify(ctx, &message{JSONRPC: "2.0", Method: "notifications/initialized"})
+	}
+	if err != nil {
+		t.close()
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	return c, nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := json.RawMessage(strconv.FormatInt(c.nextID.Add(1), 10))
+	resp, err := c.t.call(ctx, &message{JSONRPC: "2.0", ID: &id, Method: method, Params: p})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// ListTools returns every tool the server offers.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	var cursor string
+	for {
+		var res listToolsResult
+		if err := c.call(ctx, "tools/list", listToolsParams{Cursor: cursor}, &res); err != nil {
+			return nil, err
+		}
+		tools = append(tools, res.Tools...)
+		if res.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// CallTool runs the named tool with the given JSON arguments.
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallToolResult, error) {
+	var res CallToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: args}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Close ends the connection, stopping the server if it is a subprocess.
+func (c *Client) Close() error {
+	return c.t.close()
+}
@@ -0,0 +1,189 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestMain doubles as a stdio MCP server when re-executed by the tests.
+func TestMain(m *testing.M) {
+	if os.Getenv("MCP_TEST_SERVER") == "1" {
+		enc := json.NewEncoder(os.Stdout)
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			var req message
+			if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+				os.Exit(1)
+			}
+			if resp := fakeServer(&req); resp != nil {
+				enc.Encode(resp)
+			}
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeServer answers requests for a server with "echo" and "fail" tools.
+func fakeServer(req *message) *message {
+	if req.ID == nil {
+		return nil
+	}
+	resp := &message{JSONRPC: "2.0", ID: req.ID}
+	var result any
+	switch req.Method {
+	case "initialize":
+		result = initializeResult{ProtocolVersion: ProtocolVersion, ServerInfo: implementation{Name: "fake", Version: "1"}}
+	case "tools/list":
+		var p listToolsParams
+		json.Unmarshal(req.Params, &p)
+		// Page the listing to exercise cursors.
+		if p.Cursor == "" {
+			result = listToolsResult{Tools: []Tool{{Name: "echo", Description: "Echo text.", InputSchema: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}}}`)}}, NextCursor: "2"}
+		} else {
+			result = listToolsResult{Tools: []Tool{{Name: "fail"}}}
+		}
+	case "tools/call":
+		var p struct {
+			Name      string `json:"name"`
+			Arguments struct {
+				Text string `json:"text"`
+			} `json:"arguments"`
+		}
+		json.Unmarshal(req.Params, &p)
+		switch {
+		case p.Name == "fail":
+			result = CallToolResult{Content: []Content{{Type: "text", Text: "it broke"}}, IsError: true}
+		case p.Arguments.Text == "structured":
+			result = CallToolResult{Content: []Content{}, StructuredContent: json.RawMessage(`{"n":1}`)}
+		default:
+			result = CallToolResult{Content: []Content{
+				{Type: "text", Text: p.Arguments.Text},
+				{Type: "image", Data: testPNG(), MimeType: "image/png"},
+				{Type: "audio", Data: "AAAA", MimeType: "audio/wav"},
+			}}
+		}
+	default:
+		resp.Error = &rpcError{Code: codeMethodNotFound, Message: "method not found"}
+		return resp
+	}
+	resp.Result, _ = json.Marshal(result)
+	return resp
+}
+
+// testPNG returns a base64-encoded 2x2 PNG.
+func testPNG() string {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestTools(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req message
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Method != "initialize" && r.Header.Get("Mcp-Session-Id") != "s1" {
+			http.Error(w, "missing session", http.StatusBadRequest)
+			return
+		}
+		resp := fakeServer(&req)
+		switch {
+		case resp == nil:
+			w.WriteHeader(http.StatusAccepted)
+		case req.Method == "tools/list":
+			// Reply over SSE, after an unrelated notification.
+			w.Header().Set("Content-Type", "text/event-stream")
+			data, _ := json.Marshal(resp)
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\nevent: message\ndata: %s\n\n", data)
+		default:
+			w.Header().Set("Mcp-Session-Id", "s1")
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	tools, closeAll, err := Tools(ctx, map[string]ServerConfig{
+		"remote": {URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer secret"}},
+		"local":  {Command: os.Args[0], Env: map[string]string{"MCP_TEST_SERVER": "1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAll()
+
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	if got, want := strings.Join(names, " "), "mcp__local__echo mcp__local__fail mcp__remote__echo mcp__remote__fail"; got != want {
+		t.Fatalf("tools = %s, want %s", got, want)
+	}
+	if string(tools[1].InputSchema) != `{"type":"object"}` {
+		t.Errorf("missing schema should default to an object, got %s", tools[1].InputSchema)
+	}
+
+	for _, i := range []int{0, 2} {
+		out := tools[i].Run(ctx, json.RawMessage(`{"text":"hello"}`))
+		if out.Error != nil {
+			t.Fatalf("%s: %v", tools[i].Name, out.Error)
+		}
+		if c := out.LLMContent; len(c) != 3 || c[0].Text != "hello" || c[1].MediaType != "image/png" || c[1].DisplayWidth != 2 || c[2].Text != "[audio content omitted]" {
+			t.Errorf("%s: got %+v", tools[i].Name, c)
+		}
+		out = tools[i].Run(ctx, json.RawMessage(`{"text":"structured"}`))
+		if out.Error != nil || out.LLMContent[0].Text != `{"n":1}` {
+			t.Errorf("%s: structured content not used: %+v", tools[i].Name, out)
+		}
+		out = tools[i+1].Run(ctx, json.RawMessage(`{}`))
+		if out.Error == nil || out.Error.Error() != "it broke" {
+			t.Errorf("%s: expected tool error, got %v", tools[i+1].Name, out.Error)
+		}
+	}
+}
+
+func TestToolsConnectError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	_, _, err := Tools(context.Background(), map[string]ServerConfig{"remote": {URL: srv.URL}})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401 error, got %v", err)
+	}
+}
+
+func TestServerConfigValidate(t *testing.T) {
+	for _, bad := range []ServerConfig{
+		{},
+		{Command: "x", URL: "http://x"},
+		{URL: "http://x", Args: []string{"a"}},
+		{Command: "x", Headers: map[string]string{"a": "b"}},
+	} {
+		if bad.Validate() == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
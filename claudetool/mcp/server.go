@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/version"
+)
+
+type cancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+}
+
+// Serve offers tools over the stdio transport: it answers newline-delimited
+// JSON-RPC requests read from r, writing responses to w, until r reaches EOF.
+// Tool calls run concurrently and can be cancelled by the client.
+func Serve(ctx context.Context, r io.Reader, w io.Writer, tools []*llm.Tool) error {
+	byName := make(map[string]*llm.Tool, len(tools))
+	for _, t := range tools {
+		byName[t.Name] = t
+	}
+
+	var wmu sync.Mutex
+	write := func(m *message) {
+		data, _ := json.Marshal(m) // only raw JSON and plain fields
+		wmu.Lock()
+		defer wmu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+	reply := func(id *json.RawMessage, result any) {
+		data, err := json.Marshal(result)
+		if err != nil {
+			write(&message{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: codeInternalError, Message: err.Error()}})
+			return
+		}
+		write(&message{JSONRPC: "2.0", ID: id, Result: data})
+	}
+	replyError := func(id *json.RawMessage, code int, msg string) {
+		write(&message{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: msg}})
+	}
+
+	// In-flight calls finish before Serve returns, so a client that writes
+	// its requests and closes stdin still gets every response.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	var mu sync.Mutex
+	running := make(map[string]context.CancelFunc) // by request id
+
+	dec := json.NewDecoder(r)
+	for {
+		var m message
+		if err := dec.Decode(&m); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if m.ID == nil {
+			if m.Method == "notifications/cancelled" {
+				var p cancelledParams
+				if json.Unmarshal(m.Params, &p) == nil {
+					mu.Lock()
+					if c := running[string(p.RequestID)]; c != nil {
+						c()
+					}
+					mu.Unlock()
+				}
+			}
+			continue
+		}
+		if m.Method == "" {
+			// A response; we never send requests.
+			continue
+		}
+		switch m.Method {
+		case "initialize":
+			reply(m.ID, initializeResult{
+				ProtocolVersion: ProtocolVersion,
+				Capabilities:    map[string]any{"tools": map[string]any{}},
+				ServerInfo:      implementation{Name: "shelley", Version: version.Version},
+			})
+		case "ping":
+			reply(m.ID, struct{}{})
+		case "tools/list":
+			res := listToolsResult{Tools: make([]Tool, 0, len(tools))}
+			for _, t := range tools {
+				res.Tools = append(res.Tools, Tool{Name: t.Name, Description: t.Description, InputSchema: t.InputSchema})
+			}
+			reply(m.ID, res)
+		case "tools/call":
+			var p callToolParams
+			if err := json.Unmarshal(m.Params, &p); err != nil {
+				replyError(m.ID, codeInvalidParams, err.Error())
+				continue
+			}
+			t := byName[p.Name]
+			if t == nil {
+				replyError(m.ID, codeInvalidParams, "unknown tool: "+p.Name)
+				continue
+			}
+			if len(p.Arguments) == 0 {
+				p.Arguments = json.RawMessage("{}")
+			}
+			callCtx, callCancel := context.WithCancel(ctx)
+			key := string(*m.ID)
+			mu.Lock()
+			running[key] = callCancel
+			mu.Unlock()
+			wg.Add(1)
+			go func(id *json.RawMessage) {
+				defer wg.Done()
+				out := t.Run(callCtx, p.Arguments)
+				mu.Lock()
+				delete(running, key)
+				mu.Unlock()
+				callCancel()
+				reply(id, toolResult(out))
+			}(m.ID)
+		default:
+			replyError(m.ID, codeMethodNotFound, "method not found: "+m.Method)
+		}
+	}
+}
+
+// toolResult converts a tool's output to an MCP tool result, keeping every
+// text and image block.
+func toolResult(out llm.ToolOut) CallToolResult {
+	if out.Error != nil {
+		return CallToolResult{Content: []Content{{Type: "text", Text: out.Error.Error()}}, IsError: true}
+	}
+	res := CallToolResult{Content: []Content{}}
+	for _, c := range out.LLMContent {
+		switch {
+		case c.Type != llm.ContentTypeText:
+		case c.MediaType != "":
+			res.Content = append(res.Content, Content{Type: "image", Data: c.Data, MimeType: c.MediaType})
+		default:
+			res.Content = append(res.Content, Content{Type: "text", Text: c.Text})
+		}
+	}
+	return res
+}
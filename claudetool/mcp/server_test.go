@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"shelley.exe.dev/llm"
+)
+
+func TestServe(t *testing.T) {
+	started := make(chan struct{})
+	tools := []*llm.Tool{
+		{
+			Name:        "echo",
+			Description: "Echo text.",
+			InputSchema: llm.MustSchema(`{"type":"object","properties":{"text":{"type":"string"}}}`),
+			Run: func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+				var in struct{ Text string }
+				json.Unmarshal(input, &in)
+				return llm.ToolOut{LLMContent: []llm.Content{
+					llm.StringContent(in.Text),
+					{Type: llm.ContentTypeText, MediaType: "image/png", Data: "iVBORw0KGgo="},
+				}}
+			},
+		},
+		{
+			Name:        "block",
+			InputSchema: llm.MustSchema(`{"type":"object","properties":{}}`),
+			Run: func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+				close(started)
+				<-ctx.Done()
+				return llm.ErrorToolOut(ctx.Err())
+			},
+		},
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(context.Background(), inR, outW, tools)
+		outW.Close()
+	}()
+	enc := json.NewEncoder(inW)
+	dec := json.NewDecoder(outR)
+	send := func(s string) {
+		if err := enc.Encode(json.RawMessage(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recv := func() message {
+		var m message
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`)
+	var init initializeResult
+	json.Unmarshal(recv().Result, &init)
+	if init.ServerInfo.Name != "shelley" || init.Capabilities["tools"] == nil {
+		t.Fatalf("unexpected initialize result: %+v", init)
+	}
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	send(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	var list listToolsResult
+	json.Unmarshal(recv().Result, &list)
+	if len(list.Tools) != 2 || list.Tools[0].Name != "echo" || list.Tools[0].Description != "Echo text." {
+		t.Fatalf("unexpected tools: %+v", list.Tools)
+	}
+
+	send(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`)
+	var res CallToolResult
+	json.Unmarshal(recv().Result, &res)
+	if res.IsError || len(res.Content) != 2 || res.Content[0].Text != "hi" ||
+		res.Content[1] != (Content{Type: "image", Data: "iVBORw0KGgo=", MimeType: "image/png"}) {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	send(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"nope"}}`)
+	if m := recv(); m.Error == nil || m.Error.Code != codeInvalidParams {
+		t.Fatalf("expected invalid params error, got %+v", m)
+	}
+
+	// A blocked call ends when the client cancels it.
+	send(`{"jsonrpc":"2.0","id":"b","method":"tools/call","params":{"name":"block"}}`)
+	<-started
+	send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"b"}}`)
+	m := recv()
+	json.Unmarshal(m.Result, &res)
+	if string(*m.ID) != `"b"` || !res.IsError || res.Content[0].Text != "context canceled" {
+		t.Fatalf("unexpected cancelled result: %s %+v", *m.ID, res)
+	}
+
+	inW.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,157 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/llm/imageutil"
+)
+
+// toolNamePattern matches the tool names LLM providers accept.
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ToolName is the name under which a server's tool is offered to the model.
+// The prefix keeps tools from different servers, and Shelley's own tools,
+// from colliding.
+func ToolName(server, tool string) string {
+	return "mcp__" + server + "__" + tool
+}
+
+// Tools connects to every configured server, keyed by name, and returns
+// their tools. The returned function closes all connections.
+func Tools(ctx context.Context, servers map[string]ServerConfig) ([]*llm.Tool, func(), error) {
+	var clients []*Client
+	closeAll := func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}
+	var tools []*llm.Tool
+	for _, name := range slices.Sorted(maps.Keys(servers)) {
+		c, err := Connect(ctx, servers[name])
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("mcp server %s: %w", name, err)
+		}
+		clients = append(clients, c)
+		remote, err := c.ListTools(ctx)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("mcp server %s: list tools: %w", name, err)
+		}
+		for _, rt := range remote {
+			t := &llm.Tool{
+				Name:        ToolName(name, rt.Name),
+				Description: rt.Description,
+				InputSchema: rt.InputSchema,
+				Run:         runTool(c, rt.Name),
+			}
+			if !toolNamePattern.MatchString(t.Name) {
+				closeAll()
+				return nil, nil, fmt.Errorf("mcp server %s: tool name %q is not a valid tool name", name, t.Name)
+			}
+			if len(t.InputSchema) == 0 || string(t.InputSchema) == "null" {
+				t.InputSchema = json.RawMessage(`{"type":"object"}`)
+			}
+			tools = append(tools, t)
+		}
+	}
+	return tools, closeAll, nil
+}
+
+func runTool(c *Client, name string) func(context.Context, json.RawMessage) llm.ToolOut {
+	return func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+		res, err := c.CallTool(ctx, name, input)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		content, err := resultContent(ctx, res)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		if res.IsError {
+			var texts []string
+			for _, c := range content {
+				if c.MediaType == "" {
+					texts = append(texts, c.Text)
+				}
+			}
+			return llm.ErrorToolOut(errors.New(strings.Join(texts, "\n")))
+		}
+		return llm.ToolOut{LLMContent: content}
+	}
+}
+
+// resultContent converts a tool result to content for the model. Images
+// are fitted to the model's limits; audio and binary resources, which
+// models can't take as tool output, are noted as omitted. Structured
+// content is used only when the server sent no content blocks, since
+// servers that return it are expected to repeat it as text.
+func resultContent(ctx context.Context, res *CallToolResult) ([]llm.Content, error) {
+	var out []llm.Content
+	for _, c := range res.Content {
+		switch c.Type {
+		case "text":
+			out = append(out, llm.StringContent(c.Text))
+		case "image":
+			img, err := imageContent(ctx, c)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, img)
+		case "resource":
+			if c.Resource != nil && c.Resource.Blob == "" {
+				out = append(out, llm.StringContent(fmt.Sprintf("[resource %s]\n%s", c.Resource.URI, c.Resource.Text)))
+			} else if c.Resource != nil {
+				out = append(out, llm.StringContent(fmt.Sprintf("[resource %s: %s content omitted]", c.Resource.URI, c.Resource.MimeType)))
+			}
+		case "resource_link":
+			out = append(out, llm.StringContent(fmt.Sprintf("[resource link %s: %s]", c.Name, c.URI)))
+		default:
+			out = append(out, llm.StringContent(fmt.Sprintf("[%s content omitted]", c.Type)))
+		}
+	}
+	if len(out) == 0 && len(res.StructuredContent) > 0 {
+		out = append(out, llm.StringContent(string(res.StructuredContent)))
+	}
+	if len(out) == 0 {
+		out = append(out, llm.StringContent("(no output)"))
+	}
+	return out, nil
+}
+
+// imageContent prepares an image block for the conversation's model, or
+// describes it if the model can't take images.
+func imageContent(ctx context.Context, c Content) (llm.Content, error) {
+	svc := llm.ServiceFromContext(ctx)
+	if svc != nil && !svc.SupportsImages() {
+		return llm.StringContent(fmt.Sprintf("[%s image omitted: model does not accept images]", c.MimeType)), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(c.Data)
+	if err != nil {
+		return llm.Content{}, fmt.Errorf("decode image: %w", err)
+	}
+	var maxDimension, maxBytes int
+	if svc != nil {
+		maxDimension, maxBytes = svc.MaxImageDimension(), svc.MaxImageBytes()
+	}
+	prepared, err := imageutil.Prepare(data, "tool result", maxDimension, maxBytes)
+	if err != nil {
+		return llm.Content{}, err
+	}
+	return llm.Content{
+		Type:          llm.ContentTypeText,
+		MediaType:     prepared.MediaType,
+		Data:          base64.StdEncoding.EncodeToString(prepared.Data),
+		DisplayWidth:  prepared.Width,
+		DisplayHeight: prepared.Height,
+	}, nil
+}
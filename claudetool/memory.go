@@ -0,0 +1,113 @@
+package claudetool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"shelley.exe.dev/llm"
+)
+
+// ProjectMemoryFile is where a repository keeps the learnings recorded by
+// the remember tool, relative to its root. New conversations in the
+// repository get it in their system prompt.
+const ProjectMemoryFile = ".shelley/memory.md"
+
+// ProjectMemoryMaxBytes caps a project memory file, since all of it is
+// sent with every request.
+const ProjectMemoryMaxBytes = 8 * 1024
+
+// RememberTool appends learnings about the working directory's repository
+// to its ProjectMemoryFile.
+type RememberTool struct {
+	WorkingDir *MutableWorkingDir
+}
+
+const (
+	rememberName        = "remember"
+	rememberDescription = `Record a durable learning about this repository for future conversations in it, which get the repository's memory file (` + ProjectMemoryFile + `) in their system prompt.
+
+Record what a future conversation would otherwise have to rediscover: build and test quirks, commands that work, required environment, non-obvious conventions, pitfalls. Don't record task progress or anything already in the guidance files.
+
+Each learning is one short, self-contained line. The memory file is limited to %s; when it's full, edit it to merge or drop stale entries.
+`
+	rememberInputSchema = `{
+  "type": "object",
+  "required": ["learning"],
+  "properties": {
+    "learning": {
+      "type": "string",
+      "description": "The learning, e.g. \"Run tests with make test; go test ./... misses the generated code\""
+    }
+  }
+}`
+)
+
+type rememberInput struct {
+	Learning string `json:"learning"`
+}
+
+// Tool returns an llm.Tool for recording learnings.
+func (r *RememberTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        rememberName,
+		Description: fmt.Sprintf(rememberDescription, humanizeBytes(ProjectMemoryMaxBytes)),
+		InputSchema: llm.MustSchema(rememberInputSchema),
+		Run:         llm.RunJSON(r.run),
+	}
+}
+
+func (r *RememberTool) run(ctx context.Context, req rememberInput) llm.ToolOut {
+	learning := strings.Join(strings.Fields(req.Learning), " ")
+	if learning == "" {
+		return llm.ErrorfToolOut("learning is required")
+	}
+	root, err := FindRepoRoot(r.WorkingDir.Get())
+	if err != nil {
+		return llm.ErrorfToolOut("the working directory is not in a git repository")
+	}
+	path := filepath.Join(root, ProjectMemoryFile)
+	memory, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return llm.ErrorToolOut(err)
+	}
+	if strings.Contains(string(memory), "- "+learning+"\n") {
+		return llm.ToolOut{LLMContent: llm.TextContent("already remembered")}
+	}
+	entry := "- " + learning + "\n"
+	if len(memory) > 0 && !strings.HasSuffix(string(memory), "\n") {
+		entry = "\n" + entry
+	}
+	if size := len(memory) + len(entry); size > ProjectMemoryMaxBytes {
+		return llm.ErrorfToolOut("%s would be %s, over the %s limit; edit it to merge or drop stale entries first", path, humanizeBytes(size), humanizeBytes(ProjectMemoryMaxBytes))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	if _, err := f.WriteString(entry); err != nil {
+		f.Close()
+		return llm.ErrorToolOut(err)
+	}
+	if err := f.Close(); err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("remembered in %s", path))}
+}
+
+// ReadProjectMemory returns the memory file of the repository rooted at
+// root, capped at ProjectMemoryMaxBytes, or "" if it has none.
+func ReadProjectMemory(root string) string {
+	data, err := os.ReadFile(filepath.Join(root, ProjectMemoryFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data[:min(len(data), ProjectMemoryMaxBytes)]))
+}
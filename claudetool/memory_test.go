@@ -0,0 +1,48 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRememberTool(t *testing.T) {
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	sub := filepath.Join(repo, "pkg")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tool := (&RememberTool{WorkingDir: NewMutableWorkingDir(sub)}).Tool()
+	remember := func(learning string) string {
+		input, _ := json.Marshal(rememberInput{Learning: learning})
+		out := tool.Run(context.Background(), input)
+		if out.Error != nil {
+			return out.Error.Error()
+		}
+		return out.LLMContent[0].Text
+	}
+
+	remember("Run tests with\nmake test")
+	remember("Needs CGO_ENABLED=1")
+	if got := remember("Needs CGO_ENABLED=1"); got != "already remembered" {
+		t.Errorf("repeated learning: %q", got)
+	}
+	if got, want := ReadProjectMemory(repo), "- Run tests with make test\n- Needs CGO_ENABLED=1"; got != want {
+		t.Errorf("memory %q, want %q", got, want)
+	}
+	if got := remember(strings.Repeat("x", ProjectMemoryMaxBytes)); !strings.Contains(got, "over the") {
+		t.Errorf("oversized learning: %q", got)
+	}
+
+	tool = (&RememberTool{WorkingDir: NewMutableWorkingDir(t.TempDir())}).Tool()
+	if got := remember("anything"); !strings.Contains(got, "not in a git repository") {
+		t.Errorf("outside a repository: %q", got)
+	}
+}
@@ -0,0 +1,322 @@
+package claudetool
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"shelley.exe.dev/llm"
+)
+
+// NotebookTool reads, edits, and executes Jupyter notebooks cell by cell,
+// leaving outputs and metadata of untouched cells intact.
+type NotebookTool struct {
+	// WorkingDir is the shared mutable working directory.
+	WorkingDir *MutableWorkingDir
+	// Env is exposed to the kernel as SHELLEY_* variables.
+	Env ShelleyEnv
+	// Sandbox, if set, confines notebook execution.
+	Sandbox Sandbox
+}
+
+const (
+	notebookName        = "notebook"
+	notebookDescription = `Read, edit, and execute Jupyter notebooks (.ipynb). Use this instead of patch for notebooks: patching the raw JSON corrupts outputs and metadata.
+
+Actions:
+- read: show cells with their index, type, source, and outputs (or only "cell")
+- edit: replace the source of "cell" (and optionally its cell_type)
+- insert: insert a new cell with "source" at index "cell" (default: append)
+- delete: remove "cell"
+- execute: run the whole notebook top to bottom in a fresh kernel via jupyter nbconvert, save the outputs, and show "cell" (or all cells)
+`
+	notebookInputSchema = `{
+  "type": "object",
+  "required": ["path", "action"],
+  "properties": {
+    "path": {
+      "type": "string",
+      "description": "Notebook path, absolute or relative to the working directory"
+    },
+    "action": {
+      "type": "string",
+      "enum": ["read", "edit", "insert", "delete", "execute"]
+    },
+    "cell": {
+      "type": "integer",
+      "description": "Zero-based cell index"
+    },
+    "source": {
+      "type": "string",
+      "description": "New cell source (edit, insert)"
+    },
+    "cell_type": {
+      "type": "string",
+      "enum": ["code", "markdown", "raw"],
+      "description": "Cell type (insert defaults to code)"
+    }
+  }
+}`
+)
+
+// maxCellOutput caps the output text shown for each cell.
+const maxCellOutput = 4 * 1024
+
+type notebookInput struct {
+	Path     string `json:"path"`
+	Action   string `json:"action"`
+	Cell     *int   `json:"cell"`
+	Source   string `json:"source"`
+	CellType string `json:"cell_type"`
+}
+
+// notebook is a parsed .ipynb file. Fields Shelley doesn't interpret are
+// kept as decoded so they round-trip unchanged.
+type notebook struct {
+	fields map[string]any
+	cells  []map[string]any
+}
+
+// Tool returns an llm.Tool for working with notebooks.
+func (n *NotebookTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        notebookName,
+		Description: notebookDescription,
+		InputSchema: llm.MustSchema(notebookInputSchema),
+		Run:         llm.RunJSON(n.run),
+	}
+}
+
+func (n *NotebookTool) run(ctx context.Context, req notebookInput) llm.ToolOut {
+	path := req.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(n.WorkingDir.Get(), path)
+	}
+	if req.Action == "execute" {
+		if out, err := n.execute(ctx, path); err != nil {
+			return llm.ErrorfToolOut("%w\n%s", err, tail(out, maxCellOutput))
+		}
+	}
+	nb, err := readNotebook(path)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	if req.Cell != nil {
+		limit := len(nb.cells)
+		if req.Action == "insert" {
+			limit++ // inserting at the end
+		}
+		if *req.Cell < 0 || *req.Cell >= limit {
+			return llm.ErrorfToolOut("cell %d out of range; notebook has %d cells", *req.Cell, len(nb.cells))
+		}
+	}
+
+	switch req.Action {
+	case "read", "execute":
+		if req.Cell != nil {
+			return llm.ToolOut{LLMContent: llm.TextContent(formatCell(*req.Cell, nb.cells[*req.Cell]))}
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent(nb.String())}
+	case "edit":
+		if req.Cell == nil {
+			return llm.ErrorfToolOut("edit requires cell")
+		}
+		cell := nb.cells[*req.Cell]
+		cell["source"] = splitSource(req.Source)
+		if req.CellType != "" {
+			setCellType(cell, req.CellType)
+		}
+	case "insert":
+		i := len(nb.cells)
+		if req.Cell != nil {
+			i = *req.Cell
+		}
+		cell := map[string]any{"metadata": map[string]any{}, "source": splitSource(req.Source)}
+		setCellType(cell, cmp.Or(req.CellType, "code"))
+		nb.cells = slices.Insert(nb.cells, i, cell)
+	case "delete":
+		if req.Cell == nil {
+			return llm.ErrorfToolOut("delete requires cell")
+		}
+		nb.cells = slices.Delete(nb.cells, *req.Cell, *req.Cell+1)
+	default:
+		return llm.ErrorfToolOut("unknown action %q", req.Action)
+	}
+
+	if err := nb.write(path); err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("%s: %s done; notebook has %d cells", req.Path, req.Action, len(nb.cells)))}
+}
+
+// execute runs every cell of the notebook at path and saves the outputs.
+func (n *NotebookTool) execute(ctx context.Context, path string) (string, error) {
+	execCtx, cancel := context.WithTimeout(ctx, DefaultSlowTimeout)
+	defer cancel()
+	var out bytes.Buffer
+	args := []string{"jupyter", "nbconvert", "--to", "notebook", "--execute", "--inplace", "--allow-errors", path}
+	cmd := (&BashTool{WorkingDir: n.WorkingDir, Env: n.Env, Sandbox: n.Sandbox}).makeArgvCommand(execCtx, args, &out)
+	if err := cmd.Run(); err != nil {
+		if execCtx.Err() == context.DeadlineExceeded {
+			return out.String(), fmt.Errorf("notebook execution timed out after %s", DefaultSlowTimeout)
+		}
+		return out.String(), fmt.Errorf("notebook execution failed: %w", err)
+	}
+	return out.String(), nil
+}
+
+func readNotebook(path string) (*notebook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as written, e.g. execution counts.
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("failed to parse notebook %s: %w", path, err)
+	}
+	rawCells, _ := fields["cells"].([]any)
+	cells := make([]map[string]any, 0, len(rawCells))
+	for i, c := range rawCells {
+		cell, ok := c.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("notebook %s: cell %d is not an object", path, i)
+		}
+		cells = append(cells, cell)
+	}
+	return &notebook{fields: fields, cells: cells}, nil
+}
+
+// write saves the notebook the way Jupyter does: one-space indent, sorted
+// keys, unescaped HTML.
+func (nb *notebook) write(path string) error {
+	cells := make([]any, len(nb.cells))
+	for i, c := range nb.cells {
+		cells[i] = c
+	}
+	nb.fields["cells"] = cells
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", " ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(nb.fields); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+func (nb *notebook) String() string {
+	var sb strings.Builder
+	kernel := "unknown kernel"
+	if md, ok := nb.fields["metadata"].(map[string]any); ok {
+		if ks, ok := md["kernelspec"].(map[string]any); ok {
+			if name, ok := ks["name"].(string); ok {
+				kernel = "kernel " + name
+			}
+		}
+	}
+	fmt.Fprintf(&sb, "%d cells, %s\n", len(nb.cells), kernel)
+	for i, c := range nb.cells {
+		sb.WriteString("\n")
+		sb.WriteString(formatCell(i, c))
+	}
+	return sb.String()
+}
+
+func formatCell(i int, cell map[string]any) string {
+	var sb strings.Builder
+	cellType, _ := cell["cell_type"].(string)
+	fmt.Fprintf(&sb, "[%d] %s", i, cellType)
+	if n, ok := cell["execution_count"].(json.Number); ok {
+		fmt.Fprintf(&sb, " (execution_count %s)", n)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(joinSource(cell["source"]))
+	sb.WriteString("\n")
+	if outputs, _ := cell["outputs"].([]any); len(outputs) > 0 {
+		var out strings.Builder
+		for _, o := range outputs {
+			if o, ok := o.(map[string]any); ok {
+				out.WriteString(formatOutput(o))
+			}
+		}
+		fmt.Fprintf(&sb, "--- output ---\n%s", tail(out.String(), maxCellOutput))
+	}
+	return sb.String()
+}
+
+// formatOutput renders one output as text; rich data is named, not shown.
+func formatOutput(o map[string]any) string {
+	switch o["output_type"] {
+	case "stream":
+		return joinSource(o["text"])
+	case "error":
+		return fmt.Sprintf("%v: %v\n", o["ename"], o["evalue"])
+	case "execute_result", "display_data":
+		data, _ := o["data"].(map[string]any)
+		if text, ok := data["text/plain"]; ok {
+			return joinSource(text) + "\n"
+		}
+		var types []string
+		for t := range data {
+			types = append(types, t)
+		}
+		return fmt.Sprintf("[%s]\n", strings.Join(types, ", "))
+	}
+	return ""
+}
+
+// joinSource returns multiline notebook text, which is a string or a list
+// of lines.
+func joinSource(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []any:
+		var sb strings.Builder
+		for _, line := range v {
+			s, _ := line.(string)
+			sb.WriteString(s)
+		}
+		return sb.String()
+	}
+	return ""
+}
+
+// splitSource returns s as a list of lines, each keeping its newline, as
+// Jupyter writes it.
+func splitSource(s string) []any {
+	lines := strings.SplitAfter(s, "\n")
+	out := make([]any, 0, len(lines))
+	for _, l := range lines {
+		if l != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// setCellType changes the type of cell, adding or removing the fields only
+// code cells have.
+func setCellType(cell map[string]any, cellType string) {
+	cell["cell_type"] = cellType
+	if cellType == "code" {
+		if _, ok := cell["outputs"]; !ok {
+			cell["outputs"] = []any{}
+		}
+		if _, ok := cell["execution_count"]; !ok {
+			cell["execution_count"] = nil
+		}
+		return
+	}
+	delete(cell, "outputs")
+	delete(cell, "execution_count")
+}
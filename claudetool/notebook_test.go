@@ -0,0 +1,116 @@
+package claudetool
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "metadata": {},
+   "source": ["# Title\n"]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 3,
+   "metadata": {"tags": ["keep"]},
+   "outputs": [
+    {"name": "stdout", "output_type": "stream", "text": ["<b>hi</b>\n"]},
+    {"data": {"image/png": "iVBOR"}, "metadata": {}, "output_type": "display_data"}
+   ],
+   "source": ["print('<b>hi</b>')\n"]
+  }
+ ],
+ "metadata": {"kernelspec": {"display_name": "Python 3", "language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`
+
+func newTestNotebookTool(t *testing.T) (*NotebookTool, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nb.ipynb")
+	if err := os.WriteFile(path, []byte(testNotebook), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return &NotebookTool{WorkingDir: NewMutableWorkingDir(dir)}, path
+}
+
+func TestNotebookRead(t *testing.T) {
+	tool, _ := newTestNotebookTool(t)
+	out := tool.run(context.Background(), notebookInput{Path: "nb.ipynb", Action: "read"})
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	text := out.LLMContent[0].Text
+	for _, want := range []string{"2 cells, kernel python3", "[0] markdown\n# Title", "[1] code (execution_count 3)", "<b>hi</b>", "[image/png]"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestNotebookEditPreservesOutputs(t *testing.T) {
+	tool, path := newTestNotebookTool(t)
+	cell := 1
+	out := tool.run(context.Background(), notebookInput{Path: "nb.ipynb", Action: "edit", Cell: &cell, Source: "x = 1\nprint(x)"})
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{`"x = 1\n",`, `"print(x)"`, `"tags": [`, `"image/png": "iVBOR"`, `"execution_count": 3`, `<b>hi</b>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q after edit:\n%s", want, got)
+		}
+	}
+}
+
+func TestNotebookInsertDelete(t *testing.T) {
+	tool, _ := newTestNotebookTool(t)
+	ctx := context.Background()
+	zero := 0
+	if out := tool.run(ctx, notebookInput{Path: "nb.ipynb", Action: "insert", Cell: &zero, Source: "import os"}); out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	out := tool.run(ctx, notebookInput{Path: "nb.ipynb", Action: "read", Cell: &zero})
+	if !strings.HasPrefix(out.LLMContent[0].Text, "[0] code\nimport os") {
+		t.Errorf("inserted cell: %s", out.LLMContent[0].Text)
+	}
+	if out := tool.run(ctx, notebookInput{Path: "nb.ipynb", Action: "delete", Cell: &zero}); out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	out = tool.run(ctx, notebookInput{Path: "nb.ipynb", Action: "read", Cell: &zero})
+	if !strings.HasPrefix(out.LLMContent[0].Text, "[0] markdown") {
+		t.Errorf("after delete: %s", out.LLMContent[0].Text)
+	}
+	five := 5
+	if out := tool.run(ctx, notebookInput{Path: "nb.ipynb", Action: "delete", Cell: &five}); out.Error == nil {
+		t.Error("expected out of range error")
+	}
+}
+
+func TestNotebookExecute(t *testing.T) {
+	if _, err := exec.LookPath("jupyter"); err != nil {
+		t.Skip("jupyter not installed")
+	}
+	tool, _ := newTestNotebookTool(t)
+	cell := 1
+	out := tool.run(context.Background(), notebookInput{Path: "nb.ipynb", Action: "execute", Cell: &cell})
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	if !strings.Contains(out.LLMContent[0].Text, "execution_count 1") {
+		t.Errorf("expected a fresh run: %s", out.LLMContent[0].Text)
+	}
+}
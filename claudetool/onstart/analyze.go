@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -35,78 +36,107 @@ type Codebase struct {
 	// InjectFileContents maps paths to file contents for critical inject files
 	// to avoid requiring an extra file read during template rendering
 	InjectFileContents map[string]string
+	// ProjectFiles contains repository-root language and package manifests
+	// (go.mod, package.json, Cargo.toml, pyproject.toml, ...)
+	ProjectFiles []string
+	// Projects are the package manifests anywhere in the repository,
+	// parsed; several mean a monorepo.
+	Projects []Project
+	// Dockerfiles are the repository's container build files.
+	Dockerfiles []Dockerfile
+	// ToolVersions maps tools to the versions pinned by root-level files
+	// such as .nvmrc, .python-version, and .tool-versions.
+	ToolVersions map[string]string
+	// Commands are the build, test, and lint commands inferred from the
+	// root Makefile, package.json scripts, and manifests. They are not verified;
+	// see VerifyCommands.
+	Commands []Command
+	// TopLevelDirs are the directories in the root that hold files, sorted.
+	TopLevelDirs []string
+	// Sampled reports that the codebase was too large to analyze fully (see
+	// Limits). ExtensionCounts are then estimated from a sample, build,
+	// guidance, and project files are only looked for near the root, and if
+	// listing files ran out of time, TotalFiles is a lower bound.
+	Sampled bool
 }
 
 // AnalyzeCodebase walks the codebase and analyzes the paths it finds.
+// In a git repository, those are the files git tracks, which leaves out
+// dependencies and build output, though some guidance files might be
+// locally .gitignored. Elsewhere, they are found by walking the directory
+// (see walkFiles). Either way, paths matched by ShelleyIgnoreFile are left out.
+// AnalyzeCodebase uses the default Limits.
 func AnalyzeCodebase(ctx context.Context, repoPath string) (*Codebase, error) {
-	// TODO: do a filesystem walk instead?
-	// There's a balance: git ls-files skips node_modules etc,
-	// but some guidance files might be locally .gitignored.
-	cmd := exec.Command("git", "ls-files", "-z")
-	cmd.Dir = repoPath
-
-	r, w := io.Pipe() // stream and scan rather than buffer
-	cmd.Stdout = w
-
-	err := cmd.Start()
-	if err != nil {
-		return nil, err
-	}
+	return AnalyzeCodebaseLimited(ctx, repoPath, Limits{})
+}
 
+// AnalyzeCodebaseLimited is AnalyzeCodebase with the given limits on the
+// analysis of large codebases.
+func AnalyzeCodebaseLimited(ctx context.Context, repoPath string, limits Limits) (*Codebase, error) {
 	extCounts := make(map[string]int)
 	var buildFiles []string
 	var documentationFiles []string
 	var guidanceFiles []string
 	var injectFiles []string
+	var projectFiles []string
+	var manifests []string
 	injectFileContents := make(map[string]string)
 	var totalFiles int
+	topLevelDirs := make(map[string]bool)
+	threshold := limits.sampleThreshold()
 
-	eg, _ := errgroup.WithContext(ctx)
-
-	eg.Go(func() error {
-		defer r.Close()
-
-		scanner := bufio.NewScanner(r)
-		scanner.Split(scanZero)
-		for scanner.Scan() {
-			file := scanner.Text()
-			file = strings.TrimSpace(file)
-			if file == "" {
-				continue
-			}
-			totalFiles++
-			ext := strings.ToLower(filepath.Ext(file))
-			ext = cmp.Or(ext, "<no-extension>")
+	ignore := ReadShelleyIgnore(repoPath)
+	add := func(file string) {
+		file = strings.TrimSpace(file)
+		if file == "" || ignore.Excludes(file) {
+			return
+		}
+		totalFiles++
+		if dir := topLevelDir(file); dir != "" {
+			topLevelDirs[dir] = true
+		}
+		sampling := totalFiles > threshold
+		ext := strings.ToLower(filepath.Ext(file))
+		ext = cmp.Or(ext, "<no-extension>")
+		switch {
+		case !sampling:
 			extCounts[ext]++
-
-			fileCategory := categorizeFile(file)
-			// fmt.Println(file, "->", fileCategory)
-			switch fileCategory {
-			case "build":
-				buildFiles = append(buildFiles, file)
-			case "documentation":
-				documentationFiles = append(documentationFiles, file)
-			case "guidance":
-				guidanceFiles = append(guidanceFiles, file)
-			case "inject":
-				injectFiles = append(injectFiles, file)
-			}
+		case totalFiles%sampleStride == 0:
+			extCounts[ext] += sampleStride
+		}
+		if sampling && strings.Count(file, "/") > maxSampledDepth {
+			return
 		}
-		return scanner.Err()
-	})
 
-	// Wait for the command to complete
-	eg.Go(func() error {
-		err := cmd.Wait()
-		if err != nil {
-			w.CloseWithError(err)
-		} else {
-			w.Close()
+		if isManifest(file) {
+			manifests = append(manifests, file)
 		}
-		return err
-	})
 
-	if err := eg.Wait(); err != nil {
+		fileCategory := categorizeFile(file)
+		// fmt.Println(file, "->", fileCategory)
+		switch fileCategory {
+		case "build":
+			buildFiles = append(buildFiles, file)
+		case "documentation":
+			documentationFiles = append(documentationFiles, file)
+		case "guidance":
+			guidanceFiles = append(guidanceFiles, file)
+		case "inject":
+			injectFiles = append(injectFiles, file)
+		case "project":
+			projectFiles = append(projectFiles, file)
+		}
+	}
+	listCtx, cancel := context.WithTimeout(ctx, limits.timeBudget())
+	defer cancel()
+	var err error
+	if isGitWorkTree(listCtx, repoPath) {
+		err = gitLsFiles(listCtx, repoPath, add)
+	} else {
+		err = walkFiles(listCtx, repoPath, add)
+	}
+	outOfTime := listCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+	if err != nil && !outOfTime {
 		return nil, err
 	}
 
@@ -121,7 +151,7 @@ func AnalyzeCodebase(ctx context.Context, repoPath string) (*Codebase, error) {
 		injectFileContents[filePath] = string(content)
 	}
 
-	return &Codebase{
+	c := &Codebase{
 		ExtensionCounts:    extCounts,
 		TotalFiles:         totalFiles,
 		BuildFiles:         buildFiles,
@@ -129,12 +159,56 @@ func AnalyzeCodebase(ctx context.Context, repoPath string) (*Codebase, error) {
 		GuidanceFiles:      guidanceFiles,
 		InjectFiles:        injectFiles,
 		InjectFileContents: injectFileContents,
-	}, nil
+		ProjectFiles:       projectFiles,
+		TopLevelDirs:       slices.Sorted(maps.Keys(topLevelDirs)),
+		Sampled:            totalFiles > threshold || outOfTime,
+	}
+	c.readManifests(repoPath, manifests)
+	c.inferCommands(repoPath)
+	return c, nil
 }
 
-// categorizeFile categorizes a file into one of four categories: build, documentation, guidance, or inject.
-// Returns an empty string if the file doesn't belong to any of these categories.
-// categorizeFile categorizes a file into one of four categories: build, documentation, guidance, or inject.
+// gitLsFiles calls fn with each file git tracks in repoPath.
+func gitLsFiles(ctx context.Context, repoPath string, fn func(path string)) error {
+	cmd := exec.CommandContext(ctx, "git", "ls-files", "-z")
+	cmd.Dir = repoPath
+
+	r, w := io.Pipe() // stream and scan rather than buffer
+	cmd.Stdout = w
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	eg, _ := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		defer r.Close()
+
+		scanner := bufio.NewScanner(r)
+		scanner.Split(scanZero)
+		for scanner.Scan() {
+			fn(scanner.Text())
+		}
+		return scanner.Err()
+	})
+
+	// Wait for the command to complete
+	eg.Go(func() error {
+		err := cmd.Wait()
+		if err != nil {
+			w.CloseWithError(err)
+		} else {
+			w.Close()
+		}
+		return err
+	})
+
+	return eg.Wait()
+}
+
+// categorizeFile categorizes a file into one of five categories: build, documentation, guidance, inject, or project.
 // Returns an empty string if the file doesn't belong to any of these categories.
 // The path parameter is relative to the repository root as returned by git ls-files.
 func categorizeFile(path string) string {
@@ -155,6 +229,11 @@ func categorizeFile(path string) string {
 		}
 	}
 
+	// ProjectFiles - root-level manifests that identify the language toolchain
+	if isRepoRootFile && slices.Contains(projectFileNames, filename) {
+		return "project"
+	}
+
 	// GitHub Copilot: https://code.visualstudio.com/docs/copilot/copilot-customization
 	if path == ".github/copilot-instructions.md" {
 		return "inject"
@@ -182,6 +261,78 @@ func categorizeFile(path string) string {
 	return ""
 }
 
+// projectFileNames are the manifest files categorized as "project" when found
+// at the repository root.
+var projectFileNames = []string{
+	"go.mod",
+	"Cargo.toml",
+	"package.json",
+	"tsconfig.json",
+	"pyproject.toml",
+	"setup.py",
+	"setup.cfg",
+	"pytest.ini",
+	"tox.ini",
+	"conftest.py",
+	"ruff.toml",
+	"eslint.config.js",
+	"eslint.config.mjs",
+	"eslint.config.cjs",
+	".eslintrc.js",
+	".eslintrc.cjs",
+	".eslintrc.json",
+}
+
+// HasProjectFile reports whether the repository root contains the named manifest.
+func (c *Codebase) HasProjectFile(name string) bool {
+	return slices.Contains(c.ProjectFiles, name)
+}
+
+// TestFramework returns the test framework for the codebase: "go", "cargo",
+// "pytest", or "jest". It returns "" if none is recognized. When several
+// manifests are present, the first match in that order wins.
+func (c *Codebase) TestFramework() string {
+	switch {
+	case c.HasProjectFile("go.mod"):
+		return "go"
+	case c.HasProjectFile("Cargo.toml"):
+		return "cargo"
+	case c.isPython():
+		return "pytest"
+	case c.HasProjectFile("package.json"):
+		return "jest"
+	}
+	return ""
+}
+
+// isPython reports whether the repository root has a Python project manifest.
+func (c *Codebase) isPython() bool {
+	return c.HasProjectFile("pyproject.toml") || c.HasProjectFile("setup.py") || c.HasProjectFile("setup.cfg") ||
+		c.HasProjectFile("pytest.ini") || c.HasProjectFile("tox.ini") || c.HasProjectFile("conftest.py")
+}
+
+// Linters returns the linters and type checkers that apply to the codebase,
+// in a stable order: "go vet", "eslint", "tsc", "ruff".
+func (c *Codebase) Linters() []string {
+	var linters []string
+	if c.HasProjectFile("go.mod") {
+		linters = append(linters, "go vet")
+	}
+	for _, f := range c.ProjectFiles {
+		if strings.HasPrefix(f, "eslint.config.") || strings.HasPrefix(f, ".eslintrc.") {
+			linters = append(linters, "eslint")
+			break
+		}
+	}
+	if c.HasProjectFile("tsconfig.json") {
+		linters = append(linters, "tsc")
+	}
+	if c.isPython() || c.HasProjectFile("ruff.toml") {
+		linters = append(linters, "ruff")
+	}
+	return linters
+}
+
 // TopExtensions returns the top 5 most common file extensions in the codebase
 func (c *Codebase) TopExtensions() []string {
 	type extCount struct {
@@ -245,11 +245,96 @@ func TestAnalyzeCodebaseErrors(t *testing.T) {
 		t.Error("Expected error for non-existent path")
 	}
 
-	// Test with directory that doesn't have git
-	tempDir := t.TempDir()
-	_, err = AnalyzeCodebase(context.Background(), tempDir)
-	if err == nil {
-		t.Error("Expected error for directory without git")
+}
+
+func TestAnalyzeCodebaseWithoutGit(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"AGENTS.md":                       "# Agents\n",
+		"go.mod":                          "module example.com/m\n",
+		"main.go":                         "package main\n",
+		"docs/README.md":                  "# Docs\n",
+		"node_modules/x/index.js":         "",
+		"gen/out.go":                      "package gen\n",
+		"gen/keep.go":                     "package gen\n",
+		"logs/today.log":                  "",
+		".gitignore":                      "gen/*\n!gen/keep.go\n*.log\n",
+		".github/copilot-instructions.md": "Be brief.\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := AnalyzeCodebase(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// .gitignore, AGENTS.md, go.mod, main.go, docs/README.md, gen/keep.go,
+	// and the copilot instructions.
+	if c.TotalFiles != 7 {
+		t.Errorf("TotalFiles = %d, want 7; extensions %v", c.TotalFiles, c.ExtensionCounts)
+	}
+	if c.ExtensionCounts[".go"] != 2 || c.ExtensionCounts[".js"] != 0 || c.ExtensionCounts[".log"] != 0 {
+		t.Errorf("ExtensionCounts = %v", c.ExtensionCounts)
+	}
+	slices.Sort(c.InjectFiles)
+	if want := []string{".github/copilot-instructions.md", "AGENTS.md"}; !slices.Equal(c.InjectFiles, want) {
+		t.Errorf("InjectFiles = %v, want %v", c.InjectFiles, want)
+	}
+	if !c.HasProjectFile("go.mod") || c.TestFramework() != "go" {
+		t.Errorf("ProjectFiles = %v", c.ProjectFiles)
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules("# comment\n*.tmp\n/build\ndocs/*.html\n**/fixtures/big\ncache/\n!keep.tmp\n")
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.tmp", false, true},
+		{"x/y/a.tmp", false, true},
+		{"keep.tmp", false, false},
+		{"build", true, true},
+		{"src/build", true, false},
+		{"docs/index.html", false, true},
+		{"src/docs/index.html", false, false},
+		{"fixtures/big", true, true},
+		{"test/fixtures/big", true, true},
+		{"cache", true, true},
+		{"cache", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := rules.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+	for p, want := range map[string]bool{"cache/x/y.go": true, "src/cache/y.go": true, "src/y.go": false, "a.tmp": true} {
+		if got := rules.Excludes(p); got != want {
+			t.Errorf("Excludes(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestAnalyzeCodebaseShelleyIgnore(t *testing.T) {
+	c := analyzeFiles(t, map[string]string{
+		ShelleyIgnoreFile:            "testdata/\n*.pb.go\n",
+		"main.go":                    "package main\n",
+		"api/api.pb.go":              "package api\n",
+		"testdata/fixture/go.mod":    "module fixture\n",
+		"testdata/fixture/CLAUDE.md": "fixture\n",
+	})
+	// .shelleyignore and main.go.
+	if c.TotalFiles != 2 || len(c.Projects) != 0 || len(c.GuidanceFiles) != 0 {
+		t.Errorf("TotalFiles = %d, Projects = %v, GuidanceFiles = %v; want only the ignore file and main.go", c.TotalFiles, c.Projects, c.GuidanceFiles)
 	}
 }
 
@@ -279,6 +364,16 @@ func TestCategorizeFileEdgeCases(t *testing.T) {
 			path:     "docs/contributing.md",
 			expected: "documentation",
 		},
+		{
+			name:     "root go.mod",
+			path:     "go.mod",
+			expected: "project",
+		},
+		{
+			name:     "nested package.json",
+			path:     "web/package.json",
+			expected: "",
+		},
 		{
 			name:     "non matching file",
 			path:     "src/main.go",
@@ -296,6 +391,38 @@ func TestCategorizeFileEdgeCases(t *testing.T) {
 	}
 }
 
+func TestTestFramework(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"go.mod"}, "go"},
+		{[]string{"Cargo.toml"}, "cargo"},
+		{[]string{"pyproject.toml"}, "pytest"},
+		{[]string{"package.json"}, "jest"},
+		{[]string{"package.json", "go.mod"}, "go"},
+	}
+	for _, tt := range tests {
+		c := &Codebase{ProjectFiles: tt.files}
+		if got := c.TestFramework(); got != tt.want {
+			t.Errorf("TestFramework(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestLinters(t *testing.T) {
+	c := &Codebase{ProjectFiles: []string{"go.mod", "package.json", "tsconfig.json", "eslint.config.js", "pyproject.toml"}}
+	want := []string{"go vet", "eslint", "tsc", "ruff"}
+	if got := c.Linters(); !slices.Equal(got, want) {
+		t.Errorf("Linters() = %v, want %v", got, want)
+	}
+	c = &Codebase{ProjectFiles: []string{"package.json"}}
+	if got := c.Linters(); len(got) != 0 {
+		t.Errorf("Linters() without eslint config = %v, want none", got)
+	}
+}
+
 func TestScanZero(t *testing.T) {
 	tests := []struct {
 		name     string
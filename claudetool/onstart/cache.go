@@ -0,0 +1,91 @@
+package onstart
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// An AnalysisCache stores codebase analyses, one per repository root.
+// *db.DB implements it.
+type AnalysisCache interface {
+	// CodebaseAnalysis returns the analysis stored for root under
+	// fingerprint and when it was stored, or "" if there is none.
+	CodebaseAnalysis(ctx context.Context, root, fingerprint string) (string, time.Time, error)
+	// SetCodebaseAnalysis stores root's analysis under fingerprint,
+	// replacing any other analysis of root.
+	SetCodebaseAnalysis(ctx context.Context, root, fingerprint, analysis string) error
+}
+
+// analysisTTL is how long a cached analysis is used. The fingerprint covers
+// tracked files; the TTL bounds staleness from anything it misses, such as
+// changes to ignored files that are nonetheless read.
+const analysisTTL = 24 * time.Hour
+
+// AnalyzeCodebaseCached is AnalyzeCodebaseLimited, reusing an analysis from cache
+// if repoPath's HEAD commit and uncommitted changes are the same as when it
+// was made, and it is less than a day old. A nil cache disables caching.
+// Failing to use the cache only costs time, so it is not an error.
+func AnalyzeCodebaseCached(ctx context.Context, repoPath string, cache AnalysisCache, limits Limits) (*Codebase, error) {
+	if cache == nil {
+		return AnalyzeCodebaseLimited(ctx, repoPath, limits)
+	}
+	fingerprint, err := fingerprintRepo(ctx, repoPath)
+	if err != nil {
+		return AnalyzeCodebaseLimited(ctx, repoPath, limits)
+	}
+	if analysis, updated, err := cache.CodebaseAnalysis(ctx, repoPath, fingerprint); err == nil && analysis != "" && time.Since(updated) < analysisTTL {
+		c := new(Codebase)
+		if json.Unmarshal([]byte(analysis), c) == nil {
+			return c, nil
+		}
+	}
+	c, err := AnalyzeCodebaseLimited(ctx, repoPath, limits)
+	if err != nil {
+		return nil, err
+	}
+	if analysis, err := json.Marshal(c); err == nil {
+		cache.SetCodebaseAnalysis(ctx, repoPath, fingerprint, string(analysis))
+	}
+	return c, nil
+}
+
+// fingerprintRepo identifies the state of repoPath's tracked files: its HEAD
+// commit, and the paths, sizes, and modification times of tracked files with
+// uncommitted changes. It also covers ShelleyIgnoreFile. It is cheap even in
+// large repositories.
+func fingerprintRepo(ctx context.Context, repoPath string) (string, error) {
+	head, err := exec.CommandContext(ctx, "git", "-C", repoPath, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse HEAD: %w", err)
+	}
+	status, err := exec.CommandContext(ctx, "git", "-C", repoPath, "status", "--porcelain", "-z", "--untracked-files=no").Output()
+	if err != nil {
+		return "", fmt.Errorf("git status: %w", err)
+	}
+	h := sha256.New()
+	h.Write(head)
+	h.Write(status)
+	// The ignore file changes the analysis even when git ignores it.
+	if ignore, err := os.ReadFile(filepath.Join(repoPath, ShelleyIgnoreFile)); err == nil {
+		h.Write(ignore)
+	}
+	// Entries are "XY path". A rename's entry is followed by its source
+	// path, which has no status; stat'ing a mangled path is harmless.
+	for entry := range bytes.SplitSeq(status, []byte{0}) {
+		if len(entry) < 4 {
+			continue
+		}
+		if fi, err := os.Stat(filepath.Join(repoPath, string(entry[3:]))); err == nil {
+			fmt.Fprintf(h, "%d %d\n", fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,100 @@
+package onstart
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mapCache is an AnalysisCache in memory.
+type mapCache map[string]struct {
+	fingerprint, analysis string
+	updated               time.Time
+}
+
+func (m mapCache) CodebaseAnalysis(ctx context.Context, root, fingerprint string) (string, time.Time, error) {
+	e := m[root]
+	if e.fingerprint != fingerprint {
+		return "", time.Time{}, nil
+	}
+	return e.analysis, e.updated, nil
+}
+
+func (m mapCache) SetCodebaseAnalysis(ctx context.Context, root, fingerprint, analysis string) error {
+	m[root] = struct {
+		fingerprint, analysis string
+		updated               time.Time
+	}{fingerprint, analysis, time.Now()}
+	return nil
+}
+
+func TestAnalyzeCodebaseCached(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@e"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q")
+	write("go.mod", "module example.com/m\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+
+	ctx := context.Background()
+	cache := make(mapCache)
+	analyze := func() *Codebase {
+		t.Helper()
+		c, err := AnalyzeCodebaseCached(ctx, dir, cache, Limits{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	if c := analyze(); c.TotalFiles != 1 || len(cache) != 1 {
+		t.Fatalf("first analysis: %d files, %d cached", c.TotalFiles, len(cache))
+	}
+
+	// A cached analysis is returned as stored.
+	e := cache[dir]
+	e.analysis = `{"TotalFiles": 42}`
+	cache[dir] = e
+	if c := analyze(); c.TotalFiles != 42 {
+		t.Errorf("unchanged repository: TotalFiles = %d, want the cached 42", c.TotalFiles)
+	}
+
+	// Uncommitted changes to tracked files invalidate it.
+	write("go.mod", "module example.com/m\n\ngo 1.24\n")
+	if c := analyze(); c.TotalFiles != 1 || c.Projects[0].Versions["go"] != "1.24" {
+		t.Errorf("dirty repository: got %d files, projects %+v; want a fresh analysis", c.TotalFiles, c.Projects)
+	}
+
+	// So does a new commit.
+	git("commit", "-q", "-am", "go version")
+	e = cache[dir]
+	e.analysis = `{"TotalFiles": 42}`
+	cache[dir] = e
+	if c := analyze(); c.TotalFiles != 1 {
+		t.Errorf("after commit: TotalFiles = %d, want a fresh analysis", c.TotalFiles)
+	}
+
+	// Old analyses are not used.
+	e = cache[dir]
+	e.analysis = `{"TotalFiles": 42}`
+	e.updated = time.Now().Add(-analysisTTL - time.Minute)
+	cache[dir] = e
+	if c := analyze(); c.TotalFiles != 1 {
+		t.Errorf("expired analysis: TotalFiles = %d, want a fresh analysis", c.TotalFiles)
+	}
+}
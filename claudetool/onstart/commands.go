@@ -0,0 +1,208 @@
+package onstart
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Command is a build, test, or lint command inferred from the codebase's build files.
+type Command struct {
+	// Kind is "build", "test", or "lint".
+	Kind string
+	// Command is the shell command, run from the repository root.
+	Command string
+	// Source is where the command was inferred from, such as Makefile or go.mod.
+	Source string
+	// Probe is a cheap command that succeeds if Command can run.
+	Probe []string
+}
+
+// commandKinds are the kinds of commands, in the order they are reported.
+var commandKinds = []string{"build", "test", "lint"}
+
+// makeTargets maps command kinds to the Makefile targets that provide them, most likely first.
+var makeTargets = map[string][]string{
+	"build": {"build", "all"},
+	"test":  {"test", "tests", "check"},
+	"lint":  {"lint", "vet"},
+}
+
+// packageScripts maps command kinds to the package.json scripts that provide them, most likely first.
+var packageScripts = map[string][]string{
+	"build": {"build"},
+	"test":  {"test"},
+	"lint":  {"lint", "typecheck", "check"},
+}
+
+// inferCommands sets c.Commands from the root Makefile, package.json scripts,
+// and root manifests, in that order of preference. A Makefile target is taken
+// to cover the whole repository; otherwise each ecosystem gets its own command.
+func (c *Codebase) inferCommands(repoPath string) {
+	targets := c.readMakeTargets(repoPath)
+	var scripts []string
+	for _, p := range c.Projects {
+		if p.Dir == "." && p.Manifest == "package.json" {
+			scripts = p.Scripts
+		}
+	}
+	pm := c.packageManager(repoPath)
+
+	for _, kind := range commandKinds {
+		if t := firstOf(makeTargets[kind], targets); t != "" {
+			c.Commands = append(c.Commands, Command{Kind: kind, Command: "make " + t, Source: "Makefile", Probe: []string{"make", "-n", t}})
+			continue
+		}
+		if s := firstOf(packageScripts[kind], scripts); s != "" {
+			cmd := pm + " run " + s
+			if s == "test" {
+				cmd = pm + " test"
+			}
+			c.Commands = append(c.Commands, Command{Kind: kind, Command: cmd, Source: "package.json", Probe: []string{pm, "--version"}})
+		}
+		for _, cmd := range manifestCommands {
+			if cmd.Kind == kind && c.hasManifestFor(cmd) {
+				c.Commands = append(c.Commands, cmd)
+			}
+		}
+	}
+}
+
+// pythonSource is the Source of commands for Python projects, which have several possible manifests.
+const pythonSource = "Python project files"
+
+// manifestCommands are the conventional commands for projects with root manifests.
+var manifestCommands = []Command{
+	{Kind: "build", Command: "go build ./...", Source: "go.mod", Probe: []string{"go", "list", "-m"}},
+	{Kind: "test", Command: "go test ./...", Source: "go.mod", Probe: []string{"go", "list", "-m"}},
+	{Kind: "lint", Command: "go vet ./...", Source: "go.mod", Probe: []string{"go", "list", "-m"}},
+	{Kind: "build", Command: "cargo build", Source: "Cargo.toml", Probe: []string{"cargo", "--version"}},
+	{Kind: "test", Command: "cargo test", Source: "Cargo.toml", Probe: []string{"cargo", "--version"}},
+	{Kind: "lint", Command: "cargo clippy", Source: "Cargo.toml", Probe: []string{"cargo", "clippy", "--version"}},
+	{Kind: "test", Command: "python3 -m pytest", Source: pythonSource, Probe: []string{"python3", "-m", "pytest", "--version"}},
+	{Kind: "lint", Command: "ruff check .", Source: pythonSource, Probe: []string{"ruff", "--version"}},
+}
+
+// hasManifestFor reports whether the repository root has the manifest cmd was inferred from.
+func (c *Codebase) hasManifestFor(cmd Command) bool {
+	if cmd.Source == pythonSource {
+		return c.isPython()
+	}
+	return c.HasProjectFile(cmd.Source)
+}
+
+// makeTargetLine matches a Makefile rule, capturing its targets.
+// It excludes variable assignments (:=, ::=) and recipe lines.
+var makeTargetLine = regexp.MustCompile(`^([A-Za-z0-9_./-][A-Za-z0-9_./ -]*?)\s*::?(?:[^=]|$)`)
+
+// readMakeTargets returns the explicit targets of the root Makefile.
+func (c *Codebase) readMakeTargets(repoPath string) []string {
+	var makefile string
+	for _, name := range []string{"GNUmakefile", "makefile", "Makefile"} {
+		if slices.Contains(c.BuildFiles, name) {
+			makefile = name
+			break
+		}
+	}
+	if makefile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(repoPath, makefile))
+	if err != nil {
+		return nil
+	}
+	var targets []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		m := makeTargetLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		for _, t := range strings.Fields(m[1]) {
+			if !strings.HasPrefix(t, ".") && !slices.Contains(targets, t) {
+				targets = append(targets, t)
+			}
+		}
+	}
+	return targets
+}
+
+// packageManager returns the JavaScript package manager for the root package.json:
+// the one named by its packageManager field, else the one whose lockfile is present, else npm.
+func (c *Codebase) packageManager(repoPath string) string {
+	for _, p := range c.Projects {
+		if p.Dir != "." || p.Manifest != "package.json" {
+			continue
+		}
+		for _, pm := range []string{"pnpm", "yarn", "bun", "npm"} {
+			if _, ok := p.Versions[pm]; ok {
+				return pm
+			}
+		}
+	}
+	for _, lock := range []struct{ file, pm string }{{"pnpm-lock.yaml", "pnpm"}, {"yarn.lock", "yarn"}, {"bun.lock", "bun"}, {"bun.lockb", "bun"}} {
+		if _, err := os.Stat(filepath.Join(repoPath, lock.file)); err == nil {
+			return lock.pm
+		}
+	}
+	return "npm"
+}
+
+// firstOf returns the first of want that is in have, or "".
+func firstOf(want, have []string) string {
+	for _, w := range want {
+		if slices.Contains(have, w) {
+			return w
+		}
+	}
+	return ""
+}
+
+// probeTimeout bounds each command's probe.
+const probeTimeout = 10 * time.Second
+
+// VerifyCommands returns the commands that can run in repoPath, as shown by
+// cheap probes rather than by running them: make -n for Makefile targets,
+// go list -m for Go, and the tools' --version otherwise.
+func VerifyCommands(ctx context.Context, repoPath string, cmds []Command) []Command {
+	ok := make([]bool, len(cmds))
+	var wg sync.WaitGroup
+	for i, cmd := range cmds {
+		wg.Go(func() {
+			if len(cmd.Probe) == 0 {
+				return
+			}
+			ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+			defer cancel()
+			probe := exec.CommandContext(ctx, cmd.Probe[0], cmd.Probe[1:]...)
+			probe.Dir = repoPath
+			ok[i] = probe.Run() == nil
+		})
+	}
+	wg.Wait()
+	var verified []Command
+	for i, cmd := range cmds {
+		if ok[i] {
+			verified = append(verified, cmd)
+		}
+	}
+	return verified
+}
+
+// FormatCommands lists cmds one per line, for the system prompt.
+func FormatCommands(cmds []Command) string {
+	var b strings.Builder
+	for _, cmd := range cmds {
+		fmt.Fprintf(&b, "- %s: %s (%s)\n", cmd.Kind, cmd.Command, cmd.Source)
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package onstart
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestInferCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "makefile wins",
+			files: map[string]string{
+				"Makefile": "GO := go\nLDFLAGS ::= -s\n.PHONY: build test\nbuild test: deps\n\t$(GO) build ./...\ncheck:\n\t@true\n%.o: %.c\n",
+				"go.mod":   "module example.com/m\n",
+			},
+			want: []string{
+				"build: make build (Makefile)",
+				"test: make test (Makefile)",
+				"lint: go vet ./... (go.mod)",
+			},
+		},
+		{
+			name: "package scripts and manifests",
+			files: map[string]string{
+				"package.json":   `{"scripts": {"test": "vitest", "typecheck": "tsc --noEmit"}}`,
+				"pnpm-lock.yaml": "",
+				"pyproject.toml": "[project]\nname = \"p\"\n",
+			},
+			want: []string{
+				"test: pnpm test (package.json)",
+				"test: python3 -m pytest (Python project files)",
+				"lint: pnpm run typecheck (package.json)",
+				"lint: ruff check . (Python project files)",
+			},
+		},
+		{
+			name:  "nothing",
+			files: map[string]string{"README.md": "hi\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := analyzeFiles(t, tt.files)
+			var got []string
+			for _, cmd := range c.Commands {
+				got = append(got, cmd.Kind+": "+cmd.Command+" ("+cmd.Source+")")
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Commands = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyCommands(t *testing.T) {
+	cmds := []Command{
+		{Kind: "build", Command: "ok", Probe: []string{"true"}},
+		{Kind: "test", Command: "fails", Probe: []string{"false"}},
+		{Kind: "lint", Command: "missing", Probe: []string{"shelley-no-such-tool"}},
+	}
+	got := VerifyCommands(context.Background(), t.TempDir(), cmds)
+	if len(got) != 1 || got[0].Command != "ok" {
+		t.Errorf("VerifyCommands = %+v, want only ok", got)
+	}
+	if want := "- build: ok ()\n"; FormatCommands(got) != want {
+		t.Errorf("FormatCommands = %q, want %q", FormatCommands(got), want)
+	}
+}
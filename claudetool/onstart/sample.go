@@ -0,0 +1,50 @@
+package onstart
+
+import (
+	"cmp"
+	"strings"
+	"time"
+)
+
+// Limits bound the analysis of large codebases.
+// The zero value uses the defaults.
+type Limits struct {
+	// SampleThreshold is the file count past which the analysis samples:
+	// it counts the extensions of one file in sampleStride and looks for
+	// build, guidance, and project files only near the root.
+	// Zero means DefaultSampleThreshold.
+	SampleThreshold int
+	// TimeBudget bounds listing the codebase's files. When it runs out, the
+	// analysis goes on with the files listed so far.
+	// Zero means DefaultTimeBudget.
+	TimeBudget time.Duration
+}
+
+const (
+	// DefaultSampleThreshold is the default Limits.SampleThreshold.
+	DefaultSampleThreshold = 100_000
+	// DefaultTimeBudget is the default Limits.TimeBudget.
+	DefaultTimeBudget = 5 * time.Second
+	// sampleStride is how many files past the threshold each sampled file stands for.
+	sampleStride = 16
+	// maxSampledDepth is how deeply below the root a sampling analysis looks for build files.
+	maxSampledDepth = 1
+)
+
+func (l Limits) sampleThreshold() int {
+	return cmp.Or(l.SampleThreshold, DefaultSampleThreshold)
+}
+
+func (l Limits) timeBudget() time.Duration {
+	return cmp.Or(l.TimeBudget, DefaultTimeBudget)
+}
+
+// topLevelDir returns the first element of file, a slash-separated path,
+// or "" if file is in the root.
+func topLevelDir(file string) string {
+	dir, _, ok := strings.Cut(file, "/")
+	if !ok {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,70 @@
+package onstart
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeCodebaseSampling(t *testing.T) {
+	files := map[string]string{
+		"Makefile":          "test:\n",
+		"a/go.mod":          "module a\n",
+		"z/deep/go.mod":     "module deep\n",
+		"z/deep/CLAUDE.md":  "deep\n",
+		"z/Cargo.toml":      "[package]\nname = \"z\"\n",
+		"root.txt":          "",
+		"docs/README.md":    "",
+		"third_party/x.txt": "",
+	}
+	for i := range 3 * sampleStride {
+		files[fmt.Sprintf("m/%03d.go", i)] = "package m\n"
+	}
+	dir := writeRepo(t, files)
+
+	c, err := AnalyzeCodebaseLimited(context.Background(), dir, Limits{SampleThreshold: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Sampled || c.TotalFiles != len(files) {
+		t.Errorf("Sampled = %v, TotalFiles = %d; want true, %d", c.Sampled, c.TotalFiles, len(files))
+	}
+	if want := []string{"a", "docs", "m", "third_party", "z"}; !slices.Equal(c.TopLevelDirs, want) {
+		t.Errorf("TopLevelDirs = %v, want %v", c.TopLevelDirs, want)
+	}
+	// In git ls-files order, m/000.go to m/006.go are under the threshold,
+	// and files 16, 32, and 48 are sampled past it.
+	if n, want := c.ExtensionCounts[".go"], 7+3*sampleStride; n != want {
+		t.Errorf(".go count = %d, want %d", n, want)
+	}
+	// Manifests one directory deep are still read past the threshold;
+	// deeper ones and deep guidance files are not.
+	var manifests []string
+	for _, p := range c.Projects {
+		manifests = append(manifests, p.Dir+"/"+p.Manifest)
+	}
+	if !slices.Contains(manifests, "z/Cargo.toml") || slices.Contains(manifests, "z/deep/go.mod") || len(c.GuidanceFiles) != 0 {
+		t.Errorf("manifests = %v, guidance files = %v", manifests, c.GuidanceFiles)
+	}
+	if summary := c.ToolchainSummary(); !strings.HasPrefix(summary, "- large codebase, analyzed from a sample: at least") ||
+		!strings.Contains(summary, "top-level directories: a, docs, m, third_party, z") {
+		t.Errorf("ToolchainSummary() = %q", summary)
+	}
+
+	c, err = AnalyzeCodebaseLimited(context.Background(), dir, Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Sampled || c.ExtensionCounts[".go"] != 3*sampleStride {
+		t.Errorf("under the threshold: Sampled = %v, .go count = %d", c.Sampled, c.ExtensionCounts[".go"])
+	}
+
+	// Running out of time is not an error.
+	c, err = AnalyzeCodebaseLimited(context.Background(), dir, Limits{TimeBudget: time.Nanosecond})
+	if err != nil || !c.Sampled {
+		t.Errorf("out of time: Sampled = %v, err = %v", c != nil && c.Sampled, err)
+	}
+}
@@ -0,0 +1,615 @@
+package onstart
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A Project is a package manifest in the codebase and what it declares.
+type Project struct {
+	// Dir is the manifest's directory, relative to the repository root ("." for the root).
+	Dir string
+	// Manifest is the manifest's file name, such as go.mod or package.json.
+	Manifest string
+	// Language is the project's language: Go, JavaScript, TypeScript, Python, or Rust.
+	Language string
+	// Name is the module or package name, if declared.
+	Name string
+	// Frameworks are the well-known frameworks and tools among its dependencies.
+	Frameworks []string
+	// Versions maps tools (go, node, python, rust, and package managers) to their declared versions.
+	Versions map[string]string
+	// Workspaces are the workspace members the manifest declares, as written (often globs).
+	Workspaces []string
+	// Scripts are the names of package.json scripts, sorted.
+	Scripts []string
+}
+
+// A Dockerfile is a container build file and the base images it builds from.
+type Dockerfile struct {
+	Path   string
+	Images []string
+}
+
+// maxManifests bounds how many manifests AnalyzeCodebase reads.
+const maxManifests = 50
+
+// isManifest reports whether the file at path is a manifest parsed into a Project or Dockerfile.
+func isManifest(path string) bool {
+	name := filepath.Base(path)
+	switch name {
+	case "go.mod", "go.work", "package.json", "pyproject.toml", "Cargo.toml", "Containerfile":
+		return true
+	}
+	lower := strings.ToLower(name)
+	return lower == "dockerfile" || strings.HasPrefix(lower, "dockerfile.") || strings.HasSuffix(lower, ".dockerfile")
+}
+
+// versionFiles are root-level files that pin tool versions, mapped to the tool they pin.
+// .tool-versions (asdf, mise) can pin several and is handled separately.
+var versionFiles = map[string]string{
+	".go-version":     "go",
+	".nvmrc":          "node",
+	".node-version":   "node",
+	".python-version": "python",
+	"rust-toolchain":  "rust",
+}
+
+// readManifests parses the manifests at paths (relative to repoPath) into c.
+// Unreadable or malformed manifests are skipped.
+func (c *Codebase) readManifests(repoPath string, paths []string) {
+	for _, p := range paths[:min(len(paths), maxManifests)] {
+		data, err := os.ReadFile(filepath.Join(repoPath, p))
+		if err != nil {
+			continue
+		}
+		dir, name := path.Split(p)
+		dir = path.Clean(cmp.Or(dir, "."))
+		var proj *Project
+		switch name {
+		case "go.mod", "go.work":
+			proj = parseGoMod(data)
+		case "package.json":
+			proj = parsePackageJSON(data)
+		case "pyproject.toml":
+			proj = parsePyproject(data)
+		case "Cargo.toml":
+			proj = parseCargoToml(data)
+		default:
+			if images := dockerfileImages(data); len(images) > 0 {
+				c.Dockerfiles = append(c.Dockerfiles, Dockerfile{Path: p, Images: images})
+			}
+			continue
+		}
+		if proj == nil {
+			continue
+		}
+		proj.Dir, proj.Manifest = dir, name
+		if proj.Language == "JavaScript" && slices.Contains(c.ProjectFiles, "tsconfig.json") && dir == "." {
+			proj.Language = "TypeScript"
+		}
+		c.Projects = append(c.Projects, *proj)
+	}
+
+	for name, tool := range versionFiles {
+		if data, err := os.ReadFile(filepath.Join(repoPath, name)); err == nil {
+			if v := strings.TrimSpace(firstLine(string(data))); v != "" {
+				c.setToolVersion(tool, v)
+			}
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(repoPath, "rust-toolchain.toml")); err == nil {
+		if v := parseTOML(data)["toolchain"]["channel"]; len(v) > 0 {
+			c.setToolVersion("rust", v[0])
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(repoPath, ".tool-versions")); err == nil {
+		for line := range strings.Lines(string(data)) {
+			if f := strings.Fields(line); len(f) >= 2 && !strings.HasPrefix(f[0], "#") {
+				c.setToolVersion(f[0], f[1])
+			}
+		}
+	}
+}
+
+func (c *Codebase) setToolVersion(tool, version string) {
+	if c.ToolVersions == nil {
+		c.ToolVersions = make(map[string]string)
+	}
+	c.ToolVersions[tool] = version
+}
+
+// Languages returns the languages of the codebase's projects, in order of first appearance.
+func (c *Codebase) Languages() []string {
+	var langs []string
+	for _, p := range c.Projects {
+		if p.Language != "" && !slices.Contains(langs, p.Language) {
+			langs = append(langs, p.Language)
+		}
+	}
+	return langs
+}
+
+// ToolchainSummary describes the codebase's projects, declared tool versions,
+// workspaces, and container base images, one item per line, for the system prompt.
+// For sampled codebases, it starts with the top-level directories.
+// It returns "" if nothing was found.
+func (c *Codebase) ToolchainSummary() string {
+	var b strings.Builder
+	if c.Sampled {
+		const maxDirs = 30
+		dirs := c.TopLevelDirs
+		if len(dirs) > maxDirs {
+			dirs = append(dirs[:maxDirs:maxDirs], fmt.Sprintf("(%d more)", len(c.TopLevelDirs)-maxDirs))
+		}
+		fmt.Fprintf(&b, "- large codebase, analyzed from a sample: at least %d files; manifests deep in the tree may be missing", c.TotalFiles)
+		if len(dirs) > 0 {
+			fmt.Fprintf(&b, "; top-level directories: %s", strings.Join(dirs, ", "))
+		}
+		b.WriteByte('\n')
+	}
+	const maxProjects = 20
+	for i, p := range c.Projects {
+		if i == maxProjects {
+			fmt.Fprintf(&b, "- (%d more manifests)\n", len(c.Projects)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- %s: %s", path.Join(p.Dir, p.Manifest), cmp.Or(p.Language, "unknown language"))
+		if p.Name != "" {
+			fmt.Fprintf(&b, " %q", p.Name)
+		}
+		if len(p.Versions) > 0 {
+			fmt.Fprintf(&b, "; versions: %s", formatVersions(p.Versions))
+		}
+		if len(p.Frameworks) > 0 {
+			fmt.Fprintf(&b, "; uses %s", strings.Join(p.Frameworks, ", "))
+		}
+		if len(p.Workspaces) > 0 {
+			fmt.Fprintf(&b, "; workspace members: %s", strings.Join(p.Workspaces, ", "))
+		}
+		b.WriteByte('\n')
+	}
+	if len(c.ToolVersions) > 0 {
+		fmt.Fprintf(&b, "- pinned tool versions: %s\n", formatVersions(c.ToolVersions))
+	}
+	for _, d := range c.Dockerfiles[:min(len(c.Dockerfiles), 5)] {
+		fmt.Fprintf(&b, "- %s: builds from %s\n", d.Path, strings.Join(d.Images, ", "))
+	}
+	return b.String()
+}
+
+func formatVersions(versions map[string]string) string {
+	var parts []string
+	for tool, v := range versions {
+		parts = append(parts, tool+" "+v)
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ", ")
+}
+
+// frameworks maps dependency names to the frameworks and tools worth
+// mentioning. Go module paths match by prefix, to cover major versions.
+var frameworks = map[string]string{
+	// Go
+	"github.com/gin-gonic/gin":           "Gin",
+	"github.com/labstack/echo":           "Echo",
+	"github.com/gofiber/fiber":           "Fiber",
+	"github.com/go-chi/chi":              "chi",
+	"github.com/gorilla/mux":             "gorilla/mux",
+	"github.com/spf13/cobra":             "Cobra",
+	"google.golang.org/grpc":             "gRPC",
+	"gorm.io/gorm":                       "GORM",
+	"github.com/stretchr/testify":        "testify",
+	"github.com/jackc/pgx":               "pgx",
+	"github.com/mattn/go-sqlite3":        "SQLite",
+	"modernc.org/sqlite":                 "SQLite",
+	"github.com/a-h/templ":               "templ",
+	"github.com/wailsapp/wails":          "Wails",
+	"github.com/charmbracelet/bubbletea": "Bubble Tea",
+	// JavaScript and TypeScript
+	"react":            "React",
+	"next":             "Next.js",
+	"vue":              "Vue",
+	"nuxt":             "Nuxt",
+	"svelte":           "Svelte",
+	"@sveltejs/kit":    "SvelteKit",
+	"@angular/core":    "Angular",
+	"solid-js":         "Solid",
+	"express":          "Express",
+	"fastify":          "Fastify",
+	"@nestjs/core":     "NestJS",
+	"hono":             "Hono",
+	"electron":         "Electron",
+	"vite":             "Vite",
+	"webpack":          "webpack",
+	"esbuild":          "esbuild",
+	"jest":             "Jest",
+	"vitest":           "Vitest",
+	"mocha":            "Mocha",
+	"@playwright/test": "Playwright",
+	"cypress":          "Cypress",
+	"eslint":           "ESLint",
+	"prettier":         "Prettier",
+	"tailwindcss":      "Tailwind CSS",
+	"prisma":           "Prisma",
+	// Python
+	"django":     "Django",
+	"flask":      "Flask",
+	"fastapi":    "FastAPI",
+	"pytest":     "pytest",
+	"sqlalchemy": "SQLAlchemy",
+	"pydantic":   "Pydantic",
+	"numpy":      "NumPy",
+	"pandas":     "pandas",
+	"torch":      "PyTorch",
+	"tensorflow": "TensorFlow",
+	"celery":     "Celery",
+	"ruff":       "Ruff",
+	"mypy":       "mypy",
+	// Rust
+	"tokio":     "Tokio",
+	"axum":      "Axum",
+	"actix-web": "Actix Web",
+	"rocket":    "Rocket",
+	"serde":     "Serde",
+	"clap":      "clap",
+	"bevy":      "Bevy",
+	"tauri":     "Tauri",
+	"diesel":    "Diesel",
+	"sqlx":      "SQLx",
+}
+
+// frameworksOf returns the frameworks among deps, sorted and without duplicates.
+func frameworksOf(deps []string) []string {
+	var found []string
+	for _, dep := range deps {
+		name, ok := frameworks[dep]
+		if !ok && strings.Contains(dep, ".") {
+			// Go module paths: match github.com/labstack/echo/v4 by prefix.
+			for prefix, fw := range frameworks {
+				if strings.HasPrefix(dep, prefix+"/") {
+					name, ok = fw, true
+					break
+				}
+			}
+		}
+		if ok && !slices.Contains(found, name) {
+			found = append(found, name)
+		}
+	}
+	slices.Sort(found)
+	return found
+}
+
+// parseGoMod reads the module path, versions, requirements, and (for go.work) use directives.
+func parseGoMod(data []byte) *Project {
+	p := &Project{Language: "Go", Versions: make(map[string]string)}
+	var deps []string
+	block := ""
+	for line := range strings.Lines(string(data)) {
+		line, _, _ = strings.Cut(line, "//")
+		f := strings.Fields(line)
+		switch {
+		case len(f) == 0:
+			continue
+		case f[0] == ")":
+			block = ""
+			continue
+		case len(f) == 2 && f[1] == "(":
+			block = f[0]
+			continue
+		case block != "":
+			f = append([]string{block}, f...)
+		}
+		if len(f) < 2 {
+			continue
+		}
+		switch f[0] {
+		case "module":
+			p.Name = unquote(f[1])
+		case "go":
+			p.Versions["go"] = f[1]
+		case "toolchain":
+			p.Versions["toolchain"] = f[1]
+		case "require":
+			deps = append(deps, f[1])
+		case "use":
+			p.Workspaces = append(p.Workspaces, unquote(f[1]))
+		}
+	}
+	p.Frameworks = frameworksOf(deps)
+	return p
+}
+
+// parsePackageJSON reads a package.json.
+func parsePackageJSON(data []byte) *Project {
+	var pkg struct {
+		Name            string            `json:"name"`
+		Engines         map[string]string `json:"engines"`
+		PackageManager  string            `json:"packageManager"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+		Workspaces      json.RawMessage   `json:"workspaces"`
+		Scripts         map[string]string `json:"scripts"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return nil
+	}
+	p := &Project{Language: "JavaScript", Name: pkg.Name, Versions: make(map[string]string)}
+	for tool, v := range pkg.Engines {
+		p.Versions[tool] = v
+	}
+	if pm, v, ok := strings.Cut(pkg.PackageManager, "@"); ok {
+		v, _, _ = strings.Cut(v, "+") // drop the integrity hash
+		p.Versions[pm] = v
+	}
+	var deps []string
+	for dep := range pkg.Dependencies {
+		deps = append(deps, dep)
+	}
+	for dep := range pkg.DevDependencies {
+		deps = append(deps, dep)
+	}
+	if slices.Contains(deps, "typescript") {
+		p.Language = "TypeScript"
+	}
+	p.Frameworks = frameworksOf(deps)
+	p.Scripts = slices.Sorted(maps.Keys(pkg.Scripts))
+	// "workspaces" is a list of globs, or for Yarn an object holding one.
+	if json.Unmarshal(pkg.Workspaces, &p.Workspaces) != nil {
+		var ws struct {
+			Packages []string `json:"packages"`
+		}
+		if json.Unmarshal(pkg.Workspaces, &ws) == nil {
+			p.Workspaces = ws.Packages
+		}
+	}
+	return p
+}
+
+// parsePyproject reads a pyproject.toml: PEP 621 metadata, Poetry, and uv workspaces.
+func parsePyproject(data []byte) *Project {
+	t := parseTOML(data)
+	p := &Project{Language: "Python", Versions: make(map[string]string)}
+	p.Name = first(t["project"]["name"], t["tool.poetry"]["name"])
+	if v := first(t["project"]["requires-python"], t["tool.poetry.dependencies"]["python"]); v != "" {
+		p.Versions["python"] = v
+	}
+	var deps []string
+	for _, req := range t["project"]["dependencies"] {
+		deps = append(deps, requirementName(req))
+	}
+	// Optional dependencies and dependency groups map names to lists of requirements.
+	for _, table := range []string{"project.optional-dependencies", "dependency-groups"} {
+		for _, reqs := range t[table] {
+			for _, req := range reqs {
+				deps = append(deps, requirementName(req))
+			}
+		}
+	}
+	// Poetry maps package names to version constraints.
+	for _, table := range []string{"tool.poetry.dependencies", "tool.poetry.dev-dependencies", "tool.poetry.group.dev.dependencies"} {
+		for name := range t[table] {
+			deps = append(deps, strings.ToLower(name))
+		}
+	}
+	p.Frameworks = frameworksOf(deps)
+	// The project manager is configured under [tool.NAME].
+	for tool, name := range map[string]string{"poetry": "Poetry", "uv": "uv", "hatch": "Hatch", "pdm": "PDM"} {
+		for table := range t {
+			if (table == "tool."+tool || strings.HasPrefix(table, "tool."+tool+".")) && !slices.Contains(p.Frameworks, name) {
+				p.Frameworks = append(p.Frameworks, name)
+			}
+		}
+	}
+	slices.Sort(p.Frameworks)
+	p.Workspaces = t["tool.uv.workspace"]["members"]
+	return p
+}
+
+// requirementName returns the package name of a PEP 508 requirement like "Django>=4.2".
+func requirementName(req string) string {
+	end := strings.IndexFunc(req, func(r rune) bool {
+		return !(r == '-' || r == '_' || r == '.' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+	})
+	if end >= 0 {
+		req = req[:end]
+	}
+	return strings.ToLower(req)
+}
+
+// parseCargoToml reads a Cargo.toml.
+func parseCargoToml(data []byte) *Project {
+	t := parseTOML(data)
+	p := &Project{Language: "Rust", Versions: make(map[string]string)}
+	p.Name = first(t["package"]["name"])
+	if v := first(t["package"]["rust-version"], t["workspace.package"]["rust-version"]); v != "" {
+		p.Versions["rust"] = v
+	}
+	if v := first(t["package"]["edition"], t["workspace.package"]["edition"]); v != "" {
+		p.Versions["edition"] = v
+	}
+	var deps []string
+	for _, table := range []string{"dependencies", "dev-dependencies", "workspace.dependencies"} {
+		for dep := range t[table] {
+			deps = append(deps, dep)
+		}
+	}
+	p.Frameworks = frameworksOf(deps)
+	p.Workspaces = t["workspace"]["members"]
+	return p
+}
+
+// dockerfileImages returns the base images a Dockerfile builds from,
+// leaving out scratch and earlier build stages.
+func dockerfileImages(data []byte) []string {
+	var images, stages []string
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) < 2 || !strings.EqualFold(f[0], "FROM") {
+			continue
+		}
+		f = f[1:]
+		for len(f) > 0 && strings.HasPrefix(f[0], "--") {
+			f = f[1:] // --platform=...
+		}
+		if len(f) == 0 {
+			continue
+		}
+		image := f[0]
+		if image != "scratch" && !slices.Contains(stages, strings.ToLower(image)) && !slices.Contains(images, image) {
+			images = append(images, image)
+		}
+		if len(f) >= 3 && strings.EqualFold(f[1], "AS") {
+			stages = append(stages, strings.ToLower(f[2]))
+		}
+	}
+	return images
+}
+
+// parseTOML is a minimal TOML reader, enough for package manifests.
+// It maps table names to keys to values. String values are one-element
+// lists; arrays of strings (possibly spanning lines) are lists; inline
+// tables and other values are kept as their raw text. Dotted keys and
+// arrays of tables are not handled.
+func parseTOML(data []byte) map[string]map[string][]string {
+	t := make(map[string]map[string][]string)
+	table := ""
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := tomlTrimComment(lines[i])
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			table = strings.Trim(line, "[] ")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = unquote(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "[") {
+			for !tomlArrayClosed(value) && i+1 < len(lines) {
+				i++
+				value += " " + tomlTrimComment(lines[i])
+			}
+		}
+		if t[table] == nil {
+			t[table] = make(map[string][]string)
+		}
+		t[table][key] = tomlStrings(value)
+	}
+	return t
+}
+
+// tomlArrayClosed reports whether the array starting value has ended.
+func tomlArrayClosed(value string) bool {
+	depth, quote := 0, byte(0)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tomlTrimComment trims space and any comment from a line.
+func tomlTrimComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return strings.TrimSpace(line[:i])
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+// tomlStrings returns the strings in a TOML value.
+func tomlStrings(value string) []string {
+	if !strings.HasPrefix(value, "[") {
+		if s, ok := tomlString(value); ok {
+			return []string{s}
+		}
+		return []string{value}
+	}
+	var out []string
+	for i := 0; i < len(value); i++ {
+		if value[i] == '"' || value[i] == '\'' {
+			if s, ok := tomlString(value[i:]); ok {
+				out = append(out, s)
+				i += strings.IndexByte(value[i+1:], value[i]) + 1
+			}
+		}
+	}
+	return out
+}
+
+// tomlString reads the quoted string at the start of s.
+func tomlString(s string) (string, bool) {
+	if s == "" || s[0] != '"' && s[0] != '\'' {
+		return "", false
+	}
+	end := strings.IndexByte(s[1:], s[0])
+	if end < 0 {
+		return "", false
+	}
+	return s[1 : 1+end], true
+}
+
+func unquote(s string) string {
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return strings.Trim(s, `'"`)
+}
+
+// first returns the first value of the first non-empty list.
+func first(lists ...[]string) string {
+	for _, l := range lists {
+		if len(l) > 0 {
+			return l[0]
+		}
+	}
+	return ""
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
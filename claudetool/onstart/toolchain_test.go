@@ -0,0 +1,147 @@
+package onstart
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeCodebaseToolchain(t *testing.T) {
+	files := map[string]string{
+		"go.work": "go 1.24\n\nuse (\n\t./api\n\t./tools\n)\n",
+		"api/go.mod": `module example.com/api
+
+go 1.24.0
+
+toolchain go1.24.2
+
+require (
+	github.com/labstack/echo/v4 v4.12.0
+	github.com/stretchr/testify v1.9.0 // indirect
+)
+`,
+		"web/package.json": `{
+  "name": "web",
+  "packageManager": "pnpm@9.1.0+sha512.abc",
+  "engines": {"node": ">=20"},
+  "workspaces": ["packages/*"],
+  "dependencies": {"react": "^18.0.0"},
+  "devDependencies": {"typescript": "^5.4.0", "vitest": "^1.0.0"}
+}`,
+		"svc/pyproject.toml": `[project]
+name = "svc"
+requires-python = ">=3.11"
+dependencies = [
+    "fastapi>=0.110",  # web
+    "SQLAlchemy[asyncio]~=2.0",
+]
+
+[dependency-groups]
+dev = ["pytest>=8"]
+
+[tool.uv]
+dev-dependencies = []
+`,
+		"engine/Cargo.toml": `[package]
+name = "engine"
+edition = "2021"
+rust-version = "1.75"
+
+[dependencies]
+tokio = { version = "1", features = ["full"] }
+serde = "1"
+
+[workspace]
+members = ["crates/*",
+  "cli"]
+`,
+		"Dockerfile":     "FROM golang:1.24 AS build\nRUN go build\nFROM --platform=linux/amd64 gcr.io/distroless/base\nCOPY --from=build /app /app\nFROM build AS test\n",
+		".nvmrc":         "20.11.0\n",
+		".tool-versions": "python 3.12.2\n# comment\n",
+	}
+	c := analyzeFiles(t, files)
+	projects := make(map[string]Project)
+	for _, p := range c.Projects {
+		projects[filepath.Join(p.Dir, p.Manifest)] = p
+	}
+
+	if p := projects["go.work"]; !slices.Equal(p.Workspaces, []string{"./api", "./tools"}) || p.Versions["go"] != "1.24" {
+		t.Errorf("go.work = %+v", p)
+	}
+	if p := projects["api/go.mod"]; p.Name != "example.com/api" || p.Versions["toolchain"] != "go1.24.2" || !slices.Equal(p.Frameworks, []string{"Echo", "testify"}) {
+		t.Errorf("api/go.mod = %+v", p)
+	}
+	if p := projects["web/package.json"]; p.Language != "TypeScript" || p.Versions["pnpm"] != "9.1.0" || p.Versions["node"] != ">=20" ||
+		!slices.Equal(p.Frameworks, []string{"React", "Vitest"}) || !slices.Equal(p.Workspaces, []string{"packages/*"}) {
+		t.Errorf("web/package.json = %+v", p)
+	}
+	if p := projects["svc/pyproject.toml"]; p.Name != "svc" || p.Versions["python"] != ">=3.11" ||
+		!slices.Equal(p.Frameworks, []string{"FastAPI", "SQLAlchemy", "pytest", "uv"}) {
+		t.Errorf("svc/pyproject.toml = %+v", p)
+	}
+	if p := projects["engine/Cargo.toml"]; p.Name != "engine" || p.Versions["rust"] != "1.75" || p.Versions["edition"] != "2021" ||
+		!slices.Equal(p.Frameworks, []string{"Serde", "Tokio"}) || !slices.Equal(p.Workspaces, []string{"crates/*", "cli"}) {
+		t.Errorf("engine/Cargo.toml = %+v", p)
+	}
+	if want := []Dockerfile{{Path: "Dockerfile", Images: []string{"golang:1.24", "gcr.io/distroless/base"}}}; !slices.EqualFunc(c.Dockerfiles, want, func(a, b Dockerfile) bool {
+		return a.Path == b.Path && slices.Equal(a.Images, b.Images)
+	}) {
+		t.Errorf("Dockerfiles = %+v, want %+v", c.Dockerfiles, want)
+	}
+	if c.ToolVersions["node"] != "20.11.0" || c.ToolVersions["python"] != "3.12.2" {
+		t.Errorf("ToolVersions = %v", c.ToolVersions)
+	}
+	// Projects are in git ls-files order.
+	if got := c.Languages(); !slices.Equal(got, []string{"Go", "Rust", "Python", "TypeScript"}) {
+		t.Errorf("Languages() = %v", got)
+	}
+
+	summary := c.ToolchainSummary()
+	for _, want := range []string{
+		`- api/go.mod: Go "example.com/api"; versions: go 1.24.0, toolchain go1.24.2; uses Echo, testify`,
+		"- pinned tool versions: node 20.11.0, python 3.12.2",
+		"- Dockerfile: builds from golang:1.24, gcr.io/distroless/base",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("ToolchainSummary() missing %q:\n%s", want, summary)
+		}
+	}
+}
+
+// analyzeFiles analyzes a new git repository holding files, which maps paths to contents.
+func analyzeFiles(t *testing.T, files map[string]string) *Codebase {
+	t.Helper()
+	c, err := AnalyzeCodebase(context.Background(), writeRepo(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// writeRepo creates a git repository holding files, which maps paths to
+// contents, and returns its directory.
+func writeRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "."}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	return dir
+}
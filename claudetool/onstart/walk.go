@@ -0,0 +1,179 @@
+package onstart
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxWalkFiles caps how many files walkFiles reports, so that analyzing a
+// home directory or a mounted archive stays quick. Larger trees are analyzed
+// from a sample.
+const maxWalkFiles = 50_000
+
+// walkExcludes are directories walkFiles skips wherever they are: version
+// control metadata, dependencies, caches, and build output, which git
+// repositories usually ignore.
+var walkExcludes = []string{
+	".git", ".hg", ".svn", ".jj",
+	"node_modules", "vendor", "bower_components",
+	".venv", "venv", "__pycache__", ".tox", ".mypy_cache", ".pytest_cache", ".ruff_cache",
+	".cache", ".next", ".nuxt", "dist", "build", "target", "coverage",
+}
+
+// ShelleyIgnoreFile is the file in a repository's root that lists paths, in
+// .gitignore syntax, for codebase analysis, keyword search, and guidance-file
+// discovery to leave out, such as generated code and test fixtures.
+const ShelleyIgnoreFile = ".shelleyignore"
+
+// ReadShelleyIgnore returns the rules in root's ShelleyIgnoreFile, if any.
+func ReadShelleyIgnore(root string) IgnoreRules {
+	return readIgnoreFile(filepath.Join(root, ShelleyIgnoreFile))
+}
+
+// isGitWorkTree reports whether dir is in a git working tree.
+func isGitWorkTree(ctx context.Context, dir string) bool {
+	return exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run() == nil
+}
+
+// walkFiles calls fn with the path, relative to root and slash-separated, of
+// each regular file under root, stopping after maxWalkFiles. It is the
+// fallback for directories that aren't in a git repository. It skips
+// walkExcludes and files matched by root's .gitignore or ShelleyIgnoreFile,
+// and doesn't follow symbolic links.
+func walkFiles(ctx context.Context, root string, fn func(path string)) error {
+	ignore := append(readIgnoreFile(filepath.Join(root, ".gitignore")), ReadShelleyIgnore(root)...)
+	n := 0
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil // unreadable directories are skipped
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			for _, ex := range walkExcludes {
+				if d.Name() == ex {
+					return filepath.SkipDir
+				}
+			}
+			if ignore.Match(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || ignore.Match(rel, false) {
+			return nil
+		}
+		fn(rel)
+		if n++; n >= maxWalkFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+}
+
+// IgnoreRules are .gitignore-style patterns. Later patterns win, and ones
+// starting with ! re-include paths.
+type IgnoreRules []ignoreRule
+
+type ignoreRule struct {
+	pattern  string // path.Match pattern
+	negate   bool   // !pattern
+	dirOnly  bool   // pattern/
+	anchored bool   // matched against the whole path, not just its last elements
+}
+
+// readIgnoreFile reads the ignore rules in the file at name, if it exists.
+func readIgnoreFile(name string) IgnoreRules {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+	return parseIgnoreRules(string(data))
+}
+
+// parseIgnoreRules parses .gitignore-style rules, one per line.
+func parseIgnoreRules(data string) IgnoreRules {
+	var rules IgnoreRules
+	for line := range strings.Lines(data) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var r ignoreRule
+		if r.negate = strings.HasPrefix(line, "!"); r.negate {
+			line = line[1:]
+		}
+		if r.dirOnly = strings.HasSuffix(line, "/"); r.dirOnly {
+			line = strings.TrimRight(line, "/")
+		}
+		line, r.anchored = strings.CutPrefix(line, "/")
+		r.anchored = r.anchored || strings.Contains(line, "/")
+		if rest, ok := strings.CutPrefix(line, "**/"); ok {
+			line, r.anchored = rest, false
+		}
+		if line == "" {
+			continue
+		}
+		r.pattern = line
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// Match reports whether the rules exclude p, a slash-separated path relative
+// to the directory holding the rules.
+func (rules IgnoreRules) Match(p string, isDir bool) bool {
+	ignored := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir || ignored == !r.negate {
+			continue
+		}
+		if r.matches(p) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// Excludes reports whether the rules exclude p, a slash-separated file path
+// relative to the directory holding the rules, or a directory containing it.
+func (rules IgnoreRules) Excludes(p string) bool {
+	if len(rules) == 0 {
+		return false
+	}
+	for i := range len(p) {
+		if p[i] == '/' && rules.Match(p[:i], true) {
+			return true
+		}
+	}
+	return rules.Match(p, false)
+}
+
+func (r ignoreRule) matches(p string) bool {
+	if r.anchored {
+		ok, _ := path.Match(r.pattern, p)
+		return ok
+	}
+	// Unanchored patterns match the last elements of p.
+	n := strings.Count(r.pattern, "/") + 1
+	elems := strings.Split(p, "/")
+	if len(elems) < n {
+		return false
+	}
+	ok, _ := path.Match(r.pattern, strings.Join(elems[len(elems)-n:], "/"))
+	return ok
+}
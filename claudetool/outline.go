@@ -0,0 +1,219 @@
+package claudetool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"shelley.exe.dev/llm"
+)
+
+// OutlineTool lists the symbols of a file or directory with line numbers,
+// so the model can find its way around large files without reading them.
+type OutlineTool struct {
+	// WorkingDir is the shared mutable working directory.
+	WorkingDir *MutableWorkingDir
+}
+
+const (
+	outlineName        = "outline"
+	outlineDescription = `List the functions, types, methods, and other top-level symbols of a file or directory, with line numbers.
+
+Use this to orient in a large file or package before reading specific line ranges.
+Go is parsed exactly (signatures included); Python, JavaScript/TypeScript, and Rust are outlined from declaration lines.
+For a directory, every supported file directly in it is outlined (Go test files only with include_tests).
+`
+	outlineInputSchema = `{
+  "type": "object",
+  "required": ["path"],
+  "properties": {
+    "path": {
+      "type": "string",
+      "description": "File or directory, absolute or relative to the working directory"
+    },
+    "include_tests": {
+      "type": "boolean",
+      "description": "Include Go _test.go files when outlining a directory"
+    }
+  }
+}`
+)
+
+// outlineMaxBytes caps the outline returned for a directory.
+const outlineMaxBytes = 64 * 1024
+
+type outlineInput struct {
+	Path         string `json:"path"`
+	IncludeTests bool   `json:"include_tests"`
+}
+
+var (
+	outlineJS = regexp.MustCompile(`^(\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?(?:function\*?\s+\w+|class\s+\w+|(?:const|let)\s+\w+\s*=\s*(?:async\s+)?(?:\([^)]*\)|\w+)\s*=>)).*$`)
+	outlineTS = regexp.MustCompile(`^(\s*(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?(?:async\s+)?(?:function\*?\s+\w+|class\s+\w+|interface\s+\w+|type\s+\w+|enum\s+\w+|(?:const|let)\s+\w+\s*=\s*(?:async\s+)?(?:\([^)]*\)|\w+)\s*=>)).*$`)
+)
+
+// outlinePatterns match declaration lines in languages outlined without a
+// parser. The first submatch is the declaration to show.
+var outlinePatterns = map[string]*regexp.Regexp{
+	".py":  regexp.MustCompile(`^(\s*(?:async\s+)?(?:def|class)\s+\w+.*?):?\s*$`),
+	".js":  outlineJS,
+	".jsx": outlineJS,
+	".ts":  outlineTS,
+	".tsx": outlineTS,
+	".rs":  regexp.MustCompile(`^(\s*(?:pub(?:\([^)]*\))?\s+)?(?:async\s+)?(?:unsafe\s+)?(?:fn|struct|enum|trait|impl|mod|type)\b[^{;]*).*$`),
+}
+
+// Tool returns an llm.Tool for outlining code.
+func (o *OutlineTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        outlineName,
+		Description: outlineDescription,
+		InputSchema: llm.MustSchema(outlineInputSchema),
+		Run:         llm.RunJSON(o.run),
+		ReadOnly:    true,
+	}
+}
+
+func (o *OutlineTool) run(ctx context.Context, req outlineInput) llm.ToolOut {
+	path := req.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(o.WorkingDir.Get(), path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	if !info.IsDir() {
+		out, err := outlineFile(path)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent(out)}
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	var sb strings.Builder
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !outlineSupported(name) || strings.HasSuffix(name, "_test.go") && !req.IncludeTests {
+			continue
+		}
+		out, err := outlineFile(filepath.Join(path, name))
+		if err != nil {
+			fmt.Fprintf(&sb, "%s: %v\n\n", name, err)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s\n%s\n", name, out)
+		if sb.Len() > outlineMaxBytes {
+			sb.WriteString("[outline truncated; outline individual files]\n")
+			break
+		}
+	}
+	if sb.Len() == 0 {
+		return llm.ErrorfToolOut("no supported source files in %s", path)
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(sb.String())}
+}
+
+func outlineSupported(name string) bool {
+	ext := filepath.Ext(name)
+	_, ok := outlinePatterns[ext]
+	return ext == ".go" || ok
+}
+
+// outlineFile returns one line per symbol in path: its line number and
+// declaration.
+func outlineFile(path string) (string, error) {
+	ext := filepath.Ext(path)
+	if ext == ".go" {
+		return outlineGo(path)
+	}
+	re, ok := outlinePatterns[ext]
+	if !ok {
+		return "", fmt.Errorf("outline does not support %s files", ext)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, line := range strings.Split(string(data), "\n") {
+		if m := re.FindStringSubmatch(line); m != nil {
+			fmt.Fprintf(&sb, "%5d: %s\n", i+1, strings.TrimRight(m[1], " \t"))
+		}
+	}
+	return sb.String(), nil
+}
+
+func outlineGo(path string) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%5d: package %s\n", fset.Position(f.Package).Line, f.Name.Name)
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			sig := *d
+			sig.Body = nil
+			sig.Doc = nil
+			fmt.Fprintf(&sb, "%5d: %s\n", fset.Position(d.Pos()).Line, goNode(fset, &sig))
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					fmt.Fprintf(&sb, "%5d: type %s %s\n", fset.Position(s.Pos()).Line, s.Name.Name, goTypeKind(fset, s.Type))
+					if it, ok := s.Type.(*ast.InterfaceType); ok {
+						for _, m := range it.Methods.List {
+							if len(m.Names) > 0 {
+								fmt.Fprintf(&sb, "%5d:   %s%s\n", fset.Position(m.Pos()).Line, m.Names[0].Name, strings.TrimPrefix(goNode(fset, m.Type), "func"))
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					names := make([]string, len(s.Names))
+					for i, n := range s.Names {
+						names[i] = n.Name
+					}
+					fmt.Fprintf(&sb, "%5d: %s %s\n", fset.Position(s.Pos()).Line, d.Tok, strings.Join(names, ", "))
+				}
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// goTypeKind describes a type expression briefly: its kind for composite
+// types, the expression itself otherwise.
+func goTypeKind(fset *token.FileSet, expr ast.Expr) string {
+	switch expr.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	}
+	return goNode(fset, expr)
+}
+
+func goNode(fset *token.FileSet, node any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	// Keep multi-line signatures on one line.
+	s := strings.Join(strings.Fields(buf.String()), " ")
+	return strings.NewReplacer("( ", "(", ", )", ")").Replace(s)
+}
@@ -0,0 +1,76 @@
+package claudetool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutlineGo(t *testing.T) {
+	dir := t.TempDir()
+	src := `package demo
+
+// Server serves.
+type Server struct {
+	addr string
+}
+
+type Handler interface {
+	Handle(ctx context.Context) error
+}
+
+const Version = "1"
+
+func (s *Server) Start(
+	addr string,
+) error {
+	return nil
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "demo.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "demo_test.go"), []byte("package demo\n\nfunc TestX() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tool := &OutlineTool{WorkingDir: NewMutableWorkingDir(dir)}
+	out := tool.run(context.Background(), outlineInput{Path: "."})
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	text := out.LLMContent[0].Text
+	for _, want := range []string{
+		"    1: package demo",
+		"    4: type Server struct",
+		"    8: type Handler interface",
+		"    9:   Handle(ctx context.Context) error",
+		"   12: const Version",
+		"   14: func (s *Server) Start(addr string) error",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "TestX") {
+		t.Errorf("test file outlined without include_tests:\n%s", text)
+	}
+}
+
+func TestOutlinePython(t *testing.T) {
+	dir := t.TempDir()
+	src := "import os\n\nclass Thing(Base):\n    def run(self, x):\n        pass\n\nasync def main():\n    pass\n"
+	if err := os.WriteFile(filepath.Join(dir, "app.py"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tool := &OutlineTool{WorkingDir: NewMutableWorkingDir(dir)}
+	out := tool.run(context.Background(), outlineInput{Path: "app.py"})
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	want := "    3: class Thing(Base)\n    4:     def run(self, x)\n    7: async def main()\n"
+	if got := out.LLMContent[0].Text; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
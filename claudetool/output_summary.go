@@ -0,0 +1,109 @@
+package claudetool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"shelley.exe.dev/llm"
+)
+
+// ToolOutputDir is where tool output too large for its policy is saved when
+// it's summarized, so the model can read it and /api/read can serve it.
+const ToolOutputDir = "/tmp/shelley-tool-output"
+
+// OutputSummarizer condenses the text output of the named tool to at most
+// maxBytes.
+type OutputSummarizer func(ctx context.Context, tool, text string, maxBytes int) (string, error)
+
+// LLMOutputSummarizer returns an OutputSummarizer that asks the first
+// available of PreferredToolModels.
+func LLMOutputSummarizer(provider LLMServiceProvider) OutputSummarizer {
+	return func(ctx context.Context, tool, text string, maxBytes int) (string, error) {
+		var svc llm.Service
+		for _, model := range PreferredToolModels {
+			if s, err := provider.GetService(model); err == nil {
+				svc = s
+				break
+			}
+		}
+		if svc == nil {
+			return "", fmt.Errorf("no model available to summarize tool output")
+		}
+		prompt := fmt.Sprintf(`Summarize the output of the %s tool below for a coding agent, in at most %d bytes.
+Quote every error message, failing test, and warning verbatim, with the file paths and line numbers it gives. Keep the key findings and any result the agent asked for. Say what kind of output was dropped, such as passing tests or progress lines.
+Respond with only the summary.
+
+%s`, tool, maxBytes*9/10, text)
+		resp, err := svc.Do(ctx, &llm.Request{Messages: []llm.Message{llm.UserStringMessage(prompt)}})
+		if err != nil {
+			return "", err
+		}
+		var summary strings.Builder
+		for _, c := range resp.Content {
+			if c.Type == llm.ContentTypeText {
+				summary.WriteString(c.Text)
+			}
+		}
+		if summary.Len() == 0 {
+			return "", fmt.Errorf("empty summary")
+		}
+		return summary.String(), nil
+	}
+}
+
+// summarizeToolOut replaces the text of out, if it's over limit bytes, with
+// a summary, saving the full text to ToolOutputDir. It truncates out instead
+// if the summary fails.
+func summarizeToolOut(ctx context.Context, out llm.ToolOut, name string, limit int, summarize OutputSummarizer) llm.ToolOut {
+	if out.Error != nil {
+		return truncateToolOut(out, limit)
+	}
+	var texts []string
+	var rest []llm.Content
+	size := 0
+	for _, c := range out.LLMContent {
+		if c.Type == llm.ContentTypeText {
+			texts = append(texts, c.Text)
+			size += len(c.Text)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	if size <= limit {
+		return out
+	}
+	text := strings.Join(texts, "\n")
+	path, err := saveToolOutput(text)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to save tool output", "tool", name, "error", err)
+		return truncateToolOut(out, limit)
+	}
+	note := fmt.Sprintf("[output too large (%s), summarized below; the full output is saved to: %s]\n\n", humanizeBytes(size), path)
+	summary, err := summarize(ctx, name, text, limit-len(note))
+	if err != nil {
+		slog.WarnContext(ctx, "failed to summarize tool output", "tool", name, "error", err)
+		os.Remove(path)
+		return truncateToolOut(out, limit)
+	}
+	out.LLMContent = append([]llm.Content{{Type: llm.ContentTypeText, Text: note + truncateHeadTail(summary, max(0, limit-len(note)))}}, rest...)
+	return out
+}
+
+// saveToolOutput writes text to a new file in ToolOutputDir and returns its
+// path.
+func saveToolOutput(text string) (string, error) {
+	if err := os.MkdirAll(ToolOutputDir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(ToolOutputDir, uuid.New().String()+".txt")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -2,21 +2,25 @@ package claudetool
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"go/parser"
 	"go/token"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/diff"
+	"shelley.exe.dev/claudetool/editbuf"
+	"shelley.exe.dev/claudetool/patchkit"
 	"shelley.exe.dev/llm"
-	"sketch.dev/claudetool/editbuf"
-	"sketch.dev/claudetool/patchkit"
 )
 
 // PatchCallback defines the signature for patch tool callbacks.
@@ -39,8 +43,16 @@ type PatchTool struct {
 	// NB: The actual implementation of the patch tool is unchanged,
 	// this flag merely extends the description and input schema to include the clipboard operations.
 	ClipboardEnabled bool
+	// CheckSyntax refuses patches that leave a file with syntax errors
+	// it didn't have before (see patchkit.CheckSyntax).
+	CheckSyntax bool
+	// Remote, if set, is the machine whose files are patched.
+	Remote Remote
 	// clipboards stores clipboard name -> text
 	clipboards map[string]string
+	// written stores path -> hash of the contents last written there,
+	// to tell the model when someone else has changed a file since.
+	written map[string][sha256.Size]byte
 }
 
 // getWorkingDir returns the current working directory.
@@ -77,6 +89,9 @@ Operations:
 - append_eof: Append new text at the end of the file
 - prepend_bof: Insert new text at the beginning of the file
 - overwrite: Replace the entire file with new content (automatically creates the file)
+- unified_diff: Apply the unified diff in newText; hunks are found by their context, so line numbers may be approximate
+- replace_function: Replace the whole declaration of the Go or Python function or method named by symbol with newText,
+  without quoting the old one; the doc comment or decorators are kept unless newText has its own
 `
 
 	PatchClipboardDescription = `
@@ -101,8 +116,12 @@ Recipes:
 
 	PatchUsageNotes = `
 Usage notes:
+- Set dry_run to get the diff the patches would make without changing the file
+- A patch may name its own path to edit several files in one call;
+  then either every patch is applied or no file is changed
 - All inputs are interpreted literally (no automatic newline or whitespace handling)
-- For replace operations, oldText must appear EXACTLY ONCE in the file
+- For replace operations, oldText must appear EXACTLY ONCE in the file,
+  unless occurrence and/or anchor say which appearance to replace
 
 IMPORTANT: Each patch call must be less than 60k tokens total. For large file
 changes, break them into multiple smaller patch operations rather than one
@@ -119,6 +138,10 @@ large overwrite. Prefer incremental replace operations over full file overwrites
       "type": "string",
       "description": "Path to the file to patch"
     },
+    "dry_run": {
+      "type": "boolean",
+      "description": "Report the diff the patches would make without writing the file"
+    },
     "patches": {
       "type": "array",
       "description": "List of patch requests to apply",
@@ -128,9 +151,13 @@ large overwrite. Prefer incremental replace operations over full file overwrites
         "properties": {
           "operation": {
             "type": "string",
-            "enum": ["replace", "append_eof", "prepend_bof", "overwrite"],
+            "enum": ["replace", "append_eof", "prepend_bof", "overwrite", "unified_diff", "replace_function"],
             "description": "Type of operation to perform"
           },
+          "path": {
+            "type": "string",
+            "description": "File to apply this patch to, if not the top-level path"
+          },
           "oldText": {
             "type": "string",
             "description": "Text to locate for the operation (must be unique in file, required for replace)"
@@ -138,6 +165,18 @@ large overwrite. Prefer incremental replace operations over full file overwrites
           "newText": {
             "type": "string",
             "description": "The new text to use (empty for deletions)"
+          },
+          "occurrence": {
+            "type": "integer",
+            "description": "For replace, when oldText appears more than once: which occurrence to replace (1 is the first), counted from anchor if given"
+          },
+          "anchor": {
+            "type": "string",
+            "description": "For replace, when oldText appears more than once: unique text at or before the intended occurrence"
+          },
+          "symbol": {
+            "type": "string",
+            "description": "For replace_function: the function's name, or Type.Name for a method"
           }
         }
       }
@@ -154,13 +193,17 @@ large overwrite. Prefer incremental replace operations over full file overwrites
       "type": "string",
       "description": "Path to the file to patch"
     },
+    "dry_run": {
+      "type": "boolean",
+      "description": "Report the diff the patches would make without writing the file"
+    },
     "patch": {
       "type": "object",
       "required": ["operation", "newText"],
       "properties": {
         "operation": {
           "type": "string",
-          "enum": ["replace", "append_eof", "prepend_bof", "overwrite"],
+          "enum": ["replace", "append_eof", "prepend_bof", "overwrite", "unified_diff", "replace_function"],
           "description": "Type of operation to perform"
         },
         "oldText": {
@@ -170,6 +213,18 @@ large overwrite. Prefer incremental replace operations over full file overwrites
         "newText": {
           "type": "string",
           "description": "The new text to use (empty for deletions)"
+        },
+        "occurrence": {
+          "type": "integer",
+          "description": "For replace, when oldText appears more than once: which occurrence to replace (1 is the first), counted from anchor if given"
+        },
+        "anchor": {
+          "type": "string",
+          "description": "For replace, when oldText appears more than once: unique text at or before the intended occurrence"
+        },
+        "symbol": {
+          "type": "string",
+          "description": "For replace_function: the function's name, or Type.Name for a method"
         }
       }
     }
@@ -185,6 +240,10 @@ large overwrite. Prefer incremental replace operations over full file overwrites
       "type": "string",
       "description": "Path to the file to patch"
     },
+    "dry_run": {
+      "type": "boolean",
+      "description": "Report the diff the patches would make without writing the file"
+    },
     "patches": {
       "type": "array",
       "description": "List of patch requests to apply",
@@ -194,9 +253,13 @@ large overwrite. Prefer incremental replace operations over full file overwrites
         "properties": {
           "operation": {
             "type": "string",
-            "enum": ["replace", "append_eof", "prepend_bof", "overwrite"],
+            "enum": ["replace", "append_eof", "prepend_bof", "overwrite", "unified_diff", "replace_function"],
             "description": "Type of operation to perform"
           },
+          "path": {
+            "type": "string",
+            "description": "File to apply this patch to, if not the top-level path"
+          },
           "oldText": {
             "type": "string",
             "description": "Text to locate (must be unique in file, required for replace)"
@@ -213,6 +276,18 @@ large overwrite. Prefer incremental replace operations over full file overwrites
             "type": "string",
             "description": "Use content from this clipboard as newText (overrides newText field)"
           },
+          "occurrence": {
+            "type": "integer",
+            "description": "For replace, when oldText appears more than once: which occurrence to replace (1 is the first), counted from anchor if given"
+          },
+          "anchor": {
+            "type": "string",
+            "description": "For replace, when oldText appears more than once: unique text at or before the intended occurrence"
+          },
+          "symbol": {
+            "type": "string",
+            "description": "For replace_function: the function's name, or Type.Name for a method"
+          },
           "reindent": {
             "type": "object",
             "description": "Modify indentation of the inserted text (newText or fromClipboard) before insertion",
@@ -241,6 +316,7 @@ large overwrite. Prefer incremental replace operations over full file overwrites
 type PatchInput struct {
 	Path    string         `json:"path"`
 	Patches []PatchRequest `json:"patches"`
+	DryRun  bool           `json:"dry_run,omitempty"`
 }
 
 // PatchInputOne is a simplified version of PatchInput for single patch operations.
@@ -262,8 +338,11 @@ type PatchInputOneString struct {
 
 // PatchDisplayData is the structured data sent to the UI for display.
 type PatchDisplayData struct {
-	Path string `json:"path"`
-	Diff string `json:"diff"`
+	Path   string `json:"path"`
+	Diff   string `json:"diff"`
+	DryRun bool   `json:"dryRun,omitempty"`
+	// Paths are the files the patch wrote.
+	Paths []string `json:"paths,omitempty"`
 }
 
 // PatchRequest represents a single patch operation.
@@ -274,6 +353,14 @@ type PatchRequest struct {
 	ToClipboard   string    `json:"toClipboard,omitempty"`
 	FromClipboard string    `json:"fromClipboard,omitempty"`
 	Reindent      *Reindent `json:"reindent,omitempty"`
+	// Occurrence and Anchor pick one of several appearances of OldText:
+	// the Occurrence'th (default 1) at or after the unique text Anchor.
+	Occurrence int    `json:"occurrence,omitempty"`
+	Anchor     string `json:"anchor,omitempty"`
+	// Symbol names the function that replace_function replaces.
+	Symbol string `json:"symbol,omitempty"`
+	// Path is the file to patch, if not PatchInput.Path.
+	Path string `json:"path,omitempty"`
 }
 
 // Reindent represents indentation adjustment configuration.
@@ -291,6 +378,9 @@ func (p *PatchTool) Run(ctx context.Context, m json.RawMessage) llm.ToolOut {
 	if p.clipboards == nil {
 		p.clipboards = make(map[string]string)
 	}
+	if p.written == nil {
+		p.written = make(map[string][sha256.Size]byte)
+	}
 	input, err := p.patchParse(m)
 	var output llm.ToolOut
 	if err != nil {
@@ -309,6 +399,20 @@ func (p *PatchTool) Run(ctx context.Context, m json.RawMessage) llm.ToolOut {
 // LLMs sometimes generate slightly different JSON structures,
 // and we may as well accept such near misses.
 func (p *PatchTool) patchParse(m json.RawMessage) (PatchInput, error) {
+	input, err := p.patchParsePatches(m)
+	if err != nil {
+		return input, err
+	}
+	var flags struct {
+		DryRun bool `json:"dry_run"`
+	}
+	json.Unmarshal(m, &flags) // already known to be a JSON object
+	input.DryRun = flags.DryRun
+	return input, nil
+}
+
+// patchParsePatches parses the path and patches of the input message.
+func (p *PatchTool) patchParsePatches(m json.RawMessage) (PatchInput, error) {
 	var input PatchInput
 	originalErr := json.Unmarshal(m, &input)
 	if originalErr == nil && len(input.Patches) > 0 {
@@ -351,42 +455,203 @@ func (p *PatchTool) patchParse(m json.RawMessage) (PatchInput, error) {
 // patchRun implements the guts of the patch tool.
 // It populates input from m.
 func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut {
-	path := input.Path
-	if !filepath.IsAbs(input.Path) {
-		// Use shared WorkingDir if available, then context, then Pwd fallback
-		pwd := p.getWorkingDir()
-		path = filepath.Join(pwd, input.Path)
-	}
-	input.Path = path
+	input.Path = p.absPath(input.Path)
 	if len(input.Patches) == 0 {
 		return llm.ErrorToolOut(fmt.Errorf("no patches provided"))
 	}
 	// TODO: check whether the file is autogenerated, and if so, require a "force" flag to modify it.
+	if input.DryRun {
+		// A dry run leaves clipboards as they were, too.
+		saved := maps.Clone(p.clipboards)
+		defer func() { p.clipboards = saved }()
+	}
+
+	// Patches may name their own files. Group them by file, in order of
+	// first appearance, and apply all of them or none.
+	var files []*filePatch
+	byPath := make(map[string]*filePatch)
+	for i, patch := range input.Patches {
+		path := input.Path
+		if patch.Path != "" {
+			path = p.absPath(patch.Path)
+		}
+		f, ok := byPath[path]
+		if !ok {
+			f = &filePatch{path: path}
+			byPath[path] = f
+			files = append(files, f)
+		}
+		f.patches = append(f.patches, patch)
+		f.indexes = append(f.indexes, i)
+	}
+	for _, f := range files {
+		if err := p.patchFile(ctx, f); err != nil {
+			if len(files) > 1 {
+				err = fmt.Errorf("%s: %w", f.path, err)
+			}
+			return llm.ErrorToolOut(err)
+		}
+	}
+
+	if input.DryRun {
+		response := new(strings.Builder)
+		fmt.Fprintf(response, "<patches_previewed>all</patches_previewed>\n<note>dry run: no files were changed</note>\n")
+		diff := new(strings.Builder)
+		for _, f := range files {
+			patched, err := f.buf.Bytes()
+			if err != nil {
+				return llm.ErrorToolOut(err)
+			}
+			for _, msg := range f.clipboardsModified {
+				fmt.Fprintln(response, msg)
+			}
+			diff.WriteString(generateUnifiedDiff(f.path, string(f.orig), string(patched)))
+		}
+		fmt.Fprintf(response, "<diff>\n%s</diff>\n", diff)
+		return llm.ToolOut{
+			LLMContent: llm.TextContent(response.String()),
+			Display:    PatchDisplayData{Path: input.Path, Diff: diff.String(), DryRun: true},
+		}
+	}
+
+	var written [][]byte
+	var err error
+	if p.Remote != nil {
+		written, err = p.writeRemote(ctx, files)
+	} else {
+		var tx editbuf.Tx
+		for _, f := range files {
+			if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+				return llm.ErrorfToolOut("failed to create directory %q: %w", filepath.Dir(f.path), err)
+			}
+			tx.Add(f.path, f.buf, 0o600)
+		}
+		written, err = tx.Commit()
+	}
+	var conflict *editbuf.ConflictError
+	switch {
+	case errors.As(err, &conflict):
+		// Someone else wrote the file while we were patching it.
+		// Show the model what they changed, so it can redo its patches.
+		return llm.ErrorfToolOut("conflict: %w; no patches were applied. Changes made to the file in the meantime:\n%s",
+			err, truncateDiff(generateUnifiedDiff(conflict.Path, string(byPath[conflict.Path].orig), string(conflict.Current)), 4<<10))
+	case err != nil:
+		return llm.ErrorfToolOut("failed to write patched contents: %w", err)
+	}
+
+	response := new(strings.Builder)
+	fmt.Fprintf(response, "<patches_applied>all</patches_applied>\n")
+	diff := new(strings.Builder)
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+		p.written[f.path] = sha256.Sum256(written[i])
+		for _, msg := range f.clipboardsModified {
+			fmt.Fprintln(response, msg)
+		}
+		if f.autogenerated {
+			fmt.Fprintf(response, "<warning>%q appears to be autogenerated. Patches were applied anyway.</warning>\n", f.path)
+		}
+		diff.WriteString(generateUnifiedDiff(f.path, string(f.orig), string(written[i])))
+	}
+
+	// Display data for the UI includes the unified diff only.
+	displayData := PatchDisplayData{
+		Path:  input.Path,
+		Diff:  diff.String(),
+		Paths: paths,
+	}
 
-	orig, err := os.ReadFile(input.Path)
+	return llm.ToolOut{
+		LLMContent: llm.TextContent(response.String()),
+		Display:    displayData,
+	}
+}
+
+// writeRemote writes the patched files on the remote machine, checking
+// first that none has changed since it was read. Unlike editbuf.Tx.Commit,
+// it is not atomic: a failed write can leave only some files patched.
+func (p *PatchTool) writeRemote(ctx context.Context, files []*filePatch) ([][]byte, error) {
+	written := make([][]byte, len(files))
+	for i, f := range files {
+		data, err := f.buf.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		written[i] = data
+		current, err := p.Remote.ReadFile(ctx, f.path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if !bytes.Equal(current, f.orig) {
+			return nil, &editbuf.ConflictError{Path: f.path, Current: current}
+		}
+	}
+	for i, f := range files {
+		if err := p.Remote.WriteFile(ctx, f.path, written[i]); err != nil {
+			return nil, err
+		}
+	}
+	return written, nil
+}
+
+// absPath resolves path against the working directory.
+func (p *PatchTool) absPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(p.getWorkingDir(), path)
+}
+
+// filePatch is the part of a patch call that applies to one file.
+type filePatch struct {
+	path    string
+	patches []PatchRequest
+	indexes []int // of patches in the call, for error messages
+
+	// Set by patchFile.
+	orig               []byte
+	buf                *editbuf.Buffer
+	autogenerated      bool
+	clipboardsModified []string
+}
+
+// patchFile reads f's file and queues f's patches in f.buf.
+func (p *PatchTool) patchFile(ctx context.Context, f *filePatch) error {
+	var orig []byte
+	var err error
+	if p.Remote != nil {
+		orig, err = p.Remote.ReadFile(ctx, f.path)
+	} else {
+		orig, err = os.ReadFile(f.path)
+	}
 	// If the file doesn't exist, we can still apply patches
 	// that don't require finding existing text.
 	switch {
 	case errors.Is(err, os.ErrNotExist):
-		for _, patch := range input.Patches {
+		for _, patch := range f.patches {
 			switch patch.Operation {
-			case "prepend_bof", "append_eof", "overwrite":
+			case "prepend_bof", "append_eof", "overwrite", "unified_diff":
 			default:
-				return llm.ErrorfToolOut("file %q does not exist", input.Path)
+				return fmt.Errorf("file %q does not exist", f.path)
 			}
 		}
 	case err != nil:
-		return llm.ErrorfToolOut("failed to read file %q: %w", input.Path, err)
+		return fmt.Errorf("failed to read file %q: %w", f.path, err)
 	}
 
-	likelyGoFile := strings.HasSuffix(input.Path, ".go")
+	lastWritten, ok := p.written[f.path]
+	changedOnDisk := ok && lastWritten != sha256.Sum256(orig)
+
+	likelyGoFile := strings.HasSuffix(f.path, ".go")
 
-	autogenerated := likelyGoFile && IsAutogeneratedGoFile(orig)
+	f.autogenerated = likelyGoFile && IsAutogeneratedGoFile(orig)
 
 	origStr := string(orig)
 	// Process the patches "simultaneously", minimizing them along the way.
 	// Claude generates patches that interact with each other.
 	buf := editbuf.NewBuffer(orig)
+	f.orig, f.buf = orig, buf
 
 	// TODO: is it better to apply the patches that apply cleanly and report on the failures?
 	// or instead have it be all-or-nothing?
@@ -395,7 +660,6 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 	// Also: how do we detect that it's in a cycle?
 	var patchErr error
 
-	var clipboardsModified []string
 	updateToClipboard := func(patch PatchRequest, spec *patchkit.Spec) {
 		if patch.ToClipboard == "" {
 			return
@@ -403,17 +667,18 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 		// Update clipboard with the actual matched text
 		matchedOldText := origStr[spec.Off : spec.Off+spec.Len]
 		p.clipboards[patch.ToClipboard] = matchedOldText
-		clipboardsModified = append(clipboardsModified, fmt.Sprintf(`<clipboard_modified name="%s"><message>clipboard contents altered in order to match uniquely</message><new_contents>%q</new_contents></clipboard_modified>`, patch.ToClipboard, matchedOldText))
+		f.clipboardsModified = append(f.clipboardsModified, fmt.Sprintf(`<clipboard_modified name="%s"><message>clipboard contents altered in order to match uniquely</message><new_contents>%q</new_contents></clipboard_modified>`, patch.ToClipboard, matchedOldText))
 	}
 
-	for i, patch := range input.Patches {
+	for j, patch := range f.patches {
+		i := f.indexes[j]
 		// Process toClipboard first, so that copy works
 		if patch.ToClipboard != "" {
 			if patch.Operation != "replace" {
-				return llm.ErrorfToolOut("toClipboard (%s): can only be used with replace operation", patch.ToClipboard)
+				return fmt.Errorf("toClipboard (%s): can only be used with replace operation", patch.ToClipboard)
 			}
 			if patch.OldText == "" {
-				return llm.ErrorfToolOut("toClipboard (%s): oldText cannot be empty when using toClipboard", patch.ToClipboard)
+				return fmt.Errorf("toClipboard (%s): oldText cannot be empty when using toClipboard", patch.ToClipboard)
 			}
 			p.clipboards[patch.ToClipboard] = patch.OldText
 		}
@@ -423,7 +688,7 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 		if patch.FromClipboard != "" {
 			clipboardText, ok := p.clipboards[patch.FromClipboard]
 			if !ok {
-				return llm.ErrorfToolOut("fromClipboard (%s): no clipboard with that name", patch.FromClipboard)
+				return fmt.Errorf("fromClipboard (%s): no clipboard with that name", patch.FromClipboard)
 			}
 			newText = clipboardText
 		}
@@ -432,7 +697,7 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 		if patch.Reindent != nil {
 			reindentedText, err := reindent(newText, patch.Reindent)
 			if err != nil {
-				return llm.ErrorfToolOut("reindent(%q -> %q): %w", patch.Reindent.Strip, patch.Reindent.Add, err)
+				return fmt.Errorf("reindent(%q -> %q): %w", patch.Reindent.Strip, patch.Reindent.Add, err)
 			}
 			newText = reindentedText
 		}
@@ -446,7 +711,18 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 			buf.Replace(0, len(orig), newText)
 		case "replace":
 			if patch.OldText == "" {
-				return llm.ErrorfToolOut("patch %d: oldText cannot be empty for %s operation", i, patch.Operation)
+				return fmt.Errorf("patch %d: oldText cannot be empty for %s operation", i, patch.Operation)
+			}
+
+			if patch.Occurrence != 0 || patch.Anchor != "" {
+				spec, err := patchkit.Select(origStr, patch.OldText, newText, patch.Anchor, cmp.Or(patch.Occurrence, 1))
+				if err != nil {
+					patchErr = errors.Join(patchErr, err)
+					continue
+				}
+				slog.DebugContext(ctx, "patch_applied", "method", "select")
+				spec.ApplyToEditBuf(buf)
+				continue
 			}
 
 			// Attempt to apply the patch.
@@ -461,7 +737,7 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 				continue
 			case 2:
 				// multiple matches
-				patchErr = errors.Join(patchErr, fmt.Errorf("old text not unique:\n%s", patch.OldText))
+				patchErr = errors.Join(patchErr, fmt.Errorf("old text not unique (%d occurrences; set occurrence or anchor to pick one):\n%s", strings.Count(origStr, patch.OldText), patch.OldText))
 				continue
 			default:
 				slog.ErrorContext(ctx, "unique returned unexpected count", "count", count)
@@ -492,9 +768,9 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 			}
 
 			// Try ignoring semantically insignificant whitespace.
-			spec, ok = patchkit.UniqueGoTokens(origStr, patch.OldText, newText)
+			spec, ok = patchkit.UniqueTokens(f.path, origStr, patch.OldText, newText)
 			if ok {
-				slog.DebugContext(ctx, "patch_applied", "method", "unique_go_tokens")
+				slog.DebugContext(ctx, "patch_applied", "method", "unique_tokens")
 				spec.ApplyToEditBuf(buf)
 				updateToClipboard(patch, spec)
 				continue
@@ -510,55 +786,64 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 				continue
 			}
 
-			// No dice.
-			patchErr = errors.Join(patchErr, fmt.Errorf("old text not found:\n%s", patch.OldText))
+			// No dice. Show the model the nearest thing to what it asked for.
+			notFound := fmt.Sprintf("old text not found:\n%s", patch.OldText)
+			if c, ok := patchkit.Closest(origStr, patch.OldText); ok {
+				attrs := fmt.Sprintf("line=%q", strconv.Itoa(c.Line))
+				if c.Distance >= 0 {
+					attrs += fmt.Sprintf(" edit_distance=%q", strconv.Itoa(c.Distance))
+				}
+				notFound += fmt.Sprintf("\n<closest_match %s>\n%s</closest_match>", attrs, c.Diff)
+			}
+			patchErr = errors.Join(patchErr, errors.New(notFound))
 			continue
+		case "replace_function":
+			if patch.Symbol == "" {
+				return fmt.Errorf("patch %d: symbol cannot be empty for %s operation", i, patch.Operation)
+			}
+			spec, err := patchkit.ReplaceFunc(f.path, origStr, patch.Symbol, newText)
+			if err != nil {
+				patchErr = errors.Join(patchErr, fmt.Errorf("patch %d: %w", i, err))
+				continue
+			}
+			slog.DebugContext(ctx, "patch_applied", "method", "replace_function")
+			spec.ApplyToEditBuf(buf)
+		case "unified_diff":
+			specs, err := patchkit.UnifiedDiff(origStr, newText)
+			if err != nil {
+				patchErr = errors.Join(patchErr, fmt.Errorf("patch %d: %w", i, err))
+				continue
+			}
+			slog.DebugContext(ctx, "patch_applied", "method", "unified_diff")
+			for _, spec := range specs {
+				spec.ApplyToEditBuf(buf)
+			}
 		default:
-			return llm.ErrorfToolOut("unrecognized operation %q", patch.Operation)
+			return fmt.Errorf("unrecognized operation %q", patch.Operation)
 		}
 	}
 
 	if patchErr != nil {
 		errorMsg := patchErr.Error()
-		for _, msg := range clipboardsModified {
+		for _, msg := range f.clipboardsModified {
 			errorMsg += "\n" + msg
 		}
-		return llm.ErrorToolOut(fmt.Errorf("%s", errorMsg))
-	}
-
-	patched, err := buf.Bytes()
-	if err != nil {
-		return llm.ErrorToolOut(err)
-	}
-	if err := os.MkdirAll(filepath.Dir(input.Path), 0o700); err != nil {
-		return llm.ErrorfToolOut("failed to create directory %q: %w", filepath.Dir(input.Path), err)
-	}
-	if err := os.WriteFile(input.Path, patched, 0o600); err != nil {
-		return llm.ErrorfToolOut("failed to write patched contents to file %q: %w", input.Path, err)
-	}
-
-	response := new(strings.Builder)
-	fmt.Fprintf(response, "<patches_applied>all</patches_applied>\n")
-	for _, msg := range clipboardsModified {
-		fmt.Fprintln(response, msg)
-	}
-
-	if autogenerated {
-		fmt.Fprintf(response, "<warning>%q appears to be autogenerated. Patches were applied anyway.</warning>\n", input.Path)
-	}
-
-	diff := generateUnifiedDiff(input.Path, string(orig), string(patched))
-
-	// Display data for the UI includes the unified diff only.
-	displayData := PatchDisplayData{
-		Path: input.Path,
-		Diff: diff,
+		if changedOnDisk {
+			errorMsg += "\n<note>The file has been changed by someone else since your last patch to it. Re-read it before retrying.</note>"
+		}
+		return errors.New(errorMsg)
 	}
 
-	return llm.ToolOut{
-		LLMContent: llm.TextContent(response.String()),
-		Display:    displayData,
+	if p.CheckSyntax {
+		patched, err := buf.Bytes()
+		if err != nil {
+			return err
+		}
+		if err := patchkit.CheckSyntax(ctx, f.path, orig, patched); err != nil {
+			return fmt.Errorf("patches not applied: they would introduce syntax errors, so no files were changed:\n%w", err)
+		}
 	}
+	return nil
 }
 
 // IsAutogeneratedGoFile reports whether a Go file has markers indicating it was autogenerated.
@@ -615,6 +900,15 @@ func generateUnifiedDiff(filePath, original, patched string) string {
 	return buf.String()
 }
 
+// truncateDiff shortens diff to about max bytes, cutting at a line boundary.
+func truncateDiff(diff string, max int) string {
+	if len(diff) <= max {
+		return diff
+	}
+	cut := strings.LastIndexByte(diff[:max], '\n') + 1
+	return diff[:cut] + fmt.Sprintf("... (%d more bytes of diff)\n", len(diff)-cut)
+}
+
 // reindent applies indentation adjustments to text.
 func reindent(text string, adj *Reindent) (string, error) {
 	if adj == nil {
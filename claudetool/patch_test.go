@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -236,6 +237,74 @@ func TestPatchTool_FuzzyMatching(t *testing.T) {
 	}
 }
 
+func TestPatchTool_OccurrenceSelection(t *testing.T) {
+	tempDir := t.TempDir()
+	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir)}
+	ctx := context.Background()
+
+	testFile := filepath.Join(tempDir, "dup.txt")
+	if err := os.WriteFile(testFile, []byte("[a]\nx = 1\n[b]\nx = 1\n[c]\nx = 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	run := func(patches ...PatchRequest) llm.ToolOut {
+		t.Helper()
+		msg, _ := json.Marshal(PatchInput{Path: testFile, Patches: patches})
+		return patch.Run(ctx, msg)
+	}
+
+	result := run(PatchRequest{Operation: "replace", OldText: "x = 1", NewText: "x = 2"})
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "3 occurrences") {
+		t.Fatalf("expected a not unique error counting 3 occurrences, got %v", result.Error)
+	}
+
+	result = run(
+		PatchRequest{Operation: "replace", OldText: "x = 1", NewText: "x = 2", Occurrence: 1},
+		PatchRequest{Operation: "replace", OldText: "x = 1", NewText: "x = 3", Anchor: "[c]"},
+		PatchRequest{Operation: "replace", OldText: "x = 1", NewText: "x = 4", Occurrence: 2, Anchor: "[a]"},
+	)
+	if result.Error != nil {
+		t.Fatalf("selection failed: %v", result.Error)
+	}
+	content, _ := os.ReadFile(testFile)
+	if want := "[a]\nx = 2\n[b]\nx = 4\n[c]\nx = 3\n"; string(content) != want {
+		t.Errorf("expected %q, got %q", want, content)
+	}
+}
+
+func TestPatchTool_UnifiedDiff(t *testing.T) {
+	tempDir := t.TempDir()
+	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir)}
+	ctx := context.Background()
+
+	testFile := filepath.Join(tempDir, "diff.txt")
+	if err := os.WriteFile(testFile, []byte("one\ntwo\nthree\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	input := PatchInput{
+		Path: testFile,
+		Patches: []PatchRequest{{
+			Operation: "unified_diff",
+			NewText:   "--- a/diff.txt\n+++ b/diff.txt\n@@ -12,3 +12,3 @@\n one\n-two\n+2\n three\n",
+		}},
+	}
+	msg, _ := json.Marshal(input)
+	result := patch.Run(ctx, msg)
+	if result.Error != nil {
+		t.Fatalf("unified_diff failed: %v", result.Error)
+	}
+	content, _ := os.ReadFile(testFile)
+	if string(content) != "one\n2\nthree\n" {
+		t.Errorf("expected %q, got %q", "one\n2\nthree\n", content)
+	}
+
+	input.Patches[0].NewText = "@@ -1,1 +1,1 @@\n-four\n+4\n"
+	msg, _ = json.Marshal(input)
+	result = patch.Run(ctx, msg)
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "not found") {
+		t.Errorf("expected a not found error, got %v", result.Error)
+	}
+}
+
 func TestPatchTool_ErrorCases(t *testing.T) {
 	tempDir := t.TempDir()
 	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir)}
@@ -297,6 +366,19 @@ func TestPatchTool_ErrorCases(t *testing.T) {
 		t.Error("expected not found error")
 	}
 
+	// Test missing text with a near miss
+	input.Patches = []PatchRequest{{
+		Operation: "replace",
+		OldText:   "duplicat\n",
+		NewText:   "something",
+	}}
+
+	msg, _ = json.Marshal(input)
+	result = patch.Run(ctx, msg)
+	if result.Error == nil || !strings.Contains(result.Error.Error(), `<closest_match line="1" edit_distance="1">`) {
+		t.Errorf("expected closest match in error, got %v", result.Error)
+	}
+
 	// Test invalid clipboard reference
 	input.Patches = []PatchRequest{{
 		Operation:     "append_eof",
@@ -705,3 +787,168 @@ func TestPatchTool_DisplayDataContainsUnifiedDiffOnly(t *testing.T) {
 		t.Fatalf("display payload should not include newContent: %s", string(displayJSON))
 	}
 }
+
+func TestPatchTool_ChangedOnDisk(t *testing.T) {
+	tempDir := t.TempDir()
+	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir)}
+	ctx := context.Background()
+	testFile := filepath.Join(tempDir, "changed.txt")
+	input := PatchInput{
+		Path:    testFile,
+		Patches: []PatchRequest{{Operation: "overwrite", NewText: "alpha\nbeta\n"}},
+	}
+	msg, _ := json.Marshal(input)
+	if result := patch.Run(ctx, msg); result.Error != nil {
+		t.Fatalf("overwrite failed: %v", result.Error)
+	}
+
+	// Someone else edits the file between patches.
+	if err := os.WriteFile(testFile, []byte("alpha\ngamma\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	input.Patches = []PatchRequest{{Operation: "replace", OldText: "beta", NewText: "BETA"}}
+	msg, _ = json.Marshal(input)
+	result := patch.Run(ctx, msg)
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "changed by someone else") {
+		t.Errorf("expected a changed-on-disk note, got %v", result.Error)
+	}
+
+	// Patches against the new contents still apply.
+	input.Patches = []PatchRequest{{Operation: "replace", OldText: "gamma", NewText: "GAMMA"}}
+	msg, _ = json.Marshal(input)
+	if result := patch.Run(ctx, msg); result.Error != nil {
+		t.Errorf("replace failed: %v", result.Error)
+	}
+}
+
+func TestPatchTool_CheckSyntax(t *testing.T) {
+	tempDir := t.TempDir()
+	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir), CheckSyntax: true}
+	ctx := context.Background()
+	testFile := filepath.Join(tempDir, "p.go")
+	orig := "package p\n\nfunc f() {\n}\n"
+	if err := os.WriteFile(testFile, []byte(orig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	input := PatchInput{
+		Path:    testFile,
+		Patches: []PatchRequest{{Operation: "replace", OldText: "func f() {", NewText: "func f( {"}},
+	}
+	msg, _ := json.Marshal(input)
+	result := patch.Run(ctx, msg)
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "syntax errors") {
+		t.Errorf("expected a syntax error, got %v", result.Error)
+	}
+	if content, _ := os.ReadFile(testFile); string(content) != orig {
+		t.Errorf("file changed despite syntax error: %q", content)
+	}
+
+	input.Patches[0].NewText = "func g() {"
+	msg, _ = json.Marshal(input)
+	if result := patch.Run(ctx, msg); result.Error != nil {
+		t.Errorf("valid patch failed: %v", result.Error)
+	}
+}
+
+func TestPatchTool_ReplaceFunction(t *testing.T) {
+	tempDir := t.TempDir()
+	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir)}
+	ctx := context.Background()
+	testFile := filepath.Join(tempDir, "f.go")
+	if err := os.WriteFile(testFile, []byte("package p\n\n// F is f.\nfunc F() int {\n\treturn 1\n}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	input := PatchInput{
+		Path:    testFile,
+		Patches: []PatchRequest{{Operation: "replace_function", Symbol: "F", NewText: "func F() int {\n\treturn 2\n}\n"}},
+	}
+	msg, _ := json.Marshal(input)
+	if result := patch.Run(ctx, msg); result.Error != nil {
+		t.Fatalf("replace_function failed: %v", result.Error)
+	}
+	content, _ := os.ReadFile(testFile)
+	if want := "package p\n\n// F is f.\nfunc F() int {\n\treturn 2\n}\n"; string(content) != want {
+		t.Errorf("expected %q, got %q", want, content)
+	}
+
+	input.Patches[0].Symbol = "G"
+	msg, _ = json.Marshal(input)
+	result := patch.Run(ctx, msg)
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "function G not found") {
+		t.Errorf("expected a not found error, got %v", result.Error)
+	}
+}
+
+func TestPatchTool_DryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir), ClipboardEnabled: true}
+	ctx := context.Background()
+	testFile := filepath.Join(tempDir, "dry.txt")
+	if err := os.WriteFile(testFile, []byte("one\ntwo\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	msg := json.RawMessage(`{"path": "` + testFile + `", "dry_run": true, "patches": {"operation": "replace", "oldText": "two", "newText": "2", "toClipboard": "c"}}`)
+	result := patch.Run(ctx, msg)
+	if result.Error != nil {
+		t.Fatalf("dry run failed: %v", result.Error)
+	}
+	if content, _ := os.ReadFile(testFile); string(content) != "one\ntwo\n" {
+		t.Errorf("dry run changed the file: %q", content)
+	}
+	if _, ok := patch.clipboards["c"]; ok {
+		t.Error("dry run changed the clipboards")
+	}
+	display, ok := result.Display.(PatchDisplayData)
+	if !ok || !display.DryRun || !strings.Contains(display.Diff, "+2") {
+		t.Errorf("unexpected display data: %#v", result.Display)
+	}
+	if text := result.LLMContent[0].Text; !strings.Contains(text, "<patches_previewed>") || !strings.Contains(text, "-two") {
+		t.Errorf("unexpected response: %s", text)
+	}
+}
+
+func TestPatchTool_MultipleFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir)}
+	ctx := context.Background()
+	a, b := filepath.Join(tempDir, "a.txt"), filepath.Join(tempDir, "b.txt")
+	for _, f := range []string{a, b} {
+		if err := os.WriteFile(f, []byte("old name\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	input := PatchInput{
+		Path: a,
+		Patches: []PatchRequest{
+			{Operation: "replace", OldText: "old name", NewText: "new name"},
+			{Operation: "replace", OldText: "missing", NewText: "new name", Path: "b.txt"},
+		},
+	}
+	msg, _ := json.Marshal(input)
+	result := patch.Run(ctx, msg)
+	if result.Error == nil || !strings.Contains(result.Error.Error(), b+": old text not found") {
+		t.Errorf("expected not found error for b.txt, got %v", result.Error)
+	}
+	if content, _ := os.ReadFile(a); string(content) != "old name\n" {
+		t.Errorf("a.txt changed although b.txt's patch failed: %q", content)
+	}
+
+	input.Patches[1].OldText = "old name"
+	msg, _ = json.Marshal(input)
+	result = patch.Run(ctx, msg)
+	if result.Error != nil {
+		t.Fatalf("multi-file patch failed: %v", result.Error)
+	}
+	for _, f := range []string{a, b} {
+		if content, _ := os.ReadFile(f); string(content) != "new name\n" {
+			t.Errorf("%s = %q", f, content)
+		}
+	}
+	display := result.Display.(PatchDisplayData)
+	if !strings.Contains(display.Diff, a) || !strings.Contains(display.Diff, b) {
+		t.Errorf("diff does not cover both files:\n%s", display.Diff)
+	}
+	if !slices.Equal(display.Paths, []string{a, b}) {
+		t.Errorf("paths = %q, want %q", display.Paths, []string{a, b})
+	}
+}
@@ -0,0 +1,113 @@
+package patchkit
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pkg/diff"
+)
+
+// A Candidate is the region of a file most like a needle that could not be found,
+// for telling the model what it got wrong.
+type Candidate struct {
+	Line     int    // 1-based line where the region starts
+	Text     string // the region, as many lines as the needle
+	Distance int    // edit distance from Text to the needle, in runes; -1 for very long needles
+	Diff     string // unified diff from Text to the needle
+}
+
+// maxDistanceLen bounds the size of needles whose candidates are compared
+// by edit distance, which is quadratic. Longer needles get the best
+// candidate by trigram overlap alone, and no distance.
+const maxDistanceLen = 8 << 10
+
+// Closest finds the region of haystack most like needle.
+// Candidates are windows of as many lines as needle has.
+// They are ranked by how many of their trigrams needle shares,
+// and the best few are then compared by edit distance.
+// Closest reports false if no region shares anything with needle.
+func Closest(haystack, needle string) (*Candidate, bool) {
+	haystackLines := slices.Collect(strings.Lines(haystack))
+	n := strings.Count(strings.TrimRight(needle, "\n"), "\n") + 1
+	if len(haystackLines) == 0 || strings.TrimSpace(needle) == "" {
+		return nil, false
+	}
+	n = min(n, len(haystackLines))
+
+	grams := trigrams(needle)
+	// prefix[i] is the trigram score of haystackLines[:i].
+	prefix := make([]int, len(haystackLines)+1)
+	for i, line := range haystackLines {
+		score := 0
+		for g := range trigrams(line) {
+			if grams[g] {
+				score++
+			}
+		}
+		prefix[i+1] = prefix[i] + score
+	}
+	type window struct{ start, score int }
+	var windows []window
+	for start := 0; start+n <= len(haystackLines); start++ {
+		if score := prefix[start+n] - prefix[start]; score > 0 {
+			windows = append(windows, window{start, score})
+		}
+	}
+	if len(windows) == 0 {
+		return nil, false
+	}
+	slices.SortStableFunc(windows, func(a, b window) int { return b.score - a.score })
+
+	text := func(w window) string { return strings.Join(haystackLines[w.start:w.start+n], "") }
+	best, bestDist := windows[0], -1
+	if len(needle) <= maxDistanceLen {
+		for _, w := range windows[:min(len(windows), 5)] {
+			if d := editDistance(text(w), needle); bestDist < 0 || d < bestDist {
+				best, bestDist = w, d
+			}
+		}
+	}
+	c := &Candidate{Line: best.start + 1, Text: text(best), Distance: bestDist}
+	buf := new(strings.Builder)
+	name := fmt.Sprintf("file (line %d)", c.Line)
+	if err := diff.Text(name, "oldText", c.Text, needle, buf); err == nil {
+		c.Diff = buf.String()
+	}
+	return c, true
+}
+
+// trigrams returns the set of three-byte substrings of s, ignoring
+// leading and trailing whitespace on each line.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	for line := range strings.Lines(s) {
+		line = strings.TrimSpace(line)
+		for i := 0; i+3 <= len(line); i++ {
+			set[line[i:i+3]] = true
+		}
+	}
+	return set
+}
+
+// editDistance returns the Levenshtein distance between a and b, in runes.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := range ar {
+		cur[0] = i + 1
+		for j := range br {
+			cost := 1
+			if ar[i] == br[j] {
+				cost = 0
+			}
+			cur[j+1] = min(prev[j+1]+1, cur[j]+1, prev[j]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,57 @@
+package patchkit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClosest(t *testing.T) {
+	haystack := "package p\n\nfunc a() int {\n\treturn 1\n}\n\nfunc b() string {\n\treturn \"b\"\n}\n"
+
+	c, ok := Closest(haystack, "func b() string {\n\treturn \"c\"\n}\n")
+	if !ok {
+		t.Fatal("Closest() found nothing")
+	}
+	if c.Line != 7 {
+		t.Errorf("Line = %d, want 7", c.Line)
+	}
+	if want := "func b() string {\n\treturn \"b\"\n}\n"; c.Text != want {
+		t.Errorf("Text = %q, want %q", c.Text, want)
+	}
+	if c.Distance != 1 {
+		t.Errorf("Distance = %d, want 1", c.Distance)
+	}
+	if !strings.Contains(c.Diff, "-\treturn \"b\"") || !strings.Contains(c.Diff, "+\treturn \"c\"") {
+		t.Errorf("Diff does not show the differing line:\n%s", c.Diff)
+	}
+
+	// Single-line needles are compared by edit distance too.
+	c, ok = Closest(haystack, "func a() int64 {\n")
+	if !ok || c.Line != 3 || c.Distance != 2 {
+		t.Errorf("single line: got %+v, %v; want line 3, distance 2", c, ok)
+	}
+
+	if _, ok := Closest(haystack, "zzzz qqqq"); ok {
+		t.Error("Closest() matched a needle sharing nothing with the file")
+	}
+	if _, ok := Closest("", "func"); ok {
+		t.Error("Closest() matched in an empty file")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package patchkit
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// A funcSpan is where a function declaration is in a file.
+type funcSpan struct {
+	name     string // Name, or Type.Name for methods
+	off, end int    // the declaration, from func or def to its end
+	docOff   int    // the start of its doc comment or decorators; off if none
+}
+
+// ReplaceFunc generates a patch spec replacing the declaration of the function
+// or method named symbol in src, the contents of the file named filename.
+// It saves models from quoting a whole function to replace it.
+// Symbol is Name for functions and Type.Name for methods; a bare Name
+// also matches a method if there is exactly one by that name.
+// Go files are parsed with go/parser; Python files are split into blocks by
+// indentation, and Type is the enclosing class or function.
+// The declaration runs from func or def to its end, keeping the doc comment
+// (Go) or decorators (Python) unless replace starts with new ones.
+// In Python, replace should be indented as the declaration is in the file.
+func ReplaceFunc(filename, src, symbol, replace string) (*Spec, error) {
+	var spans []funcSpan
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".go":
+		spans, err = goFuncSpans(src)
+	case ".py", ".pyi":
+		spans, err = pythonFuncSpans(src)
+	default:
+		return nil, errors.New("function replacement supports Go and Python files only")
+	}
+	if err != nil {
+		return nil, err
+	}
+	span, err := findFunc(spans, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	off := span.off
+	trimmed := strings.TrimSpace(replace)
+	if strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "@") {
+		off = span.docOff
+	}
+	if strings.HasPrefix(replace, " ") || strings.HasPrefix(replace, "\t") {
+		// replace is indented as in the file; take in the line's indentation.
+		off = strings.LastIndexByte(src[:off], '\n') + 1
+	}
+	// The declaration's trailing newline stays in the file.
+	replace = strings.TrimSuffix(replace, "\n")
+	return &Spec{
+		Off: off,
+		Len: span.end - off,
+		Src: src,
+		Old: src[off:span.end],
+		New: replace,
+	}, nil
+}
+
+// findFunc returns the span named symbol.
+func findFunc(spans []funcSpan, symbol string) (funcSpan, error) {
+	var byName []funcSpan
+	for _, s := range spans {
+		if s.name == symbol {
+			return s, nil
+		}
+		if _, name, _ := cutLast(s.name, "."); name == symbol {
+			byName = append(byName, s)
+		}
+	}
+	switch len(byName) {
+	case 0:
+		return funcSpan{}, fmt.Errorf("function %s not found", symbol)
+	case 1:
+		return byName[0], nil
+	}
+	var names []string
+	for _, s := range byName {
+		names = append(names, s.name)
+	}
+	return funcSpan{}, fmt.Errorf("function %s is ambiguous; use one of: %s", symbol, strings.Join(names, ", "))
+}
+
+// cutLast is strings.Cut at the last occurrence of sep.
+// If sep is absent, it returns "", s, false.
+func cutLast(s, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return "", s, false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// goFuncSpans returns the function declarations in src, a Go file.
+func goFuncSpans(src string) ([]funcSpan, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("cannot locate functions in a file that doesn't parse: %w", err)
+	}
+	offset := func(p token.Pos) int { return fset.Position(p).Offset }
+	var spans []funcSpan
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		s := funcSpan{name: fn.Name.Name, off: offset(fn.Pos()), end: offset(fn.End())}
+		s.docOff = s.off
+		if fn.Doc != nil {
+			s.docOff = offset(fn.Doc.Pos())
+		}
+		if fn.Recv != nil && len(fn.Recv.List) == 1 {
+			if recv := goRecvTypeName(fn.Recv.List[0].Type); recv != "" {
+				s.name = recv + "." + s.name
+			}
+		}
+		spans = append(spans, s)
+	}
+	return spans, nil
+}
+
+// goRecvTypeName returns the name of a receiver's type, without pointers or type parameters.
+func goRecvTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// pythonFuncSpans returns the def statements in src, a Python file,
+// named by their enclosing classes and functions.
+func pythonFuncSpans(src string) ([]funcSpan, error) {
+	toks, ok := pythonLexer.lex(src)
+	if !ok {
+		return nil, errors.New("cannot locate functions in a file with an unterminated string")
+	}
+	// A logical line is a run of tokens up to a newline token.
+	// Its indentation is the column of its first token.
+	type line struct {
+		first, last lexToken
+		indent      int
+		words       []string // the first three tokens
+	}
+	var lines []line
+	for i := 0; i < len(toks); {
+		j := i
+		for j < len(toks) && toks[j].text != "\n" {
+			j++
+		}
+		if first := toks[i]; !strings.HasPrefix(first.text, "#") && j > i {
+			l := line{first: first, last: toks[j-1], indent: first.off - (strings.LastIndexByte(src[:first.off], '\n') + 1)}
+			for _, t := range toks[i:min(j, i+3)] {
+				l.words = append(l.words, t.text)
+			}
+			lines = append(lines, l)
+		}
+		i = j + 1
+	}
+
+	type scope struct {
+		name   string
+		indent int
+	}
+	var spans []funcSpan
+	var stack []scope
+	for n, l := range lines {
+		for len(stack) > 0 && stack[len(stack)-1].indent >= l.indent {
+			stack = stack[:len(stack)-1]
+		}
+		words := l.words
+		if len(words) > 0 && words[0] == "async" {
+			words = words[1:]
+		}
+		if len(words) < 2 || words[0] != "def" && words[0] != "class" {
+			continue
+		}
+		var qual []string
+		for _, s := range stack {
+			qual = append(qual, s.name)
+		}
+		name := strings.Join(append(qual, words[1]), ".")
+		stack = append(stack, scope{words[1], l.indent})
+		if words[0] != "def" {
+			continue
+		}
+		s := funcSpan{name: name, off: l.first.off, end: l.last.end, docOff: l.first.off}
+		for _, body := range lines[n+1:] {
+			if body.indent <= l.indent {
+				break
+			}
+			s.end = body.last.end
+		}
+		for d := n - 1; d >= 0 && lines[d].indent == l.indent && lines[d].first.text == "@"; d-- {
+			s.docOff = lines[d].first.off
+		}
+		spans = append(spans, s)
+	}
+	return spans, nil
+}
@@ -0,0 +1,126 @@
+package patchkit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplaceFunc(t *testing.T) {
+	goSrc := `package p
+
+// A does a.
+func A() int {
+	return 1
+}
+
+type T struct{}
+
+// A is a method.
+func (t *T) A() int { return 2 }
+
+func (T) B() {}
+
+func G[E any](e E) {
+	_ = e
+}
+`
+	pySrc := `import os
+
+@cache
+def a():
+    s = """
+not a def
+"""
+    return s
+
+class C:
+    # comment
+    @property
+    def m(self):
+        return 1
+  # dedented comment
+        return 2
+
+    async def n(self):
+        pass
+`
+	tests := []struct {
+		name     string
+		filename string
+		src      string
+		symbol   string
+		replace  string
+		want     string // src with the replacement applied
+		wantErr  string
+	}{
+		{
+			name: "go function keeps doc", filename: "p.go", src: goSrc, symbol: "A",
+			replace: "func A() int {\n\treturn 3\n}\n",
+			want:    strings.Replace(goSrc, "func A() int {\n\treturn 1\n}", "func A() int {\n\treturn 3\n}", 1),
+		},
+		{
+			name: "go method", filename: "p.go", src: goSrc, symbol: "T.A",
+			replace: "func (t *T) A() int { return 4 }",
+			want:    strings.Replace(goSrc, "return 2", "return 4", 1),
+		},
+		{
+			name: "go method by bare name", filename: "p.go", src: goSrc, symbol: "B",
+			replace: "func (T) B() { println() }",
+			want:    strings.Replace(goSrc, "func (T) B() {}", "func (T) B() { println() }", 1),
+		},
+		{
+			name: "go generic function with new doc", filename: "p.go", src: goSrc, symbol: "G",
+			replace: "// G ignores e.\nfunc G[E any](e E) {}\n",
+			want:    strings.Replace(goSrc, "func G[E any](e E) {\n\t_ = e\n}", "// G ignores e.\nfunc G[E any](e E) {}", 1),
+		},
+		{
+			name: "go missing", filename: "p.go", src: goSrc, symbol: "Z",
+			wantErr: "function Z not found",
+		},
+		{
+			name: "python function keeps decorator", filename: "m.py", src: pySrc, symbol: "a",
+			replace: "def a():\n    return 0\n",
+			want:    strings.Replace(pySrc, "def a():\n    s = \"\"\"\nnot a def\n\"\"\"\n    return s", "def a():\n    return 0", 1),
+		},
+		{
+			name: "python method", filename: "m.py", src: pySrc, symbol: "C.m",
+			replace: "    def m(self):\n        return 3\n",
+			want:    strings.Replace(pySrc, "    def m(self):\n        return 1\n  # dedented comment\n        return 2", "    def m(self):\n        return 3", 1),
+		},
+		{
+			name: "python async method with decorator", filename: "m.py", src: pySrc, symbol: "n",
+			replace: "    @staticmethod\n    async def n():\n        pass",
+			want:    strings.Replace(pySrc, "    async def n(self):", "    @staticmethod\n    async def n():", 1),
+		},
+		{
+			name: "unsupported language", filename: "x.rb", src: "def a\nend\n", symbol: "a",
+			wantErr: "Go and Python files only",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ReplaceFunc(tt.filename, tt.src, tt.symbol, tt.replace)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReplaceFunc() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReplaceFunc() error = %v", err)
+			}
+			got := tt.src[:spec.Off] + spec.New + tt.src[spec.Off+spec.Len:]
+			if got != tt.want {
+				t.Errorf("ReplaceFunc() result:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceFuncAmbiguous(t *testing.T) {
+	src := "package p\n\ntype T struct{}\ntype U struct{}\n\nfunc (T) M() {}\nfunc (U) M() {}\n"
+	_, err := ReplaceFunc("p.go", src, "M", "func M() {}")
+	if err == nil || !strings.Contains(err.Error(), "T.M, U.M") {
+		t.Errorf("ReplaceFunc() error = %v, want the candidates listed", err)
+	}
+}
@@ -0,0 +1,270 @@
+package patchkit
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// A lexer splits source code into tokens well enough to compare code while
+// ignoring insignificant whitespace. It is deliberately rough: it only needs
+// to agree with itself on two spellings of the same code, and to keep the
+// whitespace inside strings and comments.
+type lexer struct {
+	lineComment   string // starts a comment running to the end of the line, if any
+	blockComments bool   // /* ... */
+	quotes        string // string delimiters
+	tripleQuotes  bool   // Python """ and ''' strings
+	stringPrefix  string // letters that may prefix a string, such as r and b
+	rustStrings   bool   // raw strings (r#"..."#), char literals vs lifetimes, multi-line strings
+	newlines      bool   // newlines outside brackets are tokens (Python)
+}
+
+var (
+	pythonLexer = &lexer{lineComment: "#", quotes: `"'`, tripleQuotes: true, stringPrefix: "rRbBfFuU", newlines: true}
+	jsLexer     = &lexer{lineComment: "//", blockComments: true, quotes: "\"'`"}
+	rustLexer   = &lexer{lineComment: "//", blockComments: true, quotes: `"'`, stringPrefix: "rb", rustStrings: true}
+	jsonLexer   = &lexer{quotes: `"`}
+)
+
+// lexers maps file extensions to lexers.
+var lexers = map[string]*lexer{
+	".py":   pythonLexer,
+	".pyi":  pythonLexer,
+	".js":   jsLexer,
+	".jsx":  jsLexer,
+	".mjs":  jsLexer,
+	".cjs":  jsLexer,
+	".ts":   jsLexer,
+	".tsx":  jsLexer,
+	".mts":  jsLexer,
+	".cts":  jsLexer,
+	".rs":   rustLexer,
+	".json": jsonLexer,
+}
+
+// A lexToken is a token at src[off:end].
+type lexToken struct {
+	off, end int
+	text     string // what the token is compared by
+}
+
+// UniqueTokens is UniqueGoTokens for the language of the file named filename,
+// chosen by its extension: Python, JavaScript and TypeScript, Rust, and JSON
+// have their own tokenizers; anything else is tokenized as Go.
+// Python newlines are significant, but its indentation within a line is not.
+func UniqueTokens(filename, haystack, needle, replace string) (*Spec, bool) {
+	l, ok := lexers[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return UniqueGoTokens(haystack, needle, replace)
+	}
+	nt, ok := l.lex(needle)
+	if !ok {
+		return nil, false
+	}
+	// The needle may end mid-line; let the whitespace handling below
+	// decide whether the newline is part of the match.
+	for len(nt) > 0 && nt[len(nt)-1].text == "\n" {
+		nt = nt[:len(nt)-1]
+	}
+	if len(nt) == 0 {
+		return nil, false
+	}
+	ht, ok := l.lex(haystack)
+	if !ok {
+		return nil, false
+	}
+	match := lexTokensUniqueMatch(ht, nt)
+	if match == -1 {
+		return nil, false
+	}
+	start, end := ht[match].off, ht[match+len(nt)-1].end
+
+	// Take in the line's indentation and the line's end when the needle
+	// has them, so that the replacement's own indentation and newline
+	// don't double up with the file's.
+	lineStart := strings.LastIndexByte(haystack[:start], '\n') + 1
+	if needle[:nt[0].off] != "" && strings.TrimSpace(haystack[lineStart:start]) == "" {
+		start = lineStart
+	}
+	if strings.Contains(needle[nt[len(nt)-1].end:], "\n") {
+		if nl := strings.IndexByte(haystack[end:], '\n'); nl >= 0 && strings.TrimSpace(haystack[end:end+nl]) == "" {
+			end += nl + 1
+		}
+	}
+
+	spec, count := Unique(haystack, haystack[start:end], replace)
+	if count != 1 {
+		return nil, false
+	}
+	return spec, true
+}
+
+// lexTokensUniqueMatch returns the index of the unique occurrence of needle in haystack, or -1.
+func lexTokensUniqueMatch(haystack, needle []lexToken) int {
+	same := func(a, b lexToken) bool { return a.text == b.text }
+	match := -1
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if !slices.EqualFunc(haystack[i:i+len(needle)], needle, same) {
+			continue
+		}
+		if match != -1 {
+			return -1 // multiple matches
+		}
+		match = i
+	}
+	return match
+}
+
+// lex tokenizes src. It reports false if src has an unterminated string
+// or comment, which includes needles that start or end inside one.
+func (l *lexer) lex(src string) ([]lexToken, bool) {
+	var toks []lexToken
+	add := func(off, end int) {
+		toks = append(toks, lexToken{off: off, end: end, text: src[off:end]})
+	}
+	depth := 0 // bracket nesting, for Python newlines
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			if l.newlines && depth == 0 && len(toks) > 0 && toks[len(toks)-1].text != "\n" {
+				add(i, i+1)
+			}
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case c == '\\' && l.newlines && strings.HasPrefix(src[i+1:], "\n"):
+			i += 2 // Python line continuation
+		case l.lineComment != "" && strings.HasPrefix(src[i:], l.lineComment):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			toks = append(toks, lexToken{off: i, end: i + end, text: strings.TrimRight(src[i:i+end], " \t\r")})
+			i += end
+		case l.blockComments && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, false
+			}
+			add(i, i+2+end+2)
+			i += 2 + end + 2
+		case strings.IndexByte(l.quotes, c) >= 0:
+			if c == '\'' && l.rustStrings && !isRustChar(src[i:]) {
+				add(i, i+1) // a lifetime
+				i++
+				continue
+			}
+			end, ok := l.scanString(src, i, false)
+			if !ok {
+				return nil, false
+			}
+			add(i, end)
+			i = end
+		case isWordByte(c):
+			j := i
+			for j < len(src) && isWordByte(src[j]) {
+				j++
+			}
+			if l.isStringPrefix(src[i:j]) && j < len(src) && (strings.IndexByte(l.quotes, src[j]) >= 0 || l.rustStrings && src[j] == '#') {
+				raw := strings.ContainsAny(src[i:j], "rR")
+				end, ok := l.scanString(src, j, raw)
+				switch {
+				case ok:
+					j = end
+				case src[j] != '#': // r#ident is a Rust raw identifier, not a string
+					return nil, false
+				}
+			}
+			add(i, j)
+			i = j
+		default:
+			switch c {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				depth = max(0, depth-1)
+			}
+			_, size := utf8.DecodeRuneInString(src[i:])
+			add(i, i+size)
+			i += size
+		}
+	}
+	return toks, true
+}
+
+// isStringPrefix reports whether word can prefix a string literal, like Python's rb or Rust's br.
+func (l *lexer) isStringPrefix(word string) bool {
+	if l.stringPrefix == "" || len(word) > 2 {
+		return false
+	}
+	for _, r := range word {
+		if !strings.ContainsRune(l.stringPrefix, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanString returns the end of the string literal starting at src[i],
+// which is a quote, or a # for Rust raw strings.
+func (l *lexer) scanString(src string, i int, raw bool) (int, bool) {
+	if l.rustStrings && raw {
+		hashes := 0
+		for i+hashes < len(src) && src[i+hashes] == '#' {
+			hashes++
+		}
+		if i+hashes >= len(src) || src[i+hashes] != '"' {
+			return 0, false
+		}
+		closing := `"` + strings.Repeat("#", hashes)
+		end := strings.Index(src[i+hashes+1:], closing)
+		if end < 0 {
+			return 0, false
+		}
+		return i + hashes + 1 + end + len(closing), true
+	}
+	q := src[i]
+	if l.tripleQuotes {
+		if triple := strings.Repeat(string(q), 3); strings.HasPrefix(src[i:], triple) {
+			for j := i + 3; j < len(src); j++ {
+				if src[j] == '\\' {
+					j++
+					continue
+				}
+				if strings.HasPrefix(src[j:], triple) {
+					return j + 3, true
+				}
+			}
+			return 0, false
+		}
+	}
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case q:
+			return j + 1, true
+		case '\n':
+			if q != '`' && !l.rustStrings {
+				return 0, false
+			}
+		}
+	}
+	return 0, false
+}
+
+// isRustChar reports whether s, which starts with a quote, is a char literal rather than a lifetime.
+func isRustChar(s string) bool {
+	if strings.HasPrefix(s, `'\`) {
+		return true
+	}
+	_, size := utf8.DecodeRuneInString(s[1:])
+	return 1+size < len(s) && s[1+size] == '\''
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= utf8.RuneSelf
+}
@@ -0,0 +1,147 @@
+package patchkit
+
+import (
+	"testing"
+
+	"shelley.exe.dev/claudetool/editbuf"
+)
+
+func TestUniqueTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		haystack string
+		needle   string
+		replace  string
+		want     string // "" for no match
+	}{
+		{
+			name:     "python_spacing",
+			filename: "x.py",
+			haystack: "def f(a, b):\n    return a+b\n",
+			needle:   "    return a + b\n",
+			replace:  "    return a - b\n",
+			want:     "def f(a, b):\n    return a - b\n",
+		},
+		{
+			name:     "python_brackets_span_lines",
+			filename: "x.py",
+			haystack: "x = call(\n    1,\n    2,\n)\ny = 3\n",
+			needle:   "x = call(1, 2,)",
+			replace:  "x = call(1, 2, 3)",
+			want:     "x = call(1, 2, 3)\ny = 3\n",
+		},
+		{
+			name:     "python_newlines_significant",
+			filename: "x.py",
+			haystack: "if x:\n    y()\n",
+			needle:   "if x: y()",
+			replace:  "if z: y()",
+		},
+		{
+			name:     "python_string_whitespace_significant",
+			filename: "x.py",
+			haystack: "s = 'a  b'\n",
+			needle:   "s = 'a b'",
+			replace:  "s = 'c'",
+		},
+		{
+			name:     "python_triple_quoted",
+			filename: "x.py",
+			haystack: "def f():\n    \"\"\"Doc\n    string.\"\"\"\n    pass\n",
+			needle:   "def f():\n  \"\"\"Doc\n    string.\"\"\"\n  pass\n",
+			replace:  "def g():\n    pass\n",
+			want:     "def g():\n    pass\n",
+		},
+		{
+			name:     "typescript_reformatted",
+			filename: "x.ts",
+			haystack: "function f(a: number): number {\n\treturn a * 2; // double\n}\n",
+			needle:   "function f(a:number):number{\n  return a*2; // double\n}",
+			replace:  "function f(a: number): number {\n\treturn a * 3;\n}",
+			want:     "function f(a: number): number {\n\treturn a * 3;\n}\n",
+		},
+		{
+			name:     "javascript_template_literal",
+			filename: "x.js",
+			haystack: "const s = `a\n  b`;\n",
+			needle:   "const s = `a\nb`;",
+			replace:  "const s = 1;",
+		},
+		{
+			name:     "rust_lifetimes_and_chars",
+			filename: "x.rs",
+			haystack: "fn f<'a>(s: &'a str) -> char {\n    '\"'\n}\n",
+			needle:   "fn f<'a>(s:&'a str)->char{\n  '\"'\n}",
+			replace:  "fn f(s: &str) -> char {\n    'x'\n}",
+			want:     "fn f(s: &str) -> char {\n    'x'\n}\n",
+		},
+		{
+			name:     "rust_raw_string",
+			filename: "x.rs",
+			haystack: "let s = r#\"a \"  b\"#;\nlet t = 1;\n",
+			needle:   "let s = r#\"a \"  b\"#;\n  let t=1;",
+			replace:  "let t = 2;",
+			want:     "let t = 2;\n",
+		},
+		{
+			name:     "json",
+			filename: "package.json",
+			haystack: "{\n  \"name\": \"x\",\n  \"version\": \"1.0.0\"\n}\n",
+			needle:   "\"name\":\"x\",\n\"version\":\"1.0.0\"",
+			replace:  "\"name\": \"x\",\n  \"version\": \"1.1.0\"",
+			want:     "{\n  \"name\": \"x\",\n  \"version\": \"1.1.0\"\n}\n",
+		},
+		{
+			name:     "not_unique",
+			filename: "x.js",
+			haystack: "f(1);\nf(1);\n",
+			needle:   "f( 1 );",
+			replace:  "g(1);",
+		},
+		{
+			name:     "unterminated_needle",
+			filename: "x.py",
+			haystack: "s = 'abc'\n",
+			needle:   "s = 'ab",
+			replace:  "s = 'x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, ok := UniqueTokens(tt.filename, tt.haystack, tt.needle, tt.replace)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("UniqueTokens() matched %q, want no match", spec.Old)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("UniqueTokens() found no match")
+			}
+			buf := editbuf.NewBuffer([]byte(tt.haystack))
+			spec.ApplyToEditBuf(buf)
+			got, err := buf.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueTokensFallsBackToGo(t *testing.T) {
+	haystack := "package p\n\nvar x = f(a,b)\nvar y = 2\n"
+	want, wantOK := UniqueGoTokens(haystack, "f(a, b)", "g()")
+	for _, name := range []string{"x.go", "Makefile", "x.txt"} {
+		got, ok := UniqueTokens(name, haystack, "f(a, b)", "g()")
+		if ok != wantOK || ok && *got != *want {
+			t.Errorf("UniqueTokens(%q) = %+v, %v; want %+v, %v", name, got, ok, want, wantOK)
+		}
+	}
+	if !wantOK {
+		t.Error("UniqueGoTokens found no match")
+	}
+}
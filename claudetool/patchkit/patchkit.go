@@ -8,7 +8,7 @@ import (
 	"strings"
 	"unicode"
 
-	"sketch.dev/claudetool/editbuf"
+	"shelley.exe.dev/claudetool/editbuf"
 )
 
 // A Spec specifies a single patch.
@@ -40,6 +40,51 @@ func Unique(haystack, needle, replace string) (*Spec, int) {
 	return s, 1
 }
 
+// Select generates a patch spec for one of several occurrences of needle in haystack,
+// for callers that can't make needle unique: the nth (1-based) occurrence
+// at or after the start of anchor, which must itself appear exactly once in haystack.
+// An empty anchor means the start of haystack.
+// Occurrences are counted without overlap, as strings.Count does.
+func Select(haystack, needle, replace, anchor string, n int) (*Spec, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("occurrence must be at least 1, got %d", n)
+	}
+	from := 0
+	if anchor != "" {
+		switch strings.Count(haystack, anchor) {
+		case 0:
+			return nil, fmt.Errorf("anchor not found:\n%s", anchor)
+		case 1:
+			from = strings.Index(haystack, anchor)
+		default:
+			return nil, fmt.Errorf("anchor not unique:\n%s", anchor)
+		}
+	}
+	off := from
+	for i := 1; ; i++ {
+		j := strings.Index(haystack[off:], needle)
+		if j < 0 {
+			where := ""
+			if anchor != "" {
+				where = " after the anchor"
+			}
+			return nil, fmt.Errorf("occurrence %d requested, but old text appears %d time(s)%s", n, i-1, where)
+		}
+		off += j
+		if i == n {
+			break
+		}
+		off += max(len(needle), 1)
+	}
+	return &Spec{
+		Off: off,
+		Len: len(needle),
+		Src: haystack,
+		Old: needle,
+		New: replace,
+	}, nil
+}
+
 // minimize reduces the size of the patch by removing any shared prefix and suffix.
 func (s *Spec) minimize() {
 	pre := commonPrefixLen(s.Old, s.New)
@@ -5,7 +5,7 @@ import (
 	"strings"
 	"testing"
 
-	"sketch.dev/claudetool/editbuf"
+	"shelley.exe.dev/claudetool/editbuf"
 )
 
 func TestUnique(t *testing.T) {
@@ -67,6 +67,43 @@ func TestUnique(t *testing.T) {
 	}
 }
 
+func TestSelect(t *testing.T) {
+	haystack := "func a() {\n\treturn nil\n}\n\nfunc b() {\n\treturn nil\n}\n"
+	tests := []struct {
+		name    string
+		anchor  string
+		n       int
+		wantOff int
+		wantErr string
+	}{
+		{name: "first", n: 1, wantOff: 12},
+		{name: "second", n: 2, wantOff: 38},
+		{name: "too_few", n: 3, wantErr: "appears 2 time(s)"},
+		{name: "anchor", anchor: "func b()", n: 1, wantOff: 38},
+		{name: "anchor_too_few", anchor: "func b()", n: 2, wantErr: "appears 1 time(s) after the anchor"},
+		{name: "anchor_missing", anchor: "func c()", n: 1, wantErr: "anchor not found"},
+		{name: "anchor_not_unique", anchor: "func", n: 1, wantErr: "anchor not unique"},
+		{name: "zero", n: 0, wantErr: "at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := Select(haystack, "return nil", "return err", tt.anchor, tt.n)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Select() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if spec.Off != tt.wantOff || spec.Len != len("return nil") || spec.New != "return err" {
+				t.Errorf("Select() = %+v, want offset %d", spec, tt.wantOff)
+			}
+		})
+	}
+}
+
 func TestSpec_ApplyToEditBuf(t *testing.T) {
 	haystack := "hello world hello"
 	spec, count := Unique(haystack, "world", "universe")
@@ -0,0 +1,126 @@
+package patchkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// A syntaxChecker reports syntax errors in src, the contents of the file
+// named filename. It returns nil if src is valid or can't be checked,
+// for instance because the tool it needs isn't installed.
+type syntaxChecker func(ctx context.Context, filename string, src []byte) error
+
+// syntaxCheckers maps file extensions to syntax checkers.
+var syntaxCheckers = map[string]syntaxChecker{
+	".go":  checkGoSyntax,
+	".py":  checkPythonSyntax,
+	".ts":  checkTypeScriptSyntax,
+	".tsx": checkTypeScriptSyntax,
+	".mts": checkTypeScriptSyntax,
+	".cts": checkTypeScriptSyntax,
+	".js":  checkTypeScriptSyntax,
+	".jsx": checkTypeScriptSyntax,
+	".mjs": checkTypeScriptSyntax,
+	".cjs": checkTypeScriptSyntax,
+}
+
+// syntaxCheckTimeout bounds external syntax checkers.
+// A checker that runs out of time counts as having found nothing.
+const syntaxCheckTimeout = 30 * time.Second
+
+// CheckSyntax reports syntax errors that patching the file named filename
+// from orig to patched would introduce, so that edits that break a file
+// can be refused before the build does. Go is checked with go/parser,
+// Python with python3 -m py_compile, and JavaScript and TypeScript with
+// tsc --noEmit, counting only its syntax errors. Errors that orig already
+// has are not reported, so a broken file can be fixed a step at a time.
+// Files in other languages are not checked.
+func CheckSyntax(ctx context.Context, filename string, orig, patched []byte) error {
+	check, ok := syntaxCheckers[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, syntaxCheckTimeout)
+	defer cancel()
+	err := check(ctx, filename, patched)
+	if err == nil {
+		return nil
+	}
+	if len(orig) > 0 && check(ctx, filename, orig) != nil {
+		return nil
+	}
+	return err
+}
+
+func checkGoSyntax(ctx context.Context, filename string, src []byte) error {
+	_, err := parser.ParseFile(token.NewFileSet(), filepath.Base(filename), src, parser.AllErrors|parser.SkipObjectResolution)
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return err
+	}
+	var msgs []string
+	for i, e := range list {
+		if i == 10 {
+			msgs = append(msgs, fmt.Sprintf("(and %d more errors)", len(list)-i))
+			break
+		}
+		msgs = append(msgs, e.Error())
+	}
+	return errors.New(strings.Join(msgs, "\n"))
+}
+
+func checkPythonSyntax(ctx context.Context, filename string, src []byte) error {
+	if _, err := exec.LookPath("python3"); err != nil {
+		return nil
+	}
+	return runSyntaxChecker(filename, src, func(path string) (string, bool) {
+		out, err := exec.CommandContext(ctx, "python3", "-m", "py_compile", path).CombinedOutput()
+		return string(out), err == nil || ctx.Err() != nil
+	})
+}
+
+// tscSyntaxError matches tsc diagnostics in the TS1xxx range, which are
+// syntax errors. Other diagnostics, such as unresolved imports, are
+// expected when checking a file on its own.
+var tscSyntaxError = regexp.MustCompile(`(?m)^.*\(\d+,\d+\): error TS1\d{3}:.*$`)
+
+func checkTypeScriptSyntax(ctx context.Context, filename string, src []byte) error {
+	if _, err := exec.LookPath("tsc"); err != nil {
+		return nil
+	}
+	return runSyntaxChecker(filename, src, func(path string) (string, bool) {
+		out, _ := exec.CommandContext(ctx, "tsc", "--noEmit", "--pretty", "false", "--allowJs", "--skipLibCheck", "--jsx", "preserve", "--target", "esnext", path).CombinedOutput()
+		errs := tscSyntaxError.FindAllString(string(out), 10)
+		return strings.Join(errs, "\n"), len(errs) == 0 || ctx.Err() != nil
+	})
+}
+
+// runSyntaxChecker writes src to a temporary file with filename's base name
+// and runs check on it. check returns its diagnostics and whether src is ok.
+// Mentions of the temporary file in the diagnostics are replaced with filename.
+func runSyntaxChecker(filename string, src []byte, check func(path string) (string, bool)) error {
+	dir, err := os.MkdirTemp("", "shelley-syntax-")
+	if err != nil {
+		return nil
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, filepath.Base(filename))
+	if err := os.WriteFile(path, src, 0o600); err != nil {
+		return nil
+	}
+	out, ok := check(path)
+	if ok {
+		return nil
+	}
+	return errors.New(strings.TrimSpace(strings.ReplaceAll(out, path, filename)))
+}
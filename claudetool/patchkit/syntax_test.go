@@ -0,0 +1,43 @@
+package patchkit
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCheckSyntax(t *testing.T) {
+	ctx := context.Background()
+	good := []byte("package p\n\nfunc f() {}\n")
+	bad := []byte("package p\n\nfunc f() {\n")
+
+	if err := CheckSyntax(ctx, "p.go", good, bad); err == nil || !strings.Contains(err.Error(), "p.go:3:12") {
+		t.Errorf("broken Go: got %v, want an error at p.go:3:12", err)
+	}
+	if err := CheckSyntax(ctx, "p.go", bad, good); err != nil {
+		t.Errorf("fixed Go: %v", err)
+	}
+	if err := CheckSyntax(ctx, "p.go", bad, append(bad, "}\nfunc g( {\n"...)); err != nil {
+		t.Errorf("already broken Go: %v", err)
+	}
+	if err := CheckSyntax(ctx, "p.txt", good, bad); err != nil {
+		t.Errorf("unchecked language: %v", err)
+	}
+}
+
+func TestCheckSyntaxPython(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	ctx := context.Background()
+	good := []byte("def f():\n    return 1\n")
+	bad := []byte("def f(:\n    return 1\n")
+	err := CheckSyntax(ctx, "/src/mod.py", good, bad)
+	if err == nil || !strings.Contains(err.Error(), "SyntaxError") || !strings.Contains(err.Error(), "/src/mod.py") {
+		t.Errorf("broken Python: got %v, want a SyntaxError naming /src/mod.py", err)
+	}
+	if err := CheckSyntax(ctx, "/src/mod.py", bad, good); err != nil {
+		t.Errorf("fixed Python: %v", err)
+	}
+}
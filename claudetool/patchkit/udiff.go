@@ -0,0 +1,256 @@
+package patchkit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A hunk is one @@ section of a unified diff.
+type hunk struct {
+	header  string // the @@ line, for error messages
+	hasLine bool   // whether the header has line numbers
+	oldLine int    // first old line from the header, 1-based (0 for insertions at the top)
+	lines   []hunkLine
+}
+
+// A hunkLine is a line of a hunk: context (' '), removed ('-'), or added ('+').
+type hunkLine struct {
+	op   byte
+	text string // without the op, including any trailing newline
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+\d+(?:,\d+)? @@`)
+
+// UnifiedDiff generates patch specs for the hunks of a unified diff against haystack.
+// It is for LLMs that express edits as diffs rather than search/replace pairs.
+// Their diffs are rarely exact: line numbers drift, context whitespace differs,
+// and context lines at the edges of a hunk are sometimes wrong.
+// Like patch(1), UnifiedDiff locates each hunk by its content, preferring the match
+// nearest the line its header names (adjusted by the drift of earlier hunks).
+// It compares lines exactly, then ignoring leading and trailing whitespace,
+// then again after dropping up to two context lines from each end of the hunk.
+// The returned specs are in file order and do not overlap.
+func UnifiedDiff(haystack, diff string) ([]*Spec, error) {
+	hunks, err := parseUnifiedDiff(diff)
+	if err != nil {
+		return nil, err
+	}
+	haystackLines := slices.Collect(strings.Lines(haystack))
+	offsets := make([]int, len(haystackLines)+1)
+	for i, line := range haystackLines {
+		offsets[i+1] = offsets[i] + len(line)
+	}
+
+	var specs []*Spec
+	from := 0  // first haystack line later hunks may match
+	drift := 0 // how far the previous hunk was from where its header said
+	for n, h := range hunks {
+		start, lines, err := h.locate(haystackLines, from, drift)
+		if err != nil {
+			return nil, fmt.Errorf("hunk %d (%s): %w", n+1, h.header, err)
+		}
+		if h.hasLine && h.oldLine > 0 {
+			drift = start - (h.oldLine - 1)
+		}
+		end := start
+		newText := new(strings.Builder)
+		eol := "\n"
+		if start < len(haystackLines) && strings.HasSuffix(haystackLines[start], "\r\n") {
+			eol = "\r\n"
+		}
+		if start == len(haystackLines) && start > 0 && !strings.HasSuffix(haystackLines[start-1], "\n") {
+			// Appending to a file that doesn't end in a newline.
+			newText.WriteString(eol)
+		}
+		write := func(line string) {
+			if s := newText.String(); s != "" && !strings.HasSuffix(s, "\n") {
+				newText.WriteString(eol)
+			}
+			newText.WriteString(line)
+		}
+		for _, l := range lines {
+			switch l.op {
+			case ' ':
+				write(haystackLines[end])
+				end++
+			case '-':
+				end++
+			case '+':
+				if eol == "\r\n" && strings.HasSuffix(l.text, "\n") && !strings.HasSuffix(l.text, "\r\n") {
+					l.text = strings.TrimSuffix(l.text, "\n") + eol
+				}
+				write(l.text)
+			}
+		}
+		replacement := newText.String()
+		// Keep a missing newline at the end of the file missing.
+		if end == len(haystackLines) && end > start && !strings.HasSuffix(haystackLines[end-1], "\n") {
+			replacement = strings.TrimRight(replacement, "\r\n")
+		}
+		specs = append(specs, &Spec{
+			Off: offsets[start],
+			Len: offsets[end] - offsets[start],
+			Src: haystack,
+			Old: haystack[offsets[start]:offsets[end]],
+			New: replacement,
+		})
+		from = end
+	}
+	return specs, nil
+}
+
+// parseUnifiedDiff parses the hunks of a unified diff of a single file.
+// File headers are skipped. Lines missing their leading space are taken as
+// context, since models often drop it, especially on blank lines.
+func parseUnifiedDiff(diff string) ([]hunk, error) {
+	lines := slices.Collect(strings.Lines(diff))
+	var hunks []hunk
+	files := 0
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			files++
+			if files > 1 {
+				return nil, errors.New("diff changes more than one file; send one diff per file")
+			}
+			i++
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			h := hunk{header: strings.TrimSpace(line)}
+			if m := hunkHeader.FindStringSubmatch(line); m != nil {
+				h.oldLine, _ = strconv.Atoi(m[1])
+				h.hasLine = true
+			}
+			hunks = append(hunks, h)
+			continue
+		}
+		if len(hunks) == 0 {
+			continue // "diff --git", "index", and other preamble
+		}
+		h := &hunks[len(hunks)-1]
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		switch line[0] {
+		case ' ', '-', '+':
+			h.lines = append(h.lines, hunkLine{op: line[0], text: line[1:]})
+		case '\\':
+			// "\ No newline at end of file" applies to the line before it.
+			if len(h.lines) > 0 {
+				last := &h.lines[len(h.lines)-1]
+				last.text = strings.TrimRight(last.text, "\r\n")
+			}
+		default:
+			if strings.HasPrefix(line, "diff ") || strings.HasPrefix(line, "index ") {
+				continue
+			}
+			h.lines = append(h.lines, hunkLine{op: ' ', text: line})
+		}
+	}
+	if len(hunks) == 0 {
+		return nil, errors.New("no @@ hunks found in diff")
+	}
+	return hunks, nil
+}
+
+// locate finds where h applies in haystackLines, at or after line from.
+// It returns the first matched line and the hunk's lines, less any context
+// dropped to make it match.
+func (h hunk) locate(haystackLines []string, from, drift int) (int, []hunkLine, error) {
+	hint := -1
+	if h.hasLine {
+		hint = max(0, h.oldLine-1+drift)
+	}
+	old := func(lines []hunkLine) []string {
+		var old []string
+		for _, l := range lines {
+			if l.op != '+' {
+				old = append(old, l.text)
+			}
+		}
+		return old
+	}
+	if len(old(h.lines)) == 0 {
+		// Pure insertion: "@@ -N,0 ..." adds after line N.
+		at := max(h.oldLine+drift, from)
+		if !h.hasLine || at > len(haystackLines) {
+			return 0, nil, errors.New("hunk has no context or removed lines to locate it by")
+		}
+		return at, h.lines, nil
+	}
+
+	exact := func(a, b string) bool { return strings.TrimRight(a, "\r\n") == strings.TrimRight(b, "\r\n") }
+	trimmed := func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) }
+	for fuzz := 0; fuzz <= 2; fuzz++ {
+		lines := trimContext(h.lines, fuzz)
+		if lines == nil {
+			break
+		}
+		for _, eq := range []func(a, b string) bool{exact, trimmed} {
+			start, err := matchLines(haystackLines, old(lines), from, hint, eq)
+			if err != nil {
+				return 0, nil, err
+			}
+			if start >= 0 {
+				return start, lines, nil
+			}
+		}
+	}
+	return 0, nil, errors.New("context and removed lines not found in file")
+}
+
+// trimContext drops up to n context lines from each end of lines.
+// It returns nil if that would leave nothing to locate the hunk by,
+// or if there is no context to drop.
+func trimContext(lines []hunkLine, n int) []hunkLine {
+	if n == 0 {
+		return lines
+	}
+	start, end := 0, len(lines)
+	for start < n && start < end && lines[start].op == ' ' {
+		start++
+	}
+	for len(lines)-end < n && end > start && lines[end-1].op == ' ' {
+		end--
+	}
+	if start == 0 && end == len(lines) {
+		return nil
+	}
+	for _, l := range lines[start:end] {
+		if l.op != '+' {
+			return lines[start:end]
+		}
+	}
+	return nil
+}
+
+// matchLines returns the start of the match of needle in haystack at or after
+// line from, or -1 if there is none. Of several matches, it picks the one
+// nearest hint, failing if there is no hint or two are equally near.
+func matchLines(haystack, needle []string, from, hint int, eq func(a, b string) bool) (int, error) {
+	var matches []int
+	for i := from; i+len(needle) <= len(haystack); i++ {
+		if slices.EqualFunc(haystack[i:i+len(needle)], needle, eq) {
+			matches = append(matches, i)
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return -1, nil
+	case len(matches) == 1:
+		return matches[0], nil
+	case hint < 0:
+		return 0, fmt.Errorf("matches %d places; add line numbers to the @@ header or more context", len(matches))
+	}
+	dist := func(i int) int { return max(i-hint, hint-i) }
+	slices.SortStableFunc(matches, func(a, b int) int { return dist(a) - dist(b) })
+	if dist(matches[0]) == dist(matches[1]) {
+		return 0, fmt.Errorf("matches lines %d and %d equally well; add more context", matches[0]+1, matches[1]+1)
+	}
+	return matches[0], nil
+}
@@ -0,0 +1,129 @@
+package patchkit
+
+import (
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/claudetool/editbuf"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	file := "package main\n\nfunc a() {\n\treturn\n}\n\nfunc b() {\n\treturn\n}\n"
+	tests := []struct {
+		name     string
+		haystack string
+		diff     string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "exact",
+			haystack: file,
+			diff:     "--- a/x.go\n+++ b/x.go\n@@ -3,3 +3,4 @@\n func a() {\n+\tprintln(\"a\")\n \treturn\n }\n",
+			want:     "package main\n\nfunc a() {\n\tprintln(\"a\")\n\treturn\n}\n\nfunc b() {\n\treturn\n}\n",
+		},
+		{
+			name:     "line_numbers_drifted",
+			haystack: file,
+			diff:     "@@ -40,3 +40,3 @@\n func b() {\n-\treturn\n+\treturn // b\n }\n",
+			want:     "package main\n\nfunc a() {\n\treturn\n}\n\nfunc b() {\n\treturn // b\n}\n",
+		},
+		{
+			name:     "ambiguous_resolved_by_line_number",
+			haystack: file,
+			diff:     "@@ -8,2 +8,2 @@\n-\treturn\n+\treturn 2\n }\n",
+			want:     "package main\n\nfunc a() {\n\treturn\n}\n\nfunc b() {\n\treturn 2\n}\n",
+		},
+		{
+			name:     "ambiguous_without_line_number",
+			haystack: file,
+			diff:     "@@ @@\n-\treturn\n+\treturn 2\n }\n",
+			wantErr:  "matches 2 places",
+		},
+		{
+			name:     "whitespace_mismatch_keeps_file_context",
+			haystack: file,
+			diff:     "@@ -3,3 +3,3 @@\n func a() {\n-    return\n+\treturn 1\n }\n",
+			want:     "package main\n\nfunc a() {\n\treturn 1\n}\n\nfunc b() {\n\treturn\n}\n",
+		},
+		{
+			name:     "wrong_edge_context",
+			haystack: file,
+			diff:     "@@ -7,3 +7,3 @@\n func bb() {\n-\treturn\n+\treturn 2\n } // end\n",
+			want:     "package main\n\nfunc a() {\n\treturn\n}\n\nfunc b() {\n\treturn 2\n}\n",
+		},
+		{
+			name:     "missing_space_on_blank_context",
+			haystack: file,
+			diff:     "@@ -5,3 +5,4 @@\n }\n\n+// b does nothing.\n func b() {\n",
+			want:     "package main\n\nfunc a() {\n\treturn\n}\n\n// b does nothing.\nfunc b() {\n\treturn\n}\n",
+		},
+		{
+			name:     "multiple_hunks",
+			haystack: file,
+			diff:     "@@ -1,1 +1,1 @@\n-package main\n+package lib\n@@ -7,1 +7,1 @@\n-func b() {\n+func B() {\n",
+			want:     "package lib\n\nfunc a() {\n\treturn\n}\n\nfunc B() {\n\treturn\n}\n",
+		},
+		{
+			name:     "insert_into_empty_file",
+			haystack: "",
+			diff:     "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n",
+			want:     "one\ntwo\n",
+		},
+		{
+			name:     "no_newline_at_eof_preserved",
+			haystack: "a\nb",
+			diff:     "@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+			want:     "a\nc",
+		},
+		{
+			name:     "crlf",
+			haystack: "a\r\nb\r\n",
+			diff:     "@@ -1,2 +1,3 @@\n a\n+x\n b\n",
+			want:     "a\r\nx\r\nb\r\n",
+		},
+		{
+			name:     "not_found",
+			haystack: file,
+			diff:     "@@ -1,1 +1,1 @@\n-package other\n+package lib\n",
+			wantErr:  "hunk 1 (@@ -1,1 +1,1 @@): context and removed lines not found",
+		},
+		{
+			name:     "multiple_files",
+			haystack: file,
+			diff:     "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n--- a/y\n+++ b/y\n@@ -1 +1 @@\n-a\n+b\n",
+			wantErr:  "more than one file",
+		},
+		{
+			name:     "no_hunks",
+			haystack: file,
+			diff:     "just some text\n",
+			wantErr:  "no @@ hunks",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs, err := UnifiedDiff(tt.haystack, tt.diff)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("UnifiedDiff() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnifiedDiff() error = %v", err)
+			}
+			buf := editbuf.NewBuffer([]byte(tt.haystack))
+			for _, spec := range specs {
+				spec.ApplyToEditBuf(buf)
+			}
+			got, err := buf.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package claudetool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"shelley.exe.dev/llm"
+)
+
+// PlanTool lets the model keep a task list that the UI shows as live
+// progress. Each call replaces the whole list, so the latest call's
+// display data is the current plan.
+type PlanTool struct{}
+
+const (
+	planName        = "plan"
+	planDescription = `Maintain the task list for a multi-step task. The user sees it as a checklist that updates live.
+
+Each call replaces the whole list: send every item, in order, with its current status.
+Use it for work with three or more distinct steps. Mark an item in_progress before starting it and completed as soon as it is done; keep only one item in_progress at a time.
+Skip it for simple, single-step requests.
+`
+	planInputSchema = `{
+  "type": "object",
+  "required": ["items"],
+  "properties": {
+    "items": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["content", "status"],
+        "properties": {
+          "content": {
+            "type": "string",
+            "description": "What needs to be done, as a short imperative"
+          },
+          "status": {
+            "type": "string",
+            "enum": ["pending", "in_progress", "completed"]
+          }
+        }
+      }
+    }
+  }
+}`
+)
+
+// PlanItem is one task in a plan.
+type PlanItem struct {
+	Content string `json:"content"`
+	Status  string `json:"status"`
+}
+
+// PlanDisplay is the display data of a plan tool result.
+type PlanDisplay struct {
+	Items []PlanItem `json:"items"`
+}
+
+type planInput struct {
+	Items []PlanItem `json:"items"`
+}
+
+// Tool returns an llm.Tool for maintaining a plan.
+func (p *PlanTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        planName,
+		Description: planDescription,
+		InputSchema: llm.MustSchema(planInputSchema),
+		Run:         llm.RunJSON(p.run),
+	}
+}
+
+func (p *PlanTool) run(ctx context.Context, req planInput) llm.ToolOut {
+	inProgress := 0
+	for i, item := range req.Items {
+		if strings.TrimSpace(item.Content) == "" {
+			return llm.ErrorfToolOut("item %d has no content", i)
+		}
+		switch item.Status {
+		case "pending", "completed":
+		case "in_progress":
+			inProgress++
+		default:
+			return llm.ErrorfToolOut("item %d has unknown status %q", i, item.Status)
+		}
+	}
+	if inProgress > 1 {
+		return llm.ErrorfToolOut("%d items are in_progress; keep at most one", inProgress)
+	}
+	return llm.ToolOut{
+		LLMContent: llm.TextContent(formatPlan(req.Items)),
+		Display:    PlanDisplay{Items: req.Items},
+	}
+}
+
+func formatPlan(items []PlanItem) string {
+	if len(items) == 0 {
+		return "plan cleared"
+	}
+	done := 0
+	var sb strings.Builder
+	for _, item := range items {
+		mark := " "
+		switch item.Status {
+		case "completed":
+			mark = "x"
+			done++
+		case "in_progress":
+			mark = ">"
+		}
+		fmt.Fprintf(&sb, "[%s] %s\n", mark, item.Content)
+	}
+	return fmt.Sprintf("plan updated: %d/%d completed\n%s", done, len(items), sb.String())
+}
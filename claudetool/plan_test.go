@@ -0,0 +1,41 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestPlanTool(t *testing.T) {
+	tool := (&PlanTool{}).Tool()
+	run := func(input string) (string, any, error) {
+		out := tool.Run(context.Background(), json.RawMessage(input))
+		if out.Error != nil {
+			return "", nil, out.Error
+		}
+		return out.LLMContent[0].Text, out.Display, nil
+	}
+
+	text, display, err := run(`{"items":[{"content":"read code","status":"completed"},{"content":"fix bug","status":"in_progress"},{"content":"add test","status":"pending"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "plan updated: 1/3 completed\n[x] read code\n[>] fix bug\n[ ] add test\n"
+	if text != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", text, want)
+	}
+	d, ok := display.(PlanDisplay)
+	if !ok || len(d.Items) != 3 || d.Items[1].Status != "in_progress" {
+		t.Fatalf("unexpected display: %#v", display)
+	}
+
+	for _, bad := range []string{
+		`{"items":[{"content":"a","status":"done"}]}`,
+		`{"items":[{"content":" ","status":"pending"}]}`,
+		`{"items":[{"content":"a","status":"in_progress"},{"content":"b","status":"in_progress"}]}`,
+	} {
+		if _, _, err := run(bad); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
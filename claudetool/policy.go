@@ -0,0 +1,163 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"shelley.exe.dev/llm"
+)
+
+// ToolPolicy bounds how a tool runs. Zero fields are unlimited.
+type ToolPolicy struct {
+	// TimeoutSeconds bounds the wall-clock time of each call, after which
+	// its context is canceled.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// MaxOutputBytes bounds the text returned to the model. Longer output
+	// keeps its head and tail, with a note about what was dropped.
+	MaxOutputBytes int `json:"max_output_bytes,omitempty"`
+	// SummarizeOutput has a cheap model summarize output over
+	// MaxOutputBytes, keeping its errors and key findings, instead of
+	// truncating it. The full output is saved to ToolOutputDir.
+	SummarizeOutput bool `json:"summarize_output,omitempty"`
+	// MaxConcurrent bounds simultaneous calls of the tool within one
+	// conversation; further calls wait.
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+}
+
+// DefaultToolPolicyKey is the ToolPolicies key whose policy applies to every
+// tool without a policy of its own.
+const DefaultToolPolicyKey = "*"
+
+// MergeToolPolicies returns base with override's entries replacing base's,
+// tool by tool.
+func MergeToolPolicies(base, override map[string]ToolPolicy) map[string]ToolPolicy {
+	if len(override) == 0 {
+		return base
+	}
+	merged := maps.Clone(base)
+	if merged == nil {
+		merged = make(map[string]ToolPolicy, len(override))
+	}
+	maps.Copy(merged, override)
+	return merged
+}
+
+// policyFor returns the policy for the named tool.
+func policyFor(policies map[string]ToolPolicy, name string) (ToolPolicy, bool) {
+	if p, ok := policies[name]; ok {
+		return p, true
+	}
+	p, ok := policies[DefaultToolPolicyKey]
+	return p, ok
+}
+
+// applyPolicy returns a copy of t whose Run enforces p. summarize, if not
+// nil, serves p.SummarizeOutput.
+func applyPolicy(t *llm.Tool, p ToolPolicy, summarize OutputSummarizer) *llm.Tool {
+	if t.Run == nil || p == (ToolPolicy{}) {
+		return t
+	}
+	var sem chan struct{}
+	if p.MaxConcurrent > 0 {
+		sem = make(chan struct{}, p.MaxConcurrent)
+	}
+	run := t.Run
+	wrapped := *t
+	wrapped.Run = func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+		release := func() {}
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+				release = func() { <-sem }
+			case <-ctx.Done():
+				return llm.ErrorToolOut(ctx.Err())
+			}
+		}
+		out := runWithTimeout(ctx, run, input, time.Duration(p.TimeoutSeconds)*time.Second, t.Name, release)
+		if p.MaxOutputBytes > 0 {
+			if p.SummarizeOutput && summarize != nil {
+				out = summarizeToolOut(ctx, out, t.Name, p.MaxOutputBytes, summarize)
+			} else {
+				out = truncateToolOut(out, p.MaxOutputBytes)
+			}
+		}
+		return out
+	}
+	return &wrapped
+}
+
+// timeoutGrace is how long a timed-out call has, once its context is
+// canceled, to return before the timeout is reported anyway.
+var timeoutGrace = 10 * time.Second
+
+// runWithTimeout runs run, canceling its context once timeout elapses. A zero
+// timeout means no limit. done is called when run returns, which for a run
+// that ignores its context may be after the timeout is reported: the call
+// keeps its MaxConcurrent slot until then.
+func runWithTimeout(ctx context.Context, run func(context.Context, json.RawMessage) llm.ToolOut, input json.RawMessage, timeout time.Duration, name string, done func()) llm.ToolOut {
+	if timeout <= 0 {
+		defer done()
+		return run(ctx, input)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	result := make(chan llm.ToolOut, 1)
+	go func() {
+		defer done()
+		defer cancel()
+		result <- run(ctx, input)
+	}()
+	var out llm.ToolOut
+	select {
+	case out = <-result:
+	case <-ctx.Done():
+		select {
+		case out = <-result:
+		case <-time.After(timeoutGrace):
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return llm.ErrorfToolOut("%s timed out after %s and is still running", name, timeout)
+			}
+			return llm.ErrorToolOut(ctx.Err())
+		}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return llm.ErrorfToolOut("%s timed out after %s", name, timeout)
+	}
+	return out
+}
+
+// truncateToolOut caps the text of out at limit bytes, keeping head and tail.
+func truncateToolOut(out llm.ToolOut, limit int) llm.ToolOut {
+	if out.Error != nil {
+		if msg := out.Error.Error(); len(msg) > limit {
+			out.Error = errors.New(truncateHeadTail(msg, limit))
+		}
+		return out
+	}
+	budget := limit
+	contents := make([]llm.Content, len(out.LLMContent))
+	for i, c := range out.LLMContent {
+		if c.Type == llm.ContentTypeText {
+			if len(c.Text) > budget {
+				c.Text = truncateHeadTail(c.Text, budget)
+			}
+			budget = max(0, budget-len(c.Text))
+		}
+		contents[i] = c
+	}
+	out.LLMContent = contents
+	return out
+}
+
+// truncateHeadTail shortens s to about n bytes by dropping its middle.
+func truncateHeadTail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	half := n / 2
+	return fmt.Sprintf("%s\n\n[... %d bytes omitted by the output limit; narrow the request to see them ...]\n\n%s",
+		s[:half], len(s)-2*half, s[len(s)-half:])
+}
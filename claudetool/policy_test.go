@@ -0,0 +1,133 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/llm"
+)
+
+func TestPolicyTruncatesOutput(t *testing.T) {
+	tool := &llm.Tool{Name: "big", Run: func(context.Context, json.RawMessage) llm.ToolOut {
+		return llm.ToolOut{LLMContent: llm.TextContent("HEAD" + strings.Repeat("x", 1000) + "TAIL")}
+	}}
+	out := applyPolicy(tool, ToolPolicy{MaxOutputBytes: 100}, nil).Run(context.Background(), nil)
+	text := out.LLMContent[0].Text
+	if !strings.HasPrefix(text, "HEAD") || !strings.HasSuffix(text, "TAIL") {
+		t.Errorf("expected head and tail kept: %q", text)
+	}
+	if !strings.Contains(text, "908 bytes omitted") {
+		t.Errorf("expected omission note: %q", text)
+	}
+}
+
+func TestPolicySummarizesOutput(t *testing.T) {
+	full := "FAIL: TestX\n" + strings.Repeat("ok\n", 500)
+	tool := &llm.Tool{Name: "big", Run: func(context.Context, json.RawMessage) llm.ToolOut {
+		return llm.ToolOut{LLMContent: llm.TextContent(full)}
+	}}
+	var got string
+	summarize := func(ctx context.Context, name, text string, maxBytes int) (string, error) {
+		if name != "big" || maxBytes >= 200 {
+			t.Errorf("summarize(%q, %d)", name, maxBytes)
+		}
+		got = text
+		return "TestX failed", nil
+	}
+	out := applyPolicy(tool, ToolPolicy{MaxOutputBytes: 200, SummarizeOutput: true}, summarize).Run(context.Background(), nil)
+	text := out.LLMContent[0].Text
+	if got != full || !strings.HasSuffix(text, "TestX failed") {
+		t.Fatalf("summarized %q to %q", got, text)
+	}
+	path := text[strings.Index(text, ToolOutputDir):strings.Index(text, "]")]
+	saved, err := os.ReadFile(path)
+	os.Remove(path)
+	if err != nil || string(saved) != full {
+		t.Errorf("saved output: %v", err)
+	}
+
+	failing := func(context.Context, string, string, int) (string, error) { return "", errors.New("no model") }
+	out = applyPolicy(tool, ToolPolicy{MaxOutputBytes: 200, SummarizeOutput: true}, failing).Run(context.Background(), nil)
+	if text := out.LLMContent[0].Text; !strings.Contains(text, "bytes omitted") {
+		t.Errorf("expected truncation when summarizing fails: %q", text)
+	}
+}
+
+func TestPolicyTimeout(t *testing.T) {
+	tool := &llm.Tool{Name: "slow", Run: func(ctx context.Context, _ json.RawMessage) llm.ToolOut {
+		<-ctx.Done()
+		return llm.ErrorToolOut(ctx.Err())
+	}}
+	out := applyPolicy(tool, ToolPolicy{TimeoutSeconds: 1}, nil).Run(context.Background(), nil)
+	if out.Error == nil || out.Error.Error() != "slow timed out after 1s" {
+		t.Errorf("got %v", out.Error)
+	}
+}
+
+func TestPolicyTimeoutHoldsSlot(t *testing.T) {
+	defer func(grace time.Duration) { timeoutGrace = grace }(timeoutGrace)
+	timeoutGrace = 10 * time.Millisecond
+	release := make(chan struct{})
+	calls := 0
+	tool := applyPolicy(&llm.Tool{Name: "stuck", Run: func(context.Context, json.RawMessage) llm.ToolOut {
+		calls++
+		<-release // ignores its context
+		return llm.ToolOut{}
+	}}, ToolPolicy{TimeoutSeconds: 1, MaxConcurrent: 1}, nil)
+
+	out := tool.Run(context.Background(), nil)
+	if out.Error == nil || !strings.Contains(out.Error.Error(), "stuck timed out after 1s and is still running") {
+		t.Errorf("got %v", out.Error)
+	}
+
+	// The stuck call still holds the only slot, so another call can't start.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if out := tool.Run(ctx, nil); out.Error == nil {
+		t.Error("expected second call to wait for the stuck one")
+	}
+	close(release)
+	if out := tool.Run(context.Background(), nil); out.Error != nil || calls != 2 {
+		t.Errorf("after the stuck call returned: %v, %d calls", out.Error, calls)
+	}
+}
+
+func TestPolicyMaxConcurrent(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	tool := applyPolicy(&llm.Tool{Name: "slow", Run: func(context.Context, json.RawMessage) llm.ToolOut {
+		started <- struct{}{}
+		<-release
+		return llm.ToolOut{}
+	}}, ToolPolicy{MaxConcurrent: 1}, nil)
+
+	go tool.Run(context.Background(), nil)
+	<-started
+
+	// A second call must wait for the first; with its context canceled it
+	// gives up instead of starting.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if out := tool.Run(ctx, nil); out.Error == nil {
+		t.Error("expected second call to be blocked")
+	}
+	close(release)
+}
+
+func TestPolicyFor(t *testing.T) {
+	policies := MergeToolPolicies(
+		map[string]ToolPolicy{DefaultToolPolicyKey: {TimeoutSeconds: 60}, "bash": {TimeoutSeconds: 10}},
+		map[string]ToolPolicy{"bash": {MaxOutputBytes: 5}},
+	)
+	if p, _ := policyFor(policies, "bash"); p != (ToolPolicy{MaxOutputBytes: 5}) {
+		t.Errorf("bash: %+v", p)
+	}
+	if p, _ := policyFor(policies, "patch"); p.TimeoutSeconds != 60 {
+		t.Errorf("patch: %+v", p)
+	}
+}
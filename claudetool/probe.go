@@ -0,0 +1,197 @@
+package claudetool
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/llm"
+)
+
+// ProbeTool checks whether a service is up: port, HTTP endpoint, and logs,
+// in one call.
+type ProbeTool struct {
+	// WorkingDir is the shared mutable working directory.
+	WorkingDir *MutableWorkingDir
+}
+
+const (
+	probeName        = "probe"
+	probeDescription = `Check whether a service is up, in one call. Set any combination of:
+- port (and optionally host, default localhost): is a TCP port accepting connections?
+- url: status code and headers of a GET request (redirects are reported, not followed)
+- service: recent systemd journal lines for a unit
+- log_file: last lines of a log file
+
+Use this instead of separate curl/ss/journalctl/tail bash calls when checking a dev server.
+`
+	probeInputSchema = `{
+  "type": "object",
+  "properties": {
+    "host": {
+      "type": "string",
+      "description": "Host for the port check (default localhost)"
+    },
+    "port": {
+      "type": "integer",
+      "description": "TCP port to check"
+    },
+    "url": {
+      "type": "string",
+      "description": "URL to fetch"
+    },
+    "service": {
+      "type": "string",
+      "description": "systemd unit whose journal to tail"
+    },
+    "log_file": {
+      "type": "string",
+      "description": "Log file to tail"
+    },
+    "lines": {
+      "type": "integer",
+      "description": "Log lines to show (default 50, max 500)"
+    }
+  }
+}`
+)
+
+const (
+	probeDialTimeout    = 2 * time.Second
+	probeHTTPTimeout    = 10 * time.Second
+	probeDefaultLines   = 50
+	probeMaxLines       = 500
+	probeMaxLogFileRead = 1024 * 1024
+)
+
+type probeInput struct {
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	URL     string `json:"url"`
+	Service string `json:"service"`
+	LogFile string `json:"log_file"`
+	Lines   int    `json:"lines"`
+}
+
+// Tool returns an llm.Tool for probing services.
+func (p *ProbeTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        probeName,
+		Description: probeDescription,
+		InputSchema: llm.MustSchema(probeInputSchema),
+		Run:         llm.RunJSON(p.run),
+	}
+}
+
+// run reports each requested check. A failing check is a finding, not a
+// tool error, so all checks are reported.
+func (p *ProbeTool) run(ctx context.Context, req probeInput) llm.ToolOut {
+	if req.Port == 0 && req.URL == "" && req.Service == "" && req.LogFile == "" {
+		return llm.ErrorfToolOut("set at least one of port, url, service, or log_file")
+	}
+	lines := min(cmp.Or(req.Lines, probeDefaultLines), probeMaxLines)
+
+	var sections []string
+	if req.Port != 0 {
+		sections = append(sections, probePort(ctx, cmp.Or(req.Host, "localhost"), req.Port))
+	}
+	if req.URL != "" {
+		sections = append(sections, probeURL(ctx, req.URL))
+	}
+	if req.Service != "" {
+		sections = append(sections, probeJournal(ctx, req.Service, lines))
+	}
+	if req.LogFile != "" {
+		path := req.LogFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.WorkingDir.Get(), path)
+		}
+		sections = append(sections, probeLogFile(path, lines))
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(strings.Join(sections, "\n\n"))}
+}
+
+func probePort(ctx context.Context, host string, port int) string {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	d := net.Dialer{Timeout: probeDialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Sprintf("port %s: not listening (%v)", addr, err)
+	}
+	conn.Close()
+	return fmt.Sprintf("port %s: listening", addr)
+}
+
+func probeURL(ctx context.Context, url string) string {
+	ctx, cancel := context.WithTimeout(ctx, probeHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Sprintf("GET %s: %v", url, err)
+	}
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Sprintf("GET %s: %v", url, err)
+	}
+	resp.Body.Close()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "GET %s: %s", url, resp.Status)
+	keys := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		for _, v := range resp.Header[k] {
+			fmt.Fprintf(&sb, "\n%s: %s", k, v)
+		}
+	}
+	return sb.String()
+}
+
+func probeJournal(ctx context.Context, unit string, lines int) string {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, "journalctl", "--no-pager", "-u", unit, "-n", strconv.Itoa(lines))
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Sprintf("journal for %s: %v\n%s", unit, err, out.String())
+	}
+	return fmt.Sprintf("journal for %s:\n%s", unit, strings.TrimRight(out.String(), "\n"))
+}
+
+func probeLogFile(path string, lines int) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Sprintf("log %s: %v", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Sprintf("log %s: %v", path, err)
+	}
+	// Only the end of the file matters; don't read all of a huge log.
+	offset := max(0, info.Size()-probeMaxLogFileRead)
+	buf := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(buf, offset); err != nil {
+		return fmt.Sprintf("log %s: %v", path, err)
+	}
+	all := strings.Split(strings.TrimRight(string(buf), "\n"), "\n")
+	last := all[max(0, len(all)-lines):]
+	return fmt.Sprintf("log %s (last %d lines, modified %s ago):\n%s",
+		path, len(last), time.Since(info.ModTime()).Round(time.Second), strings.Join(last, "\n"))
+}
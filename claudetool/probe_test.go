@@ -0,0 +1,72 @@
+package claudetool
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProbePortAndURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer srv.Close()
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	// Grab a port nobody is listening on.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	tool := &ProbeTool{WorkingDir: NewMutableWorkingDir(t.TempDir())}
+	out := tool.run(context.Background(), probeInput{Host: "127.0.0.1", Port: port, URL: srv.URL})
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	text := out.LLMContent[0].Text
+	if !strings.Contains(text, ": listening") {
+		t.Errorf("expected port to be listening:\n%s", text)
+	}
+	if !strings.Contains(text, "302 Found") || !strings.Contains(text, "Location: /login") {
+		t.Errorf("expected unfollowed redirect:\n%s", text)
+	}
+
+	out = tool.run(context.Background(), probeInput{Host: "127.0.0.1", Port: closedPort})
+	if !strings.Contains(out.LLMContent[0].Text, "not listening") {
+		t.Errorf("expected closed port: %s", out.LLMContent[0].Text)
+	}
+}
+
+func TestProbeLogFile(t *testing.T) {
+	dir := t.TempDir()
+	var sb strings.Builder
+	for i := range 100 {
+		sb.WriteString("line ")
+		sb.WriteString(string(rune('a' + i%26)))
+		sb.WriteByte('\n')
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tool := &ProbeTool{WorkingDir: NewMutableWorkingDir(dir)}
+	out := tool.run(context.Background(), probeInput{LogFile: "app.log", Lines: 3})
+	text := out.LLMContent[0].Text
+	if !strings.Contains(text, "last 3 lines") || !strings.HasSuffix(text, "line t\nline u\nline v") {
+		t.Errorf("unexpected tail:\n%s", text)
+	}
+}
+
+func TestProbeRequiresCheck(t *testing.T) {
+	tool := &ProbeTool{WorkingDir: NewMutableWorkingDir(t.TempDir())}
+	if out := tool.run(context.Background(), probeInput{}); out.Error == nil {
+		t.Error("expected error with no checks")
+	}
+}
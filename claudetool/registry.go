@@ -19,9 +19,20 @@ var ToolRegistry = []ToolInfo{
 	{Name: "shell", Summary: "Run shell commands.", DefaultOn: false},
 	{Name: "patch", Summary: "Precise edits to files.", DefaultOn: true},
 	{Name: "keyword_search", Summary: "Search the codebase by keyword.", DefaultOn: true},
+	{Name: "outline", Summary: "List the symbols of a file or package.", DefaultOn: true},
 	{Name: "change_dir", Summary: "Change the working directory.", DefaultOn: true},
+	{Name: "run_tests", Summary: "Run tests and report failures.", DefaultOn: true},
+	{Name: "diagnostics", Summary: "Run linters and type checkers.", DefaultOn: true},
+	{Name: "probe", Summary: "Check ports, URLs, and service logs.", DefaultOn: true},
+	{Name: "notebook", Summary: "Read, edit, and run Jupyter notebooks.", DefaultOn: true},
+	{Name: "sql", Summary: "Query configured project databases.", DefaultOn: true},
+	{Name: "plan", Summary: "Track a task list shown to the user as live progress.", DefaultOn: true},
+	{Name: "use_skill", Summary: "Load a skill's instructions and files when relevant.", DefaultOn: true},
+	{Name: "scratchpad", Summary: "Keep notes that survive history summarization.", DefaultOn: true},
+	{Name: "remember", Summary: "Record learnings about the repository for future conversations.", DefaultOn: true},
 	{Name: "output_iframe", Summary: "Show HTML/visualizations to the user.", DefaultOn: true},
 	{Name: "subagent", Summary: "Spawn a subagent conversation.", DefaultOn: true},
+	{Name: "handoff", Summary: "Post a message into another conversation.", DefaultOn: true},
 	{Name: "llm_one_shot", Summary: "One-shot prompt to another LLM.", DefaultOn: true},
 	{Name: "browser", Summary: "Browser automation (navigate, eval, screenshot, emulate, network, accessibility, profile).", DefaultOn: true},
 	{Name: "read_image", Summary: "Read an image file for the model.", DefaultOn: true},
@@ -2,6 +2,7 @@ package claudetool
 
 import (
 	"context"
+	"slices"
 	"sort"
 	"testing"
 
@@ -84,3 +85,23 @@ func TestNewToolSetDisableAllTools(t *testing.T) {
 		t.Fatalf("expected only bash, got %v", names)
 	}
 }
+
+func TestNewToolSetModelOverrides(t *testing.T) {
+	ctx := context.Background()
+	ts := NewToolSet(ctx, ToolSetConfig{
+		ModelID:            "reviewer",
+		ModelToolOverrides: map[string]map[string]string{"reviewer": {"bash": "off", "patch": "off"}},
+		ToolOverrides:      map[string]string{"patch": "on"},
+	})
+	defer ts.Cleanup()
+	var names []string
+	for _, tool := range ts.Tools() {
+		names = append(names, tool.Name)
+	}
+	if slices.Contains(names, "bash") {
+		t.Errorf("bash should be off for the model: %v", names)
+	}
+	if !slices.Contains(names, "patch") {
+		t.Errorf("conversation override should re-enable patch: %v", names)
+	}
+}
@@ -0,0 +1,471 @@
+package claudetool
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"shelley.exe.dev/claudetool/onstart"
+	"shelley.exe.dev/llm"
+)
+
+// RunTestsTool runs a project's tests and reports parsed results, keeping
+// passing-test noise out of the model's context.
+type RunTestsTool struct {
+	// WorkingDir is the shared mutable working directory.
+	WorkingDir *MutableWorkingDir
+	// Env is exposed to the test command as SHELLEY_* variables.
+	Env ShelleyEnv
+	// Sandbox, if set, confines the test command.
+	Sandbox Sandbox
+}
+
+const (
+	runTestsName        = "run_tests"
+	runTestsDescription = `Run the project's tests and return pass/fail counts plus the output of failing tests only.
+
+The framework (go, pytest, jest, cargo) is detected from the repository's manifests; pass "framework" to override.
+Prefer this over running test commands with bash: passing tests are summarized instead of dumped.
+
+"target" narrows which tests run:
+- go: package pattern (default ./...)
+- pytest: file, directory, or node id
+- jest: test path pattern
+- cargo: package name (-p)
+
+"filter" selects tests by name: go -run regexp, pytest -k expression, jest -t pattern, cargo name substring.
+`
+	runTestsInputSchema = `{
+  "type": "object",
+  "properties": {
+    "framework": {
+      "type": "string",
+      "enum": ["go", "pytest", "jest", "cargo"],
+      "description": "Test framework; detected from the codebase if omitted"
+    },
+    "target": {
+      "type": "string",
+      "description": "Package, path, or pattern to test"
+    },
+    "filter": {
+      "type": "string",
+      "description": "Only run tests whose names match"
+    }
+  }
+}`
+)
+
+const (
+	// maxFailureOutput caps the output kept for each failing test (the tail is kept).
+	maxFailureOutput = 4 * 1024
+	// maxReportedFailures caps how many failing tests are shown in full.
+	maxReportedFailures = 20
+)
+
+type runTestsInput struct {
+	Framework string `json:"framework"`
+	Target    string `json:"target"`
+	Filter    string `json:"filter"`
+}
+
+// TestFailure is the output of a single failing test.
+type TestFailure struct {
+	Name   string `json:"name"`
+	Output string `json:"output"`
+}
+
+// TestResults is the parsed outcome of a test run.
+type TestResults struct {
+	Framework string        `json:"framework"`
+	Command   string        `json:"command"`
+	Passed    int           `json:"passed"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	Failures  []TestFailure `json:"failures,omitempty"`
+}
+
+// Tool returns an llm.Tool for running tests.
+func (r *RunTestsTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        runTestsName,
+		Description: runTestsDescription,
+		InputSchema: llm.MustSchema(runTestsInputSchema),
+		Run:         llm.RunJSON(r.run),
+	}
+}
+
+func (r *RunTestsTool) run(ctx context.Context, req runTestsInput) llm.ToolOut {
+	wd := r.WorkingDir.Get()
+	framework := req.Framework
+	if framework == "" {
+		cb, err := onstart.AnalyzeCodebase(ctx, wd)
+		if err != nil {
+			return llm.ErrorfToolOut("failed to detect test framework (pass framework explicitly): %w", err)
+		}
+		framework = cb.TestFramework()
+		if framework == "" {
+			for _, cmd := range cb.Commands {
+				if cmd.Kind == "test" {
+					return llm.ErrorfToolOut("no test framework detected in %s; pass framework explicitly, or run %q (from %s) with bash", wd, cmd.Command, cmd.Source)
+				}
+			}
+			return llm.ErrorfToolOut("no test framework detected in %s; pass framework explicitly", wd)
+		}
+	}
+
+	reportDir, err := os.MkdirTemp("", "shelley-tests-")
+	if err != nil {
+		return llm.ErrorfToolOut("failed to create report dir: %w", err)
+	}
+	defer os.RemoveAll(reportDir)
+	reportFile := filepath.Join(reportDir, "report")
+
+	args, err := testCommand(framework, req.Target, req.Filter, reportFile)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, DefaultSlowTimeout)
+	defer cancel()
+	var out bytes.Buffer
+	cmd := (&BashTool{WorkingDir: r.WorkingDir, Env: r.Env, Sandbox: r.Sandbox}).makeArgvCommand(execCtx, args, &out)
+	runErr := cmd.Run()
+	if execCtx.Err() == context.DeadlineExceeded {
+		return llm.ErrorfToolOut("tests timed out after %s\n%s", DefaultSlowTimeout, tail(out.String(), maxFailureOutput))
+	}
+
+	results, err := parseTestResults(framework, out.Bytes(), reportFile)
+	if err != nil {
+		return llm.ErrorfToolOut("failed to parse %s results: %w\n%s", framework, err, tail(out.String(), maxFailureOutput))
+	}
+	results.Framework = framework
+	results.Command = strings.Join(args, " ")
+
+	if runErr != nil && results.Failed == 0 {
+		// The command failed without any failing tests: a build or collection
+		// error. Show the raw output, since that's where the problem is.
+		return llm.ErrorfToolOut("[%s failed: %w]\n%s", results.Command, runErr, tail(out.String(), maxFailureOutput))
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(results.String()), Display: results}
+}
+
+// testCommand returns the argv for running framework's tests. reportFile is
+// where frameworks with machine-readable reports write them.
+func testCommand(framework, target, filter, reportFile string) ([]string, error) {
+	switch framework {
+	case "go":
+		args := []string{"go", "test", "-json"}
+		if filter != "" {
+			args = append(args, "-run", filter)
+		}
+		return append(args, cmp.Or(target, "./...")), nil
+	case "pytest":
+		args := []string{"python3", "-m", "pytest", "-q", "--junitxml=" + reportFile}
+		if filter != "" {
+			args = append(args, "-k", filter)
+		}
+		if target != "" {
+			args = append(args, target)
+		}
+		return args, nil
+	case "jest":
+		args := []string{"npx", "jest", "--json", "--outputFile=" + reportFile}
+		if filter != "" {
+			args = append(args, "-t", filter)
+		}
+		if target != "" {
+			args = append(args, target)
+		}
+		return args, nil
+	case "cargo":
+		args := []string{"cargo", "test"}
+		if target != "" {
+			args = append(args, "-p", target)
+		}
+		if filter != "" {
+			args = append(args, filter)
+		}
+		return args, nil
+	}
+	return nil, fmt.Errorf("unsupported test framework %q", framework)
+}
+
+func parseTestResults(framework string, output []byte, reportFile string) (*TestResults, error) {
+	switch framework {
+	case "go":
+		return parseGoTestJSON(output)
+	case "cargo":
+		return parseCargoTest(output), nil
+	}
+	report, err := os.ReadFile(reportFile)
+	if os.IsNotExist(err) {
+		// The runner never got far enough to write a report.
+		return &TestResults{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if framework == "pytest" {
+		return parseJUnitXML(report)
+	}
+	return parseJestJSON(report)
+}
+
+// goTestEvent is a line of `go test -json` output.
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+func parseGoTestJSON(output []byte) (*TestResults, error) {
+	res := &TestResults{}
+	outputs := make(map[goTestKey]*strings.Builder)
+	var failed []goTestKey
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || line[0] != '{' {
+			continue // e.g. build errors printed to stderr
+		}
+		var ev goTestEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return nil, err
+		}
+		key := goTestKey{ev.Package, ev.Test}
+		switch ev.Action {
+		case "output", "build-output":
+			b := outputs[key]
+			if b == nil {
+				b = new(strings.Builder)
+				outputs[key] = b
+			}
+			b.WriteString(ev.Output)
+		case "pass":
+			if ev.Test != "" {
+				res.Passed++
+			}
+		case "skip":
+			if ev.Test != "" {
+				res.Skipped++
+			}
+		case "fail":
+			if ev.Test != "" {
+				res.Failed++
+			}
+			failed = append(failed, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, key := range failed {
+		// Skip packages whose failure is explained by a failing test, and
+		// parents whose failure is explained by a failing subtest.
+		if slices.ContainsFunc(failed, key.isParentOf) {
+			continue
+		}
+		var out string
+		if b := outputs[key]; b != nil {
+			out = b.String()
+		}
+		name := key.Package
+		if key.Test != "" {
+			name += "." + key.Test
+		}
+		res.Failures = append(res.Failures, TestFailure{Name: name, Output: out})
+	}
+	return res, nil
+}
+
+type goTestKey struct {
+	Package string
+	Test    string
+}
+
+func (k goTestKey) isParentOf(child goTestKey) bool {
+	if k.Package != child.Package || child.Test == "" {
+		return false
+	}
+	return k.Test == "" || strings.HasPrefix(child.Test, k.Test+"/")
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Error     *junitFailure `xml:"error"`
+	Skipped   *junitFailure `xml:"skipped"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSuite struct {
+	Cases  []junitTestCase `xml:"testcase"`
+	Suites []junitSuite    `xml:"testsuite"`
+}
+
+func parseJUnitXML(report []byte) (*TestResults, error) {
+	// pytest writes <testsuites><testsuite>...; other tools may omit the
+	// outer element. Parsing into junitSuite handles both.
+	var root junitSuite
+	if err := xml.Unmarshal(report, &root); err != nil {
+		return nil, err
+	}
+	res := &TestResults{}
+	var walk func(s junitSuite)
+	walk = func(s junitSuite) {
+		for _, tc := range s.Cases {
+			name := tc.Name
+			if tc.ClassName != "" {
+				name = tc.ClassName + "::" + tc.Name
+			}
+			switch {
+			case tc.Failure != nil:
+				res.Failed++
+				res.Failures = append(res.Failures, TestFailure{Name: name, Output: tc.Failure.Text})
+			case tc.Error != nil:
+				res.Failed++
+				res.Failures = append(res.Failures, TestFailure{Name: name, Output: tc.Error.Text})
+			case tc.Skipped != nil:
+				res.Skipped++
+			default:
+				res.Passed++
+			}
+		}
+		for _, sub := range s.Suites {
+			walk(sub)
+		}
+	}
+	walk(root)
+	return res, nil
+}
+
+type jestReport struct {
+	TestResults []struct {
+		Name             string `json:"name"`
+		Status           string `json:"status"`
+		Message          string `json:"message"`
+		AssertionResults []struct {
+			FullName        string   `json:"fullName"`
+			Status          string   `json:"status"`
+			FailureMessages []string `json:"failureMessages"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+}
+
+func parseJestJSON(report []byte) (*TestResults, error) {
+	var jr jestReport
+	if err := json.Unmarshal(report, &jr); err != nil {
+		return nil, err
+	}
+	res := &TestResults{}
+	for _, file := range jr.TestResults {
+		if file.Status == "failed" && len(file.AssertionResults) == 0 {
+			// The suite itself failed to run (syntax error, bad import, ...).
+			res.Failed++
+			res.Failures = append(res.Failures, TestFailure{Name: file.Name, Output: file.Message})
+			continue
+		}
+		for _, a := range file.AssertionResults {
+			switch a.Status {
+			case "passed":
+				res.Passed++
+			case "failed":
+				res.Failed++
+				res.Failures = append(res.Failures, TestFailure{Name: a.FullName, Output: strings.Join(a.FailureMessages, "\n")})
+			default: // pending, skipped, todo, disabled
+				res.Skipped++
+			}
+		}
+	}
+	return res, nil
+}
+
+var (
+	cargoResultRe  = regexp.MustCompile(`^test (\S+) \.\.\. (ok|FAILED|ignored)`)
+	cargoSectionRe = regexp.MustCompile(`^---- (\S+) stdout ----$`)
+)
+
+func parseCargoTest(output []byte) *TestResults {
+	res := &TestResults{}
+	sections := make(map[string]*strings.Builder)
+	var failed []string
+	var current *strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := cargoResultRe.FindStringSubmatch(line); m != nil {
+			switch m[2] {
+			case "ok":
+				res.Passed++
+			case "FAILED":
+				res.Failed++
+				failed = append(failed, m[1])
+			case "ignored":
+				res.Skipped++
+			}
+			continue
+		}
+		if m := cargoSectionRe.FindStringSubmatch(line); m != nil {
+			current = new(strings.Builder)
+			sections[m[1]] = current
+			continue
+		}
+		if line == "failures:" || strings.HasPrefix(line, "test result:") {
+			current = nil
+			continue
+		}
+		if current != nil {
+			current.WriteString(line)
+			current.WriteByte('\n')
+		}
+	}
+	for _, name := range failed {
+		var out string
+		if b := sections[name]; b != nil {
+			out = b.String()
+		}
+		res.Failures = append(res.Failures, TestFailure{Name: name, Output: out})
+	}
+	return res
+}
+
+// String formats the results for the model: counts, then each failure's
+// output, capped.
+func (r *TestResults) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: passed %d, failed %d, skipped %d\n", r.Command, r.Passed, r.Failed, r.Skipped)
+	for i, f := range r.Failures {
+		if i == maxReportedFailures {
+			fmt.Fprintf(&sb, "\n[%d more failing tests not shown]\n", len(r.Failures)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "\n--- FAIL: %s\n%s", f.Name, tail(strings.TrimRight(f.Output, "\n"), maxFailureOutput))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// tail returns the last n bytes of s, marking the truncation.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "[...truncated...]\n" + s[len(s)-n:]
+}
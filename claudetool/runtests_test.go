@@ -0,0 +1,148 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGoTestJSON(t *testing.T) {
+	out := strings.Join([]string{
+		`{"Action":"run","Package":"ex/a","Test":"TestOK"}`,
+		`{"Action":"output","Package":"ex/a","Test":"TestOK","Output":"=== RUN   TestOK\n"}`,
+		`{"Action":"pass","Package":"ex/a","Test":"TestOK"}`,
+		`{"Action":"output","Package":"ex/a","Test":"TestBad","Output":"=== RUN   TestBad\n"}`,
+		`{"Action":"output","Package":"ex/a","Test":"TestBad/sub","Output":"    a_test.go:9: boom\n"}`,
+		`{"Action":"fail","Package":"ex/a","Test":"TestBad/sub"}`,
+		`{"Action":"fail","Package":"ex/a","Test":"TestBad"}`,
+		`{"Action":"skip","Package":"ex/a","Test":"TestSkip"}`,
+		`{"Action":"fail","Package":"ex/a"}`,
+		`# ex/b`,
+		`{"Action":"build-output","Package":"ex/b","Output":"b.go:3:1: syntax error\n"}`,
+		`{"Action":"fail","Package":"ex/b"}`,
+	}, "\n")
+	res, err := parseGoTestJSON([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Passed != 1 || res.Failed != 2 || res.Skipped != 1 {
+		t.Fatalf("counts = %d/%d/%d, want 1/2/1", res.Passed, res.Failed, res.Skipped)
+	}
+	if len(res.Failures) != 2 {
+		t.Fatalf("failures = %+v, want TestBad/sub and ex/b", res.Failures)
+	}
+	if res.Failures[0].Name != "ex/a.TestBad/sub" || !strings.Contains(res.Failures[0].Output, "boom") {
+		t.Errorf("first failure = %+v", res.Failures[0])
+	}
+	if res.Failures[1].Name != "ex/b" || !strings.Contains(res.Failures[1].Output, "syntax error") {
+		t.Errorf("second failure = %+v", res.Failures[1])
+	}
+}
+
+func TestParseJUnitXML(t *testing.T) {
+	report := `<?xml version="1.0" encoding="utf-8"?>
+<testsuites><testsuite name="pytest">
+<testcase classname="test_m" name="test_ok"/>
+<testcase classname="test_m" name="test_bad"><failure message="assert 1 == 2">def test_bad():
+&gt;       assert 1 == 2</failure></testcase>
+<testcase classname="test_m" name="test_skip"><skipped message="nope"/></testcase>
+</testsuite></testsuites>`
+	res, err := parseJUnitXML([]byte(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Passed != 1 || res.Failed != 1 || res.Skipped != 1 {
+		t.Fatalf("counts = %d/%d/%d, want 1/1/1", res.Passed, res.Failed, res.Skipped)
+	}
+	if res.Failures[0].Name != "test_m::test_bad" || !strings.Contains(res.Failures[0].Output, "> ") {
+		t.Errorf("failure = %+v", res.Failures[0])
+	}
+}
+
+func TestParseJestJSON(t *testing.T) {
+	report, _ := json.Marshal(map[string]any{
+		"testResults": []map[string]any{
+			{
+				"name":   "/p/a.test.js",
+				"status": "failed",
+				"assertionResults": []map[string]any{
+					{"fullName": "a works", "status": "passed"},
+					{"fullName": "a breaks", "status": "failed", "failureMessages": []string{"Expected 1"}},
+					{"fullName": "a later", "status": "todo"},
+				},
+			},
+			{"name": "/p/b.test.js", "status": "failed", "message": "SyntaxError"},
+		},
+	})
+	res, err := parseJestJSON(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Passed != 1 || res.Failed != 2 || res.Skipped != 1 {
+		t.Fatalf("counts = %d/%d/%d, want 1/2/1", res.Passed, res.Failed, res.Skipped)
+	}
+	if res.Failures[1].Name != "/p/b.test.js" || res.Failures[1].Output != "SyntaxError" {
+		t.Errorf("suite failure = %+v", res.Failures[1])
+	}
+}
+
+func TestParseCargoTest(t *testing.T) {
+	out := `running 3 tests
+test tests::ok ... ok
+test tests::bad ... FAILED
+test tests::slow ... ignored
+
+failures:
+
+---- tests::bad stdout ----
+thread 'tests::bad' panicked at src/lib.rs:9:9:
+assertion failed
+
+failures:
+    tests::bad
+
+test result: FAILED. 1 passed; 1 failed; 1 ignored
+`
+	res := parseCargoTest([]byte(out))
+	if res.Passed != 1 || res.Failed != 1 || res.Skipped != 1 {
+		t.Fatalf("counts = %d/%d/%d, want 1/1/1", res.Passed, res.Failed, res.Skipped)
+	}
+	if !strings.Contains(res.Failures[0].Output, "assertion failed") || strings.Contains(res.Failures[0].Output, "failures:") {
+		t.Errorf("failure output = %q", res.Failures[0].Output)
+	}
+}
+
+func TestRunTestsGo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test")
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module ex\n\ngo 1.21\n",
+		"ex_test.go": `package ex
+
+import "testing"
+
+func TestPass(t *testing.T) {}
+
+func TestFail(t *testing.T) { t.Fatal("kaboom") }
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := &RunTestsTool{WorkingDir: NewMutableWorkingDir(dir)}
+	out := tool.run(context.Background(), runTestsInput{Framework: "go"})
+	if out.Error != nil {
+		t.Fatalf("unexpected error: %v", out.Error)
+	}
+	text := out.LLMContent[0].Text
+	if !strings.Contains(text, "passed 1, failed 1") || !strings.Contains(text, "kaboom") || strings.Contains(text, "TestPass") {
+		t.Errorf("unexpected output:\n%s", text)
+	}
+}
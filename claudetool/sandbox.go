@@ -0,0 +1,261 @@
+package claudetool
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// SandboxConfig selects how the bash and shell tools confine the commands
+// they run.
+type SandboxConfig struct {
+	// Backend is "" (no sandbox), "bwrap", "docker", or "user".
+	//
+	//   - bwrap runs each command in fresh Linux namespaces via bubblewrap,
+	//     with the filesystem read-only except the working and temp dirs.
+	//   - docker runs commands in a container per conversation, with the
+	//     conversation's initial working directory mounted at the same path.
+	//   - user runs commands as an unprivileged account. Shelley must run
+	//     as root.
+	Backend string `json:"backend"`
+	// Image is the container image for the docker backend. It must provide bash.
+	Image string `json:"image,omitempty"`
+	// User is the account commands run as for the user backend.
+	User string `json:"user,omitempty"`
+	// Network allows network access from bwrap and docker sandboxes.
+	Network bool `json:"network,omitempty"`
+}
+
+// Validate reports whether c names a known backend with its required settings.
+func (c SandboxConfig) Validate() error {
+	switch c.Backend {
+	case "", "bwrap":
+	case "docker":
+		if c.Image == "" {
+			return fmt.Errorf("docker sandbox requires an image")
+		}
+	case "user":
+		if c.User == "" {
+			return fmt.Errorf("user sandbox requires a user")
+		}
+	default:
+		return fmt.Errorf("unknown sandbox backend %q", c.Backend)
+	}
+	return nil
+}
+
+// Sandbox confines commands built to run on the host.
+type Sandbox interface {
+	// Wrap rewrites cmd to run inside the sandbox. It must be called before
+	// cmd is started.
+	Wrap(cmd *exec.Cmd) error
+	// Close releases resources held by the sandbox.
+	Close() error
+}
+
+// Remote is a Sandbox whose commands run on another machine, such as one
+// running "shelley worker". The patch tool reads and writes files through
+// it, since the files are there too.
+type Remote interface {
+	Sandbox
+	// ReadFile returns the contents of the named file, or an error
+	// matching fs.ErrNotExist if there is no such file.
+	ReadFile(ctx context.Context, path string) ([]byte, error)
+	// WriteFile writes data to the named file, creating it and its
+	// directories if needed.
+	WriteFile(ctx context.Context, path string, data []byte) error
+}
+
+// NewSandbox returns the sandbox c describes, or nil if c.Backend is empty.
+// name identifies the sandbox, e.g. by conversation ID. c must be valid.
+func NewSandbox(c SandboxConfig, name string) Sandbox {
+	switch c.Backend {
+	case "bwrap":
+		return &bwrapSandbox{network: c.Network}
+	case "docker":
+		if name == "" {
+			name = rand.Text()
+		}
+		return &dockerSandbox{image: c.Image, network: c.Network, name: "shelley-" + sanitizeContainerName(name)}
+	case "user":
+		return &userSandbox{user: c.User}
+	}
+	return nil
+}
+
+// sandboxCommand wraps cmd in sb, if set. A wrapping failure is left in
+// cmd.Err so that cmd.Start reports it.
+func sandboxCommand(cmd *exec.Cmd, sb Sandbox) {
+	if sb == nil {
+		return
+	}
+	if err := sb.Wrap(cmd); err != nil {
+		cmd.Err = fmt.Errorf("sandbox: %w", err)
+	}
+}
+
+// checkWorkingDir checks that the working directory wd exists, unless sb
+// runs commands on another machine, where it can't be checked.
+func checkWorkingDir(wd string, sb Sandbox) error {
+	if _, ok := sb.(Remote); ok {
+		return nil
+	}
+	if _, err := os.Stat(wd); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("working directory does not exist: %s (use change_dir to switch to a valid directory)", wd)
+		}
+		return fmt.Errorf("cannot access working directory %s: %w", wd, err)
+	}
+	return nil
+}
+
+// prependArgs makes cmd run args followed by its original argv.
+func prependArgs(cmd *exec.Cmd, args ...string) error {
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	cmd.Path = path
+	cmd.Args = append(args, cmd.Args...)
+	return nil
+}
+
+type bwrapSandbox struct {
+	network bool
+}
+
+func (s *bwrapSandbox) Wrap(cmd *exec.Cmd) error {
+	return prependArgs(cmd, bwrapArgs(cmd.Dir, os.TempDir(), s.network)...)
+}
+
+func (s *bwrapSandbox) Close() error { return nil }
+
+// bwrapArgs returns the bubblewrap invocation that exposes the host
+// filesystem read-only, except dir and tmp.
+func bwrapArgs(dir, tmp string, network bool) []string {
+	args := []string{"bwrap", "--die-with-parent", "--unshare-all"}
+	if network {
+		args = append(args, "--share-net")
+	}
+	args = append(args,
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		// The shell tool's log files live in tmp.
+		"--bind", tmp, tmp,
+		"--bind", dir, dir,
+		"--chdir", dir,
+		"--",
+	)
+	return args
+}
+
+type dockerSandbox struct {
+	image   string
+	network bool
+	name    string
+
+	mu      sync.Mutex
+	started bool
+}
+
+func (s *dockerSandbox) Wrap(cmd *exec.Cmd) error {
+	if err := s.start(cmd.Dir); err != nil {
+		return err
+	}
+	return prependArgs(cmd, dockerExecArgs(s.name, cmd)...)
+}
+
+// dockerExecArgs returns the docker invocation that runs cmd in container.
+func dockerExecArgs(container string, cmd *exec.Cmd) []string {
+	args := []string{"docker", "exec", "-i"}
+	if cmd.Dir != "" {
+		args = append(args, "-w", cmd.Dir)
+	}
+	// Pass the variables Shelley adds, not the host's PATH and friends.
+	host := os.Environ()
+	for _, kv := range cmd.Env {
+		if !slices.Contains(host, kv) {
+			args = append(args, "-e", kv)
+		}
+	}
+	return append(args, container)
+}
+
+// start creates the container on first use, mounting dir.
+func (s *dockerSandbox) start(dir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil
+	}
+	args := []string{"run", "-d", "--rm", "--name", s.name, "-v", dir + ":" + dir, "-w", dir}
+	if !s.network {
+		args = append(args, "--network", "none")
+	}
+	args = append(args, s.image, "sleep", "infinity")
+	if out, err := exec.Command("docker", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to start container: %w\n%s", err, bytes.TrimSpace(out))
+	}
+	s.started = true
+	return nil
+}
+
+func (s *dockerSandbox) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		return nil
+	}
+	s.started = false
+	if out, err := exec.Command("docker", "rm", "-f", s.name).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove container: %w\n%s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// sanitizeContainerName keeps the characters docker allows in names.
+func sanitizeContainerName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, s)
+}
+
+type userSandbox struct {
+	user string
+}
+
+func (s *userSandbox) Wrap(cmd *exec.Cmd) error {
+	u, err := user.Lookup(s.user)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return err
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	cmd.Env = append(cmd.Env, "HOME="+u.HomeDir, "USER="+u.Username, "LOGNAME="+u.Username)
+	return nil
+}
+
+func (s *userSandbox) Close() error { return nil }
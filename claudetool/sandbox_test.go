@@ -0,0 +1,148 @@
+package claudetool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSandboxConfigValidate(t *testing.T) {
+	cases := []struct {
+		cfg     SandboxConfig
+		wantErr bool
+	}{
+		{SandboxConfig{}, false},
+		{SandboxConfig{Backend: "bwrap"}, false},
+		{SandboxConfig{Backend: "docker"}, true},
+		{SandboxConfig{Backend: "docker", Image: "debian"}, false},
+		{SandboxConfig{Backend: "user"}, true},
+		{SandboxConfig{Backend: "user", User: "nobody"}, false},
+		{SandboxConfig{Backend: "chroot"}, true},
+	}
+	for _, c := range cases {
+		if err := c.cfg.Validate(); (err != nil) != c.wantErr {
+			t.Errorf("%+v: got %v, wantErr %v", c.cfg, err, c.wantErr)
+		}
+	}
+}
+
+func TestBwrapArgs(t *testing.T) {
+	args := bwrapArgs("/work", "/tmp", false)
+	if slices.Contains(args, "--share-net") {
+		t.Errorf("network shared without Network: %v", args)
+	}
+	joined := strings.Join(args, " ")
+	for _, want := range []string{"--ro-bind / /", "--bind /work /work", "--chdir /work", "--unshare-all"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("missing %q in %s", want, joined)
+		}
+	}
+	if args[len(args)-1] != "--" {
+		t.Errorf("args must end with --: %v", args)
+	}
+	if !slices.Contains(bwrapArgs("/work", "/tmp", true), "--share-net") {
+		t.Error("expected --share-net with Network")
+	}
+}
+
+func TestSandboxDisablesProbe(t *testing.T) {
+	ts := NewToolSet(context.Background(), ToolSetConfig{
+		Sandbox:       SandboxConfig{Backend: "bwrap"},
+		ToolOverrides: map[string]string{"probe": "on"},
+	})
+	defer ts.Cleanup()
+	for _, tool := range ts.Tools() {
+		if tool.Name == "probe" {
+			t.Fatal("probe runs on the host, so it must be off with a sandbox")
+		}
+	}
+}
+
+func TestUserSandbox(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("switching users requires root")
+	}
+	cmd := exec.Command("id", "-un")
+	sandboxCommand(cmd, NewSandbox(SandboxConfig{Backend: "user", User: "nobody"}, ""))
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "nobody" {
+		t.Errorf("ran as %q", got)
+	}
+}
+
+func TestSandboxErrorFailsStart(t *testing.T) {
+	var out bytes.Buffer
+	b := &BashTool{WorkingDir: NewMutableWorkingDir("/"), Sandbox: NewSandbox(SandboxConfig{Backend: "user", User: "no-such-user-shelley"}, "")}
+	cmd := b.makeBashCommand(context.Background(), "true", &out)
+	if err := cmd.Start(); err == nil || !strings.Contains(err.Error(), "sandbox") {
+		t.Errorf("got %v", err)
+	}
+}
+
+// memRemote is a Remote with its files in memory, which runs no commands.
+type memRemote struct {
+	files map[string]string
+}
+
+func (r *memRemote) Wrap(cmd *exec.Cmd) error { return errors.New("no commands") }
+
+func (r *memRemote) Close() error { return nil }
+
+func (r *memRemote) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	data, ok := r.files[path]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+	return []byte(data), nil
+}
+
+func (r *memRemote) WriteFile(ctx context.Context, path string, data []byte) error {
+	r.files[path] = string(data)
+	return nil
+}
+
+func TestRemote(t *testing.T) {
+	ctx := context.Background()
+	r := &memRemote{files: map[string]string{"/srv/app/a.txt": "one\n"}}
+	ts := NewToolSet(ctx, ToolSetConfig{WorkingDir: "/srv/app", Remote: r})
+	defer ts.Cleanup()
+	var names []string
+	for _, tool := range ts.Tools() {
+		names = append(names, tool.Name)
+	}
+	if !slices.Contains(names, "bash") || !slices.Contains(names, "patch") ||
+		slices.Contains(names, "keyword_search") || slices.Contains(names, "remember") {
+		t.Errorf("tools for a remote: %v", names)
+	}
+
+	// The working directory is on the remote, so the bash tool gets as far
+	// as running the command there.
+	b := &BashTool{WorkingDir: NewMutableWorkingDir("/srv/app"), Sandbox: r}
+	out := b.run(ctx, bashInput{Command: "true"})
+	if out.Error == nil || !strings.Contains(out.Error.Error(), "no commands") {
+		t.Errorf("bash on a remote: %v", out.Error)
+	}
+
+	p := &PatchTool{WorkingDir: NewMutableWorkingDir("/srv/app"), Remote: r}
+	for _, input := range []string{
+		`{"path": "a.txt", "patches": [{"operation": "replace", "oldText": "one", "newText": "two"}]}`,
+		`{"path": "sub/b.txt", "patches": [{"operation": "overwrite", "newText": "new\n"}]}`,
+	} {
+		if out := p.Run(ctx, json.RawMessage(input)); out.Error != nil {
+			t.Fatalf("%s: %v", input, out.Error)
+		}
+	}
+	if r.files["/srv/app/a.txt"] != "two\n" || r.files["/srv/app/sub/b.txt"] != "new\n" {
+		t.Errorf("remote files after patching: %q", r.files)
+	}
+}
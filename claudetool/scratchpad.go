@@ -0,0 +1,139 @@
+package claudetool
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"shelley.exe.dev/llm"
+)
+
+// ScratchpadStore persists scratchpad notes per conversation.
+type ScratchpadStore interface {
+	// ScratchpadNotes returns a conversation's notes by name.
+	ScratchpadNotes(ctx context.Context, conversationID string) (map[string]string, error)
+	// SetScratchpadNote creates or replaces a note.
+	SetScratchpadNote(ctx context.Context, conversationID, name, content string) error
+	// DeleteScratchpadNote removes a note, reporting whether it existed.
+	DeleteScratchpadNote(ctx context.Context, conversationID, name string) (bool, error)
+}
+
+// ScratchpadTool keeps named notes that are shown to the model on every
+// turn, so decisions and findings survive distillation of the history.
+type ScratchpadTool struct {
+	Store          ScratchpadStore
+	ConversationID string
+}
+
+const (
+	scratchpadName        = "scratchpad"
+	scratchpadDescription = `Keep named notes for this conversation: task lists, findings, decisions.
+
+Notes are shown to you at the start of every request, even after the conversation history is summarized, so record anything you must not lose during a long task.
+
+Actions:
+- set: create or replace note "name" with "content"
+- delete: remove note "name"
+
+Keep notes short; all notes together are limited to %s.
+`
+	scratchpadInputSchema = `{
+  "type": "object",
+  "required": ["action", "name"],
+  "properties": {
+    "action": {
+      "type": "string",
+      "enum": ["set", "delete"]
+    },
+    "name": {
+      "type": "string",
+      "description": "Note name, e.g. \"plan\" or \"findings\""
+    },
+    "content": {
+      "type": "string",
+      "description": "Note content (set)"
+    }
+  }
+}`
+)
+
+// scratchpadMaxBytes caps the combined size of a conversation's notes,
+// since all of them are sent with every request.
+const scratchpadMaxBytes = 16 * 1024
+
+type scratchpadInput struct {
+	Action  string `json:"action"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Tool returns an llm.Tool for keeping notes.
+func (s *ScratchpadTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        scratchpadName,
+		Description: fmt.Sprintf(scratchpadDescription, humanizeBytes(scratchpadMaxBytes)),
+		InputSchema: llm.MustSchema(scratchpadInputSchema),
+		Run:         llm.RunJSON(s.run),
+	}
+}
+
+func (s *ScratchpadTool) run(ctx context.Context, req scratchpadInput) llm.ToolOut {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return llm.ErrorfToolOut("name is required")
+	}
+	switch req.Action {
+	case "set":
+		notes, err := s.Store.ScratchpadNotes(ctx, s.ConversationID)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		notes[name] = req.Content
+		if size := scratchpadSize(notes); size > scratchpadMaxBytes {
+			return llm.ErrorfToolOut("notes would total %s, over the %s limit; shorten or delete notes", humanizeBytes(size), humanizeBytes(scratchpadMaxBytes))
+		}
+		if err := s.Store.SetScratchpadNote(ctx, s.ConversationID, name, req.Content); err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("saved note %q", name))}
+	case "delete":
+		ok, err := s.Store.DeleteScratchpadNote(ctx, s.ConversationID, name)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		if !ok {
+			return llm.ErrorfToolOut("no note named %q", name)
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("deleted note %q", name))}
+	}
+	return llm.ErrorfToolOut("unknown action %q", req.Action)
+}
+
+func scratchpadSize(notes map[string]string) int {
+	n := 0
+	for name, content := range notes {
+		n += len(name) + len(content)
+	}
+	return n
+}
+
+// FormatScratchpad renders notes for the system prompt, or "" if there
+// are none.
+func FormatScratchpad(notes map[string]string) string {
+	if len(notes) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(notes))
+	for name := range notes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var sb strings.Builder
+	sb.WriteString("<scratchpad>\nYour notes for this conversation (kept with the scratchpad tool):\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "<note name=%q>\n%s\n</note>\n", name, strings.TrimRight(notes[name], "\n"))
+	}
+	sb.WriteString("</scratchpad>")
+	return sb.String()
+}
@@ -0,0 +1,77 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type memScratchpad map[string]string
+
+func (m memScratchpad) ScratchpadNotes(context.Context, string) (map[string]string, error) {
+	notes := make(map[string]string, len(m))
+	for k, v := range m {
+		notes[k] = v
+	}
+	return notes, nil
+}
+
+func (m memScratchpad) SetScratchpadNote(_ context.Context, _, name, content string) error {
+	m[name] = content
+	return nil
+}
+
+func (m memScratchpad) DeleteScratchpadNote(_ context.Context, _, name string) (bool, error) {
+	_, ok := m[name]
+	delete(m, name)
+	return ok, nil
+}
+
+func TestScratchpadTool(t *testing.T) {
+	store := memScratchpad{}
+	tool := (&ScratchpadTool{Store: store, ConversationID: "c1"}).Tool()
+	run := func(input string) (string, error) {
+		out := tool.Run(context.Background(), json.RawMessage(input))
+		if out.Error != nil {
+			return "", out.Error
+		}
+		return out.LLMContent[0].Text, nil
+	}
+
+	if _, err := run(`{"action":"set","name":"plan","content":"1. read\n2. fix"}`); err != nil {
+		t.Fatal(err)
+	}
+	if store["plan"] != "1. read\n2. fix" {
+		t.Fatalf("note not saved: %q", store["plan"])
+	}
+	if _, err := run(`{"action":"set","name":"big","content":"` + strings.Repeat("x", scratchpadMaxBytes) + `"}`); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Fatalf("expected size limit error, got %v", err)
+	}
+	if _, ok := store["big"]; ok {
+		t.Fatal("oversized note was saved")
+	}
+	if _, err := run(`{"action":"set","name":" ","content":"x"}`); err == nil {
+		t.Fatal("expected error for empty name")
+	}
+	if _, err := run(`{"action":"delete","name":"missing"}`); err == nil {
+		t.Fatal("expected error deleting a missing note")
+	}
+	if _, err := run(`{"action":"delete","name":"plan"}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(store) != 0 {
+		t.Fatalf("note not deleted: %v", store)
+	}
+}
+
+func TestFormatScratchpad(t *testing.T) {
+	if got := FormatScratchpad(nil); got != "" {
+		t.Fatalf("empty notes: got %q", got)
+	}
+	got := FormatScratchpad(map[string]string{"b": "second\n", "a": "first"})
+	want := "<scratchpad>\nYour notes for this conversation (kept with the scratchpad tool):\n<note name=\"a\">\nfirst\n</note>\n<note name=\"b\">\nsecond\n</note>\n</scratchpad>"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
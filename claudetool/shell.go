@@ -47,6 +47,8 @@ type ShellTool struct {
 	MaxYield time.Duration
 	// TempDir overrides the directory used for log files (default os.TempDir).
 	TempDir string
+	// Sandbox, if set, confines the commands run.
+	Sandbox Sandbox
 }
 
 const (
@@ -180,11 +182,8 @@ func (s *ShellTool) Tool() *llm.Tool {
 
 func (s *ShellTool) run(ctx context.Context, req shellInput) llm.ToolOut {
 	wd := s.WorkingDir.Get()
-	if _, err := os.Stat(wd); err != nil {
-		if os.IsNotExist(err) {
-			return llm.ErrorfToolOut("working directory does not exist: %s (use change_dir to switch to a valid directory)", wd)
-		}
-		return llm.ErrorfToolOut("cannot access working directory %s: %w", wd, err)
+	if err := checkWorkingDir(wd, s.Sandbox); err != nil {
+		return llm.ErrorToolOut(err)
 	}
 
 	if err := bashkit.Check(req.Command); err != nil {
@@ -243,6 +242,7 @@ func (s *ShellTool) run(ctx context.Context, req shellInput) llm.ToolOut {
 	)
 	env = append(env, s.Env.Environ(cmd.Dir)...)
 	cmd.Env = env
+	sandboxCommand(cmd, s.Sandbox)
 
 	if err := cmd.Start(); err != nil {
 		tmpFile.Close()
@@ -0,0 +1,106 @@
+package claudetool
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/skills"
+)
+
+// UseSkillTool loads a skill's instructions into the conversation when the
+// model decides the skill is relevant, and then, on request, the files the
+// skill refers to. The system prompt lists only each skill's name and
+// description.
+type UseSkillTool struct {
+	// WorkingDir is the shared mutable working directory, from which
+	// project skills are found.
+	WorkingDir *MutableWorkingDir
+}
+
+const (
+	useSkillName        = "use_skill"
+	useSkillDescription = `Activate a skill from <available_skills>: returns its full SKILL.md instructions and lists the files bundled with it.
+
+Call this as soon as a task matches a skill's description, before starting the task, and follow the instructions it returns.
+When the instructions refer to a bundled file (reference docs, templates, scripts), pass its path as "file" to read it; read only the files the task needs.
+`
+	useSkillInputSchema = `{
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name": {
+      "type": "string",
+      "description": "Skill name, as in <available_skills>"
+    },
+    "file": {
+      "type": "string",
+      "description": "A file bundled with the skill, relative to its directory, to read instead of SKILL.md"
+    }
+  }
+}`
+)
+
+// useSkillMaxBytes caps a bundled file returned by use_skill.
+const useSkillMaxBytes = 256 * 1024
+
+type useSkillInput struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+}
+
+// Tool returns an llm.Tool for activating skills.
+func (u *UseSkillTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        useSkillName,
+		Description: useSkillDescription,
+		InputSchema: llm.MustSchema(useSkillInputSchema),
+		Run:         llm.RunJSON(u.run),
+	}
+}
+
+func (u *UseSkillTool) run(ctx context.Context, req useSkillInput) llm.ToolOut {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return llm.ErrorfToolOut("name is required")
+	}
+	skill, err := skills.Find(name, u.WorkingDir.Get())
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	if req.File != "" {
+		data, err := skills.ReadFile(skill, req.File)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		if len(data) > useSkillMaxBytes {
+			return llm.ErrorfToolOut("%s is %s, over the %s limit; read it with bash instead", req.File, humanizeBytes(len(data)), humanizeBytes(useSkillMaxBytes))
+		}
+		if !utf8.Valid(data) {
+			return llm.ErrorfToolOut("%s is not text; use it with bash instead", req.File)
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent(string(data))}
+	}
+
+	content, err := skills.Read(skill)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	files, err := skills.Files(skill)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<skill name=%q>\n%s\n</skill>\n", skill.Name, strings.TrimSpace(content))
+	if len(files) > 0 {
+		fmt.Fprintf(&sb, "\nFiles bundled with this skill, in %s (read one with use_skill and \"file\"):\n", filepath.Dir(skill.Path))
+		for _, f := range files {
+			sb.WriteString("- " + f + "\n")
+		}
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(sb.String())}
+}
@@ -0,0 +1,70 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUseSkillTool(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	wd := t.TempDir()
+	skillDir := filepath.Join(wd, ".skills", "release")
+	for name, content := range map[string]string{
+		"SKILL.md":               "---\nname: release\ndescription: Use when cutting a release.\n---\n\nFollow reference/checklist.md.\n",
+		"reference/checklist.md": "1. Tag the commit.\n",
+		".hidden":                "secret",
+	} {
+		path := filepath.Join(skillDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(wd, "outside.md"), []byte("outside"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := (&UseSkillTool{WorkingDir: NewMutableWorkingDir(wd)}).Tool()
+	run := func(input string) (string, error) {
+		out := tool.Run(context.Background(), json.RawMessage(input))
+		if out.Error != nil {
+			return "", out.Error
+		}
+		return out.LLMContent[0].Text, nil
+	}
+
+	got, err := run(`{"name":"release"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "Follow reference/checklist.md.") || !strings.Contains(got, "- reference/checklist.md\n") {
+		t.Errorf("unexpected activation:\n%s", got)
+	}
+	if strings.Contains(got, ".hidden") {
+		t.Errorf("activation lists a hidden file:\n%s", got)
+	}
+
+	if got, err := run(`{"name":"release","file":"reference/checklist.md"}`); err != nil || got != "1. Tag the commit.\n" {
+		t.Errorf("reading a bundled file = %q, %v", got, err)
+	}
+	if _, err := run(`{"name":"release","file":"../../outside.md"}`); err == nil {
+		t.Error("read a file outside the skill directory")
+	}
+	if _, err := run(`{"name":"missing"}`); err == nil {
+		t.Error("expected error for an unknown skill")
+	}
+
+	got, err = run(`{"name":"schedule"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "name: schedule") || strings.Contains(got, "Files bundled") {
+		t.Errorf("unexpected built-in activation:\n%s", got)
+	}
+}
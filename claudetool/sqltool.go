@@ -0,0 +1,271 @@
+package claudetool
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+
+	"shelley.exe.dev/llm"
+)
+
+// SQLProfile is a named database connection for the sql tool. Profiles come
+// from configuration so that credentials never appear in the conversation.
+type SQLProfile struct {
+	// Driver is "sqlite", "postgres", or "mysql".
+	Driver string `json:"driver"`
+	// DSN is a file path for sqlite (relative paths resolve against the
+	// working directory), a connection URL or key=value string for postgres,
+	// and a go-sql-driver DSN for mysql.
+	DSN string `json:"dsn"`
+	// ReadWrite allows statements that modify data. Profiles are read-only
+	// unless this is set.
+	ReadWrite bool `json:"read_write"`
+}
+
+// SQLTool runs queries against configured databases.
+type SQLTool struct {
+	// Profiles maps profile name to connection.
+	Profiles map[string]SQLProfile
+	// WorkingDir is the shared mutable working directory.
+	WorkingDir *MutableWorkingDir
+}
+
+const (
+	sqlName        = "sql"
+	sqlDescription = `Query a configured project database.
+
+Actions:
+- query: run "query" and return the result rows
+- schema: list tables, or describe the columns of "table"
+
+Results are capped at %d rows and %s. Read-only databases reject writes.
+
+Databases:
+%s`
+	sqlInputSchema = `{
+  "type": "object",
+  "required": ["database", "action"],
+  "properties": {
+    "database": {
+      "type": "string",
+      "description": "Name of the configured database"
+    },
+    "action": {
+      "type": "string",
+      "enum": ["query", "schema"]
+    },
+    "query": {
+      "type": "string",
+      "description": "SQL to run (query action)"
+    },
+    "table": {
+      "type": "string",
+      "description": "Table to describe (schema action); omit to list tables"
+    }
+  }
+}`
+)
+
+const (
+	sqlMaxRows  = 200
+	sqlMaxBytes = 64 * 1024
+)
+
+type sqlInput struct {
+	Database string `json:"database"`
+	Action   string `json:"action"`
+	Query    string `json:"query"`
+	Table    string `json:"table"`
+}
+
+// Tool returns an llm.Tool for querying databases.
+func (s *SQLTool) Tool() *llm.Tool {
+	names := make([]string, 0, len(s.Profiles))
+	for name := range s.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var dbs strings.Builder
+	for _, name := range names {
+		p := s.Profiles[name]
+		mode := "read-only"
+		if p.ReadWrite {
+			mode = "read-write"
+		}
+		fmt.Fprintf(&dbs, "- %s (%s, %s)\n", name, p.Driver, mode)
+	}
+	return &llm.Tool{
+		Name:        sqlName,
+		Description: fmt.Sprintf(sqlDescription, sqlMaxRows, humanizeBytes(sqlMaxBytes), dbs.String()),
+		InputSchema: llm.MustSchema(sqlInputSchema),
+		Run:         llm.RunJSON(s.run),
+	}
+}
+
+func (s *SQLTool) run(ctx context.Context, req sqlInput) llm.ToolOut {
+	p, ok := s.Profiles[req.Database]
+	if !ok {
+		return llm.ErrorfToolOut("unknown database %q", req.Database)
+	}
+
+	var query string
+	var args []any
+	switch req.Action {
+	case "query":
+		if strings.TrimSpace(req.Query) == "" {
+			return llm.ErrorfToolOut("query is required")
+		}
+		query = req.Query
+	case "schema":
+		var err error
+		query, args, err = schemaQuery(p.Driver, req.Table)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+	default:
+		return llm.ErrorfToolOut("unknown action %q", req.Action)
+	}
+
+	db, err := s.open(p)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: !p.ReadWrite})
+	if err != nil {
+		return llm.ErrorfToolOut("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	out, err := runSQL(ctx, tx, query, args...)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	if p.ReadWrite {
+		if err := tx.Commit(); err != nil {
+			return llm.ErrorfToolOut("failed to commit: %w", err)
+		}
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(out)}
+}
+
+// open connects to the database described by p.
+func (s *SQLTool) open(p SQLProfile) (*sql.DB, error) {
+	switch p.Driver {
+	case "sqlite":
+		path := p.DSN
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.WorkingDir.Get(), path)
+		}
+		dsn := "file:" + path
+		if !p.ReadWrite {
+			// SQLite has no read-only transactions. Opening the file
+			// read-only makes it reject writes in a way a query can't
+			// undo, unlike PRAGMA query_only. Not immutable=1: the
+			// application may be writing the database as we read it.
+			dsn += "?mode=ro"
+		}
+		return sql.Open("sqlite", dsn)
+	case "postgres":
+		return sql.Open("pgx", p.DSN)
+	case "mysql":
+		return sql.Open("mysql", p.DSN)
+	}
+	return nil, fmt.Errorf("unsupported driver %q", p.Driver)
+}
+
+// schemaQuery returns a query listing the tables of the current schema, or
+// the columns of table if it is set.
+func schemaQuery(driver, table string) (string, []any, error) {
+	switch driver {
+	case "sqlite":
+		if table != "" {
+			return "SELECT sql FROM sqlite_master WHERE name = ?", []any{table}, nil
+		}
+		return "SELECT type, name FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name", nil, nil
+	case "postgres":
+		if table != "" {
+			return "SELECT column_name, data_type, is_nullable, column_default FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position", []any{table}, nil
+		}
+		return "SELECT table_name, table_type FROM information_schema.tables WHERE table_schema = current_schema() ORDER BY table_name", nil, nil
+	case "mysql":
+		if table != "" {
+			return "SELECT column_name, column_type, is_nullable, column_default FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? ORDER BY ordinal_position", []any{table}, nil
+		}
+		return "SELECT table_name, table_type FROM information_schema.tables WHERE table_schema = DATABASE() ORDER BY table_name", nil, nil
+	}
+	return "", nil, fmt.Errorf("unsupported driver %q", driver)
+}
+
+// runSQL runs query and formats its result as a pipe-separated table,
+// stopping at sqlMaxRows rows or sqlMaxBytes of output.
+func runSQL(ctx context.Context, tx *sql.Tx, query string, args ...any) (string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if len(cols) == 0 {
+		// A statement without a result set, e.g. an UPDATE.
+		return "OK", rows.Err()
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.Join(cols, " | "))
+	sb.WriteByte('\n')
+	vals := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	n := 0
+	for rows.Next() {
+		if n == sqlMaxRows || sb.Len() >= sqlMaxBytes {
+			fmt.Fprintf(&sb, "[truncated after %d rows]\n", n)
+			return sb.String(), nil
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", err
+		}
+		for i, v := range vals {
+			if i > 0 {
+				sb.WriteString(" | ")
+			}
+			sb.WriteString(formatSQLValue(v))
+		}
+		sb.WriteByte('\n')
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&sb, "(%d rows)\n", n)
+	return sb.String(), nil
+}
+
+func formatSQLValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return fmt.Sprintf("<%d bytes>", len(v))
+	}
+	return fmt.Sprint(v)
+}
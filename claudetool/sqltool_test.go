@@ -0,0 +1,103 @@
+package claudetool
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestSQLTool(t *testing.T, readWrite bool) *SQLTool {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite", filepath.Join(dir, "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, avatar BLOB);
+		INSERT INTO users (name, avatar) VALUES ('ada', x'ff00'), (NULL, NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	return &SQLTool{
+		Profiles:   map[string]SQLProfile{"app": {Driver: "sqlite", DSN: "app.db", ReadWrite: readWrite}},
+		WorkingDir: NewMutableWorkingDir(dir),
+	}
+}
+
+func TestSQLToolQuery(t *testing.T) {
+	tool := newTestSQLTool(t, false)
+	out := tool.run(context.Background(), sqlInput{Database: "app", Action: "query", Query: "SELECT id, name, avatar FROM users ORDER BY id"})
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	want := "id | name | avatar\n1 | ada | <2 bytes>\n2 | NULL | NULL\n(2 rows)\n"
+	if got := out.LLMContent[0].Text; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSQLToolReadOnly(t *testing.T) {
+	tool := newTestSQLTool(t, false)
+	out := tool.run(context.Background(), sqlInput{Database: "app", Action: "query", Query: "DELETE FROM users"})
+	if out.Error == nil {
+		t.Fatal("expected write to fail on a read-only profile")
+	}
+	out = tool.run(context.Background(), sqlInput{Database: "app", Action: "query", Query: "PRAGMA query_only=0; DELETE FROM users"})
+	if out.Error == nil {
+		t.Fatal("expected write after turning off query_only to fail on a read-only profile")
+	}
+	out = tool.run(context.Background(), sqlInput{Database: "app", Action: "query", Query: "SELECT count(*) FROM users"})
+	if out.Error != nil || !strings.Contains(out.LLMContent[0].Text, "\n2\n") {
+		t.Fatalf("read-only profile was written: %v %v", out.Error, out.LLMContent)
+	}
+
+	tool = newTestSQLTool(t, true)
+	out = tool.run(context.Background(), sqlInput{Database: "app", Action: "query", Query: "DELETE FROM users"})
+	if out.Error != nil {
+		t.Fatalf("write on read-write profile: %v", out.Error)
+	}
+	out = tool.run(context.Background(), sqlInput{Database: "app", Action: "query", Query: "SELECT count(*) FROM users"})
+	if out.Error != nil || !strings.Contains(out.LLMContent[0].Text, "\n0\n") {
+		t.Errorf("delete was not committed: %v %v", out.Error, out.LLMContent)
+	}
+}
+
+func TestSQLToolSchema(t *testing.T) {
+	tool := newTestSQLTool(t, false)
+	out := tool.run(context.Background(), sqlInput{Database: "app", Action: "schema"})
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	if !strings.Contains(out.LLMContent[0].Text, "table | users") {
+		t.Errorf("tables: %s", out.LLMContent[0].Text)
+	}
+	out = tool.run(context.Background(), sqlInput{Database: "app", Action: "schema", Table: "users"})
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	if !strings.Contains(out.LLMContent[0].Text, "CREATE TABLE users") {
+		t.Errorf("columns: %s", out.LLMContent[0].Text)
+	}
+}
+
+func TestSQLToolRowCap(t *testing.T) {
+	tool := newTestSQLTool(t, false)
+	q := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000) SELECT i FROM n"
+	out := tool.run(context.Background(), sqlInput{Database: "app", Action: "query", Query: q})
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	if !strings.Contains(out.LLMContent[0].Text, "[truncated after 200 rows]") {
+		t.Errorf("expected truncation, got %d bytes", len(out.LLMContent[0].Text))
+	}
+}
+
+func TestSQLToolUnknownDatabase(t *testing.T) {
+	tool := newTestSQLTool(t, false)
+	out := tool.run(context.Background(), sqlInput{Database: "nope", Action: "schema"})
+	if out.Error == nil || !strings.Contains(out.Error.Error(), "unknown database") {
+		t.Errorf("got %v", out.Error)
+	}
+}
@@ -1,8 +1,11 @@
 package claudetool
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -47,8 +50,38 @@ type AvailableModel struct {
 type SubagentDB interface {
 	// GetOrCreateSubagentConversation retrieves or creates a subagent conversation.
 	// Returns the conversation ID and the actual slug used (may differ from requested
-	// slug if a numeric suffix was added for uniqueness).
-	GetOrCreateSubagentConversation(ctx context.Context, slug, parentID, cwd string) (conversationID, actualSlug string, err error)
+	// slug if a numeric suffix was added for uniqueness). persona, if not empty,
+	// is recorded on a newly created conversation.
+	GetOrCreateSubagentConversation(ctx context.Context, slug, parentID, cwd, persona string) (conversationID, actualSlug string, err error)
+}
+
+// SubagentBudget caps the combined usage of all subagents under one
+// top-level conversation. Zero fields are unlimited.
+type SubagentBudget struct {
+	MaxTokens  int64   `json:"max_tokens"`
+	MaxCostUSD float64 `json:"max_cost_usd"`
+}
+
+// SubagentPersona is a named kind of subagent, e.g. a security reviewer,
+// defined in the server config.
+type SubagentPersona struct {
+	// Description tells the parent when to use the persona.
+	Description string `json:"description"`
+	// SystemPrompt is added to the subagent's system prompt.
+	SystemPrompt string `json:"system_prompt"`
+	// Tools, if not empty, are the only tools the subagent gets.
+	Tools []string `json:"tools"`
+	// Model and Reasoning are used unless the call names its own.
+	Model     string `json:"model"`
+	Reasoning string `json:"reasoning"`
+}
+
+// Validate reports whether the persona's reasoning level is known.
+func (p SubagentPersona) Validate() error {
+	if p.Reasoning != "" && !isValidReasoningLevel(p.Reasoning) {
+		return fmt.Errorf("unknown reasoning level %q; available: %s", p.Reasoning, strings.Join(subagentReasoningLevels, ", "))
+	}
+	return nil
 }
 
 // SubagentTool provides the ability to spawn and interact with subagent conversations.
@@ -64,6 +97,8 @@ type SubagentTool struct {
 	// service default). Subagents inherit this when the "reasoning" parameter is
 	// not specified.
 	ParentReasoning string
+	// Personas are the subagent kinds the agent can choose from, by name.
+	Personas map[string]SubagentPersona
 }
 
 const subagentName = "subagent"
@@ -110,6 +145,13 @@ parent conversation's reasoning level.`
 		}
 	}
 
+	if len(s.Personas) > 0 {
+		base += "\n\nPersonas (use the \"persona\" parameter when creating a subagent; it sets the subagent's instructions, tools, and default model):"
+		for _, name := range slices.Sorted(maps.Keys(s.Personas)) {
+			base += fmt.Sprintf("\n- %s: %s", name, s.Personas[name].Description)
+		}
+	}
+
 	return base
 }
 
@@ -142,6 +184,20 @@ func (s *SubagentTool) subagentInputSchema() string {
       "enum": [%s]
     }`, strings.Join(reasoningEnum, ", "))
 
+	personaProp := ""
+	if len(s.Personas) > 0 {
+		var personaEnum []string
+		for _, name := range slices.Sorted(maps.Keys(s.Personas)) {
+			personaEnum = append(personaEnum, fmt.Sprintf("%q", name))
+		}
+		personaProp = fmt.Sprintf(`,
+    "persona": {
+      "type": "string",
+      "description": "Persona for a new subagent. It only applies when the slug is first used; later messages keep the subagent's persona.",
+      "enum": [%s]
+    }`, strings.Join(personaEnum, ", "))
+	}
+
 	return fmt.Sprintf(`{
   "type": "object",
   "required": ["slug", "prompt"],
@@ -161,9 +217,9 @@ func (s *SubagentTool) subagentInputSchema() string {
     "wait": {
       "type": "boolean",
       "description": "Whether to wait for completion (default: true). If false, returns immediately; when the subagent eventually finishes, its response is delivered asynchronously. If wait=true and the subagent completes before timeout, no later asynchronous duplicate is delivered. Sending a new message to a subagent that is still working does NOT interrupt it: the message is queued and delivered after the current turn finishes."
-    }%s%s
+    }%s%s%s
   }
-}`, modelProp, reasoningProp)
+}`, modelProp, reasoningProp, personaProp)
 }
 
 type subagentInput struct {
@@ -173,6 +229,7 @@ type subagentInput struct {
 	Wait           *bool  `json:"wait,omitempty"`
 	Model          string `json:"model,omitempty"`
 	Reasoning      string `json:"reasoning,omitempty"`
+	Persona        string `json:"persona,omitempty"`
 }
 
 // Tool returns an llm.Tool for the subagent functionality.
@@ -215,13 +272,22 @@ func (s *SubagentTool) run(ctx context.Context, req subagentInput) llm.ToolOut {
 		wait = *req.Wait
 	}
 
-	// Determine which model to use: explicit choice > parent's model
+	var persona SubagentPersona
+	if req.Persona != "" {
+		var ok bool
+		persona, ok = s.Personas[req.Persona]
+		if !ok {
+			return llm.ErrorfToolOut("unknown persona %q; available: %s", req.Persona, strings.Join(slices.Sorted(maps.Keys(s.Personas)), ", "))
+		}
+	}
+
+	// Determine which model to use: explicit choice > persona's model > parent's model
 	modelID := s.ModelID
-	if req.Model != "" {
+	if requested := cmp.Or(req.Model, persona.Model); requested != "" {
 		if len(s.AvailableModels) > 0 {
 			found := false
 			for _, m := range s.AvailableModels {
-				if m.ID == req.Model {
+				if m.ID == requested {
 					found = true
 					break
 				}
@@ -231,23 +297,23 @@ func (s *SubagentTool) run(ctx context.Context, req subagentInput) llm.ToolOut {
 				for _, m := range s.AvailableModels {
 					ids = append(ids, m.ID)
 				}
-				return llm.ErrorfToolOut("unknown model %q; available: %s", req.Model, strings.Join(ids, ", "))
+				return llm.ErrorfToolOut("unknown model %q; available: %s", requested, strings.Join(ids, ", "))
 			}
 		}
-		modelID = req.Model
+		modelID = requested
 	}
 
-	// Determine reasoning level: explicit choice > parent's reasoning level.
+	// Determine reasoning level: explicit choice > persona's level > parent's reasoning level.
 	reasoning := s.ParentReasoning
-	if req.Reasoning != "" {
-		if !isValidReasoningLevel(req.Reasoning) {
-			return llm.ErrorfToolOut("unknown reasoning level %q; available: %s", req.Reasoning, strings.Join(subagentReasoningLevels, ", "))
+	if requested := cmp.Or(req.Reasoning, persona.Reasoning); requested != "" {
+		if !isValidReasoningLevel(requested) {
+			return llm.ErrorfToolOut("unknown reasoning level %q; available: %s", requested, strings.Join(subagentReasoningLevels, ", "))
 		}
-		reasoning = req.Reasoning
+		reasoning = requested
 	}
 
 	// Get or create the subagent conversation
-	conversationID, actualSlug, err := s.DB.GetOrCreateSubagentConversation(ctx, req.Slug, s.ParentConversationID, s.WorkingDir.Get())
+	conversationID, actualSlug, err := s.DB.GetOrCreateSubagentConversation(ctx, req.Slug, s.ParentConversationID, s.WorkingDir.Get(), req.Persona)
 	if err != nil {
 		return llm.ErrorfToolOut("failed to get/create subagent conversation: %w", err)
 	}
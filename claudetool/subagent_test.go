@@ -11,6 +11,7 @@ import (
 // mockSubagentDB implements SubagentDB for testing.
 type mockSubagentDB struct {
 	conversations map[string]string // slug -> conversationID
+	lastPersona   string
 }
 
 func newMockSubagentDB() *mockSubagentDB {
@@ -19,7 +20,8 @@ func newMockSubagentDB() *mockSubagentDB {
 	}
 }
 
-func (m *mockSubagentDB) GetOrCreateSubagentConversation(ctx context.Context, slug, parentID, cwd string) (string, string, error) {
+func (m *mockSubagentDB) GetOrCreateSubagentConversation(ctx context.Context, slug, parentID, cwd, persona string) (string, string, error) {
+	m.lastPersona = persona
 	key := parentID + ":" + slug
 	if id, ok := m.conversations[key]; ok {
 		return id, slug, nil
@@ -345,3 +347,52 @@ func TestSubagentTool_ReasoningOverride_Invalid(t *testing.T) {
 		t.Errorf("expected error to mention invalid level, got %v", result.Error)
 	}
 }
+
+func TestSubagentTool_Persona(t *testing.T) {
+	db := newMockSubagentDB()
+	runner := &mockSubagentRunner{response: "OK"}
+	tool := &SubagentTool{
+		DB:                   db,
+		ParentConversationID: "parent-123",
+		WorkingDir:           NewMutableWorkingDir("/tmp"),
+		Runner:               runner,
+		ModelID:              "claude-opus-4-6",
+		ParentReasoning:      "high",
+		AvailableModels:      []AvailableModel{{ID: "claude-opus-4-6"}, {ID: "claude-haiku-4.5"}},
+		Personas: map[string]SubagentPersona{
+			"security-reviewer": {Description: "Reviews changes for vulnerabilities", Model: "claude-haiku-4.5", Reasoning: "low"},
+		},
+	}
+	llmTool := tool.Tool()
+	if !strings.Contains(llmTool.Description, "security-reviewer: Reviews changes for vulnerabilities") {
+		t.Errorf("expected description to list personas, got %s", llmTool.Description)
+	}
+
+	run := func(input subagentInput) error {
+		inputJSON, _ := json.Marshal(input)
+		return llmTool.Run(context.Background(), inputJSON).Error
+	}
+
+	if err := run(subagentInput{Slug: "review", Prompt: "review", Persona: "security-reviewer"}); err != nil {
+		t.Fatal(err)
+	}
+	if db.lastPersona != "security-reviewer" {
+		t.Errorf("expected persona to reach the DB, got %q", db.lastPersona)
+	}
+	if runner.lastModelID != "claude-haiku-4.5" || runner.lastReasoning != "low" {
+		t.Errorf("expected persona model and reasoning, got %q %q", runner.lastModelID, runner.lastReasoning)
+	}
+
+	// Explicit choices win over the persona's.
+	if err := run(subagentInput{Slug: "review", Prompt: "review", Persona: "security-reviewer", Model: "claude-opus-4-6", Reasoning: "medium"}); err != nil {
+		t.Fatal(err)
+	}
+	if runner.lastModelID != "claude-opus-4-6" || runner.lastReasoning != "medium" {
+		t.Errorf("expected explicit model and reasoning, got %q %q", runner.lastModelID, runner.lastReasoning)
+	}
+
+	err := run(subagentInput{Slug: "x", Prompt: "x", Persona: "nope"})
+	if err == nil || !strings.Contains(err.Error(), "security-reviewer") {
+		t.Errorf("expected unknown persona error listing personas, got %v", err)
+	}
+}
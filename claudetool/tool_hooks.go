@@ -0,0 +1,60 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"shelley.exe.dev/llm"
+)
+
+// ToolHookCall describes a tool call to ToolHooks.
+type ToolHookCall struct {
+	Tool           string          `json:"tool"`
+	Input          json.RawMessage `json:"input"`
+	ConversationID string          `json:"conversation_id"`
+	Cwd            string          `json:"cwd"`
+}
+
+// ToolHooks check and annotate tool calls, e.g. by running the user's hook
+// scripts, so that policies can be enforced without changing the tools.
+type ToolHooks interface {
+	// BeforeTool runs before a call. A non-empty deny message refuses it.
+	BeforeTool(ctx context.Context, call ToolHookCall) (deny string, err error)
+	// AfterTool runs after a call. A non-empty note is added to its result.
+	AfterTool(ctx context.Context, call ToolHookCall, out llm.ToolOut) (note string, err error)
+}
+
+// applyToolHooks returns a copy of t whose Run calls hooks around the tool.
+func applyToolHooks(t *llm.Tool, hooks ToolHooks, conversationID string, wd *MutableWorkingDir) *llm.Tool {
+	if t.Run == nil {
+		return t
+	}
+	run := t.Run
+	wrapped := *t
+	wrapped.Run = func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+		call := ToolHookCall{Tool: t.Name, Input: input, ConversationID: conversationID, Cwd: wd.Get()}
+		deny, err := hooks.BeforeTool(ctx, call)
+		if err != nil {
+			return llm.ErrorfToolOut("pre-tool hook failed, so %s did not run: %v", t.Name, err)
+		}
+		if deny != "" {
+			return llm.ErrorfToolOut("denied by pre-tool hook: %s", deny)
+		}
+		out := run(ctx, input)
+		note, err := hooks.AfterTool(ctx, call, out)
+		if err != nil {
+			note = fmt.Sprintf("post-tool hook failed: %v", err)
+		}
+		switch {
+		case note == "":
+		case out.Error != nil:
+			out.Error = fmt.Errorf("%w\n\n%s", out.Error, note)
+		default:
+			out.LLMContent = append(slices.Clip(out.LLMContent), llm.Content{Type: llm.ContentTypeText, Text: note})
+		}
+		return out
+	}
+	return &wrapped
+}
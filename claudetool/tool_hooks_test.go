@@ -0,0 +1,56 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/llm"
+)
+
+type testToolHooks struct {
+	deny, note string
+	calls      []ToolHookCall
+}
+
+func (h *testToolHooks) BeforeTool(_ context.Context, call ToolHookCall) (string, error) {
+	h.calls = append(h.calls, call)
+	return h.deny, nil
+}
+
+func (h *testToolHooks) AfterTool(context.Context, ToolHookCall, llm.ToolOut) (string, error) {
+	return h.note, nil
+}
+
+func TestToolHooks(t *testing.T) {
+	ran := 0
+	tool := &llm.Tool{Name: "echo", Run: func(_ context.Context, input json.RawMessage) llm.ToolOut {
+		ran++
+		if string(input) == `"fail"` {
+			return llm.ErrorToolOut(errors.New("failed"))
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent("ok")}
+	}}
+	hooks := &testToolHooks{note: "formatted"}
+	wrapped := applyToolHooks(tool, hooks, "c1", NewMutableWorkingDir("/work"))
+
+	out := wrapped.Run(context.Background(), json.RawMessage(`"hi"`))
+	if len(out.LLMContent) != 2 || out.LLMContent[1].Text != "formatted" {
+		t.Errorf("note not appended: %+v", out.LLMContent)
+	}
+	if got := hooks.calls[0]; got.Tool != "echo" || got.ConversationID != "c1" || got.Cwd != "/work" || string(got.Input) != `"hi"` {
+		t.Errorf("unexpected hook call %+v", got)
+	}
+	out = wrapped.Run(context.Background(), json.RawMessage(`"fail"`))
+	if out.Error == nil || !strings.Contains(out.Error.Error(), "failed\n\nformatted") {
+		t.Errorf("note not added to error: %v", out.Error)
+	}
+
+	hooks.deny = "not allowed"
+	out = wrapped.Run(context.Background(), json.RawMessage(`"hi"`))
+	if out.Error == nil || !strings.Contains(out.Error.Error(), "not allowed") || ran != 2 {
+		t.Errorf("denied call: error %v, ran %d times", out.Error, ran)
+	}
+}
@@ -2,6 +2,8 @@ package claudetool
 
 import (
 	"context"
+	"log/slog"
+	"maps"
 	"os"
 	"strings"
 	"sync"
@@ -43,8 +45,9 @@ type ToolSetConfig struct {
 	LLMProvider LLMServiceProvider
 	// EnableJITInstall enables just-in-time tool installation.
 	EnableJITInstall bool
-	// EnableBrowser enables browser tools.
-	EnableBrowser bool
+	// BrowserPool, if set, enables the browser tools, which take their tab
+	// from it.
+	BrowserPool *browse.Pool
 	// ModelID is the model being used for this conversation.
 	// Used to determine tool configuration (e.g., simplified patch schema for weaker models).
 	ModelID string
@@ -80,6 +83,11 @@ type ToolSetConfig struct {
 	// A value of 0 means no limit (but SubagentRunner/SubagentDB must still be set).
 	// Set to 1 to allow only top-level conversations (depth 0) to spawn subagents.
 	MaxSubagentDepth int
+	// SubagentBudget caps the usage of all subagents under a top-level
+	// conversation; the server refuses to run subagents past it.
+	SubagentBudget SubagentBudget
+	// SubagentPersonas are the named subagent kinds offered by the subagent tool.
+	SubagentPersonas map[string]SubagentPersona
 	// BuildAvailableModels, if set, is called by NewToolSet to compute the
 	// list of models that subagent / llm_one_shot tools can choose from.
 	// It is invoked each time a ToolSet is built so new conversations pick
@@ -91,14 +99,115 @@ type ToolSetConfig struct {
 	ToolOverrides map[string]string
 	// DisableAllTools disables every tool by default; ToolOverrides with "on" re-enable.
 	DisableAllTools bool
+	// ModelToolOverrides maps model ID to tool overrides applied whenever
+	// that model is used. ToolOverrides take precedence over them.
+	ModelToolOverrides map[string]map[string]string
+	// SQLProfiles are the databases the sql tool can query, keyed by name.
+	// The sql tool is only available when at least one is configured.
+	SQLProfiles map[string]SQLProfile
+	// ToolPolicies bounds tool runs, keyed by tool name. The policy under
+	// DefaultToolPolicyKey applies to tools not listed.
+	ToolPolicies map[string]ToolPolicy
+	// Sandbox confines the commands run by the bash and shell tools.
+	// Just-in-time installation and the probe tool are disabled when a
+	// sandbox is set, since they run on the host.
+	Sandbox SandboxConfig
+	// Workspaces configures the docker workspaces conversations can be
+	// created in. Like VerifyCommands, it is applied by the server.
+	Workspaces WorkspaceConfig
+	// Remote, if set, runs commands and patches files on another machine
+	// instead of in Sandbox. The tools that read the host's files, listed
+	// in hostFileTools, are turned off.
+	Remote Remote
+	// ToolParallelism is how many read-only tool calls from one LLM response
+	// may run at once. It is applied by the conversation loop, not NewToolSet.
+	ToolParallelism int
+	// ContextLimit is the fraction of the model's context window a request
+	// may fill before the conversation loop stops the turn; zero disables
+	// the check. Like ToolParallelism, it is applied by the loop.
+	ContextLimit float64
+	// CompactWhenFull compacts a conversation whose request is over
+	// ContextLimit and continues the turn, instead of stopping it.
+	CompactWhenFull bool
+	// CacheToolResults makes repeated identical calls to read-only tools,
+	// such as keyword_search, return the earlier result until another tool
+	// runs or ToolSet.ClearCache is called.
+	CacheToolResults bool
+	// CheckPatchSyntax makes the patch tool refuse edits that introduce
+	// syntax errors into Go, Python, JavaScript, or TypeScript files.
+	CheckPatchSyntax bool
+	// VerifyCommands makes the system prompt list only the inferred build,
+	// test, and lint commands that cheap probes show can run. It is applied
+	// by the server when generating the system prompt, not NewToolSet.
+	VerifyCommands bool
+	// AnalysisSampleThreshold is the file count past which the system
+	// prompt's codebase analysis samples the repository rather than reading
+	// all of it; zero means onstart.DefaultSampleThreshold. Like
+	// VerifyCommands, it is applied by the server.
+	AnalysisSampleThreshold int
+	// GuidanceFileBytes and GuidanceTotalBytes cap the guidance files, such
+	// as AGENTS.md, in the system prompt, each and together; zero means the
+	// server's default and a negative value no cap. SummarizeGuidance has a
+	// cheap model condense files over the caps instead of truncating them.
+	// Like VerifyCommands, they are applied by the server.
+	GuidanceFileBytes  int
+	GuidanceTotalBytes int
+	SummarizeGuidance  bool
+	// GuidanceFileNames are names or paths of more guidance files, and
+	// ExcludedGuidanceFileNames built-in names to ignore, such as
+	// "readme.md". They are applied by the server.
+	GuidanceFileNames         []string
+	ExcludedGuidanceFileNames []string
+	// Scratchpad stores the scratchpad tool's notes. The tool is only
+	// available when this and ConversationID are set.
+	Scratchpad ScratchpadStore
+	// Messenger delivers handoff tool messages into other conversations.
+	// The tool is only available when this and ConversationID are set.
+	Messenger ConversationMessenger
+	// MCPTools are tools served by external MCP servers (see package mcp).
+	// They are shared by all conversations.
+	MCPTools []*llm.Tool
+	// ToolHooks, if set, run before and after every tool call.
+	ToolHooks ToolHooks
 }
 
+// toolOverrides returns the model's tool overrides with the conversation's
+// applied on top.
+func (cfg ToolSetConfig) toolOverrides() map[string]string {
+	model := cfg.ModelToolOverrides[cfg.ModelID]
+	if len(model) == 0 && cfg.Remote == nil {
+		return cfg.ToolOverrides
+	}
+	merged := maps.Clone(model)
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	maps.Copy(merged, cfg.ToolOverrides)
+	if cfg.Remote != nil {
+		for _, name := range hostFileTools {
+			merged[name] = "off"
+		}
+	}
+	return merged
+}
+
+// ToolEnabled reports whether a tool set built from cfg would include the
+// named tool, as far as its overrides decide.
+func (cfg ToolSetConfig) ToolEnabled(name string) bool {
+	return IsToolEnabled(name, cfg.toolOverrides(), cfg.DisableAllTools)
+}
+
+// hostFileTools are the tools that read or write files on the host
+// directly, rather than through the bash tool's sandbox or a Remote.
+var hostFileTools = []string{"keyword_search", "outline", "change_dir", "probe", "run_tests", "notebook", "output_iframe", "read_image", "llm_one_shot", "remember"}
+
 // ToolSet holds a set of tools for a single conversation.
 // Each conversation should have its own ToolSet.
 type ToolSet struct {
-	tools   []*llm.Tool
-	cleanup func()
-	wd      *MutableWorkingDir
+	tools      []*llm.Tool
+	cleanup    func()
+	wd         *MutableWorkingDir
+	clearCache func()
 }
 
 // Tools returns the tools in this set.
@@ -113,6 +222,15 @@ func (ts *ToolSet) Cleanup() {
 	}
 }
 
+// ClearCache forgets cached tool results (see ToolSetConfig.CacheToolResults).
+// Call it when a user turn starts, since files may have changed since the
+// last one.
+func (ts *ToolSet) ClearCache() {
+	if ts.clearCache != nil {
+		ts.clearCache()
+	}
+}
+
 // WorkingDir returns the shared working directory.
 func (ts *ToolSet) WorkingDir() *MutableWorkingDir {
 	return ts.wd
@@ -166,6 +284,7 @@ func isStrongModel(modelID string) bool {
 }
 
 func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
+	overrides := cfg.toolOverrides()
 	workingDir := cfg.WorkingDir
 	if workingDir == "" {
 		if home, err := os.UserHomeDir(); err == nil {
@@ -179,11 +298,18 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 	env := cfg.Env
 	env.ConversationID = cfg.ConversationID
 
+	sandbox := NewSandbox(cfg.Sandbox, cfg.ConversationID)
+	if cfg.Remote != nil {
+		sandbox = cfg.Remote
+	}
+	jitInstall := cfg.EnableJITInstall && sandbox == nil
+
 	bashTool := &BashTool{
 		WorkingDir:       wd,
 		LLMProvider:      cfg.LLMProvider,
-		EnableJITInstall: cfg.EnableJITInstall,
+		EnableJITInstall: jitInstall,
 		Env:              env,
+		Sandbox:          sandbox,
 	}
 
 	// Use simplified patch schema for weaker models, full schema for sonnet/opus
@@ -192,6 +318,8 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 		Simplified:       simplified,
 		WorkingDir:       wd,
 		ClipboardEnabled: true,
+		CheckSyntax:      cfg.CheckPatchSyntax,
+		Remote:           cfg.Remote,
 	}
 
 	keywordTool := NewKeywordToolWithWorkingDir(cfg.LLMProvider, wd)
@@ -206,19 +334,56 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 	shellTool := &ShellTool{
 		WorkingDir:       wd,
 		LLMProvider:      cfg.LLMProvider,
-		EnableJITInstall: cfg.EnableJITInstall,
+		EnableJITInstall: jitInstall,
 		Env:              env,
 		BackgroundCtx:    ctx,
+		Sandbox:          sandbox,
 	}
 
+	runTestsTool := &RunTestsTool{WorkingDir: wd, Env: env, Sandbox: sandbox}
+	diagnosticsTool := &DiagnosticsTool{WorkingDir: wd, Env: env, Sandbox: sandbox}
+	probeTool := &ProbeTool{WorkingDir: wd}
+	notebookTool := &NotebookTool{WorkingDir: wd, Env: env, Sandbox: sandbox}
+	outlineTool := &OutlineTool{WorkingDir: wd}
+	planTool := &PlanTool{}
+	useSkillTool := &UseSkillTool{WorkingDir: wd}
+	rememberTool := &RememberTool{WorkingDir: wd}
+
 	tools := []*llm.Tool{
 		bashTool.Tool(),
 		shellTool.Tool(),
 		patchTool.Tool(),
 		keywordTool.Tool(),
 		changeDirTool.Tool(),
+		runTestsTool.Tool(),
+		diagnosticsTool.Tool(),
+		notebookTool.Tool(),
+		outlineTool.Tool(),
+		planTool.Tool(),
+		useSkillTool.Tool(),
+		rememberTool.Tool(),
 		outputIframeTool.Tool(),
 	}
+	if sandbox == nil {
+		tools = append(tools, probeTool.Tool())
+	}
+
+	if cfg.Scratchpad != nil && cfg.ConversationID != "" {
+		scratchpadTool := &ScratchpadTool{Store: cfg.Scratchpad, ConversationID: cfg.ConversationID}
+		tools = append(tools, scratchpadTool.Tool())
+	}
+
+	if cfg.Messenger != nil && cfg.ConversationID != "" {
+		handoffTool := &HandoffTool{Messenger: cfg.Messenger, ConversationID: cfg.ConversationID}
+		tools = append(tools, handoffTool.Tool())
+	}
+
+	tools = append(tools, cfg.MCPTools...)
+
+	if len(cfg.SQLProfiles) > 0 {
+		sqlTool := &SQLTool{Profiles: cfg.SQLProfiles, WorkingDir: wd}
+		tools = append(tools, sqlTool.Tool())
+	}
 
 	// Build the available models list (shared by subagent and llm_one_shot tools).
 	// Resolved fresh on each ToolSet construction so new conversations see
@@ -244,6 +409,7 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 			ModelID:              cfg.ModelID, // Inherit parent's model
 			AvailableModels:      availableModels,
 			ParentReasoning:      cfg.ReasoningLevel,
+			Personas:             cfg.SubagentPersonas,
 		}
 		tools = append(tools, subagentTool.Tool())
 	}
@@ -259,16 +425,23 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 		tools = append(tools, llmOneShotTool.Tool())
 	}
 
-	var cleanup func()
+	var cleanups []func()
+	if sandbox != nil {
+		cleanups = append(cleanups, func() {
+			if err := sandbox.Close(); err != nil {
+				slog.Error("failed to close sandbox", "error", err)
+			}
+		})
+	}
 	anyBrowserToolEnabled := false
 	for _, name := range []string{"browser", "read_image"} {
-		if IsToolEnabled(name, cfg.ToolOverrides, cfg.DisableAllTools) {
+		if IsToolEnabled(name, overrides, cfg.DisableAllTools) {
 			anyBrowserToolEnabled = true
 			break
 		}
 	}
-	if cfg.EnableBrowser && anyBrowserToolEnabled {
-		browserTools, browserCleanup := browse.RegisterBrowserTools(ctx)
+	if cfg.BrowserPool != nil && anyBrowserToolEnabled {
+		browserTools, browserCleanup := browse.RegisterBrowserTools(ctx, cfg.BrowserPool)
 		if len(browserTools) > 0 {
 			// If the model doesn't support image inputs, drop read_image — it
 			// returns image content the model cannot consume. The `browser`
@@ -288,7 +461,7 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 				tools = append(tools, bt)
 			}
 		}
-		cleanup = browserCleanup
+		cleanups = append(cleanups, browserCleanup)
 	}
 
 	// Add server-side tools (e.g., web search for Anthropic models, or for
@@ -299,10 +472,36 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 		}
 	}
 
-	tools = FilterTools(tools, cfg.ToolOverrides, cfg.DisableAllTools)
+	tools = FilterTools(tools, overrides, cfg.DisableAllTools)
+	var summarize OutputSummarizer
+	if cfg.LLMProvider != nil {
+		summarize = LLMOutputSummarizer(cfg.LLMProvider)
+	}
+	for i, t := range tools {
+		if p, ok := policyFor(cfg.ToolPolicies, t.Name); ok {
+			tools[i] = applyPolicy(t, p, summarize)
+		}
+	}
+	var clearCache func()
+	if cfg.CacheToolResults {
+		tools, clearCache = cacheTools(tools, wd)
+	}
+	if cfg.ToolHooks != nil {
+		for i, t := range tools {
+			tools[i] = applyToolHooks(t, cfg.ToolHooks, cfg.ConversationID, wd)
+		}
+	}
+	for i, t := range tools {
+		tools[i] = traceTool(t, cfg.ConversationID)
+	}
 	return &ToolSet{
-		tools:   tools,
-		cleanup: cleanup,
-		wd:      wd,
+		tools: tools,
+		cleanup: func() {
+			for _, c := range cleanups {
+				c()
+			}
+		},
+		wd:         wd,
+		clearCache: clearCache,
 	}
 }
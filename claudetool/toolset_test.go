@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"shelley.exe.dev/claudetool/browse"
 	"shelley.exe.dev/llm"
 )
 
@@ -145,10 +146,10 @@ func TestNewToolSet_WithBrowser(t *testing.T) {
 	provider := &mockLLMProvider{}
 
 	cfg := ToolSetConfig{
-		LLMProvider:   provider,
-		ModelID:       "test-model",
-		WorkingDir:    "/test",
-		EnableBrowser: true,
+		LLMProvider: provider,
+		ModelID:     "test-model",
+		WorkingDir:  "/test",
+		BrowserPool: browse.NewPool(browse.DefaultTabsPerBrowser, browse.DefaultPoolIdleTimeout),
 	}
 
 	ctx := context.Background()
@@ -313,7 +314,7 @@ func TestToolDescriptions(t *testing.T) {
 		LLMProvider:          provider,
 		ModelID:              "claude-3-sonnet",
 		WorkingDir:           "/test",
-		EnableBrowser:        true,
+		BrowserPool:          browse.NewPool(browse.DefaultTabsPerBrowser, browse.DefaultPoolIdleTimeout),
 		SubagentRunner:       &mockSubagentRunner{},
 		SubagentDB:           &mockSubagentDB{},
 		ParentConversationID: "parent-123",
@@ -338,7 +339,6 @@ func TestToolDescriptions(t *testing.T) {
 		LLMProvider:          provider,
 		ModelID:              "claude-3-sonnet",
 		WorkingDir:           "/test",
-		EnableBrowser:        false,
 		SubagentRunner:       &mockSubagentRunner{},
 		SubagentDB:           &mockSubagentDB{},
 		ParentConversationID: "parent-123",
@@ -352,10 +352,10 @@ func TestToolDescriptions(t *testing.T) {
 
 	// Without subagent: should not include subagent
 	noSubagentCfg := ToolSetConfig{
-		LLMProvider:   provider,
-		ModelID:       "claude-3-sonnet",
-		WorkingDir:    "/test",
-		EnableBrowser: true,
+		LLMProvider: provider,
+		ModelID:     "claude-3-sonnet",
+		WorkingDir:  "/test",
+		BrowserPool: browse.NewPool(browse.DefaultTabsPerBrowser, browse.DefaultPoolIdleTimeout),
 	}
 	noSubagentTS := NewToolSet(context.Background(), noSubagentCfg)
 	for _, tool := range noSubagentTS.Tools() {
@@ -0,0 +1,40 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"shelley.exe.dev/llm"
+)
+
+var tracer = otel.Tracer("shelley.exe.dev/claudetool")
+
+// traceTool returns a copy of t whose Run records a span covering the call,
+// including its policy, cache, and hooks. Spans the tool starts, such as a
+// subagent's turns, are children of it.
+func traceTool(t *llm.Tool, conversationID string) *llm.Tool {
+	if t.Run == nil {
+		return t
+	}
+	run := t.Run
+	wrapped := *t
+	wrapped.Run = func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+		ctx, span := tracer.Start(ctx, "execute_tool "+t.Name, trace.WithAttributes(
+			semconv.GenAIOperationNameExecuteTool,
+			semconv.GenAIToolName(t.Name),
+			semconv.GenAIConversationID(conversationID),
+		))
+		defer span.End()
+		out := run(ctx, input)
+		if out.Error != nil {
+			span.RecordError(out.Error)
+			span.SetStatus(codes.Error, out.Error.Error())
+		}
+		return out
+	}
+	return &wrapped
+}
@@ -0,0 +1,201 @@
+package claudetool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WorkspaceConfig describes the docker containers of workspaces, which
+// conversations created with a repository to clone run in: the repository
+// is cloned into a fresh container, and every tool acts on the container.
+type WorkspaceConfig struct {
+	// Image is the container image. It must provide bash and git.
+	Image string `json:"image"`
+	// Dir is where the repository is cloned in the container. The default
+	// is /workspace.
+	Dir string `json:"dir,omitempty"`
+	// Mounts are more volumes, in docker's -v syntax, such as
+	// "/srv/cache:/cache:ro".
+	Mounts []string `json:"mounts,omitempty"`
+	// CPUs, Memory, and PidsLimit limit each container, as docker run's
+	// --cpus, --memory, and --pids-limit do. Empty or zero is unlimited.
+	CPUs      string `json:"cpus,omitempty"`
+	Memory    string `json:"memory,omitempty"`
+	PidsLimit int    `json:"pids_limit,omitempty"`
+	// Network allows network access from the containers.
+	Network bool `json:"network,omitempty"`
+}
+
+// Enabled reports whether workspaces are configured.
+func (c WorkspaceConfig) Enabled() bool {
+	return c.Image != ""
+}
+
+// Validate reports whether c's settings make sense.
+func (c WorkspaceConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.Dir != "" && !path.IsAbs(c.Dir) {
+		return fmt.Errorf("dir %q is not absolute", c.Dir)
+	}
+	if c.PidsLimit < 0 {
+		return fmt.Errorf("pids_limit must not be negative")
+	}
+	return nil
+}
+
+// WorkDir returns where repositories are cloned in the containers.
+func (c WorkspaceConfig) WorkDir() string {
+	if c.Dir == "" {
+		return "/workspace"
+	}
+	return c.Dir
+}
+
+// Workspace is a conversation's docker container, holding a clone of a
+// repository in Dir. Its tools run commands and edit files there.
+type Workspace struct {
+	// Container is the container's name.
+	Container string
+	// Dir is the clone's directory in the container.
+	Dir string
+}
+
+// CreateWorkspace starts the container called name and clones repo, at ref
+// if set, into it. It returns the workspace and the commit it cloned. The
+// repository is cloned on the host and copied in, so the container needs
+// no network access to get it.
+func CreateWorkspace(ctx context.Context, c WorkspaceConfig, name, repo, ref string) (*Workspace, string, error) {
+	tmp, err := os.MkdirTemp("", "shelley-workspace-")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(tmp)
+	clone := filepath.Join(tmp, "repo")
+	args := []string{"clone", "--quiet"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	if err := runHost(ctx, "git", append(args, "--", repo, clone)...); err != nil {
+		return nil, "", fmt.Errorf("failed to clone %s: %w", repo, err)
+	}
+	base, err := exec.CommandContext(ctx, "git", "-C", clone, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve the cloned commit: %w", err)
+	}
+
+	w := &Workspace{Container: name, Dir: c.WorkDir()}
+	args = []string{"run", "-d", "--name", name, "--label", "shelley.workspace=true", "--restart", "unless-stopped", "-w", w.Dir}
+	if !c.Network {
+		args = append(args, "--network", "none")
+	}
+	if c.CPUs != "" {
+		args = append(args, "--cpus", c.CPUs)
+	}
+	if c.Memory != "" {
+		args = append(args, "--memory", c.Memory)
+	}
+	if c.PidsLimit > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(c.PidsLimit))
+	}
+	for _, m := range c.Mounts {
+		args = append(args, "-v", m)
+	}
+	args = append(args, c.Image, "sleep", "infinity")
+	if err := runHost(ctx, "docker", args...); err != nil {
+		return nil, "", fmt.Errorf("failed to start container: %w", err)
+	}
+	if err := runHost(ctx, "docker", "cp", clone+"/.", name+":"+w.Dir); err != nil {
+		RemoveWorkspace(context.WithoutCancel(ctx), name)
+		return nil, "", fmt.Errorf("failed to copy %s into the container: %w", repo, err)
+	}
+	return w, string(bytes.TrimSpace(base)), nil
+}
+
+// RemoveWorkspace removes the workspace container called name, if it exists.
+func RemoveWorkspace(ctx context.Context, name string) error {
+	out, err := exec.CommandContext(ctx, "docker", "rm", "-f", name).CombinedOutput()
+	if err != nil && !bytes.Contains(out, []byte("No such container")) {
+		return fmt.Errorf("failed to remove container: %w\n%s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// runHost runs a command on the host, returning its output with any error.
+func runHost(ctx context.Context, name string, args ...string) error {
+	if out, err := exec.CommandContext(ctx, name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w\n%s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// Wrap makes cmd run in the container, in its Dir there.
+func (w *Workspace) Wrap(cmd *exec.Cmd) error {
+	args := dockerExecArgs(w.Container, cmd)
+	// The directory is the container's; docker runs on the host.
+	cmd.Dir = ""
+	return prependArgs(cmd, args...)
+}
+
+// Close leaves the container running, since the workspace outlives the
+// conversation's tools; see RemoveWorkspace.
+func (w *Workspace) Close() error { return nil }
+
+// exec runs args in the container with stdin and returns their stdout.
+// Exit status 66 (EX_NOINPUT) is reported as fs.ErrNotExist.
+func (w *Workspace) exec(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "docker", append([]string{"exec", "-i", w.Container}, args...)...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 66 {
+		return nil, fs.ErrNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func (w *Workspace) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	out, err := w.exec(ctx, nil, "sh", "-c", `[ -e "$1" ] || exit 66; exec cat -- "$1"`, "sh", name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return out, nil
+}
+
+func (w *Workspace) WriteFile(ctx context.Context, name string, data []byte) error {
+	if _, err := w.exec(ctx, data, "sh", "-c", `mkdir -p -- "$(dirname -- "$1")" && cat > "$1"`, "sh", name); err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	return nil
+}
+
+// diffScript prints the changes to repository $1 since commit $2,
+// including new files, without touching the repository's index.
+const diffScript = `set -e
+cd "$1"
+GIT_INDEX_FILE="$(mktemp -u)"
+export GIT_INDEX_FILE
+trap 'rm -f "$GIT_INDEX_FILE"' EXIT
+git add -A
+git diff --cached --binary "$2"`
+
+// Diff returns the changes made in the workspace since commit base, as a
+// patch that git apply accepts.
+func (w *Workspace) Diff(ctx context.Context, base string) ([]byte, error) {
+	return w.exec(ctx, nil, "sh", "-c", diffScript, "sh", w.Dir, base)
+}
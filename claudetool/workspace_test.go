@@ -0,0 +1,50 @@
+package claudetool
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWorkspaceConfigValidate(t *testing.T) {
+	cases := []struct {
+		cfg     WorkspaceConfig
+		wantErr bool
+	}{
+		{WorkspaceConfig{}, false},
+		{WorkspaceConfig{Dir: "relative"}, false},
+		{WorkspaceConfig{Image: "golang"}, false},
+		{WorkspaceConfig{Image: "golang", Dir: "/src"}, false},
+		{WorkspaceConfig{Image: "golang", Dir: "src"}, true},
+		{WorkspaceConfig{Image: "golang", PidsLimit: -1}, true},
+	}
+	for _, c := range cases {
+		if err := c.cfg.Validate(); (err != nil) != c.wantErr {
+			t.Errorf("%+v: got %v, wantErr %v", c.cfg, err, c.wantErr)
+		}
+	}
+	if got := (WorkspaceConfig{}).WorkDir(); got != "/workspace" {
+		t.Errorf("default WorkDir = %q", got)
+	}
+}
+
+func TestWorkspaceWrap(t *testing.T) {
+	// Wrap only looks docker up; a stand-in on PATH will do.
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	cmd := exec.Command("bash", "-c", "ls")
+	cmd.Dir = "/workspace/sub"
+	cmd.Env = []string{"SHELLEY_CONVERSATION_ID=c1"}
+	if err := (&Workspace{Container: "shelley-workspace-c1", Dir: "/workspace"}).Wrap(cmd); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"exec", "-i", "-w", "/workspace/sub", "-e", "SHELLEY_CONVERSATION_ID=c1", "shelley-workspace-c1", "bash", "-c", "ls"}
+	if !slices.Equal(cmd.Args[1:], want) || cmd.Path != filepath.Join(bin, "docker") || cmd.Dir != "" {
+		t.Errorf("Wrap made %q in %q", cmd.Args, cmd.Dir)
+	}
+}
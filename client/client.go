@@ -4,10 +4,12 @@ package client
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -58,6 +60,7 @@ func (f *multiFlag) Set(value string) error {
 type clientConfig struct {
 	serverURL string
 	headers   map[string]string
+	model     string // default for new conversations, from the profile
 }
 
 func (cc *clientConfig) newHTTPClient() (*http.Client, string, error) {
@@ -105,6 +108,7 @@ func (cc *clientConfig) newRequest(method, url string, body *strings.Reader) (*h
 func Run(args []string) {
 	fs := flag.NewFlagSet("client", flag.ExitOnError)
 	urlFlag := fs.String("url", defaultClientURL(), "Server URL (unix:///path, http://host:port, https://host:port)")
+	profileFlag := fs.String("profile", "", "Profile from "+profilePath()+" (default: the current profile)")
 	var headerFlags multiFlag
 	fs.Var(&headerFlags, "H", `Extra HTTP header ("Name: Value", can be repeated)`)
 	fs.Usage = func() {
@@ -114,10 +118,19 @@ func Run(args []string) {
 		fs.PrintDefaults()
 		fmt.Fprintf(fs.Output(), "\nSubcommands:\n")
 		fmt.Fprintf(fs.Output(), "  chat     Send a message (new or existing conversation)\n")
+		fmt.Fprintf(fs.Output(), "  run      Run a prompt to completion and print the result (for CI and cron)\n")
 		fmt.Fprintf(fs.Output(), "  read     Read conversation messages\n")
 		fmt.Fprintf(fs.Output(), "  list     List conversations\n")
 		fmt.Fprintf(fs.Output(), "  search   Search conversations by content\n")
 		fmt.Fprintf(fs.Output(), "  archive  Archive a conversation\n")
+		fmt.Fprintf(fs.Output(), "  models   List the server's models\n")
+		fmt.Fprintf(fs.Output(), "  tui      Interactive session: browse, stream, and chat\n")
+		fmt.Fprintf(fs.Output(), "  transcript  Export a conversation as Markdown, JSON, or HTML\n")
+		fmt.Fprintf(fs.Output(), "  watch    Print a line per state change across conversations\n")
+		fmt.Fprintf(fs.Output(), "  flush    Deliver messages queued by 'chat -spool'\n")
+		fmt.Fprintf(fs.Output(), "  review   Accept or revert a conversation's uncommitted changes hunk by hunk\n")
+		fmt.Fprintf(fs.Output(), "  usage    Report token and dollar totals by model or conversation\n")
+		fmt.Fprintf(fs.Output(), "  profile  Manage named server profiles (add, list, use, rm)\n")
 		fmt.Fprintf(fs.Output(), "  help     Print detailed help\n")
 	}
 	fs.Parse(args)
@@ -129,7 +142,7 @@ func Run(args []string) {
 			fmt.Fprintf(os.Stderr, "Error: invalid header %q (expected \"Name: Value\")\n", h)
 			os.Exit(1)
 		}
-		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		headers[http.CanonicalHeaderKey(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
 	}
 
 	cc := &clientConfig{serverURL: *urlFlag, headers: headers}
@@ -139,10 +152,37 @@ func Run(args []string) {
 		fs.Usage()
 		os.Exit(1)
 	}
+	if subArgs[0] == "profile" {
+		cmdProfile(subArgs[1:])
+		return
+	}
+
+	// An explicit -url bypasses the current profile, but not one named
+	// with -profile.
+	urlSet := false
+	fs.Visit(func(f *flag.Flag) { urlSet = urlSet || f.Name == "url" })
+	if !urlSet || *profileFlag != "" {
+		p, ok, err := resolveProfile(*profileFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if ok {
+			if !urlSet {
+				cc.serverURL = p.URL
+			}
+			if _, set := headers["Authorization"]; !set && p.Token != "" {
+				headers["Authorization"] = "Bearer " + p.Token
+			}
+			cc.model = p.Model
+		}
+	}
 
 	switch subArgs[0] {
 	case "chat":
 		cmdChat(cc, subArgs[1:])
+	case "run":
+		cmdRun(cc, subArgs[1:])
 	case "read":
 		cmdRead(cc, subArgs[1:])
 	case "list":
@@ -151,6 +191,20 @@ func Run(args []string) {
 		cmdSearch(cc, subArgs[1:])
 	case "archive":
 		cmdArchive(cc, subArgs[1:])
+	case "models":
+		cmdModels(cc, subArgs[1:])
+	case "tui":
+		cmdTUI(cc, subArgs[1:])
+	case "transcript":
+		cmdTranscript(cc, subArgs[1:])
+	case "watch":
+		cmdWatch(cc, subArgs[1:])
+	case "flush":
+		cmdFlush(subArgs[1:])
+	case "review":
+		cmdReview(cc, subArgs[1:])
+	case "usage":
+		cmdUsage(cc, subArgs[1:])
 	case "help":
 		cmdHelp()
 	default:
@@ -168,12 +222,31 @@ func cmdChat(cc *clientConfig, args []string) {
 	cwd := fs.String("cwd", "", "Working directory for the conversation")
 	ephemeral := fs.Bool("ephemeral", false, "Wait for end of turn, then archive the conversation (for cron-style cleanup)")
 	noNotify := fs.Bool("disable-notifications", false, "Disable end-of-turn notifications for this conversation (new conversations only)")
+	instructions := fs.String("instructions", "", "Instructions added to this conversation's system prompt (new conversations only)")
+	workerName := fs.String("worker", "", "Worker to run the conversation's tools on, in -cwd there (new conversations only)")
+	repo := fs.String("repo", "", "Repository to clone into a fresh workspace container for the conversation's tools (new conversations only)")
+	ref := fs.String("ref", "", "With -repo, the branch or tag to clone")
+	follow := fs.Bool("follow", false, "Stream the agent's reply to stdout until the turn ends")
+	tools := fs.Bool("tools", false, "With -follow, also print tool activity to stderr")
+	spool := fs.Bool("spool", false, "If the server is unreachable, queue the message for 'shelley client flush'")
 	fs.Parse(args)
 
 	if *prompt == "" {
 		fmt.Fprintf(os.Stderr, "Error: -p PROMPT is required\n")
 		os.Exit(1)
 	}
+	if *workerName != "" && *cwd == "" {
+		fmt.Fprintf(os.Stderr, "Error: -worker requires -cwd, a directory on the worker\n")
+		os.Exit(1)
+	}
+	if *ref != "" && *repo == "" {
+		fmt.Fprintf(os.Stderr, "Error: -ref requires -repo\n")
+		os.Exit(1)
+	}
+	if *spool && (*follow || *ephemeral) {
+		fmt.Fprintf(os.Stderr, "Error: -spool can't be combined with -follow or -ephemeral\n")
+		os.Exit(1)
+	}
 
 	client, baseURL, err := cc.newHTTPClient()
 	if err != nil {
@@ -191,21 +264,64 @@ func cmdChat(cc *clientConfig, args []string) {
 		}
 	}
 
+	// The stream replays the whole conversation, so remember where it stood
+	// before this message to wait for (and print) only the new turn.
+	var afterSeq int64
+	var offline error
+	if *convID != "" {
+		convModel, seq, err := conversationInfo(cc, client, baseURL, *convID)
+		switch {
+		case err == nil:
+			afterSeq = seq
+			if *model == "" {
+				*model = convModel
+			}
+		case *spool && isUnreachable(err):
+			// Without -model, the server keeps the conversation's model
+			// when the queued message is delivered.
+			offline = err
+		default:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if *model == "" {
+		*model = cc.model
+	}
+
 	reqBody := map[string]any{"message": *prompt}
 	if *model != "" {
 		reqBody["model"] = *model
 	}
+	// On an existing conversation, fail on a different -model rather than
+	// have the server quietly keep the conversation's.
+	if *convID != "" && *model != "" {
+		reqBody["require_model"] = true
+	}
 	if effectiveCwd != "" {
 		reqBody["cwd"] = effectiveCwd
 	}
 	// Conversation options are applied only at creation time, so
-	// -disable-notifications is meaningful only for new conversations (no -c).
+	// -disable-notifications, -instructions, -worker, and -repo are meaningful
+	// only for new conversations (no -c).
+	opts := map[string]any{}
 	if *noNotify {
+		opts["disable_notifications"] = true
+	}
+	if *instructions != "" {
+		opts["instructions"] = *instructions
+	}
+	if *workerName != "" {
+		opts["worker"] = *workerName
+	}
+	if *repo != "" {
+		opts["workspace"] = map[string]string{"repo": *repo, "ref": *ref}
+	}
+	if len(opts) > 0 {
 		if *convID != "" {
-			fmt.Fprintf(os.Stderr, "Error: -disable-notifications only applies to new conversations (omit -c)\n")
+			fmt.Fprintf(os.Stderr, "Error: -disable-notifications, -instructions, -worker, and -repo only apply to new conversations (omit -c)\n")
 			os.Exit(1)
 		}
-		reqBody["conversation_options"] = map[string]any{"disable_notifications": true}
+		reqBody["conversation_options"] = opts
 	}
 
 	bodyBytes, err := json.Marshal(reqBody)
@@ -214,14 +330,25 @@ func cmdChat(cc *clientConfig, args []string) {
 		os.Exit(1)
 	}
 
-	var apiURL string
+	apiPath := "/api/conversations/new"
 	if *convID != "" {
-		apiURL = baseURL + "/api/conversation/" + *convID + "/chat"
-	} else {
-		apiURL = baseURL + "/api/conversations/new"
+		apiPath = "/api/conversation/" + *convID + "/chat"
+	}
+	queue := func(reason error) {
+		id, err := spoolMessage(cc, apiPath, bodyBytes)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v (and queueing failed: %v)\n", reason, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Server unreachable (%v); queued the message. Deliver it with 'shelley client flush'.\n", reason)
+		json.NewEncoder(os.Stdout).Encode(map[string]string{"queued": id})
+	}
+	if offline != nil {
+		queue(offline)
+		return
 	}
 
-	req, err := cc.newRequest("POST", apiURL, strings.NewReader(string(bodyBytes)))
+	req, err := cc.newRequest("POST", baseURL+apiPath, strings.NewReader(string(bodyBytes)))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
 		os.Exit(1)
@@ -229,6 +356,10 @@ func cmdChat(cc *clientConfig, args []string) {
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := client.Do(req)
+	if err != nil && *spool && isUnreachable(err) {
+		queue(err)
+		return
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -236,12 +367,8 @@ func cmdChat(cc *clientConfig, args []string) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		var errBody map[string]any
-		if json.NewDecoder(resp.Body).Decode(&errBody) == nil {
-			fmt.Fprintf(os.Stderr, "Error (HTTP %d): %v\n", resp.StatusCode, errBody)
-		} else {
-			fmt.Fprintf(os.Stderr, "Error: HTTP %d\n", resp.StatusCode)
-		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		fmt.Fprintf(os.Stderr, "Error (HTTP %d): %s\n", resp.StatusCode, bytes.TrimSpace(msg))
 		os.Exit(1)
 	}
 
@@ -262,22 +389,125 @@ func cmdChat(cc *clientConfig, args []string) {
 		output["slug"] = slug
 	}
 
-	json.NewEncoder(os.Stdout).Encode(output)
+	// With -follow, stdout carries the reply, so the ID goes to stderr.
+	if *follow {
+		json.NewEncoder(os.Stderr).Encode(output)
+	} else {
+		json.NewEncoder(os.Stdout).Encode(output)
+	}
 
+	if !*follow && !*ephemeral {
+		return
+	}
+	cidStr, ok := cid.(string)
+	if !ok || cidStr == "" {
+		fmt.Fprintf(os.Stderr, "Error: could not determine conversation ID\n")
+		os.Exit(1)
+	}
+	var onMessage func(messageWire)
+	if *follow {
+		toolNames := make(map[string]string)
+		onMessage = func(msg messageWire) { printReply(msg, *tools, toolNames) }
+	}
+	failed := waitForEndOfTurn(cc, client, baseURL, cidStr, afterSeq, onMessage)
 	if *ephemeral {
-		cidStr, ok := cid.(string)
-		if !ok || cidStr == "" {
-			fmt.Fprintf(os.Stderr, "Error: -ephemeral could not determine conversation ID\n")
-			os.Exit(1)
-		}
-		waitForEndOfTurn(cc, client, baseURL, cidStr)
 		archiveConversation(cc, client, baseURL, cidStr)
 	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// conversationInfo returns the conversation's model and the sequence ID of
+// its latest message.
+func conversationInfo(cc *clientConfig, client *http.Client, baseURL, conversationID string) (model string, maxSeq int64, err error) {
+	req, err := cc.newRequest("GET", baseURL+"/api/conversation/"+conversationID, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var sr struct {
+		Conversation struct {
+			Model *string `json:"model"`
+		} `json:"conversation"`
+		MaxSequenceID int64 `json:"max_sequence_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&sr); err != nil {
+		return "", 0, fmt.Errorf("parsing response: %w", err)
+	}
+	if sr.Conversation.Model != nil {
+		model = *sr.Conversation.Model
+	}
+	return model, sr.MaxSequenceID, nil
 }
 
-// waitForEndOfTurn streams the conversation until the agent's turn ends.
-// Stream events are discarded; only end-of-turn detection is performed.
-func waitForEndOfTurn(cc *clientConfig, client *http.Client, baseURL, conversationID string) {
+// printReply writes the agent's text from msg to stdout and errors to
+// stderr. With tools, tool calls and results are summarized on stderr.
+func printReply(msg messageWire, tools bool, toolNames map[string]string) {
+	if msg.LlmData == nil {
+		return
+	}
+	var llmMsg llmMessageWire
+	if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+		return
+	}
+	for _, c := range llmMsg.Content {
+		switch {
+		case c.Type == contentTypeText && c.Text != "" && msg.Type == "agent":
+			fmt.Println(c.Text)
+		case c.Type == contentTypeText && c.Text != "" && msg.Type == "error":
+			fmt.Fprintf(os.Stderr, "Error: %s\n", c.Text)
+		case c.Type == contentTypeToolUse && tools:
+			fmt.Fprintln(os.Stderr, toolUseSummary(c, toolNames))
+		case c.Type == contentTypeToolResult && tools:
+			fmt.Fprintln(os.Stderr, toolResultSummary(c, toolNames))
+		}
+	}
+}
+
+// toolUseSummary describes a tool call in one line, remembering its name in
+// toolNames for toolResultSummary.
+func toolUseSummary(c llmContentWire, toolNames map[string]string) string {
+	toolNames[c.ID] = c.ToolName
+	return fmt.Sprintf("-> %s %s", c.ToolName, oneLine(string(c.ToolInput), 100))
+}
+
+// toolResultSummary describes a tool result in one line.
+func toolResultSummary(c llmContentWire, toolNames map[string]string) string {
+	status := "ok"
+	if c.ToolError {
+		status = "error"
+	}
+	var out string
+	for _, r := range c.ToolResult {
+		if r.Text != "" {
+			out = r.Text
+			break
+		}
+	}
+	return fmt.Sprintf("<- %s %s: %s", toolNames[c.ToolUseID], status, oneLine(out, 100))
+}
+
+// oneLine collapses whitespace in s and shortens it to at most max bytes.
+func oneLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > max {
+		return s[:max-3] + "..."
+	}
+	return s
+}
+
+// waitForEndOfTurn streams the conversation until the agent's turn ends,
+// passing each message after afterSeq to onMessage, if set. It reports
+// whether the turn ended in an error.
+func waitForEndOfTurn(cc *clientConfig, client *http.Client, baseURL, conversationID string, afterSeq int64, onMessage func(messageWire)) (failed bool) {
 	req, err := cc.newRequest("GET", baseURL+"/api/conversation/"+conversationID+"/stream", nil)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
@@ -312,8 +542,15 @@ func waitForEndOfTurn(cc *clientConfig, client *http.Client, baseURL, conversati
 			continue
 		}
 		for _, msg := range sr.Messages {
+			if msg.SequenceID <= afterSeq {
+				continue
+			}
+			afterSeq = msg.SequenceID
+			if onMessage != nil {
+				onMessage(msg)
+			}
 			if (msg.Type == "agent" || msg.Type == "error") && msg.EndOfTurn != nil && *msg.EndOfTurn {
-				return
+				return msg.Type == "error"
 			}
 		}
 	}
@@ -321,6 +558,9 @@ func waitForEndOfTurn(cc *clientConfig, client *http.Client, baseURL, conversati
 		fmt.Fprintf(os.Stderr, "Error reading stream: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Fprintf(os.Stderr, "Error: stream ended before the turn did\n")
+	os.Exit(1)
+	return false
 }
 
 func archiveConversation(cc *clientConfig, client *http.Client, baseURL, conversationID string) {
@@ -627,18 +867,83 @@ func cmdArchive(cc *clientConfig, args []string) {
 	fmt.Fprintf(os.Stderr, "Archived %s\n", conversationID)
 }
 
+func cmdModels(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client models", flag.ExitOnError)
+	fs.Parse(args)
+
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	req, err := cc.newRequest("GET", baseURL+"/api/models", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Error: HTTP %d\n", resp.StatusCode)
+		os.Exit(1)
+	}
+
+	var models []struct {
+		ID               string          `json:"id"`
+		DisplayName      string          `json:"display_name"`
+		Provider         string          `json:"provider"`
+		MaxContextTokens int             `json:"max_context_tokens"`
+		Cost             json.RawMessage `json:"cost"`
+		Ready            bool            `json:"ready"`
+		IsDefault        bool            `json:"is_default"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, m := range models {
+		json.NewEncoder(os.Stdout).Encode(struct {
+			ID            string          `json:"id"`
+			DisplayName   string          `json:"display_name,omitempty"`
+			Provider      string          `json:"provider,omitempty"`
+			ContextWindow int             `json:"context_window,omitempty"`
+			Cost          json.RawMessage `json:"cost,omitempty"` // USD per million tokens
+			Ready         bool            `json:"ready"`
+			Default       bool            `json:"default"`
+		}{m.ID, m.DisplayName, m.Provider, m.MaxContextTokens, m.Cost, m.Ready, m.IsDefault})
+	}
+}
+
 // --- Wire types for JSON parsing ---
 
 type streamResponseWire struct {
-	Messages  []messageWire `json:"messages"`
-	Heartbeat bool          `json:"heartbeat"`
+	ConversationID    string                 `json:"conversation_id,omitempty"`
+	Messages          []messageWire          `json:"messages"`
+	ConversationState *conversationStateWire `json:"conversation_state,omitempty"`
+	Heartbeat         bool                   `json:"heartbeat"`
+}
+
+type conversationStateWire struct {
+	ConversationID string `json:"conversation_id"`
+	Working        bool   `json:"working"`
+	Model          string `json:"model,omitempty"`
 }
 
 type messageWire struct {
-	SequenceID int64   `json:"sequence_id"`
-	Type       string  `json:"type"`
-	LlmData    *string `json:"llm_data,omitempty"`
-	EndOfTurn  *bool   `json:"end_of_turn,omitempty"`
+	SequenceID  int64   `json:"sequence_id"`
+	Type        string  `json:"type"`
+	LlmData     *string `json:"llm_data,omitempty"`
+	DisplayData *string `json:"display_data,omitempty"`
+	EndOfTurn   *bool   `json:"end_of_turn,omitempty"`
+	UsageData   *string `json:"usage_data,omitempty"`
 }
 
 type llmMessageWire struct {
@@ -646,14 +951,21 @@ type llmMessageWire struct {
 }
 
 type llmContentWire struct {
-	Type     int    `json:"Type"`
-	Text     string `json:"Text,omitempty"`
-	ToolName string `json:"ToolName,omitempty"`
+	ID         string           `json:"ID,omitempty"`
+	Type       int              `json:"Type"`
+	Text       string           `json:"Text,omitempty"`
+	Thinking   string           `json:"Thinking,omitempty"`
+	ToolName   string           `json:"ToolName,omitempty"`
+	ToolInput  json.RawMessage  `json:"ToolInput,omitempty"`
+	ToolUseID  string           `json:"ToolUseID,omitempty"`
+	ToolError  bool             `json:"ToolError,omitempty"`
+	ToolResult []llmContentWire `json:"ToolResult,omitempty"`
 }
 
 // Content type constants matching llm.ContentType iota values from llm/llm.go.
 const (
 	contentTypeText       = 2
+	contentTypeThinking   = 3
 	contentTypeToolUse    = 5
 	contentTypeToolResult = 6
 )
@@ -706,18 +1018,44 @@ Usage:
   shelley client [flags] <subcommand> [args...]
 
 Flags:
-  -url URL     Server URL (default: unix://%s)
-  -H HEADER    Extra HTTP header "Name: Value" (can be repeated)
+  -url URL       Server URL (default: unix://%[1]s)
+  -H HEADER      Extra HTTP header "Name: Value" (can be repeated)
+  -profile NAME  Use a saved profile instead of the current one
 
 Subcommands:
-  chat -p PROMPT [-c CONVERSATION_ID] [-model MODEL] [-cwd DIR] [-ephemeral] [-disable-notifications]
+  chat -p PROMPT [-c CONVERSATION_ID] [-model MODEL] [-cwd DIR] [-follow [-tools]] [-ephemeral] [-disable-notifications] [-instructions TEXT] [-worker NAME] [-repo URL [-ref REF]] [-spool]
       Send a message. Creates a new conversation unless -c is given.
+      With -c, the conversation's own model is used unless -model names
+      another, which the server rejects (switch with -p "/model MODEL").
       Prints JSON with conversation_id to stdout.
+      With -follow, prints the agent's reply to stdout as it arrives and
+      exits when the turn ends (status 1 if it ended in an error); the
+      conversation JSON goes to stderr instead. -tools adds one-line tool
+      call summaries on stderr.
       With -ephemeral, waits for the agent turn to end and then archives
       the conversation (useful for cron-style invocations that clean up
       after themselves).
       With -disable-notifications, disables end-of-turn notifications (push,
       email, discord, ntfy) for the conversation. New conversations only.
+      With -instructions, adds TEXT to the conversation's system prompt.
+      New conversations only; change them later with POST
+      /api/conversation/ID/instructions.
+      With -worker, runs the conversation's commands and file edits on
+      that "shelley worker", in -cwd, which is required and names a
+      directory there. New conversations only.
+      With -repo, the server clones URL, at -ref if given, into a fresh
+      workspace container and runs all the conversation's tools there;
+      fetch the result with GET /api/conversation/ID/workspace/diff. New
+      conversations only; needs workspaces in the server's shelley.json.
+      With -spool, a message the server can't be reached for is queued
+      instead of failing, and {"queued": ID} is printed.
+
+  run -p PROMPT [-cwd DIR] [-model MODEL] [-timeout DURATION] [-max-cost USD] [-tools]
+      Start a conversation in DIR (default .), wait for the agent to finish,
+      and print its final message to stdout. The conversation ID goes to
+      stderr. Exit status: 0 on success; 2 if -timeout or -max-cost was
+      reached, in which case the turn is cancelled; 3 if the agent ended in
+      an error; 1 if the server couldn't be reached.
 
   read [-wait] CONVERSATION_ID
       Read all messages in a conversation as JSON lines.
@@ -733,6 +1071,61 @@ Subcommands:
   archive CONVERSATION_ID
       Archive a conversation.
 
+  models
+      List the server's models as JSON lines: id, display_name, provider,
+      context_window, cost (USD per million tokens, when known), ready, and
+      default. Use an id as the -model value for chat.
+
+  tui [-c CONVERSATION_ID] [-model MODEL]
+      Interactive session for terminals without the web UI (e.g. over SSH).
+      Lists conversations, streams the open one live (messages, tool call
+      summaries, and optionally thinking), and sends what you type. Slash
+      commands: /list, /open, /new, /model, /models, /cancel, /thinking,
+      /quit; /help lists them.
+
+  transcript [-format md|json|html] [-tools=false] [-thinking] CONVERSATION_ID
+      Print the conversation for pasting into PRs and docs. Markdown (the
+      default) folds each tool call and its output into a <details> block.
+      -tools=false leaves tool calls out; -thinking includes the agent's
+      thinking.
+
+  watch [-c CONVERSATION_ID]... [-q QUERY]
+      Follow every conversation (or those named with -c, or whose slug
+      contains QUERY) and print a line per change: turn started, tool
+      call, failed tool, error, turn done. Each line shows the time, the
+      slug, and the conversation's cost so far. Reconnects if the server
+      goes away; meant for a terminal pane beside running agents.
+
+  flush [-retry]
+      Deliver messages queued by chat -spool, oldest first, printing
+      {"queued": ID, "conversation_id": ...} for each. Messages for a
+      server that is still down stay queued; with -retry, flush keeps
+      trying until they are all delivered. A message the server rejects
+      is set aside as a .failed file in %[2]s. Exits 1 if
+      anything was left queued or rejected.
+
+  review CONVERSATION_ID
+      Walk through the unstaged changes in the git repository holding the
+      conversation's working directory, one hunk at a time: accept stages
+      the hunk, revert undoes it in the working tree, skip leaves it.
+      Untracked files are offered whole (revert deletes them). Staged
+      changes count as accepted, so a later review resumes where this one
+      stopped. The repository must be on this machine.
+
+  usage [-since 7d|12h|DATE] [-by model|conversation] [-json]
+      Total LLM calls, tokens, and cost across all conversations, most
+      expensive first, as a table (or the raw report with -json). Costs
+      are priced from the model catalog where the model is known, and
+      otherwise are what the provider reported.
+
+  profile add -url URL [-token TOKEN] [-model MODEL] NAME
+  profile list | use NAME | rm NAME
+      Manage named servers, saved in %[3]s.
+      The current profile (marked * by list: the first one added, or the
+      last one picked with use) supplies the server URL, a bearer token,
+      and the default model for new conversations whenever -url isn't
+      given.
+
   help
       Print this help text.
 
@@ -747,9 +1140,19 @@ Examples:
   # Continue a conversation
   shelley client chat -c "$ID" -p "now count them"
 
+  # Ask and get the answer in one command
+  shelley client chat -follow -p "summarize README.md" > summary.txt
+
+  # Queue messages while offline and send them once the server is back
+  shelley client chat -spool -c "$ID" -p "also update the docs"
+  shelley client flush -retry
+
+  # Nightly job that fails the pipeline on errors or runaway cost
+  shelley client run -timeout 10m -max-cost 2 -p "fix the flaky test" || exit $?
+
   # Read current state
   shelley client read "$ID"
 
 NOTE: This feature is EXPERIMENTAL and may change without notice.
-`, DefaultSocketPath())
+`, DefaultSocketPath(), spoolDir(), profilePath())
 }
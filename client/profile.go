@@ -0,0 +1,164 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+)
+
+// profile is a named server the client can talk to.
+type profile struct {
+	URL   string `json:"url"`
+	Token string `json:"token,omitempty"` // sent as "Authorization: Bearer <token>"
+	Model string `json:"model,omitempty"` // default for new conversations
+}
+
+// profileFile is the client config file, client.json next to the default
+// socket.
+type profileFile struct {
+	Current  string             `json:"current,omitempty"`
+	Profiles map[string]profile `json:"profiles"`
+}
+
+func profilePath() string {
+	return filepath.Join(filepath.Dir(DefaultSocketPath()), "client.json")
+}
+
+// loadProfiles reads the config file; a missing file is an empty config.
+func loadProfiles() (*profileFile, error) {
+	pf := &profileFile{Profiles: map[string]profile{}}
+	b, err := os.ReadFile(profilePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return pf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, pf); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", profilePath(), err)
+	}
+	if pf.Profiles == nil {
+		pf.Profiles = map[string]profile{}
+	}
+	return pf, nil
+}
+
+func (pf *profileFile) save() error {
+	b, err := json.MarshalIndent(pf, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(profilePath()), 0o700); err != nil {
+		return err
+	}
+	// Tokens are secrets, so keep the file private.
+	return os.WriteFile(profilePath(), append(b, '\n'), 0o600)
+}
+
+// resolveProfile returns the profile named name, or the current profile
+// when name is empty. ok is false when no profile applies.
+func resolveProfile(name string) (p profile, ok bool, err error) {
+	pf, err := loadProfiles()
+	if err != nil {
+		return profile{}, false, err
+	}
+	if name == "" {
+		name = pf.Current
+		if name == "" {
+			return profile{}, false, nil
+		}
+	}
+	p, ok = pf.Profiles[name]
+	if !ok {
+		return profile{}, false, fmt.Errorf("unknown profile %q (see 'shelley client profile list')", name)
+	}
+	return p, true, nil
+}
+
+func cmdProfile(args []string) {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: shelley client profile <add|list|use|rm> ...\n")
+		os.Exit(1)
+	}
+	if len(args) == 0 {
+		usage()
+	}
+	pf, err := loadProfiles()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("client profile add", flag.ExitOnError)
+		url := fs.String("url", "", "Server URL (unix:///path, http://host:port, https://host:port) (required)")
+		token := fs.String("token", "", "Bearer token sent with every request")
+		model := fs.String("model", "", "Default model for new conversations")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 || *url == "" {
+			fmt.Fprintf(os.Stderr, "Usage: shelley client profile add -url URL [-token TOKEN] [-model MODEL] NAME\n")
+			os.Exit(1)
+		}
+		if _, _, err := parseClientURL(*url); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		name := fs.Arg(0)
+		pf.Profiles[name] = profile{URL: *url, Token: *token, Model: *model}
+		if pf.Current == "" {
+			pf.Current = name
+		}
+	case "list":
+		names := make([]string, 0, len(pf.Profiles))
+		for name := range pf.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, name := range names {
+			p := pf.Profiles[name]
+			marker := " "
+			if name == pf.Current {
+				marker = "*"
+			}
+			fmt.Fprintf(tw, "%s %s\t%s\t%s\n", marker, name, p.URL, p.Model)
+		}
+		tw.Flush()
+		return
+	case "use":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: shelley client profile use NAME\n")
+			os.Exit(1)
+		}
+		if _, ok := pf.Profiles[args[1]]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown profile %q\n", args[1])
+			os.Exit(1)
+		}
+		pf.Current = args[1]
+	case "rm":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: shelley client profile rm NAME\n")
+			os.Exit(1)
+		}
+		if _, ok := pf.Profiles[args[1]]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown profile %q\n", args[1])
+			os.Exit(1)
+		}
+		delete(pf.Profiles, args[1])
+		if pf.Current == args[1] {
+			pf.Current = ""
+		}
+	default:
+		usage()
+	}
+	if err := pf.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
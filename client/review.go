@@ -0,0 +1,290 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// reviewItem is one decision in a review: a hunk of a file's diff, or a
+// whole file when the diff has no hunks (binary files, mode changes) or
+// the file is untracked.
+type reviewItem struct {
+	path      string
+	header    string // the file's diff header, for building a one-hunk patch
+	hunk      string // empty for whole-file items
+	untracked bool
+}
+
+func cmdReview(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client review", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: shelley client review CONVERSATION_ID\n\n")
+		fmt.Fprintf(fs.Output(), "Step through the uncommitted changes in the conversation's git\n")
+		fmt.Fprintf(fs.Output(), "repository a hunk at a time, staging the ones you accept and\n")
+		fmt.Fprintf(fs.Output(), "reverting the ones you reject.\n")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	cwd, err := conversationCwd(cc, client, baseURL, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	root, err := gitOutput(cwd, "rev-parse", "--show-toplevel")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s is not in a git repository on this machine: %v\n", cwd, err)
+		os.Exit(1)
+	}
+	root = strings.TrimSpace(root)
+
+	items, err := reviewItems(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(items) == 0 {
+		fmt.Printf("No unstaged changes in %s\n", root)
+		return
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	var accepted, reverted, skipped int
+	for i, item := range items {
+		fmt.Printf("\n=== %s (%d/%d)\n", item.path, i+1, len(items))
+		switch {
+		case item.untracked:
+			fmt.Print(untrackedPreview(filepath.Join(root, item.path)))
+		case item.hunk == "":
+			fmt.Print(item.header)
+		default:
+			fmt.Print(item.hunk)
+		}
+		var action string
+		for action == "" {
+			fmt.Print("[a]ccept (stage), [r]evert, [s]kip, [q]uit? ")
+			line, err := in.ReadString('\n')
+			if err != nil && line == "" {
+				action = "q"
+				break
+			}
+			switch a := strings.TrimSpace(line); a {
+			case "a", "r", "s", "q":
+				action = a
+			}
+		}
+		if action == "q" {
+			skipped += len(items) - i
+			break
+		}
+		switch action {
+		case "a":
+			err = item.accept(root)
+			accepted++
+		case "r":
+			err = item.revert(root)
+			reverted++
+		case "s":
+			skipped++
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("\nAccepted %d, reverted %d, skipped %d. Accepted changes are staged; commit them with git commit.\n", accepted, reverted, skipped)
+}
+
+// conversationCwd returns the conversation's working directory.
+func conversationCwd(cc *clientConfig, client *http.Client, baseURL, conversationID string) (string, error) {
+	req, err := cc.newRequest("GET", baseURL+"/api/conversation/"+conversationID, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var sr struct {
+		Conversation struct {
+			Cwd *string `json:"cwd"`
+		} `json:"conversation"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&sr); err != nil {
+		return "", fmt.Errorf("parsing response: %w", err)
+	}
+	if sr.Conversation.Cwd == nil || *sr.Conversation.Cwd == "" {
+		return "", fmt.Errorf("conversation %s has no working directory", conversationID)
+	}
+	return *sr.Conversation.Cwd, nil
+}
+
+// reviewItems splits the unstaged diff in root into hunks, followed by
+// untracked files. Staged changes count as already accepted, so running
+// review again picks up where the last one stopped.
+func reviewItems(root string) ([]reviewItem, error) {
+	diff, err := gitOutput(root, "diff", "--no-color", "--no-ext-diff", "--no-renames")
+	if err != nil {
+		return nil, err
+	}
+	var items []reviewItem
+	for _, file := range splitBefore(diff, "diff --git ") {
+		parts := splitBefore(file, "@@ ")
+		header := parts[0]
+		path := diffPath(header)
+		if len(parts) == 1 {
+			items = append(items, reviewItem{path: path, header: header})
+			continue
+		}
+		for _, hunk := range parts[1:] {
+			items = append(items, reviewItem{path: path, header: header, hunk: hunk})
+		}
+	}
+
+	untracked, err := gitOutput(root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range strings.Split(untracked, "\x00") {
+		if path != "" {
+			items = append(items, reviewItem{path: path, untracked: true})
+		}
+	}
+	return items, nil
+}
+
+// splitBefore splits s into pieces that each start at a line beginning
+// with prefix. Text before the first such line is its own piece, unless
+// empty.
+func splitBefore(s, prefix string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], prefix) && i > start {
+			parts = append(parts, s[start:i])
+			start = i
+		}
+		next := strings.IndexByte(s[i:], '\n')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	if start < len(s) {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+// diffPath returns the path named by a "diff --git a/X b/X" header.
+func diffPath(header string) string {
+	line, _, _ := strings.Cut(header, "\n")
+	line = strings.TrimPrefix(line, "diff --git a/")
+	if a, b, ok := strings.Cut(line, " b/"); ok && a == b {
+		return a
+	}
+	// Paths containing " b/" are ambiguous on that line; the +++ or ---
+	// line names the file unambiguously.
+	for _, l := range strings.Split(header, "\n") {
+		if p, ok := strings.CutPrefix(l, "+++ b/"); ok {
+			return p
+		}
+		if p, ok := strings.CutPrefix(l, "--- a/"); ok {
+			return p
+		}
+	}
+	return line
+}
+
+// accept stages the item.
+func (item reviewItem) accept(root string) error {
+	if item.hunk == "" {
+		_, err := gitOutput(root, "add", "--", item.path)
+		return err
+	}
+	return gitApply(root, item.header+item.hunk, "--cached")
+}
+
+// revert undoes the item in the working tree, deleting untracked files.
+func (item reviewItem) revert(root string) error {
+	switch {
+	case item.untracked:
+		return os.Remove(filepath.Join(root, item.path))
+	case item.hunk == "":
+		_, err := gitOutput(root, "checkout", "--", item.path)
+		return err
+	}
+	return gitApply(root, item.header+item.hunk, "-R")
+}
+
+// gitApply applies a patch in root. Earlier decisions may have shifted
+// the hunk's line numbers; git apply finds it by its context.
+func gitApply(root, patch string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"apply"}, args...)...)
+	cmd.Dir = root
+	cmd.Stdin = strings.NewReader(patch)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git apply %s: %v: %s", strings.Join(args, " "), err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return string(out), nil
+}
+
+// untrackedPreview shows the start of a new file the way a diff would.
+func untrackedPreview(path string) string {
+	const maxLines = 40
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Sprintf("(new file: %v)\n", err)
+	}
+	defer f.Close()
+	head, _ := io.ReadAll(io.LimitReader(f, 64*1024))
+	if bytes.IndexByte(head, 0) >= 0 {
+		return "(new binary file)\n"
+	}
+	var b strings.Builder
+	b.WriteString("(new file)\n")
+	lines := strings.SplitAfter(string(head), "\n")
+	for i, line := range lines {
+		if i == maxLines {
+			fmt.Fprintf(&b, "... (%d more lines)\n", len(lines)-maxLines)
+			break
+		}
+		if line != "" {
+			b.WriteString("+" + strings.TrimSuffix(line, "\n") + "\n")
+		}
+	}
+	return b.String()
+}
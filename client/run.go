@@ -0,0 +1,273 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Exit codes for "shelley client run". Anything else (1) is a failure to
+// talk to the server.
+const (
+	runExitLimit = 2 // -timeout or -max-cost was reached and the turn cancelled
+	runExitAgent = 3 // the turn ended in an error
+)
+
+func cmdRun(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client run", flag.ExitOnError)
+	prompt := fs.String("p", "", "Message to send (required)")
+	model := fs.String("model", "", "Model to use (server default if empty)")
+	cwd := fs.String("cwd", ".", "Working directory for the conversation")
+	timeout := fs.Duration("timeout", 0, "Cancel the turn if it runs longer than this (0 for no limit)")
+	maxCost := fs.Float64("max-cost", 0, "Cancel the turn once it has cost more than this many USD (0 for no limit)")
+	tools := fs.Bool("tools", false, "Print tool activity to stderr")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: shelley client run -p PROMPT [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Start a conversation, wait for the agent to finish, and print its final\n")
+		fmt.Fprintf(fs.Output(), "message. Exits 0 on success, 2 if -timeout or -max-cost cancelled the\n")
+		fmt.Fprintf(fs.Output(), "turn, 3 if the agent hit an error, and 1 if the server couldn't be reached.\n\n")
+		fmt.Fprintf(fs.Output(), "Flags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *prompt == "" {
+		fmt.Fprintf(os.Stderr, "Error: -p PROMPT is required\n")
+		os.Exit(1)
+	}
+	dir, err := filepath.Abs(*cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	body := map[string]any{"message": *prompt, "cwd": dir}
+	if *model == "" {
+		*model = cc.model
+	}
+	if *model != "" {
+		body["model"] = *model
+	}
+	conversationID, err := startConversation(cc, client, baseURL, body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Conversation %s\n", conversationID)
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	r := &runTurn{maxCost: *maxCost, tools: *tools, toolNames: make(map[string]string)}
+	err = r.wait(ctx, cc, client, baseURL, conversationID)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, errMaxCost):
+		if errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprintf(os.Stderr, "Error: turn did not finish within %s; cancelling\n", *timeout)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: turn cost $%.4f, over the $%.4f limit; cancelling\n", r.cost, *maxCost)
+		}
+		if err := cancelConversation(cc, client, baseURL, conversationID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error cancelling: %v\n", err)
+		}
+		os.Exit(runExitLimit)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	case r.failed:
+		fmt.Fprintf(os.Stderr, "Error: %s\n", r.final)
+		os.Exit(runExitAgent)
+	}
+	fmt.Println(r.final)
+}
+
+var errMaxCost = errors.New("cost limit reached")
+
+// TurnResult is the outcome of RunTurn.
+type TurnResult struct {
+	ConversationID string
+	// Final is the text of the last agent or error message.
+	Final   string
+	Failed  bool // the turn ended in an error
+	CostUSD float64
+}
+
+// RunTurn starts a conversation in cwd on the server at serverURL, such as
+// unix:///path/to/shelley.sock, and waits for its turn to end. An empty
+// model means the server's default. If ctx ends first, RunTurn cancels the
+// turn and returns ctx's error with the result so far.
+func RunTurn(ctx context.Context, serverURL, prompt, model, cwd string) (TurnResult, error) {
+	cc := &clientConfig{serverURL: serverURL}
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		return TurnResult{}, err
+	}
+	body := map[string]any{"message": prompt, "cwd": cwd}
+	if model != "" {
+		body["model"] = model
+	}
+	conversationID, err := startConversation(cc, client, baseURL, body)
+	if err != nil {
+		return TurnResult{}, err
+	}
+	r := &runTurn{toolNames: make(map[string]string)}
+	err = r.wait(ctx, cc, client, baseURL, conversationID)
+	if ctx.Err() != nil {
+		if cancelErr := cancelConversation(cc, client, baseURL, conversationID); cancelErr != nil {
+			err = errors.Join(err, fmt.Errorf("cancelling: %w", cancelErr))
+		}
+	}
+	return TurnResult{ConversationID: conversationID, Final: r.final, Failed: r.failed, CostUSD: r.cost}, err
+}
+
+// runTurn follows one turn of a conversation for cmdRun.
+type runTurn struct {
+	maxCost   float64
+	tools     bool
+	toolNames map[string]string
+
+	cost   float64
+	final  string // text of the last agent or error message
+	failed bool
+}
+
+// wait streams the conversation until its turn ends, returning
+// context.DeadlineExceeded on timeout and errMaxCost once the turn costs
+// more than r.maxCost.
+func (r *runTurn) wait(ctx context.Context, cc *clientConfig, client *http.Client, baseURL, conversationID string) error {
+	req, err := cc.newRequest("GET", baseURL+"/api/conversation/"+conversationID+"/stream", nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var sr streamResponseWire
+		if err := json.Unmarshal([]byte(data), &sr); err != nil || sr.Heartbeat {
+			continue
+		}
+		// A new conversation's stream replays only this turn, so every
+		// message counts.
+		for _, msg := range sr.Messages {
+			if r.message(msg) {
+				return nil
+			}
+		}
+		if r.maxCost > 0 && r.cost > r.maxCost {
+			return errMaxCost
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading stream: %w", err)
+	}
+	return fmt.Errorf("stream ended before the turn did")
+}
+
+// message records msg, reporting whether it ended the turn.
+func (r *runTurn) message(msg messageWire) (done bool) {
+	r.cost += messageCost(msg)
+	if msg.LlmData == nil || (msg.Type != "agent" && msg.Type != "error") {
+		return false
+	}
+	var llmMsg llmMessageWire
+	if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+		return false
+	}
+	var texts []string
+	for _, c := range llmMsg.Content {
+		switch {
+		case c.Type == contentTypeText && c.Text != "":
+			texts = append(texts, c.Text)
+		case c.Type == contentTypeToolUse && r.tools:
+			fmt.Fprintln(os.Stderr, toolUseSummary(c, r.toolNames))
+		}
+	}
+	if len(texts) > 0 {
+		r.final = strings.Join(texts, "\n")
+	}
+	if msg.EndOfTurn != nil && *msg.EndOfTurn {
+		r.failed = msg.Type == "error"
+		return true
+	}
+	return false
+}
+
+// startConversation creates a conversation from body and returns its ID.
+func startConversation(cc *clientConfig, client *http.Client, baseURL string, body map[string]any) (string, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	req, err := cc.newRequest("POST", baseURL+"/api/conversations/new", strings.NewReader(string(b)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var created struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("parsing response: %w", err)
+	}
+	return created.ConversationID, nil
+}
+
+func cancelConversation(cc *clientConfig, client *http.Client, baseURL, conversationID string) error {
+	req, err := cc.newRequest("POST", baseURL+"/api/conversation/"+conversationID+"/cancel", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
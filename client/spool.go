@@ -0,0 +1,195 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// spooledMessage is a chat request that couldn't reach the server, kept
+// for "shelley client flush".
+type spooledMessage struct {
+	ServerURL string            `json:"server_url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Path      string            `json:"path"` // e.g. /api/conversation/<id>/chat
+	Body      json.RawMessage   `json:"body"`
+	QueuedAt  time.Time         `json:"queued_at"`
+}
+
+// spoolDir holds one file per queued message, named so that sorting the
+// names gives delivery order.
+func spoolDir() string {
+	return filepath.Join(filepath.Dir(DefaultSocketPath()), "spool")
+}
+
+// spoolMessage queues a chat request and returns its spool ID.
+func spoolMessage(cc *clientConfig, path string, body []byte) (string, error) {
+	now := time.Now()
+	b, err := json.MarshalIndent(spooledMessage{
+		ServerURL: cc.serverURL,
+		Headers:   cc.headers,
+		Path:      path,
+		Body:      body,
+		QueuedAt:  now,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(spoolDir(), 0o700); err != nil {
+		return "", err
+	}
+	id := now.UTC().Format("20060102T150405.000000000")
+	// Headers may carry tokens, so keep the file private.
+	if err := os.WriteFile(filepath.Join(spoolDir(), id+".json"), append(b, '\n'), 0o600); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func cmdFlush(args []string) {
+	fs := flag.NewFlagSet("client flush", flag.ExitOnError)
+	retry := fs.Bool("retry", false, "If a server is unreachable, keep retrying until it answers")
+	fs.Parse(args)
+
+	backoff := time.Second
+	failed := 0
+	for {
+		pending, rejected, err := flushSpool()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		failed += rejected
+		if pending == 0 {
+			if failed > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+		if !*retry {
+			fmt.Fprintf(os.Stderr, "%d message(s) still queued; run 'shelley client flush' again later\n", pending)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "%d message(s) still queued; retrying in %s\n", pending, backoff)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+// flushSpool delivers queued messages in order and returns how many are
+// left and how many the server rejected. A message for an unreachable server stays queued, along with the
+// later messages for that server, so they arrive in order. A message the
+// server rejects is renamed to .failed so it stops blocking the queue.
+func flushSpool() (pending, rejected int, err error) {
+	entries, err := os.ReadDir(spoolDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	var names []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	unreachable := make(map[string]bool) // server URLs
+	for _, name := range names {
+		path := filepath.Join(spoolDir(), name)
+		id := strings.TrimSuffix(name, ".json")
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return 0, 0, err
+		}
+		var m spooledMessage
+		if err := json.Unmarshal(b, &m); err != nil {
+			return 0, 0, fmt.Errorf("parse %s: %w", path, err)
+		}
+		if unreachable[m.ServerURL] {
+			pending++
+			continue
+		}
+		conversationID, err := deliverSpooled(m)
+		var rejectedErr *rejectedError
+		switch {
+		case errors.As(err, &rejectedErr):
+			fmt.Fprintf(os.Stderr, "Error: queued message %s rejected: %v; kept as %s.failed\n", id, err, path)
+			if err := os.Rename(path, path+".failed"); err != nil {
+				return 0, 0, err
+			}
+			rejected++
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "%s unreachable: %v\n", m.ServerURL, err)
+			unreachable[m.ServerURL] = true
+			pending++
+		default:
+			json.NewEncoder(os.Stdout).Encode(map[string]string{"queued": id, "conversation_id": conversationID})
+			if err := os.Remove(path); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	return pending, rejected, nil
+}
+
+// rejectedError is a response from the server refusing a queued message;
+// retrying won't help.
+type rejectedError struct {
+	status int
+	body   string
+}
+
+func (e *rejectedError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.status, e.body)
+}
+
+// deliverSpooled sends m and returns the conversation it went to.
+func deliverSpooled(m spooledMessage) (string, error) {
+	cc := &clientConfig{serverURL: m.ServerURL, headers: m.Headers}
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		return "", &rejectedError{body: err.Error()}
+	}
+	req, err := cc.newRequest("POST", baseURL+m.Path, strings.NewReader(string(m.Body)))
+	if err != nil {
+		return "", &rejectedError{body: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", &rejectedError{status: resp.StatusCode, body: strings.TrimSpace(string(msg))}
+	}
+	var created struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	if created.ConversationID == "" {
+		// The chat endpoint doesn't echo the ID back.
+		created.ConversationID = strings.TrimSuffix(strings.TrimPrefix(m.Path, "/api/conversation/"), "/chat")
+	}
+	return created.ConversationID, nil
+}
+
+// isUnreachable reports whether err, from client.Do, means the server
+// couldn't be reached, as opposed to answering with an error.
+func isUnreachable(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,299 @@
+package client
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// transcript is a conversation reduced to what a reader needs, rendered by
+// the transcript subcommand.
+type transcript struct {
+	ConversationID string            `json:"conversation_id"`
+	Slug           string            `json:"slug,omitempty"`
+	Model          string            `json:"model,omitempty"`
+	Entries        []transcriptEntry `json:"entries"`
+}
+
+// transcriptEntry is one block of a transcript: text from the user, the
+// agent, or an error; the agent's thinking; or a tool call with its result.
+type transcriptEntry struct {
+	Role       string          `json:"role"` // "user", "agent", "error", or "tool"
+	Text       string          `json:"text,omitempty"`
+	Thinking   string          `json:"thinking,omitempty"`
+	ToolName   string          `json:"tool_name,omitempty"`
+	ToolInput  json.RawMessage `json:"tool_input,omitempty"`
+	ToolOutput string          `json:"tool_output,omitempty"`
+	ToolError  bool            `json:"tool_error,omitempty"`
+	Diff       string          `json:"diff,omitempty"` // the change a patch tool call made
+}
+
+func cmdTranscript(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client transcript", flag.ExitOnError)
+	format := fs.String("format", "md", "Output format: md, json, or html")
+	tools := fs.Bool("tools", true, "Include tool calls and their output")
+	thinking := fs.Bool("thinking", false, "Include the agent's thinking")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: shelley client transcript [-format md|json|html] [-tools=false] [-thinking] CONVERSATION_ID\n")
+		os.Exit(1)
+	}
+	var render func(io.Writer, transcript) error
+	switch *format {
+	case "md":
+		render = renderMarkdownTranscript
+	case "json":
+		render = func(w io.Writer, t transcript) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(t)
+		}
+	case "html":
+		render = renderHTMLTranscript
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (use md, json, or html)\n", *format)
+		os.Exit(1)
+	}
+	conversationID := fs.Arg(0)
+
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	req, err := cc.newRequest("GET", baseURL+"/api/conversation/"+conversationID, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
+		os.Exit(1)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Error: HTTP %d\n", resp.StatusCode)
+		os.Exit(1)
+	}
+	var sr struct {
+		streamResponseWire
+		Conversation struct {
+			Slug  *string `json:"slug"`
+			Model *string `json:"model"`
+		} `json:"conversation"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&sr); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	t := buildTranscript(sr.Messages, *tools, *thinking)
+	t.ConversationID = conversationID
+	if sr.Conversation.Slug != nil {
+		t.Slug = *sr.Conversation.Slug
+	}
+	if sr.Conversation.Model != nil {
+		t.Model = *sr.Conversation.Model
+	}
+	if err := render(os.Stdout, t); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// buildTranscript turns messages into transcript entries, pairing each tool
+// result with its call. System messages are left out.
+func buildTranscript(messages []messageWire, tools, thinking bool) transcript {
+	var t transcript
+	calls := make(map[string]int) // tool use ID -> index in t.Entries
+	for _, msg := range messages {
+		if msg.LlmData == nil || msg.Type == "system" {
+			continue
+		}
+		var llmMsg llmMessageWire
+		if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+			continue
+		}
+		diffs := toolDiffs(msg.DisplayData)
+		for _, c := range llmMsg.Content {
+			switch c.Type {
+			case contentTypeText:
+				if c.Text == "" {
+					continue
+				}
+				role := msg.Type
+				if role != "user" && role != "error" {
+					role = "agent"
+				}
+				t.Entries = append(t.Entries, transcriptEntry{Role: role, Text: c.Text})
+			case contentTypeThinking:
+				if thinking && c.Thinking != "" {
+					t.Entries = append(t.Entries, transcriptEntry{Role: "agent", Thinking: c.Thinking})
+				}
+			case contentTypeToolUse:
+				if tools {
+					calls[c.ID] = len(t.Entries)
+					t.Entries = append(t.Entries, transcriptEntry{Role: "tool", ToolName: c.ToolName, ToolInput: c.ToolInput})
+				}
+			case contentTypeToolResult:
+				i, ok := calls[c.ToolUseID]
+				if !ok {
+					continue
+				}
+				var out []string
+				for _, r := range c.ToolResult {
+					if r.Text != "" {
+						out = append(out, r.Text)
+					}
+				}
+				t.Entries[i].ToolOutput = strings.TrimRight(strings.Join(out, "\n"), "\n")
+				t.Entries[i].ToolError = c.ToolError
+				t.Entries[i].Diff = diffs[c.ToolUseID]
+			}
+		}
+	}
+	return t
+}
+
+// toolDiffs returns the diffs in a message's display data, keyed by tool use ID.
+func toolDiffs(displayData *string) map[string]string {
+	if displayData == nil {
+		return nil
+	}
+	var items []struct {
+		ToolUseID string `json:"tool_use_id"`
+		Display   struct {
+			Diff string `json:"diff"`
+		} `json:"display"`
+	}
+	if json.Unmarshal([]byte(*displayData), &items) != nil {
+		return nil
+	}
+	diffs := make(map[string]string)
+	for _, item := range items {
+		if item.Display.Diff != "" {
+			diffs[item.ToolUseID] = item.Display.Diff
+		}
+	}
+	return diffs
+}
+
+// toolInputText formats a tool call's input for display: a bare command
+// for tools that take one, indented JSON otherwise.
+func toolInputText(input json.RawMessage) string {
+	var fields map[string]any
+	if json.Unmarshal(input, &fields) == nil && len(fields) == 1 {
+		if cmd, ok := fields["command"].(string); ok {
+			return cmd
+		}
+	}
+	var v any
+	if json.Unmarshal(input, &v) != nil {
+		return string(input)
+	}
+	b, _ := json.MarshalIndent(v, "", "  ")
+	return string(b)
+}
+
+// fence returns a backtick fence longer than any backtick run in s.
+func fence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func renderMarkdownTranscript(w io.Writer, t transcript) error {
+	var sb strings.Builder
+	title := t.Slug
+	if title == "" {
+		title = t.ConversationID
+	}
+	fmt.Fprintf(&sb, "# %s\n", title)
+	if t.Model != "" {
+		fmt.Fprintf(&sb, "\nModel: `%s`\n", t.Model)
+	}
+	for _, e := range t.Entries {
+		switch {
+		case e.Role == "tool":
+			summary := e.ToolName
+			if e.ToolError {
+				summary += " (error)"
+			}
+			in := toolInputText(e.ToolInput)
+			fmt.Fprintf(&sb, "\n<details>\n<summary>%s</summary>\n\n%s\n%s\n%s\n", template.HTMLEscapeString(summary), fence(in), in, fence(in))
+			if e.ToolOutput != "" {
+				fmt.Fprintf(&sb, "\n%s\n%s\n%s\n", fence(e.ToolOutput), e.ToolOutput, fence(e.ToolOutput))
+			}
+			if e.Diff != "" {
+				diff := strings.TrimRight(e.Diff, "\n")
+				fmt.Fprintf(&sb, "\n%sdiff\n%s\n%s\n", fence(diff), diff, fence(diff))
+			}
+			sb.WriteString("\n</details>\n")
+		case e.Thinking != "":
+			fmt.Fprintf(&sb, "\n> *Thinking:* %s\n", strings.ReplaceAll(e.Thinking, "\n", "\n> "))
+		case e.Role == "user":
+			fmt.Fprintf(&sb, "\n**User:**\n\n%s\n", e.Text)
+		case e.Role == "error":
+			fmt.Fprintf(&sb, "\n**Error:** %s\n", e.Text)
+		default:
+			fmt.Fprintf(&sb, "\n**Shelley:**\n\n%s\n", e.Text)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+var transcriptHTML = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"toolInput": toolInputText,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{or .Slug .ConversationID}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 50rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; }
+.entry { margin: 1rem 0; white-space: pre-wrap; }
+.role { font-weight: bold; }
+.user { background: #f1f5f9; padding: 0.5rem 0.75rem; border-radius: 0.5rem; }
+.error { color: #b91c1c; }
+.thinking { color: #64748b; font-style: italic; }
+.diff { border-left: 3px solid #16a34a; }
+pre { background: #f8fafc; border: 1px solid #e2e8f0; padding: 0.5rem; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>{{or .Slug .ConversationID}}</h1>
+{{if .Model}}<p>Model: <code>{{.Model}}</code></p>{{end}}
+{{range .Entries}}{{if eq .Role "tool"}}<details>
+<summary>{{.ToolName}}{{if .ToolError}} (error){{end}}</summary>
+<pre>{{toolInput .ToolInput}}</pre>
+{{if .ToolOutput}}<pre>{{.ToolOutput}}</pre>{{end}}
+{{if .Diff}}<pre class="diff">{{.Diff}}</pre>{{end}}
+</details>
+{{else if .Thinking}}<div class="entry thinking">{{.Thinking}}</div>
+{{else if eq .Role "user"}}<div class="entry user"><span class="role">User:</span>
+{{.Text}}</div>
+{{else if eq .Role "error"}}<div class="entry error"><span class="role">Error:</span> {{.Text}}</div>
+{{else}}<div class="entry"><span class="role">Shelley:</span>
+{{.Text}}</div>
+{{end}}{{end}}</body>
+</html>
+`))
+
+func renderHTMLTranscript(w io.Writer, t transcript) error {
+	return transcriptHTML.Execute(w, t)
+}
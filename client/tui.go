@@ -0,0 +1,417 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// tui is an interactive session: it streams the open conversation to
+// stdout while reading messages and slash commands from stdin. It is
+// line-oriented rather than full-screen, so it works in any terminal,
+// including over SSH.
+type tui struct {
+	cc      *clientConfig
+	client  *http.Client
+	baseURL string
+
+	mu           sync.Mutex // guards everything below and serializes output
+	convID       string
+	model        string // for new conversations
+	showThinking bool
+	listed       []string // conversation IDs from the last /list, for /open N
+	stopStream   context.CancelFunc
+}
+
+func cmdTUI(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client tui", flag.ExitOnError)
+	convID := fs.String("c", "", "Conversation ID to open")
+	model := fs.String("model", "", "Model for new conversations (server default if empty)")
+	fs.Parse(args)
+
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *model == "" {
+		*model = cc.model
+	}
+	t := &tui{cc: cc, client: client, baseURL: baseURL, model: *model}
+	if *convID != "" {
+		t.open(*convID)
+	} else {
+		t.list()
+	}
+	t.printf("Type a message, or /help for commands.\n")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if !t.handle(strings.TrimSpace(scanner.Text())) {
+			break
+		}
+	}
+	t.mu.Lock()
+	if t.stopStream != nil {
+		t.stopStream()
+	}
+	t.mu.Unlock()
+}
+
+func (t *tui) printf(format string, args ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Printf(format, args...)
+}
+
+const tuiHelp = `Commands:
+  /list            List recent conversations
+  /open N|ID       Open conversation N from /list, or by ID, and stream it
+  /new             Start a new conversation with the next message
+  /model [MODEL]   Show or switch the model (and reasoning level)
+  /models          List available models
+  /cancel          Cancel the current turn
+  /thinking        Toggle display of the agent's thinking
+  /quit            Exit
+Anything else is sent as a message.
+`
+
+// handle runs one line of input, reporting false when the session should end.
+func (t *tui) handle(line string) bool {
+	if line == "" {
+		return true
+	}
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case "/help":
+		t.printf("%s", tuiHelp)
+	case "/list":
+		t.list()
+	case "/open":
+		t.openArg(arg)
+	case "/new":
+		t.mu.Lock()
+		if t.stopStream != nil {
+			t.stopStream()
+			t.stopStream = nil
+		}
+		t.convID = ""
+		t.mu.Unlock()
+		t.printf("The next message starts a new conversation.\n")
+	case "/model":
+		t.mu.Lock()
+		convID := t.convID
+		if convID == "" && arg != "" {
+			t.model = arg
+		}
+		model := t.model
+		t.mu.Unlock()
+		// In a conversation, the server's /model command switches it and
+		// replies in the transcript.
+		if convID != "" {
+			t.send(line)
+		} else if model == "" {
+			t.printf("New conversations use the server's default model.\n")
+		} else {
+			t.printf("New conversations use %s.\n", model)
+		}
+	case "/models":
+		t.models()
+	case "/cancel":
+		t.cancel()
+	case "/thinking":
+		t.mu.Lock()
+		t.showThinking = !t.showThinking
+		state := "off"
+		if t.showThinking {
+			state = "on"
+		}
+		t.mu.Unlock()
+		t.printf("Thinking display %s (applies to new messages).\n", state)
+	case "/quit", "/exit":
+		return false
+	default:
+		t.send(line)
+	}
+	return true
+}
+
+// do sends a request and decodes a JSON response into out, if non-nil.
+func (t *tui) do(method, path string, body, out any) error {
+	var reader *strings.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = strings.NewReader(string(b))
+	}
+	req, err := t.cc.newRequest(method, t.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (t *tui) list() {
+	var convs []struct {
+		ConversationID string  `json:"conversation_id"`
+		Slug           *string `json:"slug"`
+		UpdatedAt      string  `json:"updated_at"`
+		Working        bool    `json:"working"`
+		Model          *string `json:"model"`
+	}
+	if err := t.do("GET", "/api/conversations?limit=20", nil, &convs); err != nil {
+		t.printf("Error listing conversations: %v\n", err)
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.listed = t.listed[:0]
+	for i, c := range convs {
+		t.listed = append(t.listed, c.ConversationID)
+		slug, model, state := "(untitled)", "", ""
+		if c.Slug != nil {
+			slug = *c.Slug
+		}
+		if c.Model != nil {
+			model = *c.Model
+		}
+		if c.Working {
+			state = " [working]"
+		}
+		fmt.Printf("%3d  %-40s %-24s %s%s\n", i+1, slug, model, c.UpdatedAt, state)
+	}
+	if len(convs) == 0 {
+		fmt.Printf("No conversations yet.\n")
+	}
+}
+
+func (t *tui) openArg(arg string) {
+	if arg == "" {
+		t.printf("Usage: /open N|ID\n")
+		return
+	}
+	t.mu.Lock()
+	id := arg
+	if n, err := strconv.Atoi(arg); err == nil && n >= 1 && n <= len(t.listed) {
+		id = t.listed[n-1]
+	}
+	t.mu.Unlock()
+	t.open(id)
+}
+
+// open makes id the current conversation and streams it, replaying its
+// history first.
+func (t *tui) open(id string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.mu.Lock()
+	if t.stopStream != nil {
+		t.stopStream()
+	}
+	t.convID = id
+	t.stopStream = cancel
+	t.mu.Unlock()
+	go t.stream(ctx, id)
+}
+
+func (t *tui) stream(ctx context.Context, id string) {
+	req, err := t.cc.newRequest("GET", t.baseURL+"/api/conversation/"+id+"/stream", nil)
+	if err != nil {
+		t.printf("Error: %v\n", err)
+		return
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			t.printf("Error opening %s: %v\n", id, err)
+		}
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.printf("Error opening %s: HTTP %d\n", id, resp.StatusCode)
+		return
+	}
+
+	seen := make(map[int64]bool)
+	toolNames := make(map[string]string) // tool use ID -> tool name
+	working := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var sr streamResponseWire
+		if err := json.Unmarshal([]byte(data), &sr); err != nil || sr.Heartbeat {
+			continue
+		}
+		t.mu.Lock()
+		if ctx.Err() != nil { // a newer /open or /new took over
+			t.mu.Unlock()
+			return
+		}
+		for _, msg := range sr.Messages {
+			if seen[msg.SequenceID] {
+				continue
+			}
+			seen[msg.SequenceID] = true
+			t.printMessage(msg, toolNames)
+		}
+		if st := sr.ConversationState; st != nil && st.ConversationID == id && st.Working != working {
+			working = st.Working
+			if working {
+				fmt.Printf("[working on %s]\n", st.Model)
+			} else {
+				fmt.Printf("[turn done]\n")
+			}
+		}
+		t.mu.Unlock()
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err := scanner.Err(); err != nil {
+		t.printf("[stream for %s failed: %v]\n", id, err)
+	} else {
+		t.printf("[stream for %s closed]\n", id)
+	}
+}
+
+// printMessage writes one message as the transcript shows it. Callers hold
+// t.mu.
+func (t *tui) printMessage(msg messageWire, toolNames map[string]string) {
+	if msg.LlmData == nil || msg.Type == "system" {
+		return
+	}
+	var llmMsg llmMessageWire
+	if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+		return
+	}
+	for _, c := range llmMsg.Content {
+		switch c.Type {
+		case contentTypeText:
+			if c.Text == "" {
+				continue
+			}
+			switch msg.Type {
+			case "user":
+				fmt.Printf("\nyou> %s\n", c.Text)
+			case "error":
+				fmt.Printf("\n[error] %s\n", c.Text)
+			default:
+				fmt.Printf("\n%s\n", c.Text)
+			}
+		case contentTypeThinking:
+			if t.showThinking && c.Thinking != "" {
+				fmt.Printf("  (thinking) %s\n", c.Thinking)
+			}
+		case contentTypeToolUse:
+			fmt.Printf("  %s\n", toolUseSummary(c, toolNames))
+		case contentTypeToolResult:
+			fmt.Printf("  %s\n", toolResultSummary(c, toolNames))
+		}
+	}
+}
+
+// send posts a message to the current conversation, or starts a new one.
+func (t *tui) send(message string) {
+	t.mu.Lock()
+	convID, model := t.convID, t.model
+	t.mu.Unlock()
+
+	if convID != "" {
+		if err := t.do("POST", "/api/conversation/"+convID+"/chat", map[string]any{"message": message}, nil); err != nil {
+			t.printf("Error sending: %v\n", err)
+		}
+		return
+	}
+	body := map[string]any{"message": message}
+	if model != "" {
+		body["model"] = model
+	}
+	if wd, err := os.Getwd(); err == nil {
+		body["cwd"] = wd
+	}
+	var created struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	if err := t.do("POST", "/api/conversations/new", body, &created); err != nil {
+		t.printf("Error starting conversation: %v\n", err)
+		return
+	}
+	t.printf("Started conversation %s\n", created.ConversationID)
+	t.open(created.ConversationID)
+}
+
+func (t *tui) cancel() {
+	t.mu.Lock()
+	convID := t.convID
+	t.mu.Unlock()
+	if convID == "" {
+		t.printf("No conversation is open.\n")
+		return
+	}
+	if err := t.do("POST", "/api/conversation/"+convID+"/cancel", nil, nil); err != nil {
+		t.printf("Error cancelling: %v\n", err)
+	}
+}
+
+func (t *tui) models() {
+	var models []struct {
+		ID        string `json:"id"`
+		Ready     bool   `json:"ready"`
+		IsDefault bool   `json:"is_default"`
+	}
+	if err := t.do("GET", "/api/models", nil, &models); err != nil {
+		t.printf("Error listing models: %v\n", err)
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, m := range models {
+		var notes []string
+		if m.IsDefault {
+			notes = append(notes, "default")
+		}
+		if !m.Ready {
+			notes = append(notes, "not ready")
+		}
+		if len(notes) > 0 {
+			fmt.Printf("%s (%s)\n", m.ID, strings.Join(notes, ", "))
+		} else {
+			fmt.Printf("%s\n", m.ID)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// usageTotalsWire mirrors server.UsageTotals.
+type usageTotalsWire struct {
+	Model          string  `json:"model,omitempty"`
+	ConversationID string  `json:"conversation_id,omitempty"`
+	Slug           string  `json:"slug,omitempty"`
+	LLMCalls       int64   `json:"llm_calls"`
+	InputTokens    int64   `json:"input_tokens"`
+	CacheWrite     int64   `json:"cache_creation_input_tokens"`
+	CacheRead      int64   `json:"cache_read_input_tokens"`
+	OutputTokens   int64   `json:"output_tokens"`
+	CostUSD        float64 `json:"cost_usd"`
+}
+
+func cmdUsage(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client usage", flag.ExitOnError)
+	sinceFlag := fs.String("since", "", "Only count usage since this long ago (7d, 12h) or this date (2006-01-02 or RFC 3339); default all time")
+	by := fs.String("by", "model", "Group by model or conversation")
+	jsonOut := fs.Bool("json", false, "Print the report as JSON instead of a table")
+	fs.Parse(args)
+
+	q := url.Values{"by": {*by}}
+	if *sinceFlag != "" {
+		since, err := parseSince(*sinceFlag, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		q.Set("since", since.Format(time.RFC3339))
+	}
+
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	req, err := cc.newRequest("GET", baseURL+"/api/usage?"+q.Encode(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
+		os.Exit(1)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Error (HTTP %d): %s\n", resp.StatusCode, strings.TrimSpace(string(body)))
+		os.Exit(1)
+	}
+	if *jsonOut {
+		os.Stdout.Write(body)
+		return
+	}
+
+	var report struct {
+		Rows  []usageTotalsWire `json:"rows"`
+		Total usageTotalsWire   `json:"total"`
+	}
+	if err := json.Unmarshal(body, &report); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	label := "MODEL"
+	if *by == "conversation" {
+		label = "CONVERSATION"
+	}
+	fmt.Fprintf(tw, "%s\tCALLS\tINPUT\tCACHE WRITE\tCACHE READ\tOUTPUT\tCOST\n", label)
+	row := func(name string, t usageTotalsWire) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t$%.2f\n", name, t.LLMCalls, t.InputTokens, t.CacheWrite, t.CacheRead, t.OutputTokens, t.CostUSD)
+	}
+	for _, r := range report.Rows {
+		name := r.Model
+		if *by == "conversation" {
+			name = r.ConversationID
+			if r.Slug != "" {
+				name += " " + oneLine(r.Slug, 30)
+			}
+		}
+		if name == "" {
+			name = "(unknown)"
+		}
+		row(name, r)
+	}
+	row("TOTAL", report.Total)
+	tw.Flush()
+}
+
+// parseSince reads a -since value: a number of days, hours, or minutes
+// back from now ("7d", "12h", "30m"), a date, or an RFC 3339 time.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		if days, err := strconv.Atoi(n); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid -since %q (want e.g. 7d, 12h, 2006-01-02, or RFC 3339)", s)
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// watchedConversation is what watch remembers about a conversation to label
+// its lines and total its cost.
+type watchedConversation struct {
+	slug    string
+	cost    float64
+	lastSeq int64
+	working bool
+}
+
+// watcher prints a line per state change across conversations.
+type watcher struct {
+	cc      *clientConfig
+	client  *http.Client
+	baseURL string
+	ids     map[string]bool // if non-empty, only these conversations
+	query   string          // if set, only conversations whose slug contains it
+	convs   map[string]*watchedConversation
+}
+
+func cmdWatch(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client watch", flag.ExitOnError)
+	var ids multiFlag
+	fs.Var(&ids, "c", "Conversation ID to watch (can be repeated; default: all)")
+	query := fs.String("q", "", "Only watch conversations whose slug contains this")
+	fs.Parse(args)
+
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	w := &watcher{
+		cc:      cc,
+		client:  client,
+		baseURL: baseURL,
+		ids:     make(map[string]bool),
+		query:   *query,
+		convs:   make(map[string]*watchedConversation),
+	}
+	for _, id := range ids {
+		w.ids[id] = true
+	}
+
+	// Keep going across server restarts and dropped connections; this is
+	// meant to sit in a terminal pane indefinitely.
+	for {
+		if err := w.stream(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s  stream failed: %v; reconnecting\n", time.Now().Format(time.TimeOnly), err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+func (w *watcher) stream() error {
+	req, err := w.cc.newRequest("GET", w.baseURL+"/api/stream2", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var sr struct {
+			streamResponseWire
+			ConversationListUpdate *struct {
+				Conversation *struct {
+					Slug *string `json:"slug"`
+				} `json:"conversation"`
+			} `json:"conversation_list_update"`
+		}
+		if err := json.Unmarshal([]byte(data), &sr); err != nil || sr.ConversationID == "" {
+			continue
+		}
+		c := w.conversation(sr.ConversationID)
+		if c == nil {
+			continue
+		}
+		if u := sr.ConversationListUpdate; u != nil && u.Conversation != nil && u.Conversation.Slug != nil {
+			c.slug = *u.Conversation.Slug
+		}
+		for _, msg := range sr.Messages {
+			if msg.SequenceID <= c.lastSeq {
+				continue
+			}
+			c.lastSeq = msg.SequenceID
+			w.message(c, msg)
+		}
+		if st := sr.ConversationState; st != nil && st.Working != c.working {
+			c.working = st.Working
+			if c.working {
+				w.printf(c, "started", st.Model)
+			} else {
+				w.printf(c, "done", "")
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("server closed the stream")
+}
+
+// conversation returns the state for id, loading its slug and cost so far
+// the first time it is seen. It returns nil for conversations not named
+// with -c.
+func (w *watcher) conversation(id string) *watchedConversation {
+	if c, ok := w.convs[id]; ok {
+		return c
+	}
+	if len(w.ids) > 0 && !w.ids[id] {
+		return nil
+	}
+	c := &watchedConversation{}
+	w.convs[id] = c
+	req, err := w.cc.newRequest("GET", w.baseURL+"/api/conversation/"+id, nil)
+	if err != nil {
+		return c
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return c
+	}
+	defer resp.Body.Close()
+	var sr struct {
+		streamResponseWire
+		Conversation struct {
+			Slug *string `json:"slug"`
+		} `json:"conversation"`
+		MaxSequenceID int64 `json:"max_sequence_id"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&sr) != nil {
+		return c
+	}
+	if sr.Conversation.Slug != nil {
+		c.slug = *sr.Conversation.Slug
+	}
+	for _, msg := range sr.Messages {
+		c.cost += messageCost(msg)
+	}
+	c.lastSeq = sr.MaxSequenceID
+	return c
+}
+
+// message prints the tool calls, failed tool results, and errors in msg,
+// and adds its cost to the conversation's total.
+func (w *watcher) message(c *watchedConversation, msg messageWire) {
+	c.cost += messageCost(msg)
+	if msg.LlmData == nil {
+		return
+	}
+	var llmMsg llmMessageWire
+	if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+		return
+	}
+	for _, content := range llmMsg.Content {
+		switch {
+		case content.Type == contentTypeToolUse:
+			w.printf(c, "tool", content.ToolName+" "+oneLine(string(content.ToolInput), 60))
+		case content.Type == contentTypeToolResult && content.ToolError:
+			var out string
+			for _, r := range content.ToolResult {
+				if r.Text != "" {
+					out = r.Text
+					break
+				}
+			}
+			w.printf(c, "tool failed", oneLine(out, 60))
+		case content.Type == contentTypeText && msg.Type == "error":
+			w.printf(c, "error", oneLine(content.Text, 80))
+		}
+	}
+}
+
+// printf prints a line for c, unless its slug doesn't match -q. The slug is
+// checked per line because new conversations are only named after their
+// first turn starts.
+func (w *watcher) printf(c *watchedConversation, event, detail string) {
+	if w.query != "" && !strings.Contains(c.slug, w.query) {
+		return
+	}
+	slug := c.slug
+	if slug == "" {
+		slug = "(untitled)"
+	}
+	fmt.Printf("%s  %-30s  $%.4f  %-11s  %s\n", time.Now().Format(time.TimeOnly), oneLine(slug, 30), c.cost, event, detail)
+}
+
+// messageCost returns the LLM cost recorded on msg, if any.
+func messageCost(msg messageWire) float64 {
+	if msg.UsageData == nil {
+		return 0
+	}
+	var usage struct {
+		CostUSD float64 `json:"cost_usd"`
+	}
+	if json.Unmarshal([]byte(*msg.UsageData), &usage) != nil {
+		return 0
+	}
+	return usage.CostUSD
+}
@@ -102,6 +102,7 @@ type conversationStateForTS struct {
 	ConversationID string `json:"conversation_id"`
 	Working        bool   `json:"working"`
 	Model          string `json:"model,omitempty"`
+	Status         string `json:"status,omitempty"`
 }
 
 type conversationWithStateForTS struct {
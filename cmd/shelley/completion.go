@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Completion scripts complete commands, client subcommands, and -model
+// values. Model IDs come from "shelley client models", so they reflect
+// what the running server actually offers.
+
+const bashCompletion = `# shelley bash completion. Load with: source <(shelley completion bash)
+_shelley_models() {
+	shelley client models 2>/dev/null | sed -n 's/^{"id":"\([^"]*\)".*/\1/p'
+}
+
+_shelley() {
+	local cur prev i cmd sub
+	cur="${COMP_WORDS[COMP_CWORD]}"
+	prev="${COMP_WORDS[COMP_CWORD-1]}"
+	if [[ "$prev" == "-model" || "$prev" == "--model" ]]; then
+		COMPREPLY=($(compgen -W "$(_shelley_models)" -- "$cur"))
+		return
+	fi
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		case "${COMP_WORDS[i]}" in
+		%[3]s) ((i++)) ;;
+		-*) ;;
+		*)
+			if [[ -z "$cmd" ]]; then
+				cmd="${COMP_WORDS[i]}"
+			elif [[ -z "$sub" ]]; then
+				sub="${COMP_WORDS[i]}"
+			fi
+			;;
+		esac
+	done
+	case "$cmd" in
+	"")
+		COMPREPLY=($(compgen -W "%[1]s" -- "$cur"))
+		;;
+	client)
+		if [[ -z "$sub" ]]; then
+			COMPREPLY=($(compgen -W "%[2]s" -- "$cur"))
+		fi
+		;;
+	completion)
+		COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
+		;;
+	esac
+}
+complete -o default -F _shelley shelley
+`
+
+const zshCompletion = `#compdef shelley
+# shelley zsh completion. Load with: source <(shelley completion zsh)
+_shelley() {
+	local cmd sub w skip
+	if [[ "${words[CURRENT-1]}" == (-model|--model) ]]; then
+		compadd -- ${(f)"$(shelley client models 2>/dev/null | sed -n 's/^{"id":"\([^"]*\)".*/\1/p')"}
+		return
+	fi
+	for w in "${words[@]:1:CURRENT-2}"; do
+		if [[ -n "$skip" ]]; then
+			skip=
+			continue
+		fi
+		if [[ "$w" == (%[3]s) ]]; then
+			skip=1
+			continue
+		fi
+		[[ "$w" == -* ]] && continue
+		if [[ -z "$cmd" ]]; then
+			cmd="$w"
+		elif [[ -z "$sub" ]]; then
+			sub="$w"
+		fi
+	done
+	case "$cmd" in
+	"") compadd -- %[1]s ;;
+	client) [[ -z "$sub" ]] && compadd -- %[2]s ;;
+	completion) compadd -- bash zsh fish ;;
+	*) _files ;;
+	esac
+}
+compdef _shelley shelley
+`
+
+const fishCompletion = `# shelley fish completion. Load with: shelley completion fish | source
+function __shelley_models
+	shelley client models 2>/dev/null | sed -n 's/^{"id":"\([^"]*\)".*/\1/p'
+end
+complete -c shelley -f
+complete -c shelley -n __fish_use_subcommand -a "%[1]s"
+complete -c shelley -n "__fish_seen_subcommand_from client; and not __fish_seen_subcommand_from %[2]s" -a "%[2]s"
+complete -c shelley -n "__fish_seen_subcommand_from completion" -a "bash zsh fish"
+complete -c shelley -o model -x -a "(__shelley_models)"
+`
+
+// Kept in sync with the usage in main and client.Run.
+const (
+	completionCommands       = "serve models config client skill system-prompt dtach mcp eval worker unpack-template version completion"
+	completionClientCommands = "chat run read list search archive models tui transcript watch flush review usage profile help"
+	// Flags before the command or subcommand that take a separate value.
+	completionValueFlags = "-db|-config|-default-model|-url|-H|-profile"
+)
+
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: shelley completion bash|zsh|fish\n")
+		os.Exit(1)
+	}
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown shell %q (use bash, zsh, or fish)\n", args[0])
+		os.Exit(1)
+	}
+	fmt.Printf(script, completionCommands, completionClientCommands, completionValueFlags)
+}
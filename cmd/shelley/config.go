@@ -0,0 +1,273 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/claudetool/mcp"
+	"shelley.exe.dev/coldstore"
+	"shelley.exe.dev/github"
+	"shelley.exe.dev/inbox"
+	"shelley.exe.dev/slackbot"
+)
+
+// shelleyConfig is the contents of shelley.json.
+type shelleyConfig struct {
+	// Schema is the path or URL of the file's JSON schema, for editors. Write
+	// the schema with "shelley config schema > shelley.schema.json".
+	Schema       string `json:"$schema,omitempty"`
+	LLMGateway   string `json:"llm_gateway"`
+	DefaultModel string `json:"default_model"`
+	// Databases are the connection profiles for the sql tool, keyed by name.
+	Databases map[string]claudetool.SQLProfile `json:"databases"`
+	// ToolPolicies bounds tool runs, keyed by tool name or "*" for all tools.
+	ToolPolicies map[string]claudetool.ToolPolicy `json:"tool_policies"`
+	// ModelToolOverrides maps model ID to tool name to "on" or "off".
+	ModelToolOverrides map[string]map[string]string `json:"model_tool_overrides"`
+	// Sandbox confines the commands the bash and shell tools run.
+	Sandbox claudetool.SandboxConfig `json:"sandbox"`
+	// Workspaces lets conversations be created with a repository to clone
+	// into a fresh docker container, where all their tools run.
+	Workspaces claudetool.WorkspaceConfig `json:"workspaces"`
+	// ToolParallelism is how many calls to read-only tools, such as
+	// keyword_search, from one response run at once. Others run in order.
+	ToolParallelism int `json:"tool_parallelism"`
+	// ContextLimit is the fraction of a model's context window a request may
+	// fill before the turn stops with a warning; 0 means the default, 0.9,
+	// and a negative value disables the check.
+	ContextLimit float64 `json:"context_limit"`
+	// CompactWhenFull compacts a conversation over ContextLimit and
+	// continues the turn instead of stopping it.
+	CompactWhenFull bool `json:"compact_when_full"`
+	// CacheToolResults reuses results of repeated read-only tool calls.
+	CacheToolResults bool `json:"cache_tool_results"`
+	// CheckPatchSyntax refuses patches that introduce syntax errors.
+	CheckPatchSyntax bool `json:"check_patch_syntax"`
+	// VerifyCommands checks inferred build, test, and lint commands before
+	// listing them in the system prompt.
+	VerifyCommands bool `json:"verify_commands"`
+	// AnalysisSampleThreshold is the repository file count past which codebase
+	// analysis samples; 0 means the default.
+	AnalysisSampleThreshold int `json:"analysis_sample_threshold"`
+	// GuidanceFileBytes and GuidanceTotalBytes cap the guidance files in the
+	// system prompt, each and together; 0 means the default and -1 no cap.
+	GuidanceFileBytes  int `json:"guidance_file_bytes"`
+	GuidanceTotalBytes int `json:"guidance_total_bytes"`
+	// SummarizeGuidance condenses guidance files over the caps with a cheap
+	// model instead of truncating them.
+	SummarizeGuidance bool `json:"summarize_guidance"`
+	// GuidanceFiles are more guidance file names or paths, which may be
+	// patterns, such as ".github/copilot-instructions.md".
+	GuidanceFiles []string `json:"guidance_files"`
+	// ExcludeGuidanceFiles are built-in guidance file names to ignore, such
+	// as "readme.md".
+	ExcludeGuidanceFiles []string `json:"exclude_guidance_files"`
+	// SubagentPersonas are the named subagent kinds the subagent tool offers.
+	SubagentPersonas map[string]claudetool.SubagentPersona `json:"subagent_personas"`
+	// MaxSubagentDepth is how deeply subagents may nest; 0 means 1, so only
+	// top-level conversations spawn subagents.
+	MaxSubagentDepth int `json:"max_subagent_depth"`
+	// SubagentBudget caps the usage of all subagents under a top-level
+	// conversation.
+	SubagentBudget claudetool.SubagentBudget `json:"subagent_budget"`
+	// MCPServers are external MCP servers whose tools conversations may
+	// use, keyed by a name that prefixes their tool names.
+	MCPServers map[string]mcp.ServerConfig `json:"mcp_servers"`
+	// OTLPEndpoint is the URL of an OTLP/HTTP collector, such as
+	// "http://localhost:4318", to export traces to.
+	OTLPEndpoint string `json:"otlp_endpoint"`
+	// AdminToken is the bearer token for the admin, profiling and
+	// diagnostics endpoints under /api/admin, which only answer localhost
+	// without one. Keep it out of the file with a reference such as
+	// "${SHELLEY_ADMIN_TOKEN}".
+	AdminToken string `json:"admin_token"`
+	// ColdStorage moves the messages of long-archived conversations out of
+	// the database, into a directory or an S3-compatible bucket, until
+	// someone opens them again.
+	ColdStorage coldstore.Config `json:"cold_storage"`
+	// WorkerToken is the bearer token machines running "shelley worker"
+	// connect with. Conversations whose options name a connected worker run
+	// their commands and file edits there. Workers are off without one.
+	WorkerToken string `json:"worker_token"`
+	// GitHub is a GitHub App whose webhook deliveries Shelley receives: a
+	// comment mentioning the app on an issue or pull request starts a
+	// conversation in a checkout of the repository and pushes its changes.
+	GitHub github.Config `json:"github"`
+	// Inbox is an email gateway: mail posted by an inbound mail service
+	// starts a conversation, and the final response is emailed back.
+	Inbox inbox.Config `json:"inbox"`
+	// Slack is a Slack app whose events Shelley receives: a message
+	// mentioning the app starts a conversation mirrored in its thread, and
+	// replies in the thread continue it.
+	Slack slackbot.Config `json:"slack"`
+}
+
+// loadConfig reads shelley.json from path. A missing file, or an empty
+// path, yields the zero config. String values may refer to secrets kept
+// elsewhere; see expandConfigRefs.
+func loadConfig(path string) (shelleyConfig, error) {
+	var cfg shelleyConfig
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, err
+	}
+	err = expandConfigRefs(&cfg, filepath.Dir(path))
+	return cfg, err
+}
+
+// expandConfigRefs replaces references in cfg's credentials, so that a
+// config can be shared without the secrets in it: ${NAME} becomes the value
+// of environment variable NAME, and ${file:PATH} the contents of the file at
+// PATH without trailing newlines. Relative paths are relative to dir, and
+// "~/" is the home directory. $${ stands for a literal ${. Only the values
+// of secretConfigKeys are expanded; other strings, such as system prompts,
+// are used as written.
+func expandConfigRefs(cfg *shelleyConfig, dir string) error {
+	return expandRefsIn(reflect.ValueOf(cfg).Elem(), "", dir, false)
+}
+
+// secretConfigKeys are the config keys whose values may hold references:
+// credentials, and the environment and headers of MCP servers, everything
+// within which may.
+var secretConfigKeys = map[string]bool{
+	"dsn":               true,
+	"env":               true,
+	"headers":           true,
+	"admin_token":       true,
+	"worker_token":      true,
+	"access_key_id":     true,
+	"secret_access_key": true,
+	"webhook_secret":    true,
+	"token":             true,
+	"password":          true,
+	"bot_token":         true,
+	"signing_secret":    true,
+}
+
+// expandRefsIn expands the references in the strings within v, which is at
+// path in the config, if v is or is within the value of a secretConfigKeys
+// key.
+func expandRefsIn(v reflect.Value, path, dir string, secret bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		if !secret {
+			return nil
+		}
+		s, err := expandRefs(v.String(), dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetString(s)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return expandRefsIn(v.Elem(), path, dir, secret)
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			f := v.Type().Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			name = cmp.Or(name, f.Name)
+			if err := expandRefsIn(v.Field(i), joinConfigPath(path, name), dir, secret || secretConfigKeys[name]); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			if err := expandRefsIn(v.Index(i), fmt.Sprintf("%s[%d]", path, i), dir, secret); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Map elements are not addressable, so expand a copy and store it.
+		for _, k := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(k))
+			if err := expandRefsIn(elem, joinConfigPath(path, k.String()), dir, secret); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, elem)
+		}
+	}
+	return nil
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// expandRefs expands the references in s, as described at expandConfigRefs.
+func expandRefs(s, dir string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${")
+		}
+		val, err := resolveRef(s[i+2:i+end], dir)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:i] + val)
+		s = s[i+end+1:]
+	}
+}
+
+// resolveRef returns the value of an environment variable or file reference.
+func resolveRef(ref, dir string) (string, error) {
+	path, ok := strings.CutPrefix(ref, "file:")
+	if !ok {
+		if ref == "" {
+			return "", fmt.Errorf("empty ${} reference")
+		}
+		val, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref)
+		}
+		return val, nil
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, rest)
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
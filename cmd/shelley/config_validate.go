@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/github"
+	"shelley.exe.dev/llm"
+)
+
+// runConfig checks shelley.json or prints its schema.
+func runConfig(global GlobalConfig, args []string) {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: shelley [global-flags] config <validate|schema>\n\n")
+		fmt.Fprintf(os.Stderr, "  validate [-offline] [path]  Check shelley.json (default: --config) and test its model providers\n")
+		fmt.Fprintf(os.Stderr, "  schema                      Print the JSON schema of shelley.json\n")
+	}
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "validate":
+		runConfigValidate(global, args[1:])
+	case "schema":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(configSchema()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func runConfigValidate(global GlobalConfig, args []string) {
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	offline := fs.Bool("offline", false, "Skip sending a test request to each model provider")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: shelley [global-flags] config validate [-offline] [path]\n\n")
+		fmt.Fprintf(fs.Output(), "Checks shelley.json against its schema, checks the binaries, credentials,\n")
+		fmt.Fprintf(fs.Output(), "and model IDs it refers to, and sends a test request to each model provider.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if fs.NArg() == 1 {
+		global.ConfigPath = fs.Arg(0)
+	}
+	if global.ConfigPath == "" {
+		fmt.Fprintf(os.Stderr, "Error: no config file; pass a path or --config\n")
+		os.Exit(2)
+	}
+
+	report := validateConfig(context.Background(), global, !*offline)
+	for _, w := range report.Warnings {
+		fmt.Printf("warning: %s\n", w)
+	}
+	for _, e := range report.Errors {
+		fmt.Printf("error: %s\n", e)
+	}
+	if len(report.Errors) > 0 {
+		fmt.Printf("%s: %d error(s)\n", global.ConfigPath, len(report.Errors))
+		os.Exit(1)
+	}
+	fmt.Printf("%s: ok\n", global.ConfigPath)
+}
+
+// configReport collects the problems "shelley config validate" finds.
+type configReport struct {
+	Errors   []string
+	Warnings []string
+}
+
+func (r *configReport) errorf(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+func (r *configReport) warnf(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// validateConfig checks the config file at global.ConfigPath. If connect is
+// set, it also sends a one-word request to a model of each provider.
+func validateConfig(ctx context.Context, global GlobalConfig, connect bool) *configReport {
+	report := &configReport{}
+	data, err := os.ReadFile(global.ConfigPath)
+	if err != nil {
+		report.errorf("%v", err)
+		return report
+	}
+	cfg, err := decodeConfigStrict(data)
+	if err != nil {
+		report.errorf("%v", err)
+		return report
+	}
+	if err := expandConfigRefs(&cfg, filepath.Dir(global.ConfigPath)); err != nil {
+		report.errorf("%v", err)
+		return report
+	}
+
+	var tc claudetool.ToolSetConfig
+	if err := applyToolConfig(cfg, &tc); err != nil {
+		report.errorf("%v", err)
+	}
+	checkSandbox(report, cfg.Sandbox)
+	if cfg.Workspaces.Enabled() {
+		if _, err := exec.LookPath("docker"); err != nil {
+			report.errorf("workspaces: workspaces need docker on PATH; install it or remove workspaces.image")
+		}
+	}
+	for name, s := range cfg.MCPServers {
+		if err := s.Validate(); err != nil {
+			report.errorf("mcp_servers.%s: %v", name, err)
+		} else if s.Command != "" {
+			if _, err := exec.LookPath(s.Command); err != nil {
+				report.errorf("mcp_servers.%s: command %q not found; install it or use an absolute path", name, s.Command)
+			}
+		}
+	}
+	if cfg.OTLPEndpoint != "" {
+		if u, err := url.Parse(cfg.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			report.errorf("otlp_endpoint: %q is not an http or https URL", cfg.OTLPEndpoint)
+		}
+	}
+	if cfg.ColdStorage.Enabled() {
+		if err := cfg.ColdStorage.Validate(); err != nil {
+			report.errorf("cold_storage: %v", err)
+		}
+	}
+	if cfg.GitHub.Enabled() {
+		if _, err := github.New(cfg.GitHub); err != nil {
+			report.errorf("github: %v", err)
+		}
+		if _, err := exec.LookPath("git"); err != nil {
+			report.errorf("github: the GitHub integration needs git on PATH")
+		}
+	}
+	if cfg.Inbox.Enabled() {
+		if err := cfg.Inbox.Validate(); err != nil {
+			report.errorf("inbox: %v", err)
+		}
+	}
+	if cfg.Slack.Enabled() {
+		if err := cfg.Slack.Validate(); err != nil {
+			report.errorf("slack: %v", err)
+		}
+	}
+	for name, p := range cfg.Databases {
+		switch {
+		case !slices.Contains([]string{"sqlite", "postgres", "mysql"}, p.Driver):
+			report.errorf("databases.%s: unknown driver %q; use sqlite, postgres, or mysql", name, p.Driver)
+		case p.DSN == "":
+			report.errorf("databases.%s: dsn is required", name)
+		}
+	}
+	for name := range cfg.ToolPolicies {
+		if name != claudetool.DefaultToolPolicyKey {
+			checkToolName(report, "tool_policies", name)
+		}
+	}
+	for model, overrides := range cfg.ModelToolOverrides {
+		for name, v := range overrides {
+			checkToolName(report, "model_tool_overrides."+model, name)
+			if v != "on" && v != "off" {
+				report.errorf("model_tool_overrides.%s.%s: %q must be \"on\" or \"off\"", model, name, v)
+			}
+		}
+	}
+	for persona, p := range cfg.SubagentPersonas {
+		for _, name := range p.Tools {
+			checkToolName(report, "subagent_personas."+persona+".tools", name)
+		}
+	}
+
+	// Build the model catalog the server would use, and check the model IDs
+	// the config names against it.
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	llmCfg := buildLLMConfig(global, logger, nil)
+	var ids []string
+	for _, m := range llmCfg.Models {
+		ids = append(ids, m.ID)
+	}
+	checkModel := func(field, id string) {
+		if id != "" && !slices.Contains(ids, id) {
+			report.errorf("%s: model %q is not available; available: %s", field, id, strings.Join(ids, ", "))
+		}
+	}
+	checkModel("default_model", cfg.DefaultModel)
+	for persona, p := range cfg.SubagentPersonas {
+		checkModel("subagent_personas."+persona+".model", p.Model)
+	}
+	for model := range cfg.ModelToolOverrides {
+		if !slices.Contains(ids, model) {
+			report.warnf("model_tool_overrides: model %q is not built in; this is fine if it is a custom model", model)
+		}
+	}
+	if len(ids) == 1 && !global.PredictableOnly {
+		report.errorf("no model provider is configured; set llm_gateway, or ANTHROPIC_API_KEY, OPENAI_API_KEY, GEMINI_API_KEY, or FIREWORKS_API_KEY")
+	}
+
+	if connect {
+		// One test request per source and provider suffices: models that
+		// share them share credentials and endpoints.
+		tested := make(map[string]bool)
+		for _, m := range llmCfg.Models {
+			key := m.Source + "\x00" + string(m.Provider)
+			if m.ID == "predictable" || tested[key] {
+				continue
+			}
+			tested[key] = true
+			if err := testModelService(ctx, m.Service); err != nil {
+				report.errorf("model %s (%s): test request failed: %v", m.ID, m.Source, err)
+			}
+		}
+	}
+	return report
+}
+
+// decodeConfigStrict decodes shelley.json, rejecting unknown keys and
+// reporting where type errors are.
+func decodeConfigStrict(data []byte) (shelleyConfig, error) {
+	var cfg shelleyConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&cfg)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return cfg, nil
+	case errors.As(err, &syntaxErr):
+		return cfg, fmt.Errorf("line %d: %v", lineOf(data, syntaxErr.Offset), err)
+	case errors.As(err, &typeErr):
+		return cfg, fmt.Errorf("line %d: %s: got a JSON %s, want %s", lineOf(data, typeErr.Offset), typeErr.Field, typeErr.Value, typeErr.Type)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		if known := closestConfigKey(field); known != "" {
+			return cfg, fmt.Errorf("unknown key %q; did you mean %q?", field, known)
+		}
+		return cfg, fmt.Errorf("unknown key %q; run 'shelley config schema' for the valid keys", field)
+	}
+	return cfg, err
+}
+
+// lineOf returns the 1-based line of a byte offset in data.
+func lineOf(data []byte, offset int64) int {
+	return bytes.Count(data[:min(int(offset), len(data))], []byte("\n")) + 1
+}
+
+// closestConfigKey returns the config key nearest to key, if one is within a
+// typo of it.
+func closestConfigKey(key string) string {
+	best, bestDist := "", 3
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Map || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		for f := range t.Fields() {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if d := editDistance(strings.ToLower(key), name); d < bestDist {
+				best, bestDist = name, d
+			}
+			walk(f.Type)
+		}
+	}
+	walk(reflect.TypeFor[shelleyConfig]())
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// checkSandbox checks that the sandbox backend can run on this machine.
+func checkSandbox(report *configReport, c claudetool.SandboxConfig) {
+	switch c.Backend {
+	case "bwrap", "docker":
+		if _, err := exec.LookPath(c.Backend); err != nil {
+			report.errorf("sandbox: the %s backend needs %s on PATH; install it or change sandbox.backend", c.Backend, c.Backend)
+		}
+	case "user":
+		if os.Geteuid() != 0 {
+			report.errorf("sandbox: the user backend needs Shelley to run as root")
+		}
+		if _, err := user.Lookup(c.User); err != nil {
+			report.errorf("sandbox: user %q: %v", c.User, err)
+		}
+	}
+}
+
+// checkToolName reports a tool name that no conversation has.
+func checkToolName(report *configReport, field, name string) {
+	if strings.HasPrefix(name, "mcp__") {
+		return
+	}
+	var known []string
+	for _, t := range claudetool.ToolRegistry {
+		known = append(known, t.Name)
+	}
+	if !slices.Contains(known, name) {
+		report.errorf("%s: unknown tool %q; known tools: %s", field, name, strings.Join(known, ", "))
+	}
+}
+
+// testModelService sends svc a minimal request.
+func testModelService(ctx context.Context, svc llm.Service) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	_, err := svc.Do(ctx, &llm.Request{Messages: []llm.Message{{
+		Role:    llm.MessageRoleUser,
+		Content: []llm.Content{{Type: llm.ContentTypeText, Text: "Reply with OK."}},
+	}}})
+	return err
+}
+
+// configSchema returns the JSON schema of shelley.json, derived from
+// shelleyConfig so that the two cannot drift apart.
+func configSchema() map[string]any {
+	schema := jsonSchema(reflect.TypeFor[shelleyConfig]())
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "shelley.json"
+	return schema
+}
+
+// jsonSchema describes the JSON encoding of t.
+func jsonSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		props := make(map[string]any)
+		for f := range t.Fields() {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "" || name == "-" || !f.IsExported() {
+				continue
+			}
+			props[name] = jsonSchema(f.Type)
+		}
+		return map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	}
+	return map[string]any{}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"shelley.exe.dev/claudetool/browse"
+	"shelley.exe.dev/client"
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/models"
+	"shelley.exe.dev/server"
+)
+
+// evalCheckTimeout limits a task's check command.
+const evalCheckTimeout = 5 * time.Minute
+
+// maxEvalDetail is how much of a check's output is recorded, from its end.
+const maxEvalDetail = 4096
+
+// evalTask is a task for "shelley eval", read from a JSON file named after
+// the task.
+type evalTask struct {
+	// Prompt starts the task's conversation.
+	Prompt string `json:"prompt"`
+	// Fixture is a directory, relative to the task file, copied into a fresh
+	// working directory for each run. It may not contain symlinks. Without
+	// one, runs start in an empty directory.
+	Fixture string `json:"fixture"`
+	// Check is a shell command run in the working directory once the agent
+	// has finished. The task passes if it exits 0.
+	Check string `json:"check"`
+	// TimeoutSeconds limits the agent's turn, instead of -timeout.
+	TimeoutSeconds int `json:"timeout_seconds"`
+
+	name string
+}
+
+// loadEvalTasks reads the task definitions in dir, sorted by name.
+func loadEvalTasks(dir string) ([]evalTask, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no task definitions (*.json) in %s", dir)
+	}
+	var tasks []evalTask
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var task evalTask
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&task); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if task.Prompt == "" || task.Check == "" {
+			return nil, fmt.Errorf("%s: prompt and check are required", path)
+		}
+		if task.Fixture != "" && !filepath.IsAbs(task.Fixture) {
+			task.Fixture = filepath.Join(dir, task.Fixture)
+		}
+		task.name = strings.TrimSuffix(filepath.Base(path), ".json")
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// runEval runs each task in a directory against one or more models with
+// an in-process server, recording the results in the database. It exits 1
+// if any task didn't pass.
+func runEval(global GlobalConfig, args []string) {
+	if len(args) > 0 && args[0] == "report" {
+		runEvalReport(global, args[1:])
+		return
+	}
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	modelList := fs.String("models", "", "Comma-separated models to run each task against (default: the default model)")
+	timeout := fs.Duration("timeout", 10*time.Minute, "Time limit for each task's agent turn, unless the task sets timeout_seconds")
+	runID := fs.String("run", "", "ID to record the results under (default: the current time)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: shelley [global-flags] eval [flags] TASK_DIR\n")
+		fmt.Fprintf(fs.Output(), "       shelley [global-flags] eval report [RUN_ID...]\n\n")
+		fmt.Fprintf(fs.Output(), "Runs each task in TASK_DIR against each model and records whether it passed,\n")
+		fmt.Fprintf(fs.Output(), "its cost and duration, and its conversation in the database. A task is a JSON\n")
+		fmt.Fprintf(fs.Output(), "file with a prompt, an optional fixture directory to copy into the working\n")
+		fmt.Fprintf(fs.Output(), "directory, and a check command that exits 0 if the agent succeeded:\n\n")
+		fmt.Fprintf(fs.Output(), "  {\"prompt\": \"Fix the failing test\", \"fixture\": \"calc\", \"check\": \"go test ./...\"}\n\n")
+		fmt.Fprintf(fs.Output(), "Use -db to keep eval conversations apart from your own. \"eval report\"\n")
+		fmt.Fprintf(fs.Output(), "compares runs, by default the latest, task by task.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	tasks, err := loadEvalTasks(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	passed, err := evalTasks(global, tasks, *modelList, *timeout, cmp.Or(*runID, time.Now().UTC().Format("20060102-150405")))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !passed {
+		os.Exit(1)
+	}
+}
+
+// evalTasks runs tasks against the models in modelList and prints a report,
+// reporting whether every run passed.
+func evalTasks(global GlobalConfig, tasks []evalTask, modelList string, timeout time.Duration, runID string) (bool, error) {
+	workRoot, err := os.MkdirTemp("", "shelley-eval-")
+	if err != nil {
+		return false, err
+	}
+	logFile, err := os.Create(filepath.Join(workRoot, "shelley.log"))
+	if err != nil {
+		return false, err
+	}
+	defer logFile.Close()
+	logger := setupLogging(logFile, global.Debug)
+	database := setupDatabase(global.DBPath, logger)
+	defer database.Close()
+	server.DBPath = global.DBPath
+
+	llmConfig := buildLLMConfig(global, logger, database)
+	llmManager := server.NewLLMServiceManager(llmConfig)
+	browserPool := browse.NewPool(browse.DefaultTabsPerBrowser, browse.DefaultPoolIdleTimeout)
+	defer browserPool.Close()
+	toolSetConfig := setupToolSetConfig(llmManager, llmManager, browserPool)
+	cfg, err := loadConfig(global.ConfigPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to load config %s: %w", global.ConfigPath, err)
+	}
+	if err := applyToolConfig(cfg, &toolSetConfig); err != nil {
+		return false, fmt.Errorf("invalid config %s: %w", global.ConfigPath, err)
+	}
+	svr := server.NewServer(database, llmManager, toolSetConfig, logger, global.PredictableOnly, llmConfig.DefaultModel, "")
+	socket := filepath.Join(workRoot, "shelley.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return false, err
+	}
+	defer listener.Close()
+	mux := http.NewServeMux()
+	svr.RegisterRoutes(mux)
+	go http.Serve(listener, mux)
+
+	modelIDs := strings.Split(modelList, ",")
+	if modelList == "" {
+		modelIDs = []string{cmp.Or(llmConfig.DefaultModel, models.Default().ID)}
+	}
+	fmt.Fprintf(os.Stderr, "Run %s: %d tasks, %d models, working in %s\n", runID, len(tasks), len(modelIDs), workRoot)
+	ctx := context.Background()
+	passed := true
+	for _, model := range modelIDs {
+		for _, task := range tasks {
+			res := runEvalTask(ctx, "unix://"+socket, filepath.Join(workRoot, model, task.name), task, model, timeout)
+			res.RunID = runID
+			if err := database.RecordEvalResult(ctx, res); err != nil {
+				return false, err
+			}
+			fmt.Fprintf(os.Stderr, "%-7s %s %s ($%.4f, %s)\n", res.Status, model, task.name, res.CostUsd, evalDuration(res.DurationMs))
+			passed = passed && res.Status == "passed"
+		}
+	}
+
+	results, err := database.EvalResults(ctx, runID)
+	if err != nil {
+		return false, err
+	}
+	printEvalReport(os.Stdout, results)
+	return passed, nil
+}
+
+// runEvalTask runs task against model in dir, returning its result without
+// the run ID.
+func runEvalTask(ctx context.Context, serverURL, dir string, task evalTask, model string, timeout time.Duration) generated.InsertEvalResultParams {
+	res := generated.InsertEvalResultParams{Task: task.name, Model: model}
+	var err error
+	if task.Fixture == "" {
+		err = os.MkdirAll(dir, 0o755)
+	} else {
+		err = os.CopyFS(dir, os.DirFS(task.Fixture))
+	}
+	if err != nil {
+		res.Status, res.Detail = "error", fmt.Sprintf("preparing the working directory: %v", err)
+		return res
+	}
+
+	if task.TimeoutSeconds > 0 {
+		timeout = time.Duration(task.TimeoutSeconds) * time.Second
+	}
+	turnCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	turn, err := client.RunTurn(turnCtx, serverURL, task.Prompt, model, dir)
+	res.DurationMs = time.Since(start).Milliseconds()
+	res.ConversationID, res.CostUsd = turn.ConversationID, turn.CostUSD
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		res.Status, res.Detail = "timeout", fmt.Sprintf("the agent did not finish within %s", timeout)
+		return res
+	case err != nil:
+		res.Status, res.Detail = "error", err.Error()
+		return res
+	case turn.Failed:
+		res.Status, res.Detail = "error", turn.Final
+		return res
+	}
+
+	checkCtx, cancelCheck := context.WithTimeout(ctx, evalCheckTimeout)
+	defer cancelCheck()
+	cmd := exec.CommandContext(checkCtx, "sh", "-c", task.Check)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if len(out) > maxEvalDetail {
+		out = out[len(out)-maxEvalDetail:]
+	}
+	res.Status, res.Detail = "passed", string(out)
+	if err != nil {
+		res.Status = "failed"
+		if len(out) == 0 {
+			res.Detail = err.Error()
+		}
+	}
+	return res
+}
+
+// runEvalReport prints the report of eval runs.
+func runEvalReport(global GlobalConfig, args []string) {
+	fs := flag.NewFlagSet("eval report", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: shelley [global-flags] eval report [RUN_ID...]\n\n")
+		fmt.Fprintf(fs.Output(), "Compares eval runs, by default the latest, task by task: whether each\n")
+		fmt.Fprintf(fs.Output(), "model passed, its cost, and its duration.\n")
+	}
+	fs.Parse(args)
+
+	ctx := context.Background()
+	database, err := db.New(db.Config{DSN: global.DBPath})
+	if err == nil {
+		defer database.Close()
+		err = database.Migrate(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	runIDs := fs.Args()
+	if len(runIDs) == 0 {
+		latest, err := database.LatestEvalRunID(ctx)
+		if err == nil && latest == "" {
+			err = fmt.Errorf("no eval runs in %s", global.DBPath)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		runIDs = []string{latest}
+	}
+	var results []generated.EvalResult
+	for _, runID := range runIDs {
+		rs, err := database.EvalResults(ctx, runID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(rs) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: no results for run %s\n", runID)
+			os.Exit(1)
+		}
+		results = append(results, rs...)
+	}
+	printEvalReport(os.Stdout, results)
+}
+
+// printEvalReport prints a table of results with a row per task and a
+// column per model, or per model and run when there are several runs,
+// followed by the details of the runs that didn't pass.
+func printEvalReport(w io.Writer, results []generated.EvalResult) {
+	multipleRuns := slices.ContainsFunc(results, func(r generated.EvalResult) bool { return r.RunID != results[0].RunID })
+	column := func(r generated.EvalResult) string {
+		if multipleRuns {
+			return r.Model + "@" + r.RunID
+		}
+		return r.Model
+	}
+	var columns, tasks []string
+	cells := make(map[[2]string]generated.EvalResult)
+	for _, r := range results {
+		if !slices.Contains(columns, column(r)) {
+			columns = append(columns, column(r))
+		}
+		if !slices.Contains(tasks, r.Task) {
+			tasks = append(tasks, r.Task)
+		}
+		cells[[2]string{r.Task, column(r)}] = r
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "TASK\t%s\n", strings.Join(columns, "\t"))
+	for _, task := range tasks {
+		fmt.Fprint(tw, task)
+		for _, col := range columns {
+			r, ok := cells[[2]string{task, col}]
+			if !ok {
+				fmt.Fprint(tw, "\t-")
+				continue
+			}
+			fmt.Fprintf(tw, "\t%s $%.4f %s", r.Status, r.CostUsd, evalDuration(r.DurationMs))
+		}
+		fmt.Fprintln(tw)
+	}
+	fmt.Fprint(tw, "TOTAL")
+	for _, col := range columns {
+		var passed, ran int
+		var cost float64
+		var ms int64
+		for _, task := range tasks {
+			if r, ok := cells[[2]string{task, col}]; ok {
+				ran++
+				if r.Status == "passed" {
+					passed++
+				}
+				cost += r.CostUsd
+				ms += r.DurationMs
+			}
+		}
+		fmt.Fprintf(tw, "\t%d/%d passed $%.4f %s", passed, ran, cost, evalDuration(ms))
+	}
+	fmt.Fprintln(tw)
+	tw.Flush()
+
+	for _, r := range results {
+		if r.Status == "passed" {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s %s %s", r.Status, column(r), r.Task)
+		if r.ConversationID != "" {
+			fmt.Fprintf(w, " (conversation %s)", r.ConversationID)
+		}
+		fmt.Fprintln(w)
+		if detail := strings.TrimSpace(r.Detail); detail != "" {
+			fmt.Fprintf(w, "  %s\n", strings.ReplaceAll(detail, "\n", "\n  "))
+		}
+	}
+}
+
+// evalDuration formats a duration in milliseconds for reports.
+func evalDuration(ms int64) time.Duration {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond)
+}
@@ -1,26 +1,36 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"os"
+	"os/exec"
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/claudetool/browse"
+	"shelley.exe.dev/claudetool/mcp"
 	"shelley.exe.dev/client"
+	"shelley.exe.dev/coldstore"
 	"shelley.exe.dev/db"
+	"shelley.exe.dev/github"
+	"shelley.exe.dev/inbox"
 	"shelley.exe.dev/llm/llmhttp"
 	"shelley.exe.dev/models"
 	"shelley.exe.dev/modelsources"
 	"shelley.exe.dev/server"
 	_ "shelley.exe.dev/server/notifications/channels" // register channel types
 	"shelley.exe.dev/skills"
+	"shelley.exe.dev/slackbot"
 	"shelley.exe.dev/templates"
 	"shelley.exe.dev/version"
 )
@@ -57,11 +67,17 @@ func main() {
 		fmt.Fprintf(flag.CommandLine.Output(), "\nCommands:\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  serve [flags]                 Start the web server\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  models [flags]                List the models the server would expose, without starting it\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  client [flags] <subcommand>   CLI client (chat, read, list, archive) (experimental)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  config <validate|schema>      Check shelley.json, or print its JSON schema\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  client [flags] <subcommand>   CLI client (chat, run, read, list, archive, tui, watch) (experimental)\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  skill <cat|ls|new> [name]     Read, list, or create skills\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  system-prompt [flags]         Render the system prompt, to check template overrides\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  dtach <new|attach> ...        Persistent PTY sessions over a Unix socket\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  mcp [flags]                   Serve Shelley's tools over MCP on stdin/stdout\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  eval [flags] <dir>            Benchmark models on a directory of tasks (eval report compares runs)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  worker [flags]                Run conversations' tools on this machine for a remote server\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  unpack-template <name> <dir>  Unpack a project template to a directory\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  version                       Print version information as JSON\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  completion <bash|zsh|fish>    Print a shell completion script\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "\nUse '%s <command> -h' for command-specific help\n", os.Args[0])
 	}
 
@@ -80,16 +96,28 @@ func main() {
 		runServe(global, args[1:])
 	case "models":
 		runModels(global, args[1:])
+	case "config":
+		runConfig(global, args[1:])
 	case "client":
 		client.Run(args[1:])
 	case "skill":
 		runSkill(args[1:])
+	case "system-prompt":
+		runSystemPrompt(args[1:])
 	case "dtach":
 		runDtach(args[1:])
+	case "mcp":
+		runMCP(global, args[1:])
+	case "eval":
+		runEval(global, args[1:])
+	case "worker":
+		runWorker(args[1:])
 	case "unpack-template":
 		runUnpackTemplate(args[1:])
 	case "version":
 		runVersion()
+	case "completion":
+		runCompletion(args[1:])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		flag.Usage()
@@ -150,6 +178,49 @@ func runSkill(args []string) {
 	}
 }
 
+// runSystemPrompt renders the system prompt a conversation in a directory
+// would get, so that overrides of its template can be checked without
+// starting one. It lists the template files used on stderr.
+func runSystemPrompt(args []string) {
+	fs := flag.NewFlagSet("system-prompt", flag.ExitOnError)
+	dir := fs.String("dir", "", "Working directory to render the prompt for (default: current directory)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: shelley system-prompt [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Renders the system prompt from ~/.config/shelley/%s and .shelley/%s\n", server.SystemPromptFile, server.SystemPromptFile)
+		fmt.Fprintf(fs.Output(), "in the project, with partials from %s/*.txt next to them,\n", server.SystemPromptPartialsDir)
+		fmt.Fprintf(fs.Output(), "or the built-in template.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	wd := *dir
+	if wd == "" {
+		var err error
+		if wd, err = os.Getwd(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	projectDir := wd
+	if root, err := exec.Command("git", "-C", wd, "rev-parse", "--show-toplevel").Output(); err == nil {
+		projectDir = strings.TrimSpace(string(root))
+	}
+	files := server.SystemPromptFiles(projectDir)
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "Using the built-in template\n")
+	}
+	for _, f := range files {
+		fmt.Fprintf(os.Stderr, "Using %s\n", f)
+	}
+
+	prompt, err := server.GenerateSystemPrompt(wd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(prompt)
+}
+
 func runServe(global GlobalConfig, args []string) {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
 	port := fs.String("port", "9000", "Port to listen on")
@@ -158,9 +229,10 @@ func runServe(global GlobalConfig, args []string) {
 	requireHeader := fs.String("require-header", "", "Require this header on all API requests (e.g., X-Exedev-Userid)")
 	socketPath := fs.String("socket", client.DefaultSocketPath(), "Path to Unix socket for local CLI client access (set to 'none' to disable)")
 	banner := fs.String("banner", "", "If set, shows this text in a banner at the top of the UI (useful for marking demo instances)")
+	browserTabs := fs.Int("browser-tabs", browse.DefaultTabsPerBrowser, "Conversations sharing one headless browser before another is launched")
 	fs.Parse(args)
 
-	logger := setupLogging(global.Debug)
+	logger := setupLogging(os.Stdout, global.Debug)
 
 	database := setupDatabase(global.DBPath, logger)
 	defer database.Close()
@@ -178,11 +250,100 @@ func runServe(global GlobalConfig, args []string) {
 	availableModels := llmManager.GetAvailableModels()
 	logger.Info("Available models", "models", strings.Join(availableModels, ", "))
 
-	toolSetConfig := setupToolSetConfig(llmManager, llmManager)
+	toolSetConfig := setupToolSetConfig(llmManager, llmManager, browse.NewPool(*browserTabs, browse.DefaultPoolIdleTimeout))
+	var adminToken, workerToken string
+	var coldStore coldstore.Store
+	var githubApp *github.App
+	var inboxConfig inbox.Config
+	var slackApp *slackbot.App
+	var coldAfter time.Duration
+	cfg, err := loadConfig(global.ConfigPath)
+	if err != nil {
+		logger.Error("Failed to load config", "path", global.ConfigPath, "error", err)
+		os.Exit(1)
+	}
+	if err := applyToolConfig(cfg, &toolSetConfig); err != nil {
+		logger.Error("Invalid config", "path", global.ConfigPath, "error", err)
+		os.Exit(1)
+	}
+	if len(cfg.MCPServers) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		mcpTools, closeMCP, err := mcp.Tools(ctx, cfg.MCPServers)
+		cancel()
+		if err != nil {
+			logger.Error("Failed to connect to MCP servers", "error", err)
+			os.Exit(1)
+		}
+		defer closeMCP()
+		toolSetConfig.MCPTools = mcpTools
+		logger.Info("Loaded MCP tools", "count", len(mcpTools))
+	}
+	adminToken = cfg.AdminToken
+	workerToken = cfg.WorkerToken
+	if cfg.ColdStorage.Enabled() {
+		coldStore, err = coldstore.New(cfg.ColdStorage)
+		if err != nil {
+			logger.Error("Invalid cold_storage config", "error", err)
+			os.Exit(1)
+		}
+		coldAfter = cfg.ColdStorage.After()
+	}
+	if cfg.GitHub.Enabled() {
+		githubApp, err = github.New(cfg.GitHub)
+		if err != nil {
+			logger.Error("Invalid github config", "error", err)
+			os.Exit(1)
+		}
+	}
+	if cfg.Inbox.Enabled() {
+		if err := cfg.Inbox.Validate(); err != nil {
+			logger.Error("Invalid inbox config", "error", err)
+			os.Exit(1)
+		}
+		inboxConfig = cfg.Inbox
+	}
+	if cfg.Slack.Enabled() {
+		slackApp, err = slackbot.New(cfg.Slack)
+		if err != nil {
+			logger.Error("Invalid slack config", "error", err)
+			os.Exit(1)
+		}
+	}
+	shutdownTracing, err := setupTracing(context.Background(), cfg.OTLPEndpoint)
+	if err != nil {
+		logger.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warn("Failed to flush traces", "error", err)
+		}
+	}()
 
 	// Create server
 	svr := server.NewServer(database, llmManager, toolSetConfig, logger, global.PredictableOnly, llmConfig.DefaultModel, *requireHeader)
 	svr.SetModelRefresher(llmConfig.RefreshBuiltModels)
+	svr.SetAdminToken(adminToken)
+	svr.SetColdStorage(coldStore, coldAfter)
+	svr.SetGitHub(githubApp)
+	svr.SetInbox(inboxConfig)
+	svr.SetSlack(slackApp)
+	if err := svr.SetWorkerToken(workerToken); err != nil {
+		logger.Error("Failed to enable workers", "error", err)
+		os.Exit(1)
+	}
+	svr.SetConfigLoader(func(tc *claudetool.ToolSetConfig) (string, error) {
+		cfg, err := loadConfig(global.ConfigPath)
+		if err != nil {
+			return "", err
+		}
+		if err := applyToolConfig(cfg, tc); err != nil {
+			return "", err
+		}
+		return cmp.Or(global.DefaultModel, cfg.DefaultModel), nil
+	})
 	svr.Banner = *banner
 
 	// Load notification channels from DB.
@@ -194,7 +355,6 @@ func runServe(global GlobalConfig, args []string) {
 		effectiveSocket = ""
 	}
 
-	var err error
 	if *systemdActivation {
 		listener, listenerErr := systemdListener()
 		if listenerErr != nil {
@@ -225,12 +385,107 @@ func runServe(global GlobalConfig, args []string) {
 	}
 }
 
-func setupLogging(debug bool) *slog.Logger {
+// runMCP serves the tools a conversation would get over the stdio MCP
+// transport, so editors and other agents can use them without the web
+// server. Logs go to stderr, since stdout carries the protocol.
+func runMCP(global GlobalConfig, args []string) {
+	fs := flag.NewFlagSet("mcp", flag.ExitOnError)
+	dir := fs.String("dir", "", "Working directory for tools (default: current directory)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: shelley [global-flags] mcp [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Serves Shelley's tools (bash, patch, browser, ...) as an MCP server\n")
+		fmt.Fprintf(fs.Output(), "on stdin/stdout.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	logger := setupLogging(os.Stderr, global.Debug)
+	llmManager := server.NewLLMServiceManager(buildLLMConfig(global, logger, nil))
+	browserPool := browse.NewPool(browse.DefaultTabsPerBrowser, browse.DefaultPoolIdleTimeout)
+	defer browserPool.Close()
+	toolSetConfig := setupToolSetConfig(llmManager, llmManager, browserPool)
+	if *dir != "" {
+		toolSetConfig.WorkingDir = *dir
+	}
+	cfg, err := loadConfig(global.ConfigPath)
+	if err != nil {
+		logger.Error("Failed to load config", "path", global.ConfigPath, "error", err)
+		os.Exit(1)
+	}
+	if err := applyToolConfig(cfg, &toolSetConfig); err != nil {
+		logger.Error("Invalid config", "path", global.ConfigPath, "error", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	ts := claudetool.NewToolSet(ctx, toolSetConfig)
+	defer ts.Cleanup()
+	if err := mcp.Serve(ctx, os.Stdin, os.Stdout, ts.Tools()); err != nil {
+		logger.Error("MCP server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// defaultContextLimit is the context_limit used when shelley.json sets none.
+const defaultContextLimit = 0.9
+
+// applyToolConfig applies shelley.json's tool settings to tc. It returns an
+// error, leaving tc unchanged, for invalid values.
+func applyToolConfig(cfg shelleyConfig, tc *claudetool.ToolSetConfig) error {
+	if err := cfg.Sandbox.Validate(); err != nil {
+		return fmt.Errorf("invalid sandbox config: %w", err)
+	}
+	if err := cfg.Workspaces.Validate(); err != nil {
+		return fmt.Errorf("invalid workspaces config: %w", err)
+	}
+	for name, p := range cfg.SubagentPersonas {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("invalid subagent persona %q: %w", name, err)
+		}
+	}
+	if cfg.ContextLimit > 1 {
+		return fmt.Errorf("invalid context_limit %v: must be at most 1", cfg.ContextLimit)
+	}
+	tc.SQLProfiles = cfg.Databases
+	tc.ToolPolicies = cfg.ToolPolicies
+	tc.ModelToolOverrides = cfg.ModelToolOverrides
+	tc.Sandbox = cfg.Sandbox
+	tc.Workspaces = cfg.Workspaces
+	tc.ToolParallelism = cfg.ToolParallelism
+	switch {
+	case cfg.ContextLimit == 0:
+		tc.ContextLimit = defaultContextLimit
+	case cfg.ContextLimit < 0:
+		tc.ContextLimit = 0
+	default:
+		tc.ContextLimit = cfg.ContextLimit
+	}
+	tc.CompactWhenFull = cfg.CompactWhenFull
+	tc.CacheToolResults = cfg.CacheToolResults
+	tc.CheckPatchSyntax = cfg.CheckPatchSyntax
+	tc.VerifyCommands = cfg.VerifyCommands
+	tc.AnalysisSampleThreshold = cfg.AnalysisSampleThreshold
+	tc.GuidanceFileBytes = cfg.GuidanceFileBytes
+	tc.GuidanceTotalBytes = cfg.GuidanceTotalBytes
+	tc.SummarizeGuidance = cfg.SummarizeGuidance
+	tc.GuidanceFileNames = cfg.GuidanceFiles
+	tc.ExcludedGuidanceFileNames = cfg.ExcludeGuidanceFiles
+	tc.SubagentPersonas = cfg.SubagentPersonas
+	tc.MaxSubagentDepth = cfg.MaxSubagentDepth
+	tc.SubagentBudget = cfg.SubagentBudget
+	return nil
+}
+
+func setupLogging(w io.Writer, debug bool) *slog.Logger {
 	logLevel := slog.LevelInfo
 	if debug {
 		logLevel = slog.LevelDebug
 	}
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	logger := slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
 		Level: logLevel,
 	}))
 	slog.SetDefault(logger)
@@ -340,7 +595,7 @@ func runVersion() {
 	}
 }
 
-func setupToolSetConfig(llmProvider claudetool.LLMServiceProvider, llmManager server.LLMProvider) claudetool.ToolSetConfig {
+func setupToolSetConfig(llmProvider claudetool.LLMServiceProvider, llmManager server.LLMProvider, browserPool *browse.Pool) claudetool.ToolSetConfig {
 	wd, err := os.Getwd()
 	if err != nil {
 		// Fallback to "/" if we can't get working directory
@@ -376,7 +631,7 @@ func setupToolSetConfig(llmProvider claudetool.LLMServiceProvider, llmManager se
 		WorkingDir:           wd,
 		LLMProvider:          llmProvider,
 		EnableJITInstall:     claudetool.EnableBashToolJITInstall,
-		EnableBrowser:        true,
+		BrowserPool:          browserPool,
 		BuildAvailableModels: buildAvailableModels,
 	}
 }
@@ -395,9 +650,14 @@ func setupToolSetConfig(llmProvider claudetool.LLMServiceProvider, llmManager se
 //  3. Provider env vars (ANTHROPIC_API_KEY, ...) when no gateway is set.
 //  4. Predictable (always available).
 //
-// Custom DB-backed models load on top of the returned set.
+// Custom DB-backed models load on top of the returned set. A shelley.json
+// that fails to load is fatal: running without it would drop its settings.
 func buildLLMConfig(global GlobalConfig, logger *slog.Logger, database *db.DB) *server.LLMConfig {
-	defaultModel, sources := buildLLMModelSources(context.Background(), global, logger)
+	defaultModel, sources, err := buildLLMModelSources(context.Background(), global, logger)
+	if err != nil {
+		logger.Error("Failed to load config", "path", global.ConfigPath, "error", err)
+		os.Exit(1)
+	}
 
 	httpc := llmhttp.NewClient(nil)
 	return &server.LLMConfig{
@@ -406,14 +666,17 @@ func buildLLMConfig(global GlobalConfig, logger *slog.Logger, database *db.DB) *
 		DB:           database,
 		HTTPC:        httpc,
 		RefreshBuiltModels: func(ctx context.Context) ([]models.Built, error) {
-			_, sources := buildLLMModelSources(ctx, global, logger)
+			_, sources, err := buildLLMModelSources(ctx, global, logger)
+			if err != nil {
+				return nil, err
+			}
 			return modelsources.Build(models.All(), sources, httpc, logger), nil
 		},
 		Logger: logger,
 	}
 }
 
-func buildLLMModelSources(ctx context.Context, global GlobalConfig, logger *slog.Logger) (string, []modelsources.Source) {
+func buildLLMModelSources(ctx context.Context, global GlobalConfig, logger *slog.Logger) (string, []modelsources.Source, error) {
 	configPath := global.ConfigPath
 	defaultModel := global.DefaultModel
 	anthropicKey := os.Getenv("ANTHROPIC_API_KEY")
@@ -442,26 +705,14 @@ func buildLLMModelSources(ctx context.Context, global GlobalConfig, logger *slog
 		sources = append(sources, modelsources.LLMIntegration(integ, suffix))
 	}
 
-	var gateway string
-	if configPath != "" {
-		data, err := os.ReadFile(configPath)
-		if err == nil {
-			var cfg struct {
-				LLMGateway   string `json:"llm_gateway"`
-				DefaultModel string `json:"default_model"`
-			}
-			if err := json.Unmarshal(data, &cfg); err != nil {
-				logger.Warn("Failed to parse config file", "path", configPath, "error", err)
-			} else {
-				gateway = strings.TrimSuffix(cfg.LLMGateway, "/")
-				if cfg.DefaultModel != "" && defaultModel == "" {
-					defaultModel = cfg.DefaultModel
-					logger.Info("Using default model from config", "model", cfg.DefaultModel)
-				}
-			}
-		} else if !os.IsNotExist(err) {
-			logger.Warn("Failed to read config file", "path", configPath, "error", err)
-		}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return "", nil, err
+	}
+	gateway := strings.TrimSuffix(cfg.LLMGateway, "/")
+	if cfg.DefaultModel != "" && defaultModel == "" {
+		defaultModel = cfg.DefaultModel
+		logger.Info("Using default model from config", "model", cfg.DefaultModel)
 	}
 
 	if global.DisableGateway {
@@ -491,7 +742,7 @@ func buildLLMModelSources(ctx context.Context, global GlobalConfig, logger *slog
 
 	// 4. Predictable always available.
 	sources = append(sources, modelsources.Predictable())
-	return defaultModel, sources
+	return defaultModel, sources, nil
 }
 
 // runModels prints the materialized list of built-in models the server
@@ -511,7 +762,7 @@ func runModels(global GlobalConfig, args []string) {
 		os.Exit(2)
 	}
 
-	logger := setupLogging(global.Debug)
+	logger := setupLogging(os.Stdout, global.Debug)
 	llmCfg := buildLLMConfig(global, logger, nil)
 
 	defaultID := llmCfg.DefaultModel
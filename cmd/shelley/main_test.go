@@ -15,6 +15,8 @@ import (
 	"testing"
 	"time"
 
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/db/generated"
 	"shelley.exe.dev/llm"
 	"shelley.exe.dev/models"
 	"shelley.exe.dev/modelsources"
@@ -88,6 +90,22 @@ func TestBuildLLMConfigSkipsGatewayWhenReflectionFoundLLMIntegration(t *testing.
 	}
 }
 
+func TestBuildLLMModelSourcesFailsOnBadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "shelley.json")
+	if err := os.WriteFile(configPath, []byte(`{"admin_token": "${SHELLEY_TEST_UNSET}"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	global := GlobalConfig{ConfigPath: configPath, DisableLLMIntegration: true}
+	if _, _, err := buildLLMModelSources(context.Background(), global, logger); err == nil {
+		t.Fatal("expected an error for a config that fails to load")
+	}
+	global.ConfigPath = filepath.Join(t.TempDir(), "missing.json")
+	if _, _, err := buildLLMModelSources(context.Background(), global, logger); err != nil {
+		t.Fatalf("missing config: %v", err)
+	}
+}
+
 func TestToolModelsHideUnknownIntegrationModelsButKeepCustomModels(t *testing.T) {
 	provider := &tieredModelProvider{
 		ids: []string{"gpt-5.6-sol", "upstream-only", "my-custom-model"},
@@ -98,7 +116,7 @@ func TestToolModelsHideUnknownIntegrationModelsButKeepCustomModels(t *testing.T)
 		},
 	}
 
-	got := setupToolSetConfig(nil, provider).BuildAvailableModels()
+	got := setupToolSetConfig(nil, provider, nil).BuildAvailableModels()
 	if len(got) != 2 || got[0].ID != "gpt-5.6-sol" || got[1].ID != "my-custom-model" {
 		t.Fatalf("available tool models = %+v, want known and custom models", got)
 	}
@@ -113,6 +131,144 @@ func findBuiltModelSource(built []models.Built, id string) string {
 	return ""
 }
 
+func TestLoadConfigExpandsRefs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SHELLEY_TEST_DSN", "postgres://db")
+	path := filepath.Join(dir, "shelley.json")
+	config := `{
+		"databases": {"main": {"driver": "postgres", "dsn": "${SHELLEY_TEST_DSN}"}},
+		"mcp_servers": {"gh": {"url": "https://mcp.example.com", "headers": {"Authorization": "Bearer ${file:token}", "X-Literal": "$${x}"}}},
+		"subagent_personas": {"r": {"system_prompt": "Use ${HOME}."}}
+	}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Databases["main"].DSN; got != "postgres://db" {
+		t.Errorf("dsn = %q", got)
+	}
+	if got := cfg.MCPServers["gh"].Headers["Authorization"]; got != "Bearer s3cret" {
+		t.Errorf("header = %q", got)
+	}
+	if got := cfg.MCPServers["gh"].Headers["X-Literal"]; got != "${x}" {
+		t.Errorf("escaped reference = %q", got)
+	}
+	if got := cfg.SubagentPersonas["r"].SystemPrompt; got != "Use ${HOME}." {
+		t.Errorf("reference outside a credential = %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"admin_token": "${SHELLEY_TEST_UNSET}"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path); err == nil || !strings.Contains(err.Error(), "admin_token: environment variable SHELLEY_TEST_UNSET is not set") {
+		t.Errorf("unset variable: %v", err)
+	}
+}
+
+func TestReloadContextLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shelley.json")
+	var tc claudetool.ToolSetConfig
+	// Each load applies to the tool settings the previous one left, as a
+	// reload does.
+	for _, c := range []struct {
+		config string
+		want   float64
+	}{
+		{`{"context_limit": 0.5}`, 0.5},
+		{`{"context_limit": -1}`, 0},
+		{`{}`, defaultContextLimit},
+		{`{"context_limit": -1}`, 0},
+	} {
+		if err := os.WriteFile(path, []byte(c.config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := loadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := applyToolConfig(cfg, &tc); err != nil {
+			t.Fatal(err)
+		}
+		if tc.ContextLimit != c.want {
+			t.Errorf("%s: context limit = %v, want %v", c.config, tc.ContextLimit, c.want)
+		}
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	oldDiscover := discoverLLMIntegrations
+	discoverLLMIntegrations = func(context.Context, *http.Client, *slog.Logger) modelsources.LLMIntegrationDiscoveryResult {
+		return modelsources.LLMIntegrationDiscoveryResult{}
+	}
+	t.Cleanup(func() { discoverLLMIntegrations = oldDiscover })
+	for _, key := range []string{"ANTHROPIC_API_KEY", "OPENAI_API_KEY", "GEMINI_API_KEY", "FIREWORKS_API_KEY"} {
+		t.Setenv(key, "")
+	}
+
+	validate := func(config string) *configReport {
+		path := filepath.Join(t.TempDir(), "shelley.json")
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return validateConfig(context.Background(), GlobalConfig{ConfigPath: path, PredictableOnly: true}, true)
+	}
+
+	if r := validate(`{"$schema": "shelley.schema.json", "default_model": "predictable", "tool_policies": {"*": {"timeout_seconds": 60}}}`); len(r.Errors) > 0 {
+		t.Errorf("valid config: %v", r.Errors)
+	}
+	for config, want := range map[string]string{
+		`{"defualt_model": "predictable"}`:                           `did you mean "default_model"`,
+		"{\n\"tool_parallelism\": \"4\"}":                            "line 2: tool_parallelism",
+		`{"default_model": "no-such-model"}`:                         `model "no-such-model" is not available`,
+		`{"model_tool_overrides": {"predictable": {"bsah": "on"}}}`:  `unknown tool "bsah"`,
+		`{"databases": {"main": {"driver": "oracle", "dsn": "x"}}}`:  `unknown driver "oracle"`,
+		`{"mcp_servers": {"x": {"command": "no-such-shelley-cmd"}}}`: `command "no-such-shelley-cmd" not found`,
+		`{"subagent_personas": {"r": {"reasoning": "extreme"}}}`:     "unknown reasoning level",
+		`{"otlp_endpoint": "localhost:4318"}`:                        "otlp_endpoint",
+		`{"context_limit": 1.5}`:                                     "context_limit",
+	} {
+		r := validate(config)
+		if len(r.Errors) != 1 || !strings.Contains(r.Errors[0], want) {
+			t.Errorf("%s: got errors %q, want one containing %q", config, r.Errors, want)
+		}
+	}
+}
+
+func TestEvalTasksAndReport(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "fix.json"), []byte(`{"prompt": "fix it", "fixture": "calc", "check": "go test ./..."}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "add.json"), []byte(`{"prompt": "add it", "check": "true", "timeout_seconds": 60}`), 0o644)
+	tasks, err := loadEvalTasks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[0].name != "add" || tasks[1].Fixture != filepath.Join(dir, "calc") {
+		t.Fatalf("tasks = %+v", tasks)
+	}
+	os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"prompt": "p", "check": "c", "cmd": "x"}`), 0o644)
+	if _, err := loadEvalTasks(dir); err == nil || !strings.Contains(err.Error(), "bad.json") {
+		t.Errorf("unknown field: err = %v", err)
+	}
+
+	var out bytes.Buffer
+	printEvalReport(&out, []generated.EvalResult{
+		{RunID: "r1", Task: "add", Model: "m", Status: "passed", CostUsd: 0.5, DurationMs: 1000},
+		{RunID: "r1", Task: "fix", Model: "m", Status: "failed", CostUsd: 0.25, DurationMs: 2000, ConversationID: "c1", Detail: "FAIL calc"},
+		{RunID: "r2", Task: "add", Model: "m", Status: "timeout", DurationMs: 3000},
+	})
+	for _, want := range []string{"m@r1", "m@r2", "1/2 passed $0.7500 3s", "0/1 passed", "failed m@r1 fix (conversation c1)\n  FAIL calc"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
 func TestCLICommands(t *testing.T) {
 	// Build the binary once for this test and its subtests
 	tempDir := t.TempDir()
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"shelley.exe.dev/worker"
+)
+
+// Reconnection backoff for "shelley worker".
+const (
+	workerMinBackoff = time.Second
+	workerMaxBackoff = 30 * time.Second
+)
+
+func runWorker(args []string) {
+	if len(args) > 0 && args[0] == "exec" {
+		runWorkerExec(args[1:])
+		return
+	}
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	serverURL := fs.String("server", "", "URL of the Shelley server (required)")
+	hostname, _ := os.Hostname()
+	name := fs.String("name", hostname, "Name conversations select this worker by")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: shelley worker -server URL [-name NAME]\n\n")
+		fmt.Fprintf(fs.Output(), "Connects to the Shelley server and runs the commands and file edits of\n")
+		fmt.Fprintf(fs.Output(), "conversations whose \"worker\" option names this worker, reconnecting when\n")
+		fmt.Fprintf(fs.Output(), "the connection drops. The server's worker_token is read from the\n")
+		fmt.Fprintf(fs.Output(), "SHELLEY_WORKER_TOKEN environment variable.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	token := os.Getenv("SHELLEY_WORKER_TOKEN")
+	if *serverURL == "" || *name == "" || token == "" || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	backoff := workerMinBackoff
+	for {
+		fmt.Fprintf(os.Stderr, "Connecting to %s as worker %s\n", *serverURL, *name)
+		start := time.Now()
+		err := worker.Connect(ctx, *serverURL, *name, token)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > workerMaxBackoff {
+			backoff = workerMinBackoff
+		}
+		fmt.Fprintf(os.Stderr, "Disconnected: %v; reconnecting in %v\n", err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(2*backoff, workerMaxBackoff)
+	}
+}
+
+// runWorkerExec relays a command to a worker through the server's relay
+// socket. Tools run it in place of their command, the way the docker
+// sandbox runs "docker exec".
+func runWorkerExec(args []string) {
+	fs := flag.NewFlagSet("worker exec", flag.ExitOnError)
+	socket := fs.String("socket", "", "the server's relay socket")
+	name := fs.String("name", "", "the worker to run the command on")
+	dir := fs.String("dir", "", "the working directory on the worker")
+	var env envFlag
+	fs.Var(&env, "e", "KEY=VALUE to add to the command's environment (repeatable)")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: shelley worker exec -socket SOCKET -name NAME [-dir DIR] [-e KEY=VALUE]... -- CMD [ARGS...]\n")
+		os.Exit(2)
+	}
+	c, err := net.Dial("unix", *socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shelley worker exec: %v\n", err)
+		os.Exit(255)
+	}
+	exec := worker.Frame{Worker: *name, Args: fs.Args(), Dir: *dir, Env: env}
+	code, err := worker.Relay(context.Background(), worker.Stream(c), exec, os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		// Like ssh, exit 255 when the command couldn't be run.
+		fmt.Fprintf(os.Stderr, "shelley worker exec: %v\n", err)
+		os.Exit(255)
+	}
+	os.Exit(code)
+}
+
+type envFlag []string
+
+func (f *envFlag) String() string { return strings.Join(*f, " ") }
+
+func (f *envFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
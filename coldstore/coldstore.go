@@ -0,0 +1,143 @@
+// Package coldstore stores blobs, such as the messages of long-archived
+// conversations, outside the database: in a local directory or an
+// S3-compatible bucket.
+package coldstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Get for a key that isn't stored.
+var ErrNotFound = errors.New("not found in cold storage")
+
+// Store is a place to put blobs by key. Keys are slash-separated paths.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes a blob. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config configures cold storage in shelley.json. Set either Dir or S3.
+type Config struct {
+	// AfterDays is how long a conversation stays archived before its
+	// messages move to cold storage. The default is 30.
+	AfterDays int `json:"after_days"`
+	// Dir is a local directory to store blobs in.
+	Dir string `json:"dir"`
+	// S3 is an S3-compatible bucket to store blobs in.
+	S3 S3Config `json:"s3"`
+}
+
+// Enabled reports whether c configures a store.
+func (c Config) Enabled() bool {
+	return c.Dir != "" || c.S3 != S3Config{}
+}
+
+// After is how long a conversation stays archived before it moves.
+func (c Config) After() time.Duration {
+	if c.AfterDays == 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(c.AfterDays) * 24 * time.Hour
+}
+
+// Validate checks an enabled c.
+func (c Config) Validate() error {
+	switch {
+	case c.AfterDays < 0:
+		return fmt.Errorf("after_days must be positive")
+	case c.Dir != "" && c.S3 != S3Config{}:
+		return fmt.Errorf("set dir or s3, not both")
+	case c.Dir != "":
+		return nil
+	}
+	return c.S3.validate()
+}
+
+// New returns the store c configures.
+func New(c Config) (Store, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if c.Dir != "" {
+		return Dir(c.Dir), nil
+	}
+	return &S3{Config: c.S3, Client: http.DefaultClient}, nil
+}
+
+// Dir is a Store that keeps each blob in a file under a directory.
+type Dir string
+
+func (d Dir) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid cold storage key %q", key)
+	}
+	return filepath.Join(string(d), filepath.FromSlash(key)), nil
+}
+
+func (d Dir) Put(ctx context.Context, key string, data []byte) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// Write and rename so a crash never leaves a partial blob behind.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d Dir) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	return data, err
+}
+
+func (d Dir) Delete(ctx context.Context, key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// joinKey joins a key prefix, which may be empty, and a key.
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + key
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"errors"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -1091,3 +1092,72 @@ func TestPromoteDraftAtomicOverrides(t *testing.T) {
 		t.Fatalf("promote2 options clobbered: got %+v", got)
 	}
 }
+
+func TestScratchpadNotes(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	conv, err := db.CreateConversation(ctx, stringPtr("notes"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := conv.ConversationID
+	if err := db.SetScratchpadNote(ctx, id, "plan", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetScratchpadNote(ctx, id, "plan", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := db.ScratchpadNotes(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes["plan"] != "v2" {
+		t.Fatalf("got %v, want plan=v2", notes)
+	}
+	if ok, err := db.DeleteScratchpadNote(ctx, id, "plan"); err != nil || !ok {
+		t.Fatalf("delete: %v %v", ok, err)
+	}
+	if ok, err := db.DeleteScratchpadNote(ctx, id, "plan"); err != nil || ok {
+		t.Fatalf("second delete: %v %v", ok, err)
+	}
+}
+
+func TestConversationStatuses(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	conv, err := db.CreateConversation(ctx, stringPtr("statuses"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := conv.ConversationID
+	if status, err := db.ConversationStatus(ctx, id); err != nil || status != "" {
+		t.Fatalf("initial status: %q %v", status, err)
+	}
+	for _, status := range []string{"thinking", "thinking", "running-tool:bash", "awaiting-user"} {
+		if err := db.RecordConversationStatus(ctx, id, status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.CreateMessage(ctx, CreateMessageParams{
+		ConversationID: id,
+		Type:           MessageTypeUser,
+		LLMData:        map[string]string{"text": "hi"},
+		Status:         "thinking",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := db.ConversationStatus(ctx, id); err != nil || status != "thinking" {
+		t.Fatalf("status: %q %v", status, err)
+	}
+	history, err := db.ConversationStatusHistory(ctx, id, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, h := range history {
+		got = append(got, h.Status)
+	}
+	if want := []string{"thinking", "awaiting-user", "running-tool:bash", "thinking"}; !slices.Equal(got, want) {
+		t.Fatalf("history = %v, want %v", got, want)
+	}
+}
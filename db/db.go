@@ -262,6 +262,65 @@ type ConversationOptions struct {
 	// discord, ntfy) for this conversation. Useful for cron-style or
 	// self-invoked conversations that shouldn't ping the user.
 	DisableNotifications bool `json:"disable_notifications,omitempty"`
+	// Notifications subscribes (NotificationsSubscribed) or mutes
+	// (NotificationsMuted) this conversation regardless of the server-wide
+	// default. Empty follows the default.
+	Notifications string `json:"notifications,omitempty"`
+	// ToolPolicies bounds tool runs, keyed by tool name or "*" for all tools.
+	// Entries replace the server-wide policy for the same key.
+	ToolPolicies map[string]ToolPolicy `json:"tool_policies,omitempty"`
+	// Persona names the configured subagent persona a subagent conversation
+	// was created with.
+	Persona string `json:"persona,omitempty"`
+	// Instructions are the user's instructions for this conversation alone,
+	// such as "don't touch the migrations directory". They are appended to
+	// the system prompt of each request, so changes apply to the next one.
+	Instructions string `json:"instructions,omitempty"`
+	// Env is set for the commands the bash and shell tools run, over the
+	// project's env. The new-conversation hook sets it.
+	Env map[string]string `json:"env,omitempty"`
+	// HookContext is text the new-conversation hook added to the system
+	// prompt.
+	HookContext string `json:"hook_context,omitempty"`
+	// Worker names the connected "shelley worker" that runs the
+	// conversation's commands and file edits. Its subagents inherit it.
+	Worker string `json:"worker,omitempty"`
+	// Workspace asks for the conversation to run in a docker workspace, a
+	// container holding a clone of a repository. Its subagents share it.
+	Workspace *WorkspaceOptions `json:"workspace,omitempty"`
+	// EmailFrom is the address whose email started the conversation. Only
+	// its answers to the server's replies continue the conversation.
+	EmailFrom string `json:"email_from,omitempty"`
+}
+
+// WorkspaceOptions describe a conversation's docker workspace. Clients set
+// Repo and Ref; the server sets the rest when it creates the container.
+type WorkspaceOptions struct {
+	// Repo is the git URL or path to clone.
+	Repo string `json:"repo"`
+	// Ref is the branch or tag to clone; empty means the default branch.
+	Ref       string `json:"ref,omitempty"`
+	Container string `json:"container,omitempty"`
+	// Dir is where the clone is in the container.
+	Dir string `json:"dir,omitempty"`
+	// Base is the commit cloned, which diffs are taken against.
+	Base string `json:"base,omitempty"`
+}
+
+// Values for ConversationOptions.Notifications.
+const (
+	NotificationsSubscribed = "subscribed"
+	NotificationsMuted      = "muted"
+)
+
+// ToolPolicy limits a tool's wall-clock time, output size, and concurrent
+// calls, and can have oversized output summarized. Zero fields are
+// unlimited.
+type ToolPolicy struct {
+	TimeoutSeconds  int  `json:"timeout_seconds,omitempty"`
+	MaxOutputBytes  int  `json:"max_output_bytes,omitempty"`
+	SummarizeOutput bool `json:"summarize_output,omitempty"`
+	MaxConcurrent   int  `json:"max_concurrent,omitempty"`
 }
 
 // ParseConversationOptions parses a JSON string into ConversationOptions.
@@ -346,6 +405,58 @@ func (db *DB) SetConversationThinkingLevel(ctx context.Context, conversationID,
 	return opts, err
 }
 
+// SetConversationNotifications atomically sets a conversation's notification
+// subscription, preserving all other option fields. It clears
+// DisableNotifications so the explicit choice wins. It returns the resulting
+// options.
+func (db *DB) SetConversationNotifications(ctx context.Context, conversationID, notifications string) (ConversationOptions, error) {
+	var opts ConversationOptions
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		raw, err := q.GetConversationOptions(ctx, conversationID)
+		if err != nil {
+			return err
+		}
+		opts = ParseConversationOptions(raw)
+		opts.Notifications = notifications
+		opts.DisableNotifications = false
+		optsJSON, err := json.Marshal(opts)
+		if err != nil {
+			return fmt.Errorf("failed to marshal conversation options: %w", err)
+		}
+		return q.UpdateConversationOptions(ctx, generated.UpdateConversationOptionsParams{
+			ConversationID:      conversationID,
+			ConversationOptions: string(optsJSON),
+		})
+	})
+	return opts, err
+}
+
+// SetConversationInstructions atomically sets a conversation's
+// instructions, preserving all other option fields. It returns the
+// resulting options.
+func (db *DB) SetConversationInstructions(ctx context.Context, conversationID, instructions string) (ConversationOptions, error) {
+	var opts ConversationOptions
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		raw, err := q.GetConversationOptions(ctx, conversationID)
+		if err != nil {
+			return err
+		}
+		opts = ParseConversationOptions(raw)
+		opts.Instructions = instructions
+		optsJSON, err := json.Marshal(opts)
+		if err != nil {
+			return fmt.Errorf("failed to marshal conversation options: %w", err)
+		}
+		return q.UpdateConversationOptions(ctx, generated.UpdateConversationOptionsParams{
+			ConversationID:      conversationID,
+			ConversationOptions: string(optsJSON),
+		})
+	})
+	return opts, err
+}
+
 // CreateConversation creates a new conversation with an optional slug.
 func (db *DB) CreateConversation(ctx context.Context, slug *string, userInitiated bool, cwd, model *string, opts ConversationOptions) (*generated.Conversation, error) {
 	conversationID, err := generateConversationID()
@@ -503,6 +614,11 @@ type QueuedMessage struct {
 	// context with no request/header available. Stamped onto the messages row
 	// when the message drains. Empty when the request carried no header.
 	UserEmail string `json:"user_email,omitempty"`
+	// HandoffFrom is the conversation that posted this message via the
+	// handoff tool or API. Stamped into the messages row's user_data on
+	// drain so the UI can show where the message came from. Empty for
+	// messages typed by a user.
+	HandoffFrom string `json:"handoff_from,omitempty"`
 }
 
 // ParseQueuedMessagesStrict parses the queued_messages JSON array, returning an
@@ -1124,6 +1240,10 @@ type CreateMessageParams struct {
 	// the row nor the array change persists, so a crash can't leave an array
 	// entry that Hydrate would re-feed as a duplicate.
 	RemoveQueuedID string
+	// Status, when non-empty, also records the conversation's new status
+	// (see RecordConversationStatus) inside the same Tx, so a status change
+	// that comes with a message costs no commit of its own.
+	Status string
 }
 
 // nullableString returns nil for an empty string so the column is stored as
@@ -1251,6 +1371,11 @@ func insertMessageTx(ctx context.Context, q *generated.Queries, params CreateMes
 			return generated.Message{}, err
 		}
 	}
+	if params.Status != "" {
+		if err := recordConversationStatus(ctx, q, params.ConversationID, params.Status); err != nil {
+			return generated.Message{}, err
+		}
+	}
 	return message, nil
 }
 
@@ -1674,6 +1799,54 @@ func (db *DB) CreateSubagentConversation(ctx context.Context, slug, parentID str
 	return &conversation, err
 }
 
+// GetConversationTreeUsage aggregates LLM usage of a conversation and all
+// its descendant conversations, grouped by model.
+func (db *DB) GetConversationTreeUsage(ctx context.Context, conversationID string) ([]generated.GetConversationTreeUsageRow, error) {
+	var rows []generated.GetConversationTreeUsageRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		rows, err = generated.New(rx.Conn()).GetConversationTreeUsage(ctx, conversationID)
+		return err
+	})
+	return rows, err
+}
+
+// GetUsageSince aggregates LLM usage recorded at or after since, grouped by
+// conversation and model.
+func (db *DB) GetUsageSince(ctx context.Context, since time.Time) ([]generated.GetUsageSinceRow, error) {
+	var rows []generated.GetUsageSinceRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		rows, err = generated.New(rx.Conn()).GetUsageSince(ctx, since.Unix())
+		return err
+	})
+	return rows, err
+}
+
+// ListRecentPrompts returns the first prompts of the top-level
+// conversations in cwd updated since since, newest first.
+func (db *DB) ListRecentPrompts(ctx context.Context, cwd string, since time.Time, limit int64) ([]generated.ListRecentPromptsRow, error) {
+	var rows []generated.ListRecentPromptsRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		rows, err = generated.New(rx.Conn()).ListRecentPrompts(ctx, generated.ListRecentPromptsParams{Cwd: &cwd, SinceUnix: since.Unix(), Limit: limit})
+		return err
+	})
+	return rows, err
+}
+
+// GetConversationAncestry returns the top-level ancestor of a conversation
+// and the conversation's subagent nesting depth.
+func (db *DB) GetConversationAncestry(ctx context.Context, conversationID string) (generated.GetConversationAncestryRow, error) {
+	var row generated.GetConversationAncestryRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		row, err = generated.New(rx.Conn()).GetConversationAncestry(ctx, conversationID)
+		return err
+	})
+	return row, err
+}
+
 // GetSubagentUsage aggregates LLM usage across all descendant conversations
 // of parentID (recursively), grouped by model.
 func (db *DB) GetSubagentUsage(ctx context.Context, parentID string) ([]generated.GetSubagentUsageRow, error) {
@@ -1814,7 +1987,7 @@ type SubagentDBAdapter struct {
 
 // GetOrCreateSubagentConversation implements claudetool.SubagentDB.
 // Returns the conversation ID and the actual slug used (may differ if a suffix was added).
-func (a *SubagentDBAdapter) GetOrCreateSubagentConversation(ctx context.Context, slug, parentID, cwd string) (string, string, error) {
+func (a *SubagentDBAdapter) GetOrCreateSubagentConversation(ctx context.Context, slug, parentID, cwd, persona string) (string, string, error) {
 	// Try to find existing with exact slug
 	existing, err := a.DB.GetConversationBySlugAndParent(ctx, slug, parentID)
 	if err != nil {
@@ -1824,12 +1997,24 @@ func (a *SubagentDBAdapter) GetOrCreateSubagentConversation(ctx context.Context,
 		return existing.ConversationID, *existing.Slug, nil
 	}
 
+	parent, err := a.DB.GetConversationByID(ctx, parentID)
+	if err != nil {
+		return "", "", err
+	}
+
 	// Try to create new, handling unique constraint violations by appending numbers
 	baseSlug := slug
 	actualSlug := slug
 	for attempt := 0; attempt < 100; attempt++ {
 		conv, err := a.DB.CreateSubagentConversation(ctx, actualSlug, parentID, &cwd)
 		if err == nil {
+			parentOpts := ParseConversationOptions(parent.ConversationOptions)
+			opts := ConversationOptions{Persona: persona, Worker: parentOpts.Worker, Workspace: parentOpts.Workspace}
+			if opts.Persona != "" || opts.Worker != "" || opts.Workspace != nil {
+				if err := a.DB.UpdateConversationOptions(ctx, conv.ConversationID, opts); err != nil {
+					return "", "", err
+				}
+			}
 			return conv.ConversationID, actualSlug, nil
 		}
 
@@ -1914,6 +2099,34 @@ func (db *DB) DeleteModel(ctx context.Context, modelID string) error {
 	})
 }
 
+// maxNotificationDeliveries is how many delivery records are kept.
+const maxNotificationDeliveries = 1000
+
+// RecordNotificationDelivery stores a notification delivery attempt, pruning
+// all but the most recent maxNotificationDeliveries.
+func (db *DB) RecordNotificationDelivery(ctx context.Context, params generated.InsertNotificationDeliveryParams) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if err := q.InsertNotificationDelivery(ctx, params); err != nil {
+			return err
+		}
+		return q.PruneNotificationDeliveries(ctx, maxNotificationDeliveries)
+	})
+}
+
+// ListNotificationDeliveries returns up to limit of the most recent
+// notification delivery attempts, newest first.
+func (db *DB) ListNotificationDeliveries(ctx context.Context, limit int64) ([]generated.NotificationDelivery, error) {
+	var deliveries []generated.NotificationDelivery
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		deliveries, err = q.ListNotificationDeliveries(ctx, limit)
+		return err
+	})
+	return deliveries, err
+}
+
 func (db *DB) GetNotificationChannels(ctx context.Context) ([]generated.NotificationChannel, error) {
 	var channels []generated.NotificationChannel
 	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
@@ -2138,3 +2351,264 @@ func (db *DB) DeleteFeatureFlagOverride(ctx context.Context, name string) error
 		return q.DeleteFeatureFlag(ctx, name)
 	})
 }
+
+// ScratchpadNotes returns a conversation's scratchpad notes by name.
+func (db *DB) ScratchpadNotes(ctx context.Context, conversationID string) (map[string]string, error) {
+	var rows []generated.ListScratchpadNotesRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		rows, err = generated.New(rx.Conn()).ListScratchpadNotes(ctx, conversationID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(rows))
+	for _, r := range rows {
+		out[r.Name] = r.Content
+	}
+	return out, nil
+}
+
+// SetScratchpadNote creates or replaces a scratchpad note.
+func (db *DB) SetScratchpadNote(ctx context.Context, conversationID, name, content string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		return generated.New(tx.Conn()).SetScratchpadNote(ctx, generated.SetScratchpadNoteParams{
+			ConversationID: conversationID,
+			Name:           name,
+			Content:        content,
+		})
+	})
+}
+
+// DeleteScratchpadNote removes a scratchpad note, reporting whether it existed.
+func (db *DB) DeleteScratchpadNote(ctx context.Context, conversationID, name string) (bool, error) {
+	var n int64
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		var err error
+		n, err = generated.New(tx.Conn()).DeleteScratchpadNote(ctx, generated.DeleteScratchpadNoteParams{
+			ConversationID: conversationID,
+			Name:           name,
+		})
+		return err
+	})
+	return n > 0, err
+}
+
+// recordConversationStatus appends status to a conversation's status
+// history within an open Tx, unless it's already the current status.
+func recordConversationStatus(ctx context.Context, q *generated.Queries, conversationID, status string) error {
+	current, err := q.GetConversationStatus(ctx, conversationID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if current == status {
+		return nil
+	}
+	return q.InsertConversationStatus(ctx, generated.InsertConversationStatusParams{
+		ConversationID: conversationID,
+		Status:         status,
+	})
+}
+
+// RecordConversationStatus records a conversation's new status, unless it's
+// already the current one.
+func (db *DB) RecordConversationStatus(ctx context.Context, conversationID, status string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		return recordConversationStatus(ctx, generated.New(tx.Conn()), conversationID, status)
+	})
+}
+
+// ConversationStatus returns a conversation's current status, or "" if it
+// has none recorded.
+func (db *DB) ConversationStatus(ctx context.Context, conversationID string) (string, error) {
+	var status string
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		status, err = generated.New(rx.Conn()).GetConversationStatus(ctx, conversationID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return status, err
+}
+
+// ConversationStatusHistory returns a conversation's newest limit status
+// changes, newest first.
+func (db *DB) ConversationStatusHistory(ctx context.Context, conversationID string, limit int64) ([]generated.ListConversationStatusesRow, error) {
+	var rows []generated.ListConversationStatusesRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		rows, err = generated.New(rx.Conn()).ListConversationStatuses(ctx, generated.ListConversationStatusesParams{
+			ConversationID: conversationID,
+			Limit:          limit,
+		})
+		return err
+	})
+	return rows, err
+}
+
+// CodebaseAnalysis returns the codebase analysis stored for root under
+// fingerprint and when it was stored, or "" if there is none.
+func (db *DB) CodebaseAnalysis(ctx context.Context, root, fingerprint string) (string, time.Time, error) {
+	var row generated.GetCodebaseAnalysisRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		row, err = generated.New(rx.Conn()).GetCodebaseAnalysis(ctx, generated.GetCodebaseAnalysisParams{
+			Root:        root,
+			Fingerprint: fingerprint,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	return row.Analysis, row.UpdatedAt, err
+}
+
+// SetCodebaseAnalysis stores root's codebase analysis under fingerprint,
+// replacing any other analysis of root.
+func (db *DB) SetCodebaseAnalysis(ctx context.Context, root, fingerprint, analysis string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		return generated.New(tx.Conn()).SetCodebaseAnalysis(ctx, generated.SetCodebaseAnalysisParams{
+			Root:        root,
+			Fingerprint: fingerprint,
+			Analysis:    analysis,
+		})
+	})
+}
+
+// RecordEvalResult stores the result of running an eval task.
+func (db *DB) RecordEvalResult(ctx context.Context, params generated.InsertEvalResultParams) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		return generated.New(tx.Conn()).InsertEvalResult(ctx, params)
+	})
+}
+
+// EvalResults returns the results of eval run runID in the order they were
+// recorded.
+func (db *DB) EvalResults(ctx context.Context, runID string) ([]generated.EvalResult, error) {
+	var results []generated.EvalResult
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		results, err = generated.New(rx.Conn()).ListEvalResults(ctx, runID)
+		return err
+	})
+	return results, err
+}
+
+// LatestEvalRunID returns the ID of the most recent eval run, or "" if there
+// are none.
+func (db *DB) LatestEvalRunID(ctx context.Context) (string, error) {
+	var runID string
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		runID, err = generated.New(rx.Conn()).LatestEvalRunID(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	return runID, err
+}
+
+// ErrConversationChanged is returned by FreezeConversation when messages
+// were added to the conversation after it was read.
+var ErrConversationChanged = errors.New("conversation changed while it was moved")
+
+// ColdStorageCandidates returns up to limit archived conversations that
+// haven't been updated, or thawed, for longer than age and whose messages
+// are in the database, oldest first.
+func (db *DB) ColdStorageCandidates(ctx context.Context, age time.Duration, limit int64) ([]string, error) {
+	var ids []string
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		var err error
+		ids, err = generated.New(rx.Conn()).ListColdStorageCandidates(ctx, generated.ListColdStorageCandidatesParams{
+			Age:              fmt.Sprintf("-%d seconds", int64(age.Seconds())),
+			MaxConversations: limit,
+		})
+		return err
+	})
+	return ids, err
+}
+
+// FreezeConversation deletes a conversation's messageCount messages, which
+// were stored in cold storage at key, and records where they are. The
+// usage of its agent messages stays in the database for usage reports, but
+// message search no longer finds the conversation.
+func (db *DB) FreezeConversation(ctx context.Context, conversationID, key string, messageCount, sizeBytes int64) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		n, err := q.CountMessagesInConversation(ctx, conversationID)
+		if err != nil {
+			return err
+		}
+		if n != messageCount {
+			return ErrConversationChanged
+		}
+		if err := q.UpsertColdConversation(ctx, generated.UpsertColdConversationParams{
+			ConversationID: conversationID,
+			StorageKey:     key,
+			MessageCount:   messageCount,
+			SizeBytes:      sizeBytes,
+		}); err != nil {
+			return err
+		}
+		if err := q.KeepColdMessageUsage(ctx, conversationID); err != nil {
+			return err
+		}
+		return q.DeleteConversationMessages(ctx, conversationID)
+	})
+}
+
+// FrozenConversation returns where a conversation's messages are in cold
+// storage, or nil if they are in the database.
+func (db *DB) FrozenConversation(ctx context.Context, conversationID string) (*generated.ColdConversation, error) {
+	var cold *generated.ColdConversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		c, err := generated.New(rx.Conn()).GetFrozenConversation(ctx, conversationID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		cold = &c
+		return err
+	})
+	return cold, err
+}
+
+// ThawConversation restores a frozen conversation's messages, as they were
+// read from cold storage.
+func (db *DB) ThawConversation(ctx context.Context, conversationID string, messages []generated.Message) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		for _, m := range messages {
+			if m.ConversationID != conversationID {
+				return fmt.Errorf("message %s belongs to conversation %s, not %s", m.MessageID, m.ConversationID, conversationID)
+			}
+			if err := q.RestoreMessage(ctx, generated.RestoreMessageParams{
+				MessageID:           m.MessageID,
+				ConversationID:      m.ConversationID,
+				SequenceID:          m.SequenceID,
+				Type:                m.Type,
+				LlmData:             m.LlmData,
+				UserData:            m.UserData,
+				UsageData:           m.UsageData,
+				CreatedAt:           m.CreatedAt.UTC().Format(time.DateTime),
+				DisplayData:         m.DisplayData,
+				ExcludedFromContext: m.ExcludedFromContext,
+				Generation:          m.Generation,
+				LlmApiUrl:           m.LlmApiUrl,
+				ModelName:           m.ModelName,
+				ForkedFromMessageID: m.ForkedFromMessageID,
+				UserEmail:           m.UserEmail,
+			}); err != nil {
+				return err
+			}
+		}
+		if err := q.DeleteColdMessageUsage(ctx, conversationID); err != nil {
+			return err
+		}
+		return q.MarkConversationThawed(ctx, conversationID)
+	})
+}
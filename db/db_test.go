@@ -2,8 +2,10 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -765,3 +767,153 @@ func TestCreateMessageFoldsAgentWorkingAndTimestamp(t *testing.T) {
 		t.Fatalf("expected error when both MarkAgentStart and MarkAgentDone are set")
 	}
 }
+
+func TestCodebaseAnalysis(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	if err := db.SetCodebaseAnalysis(ctx, "/repo", "a", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetCodebaseAnalysis(ctx, "/repo", "b", "second"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := db.CodebaseAnalysis(ctx, "/repo", "a"); err != nil || got != "" {
+		t.Fatalf("replaced fingerprint: got %q, %v; want nothing", got, err)
+	}
+	got, updated, err := db.CodebaseAnalysis(ctx, "/repo", "b")
+	if err != nil || got != "second" || updated.IsZero() {
+		t.Fatalf("got %q at %v, %v; want second", got, updated, err)
+	}
+}
+
+func TestEvalResults(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	if runID, err := db.LatestEvalRunID(ctx); err != nil || runID != "" {
+		t.Fatalf("empty database: got run %q, %v", runID, err)
+	}
+	for _, p := range []generated.InsertEvalResultParams{
+		{RunID: "r1", Task: "a", Model: "m", Status: "passed"},
+		{RunID: "r2", Task: "a", Model: "m", Status: "failed", CostUsd: 0.5},
+		{RunID: "r2", Task: "b", Model: "m", Status: "passed"},
+	} {
+		if err := db.RecordEvalResult(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runID, err := db.LatestEvalRunID(ctx)
+	if err != nil || runID != "r2" {
+		t.Fatalf("latest run = %q, %v; want r2", runID, err)
+	}
+	results, err := db.EvalResults(ctx, runID)
+	if err != nil || len(results) != 2 || results[0].Task != "a" || results[0].CostUsd != 0.5 || results[1].Task != "b" {
+		t.Fatalf("results of r2 = %+v, %v", results, err)
+	}
+}
+
+func TestFreezeAndThawConversation(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	conv, err := db.CreateConversation(ctx, stringPtr("old"), true, nil, nil, ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"first", "second"} {
+		if _, err := db.CreateMessage(ctx, CreateMessageParams{ConversationID: conv.ConversationID, Type: MessageTypeUser, UserData: map[string]any{"text": text}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.CreateMessage(ctx, CreateMessageParams{
+		ConversationID: conv.ConversationID,
+		Type:           MessageTypeAgent,
+		LLMData:        map[string]any{"Content": []any{map[string]any{"Type": 2, "Text": "a pelican"}}},
+		UsageData:      map[string]any{"input_tokens": 7, "output_tokens": 3},
+		ModelName:      "m",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	usage := func() int64 {
+		t.Helper()
+		rows, err := db.GetUsageSince(ctx, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int64
+		for _, r := range rows {
+			n += r.InputTokens
+		}
+		return n
+	}
+	found := func() bool {
+		t.Helper()
+		results, err := db.SearchConversationsFTS(ctx, "pelican", 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(results) == 1
+	}
+	if _, err := db.ArchiveConversation(ctx, conv.ConversationID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		_, err := tx.Conn().ExecContext(ctx, "UPDATE conversations SET updated_at = datetime('now', '-40 days') WHERE conversation_id = ?", conv.ConversationID)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := db.ColdStorageCandidates(ctx, 30*24*time.Hour, 10)
+	if err != nil || len(ids) != 1 || ids[0] != conv.ConversationID {
+		t.Fatalf("candidates = %v, %v", ids, err)
+	}
+	before, err := db.ListMessages(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FreezeConversation(ctx, conv.ConversationID, "k", 1, 10); !errors.Is(err, ErrConversationChanged) {
+		t.Fatalf("freezing with a stale message count: err = %v", err)
+	}
+	if err := db.FreezeConversation(ctx, conv.ConversationID, "k", int64(len(before)), 10); err != nil {
+		t.Fatal(err)
+	}
+	if msgs, _ := db.ListMessages(ctx, conv.ConversationID); len(msgs) != 0 {
+		t.Fatalf("%d messages left after freezing", len(msgs))
+	}
+	if ids, _ := db.ColdStorageCandidates(ctx, 30*24*time.Hour, 10); len(ids) != 0 {
+		t.Errorf("frozen conversation is still a candidate: %v", ids)
+	}
+	if cold, err := db.FrozenConversation(ctx, conv.ConversationID); err != nil || cold == nil || cold.StorageKey != "k" {
+		t.Fatalf("FrozenConversation = %+v, %v", cold, err)
+	}
+	if n := usage(); n != 7 {
+		t.Errorf("frozen input tokens = %d, want 7", n)
+	}
+	if found() {
+		t.Error("search finds a frozen conversation")
+	}
+
+	if err := db.ThawConversation(ctx, conv.ConversationID, before); err != nil {
+		t.Fatal(err)
+	}
+	after, err := db.ListMessages(ctx, conv.ConversationID)
+	if err != nil || len(after) != len(before) {
+		t.Fatalf("thawed messages = %d, %v", len(after), err)
+	}
+	for i := range before {
+		if after[i].MessageID != before[i].MessageID || after[i].SequenceID != before[i].SequenceID || !after[i].CreatedAt.Equal(before[i].CreatedAt) || !reflect.DeepEqual(after[i].UserData, before[i].UserData) || !reflect.DeepEqual(after[i].UsageData, before[i].UsageData) {
+			t.Errorf("message %d changed: %+v != %+v", i, after[i], before[i])
+		}
+	}
+	if n := usage(); n != 7 {
+		t.Errorf("thawed input tokens = %d, want 7", n)
+	}
+	if !found() {
+		t.Error("search does not find a thawed conversation")
+	}
+	if cold, err := db.FrozenConversation(ctx, conv.ConversationID); err != nil || cold != nil {
+		t.Errorf("thawed conversation is frozen: %+v, %v", cold, err)
+	}
+	if ids, _ := db.ColdStorageCandidates(ctx, 30*24*time.Hour, 10); len(ids) != 0 {
+		t.Errorf("just-thawed conversation is a candidate: %v", ids)
+	}
+}
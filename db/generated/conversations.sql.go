@@ -255,6 +255,33 @@ func (q *Queries) GetConversation(ctx context.Context, conversationID string) (C
 	return i, err
 }
 
+const getConversationAncestry = `-- name: GetConversationAncestry :one
+WITH RECURSIVE ancestors(conversation_id, parent_conversation_id, depth) AS (
+  SELECT c.conversation_id, c.parent_conversation_id, 0 FROM conversations c WHERE c.conversation_id = ?
+  UNION ALL
+  SELECT c.conversation_id, c.parent_conversation_id, a.depth + 1 FROM conversations c
+  JOIN ancestors a ON c.conversation_id = a.parent_conversation_id
+)
+SELECT CAST(conversation_id AS TEXT) AS root_conversation_id, CAST(depth AS INTEGER) AS depth
+FROM ancestors
+ORDER BY depth DESC
+LIMIT 1
+`
+
+type GetConversationAncestryRow struct {
+	RootConversationID string `json:"root_conversation_id"`
+	Depth              int64  `json:"depth"`
+}
+
+// The top-level ancestor of a conversation and how many subagent levels
+// below it the conversation is (0 for a top-level conversation).
+func (q *Queries) GetConversationAncestry(ctx context.Context, conversationID string) (GetConversationAncestryRow, error) {
+	row := q.db.QueryRowContext(ctx, getConversationAncestry, conversationID)
+	var i GetConversationAncestryRow
+	err := row.Scan(&i.RootConversationID, &i.Depth)
+	return i, err
+}
+
 const getConversationBySlug = `-- name: GetConversationBySlug :one
 SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, conversation_options, current_generation, agent_working, tags, is_draft, draft, queued_messages FROM conversations
 WHERE slug = ?
@@ -342,6 +369,72 @@ func (q *Queries) GetConversationQueuedMessages(ctx context.Context, conversatio
 	return queued_messages, err
 }
 
+const getConversationTreeUsage = `-- name: GetConversationTreeUsage :many
+WITH RECURSIVE tree(conversation_id) AS (
+  SELECT ? AS conversation_id
+  UNION ALL
+  SELECT c.conversation_id FROM conversations c
+  JOIN tree t ON c.parent_conversation_id = t.conversation_id
+)
+SELECT
+  m.model_name,
+  m.llm_api_url,
+  COUNT(*) AS llm_calls,
+  CAST(COALESCE(SUM(m.usage_data ->> 'input_tokens'), 0) AS INTEGER) AS input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cache_creation_input_tokens'), 0) AS INTEGER) AS cache_creation_input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cache_read_input_tokens'), 0) AS INTEGER) AS cache_read_input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'output_tokens'), 0) AS INTEGER) AS output_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cost_usd'), 0) AS REAL) AS cost_usd
+FROM agent_usage m
+JOIN tree t ON m.conversation_id = t.conversation_id
+GROUP BY m.model_name, m.llm_api_url
+`
+
+type GetConversationTreeUsageRow struct {
+	ModelName                *string `json:"model_name"`
+	LlmApiUrl                *string `json:"llm_api_url"`
+	LlmCalls                 int64   `json:"llm_calls"`
+	InputTokens              int64   `json:"input_tokens"`
+	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64   `json:"cache_read_input_tokens"`
+	OutputTokens             int64   `json:"output_tokens"`
+	CostUsd                  float64 `json:"cost_usd"`
+}
+
+// Aggregate LLM usage of a conversation and all its descendants, grouped
+// by model.
+func (q *Queries) GetConversationTreeUsage(ctx context.Context, conversationID string) ([]GetConversationTreeUsageRow, error) {
+	rows, err := q.db.QueryContext(ctx, getConversationTreeUsage, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetConversationTreeUsageRow{}
+	for rows.Next() {
+		var i GetConversationTreeUsageRow
+		if err := rows.Scan(
+			&i.ModelName,
+			&i.LlmApiUrl,
+			&i.LlmCalls,
+			&i.InputTokens,
+			&i.CacheCreationInputTokens,
+			&i.CacheReadInputTokens,
+			&i.OutputTokens,
+			&i.CostUsd,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getSubagentCounts = `-- name: GetSubagentCounts :many
 SELECT parent_conversation_id, COUNT(*) AS count
 FROM conversations
@@ -393,9 +486,8 @@ SELECT
   CAST(COALESCE(SUM(m.usage_data ->> 'cache_read_input_tokens'), 0) AS INTEGER) AS cache_read_input_tokens,
   CAST(COALESCE(SUM(m.usage_data ->> 'output_tokens'), 0) AS INTEGER) AS output_tokens,
   CAST(COALESCE(SUM(m.usage_data ->> 'cost_usd'), 0) AS REAL) AS cost_usd
-FROM messages m
+FROM agent_usage m
 JOIN descendants d ON m.conversation_id = d.conversation_id
-WHERE m.type = 'agent' AND m.usage_data IS NOT NULL
 GROUP BY m.model_name, m.llm_api_url
 `
 
@@ -491,6 +583,74 @@ func (q *Queries) GetSubagents(ctx context.Context, parentConversationID *string
 	return items, nil
 }
 
+const getUsageSince = `-- name: GetUsageSince :many
+SELECT
+  m.conversation_id,
+  c.slug,
+  m.model_name,
+  m.llm_api_url,
+  COUNT(*) AS llm_calls,
+  CAST(COALESCE(SUM(m.usage_data ->> 'input_tokens'), 0) AS INTEGER) AS input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cache_creation_input_tokens'), 0) AS INTEGER) AS cache_creation_input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cache_read_input_tokens'), 0) AS INTEGER) AS cache_read_input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'output_tokens'), 0) AS INTEGER) AS output_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cost_usd'), 0) AS REAL) AS cost_usd
+FROM agent_usage m
+JOIN conversations c ON c.conversation_id = m.conversation_id
+WHERE CAST(strftime('%s', m.created_at) AS INTEGER) >= CAST(?1 AS INTEGER)
+GROUP BY m.conversation_id, m.model_name, m.llm_api_url
+`
+
+type GetUsageSinceRow struct {
+	ConversationID           string  `json:"conversation_id"`
+	Slug                     *string `json:"slug"`
+	ModelName                *string `json:"model_name"`
+	LlmApiUrl                *string `json:"llm_api_url"`
+	LlmCalls                 int64   `json:"llm_calls"`
+	InputTokens              int64   `json:"input_tokens"`
+	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64   `json:"cache_read_input_tokens"`
+	OutputTokens             int64   `json:"output_tokens"`
+	CostUsd                  float64 `json:"cost_usd"`
+}
+
+// Aggregate LLM usage of agent messages created at or after a Unix time,
+// grouped by conversation and model, frozen conversations included.
+// Subagents count as their own conversations.
+func (q *Queries) GetUsageSince(ctx context.Context, sinceUnix int64) ([]GetUsageSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsageSince, sinceUnix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetUsageSinceRow{}
+	for rows.Next() {
+		var i GetUsageSinceRow
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.Slug,
+			&i.ModelName,
+			&i.LlmApiUrl,
+			&i.LlmCalls,
+			&i.InputTokens,
+			&i.CacheCreationInputTokens,
+			&i.CacheReadInputTokens,
+			&i.OutputTokens,
+			&i.CostUsd,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const incrementConversationGeneration = `-- name: IncrementConversationGeneration :one
 UPDATE conversations
 SET current_generation = current_generation + 1, updated_at = CURRENT_TIMESTAMP
@@ -745,6 +905,59 @@ func (q *Queries) ListConversations(ctx context.Context, arg ListConversationsPa
 	return items, nil
 }
 
+const listRecentPrompts = `-- name: ListRecentPrompts :many
+SELECT c.conversation_id, c.slug,
+  CAST(COALESCE((
+    SELECT je.value ->> 'Text'
+      FROM messages m, json_each(m.llm_data, '$.Content') je
+     WHERE m.conversation_id = c.conversation_id AND m.type = 'user'
+       AND je.value ->> 'Type' = 2
+     ORDER BY m.sequence_id, je.key LIMIT 1), '') AS TEXT) AS prompt
+FROM conversations c
+WHERE c.archived = FALSE AND c.parent_conversation_id IS NULL
+  AND c.cwd = ?1
+  AND CAST(strftime('%s', c.updated_at) AS INTEGER) >= CAST(?2 AS INTEGER)
+ORDER BY c.updated_at DESC
+LIMIT ?3
+`
+
+type ListRecentPromptsParams struct {
+	Cwd       *string `json:"cwd"`
+	SinceUnix int64   `json:"since_unix"`
+	Limit     int64   `json:"limit"`
+}
+
+type ListRecentPromptsRow struct {
+	ConversationID string  `json:"conversation_id"`
+	Slug           *string `json:"slug"`
+	Prompt         string  `json:"prompt"`
+}
+
+// The first prompts of the top-level conversations in a working directory
+// updated at or after a Unix time, newest first.
+func (q *Queries) ListRecentPrompts(ctx context.Context, arg ListRecentPromptsParams) ([]ListRecentPromptsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listRecentPrompts, arg.Cwd, arg.SinceUnix, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListRecentPromptsRow{}
+	for rows.Next() {
+		var i ListRecentPromptsRow
+		if err := rows.Scan(&i.ConversationID, &i.Slug, &i.Prompt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const promoteDraftConversation = `-- name: PromoteDraftConversation :one
 UPDATE conversations
 SET is_draft = FALSE, draft = '', updated_at = CURRENT_TIMESTAMP
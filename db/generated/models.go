@@ -15,6 +15,15 @@ type CacheSession struct {
 	LastSeenAt time.Time `json:"last_seen_at"`
 }
 
+type ColdConversation struct {
+	ConversationID string     `json:"conversation_id"`
+	StorageKey     string     `json:"storage_key"`
+	MessageCount   int64      `json:"message_count"`
+	SizeBytes      int64      `json:"size_bytes"`
+	FrozenAt       time.Time  `json:"frozen_at"`
+	ThawedAt       *time.Time `json:"thawed_at"`
+}
+
 type Conversation struct {
 	ConversationID       string    `json:"conversation_id"`
 	Slug                 *string   `json:"slug"`
@@ -34,6 +43,19 @@ type Conversation struct {
 	QueuedMessages       string    `json:"queued_messages"`
 }
 
+type EvalResult struct {
+	ResultID       int64     `json:"result_id"`
+	RunID          string    `json:"run_id"`
+	Task           string    `json:"task"`
+	Model          string    `json:"model"`
+	ConversationID string    `json:"conversation_id"`
+	Status         string    `json:"status"`
+	CostUsd        float64   `json:"cost_usd"`
+	DurationMs     int64     `json:"duration_ms"`
+	Detail         string    `json:"detail"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 type Message struct {
 	MessageID           string    `json:"message_id"`
 	ConversationID      string    `json:"conversation_id"`
@@ -78,3 +100,14 @@ type NotificationChannel struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+type NotificationDelivery struct {
+	DeliveryID     int64     `json:"delivery_id"`
+	ChannelID      string    `json:"channel_id"`
+	ChannelType    string    `json:"channel_type"`
+	EventType      string    `json:"event_type"`
+	ConversationID string    `json:"conversation_id"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error"`
+	CreatedAt      time.Time `json:"created_at"`
+}
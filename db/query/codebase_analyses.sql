@@ -0,0 +1,11 @@
+-- name: GetCodebaseAnalysis :one
+SELECT analysis, updated_at FROM codebase_analyses
+WHERE root = ? AND fingerprint = ?;
+
+-- name: SetCodebaseAnalysis :exec
+INSERT INTO codebase_analyses (root, fingerprint, analysis, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(root) DO UPDATE SET
+    fingerprint = excluded.fingerprint,
+    analysis = excluded.analysis,
+    updated_at = CURRENT_TIMESTAMP;
@@ -0,0 +1,48 @@
+-- name: ListColdStorageCandidates :many
+-- Archived conversations untouched for longer than age (a datetime
+-- modifier such as '-2592000 seconds') whose messages are in the database,
+-- oldest first. Thawed conversations qualify again once they were thawed
+-- longer than age ago.
+SELECT c.conversation_id FROM conversations c
+LEFT JOIN cold_conversations cc ON cc.conversation_id = c.conversation_id
+WHERE c.archived = TRUE
+  AND c.agent_working = FALSE
+  AND c.updated_at < datetime('now', sqlc.arg(age))
+  AND (cc.conversation_id IS NULL OR cc.thawed_at < datetime('now', sqlc.arg(age)))
+  AND EXISTS (SELECT 1 FROM messages m WHERE m.conversation_id = c.conversation_id)
+ORDER BY c.updated_at
+LIMIT sqlc.arg(max_conversations);
+
+-- name: UpsertColdConversation :exec
+INSERT INTO cold_conversations (conversation_id, storage_key, message_count, size_bytes)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (conversation_id) DO UPDATE SET
+    storage_key = excluded.storage_key,
+    message_count = excluded.message_count,
+    size_bytes = excluded.size_bytes,
+    frozen_at = CURRENT_TIMESTAMP,
+    thawed_at = NULL;
+
+-- name: KeepColdMessageUsage :exec
+-- Copies the usage of a conversation's agent messages, which freezing is
+-- about to delete.
+INSERT INTO cold_message_usage (conversation_id, model_name, llm_api_url, usage_data, created_at)
+SELECT m.conversation_id, m.model_name, m.llm_api_url, m.usage_data, m.created_at FROM messages m
+WHERE m.conversation_id = ? AND m.type = 'agent' AND m.usage_data IS NOT NULL;
+
+-- name: DeleteColdMessageUsage :exec
+DELETE FROM cold_message_usage WHERE conversation_id = ?;
+
+-- name: GetFrozenConversation :one
+SELECT * FROM cold_conversations
+WHERE conversation_id = ? AND thawed_at IS NULL;
+
+-- name: MarkConversationThawed :exec
+UPDATE cold_conversations SET thawed_at = CURRENT_TIMESTAMP
+WHERE conversation_id = ?;
+
+-- name: RestoreMessage :exec
+-- Inserts a message exactly as it was, for bringing a conversation back
+-- from cold storage. created_at is text in SQLite's datetime format.
+INSERT INTO messages (message_id, conversation_id, sequence_id, type, llm_data, user_data, usage_data, created_at, display_data, excluded_from_context, generation, llm_api_url, model_name, forked_from_message_id, user_email)
+VALUES (?, ?, ?, ?, ?, ?, ?, CAST(sqlc.arg(created_at) AS TEXT), ?, ?, ?, ?, ?, ?, ?);
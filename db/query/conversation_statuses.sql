@@ -0,0 +1,16 @@
+-- name: GetConversationStatus :one
+SELECT status FROM conversation_statuses
+WHERE conversation_id = ?
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: InsertConversationStatus :exec
+INSERT INTO conversation_statuses (conversation_id, status)
+VALUES (?, ?);
+
+-- name: ListConversationStatuses :many
+-- The newest status transitions of a conversation, newest first.
+SELECT status, created_at FROM conversation_statuses
+WHERE conversation_id = ?
+ORDER BY id DESC
+LIMIT ?;
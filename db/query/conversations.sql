@@ -222,6 +222,23 @@ WHERE slug LIKE '%' || ? || '%' AND archived = TRUE
 ORDER BY updated_at DESC
 LIMIT ? OFFSET ?;
 
+-- name: ListRecentPrompts :many
+-- The first prompts of the top-level conversations in a working directory
+-- updated at or after a Unix time, newest first.
+SELECT c.conversation_id, c.slug,
+  CAST(COALESCE((
+    SELECT je.value ->> 'Text'
+      FROM messages m, json_each(m.llm_data, '$.Content') je
+     WHERE m.conversation_id = c.conversation_id AND m.type = 'user'
+       AND je.value ->> 'Type' = 2
+     ORDER BY m.sequence_id, je.key LIMIT 1), '') AS TEXT) AS prompt
+FROM conversations c
+WHERE c.archived = FALSE AND c.parent_conversation_id IS NULL
+  AND c.cwd = sqlc.arg(cwd)
+  AND CAST(strftime('%s', c.updated_at) AS INTEGER) >= CAST(sqlc.arg(since_unix) AS INTEGER)
+ORDER BY c.updated_at DESC
+LIMIT sqlc.arg(limit);
+
 -- name: UpdateConversationSlug :one
 UPDATE conversations
 SET slug = ?, updated_at = CURRENT_TIMESTAMP
@@ -306,11 +323,66 @@ SELECT
   CAST(COALESCE(SUM(m.usage_data ->> 'cache_read_input_tokens'), 0) AS INTEGER) AS cache_read_input_tokens,
   CAST(COALESCE(SUM(m.usage_data ->> 'output_tokens'), 0) AS INTEGER) AS output_tokens,
   CAST(COALESCE(SUM(m.usage_data ->> 'cost_usd'), 0) AS REAL) AS cost_usd
-FROM messages m
+FROM agent_usage m
 JOIN descendants d ON m.conversation_id = d.conversation_id
-WHERE m.type = 'agent' AND m.usage_data IS NOT NULL
 GROUP BY m.model_name, m.llm_api_url;
 
+-- name: GetConversationTreeUsage :many
+-- Aggregate LLM usage of a conversation and all its descendants, grouped
+-- by model.
+WITH RECURSIVE tree(conversation_id) AS (
+  SELECT ? AS conversation_id
+  UNION ALL
+  SELECT c.conversation_id FROM conversations c
+  JOIN tree t ON c.parent_conversation_id = t.conversation_id
+)
+SELECT
+  m.model_name,
+  m.llm_api_url,
+  COUNT(*) AS llm_calls,
+  CAST(COALESCE(SUM(m.usage_data ->> 'input_tokens'), 0) AS INTEGER) AS input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cache_creation_input_tokens'), 0) AS INTEGER) AS cache_creation_input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cache_read_input_tokens'), 0) AS INTEGER) AS cache_read_input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'output_tokens'), 0) AS INTEGER) AS output_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cost_usd'), 0) AS REAL) AS cost_usd
+FROM agent_usage m
+JOIN tree t ON m.conversation_id = t.conversation_id
+GROUP BY m.model_name, m.llm_api_url;
+
+-- name: GetUsageSince :many
+-- Aggregate LLM usage of agent messages created at or after a Unix time,
+-- grouped by conversation and model, frozen conversations included.
+-- Subagents count as their own conversations.
+SELECT
+  m.conversation_id,
+  c.slug,
+  m.model_name,
+  m.llm_api_url,
+  COUNT(*) AS llm_calls,
+  CAST(COALESCE(SUM(m.usage_data ->> 'input_tokens'), 0) AS INTEGER) AS input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cache_creation_input_tokens'), 0) AS INTEGER) AS cache_creation_input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cache_read_input_tokens'), 0) AS INTEGER) AS cache_read_input_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'output_tokens'), 0) AS INTEGER) AS output_tokens,
+  CAST(COALESCE(SUM(m.usage_data ->> 'cost_usd'), 0) AS REAL) AS cost_usd
+FROM agent_usage m
+JOIN conversations c ON c.conversation_id = m.conversation_id
+WHERE CAST(strftime('%s', m.created_at) AS INTEGER) >= CAST(sqlc.arg(since_unix) AS INTEGER)
+GROUP BY m.conversation_id, m.model_name, m.llm_api_url;
+
+-- name: GetConversationAncestry :one
+-- The top-level ancestor of a conversation and how many subagent levels
+-- below it the conversation is (0 for a top-level conversation).
+WITH RECURSIVE ancestors(conversation_id, parent_conversation_id, depth) AS (
+  SELECT c.conversation_id, c.parent_conversation_id, 0 FROM conversations c WHERE c.conversation_id = ?
+  UNION ALL
+  SELECT c.conversation_id, c.parent_conversation_id, a.depth + 1 FROM conversations c
+  JOIN ancestors a ON c.conversation_id = a.parent_conversation_id
+)
+SELECT CAST(conversation_id AS TEXT) AS root_conversation_id, CAST(depth AS INTEGER) AS depth
+FROM ancestors
+ORDER BY depth DESC
+LIMIT 1;
+
 -- name: GetConversationBySlugAndParent :one
 SELECT * FROM conversations
 WHERE slug = ? AND parent_conversation_id = ?;
@@ -0,0 +1,13 @@
+-- name: InsertEvalResult :exec
+INSERT INTO eval_results (run_id, task, model, conversation_id, status, cost_usd, duration_ms, detail)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListEvalResults :many
+SELECT * FROM eval_results
+WHERE run_id = ?
+ORDER BY result_id;
+
+-- name: LatestEvalRunID :one
+SELECT run_id FROM eval_results
+ORDER BY result_id DESC
+LIMIT 1;
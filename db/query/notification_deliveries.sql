@@ -0,0 +1,13 @@
+-- name: InsertNotificationDelivery :exec
+INSERT INTO notification_deliveries (channel_id, channel_type, event_type, conversation_id, status, error)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: PruneNotificationDeliveries :exec
+-- Keeps only the most recent deliveries.
+DELETE FROM notification_deliveries
+WHERE delivery_id <= (SELECT MAX(delivery_id) FROM notification_deliveries) - CAST(sqlc.arg(keep) AS INTEGER);
+
+-- name: ListNotificationDeliveries :many
+SELECT * FROM notification_deliveries
+ORDER BY delivery_id DESC
+LIMIT ?;
@@ -0,0 +1,15 @@
+-- name: ListScratchpadNotes :many
+SELECT name, content FROM scratchpad_notes
+WHERE conversation_id = ?
+ORDER BY name;
+
+-- name: SetScratchpadNote :exec
+INSERT INTO scratchpad_notes (conversation_id, name, content, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(conversation_id, name) DO UPDATE SET
+    content = excluded.content,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteScratchpadNote :execrows
+DELETE FROM scratchpad_notes
+WHERE conversation_id = ? AND name = ?;
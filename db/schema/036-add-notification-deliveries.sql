@@ -0,0 +1,14 @@
+-- Outcome of each attempt to send a notification through a channel, so
+-- misconfigured channels can be diagnosed. Only recent rows are kept.
+-- channel_id is empty for channels not stored in notification_channels, and
+-- is deliberately not a foreign key so history outlives deleted channels.
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    delivery_id     INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel_id      TEXT NOT NULL,
+    channel_type    TEXT NOT NULL,
+    event_type      TEXT NOT NULL,
+    conversation_id TEXT NOT NULL,
+    status          TEXT NOT NULL, -- "sent" or "failed"
+    error           TEXT NOT NULL DEFAULT '',
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
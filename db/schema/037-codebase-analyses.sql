@@ -0,0 +1,10 @@
+-- Codebase analyses from package onstart, cached so that starting a
+-- conversation in a large repository doesn't re-walk it. There is one row
+-- per repository root; fingerprint identifies the HEAD commit and
+-- uncommitted changes the analysis was made from.
+CREATE TABLE IF NOT EXISTS codebase_analyses (
+    root        TEXT PRIMARY KEY,
+    fingerprint TEXT NOT NULL,
+    analysis    TEXT NOT NULL, -- JSON
+    updated_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,17 @@
+-- Results of "shelley eval": one row per task run against a model. The
+-- transcript is the conversation; conversation_id is empty when the run
+-- failed before one started.
+CREATE TABLE IF NOT EXISTS eval_results (
+    result_id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id          TEXT NOT NULL,
+    task            TEXT NOT NULL,
+    model           TEXT NOT NULL,
+    conversation_id TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL, -- "passed", "failed", "timeout", or "error"
+    cost_usd        REAL NOT NULL DEFAULT 0,
+    duration_ms     INTEGER NOT NULL DEFAULT 0,
+    detail          TEXT NOT NULL DEFAULT '', -- check output or error
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_eval_results_run ON eval_results(run_id);
@@ -0,0 +1,13 @@
+-- Conversations whose messages were moved out of the database into cold
+-- storage. While thawed_at is NULL the conversation is frozen: its messages
+-- live only in the compressed blob at storage_key. Thawing restores them and
+-- sets thawed_at, which keeps the conversation in the database for a while
+-- before it can move again.
+CREATE TABLE IF NOT EXISTS cold_conversations (
+    conversation_id TEXT PRIMARY KEY REFERENCES conversations(conversation_id) ON DELETE CASCADE,
+    storage_key     TEXT NOT NULL,
+    message_count   INTEGER NOT NULL,
+    size_bytes      INTEGER NOT NULL,
+    frozen_at       DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    thawed_at       DATETIME
+);
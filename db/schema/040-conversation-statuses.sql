@@ -0,0 +1,12 @@
+-- Each status a conversation moves through (idle, thinking,
+-- running-tool:<name>, awaiting-user, ...), oldest first. The newest row
+-- is the conversation's current status.
+CREATE TABLE IF NOT EXISTS conversation_statuses (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    conversation_id TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(conversation_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_statuses_conversation ON conversation_statuses(conversation_id, id);
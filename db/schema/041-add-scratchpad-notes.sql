@@ -0,0 +1,11 @@
+-- Named notes the agent keeps with the scratchpad tool. They live outside
+-- the message history so they survive distillation, and are injected into
+-- every LLM request for the conversation.
+CREATE TABLE IF NOT EXISTS scratchpad_notes (
+    conversation_id TEXT NOT NULL,
+    name            TEXT NOT NULL,
+    content         TEXT NOT NULL,
+    updated_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (conversation_id, name),
+    FOREIGN KEY (conversation_id) REFERENCES conversations(conversation_id) ON DELETE CASCADE
+);
@@ -0,0 +1,22 @@
+-- The usage of the agent messages of frozen conversations, copied before
+-- freezing deletes the messages so that usage reports still count them.
+-- Thawing deletes a conversation's rows as it restores the messages.
+CREATE TABLE IF NOT EXISTS cold_message_usage (
+    conversation_id TEXT NOT NULL REFERENCES conversations(conversation_id) ON DELETE CASCADE,
+    model_name      TEXT,
+    llm_api_url     TEXT,
+    usage_data      TEXT NOT NULL,
+    created_at      DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cold_message_usage_conversation ON cold_message_usage(conversation_id);
+
+-- The usage of every agent message, whether its conversation is frozen or
+-- not.
+CREATE VIEW IF NOT EXISTS agent_usage AS
+SELECT conversation_id, model_name, llm_api_url, usage_data, created_at
+FROM messages
+WHERE type = 'agent' AND usage_data IS NOT NULL
+UNION ALL
+SELECT conversation_id, model_name, llm_api_url, usage_data, created_at
+FROM cold_message_usage;
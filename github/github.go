@@ -0,0 +1,284 @@
+// Package github connects Shelley to GitHub as a GitHub App: it checks and
+// parses the app's webhook deliveries, and calls the REST API as one of the
+// app's installations.
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config configures the GitHub App in shelley.json.
+type Config struct {
+	// AppID is the app's numeric ID, from its settings page.
+	AppID int64 `json:"app_id"`
+	// PrivateKeyFile is the PEM private key generated for the app.
+	PrivateKeyFile string `json:"private_key_file"`
+	// WebhookSecret is the secret deliveries are signed with.
+	WebhookSecret string `json:"webhook_secret"`
+	// Bot is the name comments mention the app by, without the @: its slug.
+	Bot string `json:"bot"`
+	// Dir is where repositories are checked out for conversations.
+	Dir string `json:"dir"`
+	// Model is the model conversations use. The default is the server's.
+	Model string `json:"model,omitempty"`
+	// APIURL is the REST API's URL. The default is https://api.github.com;
+	// set it for GitHub Enterprise Server.
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// Enabled reports whether c configures an app.
+func (c Config) Enabled() bool {
+	return c.AppID != 0
+}
+
+// Validate checks an enabled c.
+func (c Config) Validate() error {
+	switch {
+	case c.PrivateKeyFile == "" || c.WebhookSecret == "":
+		return fmt.Errorf("private_key_file and webhook_secret are required")
+	case c.Bot == "" || strings.HasPrefix(c.Bot, "@"):
+		return fmt.Errorf("bot must be the app's slug, without @")
+	case !filepath.IsAbs(c.Dir):
+		return fmt.Errorf("dir %q is not absolute", c.Dir)
+	}
+	return nil
+}
+
+func (c Config) apiURL() string {
+	if c.APIURL == "" {
+		return "https://api.github.com"
+	}
+	return strings.TrimSuffix(c.APIURL, "/")
+}
+
+// App is a GitHub App, authenticated with its private key.
+type App struct {
+	Config Config
+	Client *http.Client
+
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	tokens map[int64]installationToken
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// New returns the app c configures, reading its private key.
+func New(c Config) (*App, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(c.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.PrivateKeyFile, err)
+	}
+	return &App{Config: c, Client: http.DefaultClient, key: key, tokens: map[int64]installationToken{}}, nil
+}
+
+// parsePrivateKey parses an RSA key in PKCS #1, as GitHub generates them,
+// or PKCS #8.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// VerifySignature checks a delivery's X-Hub-Signature-256 header against
+// its body.
+func (a *App) VerifySignature(body []byte, header string) error {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return fmt.Errorf("missing sha256 signature")
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("malformed signature")
+	}
+	mac := hmac.New(sha256.New, []byte(a.Config.WebhookSecret))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// jwt returns the app's own token, which it exchanges for installation
+// tokens. GitHub allows at most ten minutes; iat is backdated for clock
+// drift.
+func (a *App) jwt(now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": fmt.Sprint(a.Config.AppID),
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// Token returns an access token for installation, reusing one until it's
+// within five minutes of expiring. It also authenticates git over HTTPS, as
+// user x-access-token.
+func (a *App) Token(ctx context.Context, installation int64) (string, error) {
+	a.mu.Lock()
+	t, ok := a.tokens[installation]
+	a.mu.Unlock()
+	if ok && time.Until(t.ExpiresAt) > 5*time.Minute {
+		return t.Token, nil
+	}
+	jwt, err := a.jwt(time.Now())
+	if err != nil {
+		return "", err
+	}
+	if err := a.call(ctx, jwt, http.MethodPost, fmt.Sprintf("/app/installations/%d/access_tokens", installation), nil, &t); err != nil {
+		return "", fmt.Errorf("failed to get an installation token: %w", err)
+	}
+	a.mu.Lock()
+	a.tokens[installation] = t
+	a.mu.Unlock()
+	return t.Token, nil
+}
+
+// do calls the REST API as installation, decoding the response into out
+// if it isn't nil.
+func (a *App) do(ctx context.Context, installation int64, method, path string, in, out any) error {
+	token, err := a.Token(ctx, installation)
+	if err != nil {
+		return err
+	}
+	return a.call(ctx, token, method, path, in, out)
+}
+
+func (a *App) call(ctx context.Context, token, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.Config.apiURL()+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, apiErr.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// CreateComment comments on issue or pull request number of repo, as
+// "owner/name", and returns the comment's ID.
+func (a *App) CreateComment(ctx context.Context, installation int64, repo string, number int, body string) (int64, error) {
+	var comment struct {
+		ID int64 `json:"id"`
+	}
+	err := a.do(ctx, installation, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", repo, number), map[string]string{"body": body}, &comment)
+	return comment.ID, err
+}
+
+// UpdateComment replaces the body of a comment CreateComment made.
+func (a *App) UpdateComment(ctx context.Context, installation int64, repo string, id int64, body string) error {
+	return a.do(ctx, installation, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/comments/%d", repo, id), map[string]string{"body": body}, nil)
+}
+
+// PullRequest is the head of a pull request.
+type PullRequest struct {
+	// HeadRef is the head branch.
+	HeadRef string
+	// HeadRepo is the repository the head branch is in, as "owner/name";
+	// a fork's differs from the base repository.
+	HeadRepo string
+}
+
+// GetPullRequest returns pull request number of repo.
+func (a *App) GetPullRequest(ctx context.Context, installation int64, repo string, number int) (*PullRequest, error) {
+	var pr struct {
+		Head struct {
+			Ref  string `json:"ref"`
+			Repo *struct {
+				FullName string `json:"full_name"`
+			} `json:"repo"`
+		} `json:"head"`
+	}
+	if err := a.do(ctx, installation, http.MethodGet, fmt.Sprintf("/repos/%s/pulls/%d", repo, number), nil, &pr); err != nil {
+		return nil, err
+	}
+	if pr.Head.Repo == nil {
+		return nil, errors.New("the pull request's head repository was deleted")
+	}
+	return &PullRequest{HeadRef: pr.Head.Ref, HeadRepo: pr.Head.Repo.FullName}, nil
+}
@@ -0,0 +1,174 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testApp returns an app with a fresh key whose API is apiURL.
+func testApp(t *testing.T, apiURL string) *App {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(keyFile, pemData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	app, err := New(Config{AppID: 42, PrivateKeyFile: keyFile, WebhookSecret: "s3cret", Bot: "shelley", Dir: t.TempDir(), APIURL: apiURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestConfigValidate(t *testing.T) {
+	ok := Config{AppID: 1, PrivateKeyFile: "k.pem", WebhookSecret: "s", Bot: "shelley", Dir: "/srv/github"}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+	for _, c := range []Config{
+		{AppID: 1, WebhookSecret: "s", Bot: "shelley", Dir: "/srv/github"},
+		{AppID: 1, PrivateKeyFile: "k.pem", WebhookSecret: "s", Bot: "@shelley", Dir: "/srv/github"},
+		{AppID: 1, PrivateKeyFile: "k.pem", WebhookSecret: "s", Bot: "shelley", Dir: "github"},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", c)
+		}
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	app := testApp(t, "")
+	body := []byte(`{"action":"created"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	sig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if err := app.VerifySignature(body, sig); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+	for _, bad := range []string{"", "sha1=abc", "sha256=zz", "sha256=" + strings.Repeat("0", 64)} {
+		if err := app.VerifySignature(body, bad); err == nil {
+			t.Errorf("signature %q accepted", bad)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	for text, want := range map[string]bool{
+		"@shelley fix this":         true,
+		"Hey @Shelley, take a look": true,
+		"(@shelley)":                true,
+		"@shelley-bot please":       false,
+		"mail me@shelley.dev":       false,
+		"shelley, fix this":         false,
+		"see org/@shelley":          false,
+	} {
+		if got := Mentions(text, "shelley"); got != want {
+			t.Errorf("Mentions(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestParseMention(t *testing.T) {
+	delivery := func(assoc, userType, body string) []byte {
+		return []byte(`{"action":"created",
+			"comment":{"body":` + jsonString(body) + `,"html_url":"https://github.com/o/r/issues/7#c","author_association":"` + assoc + `","user":{"login":"alice","type":"` + userType + `"}},
+			"issue":{"number":7,"title":"Crash","body":"It crashes.","pull_request":{"url":"x"}},
+			"installation":{"id":3},
+			"repository":{"full_name":"o/r","clone_url":"https://github.com/o/r.git"}}`)
+	}
+	m, err := ParseMention("issue_comment", delivery("MEMBER", "User", "@shelley fix it"), "shelley")
+	if err != nil || m == nil {
+		t.Fatalf("ParseMention = %v, %v", m, err)
+	}
+	want := Mention{Installation: 3, Repo: "o/r", CloneURL: "https://github.com/o/r.git", Number: 7, PullRequest: true, Title: "Crash", Body: "It crashes.", Comment: "@shelley fix it", Author: "alice", URL: "https://github.com/o/r/issues/7#c"}
+	if *m != want {
+		t.Errorf("ParseMention = %+v", *m)
+	}
+	for name, body := range map[string][]byte{
+		"no mention":  delivery("MEMBER", "User", "fix it"),
+		"untrusted":   delivery("NONE", "User", "@shelley fix it"),
+		"bot comment": delivery("MEMBER", "Bot", "@shelley fix it"),
+	} {
+		if m, err := ParseMention("issue_comment", body, "shelley"); m != nil || err != nil {
+			t.Errorf("%s: ParseMention = %+v, %v", name, m, err)
+		}
+	}
+	if m, err := ParseMention("push", []byte(`{}`), "shelley"); m != nil || err != nil {
+		t.Errorf("push: ParseMention = %+v, %v", m, err)
+	}
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func TestAPI(t *testing.T) {
+	var app *App
+	tokens := 0
+	var commented string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /app/installations/3/access_tokens":
+			parts := strings.Split(auth, ".")
+			if len(parts) != 3 {
+				http.Error(w, "bad jwt", http.StatusUnauthorized)
+				return
+			}
+			sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			if err := rsa.VerifyPKCS1v15(&app.key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+				http.Error(w, "bad jwt signature", http.StatusUnauthorized)
+				return
+			}
+			tokens++
+			json.NewEncoder(w).Encode(map[string]any{"token": "inst-token", "expires_at": time.Now().Add(time.Hour)})
+		case "POST /repos/o/r/issues/7/comments":
+			if auth != "inst-token" {
+				http.Error(w, "bad token", http.StatusUnauthorized)
+				return
+			}
+			var in struct{ Body string }
+			json.NewDecoder(r.Body).Decode(&in)
+			commented = in.Body
+			w.Write([]byte(`{"id": 99}`))
+		default:
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	app = testApp(t, ts.URL)
+	ctx := context.Background()
+
+	id, err := app.CreateComment(ctx, 3, "o/r", 7, "hello")
+	if err != nil || id != 99 || commented != "hello" {
+		t.Fatalf("CreateComment = %d, %v; body %q", id, err, commented)
+	}
+	if _, err := app.CreateComment(ctx, 3, "o/r", 8, "hello"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("CreateComment on a missing issue: %v", err)
+	}
+	if tokens != 1 {
+		t.Errorf("got %d installation tokens, want 1 reused", tokens)
+	}
+}
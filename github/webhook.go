@@ -0,0 +1,109 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Mention is a new comment that mentions the app, by someone who can
+// push to the repository.
+type Mention struct {
+	Installation int64
+	// Repo is the repository, as "owner/name".
+	Repo     string
+	CloneURL string
+	// Number is the issue's or pull request's number.
+	Number      int
+	PullRequest bool
+	// Title and Body are the issue's or pull request's.
+	Title string
+	Body  string
+	// Comment is the comment's body, and Author its author's login.
+	Comment string
+	Author  string
+	URL     string
+}
+
+// trustedAssociations are the author associations of commenters allowed to
+// drive the app: people who could push the app's changes themselves.
+var trustedAssociations = []string{"OWNER", "MEMBER", "COLLABORATOR"}
+
+type comment struct {
+	Body              string `json:"body"`
+	HTMLURL           string `json:"html_url"`
+	AuthorAssociation string `json:"author_association"`
+	User              struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	} `json:"user"`
+}
+
+type issue struct {
+	Number      int             `json:"number"`
+	Title       string          `json:"title"`
+	Body        string          `json:"body"`
+	PullRequest json.RawMessage `json:"pull_request"`
+}
+
+// payload holds the fields of issue_comment and pull_request_review_comment
+// deliveries that ParseMention uses.
+type payload struct {
+	Action       string  `json:"action"`
+	Comment      comment `json:"comment"`
+	Issue        *issue  `json:"issue"`
+	PullRequest  *issue  `json:"pull_request"`
+	Installation struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+}
+
+// ParseMention parses a delivery of event, the X-GitHub-Event header. It
+// returns nil, and no error, for deliveries that aren't a new comment
+// mentioning bot by someone trusted, including the app's own comments.
+func ParseMention(event string, body []byte, bot string) (*Mention, error) {
+	if event != "issue_comment" && event != "pull_request_review_comment" {
+		return nil, nil
+	}
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", event, err)
+	}
+	c := p.Comment
+	if p.Action != "created" || c.User.Type == "Bot" || !Mentions(c.Body, bot) || !slices.Contains(trustedAssociations, c.AuthorAssociation) {
+		return nil, nil
+	}
+	m := &Mention{
+		Installation: p.Installation.ID,
+		Repo:         p.Repository.FullName,
+		CloneURL:     p.Repository.CloneURL,
+		Comment:      c.Body,
+		Author:       c.User.Login,
+		URL:          c.HTMLURL,
+	}
+	it := p.Issue
+	if event == "pull_request_review_comment" {
+		it = p.PullRequest
+		m.PullRequest = true
+	}
+	if it == nil {
+		return nil, fmt.Errorf("%s payload without an issue or pull request", event)
+	}
+	m.Number, m.Title, m.Body = it.Number, it.Title, it.Body
+	// Comments on pull requests arrive as issue comments too.
+	if len(it.PullRequest) > 0 && string(it.PullRequest) != "null" {
+		m.PullRequest = true
+	}
+	return m, nil
+}
+
+// Mentions reports whether text @-mentions name.
+func Mentions(text, name string) bool {
+	re := regexp.MustCompile(`(?i)(?:^|[^\w@/-])@` + regexp.QuoteMeta(name) + `(?:$|[^\w-])`)
+	return re.MatchString(text)
+}
@@ -0,0 +1,223 @@
+// Package inbox turns incoming email into conversation requests, and
+// sends the replies. Mail arrives as raw RFC 5322 messages posted by an
+// inbound mail service, and replies leave over SMTP.
+package inbox
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Config configures the email gateway in shelley.json.
+type Config struct {
+	// Token authenticates the inbound mail service's POSTs, as a bearer
+	// token or the password of basic auth.
+	Token string `json:"token"`
+	// AllowedSenders are the addresses that may email the agent. An entry
+	// starting with @ allows a whole domain. The inbound service must
+	// reject mail that fails SPF or DKIM, since From is easy to forge.
+	AllowedSenders []string `json:"allowed_senders"`
+	// Cwd is the working directory of emailed conversations. The default
+	// is the server's.
+	Cwd string `json:"cwd,omitempty"`
+	// Model is the model emailed conversations use. The default is the
+	// server's.
+	Model string `json:"model,omitempty"`
+	// SMTP sends the replies.
+	SMTP SMTPConfig `json:"smtp"`
+}
+
+// Enabled reports whether c configures the gateway.
+func (c Config) Enabled() bool {
+	return c.Token != ""
+}
+
+// Validate checks an enabled c.
+func (c Config) Validate() error {
+	switch {
+	case len(c.AllowedSenders) == 0:
+		return fmt.Errorf("allowed_senders is required")
+	case c.Cwd != "" && !filepath.IsAbs(c.Cwd):
+		return fmt.Errorf("cwd %q is not absolute", c.Cwd)
+	}
+	return c.SMTP.validate()
+}
+
+// Allowed reports whether mail from addr may start or continue a
+// conversation.
+func (c Config) Allowed(addr string) bool {
+	addr = strings.ToLower(addr)
+	for _, a := range c.AllowedSenders {
+		a = strings.ToLower(a)
+		if addr == a || (strings.HasPrefix(a, "@") && strings.HasSuffix(addr, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Message is an incoming email.
+type Message struct {
+	// From is the sender's address, without their name.
+	From    string
+	Subject string
+	// Text is the plain-text body, without the quoted message it replies to.
+	Text string
+	// MessageID and References identify the message and the thread it
+	// replies to, for threading the reply.
+	MessageID  string
+	References []string
+	// Attachments are the attached files.
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to an email.
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+// Parse reads a raw RFC 5322 message.
+func Parse(r io.Reader) (*Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid From: %w", err)
+	}
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	m := &Message{
+		From:       from.Address,
+		Subject:    strings.TrimSpace(subject),
+		MessageID:  strings.TrimSpace(msg.Header.Get("Message-ID")),
+		References: strings.Fields(msg.Header.Get("References") + " " + msg.Header.Get("In-Reply-To")),
+	}
+	var text, html string
+	if err := walkPart(m, msg.Header, msg.Body, &text, &html); err != nil {
+		return nil, err
+	}
+	if text == "" {
+		text = htmlToText(html)
+	}
+	m.Text = stripQuoted(text)
+	return m, nil
+}
+
+// header is the part of a MIME header walkPart reads.
+type header interface {
+	Get(key string) string
+}
+
+// walkPart collects the first text/plain and text/html bodies and the
+// attachments of a part, descending into multiparts.
+func walkPart(m *Message, h header, body io.Reader, text, html *string) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := walkPart(m, p.Header, p, text, html); err != nil {
+				return err
+			}
+		}
+	}
+	data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	name := cmp.Or(dparams["filename"], params["name"])
+	switch {
+	case disposition == "attachment" || name != "":
+		if name == "" {
+			name = "attachment"
+		}
+		m.Attachments = append(m.Attachments, Attachment{Name: name, Data: data})
+	case mediaType == "text/plain" && *text == "":
+		*text = string(data)
+	case mediaType == "text/html" && *html == "":
+		*html = string(data)
+	}
+	return nil
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// newlineStripper drops the line breaks of base64 bodies.
+type newlineStripper struct{ r io.Reader }
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		k, err := n.r.Read(p)
+		k = copy(p, bytes.ReplaceAll(bytes.ReplaceAll(p[:k], []byte("\r"), nil), []byte("\n"), nil))
+		if k > 0 || err != nil {
+			return k, err
+		}
+	}
+}
+
+var (
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+	// replyHeader is the line mail clients put above the quoted message,
+	// such as "On Mon, Jan 2, 2026 at 3:04 PM Ann <ann@example.com> wrote:".
+	replyHeader = regexp.MustCompile(`(?m)^On .*wrote:\s*$`)
+)
+
+// htmlToText reduces an HTML body to its text, for mail without a plain
+// one.
+func htmlToText(s string) string {
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlTag.ReplaceAllString(s, "")
+	r := strings.NewReplacer("&nbsp;", " ", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#39;", "'", "&amp;", "&")
+	return r.Replace(s)
+}
+
+// stripQuoted removes the quoted message a reply carries below its own
+// text.
+func stripQuoted(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if loc := replyHeader.FindStringIndex(s); loc != nil {
+		s = s[:loc[0]]
+	}
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if !strings.HasPrefix(line, ">") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,123 @@
+package inbox
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	ok := Config{Token: "t", AllowedSenders: []string{"ann@example.com"}, SMTP: SMTPConfig{Addr: "smtp.example.com:587", From: "Shelley <shelley@example.com>"}}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+	for _, c := range []Config{
+		{Token: "t", SMTP: ok.SMTP},
+		{Token: "t", AllowedSenders: ok.AllowedSenders, Cwd: "work", SMTP: ok.SMTP},
+		{Token: "t", AllowedSenders: ok.AllowedSenders, SMTP: SMTPConfig{Addr: "smtp.example.com", From: "shelley@example.com"}},
+		{Token: "t", AllowedSenders: ok.AllowedSenders, SMTP: SMTPConfig{Addr: "smtp.example.com:587"}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", c)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	c := Config{AllowedSenders: []string{"Ann@example.com", "@corp.example"}}
+	for addr, want := range map[string]bool{
+		"ann@example.com":      true,
+		"bob@example.com":      false,
+		"bob@corp.example":     true,
+		"bob@evilcorp.example": false,
+	} {
+		if got := c.Allowed(addr); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	raw := strings.ReplaceAll(`From: Ann <ann@example.com>
+To: shelley@example.com
+Subject: =?utf-8?q?Fix_the_caf=C3=A9_build?=
+Message-ID: <2@example.com>
+In-Reply-To: <1@example.com>
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+It fails on the caf=C3=A9 page.
+
+On Mon, Jan 5, 2026 at 3:04 PM Shelley <shelley@example.com> wrote:
+> Earlier answer
+--inner
+Content-Type: text/html
+
+<p>ignored</p>
+--inner--
+--outer
+Content-Type: text/plain; name="log.txt"
+Content-Disposition: attachment; filename="log.txt"
+Content-Transfer-Encoding: base64
+
+ZXJyb3I6IGJv
+b20K
+--outer--
+`, "\n", "\r\n")
+	m, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.From != "ann@example.com" || m.Subject != "Fix the café build" || m.MessageID != "<2@example.com>" {
+		t.Errorf("headers: %+v", m)
+	}
+	if len(m.References) != 1 || m.References[0] != "<1@example.com>" {
+		t.Errorf("References = %q", m.References)
+	}
+	if m.Text != "It fails on the café page." {
+		t.Errorf("Text = %q", m.Text)
+	}
+	if len(m.Attachments) != 1 || m.Attachments[0].Name != "log.txt" || string(m.Attachments[0].Data) != "error: boom\n" {
+		t.Errorf("Attachments = %+v", m.Attachments)
+	}
+}
+
+func TestParseHTML(t *testing.T) {
+	raw := "From: ann@example.com\r\nSubject: hi\r\nContent-Type: text/html\r\n\r\n<div>Run the tests &amp; report</div><blockquote>x</blockquote>"
+	m, err := Parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Text != "Run the tests & report\nx" {
+		t.Errorf("Text = %q", m.Text)
+	}
+}
+
+func TestReply(t *testing.T) {
+	m := &Message{From: "ann@example.com", Subject: "Fix the build", MessageID: "<2@example.com>", References: []string{"<1@example.com>"}}
+	r := ReplyTo(m)
+	r.MessageID = "<3@example.com>"
+	r.Body = "Fixed.\nSee the diff."
+	if r.Subject != "Re: Fix the build" || ReplyTo(&Message{Subject: "RE: x"}).Subject != "RE: x" {
+		t.Errorf("Subject = %q", r.Subject)
+	}
+	got := string(r.Bytes("shelley@example.com", time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)))
+	for _, want := range []string{
+		"To: ann@example.com\r\n",
+		"In-Reply-To: <2@example.com>\r\n",
+		"References: <1@example.com> <2@example.com>\r\n",
+		"Message-ID: <3@example.com>\r\n",
+		"\r\n\r\nFixed.\r\nSee the diff.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("reply lacks %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package inbox
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig configures the server replies are sent through.
+type SMTPConfig struct {
+	// Addr is the server's host:port, such as "smtp.example.com:587". The
+	// connection is upgraded with STARTTLS when the server offers it.
+	Addr     string `json:"addr"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// From is the address replies come from, which the inbound mail
+	// service should also receive mail for.
+	From string `json:"from"`
+}
+
+func (c SMTPConfig) validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("smtp.addr: %w", err)
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		return fmt.Errorf("smtp.from: %w", err)
+	}
+	return nil
+}
+
+// Reply is an email answering a Message.
+type Reply struct {
+	To      string
+	Subject string
+	Body    string
+	// MessageID is the reply's own Message-ID, and InReplyTo and
+	// References thread it under the message it answers.
+	MessageID  string
+	InReplyTo  string
+	References []string
+}
+
+// ReplyTo returns the start of a reply to m: addressed to its sender,
+// with its subject, and threaded under it.
+func ReplyTo(m *Message) Reply {
+	subject := m.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	r := Reply{To: m.From, Subject: subject, References: m.References}
+	if m.MessageID != "" {
+		r.InReplyTo = m.MessageID
+		r.References = append(r.References, m.MessageID)
+	}
+	return r
+}
+
+// Bytes formats r as a message from from.
+func (r Reply) Bytes(from string, date time.Time) []byte {
+	var b bytes.Buffer
+	header := func(k, v string) {
+		if v != "" {
+			fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+		}
+	}
+	header("From", from)
+	header("To", r.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", r.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", r.MessageID)
+	header("In-Reply-To", r.InReplyTo)
+	header("References", strings.Join(r.References, " "))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+	w := quotedprintable.NewWriter(&b)
+	w.Write([]byte(strings.ReplaceAll(r.Body, "\n", "\r\n")))
+	w.Close()
+	return b.Bytes()
+}
+
+// Send sends r through the server c configures.
+func (c SMTPConfig) Send(r Reply) error {
+	var auth smtp.Auth
+	if c.Username != "" {
+		host, _, _ := net.SplitHostPort(c.Addr)
+		auth = smtp.PlainAuth("", c.Username, c.Password, host)
+	}
+	from, err := mail.ParseAddress(c.From)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(c.Addr, auth, from.Address, []string{r.To}, r.Bytes(c.From, time.Now()))
+}
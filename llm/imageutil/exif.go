@@ -0,0 +1,278 @@
+package imageutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// orientation returns the EXIF orientation of a JPEG, PNG, or WebP image,
+// from 1 (upright) to 8, or 1 if it has none. Phone cameras store photos
+// sideways and record the rotation here rather than in the pixels.
+func orientation(data []byte) int {
+	exif := exifData(data)
+	if exif == nil {
+		return 1
+	}
+	o := tiffOrientation(exif)
+	if o < 1 || o > 8 {
+		return 1
+	}
+	return o
+}
+
+// stripMetadata returns data without its EXIF, XMP, and text metadata, such
+// as GPS coordinates and camera serial numbers, for JPEG, PNG, and WebP
+// images; other data is returned unchanged. Colour profiles are kept. The
+// pixels are not re-encoded, so the caller must apply the orientation first.
+func stripMetadata(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, jpegSignature):
+		return stripJPEG(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return stripPNG(data)
+	case isWebP(data):
+		return stripWebP(data)
+	}
+	return data
+}
+
+var (
+	jpegSignature = []byte{0xFF, 0xD8}
+	pngSignature  = []byte("\x89PNG\r\n\x1a\n")
+	exifHeader    = []byte("Exif\x00\x00")
+)
+
+func isWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+// exifData returns the TIFF-structured EXIF block of data, or nil.
+func exifData(data []byte) []byte {
+	var exif []byte
+	switch {
+	case bytes.HasPrefix(data, jpegSignature):
+		jpegSegments(data, func(marker byte, payload []byte) bool {
+			if marker == 0xE1 && bytes.HasPrefix(payload, exifHeader) {
+				exif = payload[len(exifHeader):]
+				return false
+			}
+			return true
+		})
+	case bytes.HasPrefix(data, pngSignature):
+		pngChunks(data, func(typ string, payload []byte) bool {
+			if typ == "eXIf" {
+				exif = payload
+				return false
+			}
+			return typ != "IDAT" // eXIf must come before the image data
+		})
+	case isWebP(data):
+		webpChunks(data, func(typ string, payload []byte) bool {
+			if typ == "EXIF" {
+				// Some writers keep the JPEG APP1 header.
+				exif = bytes.TrimPrefix(payload, exifHeader)
+				return false
+			}
+			return true
+		})
+	}
+	return exif
+}
+
+// tiffOrientation returns the Orientation tag of the first IFD of the TIFF
+// structure in exif, or 0 if there is none.
+func tiffOrientation(exif []byte) int {
+	if len(exif) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(exif[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(exif[4:8]))
+	if ifd < 8 || ifd+2 > len(exif) {
+		return 0
+	}
+	n := int(order.Uint16(exif[ifd:]))
+	for i := range n {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(exif) {
+			return 0
+		}
+		// A SHORT value is stored in the first two bytes of the value field.
+		const orientationTag, shortType = 0x0112, 3
+		if order.Uint16(exif[entry:]) == orientationTag && order.Uint16(exif[entry+2:]) == shortType {
+			return int(order.Uint16(exif[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// jpegSegments calls fn with the marker and payload of each segment of a JPEG
+// before the image data, until fn returns false.
+func jpegSegments(data []byte, fn func(marker byte, payload []byte) bool) {
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		if marker == 0xDA { // start of scan
+			return
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) || !fn(marker, data[i+4:end]) {
+			return
+		}
+		i = end
+	}
+}
+
+// stripJPEG removes the APP1 (EXIF and XMP), APP13 (IPTC), and comment
+// segments of a JPEG.
+func stripJPEG(data []byte) []byte {
+	out := append([]byte(nil), jpegSignature...)
+	i := len(jpegSignature)
+	for i+4 <= len(data) && data[i] == 0xFF && data[i+1] != 0xDA {
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) {
+			return data
+		}
+		switch data[i+1] {
+		case 0xE1, 0xED, 0xFE:
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return append(out, data[i:]...)
+}
+
+// pngChunks calls fn with the type and payload of each chunk of a PNG, until
+// fn returns false.
+func pngChunks(data []byte, fn func(typ string, payload []byte) bool) {
+	for i := len(pngSignature); i+12 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + n
+		if n < 0 || end > len(data) || !fn(string(data[i+4:i+8]), data[i+8:i+8+n]) {
+			return
+		}
+		i = end
+	}
+}
+
+// pngMetadataChunks are the PNG chunks that stripMetadata removes.
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+func stripPNG(data []byte) []byte {
+	out := append([]byte(nil), pngSignature...)
+	i := len(pngSignature)
+	for i+12 <= len(data) {
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) || end < i {
+			return data
+		}
+		if !pngMetadataChunks[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return append(out, data[i:]...)
+}
+
+// webpChunks calls fn with the type and payload of each chunk of a WebP,
+// until fn returns false.
+func webpChunks(data []byte, fn func(typ string, payload []byte) bool) {
+	for i := 12; i+8 <= len(data); {
+		n := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + n + n%2 // chunks are padded to an even size
+		if n < 0 || i+8+n > len(data) || !fn(string(data[i:i+4]), data[i+8:i+8+n]) {
+			return
+		}
+		i = min(end, len(data))
+	}
+}
+
+// stripWebP removes the EXIF and XMP chunks of a WebP, and clears their
+// flags in its VP8X header.
+func stripWebP(data []byte) []byte {
+	out := append([]byte(nil), data[:12]...)
+	i := 12
+	for i+8 <= len(data) {
+		n := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := min(i+8+n+n%2, len(data))
+		if n < 0 || i+8+n > len(data) {
+			return data
+		}
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if len(chunk) > 8 {
+				const exifFlag, xmpFlag = 0x08, 0x04
+				chunk[8] &^= exifFlag | xmpFlag
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	out = append(out, data[i:]...)
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out
+}
+
+// applyOrientation returns img turned upright according to EXIF orientation o.
+func applyOrientation(img image.Image, o int) image.Image {
+	if o <= 1 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		for x := range dw {
+			var sx, sy int
+			switch o {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // rotated 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotated 90° counterclockwise, so turn it clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated 90° clockwise, so turn it counterclockwise
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// decode decodes data like image.Decode, turning the image upright according
+// to its EXIF orientation.
+func decode(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return applyOrientation(img, orientation(data)), format, nil
+}
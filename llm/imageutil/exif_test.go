@@ -0,0 +1,181 @@
+package imageutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"testing"
+)
+
+// byteOrder is binary.LittleEndian or binary.BigEndian.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// exifWithOrientation returns a TIFF-structured EXIF block holding an
+// orientation tag and a GPS IFD pointer.
+func exifWithOrientation(order byteOrder, o int) []byte {
+	b := []byte("II*\x00\x08\x00\x00\x00")
+	if order == binary.BigEndian {
+		b = []byte("MM\x00*\x00\x00\x00\x08")
+	}
+	b = order.AppendUint16(b, 2)
+	b = order.AppendUint16(b, 0x8825) // GPS IFD
+	b = order.AppendUint16(b, 4)
+	b = order.AppendUint32(b, 1)
+	b = order.AppendUint32(b, 0)
+	b = order.AppendUint16(b, 0x0112) // orientation
+	b = order.AppendUint16(b, 3)
+	b = order.AppendUint32(b, 1)
+	b = order.AppendUint16(b, uint16(o))
+	b = order.AppendUint16(b, 0)
+	return order.AppendUint32(b, 0)
+}
+
+// createTestJPEG returns a JPEG whose left half is red and right half blue,
+// with an APP1 segment holding exif.
+func createTestJPEG(t *testing.T, width, height int, exif []byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			c := color.RGBA{255, 0, 0, 255}
+			if x >= width/2 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	payload := append(append([]byte(nil), exifHeader...), exif...)
+	segment := binary.BigEndian.AppendUint16([]byte{0xFF, 0xE1}, uint16(len(payload)+2))
+	return append(append(append([]byte(nil), data[:2]...), append(segment, payload...)...), data[2:]...)
+}
+
+// pngChunk returns an encoded PNG chunk.
+func pngChunk(typ string, payload []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	b = append(append(b, typ...), payload...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b[4:]))
+}
+
+func TestOrientation(t *testing.T) {
+	for _, order := range []byteOrder{binary.LittleEndian, binary.BigEndian} {
+		for o := 1; o <= 8; o++ {
+			if got := orientation(createTestJPEG(t, 4, 4, exifWithOrientation(order, o))); got != o {
+				t.Errorf("%v orientation = %d, want %d", order, got, o)
+			}
+		}
+	}
+
+	plain := createTestPNG(t, 4, 4)
+	if got := orientation(plain); got != 1 {
+		t.Errorf("orientation of image without EXIF = %d, want 1", got)
+	}
+	withEXIF := append(plain[:33:33], append(pngChunk("eXIf", exifWithOrientation(binary.BigEndian, 8)), plain[33:]...)...)
+	if got := orientation(withEXIF); got != 8 {
+		t.Errorf("PNG orientation = %d, want 8", got)
+	}
+	if got := orientation(createTestJPEG(t, 4, 4, exifWithOrientation(binary.LittleEndian, 9))); got != 1 {
+		t.Errorf("invalid orientation = %d, want 1", got)
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// A 3x2 image with distinct pixels, labelled by their index.
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := range 6 {
+		src.Set(i%3, i/3, color.RGBA{uint8(i), 0, 0, 255})
+	}
+	tests := []struct {
+		o    int
+		want [][]int // pixel labels, row by row
+	}{
+		{1, [][]int{{0, 1, 2}, {3, 4, 5}}},
+		{2, [][]int{{2, 1, 0}, {5, 4, 3}}},
+		{3, [][]int{{5, 4, 3}, {2, 1, 0}}},
+		{4, [][]int{{3, 4, 5}, {0, 1, 2}}},
+		{5, [][]int{{0, 3}, {1, 4}, {2, 5}}},
+		{6, [][]int{{3, 0}, {4, 1}, {5, 2}}},
+		{7, [][]int{{5, 2}, {4, 1}, {3, 0}}},
+		{8, [][]int{{2, 5}, {1, 4}, {0, 3}}},
+	}
+	for _, tt := range tests {
+		got := applyOrientation(src, tt.o)
+		if b := got.Bounds(); b.Dx() != len(tt.want[0]) || b.Dy() != len(tt.want) {
+			t.Errorf("orientation %d: bounds = %v", tt.o, b)
+			continue
+		}
+		for y, row := range tt.want {
+			for x, label := range row {
+				if r, _, _, _ := got.At(x, y).RGBA(); int(r>>8) != label {
+					t.Errorf("orientation %d: pixel (%d, %d) = %d, want %d", tt.o, x, y, r>>8, label)
+				}
+			}
+		}
+	}
+}
+
+func TestPrepareAppliesOrientation(t *testing.T) {
+	data := createTestJPEG(t, 40, 20, exifWithOrientation(binary.BigEndian, 6))
+	if w, h, err := DecodeDimensions(data); err != nil || w != 20 || h != 40 {
+		t.Fatalf("DecodeDimensions() = %d, %d, %v; want 20, 40", w, h, err)
+	}
+	prepared, err := Prepare(data, "photo.jpg", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prepared.MediaType != "image/jpeg" || prepared.Width != 20 || prepared.Height != 40 {
+		t.Fatalf("prepared = %s %dx%d, want image/jpeg 20x40", prepared.MediaType, prepared.Width, prepared.Height)
+	}
+	if bytes.Contains(prepared.Data, exifHeader) {
+		t.Error("prepared image still has EXIF data")
+	}
+	img, err := jpeg.Decode(bytes.NewReader(prepared.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Turned clockwise, the red left half is on top.
+	if r, _, b, _ := img.At(10, 5).RGBA(); r < b {
+		t.Errorf("top of the upright image is not red")
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	data := createTestJPEG(t, 8, 8, exifWithOrientation(binary.LittleEndian, 1))
+	prepared, err := Prepare(data, "photo.jpg", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(prepared.Data, exifHeader) {
+		t.Error("JPEG still has EXIF data")
+	}
+	if len(prepared.Data) != len(data)-len(exifHeader)-len(exifWithOrientation(binary.LittleEndian, 1))-4 {
+		t.Errorf("JPEG was re-encoded: %d bytes from %d", len(prepared.Data), len(data))
+	}
+
+	plain := createTestPNG(t, 4, 4)
+	withText := append(plain[:33:33], append(pngChunk("tEXt", []byte("Location\x00home")), plain[33:]...)...)
+	stripped := stripMetadata(withText)
+	if !bytes.Equal(stripped, plain) {
+		t.Error("PNG text chunk was not removed")
+	}
+
+	webp, err := os.ReadFile("testdata/gopher.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withEXIF := append(append([]byte(nil), webp...), "EXIF\x03\x00\x00\x00GPS\x00"...)
+	binary.LittleEndian.PutUint32(withEXIF[4:], uint32(len(withEXIF)-8))
+	if stripped := stripMetadata(withEXIF); !bytes.Equal(stripped, webp) {
+		t.Errorf("WebP EXIF chunk was not removed: %d bytes, want %d", len(stripped), len(webp))
+	}
+}
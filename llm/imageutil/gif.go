@@ -0,0 +1,136 @@
+package imageutil
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/png"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// MaxContactSheetFrames caps how many frames ExtractFrames tiles.
+const MaxContactSheetFrames = 16
+
+// FrameCount returns how many frames a GIF has, or 1 for other images. It
+// walks the GIF's blocks without decoding them, so it is cheap.
+func FrameCount(data []byte) int {
+	if !bytes.HasPrefix(data, []byte("GIF8")) || len(data) < 13 {
+		return 1
+	}
+	i := 13 + colorTableSize(data[10])
+	frames := 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension: label, then sub-blocks
+			i = skipSubBlocks(data, i+2)
+		case 0x2C: // image descriptor, optional color table, LZW code size, then sub-blocks
+			if i+10 > len(data) {
+				return max(frames, 1)
+			}
+			frames++
+			i = skipSubBlocks(data, i+10+colorTableSize(data[i+9])+1)
+		default: // trailer, or garbage
+			return max(frames, 1)
+		}
+	}
+	return max(frames, 1)
+}
+
+// colorTableSize returns the size of the color table that a GIF screen or
+// image descriptor with the given packed flags says follows it.
+func colorTableSize(flags byte) int {
+	if flags&0x80 == 0 {
+		return 0
+	}
+	return 3 << (flags&7 + 1)
+}
+
+// skipSubBlocks returns the index just past the GIF sub-blocks at data[i:].
+func skipSubBlocks(data []byte, i int) int {
+	for i < len(data) {
+		n := int(data[i])
+		i++
+		if n == 0 {
+			break
+		}
+		i += n
+	}
+	return i
+}
+
+// ExtractFrames returns a PNG of n frames of an animated GIF, spread evenly
+// through the animation from the first frame, and tiled left to right, top to
+// bottom. Frames are composited as they appear when played. n is clamped to
+// the number of frames and to MaxContactSheetFrames; an n of 1 gives the first
+// frame.
+func ExtractFrames(data []byte, n int) ([]byte, error) {
+	var g *gif.GIF
+	if n <= 1 {
+		// Decode just the first frame rather than the whole animation.
+		cfg, err := gif.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decode gif: %w", err)
+		}
+		first, err := gif.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decode gif: %w", err)
+		}
+		paletted, ok := first.(*image.Paletted)
+		if !ok {
+			return nil, fmt.Errorf("decode gif: unexpected frame type %T", first)
+		}
+		g = &gif.GIF{Image: []*image.Paletted{paletted}, Disposal: []byte{0}, Config: cfg}
+	} else {
+		var err error
+		g, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decode gif: %w", err)
+		}
+	}
+	n = max(min(n, len(g.Image), MaxContactSheetFrames), 1)
+
+	screen := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if screen.Empty() {
+		for _, frame := range g.Image {
+			screen = screen.Union(frame.Bounds())
+		}
+	}
+	cols := int(math.Ceil(math.Sqrt(float64(n))))
+	rows := (n + cols - 1) / cols
+	sheet := image.NewRGBA(image.Rect(0, 0, cols*screen.Dx(), rows*screen.Dy()))
+
+	canvas := image.NewRGBA(screen)
+	tile := 0
+	for i, frame := range g.Image {
+		if tile == n {
+			break
+		}
+		var previous *image.RGBA
+		if g.Disposal[i] == gif.DisposalPrevious {
+			previous = image.NewRGBA(screen)
+			copy(previous.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		// Tile frame i if it is the next of the n evenly spaced ones.
+		if i == tile*len(g.Image)/n {
+			at := image.Pt(tile%cols*screen.Dx(), tile/cols*screen.Dy())
+			draw.Draw(sheet, screen.Sub(screen.Min).Add(at), canvas, screen.Min, draw.Src)
+			tile++
+		}
+		switch g.Disposal[i] {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sheet); err != nil {
+		return nil, fmt.Errorf("encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
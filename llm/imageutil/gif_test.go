@@ -0,0 +1,110 @@
+package imageutil
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+// frameColors are the colors of the frames of createAnimatedGIF.
+var frameColors = []color.RGBA{
+	{255, 0, 0, 255},
+	{0, 255, 0, 255},
+	{0, 0, 255, 255},
+	{255, 255, 0, 255},
+}
+
+// createAnimatedGIF returns a 20x10 GIF whose first frame fills the screen
+// and whose later frames paint their color over its left half.
+func createAnimatedGIF(t *testing.T) []byte {
+	p := color.Palette{color.Transparent}
+	for _, c := range frameColors {
+		p = append(p, c)
+	}
+	g := &gif.GIF{Config: image.Config{ColorModel: p, Width: 20, Height: 10}}
+	for i := range frameColors {
+		bounds := image.Rect(0, 0, 20, 10)
+		if i > 0 {
+			bounds = image.Rect(0, 0, 10, 10)
+		}
+		frame := image.NewPaletted(bounds, p)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(i + 1)
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+		g.Disposal = append(g.Disposal, gif.DisposalNone)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFrameCount(t *testing.T) {
+	if got := FrameCount(createAnimatedGIF(t)); got != 4 {
+		t.Errorf("FrameCount(animated GIF) = %d, want 4", got)
+	}
+	if got := FrameCount(createTestPNG(t, 4, 4)); got != 1 {
+		t.Errorf("FrameCount(PNG) = %d, want 1", got)
+	}
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := FrameCount(buf.Bytes()); got != 1 {
+		t.Errorf("FrameCount(still GIF) = %d, want 1", got)
+	}
+}
+
+func TestExtractFrames(t *testing.T) {
+	data := createAnimatedGIF(t)
+	tests := []struct {
+		n             int
+		width, height int
+		// colors are the left and right halves of each tile, row by row.
+		colors [][2]color.RGBA
+	}{
+		{1, 20, 10, [][2]color.RGBA{{frameColors[0], frameColors[0]}}},
+		{2, 40, 10, [][2]color.RGBA{{frameColors[0], frameColors[0]}, {frameColors[2], frameColors[0]}}},
+		{4, 40, 20, [][2]color.RGBA{{frameColors[0], frameColors[0]}, {frameColors[1], frameColors[0]}, {frameColors[2], frameColors[0]}, {frameColors[3], frameColors[0]}}},
+		{100, 40, 20, nil},
+	}
+	for _, tt := range tests {
+		sheet, err := ExtractFrames(data, tt.n)
+		if err != nil {
+			t.Fatalf("ExtractFrames(%d) error = %v", tt.n, err)
+		}
+		img, err := png.Decode(bytes.NewReader(sheet))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+			t.Errorf("ExtractFrames(%d) is %dx%d, want %dx%d", tt.n, b.Dx(), b.Dy(), tt.width, tt.height)
+			continue
+		}
+		cols := tt.width / 20
+		for i, halves := range tt.colors {
+			x, y := i%cols*20, i/cols*10
+			for h, want := range halves {
+				if got := color.RGBAModel.Convert(img.At(x+h*10+5, y+5)); got != want {
+					t.Errorf("ExtractFrames(%d) tile %d half %d = %v, want %v", tt.n, i, h, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestPrepareAnimatedGIF(t *testing.T) {
+	prepared, err := Prepare(createAnimatedGIF(t), "recording.gif", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prepared.MediaType != "image/png" || prepared.Frames != 4 || prepared.Width != 20 || prepared.Height != 10 {
+		t.Errorf("prepared = %s %dx%d, %d frames; want a 20x10 PNG from 4 frames", prepared.MediaType, prepared.Width, prepared.Height, prepared.Frames)
+	}
+}
@@ -6,35 +6,58 @@ import (
 	"os/exec"
 )
 
-// IsHEIC checks if data is a HEIC/HEIF image based on file magic.
-// HEIC files are ISO Base Media File Format containers with specific brand codes.
+// IsHEIC checks if data is a HEIC/HEIF or AVIF image based on file magic.
+// These are ISO Base Media File Format containers with specific brand codes.
 func IsHEIC(data []byte) bool {
 	if len(data) < 12 {
 		return false
 	}
 	// ftyp box starts at offset 4, brand at offset 8
-	// Common brands: heic, heix, hevc, hevx, mif1, msf1
+	// Common brands: heic, heix, hevc, hevx, mif1, msf1, and avif, avis for AVIF
 	if data[4] != 'f' || data[5] != 't' || data[6] != 'y' || data[7] != 'p' {
 		return false
 	}
 	brand := string(data[8:12])
 	switch brand {
-	case "heic", "heix", "hevc", "hevx", "mif1", "msf1", "avif":
+	case "heic", "heix", "hevc", "hevx", "mif1", "msf1", "avif", "avis":
 		return true
 	}
 	return false
 }
 
-// ConvertHEICToPNG converts HEIC image data to PNG using ImageMagick's convert command.
+// isAVIF reports whether data, which IsHEIC accepts, is an AVIF image.
+func isAVIF(data []byte) bool {
+	brand := string(data[8:12])
+	return brand == "avif" || brand == "avis"
+}
+
+// heicConverters are the ImageMagick commands that can decode HEIC and AVIF:
+// magick for ImageMagick 7, convert for ImageMagick 6.
+var heicConverters = []string{"magick", "convert"}
+
+// ConvertHEICToPNG converts HEIC or AVIF image data to PNG using ImageMagick.
 // Returns the PNG data or an error if conversion fails.
 func ConvertHEICToPNG(data []byte) ([]byte, error) {
-	cmd := exec.Command("convert", "heic:-", "png:-")
-	cmd.Stdin = bytes.NewReader(data)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("convert heic to png: %w: %s", err, stderr.String())
+	coder := "heic"
+	if isAVIF(data) {
+		coder = "avif"
+	}
+	for _, name := range heicConverters {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		// -strip drops the metadata; the decoder has already applied the
+		// orientation to the pixels.
+		cmd := exec.Command(path, coder+":-", "-strip", "png:-")
+		cmd.Stdin = bytes.NewReader(data)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("convert %s to png: %w: %s", coder, err, stderr.String())
+		}
+		return stdout.Bytes(), nil
 	}
-	return stdout.Bytes(), nil
+	return nil, fmt.Errorf("convert %s to png: ImageMagick is not installed", coder)
 }
@@ -1,11 +1,19 @@
 package imageutil
 
 import (
+	"bytes"
 	"fmt"
+	"image/jpeg"
+	"image/png"
 	"net/http"
+	"slices"
 	"strings"
 )
 
+// providerFormats are the image formats every supported provider accepts.
+// Prepare converts others, such as GIF, which Gemini rejects, to PNG.
+var providerFormats = []string{"png", "jpeg", "webp"}
+
 // Prepared contains image bytes ready to send to an LLM.
 type Prepared struct {
 	Data      []byte
@@ -14,27 +22,33 @@ type Prepared struct {
 	Height    int
 	Converted bool
 	Resized   bool
+	// Frames is how many frames the original image had; more than one if
+	// it was animated.
+	Frames int
 }
 
 // Prepare validates image data and fits it within a model's advertised limits.
-// HEIC is converted because Go's image package does not decode it directly.
+// HEIC and AVIF are converted to PNG because Go's image package does not
+// decode them, and so are other formats that not every provider accepts.
+// Animated GIFs are reduced to their first frame; see ExtractFrames for more.
 //
 // Recognized formats are fully decoded before being returned. Header sniffing
 // alone can accept a truncated upload; embedding those bytes can make the
 // provider reject the entire request and permanently wedge the conversation.
 //
-// Dimension overflow is fixed transparently by downscaling because callers do
-// not request a specific image size. Byte overflow that remains after resizing
-// is returned as an error so the caller can recompress or choose another image
-// instead of sending a request the provider will reject. source is included in
-// errors so the caller knows which input needs attention.
+// Dimension and byte overflow are fixed transparently, by downscaling and by
+// recompressing with ResizeToBytes, because callers do not request a specific
+// image size or encoding. An image that still doesn't fit, or can't be
+// decoded to recompress, is returned as an error so the caller can choose
+// another image instead of sending a request the provider will reject. source
+// is included in errors so the caller knows which input needs attention.
 func Prepare(data []byte, source string, maxDimension, maxBytes int) (Prepared, error) {
 	converted := false
 	if IsHEIC(data) {
 		var err error
 		data, err = ConvertHEICToPNG(data)
 		if err != nil {
-			return Prepared{}, fmt.Errorf("convert HEIC image %s: %w", source, err)
+			return Prepared{}, fmt.Errorf("convert HEIC/AVIF image %s: %w", source, err)
 		}
 		converted = true
 	}
@@ -47,8 +61,31 @@ func Prepare(data []byte, source string, maxDimension, maxBytes int) (Prepared,
 		return Prepared{}, fmt.Errorf("image file appears corrupt or truncated (%s); re-upload or pick a different file: %w", source, err)
 	}
 
-	resized := false
 	format := strings.TrimPrefix(mediaType, "image/")
+	frames := FrameCount(data)
+	if frames > 1 {
+		// Send the first frame rather than let each provider pick one, or
+		// reject the animation.
+		if first, err := ExtractFrames(data, 1); err == nil {
+			data, format, converted = first, "png", true
+		}
+	} else if !slices.Contains(providerFormats, format) {
+		// Leave formats that can't be decoded here for the provider to judge.
+		if pngData, _, err := reencode(data, "png"); err == nil {
+			data, format, converted = pngData, "png", true
+		}
+	}
+
+	// Providers may ignore EXIF orientation, so turn the pixels upright.
+	if orientation(data) > 1 {
+		if upright, uprightFormat, err := reencode(data, format); err == nil {
+			data, format = upright, uprightFormat
+		}
+	}
+	// Don't send metadata such as GPS coordinates off the machine.
+	data = stripMetadata(data)
+
+	resized := false
 	if maxDimension > 0 {
 		// ResizeImage returns the original bytes when the image already fits.
 		// If it cannot decode a format such as WebP, leave the bytes unchanged
@@ -61,10 +98,14 @@ func Prepare(data []byte, source string, maxDimension, maxBytes int) (Prepared,
 		}
 	}
 	if maxBytes > 0 && len(data) > maxBytes {
-		return Prepared{}, fmt.Errorf(
-			"image too large for model: %s is %d bytes (after any auto-resize), model limit is %d bytes; recompress the image (e.g. lower JPEG quality) and try again",
-			source, len(data), maxBytes,
-		)
+		compressed, compressedFormat, err := ResizeToBytes(data, maxBytes)
+		if err != nil {
+			return Prepared{}, fmt.Errorf(
+				"image too large for model: %s is %d bytes (after any auto-resize), model limit is %d bytes, and recompressing failed: %w; pick a smaller image",
+				source, len(data), maxBytes, err,
+			)
+		}
+		data, format, resized = compressed, compressedFormat, true
 	}
 
 	width, height, _ := DecodeDimensions(data)
@@ -75,5 +116,26 @@ func Prepare(data []byte, source string, maxDimension, maxBytes int) (Prepared,
 		Height:    height,
 		Converted: converted,
 		Resized:   resized,
+		Frames:    frames,
 	}, nil
 }
+
+// reencode decodes data, turning it upright, and encodes it as JPEG if format
+// is "jpeg" and as PNG otherwise. It returns the format it used.
+func reencode(data []byte, format string) ([]byte, string, error) {
+	img, _, err := decode(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image: %w", err)
+	}
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	} else {
+		format = "png"
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("encode %s: %w", format, err)
+	}
+	return buf.Bytes(), format, nil
+}
@@ -2,6 +2,10 @@ package imageutil
 
 import (
 	"bytes"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"os"
 	"strings"
 	"testing"
 )
@@ -44,6 +48,17 @@ func TestPrepareErrors(t *testing.T) {
 	}
 }
 
+func TestPrepareRecompresses(t *testing.T) {
+	data := createNoisyPNG(t, 400, 300)
+	prepared, err := Prepare(data, "photo.png", 0, len(data)/4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prepared.MediaType != "image/jpeg" || !prepared.Resized || len(prepared.Data) > len(data)/4 {
+		t.Fatalf("prepared = %s, resized %v, %d bytes", prepared.MediaType, prepared.Resized, len(prepared.Data))
+	}
+}
+
 func TestPreparePreservesBytesWithoutLimits(t *testing.T) {
 	data := createTestPNG(t, 12, 9)
 	prepared, err := Prepare(data, "image.png", 0, 0)
@@ -54,3 +69,47 @@ func TestPreparePreservesBytesWithoutLimits(t *testing.T) {
 		t.Error("Prepare changed an image that needed no conversion or resize")
 	}
 }
+
+func TestPrepareWebP(t *testing.T) {
+	data, err := os.ReadFile("testdata/gopher.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prepared, err := Prepare(data, "gopher.webp", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prepared.MediaType != "image/webp" || prepared.Converted || !bytes.Equal(prepared.Data, data) {
+		t.Errorf("prepared = %s, converted %v; want the WebP unchanged", prepared.MediaType, prepared.Converted)
+	}
+	if prepared.Width == 0 || prepared.Height == 0 {
+		t.Errorf("dimensions = %dx%d, want the WebP's", prepared.Width, prepared.Height)
+	}
+
+	if _, err := Prepare(data[:len(data)/2], "broken.webp", 0, 0); err == nil || !strings.Contains(err.Error(), "corrupt or truncated") {
+		t.Errorf("corrupt WebP error = %v", err)
+	}
+
+	half := max(prepared.Width, prepared.Height) / 2
+	resized, err := Prepare(data, "gopher.webp", half, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resized.MediaType != "image/png" || !resized.Resized || max(resized.Width, resized.Height) != half {
+		t.Errorf("resized = %s %dx%d, resized %v; want a PNG fitting in %d", resized.MediaType, resized.Width, resized.Height, resized.Resized, half)
+	}
+}
+
+func TestPrepareConvertsGIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 20, 10), palette.Plan9), nil); err != nil {
+		t.Fatal(err)
+	}
+	prepared, err := Prepare(buf.Bytes(), "image.gif", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prepared.MediaType != "image/png" || !prepared.Converted || prepared.Width != 20 || prepared.Height != 10 {
+		t.Errorf("prepared = %s %dx%d, converted %v; want a converted 20x10 PNG", prepared.MediaType, prepared.Width, prepared.Height, prepared.Converted)
+	}
+}
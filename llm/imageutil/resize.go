@@ -6,20 +6,27 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	_ "image/gif" // register the GIF decoder
 	"image/jpeg"
 	"image/png"
 	"strings"
 
 	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
 )
 
 // DecodeDimensions returns the pixel width and height of the image encoded in
-// data. It reads only the image header (no full decode), so it is cheap.
+// data, once turned upright according to its EXIF orientation. It reads only
+// the image headers (no full decode), so it is cheap.
 func DecodeDimensions(data []byte) (width, height int, err error) {
 	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
 	if err != nil {
 		return 0, 0, fmt.Errorf("decode image config: %w", err)
 	}
+	if orientation(data) >= 5 {
+		// The image is stored on its side.
+		return cfg.Height, cfg.Width, nil
+	}
 	return cfg.Width, cfg.Height, nil
 }
 
@@ -33,11 +40,11 @@ func DecodeDimensions(data []byte) (width, height int, err error) {
 // wedges the conversation permanently. Validating here turns that into a
 // recoverable tool error instead.
 //
-// Formats without a decoder registered in this binary (currently anything but
-// PNG and JPEG — e.g. WebP or GIF) surface as image.ErrFormat; we cannot
-// verify those here, so we let them through rather than reject a valid image
-// we simply can't decode. Only a genuine decode failure of a recognized format
-// (the truncation case) is reported as an error.
+// Formats without a decoder registered in this binary (anything but PNG,
+// JPEG, GIF, and WebP) surface as image.ErrFormat; we cannot verify those
+// here, so we let them through rather than reject a valid image we simply
+// can't decode. Only a genuine decode failure of a recognized format (the
+// truncation case) is reported as an error.
 func Validate(data []byte) error {
 	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
 		if errors.Is(err, image.ErrFormat) {
@@ -48,11 +55,12 @@ func Validate(data []byte) error {
 	return nil
 }
 
-// ResizeImage resizes an image if any dimension exceeds maxDimension.
+// ResizeImage resizes an image if any dimension exceeds maxDimension, turning
+// it upright according to its EXIF orientation.
 // Returns the resized image bytes and the format ("png" or "jpeg").
 // If no resize is needed, returns the original data unchanged.
 func ResizeImage(data []byte, maxDimension int) (resized []byte, format string, didResize bool, err error) {
-	img, detectedFormat, err := image.Decode(bytes.NewReader(data))
+	img, detectedFormat, err := decode(data)
 	if err != nil {
 		return nil, "", false, fmt.Errorf("failed to decode image: %w", err)
 	}
@@ -96,3 +104,52 @@ func ResizeImage(data []byte, maxDimension int) (resized []byte, format string,
 
 	return buf.Bytes(), format, true, nil
 }
+
+// jpegQualities are the qualities ResizeToBytes tries at each size, best first.
+var jpegQualities = []int{85, 70, 55, 40}
+
+// minBytesDimension is the smallest longest side ResizeToBytes shrinks to.
+const minBytesDimension = 64
+
+// ResizeToBytes re-encodes an image as JPEG so that it fits in maxBytes,
+// lowering the quality and then, if need be, the dimensions. Transparent
+// areas become white, and the image is turned upright according to its EXIF
+// orientation. If data already fits, it is returned unchanged with
+// its format. It fails if even a small, low-quality JPEG doesn't fit.
+func ResizeToBytes(data []byte, maxBytes int) (resized []byte, format string, err error) {
+	img, detectedFormat, err := decode(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	if len(data) <= maxBytes {
+		return data, detectedFormat, nil
+	}
+
+	// Flatten onto white, since JPEG has no alpha channel.
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+
+	current := image.Image(flat)
+	for {
+		for _, quality := range jpegQualities {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, current, &jpeg.Options{Quality: quality}); err != nil {
+				return nil, "", fmt.Errorf("failed to encode image: %w", err)
+			}
+			if buf.Len() <= maxBytes {
+				return buf.Bytes(), "jpeg", nil
+			}
+		}
+
+		// Shrink by a quarter and try again.
+		width, height := current.Bounds().Dx()*3/4, current.Bounds().Dy()*3/4
+		if max(width, height) < minBytesDimension || min(width, height) < 1 {
+			return nil, "", fmt.Errorf("image does not fit in %d bytes even at %dx%d", maxBytes, current.Bounds().Dx(), current.Bounds().Dy())
+		}
+		smaller := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.BiLinear.Scale(smaller, smaller.Bounds(), current, current.Bounds(), draw.Src, nil)
+		current = smaller
+	}
+}
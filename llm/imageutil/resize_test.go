@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/jpeg"
 	"image/png"
+	"math/rand/v2"
 	"testing"
 )
 
@@ -25,6 +26,20 @@ func createTestPNG(t *testing.T, width, height int) []byte {
 	return buf.Bytes()
 }
 
+// createNoisyPNG returns a PNG of random pixels, which compresses poorly.
+func createNoisyPNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range img.Pix {
+		img.Pix[i] = byte(r.Uint32())
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
 func TestResizeImage(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -215,11 +230,44 @@ func TestValidate(t *testing.T) {
 		t.Errorf("Validate(garbage) = %v, want nil (ErrFormat is not our concern)", err)
 	}
 
-	// A format with no decoder registered in this binary (a minimal RIFF/WEBP
+	// A format with no decoder registered in this binary (a minimal BMP
 	// header) must NOT be rejected: we can't verify it, so we let it through
 	// rather than falsely flag a valid image as corrupt.
-	webp := append([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), make([]byte, 16)...)
-	if err := Validate(webp); err != nil {
+	bmp := append([]byte("BM"), make([]byte, 52)...)
+	if err := Validate(bmp); err != nil {
 		t.Errorf("Validate(undecodable format) = %v, want nil (can't verify, allow)", err)
 	}
+
+	// WebP has a decoder, so a truncated one is caught.
+	webp := append([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), make([]byte, 16)...)
+	if err := Validate(webp); err == nil {
+		t.Errorf("Validate(truncated webp) = nil, want error")
+	}
+}
+
+func TestResizeToBytes(t *testing.T) {
+	data := createNoisyPNG(t, 400, 300)
+	const maxBytes = 20_000
+	resized, format, err := ResizeToBytes(data, maxBytes)
+	if err != nil {
+		t.Fatalf("ResizeToBytes() error = %v", err)
+	}
+	if format != "jpeg" || len(resized) > maxBytes {
+		t.Fatalf("ResizeToBytes() = %d bytes of %s, want at most %d bytes of jpeg", len(resized), format, maxBytes)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(resized)); err != nil {
+		t.Fatalf("result is not a JPEG: %v", err)
+	}
+
+	unchanged, format, err := ResizeToBytes(data, len(data))
+	if err != nil || format != "png" || !bytes.Equal(unchanged, data) {
+		t.Errorf("ResizeToBytes() of fitting image = %d bytes of %s, %v; want it unchanged", len(unchanged), format, err)
+	}
+
+	if _, _, err := ResizeToBytes(data, 100); err == nil {
+		t.Error("ResizeToBytes() with an impossible limit succeeded")
+	}
+	if _, _, err := ResizeToBytes([]byte("not an image"), 100); err == nil {
+		t.Error("ResizeToBytes() of invalid data succeeded")
+	}
 }
@@ -0,0 +1,79 @@
+package imageutil
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// MaxTiles caps how many tiles Tile makes. Longer images get longer
+	// tiles, which Prepare then downscales.
+	MaxTiles = 8
+	// tileOverlapPercent is how much of each tile repeats the end of the
+	// one before it, so that text cut by one edge is whole in a tile.
+	tileOverlapPercent = 10
+)
+
+// Tile splits an image whose longer side exceeds maxDimension into
+// overlapping tiles along that side, in order from top to bottom or left to
+// right. Each tile is as long as maxDimension or the image's shorter side,
+// whichever is more, so that downscaling it to fit maxDimension keeps as much
+// detail as downscaling to fit the shorter side alone. Tiles are JPEG if the
+// image is, and PNG otherwise. An image that fits, or a maxDimension of 0,
+// gives one tile: data itself.
+func Tile(data []byte, maxDimension int) ([][]byte, error) {
+	img, format, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	bounds := img.Bounds()
+	vertical := bounds.Dy() >= bounds.Dx()
+	long, short := bounds.Dy(), bounds.Dx()
+	if !vertical {
+		long, short = short, long
+	}
+	if maxDimension <= 0 || long <= maxDimension {
+		return [][]byte{data}, nil
+	}
+
+	// Lengthen the tiles if need be so that MaxTiles cover the image.
+	size := max(maxDimension, short, ceilDiv(long*100, 100+(MaxTiles-1)*(100-tileOverlapPercent)))
+	step := size - size*tileOverlapPercent/100
+
+	sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, fmt.Errorf("cannot crop %T", img)
+	}
+	var tiles [][]byte
+	for start := 0; ; start += step {
+		end := min(start+size, long)
+		start = max(end-size, 0) // keep the last tile full length
+		r := image.Rect(0, start, short, end)
+		if !vertical {
+			r = image.Rect(start, 0, end, short)
+		}
+		var buf bytes.Buffer
+		tile := sub.SubImage(r.Add(bounds.Min))
+		if format == "jpeg" {
+			err = jpeg.Encode(&buf, tile, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, tile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tile: %w", err)
+		}
+		tiles = append(tiles, buf.Bytes())
+		if end == long {
+			return tiles, nil
+		}
+	}
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
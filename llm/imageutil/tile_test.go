@@ -0,0 +1,67 @@
+package imageutil
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// createRowsPNG returns a PNG whose pixels encode their position along its
+// longer side, so that tiles can be located in it.
+func createRowsPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			pos := max(x, y)
+			img.Set(x, y, color.RGBA{uint8(pos), uint8(pos >> 8), 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTile(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		maxDimension  int
+		starts        []int // where each tile starts along the longer side
+		size          int   // how long each tile is
+	}{
+		{"fits", 100, 300, 300, []int{0}, 300},
+		{"no limit", 100, 1000, 0, []int{0}, 1000},
+		{"tall", 100, 1000, 300, []int{0, 270, 540, 700}, 300},
+		{"wide", 1000, 100, 300, []int{0, 270, 540, 700}, 300},
+		{"wider than the limit", 400, 1000, 300, []int{0, 360, 600}, 400},
+		{"very tall", 10, 10000, 300, []int{0, 1233, 2466, 3699, 4932, 6165, 7398, 8630}, 1370},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := createRowsPNG(t, tt.width, tt.height)
+			tiles, err := Tile(data, tt.maxDimension)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tiles) != len(tt.starts) {
+				t.Fatalf("got %d tiles, want %d", len(tiles), len(tt.starts))
+			}
+			for i, tile := range tiles {
+				img, err := png.Decode(bytes.NewReader(tile))
+				if err != nil {
+					t.Fatal(err)
+				}
+				b := img.Bounds()
+				length := max(b.Dx(), b.Dy())
+				r, g, _, _ := img.At(b.Min.X, b.Min.Y).RGBA()
+				if start := int(r>>8) | int(g>>8)<<8; start != tt.starts[i] || length != tt.size {
+					t.Errorf("tile %d starts at %d and is %d long, want %d and %d", i, start, length, tt.starts[i], tt.size)
+				}
+			}
+		})
+	}
+}
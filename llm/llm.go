@@ -121,10 +121,11 @@ func EmptySchema() json.RawMessage {
 type ErrorType string
 
 const (
-	ErrorTypeNone       ErrorType = ""            // Not an error
-	ErrorTypeTruncation ErrorType = "truncation"  // Response truncated due to max tokens
-	ErrorTypeLLMRequest ErrorType = "llm_request" // LLM request failed
-	ErrorTypeRefusal    ErrorType = "refusal"     // Model declined to continue (stop_reason=refusal)
+	ErrorTypeNone        ErrorType = ""             // Not an error
+	ErrorTypeTruncation  ErrorType = "truncation"   // Response truncated due to max tokens
+	ErrorTypeLLMRequest  ErrorType = "llm_request"  // LLM request failed
+	ErrorTypeRefusal     ErrorType = "refusal"      // Model declined to continue (stop_reason=refusal)
+	ErrorTypeContextFull ErrorType = "context_full" // Request not sent: it would fill the context window
 )
 
 // StreamDelta represents a partial content update during streaming.
@@ -255,6 +256,11 @@ type Tool struct {
 	// be filtered out when sending requests to other providers.
 	ServerSide bool
 
+	// ReadOnly marks tools that change nothing, so the loop may run calls
+	// to them from one response at the same time. Other tools' calls run
+	// one at a time, in order.
+	ReadOnly bool
+
 	// The Run function is automatically called when the tool is used.
 	// Run functions may be called concurrently with each other and themselves.
 	// The input to Run function is the input to the tool, as provided by Claude, in compliance with the input schema.
@@ -637,6 +643,9 @@ type Usage struct {
 	URL       string     `json:"url,omitempty"`
 	StartTime *time.Time `json:"start_time,omitempty"`
 	EndTime   *time.Time `json:"end_time,omitempty"`
+	// LatencyMs is how long the loop waited for the response, including
+	// retries, which StartTime and EndTime, covering one attempt, leave out.
+	LatencyMs int64 `json:"latency_ms,omitempty"`
 }
 
 func (u *Usage) Add(other Usage) {
@@ -645,6 +654,7 @@ func (u *Usage) Add(other Usage) {
 	u.CacheReadInputTokens += other.CacheReadInputTokens
 	u.OutputTokens += other.OutputTokens
 	u.CostUSD += other.CostUSD
+	u.LatencyMs += other.LatencyMs
 }
 
 func (u *Usage) String() string {
@@ -14,9 +14,16 @@ import (
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"shelley.exe.dev/version"
 )
 
+var tracer = otel.Tracer("shelley.exe.dev/llm/llmhttp")
+
 // contextKey is the type for context keys in this package.
 type contextKey int
 
@@ -230,6 +237,16 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		trace.setShelleyRequestID(requestID)
 	}
 
+	// The span covers the request until its body is closed, so that it
+	// includes the time spent streaming the response.
+	ctx, span := tracer.Start(req.Context(), "HTTP "+req.Method, oteltrace.WithSpanKind(oteltrace.SpanKindClient), oteltrace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.ServerAddress(req.URL.Hostname()),
+		semconv.URLPath(req.URL.Path),
+		attribute.String("shelley.request_id", requestID),
+	))
+	req = req.WithContext(ctx)
+
 	// Add conversation ID header if present
 	if conversationID := ConversationIDFromContext(req.Context()); conversationID != "" {
 		req.Header.Set("Shelley-Conversation-Id", conversationID)
@@ -250,6 +267,7 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		if resp != nil {
 			captureUpstreamRequestID(trace, resp.Header)
 		}
+		traceResponse(span, resp, err)
 		return resp, err
 	}
 
@@ -271,7 +289,9 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if err != nil {
 		watch.stop()
 		cancel()
-		return nil, watch.translate(err)
+		err = watch.translate(err)
+		traceResponse(span, nil, err)
+		return nil, err
 	}
 
 	// Wrap the body so each read resets the idle timer, and so the final
@@ -281,9 +301,54 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		watch:      watch,
 		cancel:     cancel,
 	}
+	traceResponse(span, resp, nil)
 	return resp, nil
 }
 
+// traceResponse records the outcome of a request on span. It ends span at
+// once if the request failed, or else when resp's body is closed.
+func traceResponse(span oteltrace.Span, resp *http.Response, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	for _, name := range upstreamRequestIDHeaders {
+		if v := resp.Header.Get(name); v != "" {
+			span.SetAttributes(attribute.String("shelley.upstream_request_id", v))
+			break
+		}
+	}
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	resp.Body = &spanReadCloser{ReadCloser: resp.Body, span: span}
+}
+
+// spanReadCloser ends a request's span when its body is closed, recording
+// any error reading it.
+type spanReadCloser struct {
+	io.ReadCloser
+	span oteltrace.Span
+	once sync.Once
+}
+
+func (r *spanReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.span.RecordError(err)
+		r.span.SetStatus(codes.Error, err.Error())
+	}
+	return n, err
+}
+
+func (r *spanReadCloser) Close() error {
+	r.once.Do(func() { r.span.End() })
+	return r.ReadCloser.Close()
+}
+
 // idleWatchdog cancels a request's context if no progress is reported within
 // the timeout. Each call to reset() restarts the countdown.
 type idleWatchdog struct {
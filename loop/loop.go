@@ -7,15 +7,22 @@ import (
 	"io"
 	"log/slog"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 	"shelley.exe.dev/gitstate"
 	"shelley.exe.dev/llm"
 	"shelley.exe.dev/llm/llmhttp"
 )
 
+var tracer = otel.Tracer("shelley.exe.dev/loop")
+
 // maxTurnDuration is an absolute backstop on a single LLM request (including
 // its inner transport retries). The primary bound on a stuck stream is the
 // transport idle/stall timeout (llmhttp.DefaultIdleTimeout); this ceiling only
@@ -62,6 +69,23 @@ type Config struct {
 	// before the assistant message is recorded. Use this to flush any
 	// buffered stream deltas so they reach the UI before the full message.
 	OnStreamDone func()
+	// ToolParallelism is how many calls to read-only tools (see
+	// llm.Tool.ReadOnly) from one response may run at once. Zero or one
+	// runs them one at a time.
+	ToolParallelism int
+	// ExtraSystem, if set, is called before each LLM request; its content
+	// is appended to System. Use it for state that changes during a turn.
+	ExtraSystem func(ctx context.Context) ([]llm.SystemContent, error)
+	// ContextLimit is the fraction of the model's context window a request
+	// may fill. Before each request the loop estimates its size; over the
+	// limit, it records a warning and, instead of sending a request the
+	// provider would reject, offers it to OnContextFull or ends the turn
+	// with an ErrorTypeContextFull error. Zero disables the check.
+	ContextLimit float64
+	// OnContextFull, if set, is called with the error that would end a turn
+	// over ContextLimit, and reports whether it is compacting the
+	// conversation instead, which resets the loop and continues the turn.
+	OnContextFull func(notice llm.Message) bool
 }
 
 // Loop manages a conversation turn with an LLM including tool execution and message recording.
@@ -85,8 +109,17 @@ type Loop struct {
 	onStreamDelta    func(llm.StreamDelta)
 	onStreamDone     func()
 	thinkingLevel    llm.ThinkingLevel
-	notify           chan struct{} // signaled when a message is queued or retry requested
-	retryPending     bool          // set by Retry() to re-run processLLMRequest with current history
+	toolParallelism  int
+	extraSystem      func(ctx context.Context) ([]llm.SystemContent, error)
+	contextLimit     float64
+	onContextFull    func(notice llm.Message) bool
+	// contextTokens is the context size the last response reported, when
+	// history held contextMessages messages; see requestTokens.
+	contextTokens   int
+	contextMessages int
+	notify          chan struct{}     // signaled when a message is queued or retry requested
+	retryPending    bool              // set by Retry() to re-run processLLMRequest with current history
+	turnParent      trace.SpanContext // parent of the next turn's span; see TraceTurn
 }
 
 // NewLoop creates a new Loop instance with the provided configuration
@@ -120,6 +153,10 @@ func NewLoop(config Config) *Loop {
 		onStreamDelta:    config.OnStreamDelta,
 		onStreamDone:     config.OnStreamDone,
 		thinkingLevel:    config.ThinkingLevel,
+		toolParallelism:  config.ToolParallelism,
+		extraSystem:      config.ExtraSystem,
+		contextLimit:     config.ContextLimit,
+		onContextFull:    config.OnContextFull,
 		notify:           make(chan struct{}, 1),
 	}
 }
@@ -174,6 +211,14 @@ func (l *Loop) QueueMessages(messages ...llm.Message) {
 	}
 }
 
+// TraceTurn makes the next turn's span a child of ctx's span, so that a
+// subagent's turns appear under the tool call that started them.
+func (l *Loop) TraceTurn(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.turnParent = trace.SpanContextFromContext(ctx)
+}
+
 // GetUsage returns the total usage accumulated by this loop
 func (l *Loop) GetUsage() llm.Usage {
 	l.mu.Lock()
@@ -279,7 +324,23 @@ func (l *Loop) ProcessOneTurn(ctx context.Context) error {
 // error occurs. This iterative design avoids the O(n²) peak memory that
 // mutual recursion (processLLMRequest ↔ executeToolCalls) caused, because
 // each iteration's locals are freed before the next iteration starts.
-func (l *Loop) processLLMRequest(ctx context.Context) error {
+func (l *Loop) processLLMRequest(ctx context.Context) (err error) {
+	l.mu.Lock()
+	parent := l.turnParent
+	l.turnParent = trace.SpanContext{}
+	l.mu.Unlock()
+	if parent.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, parent)
+	}
+	ctx, span := tracer.Start(ctx, "turn", trace.WithAttributes(semconv.GenAIConversationID(llmhttp.ConversationIDFromContext(ctx))))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	for {
 		l.mu.Lock()
 		messages := append([]llm.Message(nil), l.history...)
@@ -313,6 +374,14 @@ func (l *Loop) processLLMRequest(ctx context.Context) error {
 			}
 		}
 
+		if l.extraSystem != nil {
+			extra, err := l.extraSystem(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to build system prompt: %w", err)
+			}
+			system = append(slices.Clip(system), extra...)
+		}
+
 		req := &llm.Request{
 			Messages:      messages,
 			Tools:         tools,
@@ -327,6 +396,10 @@ func (l *Loop) processLLMRequest(ctx context.Context) error {
 		// is cancelled or fails after the LLM responds but before tools execute.
 		l.insertMissingToolResults(req)
 
+		if l.contextFull(ctx, llmService, req) {
+			return nil
+		}
+
 		systemLen := 0
 		for _, sys := range system {
 			systemLen += len(sys.Text)
@@ -383,6 +456,7 @@ func (l *Loop) processLLMRequest(ctx context.Context) error {
 			return resp, err
 		}
 
+		requestStart := time.Now()
 		resp, err := sendWithRetry(req)
 
 		// Resolve server-side tool "pause_turn" responses before any further
@@ -398,6 +472,9 @@ func (l *Loop) processLLMRequest(ctx context.Context) error {
 		if err == nil && resp != nil && resp.StopReason == llm.StopReasonPause {
 			resp, err = l.resolvePausedTurn(ctx, sendWithRetry, req, resp)
 		}
+		if err == nil {
+			resp.Usage.LatencyMs = time.Since(requestStart).Milliseconds()
+		}
 
 		// Flush any buffered stream deltas before recording the message,
 		// so the UI sees the streaming text before the full message replaces it.
@@ -455,6 +532,8 @@ func (l *Loop) processLLMRequest(ctx context.Context) error {
 		assistantMessage := resp.ToMessage()
 		l.mu.Lock()
 		l.history = append(l.history, assistantMessage)
+		l.contextTokens = int(resp.Usage.ContextWindowUsed())
+		l.contextMessages = len(l.history)
 		l.mu.Unlock()
 
 		// Record assistant message with model and timing metadata
@@ -726,75 +805,118 @@ func (l *Loop) handleRefusal(ctx context.Context, resp *llm.Response) error {
 	return nil
 }
 
-// executeToolCalls runs the tools from an LLM response and appends the results
-// to l.history. It does NOT call processLLMRequest — the caller loops instead.
-func (l *Loop) executeToolCalls(ctx context.Context, content []llm.Content) error {
-	var toolResults []llm.Content
+// imageTokens is roughly what one image costs, at the size providers
+// scale images down to.
+const imageTokens = 1600
 
-	for _, c := range content {
-		if c.Type != llm.ContentTypeToolUse {
-			continue
+// contextFull reports whether req, for service, is over the context limit,
+// in which case it has warned and either handed the turn to onContextFull
+// or ended it with an error.
+func (l *Loop) contextFull(ctx context.Context, service llm.Service, req *llm.Request) bool {
+	window := service.TokenContextWindow()
+	if l.contextLimit <= 0 || window <= 0 {
+		return false
+	}
+	tokens := l.requestTokens(req)
+	if float64(tokens) <= l.contextLimit*float64(window) {
+		return false
+	}
+	l.logger.Warn("request over the context limit", "tokens", tokens, "window", window, "limit", l.contextLimit)
+	if l.recordWarning != nil {
+		warning := fmt.Sprintf("Context window nearly full: this request is about %d tokens, %d%% of the model's %d.", tokens, tokens*100/window, window)
+		if err := l.recordWarning(ctx, warning); err != nil {
+			l.logger.Error("failed to record context warning", "error", err)
 		}
+	}
+	notice := llm.Message{
+		Role: llm.MessageRoleAssistant,
+		Content: []llm.Content{{
+			Type: llm.ContentTypeText,
+			Text: "[The request was not sent: it would fill too much of the model's context window. " +
+				"Compact the conversation to continue, switch to a model with a larger context window, " +
+				"or start a new conversation.]",
+		}},
+		EndOfTurn: true,
+		ErrorType: llm.ErrorTypeContextFull,
+	}
+	if l.onContextFull != nil && l.onContextFull(notice) {
+		return true
+	}
+	if err := l.recordMessage(ctx, notice, llm.Usage{}); err != nil {
+		l.logger.Error("failed to record context full message", "error", err)
+	}
+	l.checkGitStateChange(ctx)
+	return true
+}
 
-		l.logger.Debug("executing tool", "name", c.ToolName, "id", c.ID)
-
-		// Find the tool
-		var tool *llm.Tool
-		for _, t := range l.tools {
-			if t.Name == c.ToolName {
-				tool = t
-				break
-			}
+// requestTokens estimates the size of req: the context size the last
+// response reported plus the messages added since, or without one, the
+// whole request at about four characters a token.
+func (l *Loop) requestTokens(req *llm.Request) int {
+	l.mu.Lock()
+	known, n := l.contextTokens, l.contextMessages
+	l.mu.Unlock()
+	msgs := req.Messages
+	var tokens int
+	if known > 0 && n <= len(msgs) {
+		tokens, msgs = known, msgs[n:]
+	} else {
+		chars := 0
+		for _, s := range req.System {
+			chars += len(s.Text)
 		}
-
-		if tool == nil {
-			l.logger.Error("tool not found", "name", c.ToolName)
-			toolResults = append(toolResults, llm.Content{
-				Type:      llm.ContentTypeToolResult,
-				ToolUseID: c.ID,
-				ToolError: true,
-				ToolResult: []llm.Content{
-					{Type: llm.ContentTypeText, Text: fmt.Sprintf("Tool '%s' not found", c.ToolName)},
-				},
-			})
-			continue
+		for _, t := range req.Tools {
+			chars += len(t.Name) + len(t.Description) + len(t.InputSchema)
 		}
+		tokens = chars / 4
+	}
+	for _, m := range msgs {
+		tokens += contentTokens(m.Content)
+	}
+	return tokens
+}
 
-		// Execute the tool with working directory and progress callback set in context
-		toolCtx := ctx
-		if l.workingDir != "" {
-			toolCtx = llm.WithWorkingDir(ctx, l.workingDir)
-		}
-		if l.onToolProgress != nil {
-			toolCtx = llm.WithToolProgress(toolCtx, l.onToolProgress)
+func contentTokens(content []llm.Content) int {
+	tokens := 0
+	for _, c := range content {
+		if c.MediaType != "" && c.Data != "" {
+			tokens += imageTokens
 		}
-		toolCtx = llm.WithToolUseID(toolCtx, c.ID)
-		toolCtx = llm.WithLLMService(toolCtx, l.llm)
-		startTime := time.Now()
-		result := tool.Run(toolCtx, c.ToolInput)
-		endTime := time.Now()
-
-		var toolResultContent []llm.Content
-		if result.Error != nil {
-			l.logger.Error("tool execution failed", "name", c.ToolName, "error", result.Error)
-			toolResultContent = []llm.Content{
-				{Type: llm.ContentTypeText, Text: result.Error.Error()},
-			}
-		} else {
-			toolResultContent = result.LLMContent
-			l.logger.Debug("tool executed successfully", "name", c.ToolName, "duration", endTime.Sub(startTime))
+		tokens += (len(c.Text) + len(c.Thinking) + len(c.ToolName) + len(c.ToolInput) + 3) / 4
+		tokens += contentTokens(c.ToolResult)
+	}
+	return tokens
+}
+
+// executeToolCalls runs the tools from an LLM response and appends the results
+// to l.history. It does NOT call processLLMRequest — the caller loops instead.
+// Up to l.toolParallelism calls to read-only tools run at once; any other
+// call waits for the calls before it and runs alone, since later calls may
+// depend on what it changes. Results keep the calls' order.
+func (l *Loop) executeToolCalls(ctx context.Context, content []llm.Content) error {
+	var calls []llm.Content
+	for _, c := range content {
+		if c.Type == llm.ContentTypeToolUse {
+			calls = append(calls, c)
 		}
+	}
 
-		toolResults = append(toolResults, llm.Content{
-			Type:             llm.ContentTypeToolResult,
-			ToolUseID:        c.ID,
-			ToolError:        result.Error != nil,
-			ToolResult:       toolResultContent,
-			ToolUseStartTime: &startTime,
-			ToolUseEndTime:   &endTime,
-			Display:          result.Display,
+	toolResults := make([]llm.Content, len(calls))
+	sem := make(chan struct{}, max(1, l.toolParallelism))
+	var wg sync.WaitGroup
+	for i, c := range calls {
+		if tool := l.findTool(c.ToolName); tool == nil || !tool.ReadOnly {
+			wg.Wait()
+			toolResults[i] = l.runToolCall(ctx, c)
+			continue
+		}
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			toolResults[i] = l.runToolCall(ctx, c)
 		})
 	}
+	wg.Wait()
 
 	if len(toolResults) > 0 {
 		// Add tool results to history as a user message
@@ -825,6 +947,73 @@ func (l *Loop) executeToolCalls(ctx context.Context, content []llm.Content) erro
 	return nil
 }
 
+// findTool returns the tool named name, or nil if there is none.
+func (l *Loop) findTool(name string) *llm.Tool {
+	for _, t := range l.tools {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// runToolCall runs the tool requested by c and returns its result.
+func (l *Loop) runToolCall(ctx context.Context, c llm.Content) llm.Content {
+	l.logger.Debug("executing tool", "name", c.ToolName, "id", c.ID)
+
+	tool := l.findTool(c.ToolName)
+	if tool == nil {
+		l.logger.Error("tool not found", "name", c.ToolName)
+		return llm.Content{
+			Type:      llm.ContentTypeToolResult,
+			ToolUseID: c.ID,
+			ToolError: true,
+			ToolResult: []llm.Content{
+				{Type: llm.ContentTypeText, Text: fmt.Sprintf("Tool '%s' not found", c.ToolName)},
+			},
+		}
+	}
+
+	// Execute the tool with working directory and progress callback set in context
+	toolCtx := ctx
+	workingDir := l.workingDir
+	if l.getWorkingDir != nil {
+		workingDir = l.getWorkingDir()
+	}
+	if workingDir != "" {
+		toolCtx = llm.WithWorkingDir(ctx, workingDir)
+	}
+	if l.onToolProgress != nil {
+		toolCtx = llm.WithToolProgress(toolCtx, l.onToolProgress)
+	}
+	toolCtx = llm.WithToolUseID(toolCtx, c.ID)
+	toolCtx = llm.WithLLMService(toolCtx, l.llm)
+	startTime := time.Now()
+	result := tool.Run(toolCtx, c.ToolInput)
+	endTime := time.Now()
+
+	var toolResultContent []llm.Content
+	if result.Error != nil {
+		l.logger.Error("tool execution failed", "name", c.ToolName, "error", result.Error)
+		toolResultContent = []llm.Content{
+			{Type: llm.ContentTypeText, Text: result.Error.Error()},
+		}
+	} else {
+		toolResultContent = result.LLMContent
+		l.logger.Debug("tool executed successfully", "name", c.ToolName, "duration", endTime.Sub(startTime))
+	}
+
+	return llm.Content{
+		Type:             llm.ContentTypeToolResult,
+		ToolUseID:        c.ID,
+		ToolError:        result.Error != nil,
+		ToolResult:       toolResultContent,
+		ToolUseStartTime: &startTime,
+		ToolUseEndTime:   &endTime,
+		Display:          result.Display,
+	}
+}
+
 // insertMissingToolResults fixes tool_result issues in the conversation history:
 //  1. Adds error results for tool_uses that were requested but not included in the next message.
 //     This can happen when a request is cancelled or fails after the LLM responds with tool_use
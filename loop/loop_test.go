@@ -10,11 +10,16 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"shelley.exe.dev/gitstate"
 	"shelley.exe.dev/llm"
 	"shelley.exe.dev/llm/llmhttp"
@@ -332,6 +337,48 @@ func TestLoopWithTools(t *testing.T) {
 	}
 }
 
+func TestTraceTurn(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	parentCtx, parent := otel.Tracer("test").Start(context.Background(), "execute_tool subagent")
+	defer parent.End()
+
+	var toolSpan trace.SpanContext
+	loop := NewLoop(Config{
+		LLM: NewPredictableService(),
+		Tools: []*llm.Tool{{
+			Name:        "bash",
+			InputSchema: llm.MustSchema(`{"type": "object", "properties": {"command": {"type": "string"}}}`),
+			Run: func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+				toolSpan = trace.SpanContextFromContext(ctx)
+				return llm.ToolOut{LLMContent: llm.TextContent("ok")}
+			},
+		}},
+		RecordMessage: func(context.Context, llm.Message, llm.Usage) error { return nil },
+	})
+	loop.TraceTurn(parentCtx)
+	loop.QueueUserMessage(llm.Message{Role: llm.MessageRoleUser, Content: llm.TextContent("bash: echo hello")})
+	if err := loop.ProcessOneTurn(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var turn sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "turn" {
+			turn = s
+		}
+	}
+	if turn == nil {
+		t.Fatal("no turn span")
+	}
+	if turn.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("turn span's parent is %v, want the span passed to TraceTurn", turn.Parent().SpanID())
+	}
+	if toolSpan.SpanID() != turn.SpanContext().SpanID() {
+		t.Errorf("tool ran in span %v, want the turn span", toolSpan.SpanID())
+	}
+}
+
 func TestGetHistory(t *testing.T) {
 	initialHistory := []llm.Message{
 		{Role: llm.MessageRoleUser, Content: []llm.Content{{Type: llm.ContentTypeText, Text: "Hello"}}},
@@ -1566,8 +1613,10 @@ func TestLLMRequestRetryOnEOF(t *testing.T) {
 	retryService := &retryableLLMService{failuresRemaining: 1}
 
 	var recordedMessages []llm.Message
+	var recordedUsage []llm.Usage
 	recordFunc := func(ctx context.Context, message llm.Message, usage llm.Usage) error {
 		recordedMessages = append(recordedMessages, message)
+		recordedUsage = append(recordedUsage, usage)
 		return nil
 	}
 	var warnings []string
@@ -1615,6 +1664,10 @@ func TestLLMRequestRetryOnEOF(t *testing.T) {
 	if !strings.Contains(recordedMessages[0].Content[0].Text, "Success after retry") {
 		t.Errorf("expected success message, got: %s", recordedMessages[0].Content[0].Text)
 	}
+	// The latency includes the one-second backoff before the retry.
+	if recordedUsage[0].LatencyMs < 1000 {
+		t.Errorf("latency = %dms, want at least the retry backoff", recordedUsage[0].LatencyMs)
+	}
 }
 
 func TestLLMRequestRetryExhausted(t *testing.T) {
@@ -2695,3 +2748,179 @@ func TestUserFacingLLMError(t *testing.T) {
 		t.Errorf("error message missing shelley request id: %q", withIDs)
 	}
 }
+
+func TestExecuteToolCallsParallel(t *testing.T) {
+	var recordedMessages []llm.Message
+	recordFunc := func(ctx context.Context, message llm.Message, usage llm.Usage) error {
+		recordedMessages = append(recordedMessages, message)
+		return nil
+	}
+
+	// Each call waits until all three have started, so this only finishes
+	// if the calls run concurrently.
+	var started sync.WaitGroup
+	started.Add(3)
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+	tool := &llm.Tool{
+		Name:     "barrier",
+		ReadOnly: true,
+		Run: func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+			started.Done()
+			select {
+			case <-allStarted:
+				return llm.ToolOut{LLMContent: llm.TextContent(string(input))}
+			case <-ctx.Done():
+				return llm.ErrorToolOut(ctx.Err())
+			}
+		},
+	}
+
+	loop := NewLoop(Config{
+		LLM:             NewPredictableService(),
+		Tools:           []*llm.Tool{tool},
+		RecordMessage:   recordFunc,
+		ToolParallelism: 3,
+	})
+
+	var content []llm.Content
+	for _, id := range []string{"a", "b", "c"} {
+		content = append(content, llm.Content{
+			ID:        id,
+			Type:      llm.ContentTypeToolUse,
+			ToolName:  "barrier",
+			ToolInput: json.RawMessage(`"` + id + `"`),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := loop.executeToolCalls(ctx, content); err != nil {
+		t.Fatalf("executeToolCalls failed: %v", err)
+	}
+
+	if len(recordedMessages) != 1 {
+		t.Fatalf("expected 1 recorded message, got %d", len(recordedMessages))
+	}
+	for i, r := range recordedMessages[0].Content {
+		id := content[i].ID
+		if r.ToolUseID != id || r.ToolError || r.ToolResult[0].Text != `"`+id+`"` {
+			t.Errorf("result %d out of order or failed: %+v", i, r)
+		}
+	}
+}
+
+func TestExecuteToolCallsRunsWritesAlone(t *testing.T) {
+	// Each write checks that nothing else is running and records its order.
+	var mu sync.Mutex
+	running := 0
+	var order []string
+	run := func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+		mu.Lock()
+		running++
+		alone := running == 1
+		order = append(order, strings.Trim(string(input), `"`))
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		if !alone {
+			return llm.ErrorfToolOut("ran alongside another call")
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent("ok")}
+	}
+	read := &llm.Tool{Name: "read", ReadOnly: true, Run: func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+		mu.Lock()
+		order = append(order, strings.Trim(string(input), `"`))
+		mu.Unlock()
+		return llm.ToolOut{LLMContent: llm.TextContent("ok")}
+	}}
+	write := &llm.Tool{Name: "write", Run: run}
+
+	var recorded []llm.Message
+	loop := NewLoop(Config{
+		LLM:   NewPredictableService(),
+		Tools: []*llm.Tool{read, write},
+		RecordMessage: func(ctx context.Context, message llm.Message, usage llm.Usage) error {
+			recorded = append(recorded, message)
+			return nil
+		},
+		ToolParallelism: 4,
+	})
+	var content []llm.Content
+	for _, c := range []struct{ id, tool string }{{"w1", "write"}, {"r1", "read"}, {"r2", "read"}, {"w2", "write"}, {"w3", "write"}} {
+		content = append(content, llm.Content{ID: c.id, Type: llm.ContentTypeToolUse, ToolName: c.tool, ToolInput: json.RawMessage(`"` + c.id + `"`)})
+	}
+	if err := loop.executeToolCalls(context.Background(), content); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range recorded[0].Content {
+		if r.ToolError {
+			t.Errorf("%s: %s", r.ToolUseID, r.ToolResult[0].Text)
+		}
+	}
+	if order[0] != "w1" || !slices.Equal(order[3:], []string{"w2", "w3"}) {
+		t.Errorf("calls ran in order %v", order)
+	}
+}
+
+func TestContextLimit(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		var mu sync.Mutex
+		var recorded []llm.Message
+		var warnings []string
+		var offered int
+		service := NewPredictableService()
+		loop := NewLoop(Config{
+			LLM: service,
+			RecordMessage: func(ctx context.Context, message llm.Message, usage llm.Usage) error {
+				mu.Lock()
+				recorded = append(recorded, message)
+				mu.Unlock()
+				return nil
+			},
+			RecordWarning: func(ctx context.Context, text string) error {
+				mu.Lock()
+				warnings = append(warnings, text)
+				mu.Unlock()
+				return nil
+			},
+			// 20 of the predictable service's 200000 tokens.
+			ContextLimit: 0.0001,
+			OnContextFull: func(notice llm.Message) bool {
+				mu.Lock()
+				offered++
+				mu.Unlock()
+				return compact
+			},
+		})
+		loop.QueueUserMessage(llm.UserStringMessage("echo: " + strings.Repeat("words ", 20)))
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		loop.Go(ctx)
+		cancel()
+
+		if n := len(service.GetRecentRequests()); n != 0 {
+			t.Errorf("compact=%v: sent %d requests over the limit", compact, n)
+		}
+		mu.Lock()
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "Context window nearly full") {
+			t.Errorf("compact=%v: warnings %q", compact, warnings)
+		}
+		if offered != 1 {
+			t.Errorf("compact=%v: offered to OnContextFull %d times", compact, offered)
+		}
+		if compact {
+			if len(recorded) != 0 {
+				t.Errorf("compacting: recorded %+v", recorded)
+			}
+		} else if len(recorded) != 1 || recorded[0].ErrorType != llm.ErrorTypeContextFull || !recorded[0].EndOfTurn {
+			t.Errorf("refusing: recorded %+v", recorded)
+		}
+		mu.Unlock()
+	}
+}
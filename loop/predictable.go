@@ -55,6 +55,9 @@ func requestMentions(req *llm.Request, needle string) bool {
 //   - "change_dir: <path>" - triggers change_dir tool
 //   - "delay: <seconds>" - delays response by specified seconds
 //   - "fail <error>" - emits a retry warning and returns a failure
+//   - "scenario: <name>" - plays a scripted Scenario (see AddScenario), such
+//     as the built-in "smoke", or those in the file or directory named by
+//     $PREDICTABLE_SCENARIOS
 //   - See Do() method for complete list of supported patterns
 type PredictableService struct {
 	// TokenContextWindow size
@@ -63,12 +66,16 @@ type PredictableService struct {
 	// Recent requests for testing inspection
 	recentRequests []*llm.Request
 	responseDelay  time.Duration
+	scenarios      map[string]Scenario
+	// scenarioErr is why $PREDICTABLE_SCENARIOS failed to load.
+	scenarioErr error
 }
 
 // NewPredictableService creates a new predictable LLM service
 func NewPredictableService() *PredictableService {
 	svc := &PredictableService{
 		tokenContextWindow: 200000,
+		scenarios:          map[string]Scenario{smokeScenario.Name: smokeScenario},
 	}
 
 	if delayEnv := os.Getenv("PREDICTABLE_DELAY_MS"); delayEnv != "" {
@@ -77,6 +84,14 @@ func NewPredictableService() *PredictableService {
 		}
 	}
 
+	if path := os.Getenv("PREDICTABLE_SCENARIOS"); path != "" {
+		scenarios, err := LoadScenarios(path)
+		for _, sc := range scenarios {
+			svc.scenarios[sc.Name] = sc
+		}
+		svc.scenarioErr = err
+	}
+
 	return svc
 }
 
@@ -141,6 +156,10 @@ func (s *PredictableService) Do(ctx context.Context, req *llm.Request) (*llm.Res
 		}
 	}
 
+	if resp, ok, err := s.scenarioResponse(req, inputTokens); ok {
+		return resp, err
+	}
+
 	// If the message is purely a tool result (no text), acknowledge it and end turn.
 	if hasToolResult && inputText == "" {
 		// Special case: if we previously wrote the inline-image demo file via
@@ -1003,6 +1022,82 @@ func (s *PredictableService) makeToolSmorgasbordResponse(inputTokens uint64) *ll
 		ToolInput: json.RawMessage(shellInput),
 	})
 
+	// run_tests tool (a target that matches nothing keeps it fast)
+	runTestsInput, _ := json.Marshal(map[string]string{"framework": "go", "target": "./nonexistent/..."})
+	content = append(content, llm.Content{
+		ID:        fmt.Sprintf("tool_run_tests_%d", (baseNano+20)%1000),
+		Type:      llm.ContentTypeToolUse,
+		ToolName:  "run_tests",
+		ToolInput: json.RawMessage(runTestsInput),
+	})
+
+	// diagnostics tool
+	diagnosticsInput, _ := json.Marshal(map[string]any{"linters": []string{"go vet"}, "path": "./nonexistent/..."})
+	content = append(content, llm.Content{
+		ID:        fmt.Sprintf("tool_diagnostics_%d", (baseNano+21)%1000),
+		Type:      llm.ContentTypeToolUse,
+		ToolName:  "diagnostics",
+		ToolInput: json.RawMessage(diagnosticsInput),
+	})
+
+	// probe tool
+	probeInput, _ := json.Marshal(map[string]any{"port": 1, "url": "http://localhost:1/"})
+	content = append(content, llm.Content{
+		ID:        fmt.Sprintf("tool_probe_%d", (baseNano+22)%1000),
+		Type:      llm.ContentTypeToolUse,
+		ToolName:  "probe",
+		ToolInput: json.RawMessage(probeInput),
+	})
+
+	// notebook tool (a missing notebook keeps it fast)
+	notebookInput, _ := json.Marshal(map[string]any{"path": "nonexistent.ipynb", "action": "read"})
+	content = append(content, llm.Content{
+		ID:        fmt.Sprintf("tool_notebook_%d", (baseNano+23)%1000),
+		Type:      llm.ContentTypeToolUse,
+		ToolName:  "notebook",
+		ToolInput: json.RawMessage(notebookInput),
+	})
+
+	// outline tool
+	outlineInput, _ := json.Marshal(map[string]any{"path": "."})
+	content = append(content, llm.Content{
+		ID:        fmt.Sprintf("tool_outline_%d", (baseNano+24)%1000),
+		Type:      llm.ContentTypeToolUse,
+		ToolName:  "outline",
+		ToolInput: json.RawMessage(outlineInput),
+	})
+
+	// scratchpad tool
+	scratchpadInput, _ := json.Marshal(map[string]any{"action": "set", "name": "plan", "content": "1. explore\n2. fix"})
+	content = append(content, llm.Content{
+		ID:        fmt.Sprintf("tool_scratchpad_%d", (baseNano+25)%1000),
+		Type:      llm.ContentTypeToolUse,
+		ToolName:  "scratchpad",
+		ToolInput: json.RawMessage(scratchpadInput),
+	})
+
+	// plan tool
+	planInput, _ := json.Marshal(map[string]any{"items": []map[string]any{
+		{"content": "Explore the code", "status": "completed"},
+		{"content": "Fix the bug", "status": "in_progress"},
+		{"content": "Add a test", "status": "pending"},
+	}})
+	content = append(content, llm.Content{
+		ID:        fmt.Sprintf("tool_plan_%d", (baseNano+26)%1000),
+		Type:      llm.ContentTypeToolUse,
+		ToolName:  "plan",
+		ToolInput: json.RawMessage(planInput),
+	})
+
+	// handoff tool
+	handoffInput, _ := json.Marshal(map[string]string{"conversation": "frontend", "message": "Please fix the login button."})
+	content = append(content, llm.Content{
+		ID:        fmt.Sprintf("tool_handoff_%d", (baseNano+27)%1000),
+		Type:      llm.ContentTypeToolUse,
+		ToolName:  "handoff",
+		ToolInput: json.RawMessage(handoffInput),
+	})
+
 	return &llm.Response{
 		ID:         fmt.Sprintf("pred-smorgasbord-%d", baseNano),
 		Type:       "message",
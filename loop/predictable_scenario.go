@@ -0,0 +1,222 @@
+package loop
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/llm"
+)
+
+// Scenario is a scripted conversation for the predictable service. The user
+// message "scenario: <name>" starts it, and each following request gets the
+// next step's response, so a scenario can drive tool calls end to end
+// without spending tokens. Once its steps run out, the service answers as
+// usual.
+type Scenario struct {
+	Name  string         `json:"name"`
+	Steps []ScenarioStep `json:"steps"`
+}
+
+// ScenarioStep is one assistant response in a scenario.
+type ScenarioStep struct {
+	// Expect, if set, must appear in the step's input: the user message
+	// or tool results it responds to. Otherwise the request fails.
+	Expect    string             `json:"expect,omitempty"`
+	Thinking  string             `json:"thinking,omitempty"`
+	Text      string             `json:"text,omitempty"`
+	ToolCalls []ScenarioToolCall `json:"tool_calls,omitempty"`
+	// Error fails the request with this message instead of responding.
+	Error string `json:"error,omitempty"`
+}
+
+// ScenarioToolCall is a tool call made by a scenario step.
+type ScenarioToolCall struct {
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// smokeScenario checks a deployment end to end: the bash tool runs in the
+// conversation's working directory and its output reaches the model.
+var smokeScenario = Scenario{
+	Name: "smoke",
+	Steps: []ScenarioStep{
+		{
+			Thinking: "Check that tools run and their output comes back.",
+			Text:     "Running the smoke test.",
+			ToolCalls: []ScenarioToolCall{{
+				Name:  "bash",
+				Input: json.RawMessage(`{"command": "echo shelley-smoke > shelley-smoke.txt && cat shelley-smoke.txt && pwd"}`),
+			}},
+		},
+		{Expect: "shelley-smoke", Text: "Smoke test passed."},
+	},
+}
+
+// LoadScenarios reads scenarios from a JSON file, or from each *.json file
+// in a directory. A scenario without a name is named after its file.
+func LoadScenarios(path string) ([]Scenario, error) {
+	paths := []string{path}
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		if paths, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, err
+		}
+	}
+	var scenarios []Scenario
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var sc Scenario
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&sc); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		if sc.Name == "" {
+			sc.Name = strings.TrimSuffix(filepath.Base(p), ".json")
+		}
+		if err := sc.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		scenarios = append(scenarios, sc)
+	}
+	return scenarios, nil
+}
+
+func (sc Scenario) validate() error {
+	if sc.Name == "" {
+		return errors.New("scenario has no name")
+	}
+	if len(sc.Steps) == 0 {
+		return fmt.Errorf("scenario %s has no steps", sc.Name)
+	}
+	for i, step := range sc.Steps {
+		if step.Text == "" && step.Thinking == "" && len(step.ToolCalls) == 0 && step.Error == "" {
+			return fmt.Errorf("scenario %s step %d: no text, thinking, tool_calls, or error", sc.Name, i+1)
+		}
+		for _, call := range step.ToolCalls {
+			var input map[string]any
+			if call.Name == "" || json.Unmarshal(call.Input, &input) != nil {
+				return fmt.Errorf("scenario %s step %d: a tool call needs a name and an input object", sc.Name, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// AddScenario makes a scenario available to "scenario: <name>", replacing
+// any of the same name.
+func (s *PredictableService) AddScenario(sc Scenario) error {
+	if err := sc.validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scenarios == nil {
+		s.scenarios = make(map[string]Scenario)
+	}
+	s.scenarios[sc.Name] = sc
+	return nil
+}
+
+// scenarioResponse responds to req with the next step of the scenario the
+// conversation started, if any. It reports whether req belonged to one.
+func (s *PredictableService) scenarioResponse(req *llm.Request, inputTokens uint64) (*llm.Response, bool, error) {
+	start, name := -1, ""
+	for i := len(req.Messages) - 1; i >= 0 && start < 0; i-- {
+		if req.Messages[i].Role != llm.MessageRoleUser {
+			continue
+		}
+		for _, c := range req.Messages[i].Content {
+			if rest, ok := strings.CutPrefix(strings.TrimSpace(c.Text), "scenario: "); ok {
+				start, name = i, strings.TrimSpace(rest)
+			}
+		}
+	}
+	if start < 0 {
+		return nil, false, nil
+	}
+
+	s.mu.Lock()
+	sc, ok := s.scenarios[name]
+	loadErr := s.scenarioErr
+	s.mu.Unlock()
+	if !ok {
+		// Only the message starting the scenario fails; later ones in the
+		// conversation are answered as usual.
+		if start != len(req.Messages)-1 {
+			return nil, false, nil
+		}
+		if loadErr != nil {
+			return nil, true, fmt.Errorf("predictable scenarios: %w", loadErr)
+		}
+		return nil, true, fmt.Errorf("unknown predictable scenario %q", name)
+	}
+	n := 0
+	for _, m := range req.Messages[start+1:] {
+		if m.Role == llm.MessageRoleAssistant {
+			n++
+		}
+	}
+	if n >= len(sc.Steps) {
+		return nil, false, nil
+	}
+	step := sc.Steps[n]
+
+	if step.Expect != "" {
+		var input strings.Builder
+		for _, c := range req.Messages[len(req.Messages)-1].Content {
+			input.WriteString(c.Text)
+			for _, tr := range c.ToolResult {
+				input.WriteString(tr.Text)
+			}
+		}
+		if !strings.Contains(input.String(), step.Expect) {
+			return nil, true, fmt.Errorf("scenario %s step %d: expected input containing %q, got %q", sc.Name, n+1, step.Expect, input.String())
+		}
+	}
+	if step.Error != "" {
+		return nil, true, fmt.Errorf("predictable error: %s", step.Error)
+	}
+
+	resp := &llm.Response{
+		ID:         fmt.Sprintf("pred-scenario-%d", time.Now().UnixNano()),
+		Type:       "message",
+		Role:       llm.MessageRoleAssistant,
+		Model:      "predictable-v1",
+		StopReason: llm.StopReasonStopSequence,
+		Usage:      llm.Usage{InputTokens: inputTokens, CostUSD: 0.001},
+	}
+	chars := len(step.Thinking) + len(step.Text)
+	if step.Thinking != "" {
+		resp.Content = append(resp.Content, llm.Content{Type: llm.ContentTypeThinking, Thinking: step.Thinking})
+	}
+	if step.Text != "" {
+		resp.Content = append(resp.Content, llm.Content{Type: llm.ContentTypeText, Text: step.Text})
+	}
+	for i, call := range step.ToolCalls {
+		if len(req.Tools) > 0 && !slices.ContainsFunc(req.Tools, func(t *llm.Tool) bool { return t.Name == call.Name }) {
+			return nil, true, fmt.Errorf("scenario %s step %d: the request offers no %s tool", sc.Name, n+1, call.Name)
+		}
+		resp.Content = append(resp.Content, llm.Content{
+			ID:        fmt.Sprintf("tool_scenario_%d_%d", time.Now().UnixNano(), i),
+			Type:      llm.ContentTypeToolUse,
+			ToolName:  call.Name,
+			ToolInput: call.Input,
+		})
+		resp.StopReason = llm.StopReasonToolUse
+		chars += len(call.Name) + len(call.Input)
+	}
+	resp.Usage.OutputTokens = max(uint64(chars/4), 1)
+	return resp, true, nil
+}
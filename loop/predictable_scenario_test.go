@@ -0,0 +1,100 @@
+package loop
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/llm"
+)
+
+func TestPredictableScenario(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "answer.json"), []byte(`{"steps": [
+		{"expect": "scenario: answer", "text": "Looking it up.", "tool_calls": [{"name": "lookup", "input": {"q": "answer"}}]},
+		{"expect": "42", "text": "The answer is 42."}
+	]}`), 0o644)
+	scenarios, err := LoadScenarios(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewPredictableService()
+	if err := svc.AddScenario(scenarios[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var lookups []string
+	var recorded []llm.Message
+	lookupResult := "42"
+	loop := NewLoop(Config{
+		LLM: svc,
+		Tools: []*llm.Tool{{
+			Name:        "lookup",
+			InputSchema: llm.MustSchema(`{"type": "object", "properties": {"q": {"type": "string"}}}`),
+			Run: func(ctx context.Context, input json.RawMessage) llm.ToolOut {
+				lookups = append(lookups, string(input))
+				return llm.ToolOut{LLMContent: llm.TextContent(lookupResult)}
+			},
+		}},
+		RecordMessage: func(ctx context.Context, message llm.Message, usage llm.Usage) error {
+			recorded = append(recorded, message)
+			return nil
+		},
+	})
+	loop.QueueUserMessage(llm.UserStringMessage("scenario: answer"))
+	if err := loop.ProcessOneTurn(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(lookups) != 1 || lookups[0] != `{"q": "answer"}` {
+		t.Errorf("lookups = %q", lookups)
+	}
+	last := recorded[len(recorded)-1]
+	if last.Role != llm.MessageRoleAssistant || last.Content[0].Text != "The answer is 42." {
+		t.Errorf("last message = %+v", last)
+	}
+
+	// Once the scenario is over, the service answers as usual.
+	loop.QueueUserMessage(llm.UserStringMessage("echo: after"))
+	if err := loop.ProcessOneTurn(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := recorded[len(recorded)-1].Content[0].Text; got != "after" {
+		t.Errorf("after the scenario: %q", got)
+	}
+
+	// A step whose input doesn't match fails the turn.
+	lookupResult = "41"
+	loop.QueueUserMessage(llm.UserStringMessage("scenario: answer"))
+	if err := loop.ProcessOneTurn(context.Background()); err == nil || !strings.Contains(err.Error(), `step 2: expected input containing "42"`) {
+		t.Errorf("mismatched input: err = %v", err)
+	}
+}
+
+func TestPredictableScenarioErrors(t *testing.T) {
+	svc := NewPredictableService()
+	request := func(text string) error {
+		_, err := svc.Do(context.Background(), &llm.Request{Messages: []llm.Message{llm.UserStringMessage(text)}})
+		return err
+	}
+	if err := request("scenario: nope"); err == nil || !strings.Contains(err.Error(), `unknown predictable scenario "nope"`) {
+		t.Errorf("unknown scenario: err = %v", err)
+	}
+	if err := request("scenario: smoke"); err != nil {
+		t.Errorf("built-in smoke scenario: %v", err)
+	}
+	if err := svc.AddScenario(Scenario{Name: "empty", Steps: []ScenarioStep{{Expect: "x"}}}); err == nil {
+		t.Error("a step without a response was accepted")
+	}
+	if err := svc.AddScenario(Scenario{Name: "bad", Steps: []ScenarioStep{{ToolCalls: []ScenarioToolCall{{Name: "bash", Input: json.RawMessage(`"ls"`)}}}}}); err == nil {
+		t.Error("a tool call with a non-object input was accepted")
+	}
+
+	t.Setenv("PREDICTABLE_SCENARIOS", filepath.Join(t.TempDir(), "missing"))
+	svc = NewPredictableService()
+	if err := request("scenario: mine"); err == nil || !strings.Contains(err.Error(), "predictable scenarios:") {
+		t.Errorf("unloadable scenarios: err = %v", err)
+	}
+}
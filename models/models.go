@@ -9,6 +9,10 @@ import (
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/db/generated"
 	"shelley.exe.dev/llm"
@@ -20,6 +24,8 @@ import (
 	"shelley.exe.dev/models/modelsdev"
 )
 
+var tracer = otel.Tracer("shelley.exe.dev/models")
+
 // Provider identifies an LLM upstream API family.
 type Provider string
 
@@ -112,6 +118,10 @@ type Built struct {
 	// BaseURL is the resolved upstream base URL (after applying any source
 	// override on top of the catalog's DefaultBaseURL).
 	BaseURL string
+
+	// APIModelName is the model name sent on the wire, used to look up
+	// pricing.
+	APIModelName string
 }
 
 // Config holds runtime configuration for the Manager. Built-in models
@@ -439,6 +449,7 @@ type serviceEntry struct {
 	tags        string
 	baseURL     string
 	apiType     APIType
+	apiModel    string
 }
 
 // ConfigInfo is an optional interface that services can implement to provide configuration details for logging
@@ -458,10 +469,18 @@ func (l *loggingService) Do(ctx context.Context, request *llm.Request) (*llm.Res
 	start := time.Now()
 	ctx = llmhttp.WithModelID(ctx, l.modelID)
 	ctx = llmhttp.WithProvider(ctx, string(l.provider))
+	ctx, span := tracer.Start(ctx, "chat "+l.modelID, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.GenAIOperationNameChat,
+		semconv.GenAIProviderNameKey.String(string(l.provider)),
+		semconv.GenAIRequestModel(l.modelID),
+	))
+	defer span.End()
 	response, err := l.service.Do(ctx, request)
 	durationSeconds := time.Since(start).Seconds()
 
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		logAttrs := []any{"model", l.modelID, "duration_seconds", durationSeconds}
 		if configProvider, ok := l.service.(ConfigInfo); ok {
 			for k, v := range configProvider.ConfigDetails() {
@@ -473,6 +492,11 @@ func (l *loggingService) Do(ctx context.Context, request *llm.Request) (*llm.Res
 		return response, err
 	}
 
+	span.SetAttributes(
+		semconv.GenAIResponseFinishReasons(response.StopReason.String()),
+		semconv.GenAIUsageInputTokens(int(response.Usage.TotalInputTokens())),
+		semconv.GenAIUsageOutputTokens(int(response.Usage.OutputTokens)),
+	)
 	logAttrs := []any{"model", l.modelID, "duration_seconds", durationSeconds}
 	if !response.Usage.IsZero() {
 		logAttrs = append(
@@ -568,6 +592,7 @@ func (m *Manager) registerBuiltModelsLocked(built []Built) {
 			tags:        b.Tags,
 			baseURL:     b.BaseURL,
 			apiType:     b.APIType,
+			apiModel:    b.APIModelName,
 		}
 		m.modelOrder = append(m.modelOrder, b.ID)
 		if m.logger != nil {
@@ -608,6 +633,7 @@ func (m *Manager) loadCustomModelsLocked(dbModels []generated.Model) {
 			source:      SourceCustomLabel,
 			displayName: model.DisplayName,
 			tags:        model.Tags,
+			apiModel:    model.ModelName,
 		}
 		m.modelOrder = append(m.modelOrder, model.ModelID)
 	}
@@ -688,11 +714,13 @@ func (m *Manager) HasModel(modelID string) bool {
 
 // ModelInfo contains display name, tags, source, base URL, and API type for a model.
 type ModelInfo struct {
-	DisplayName string
-	Tags        string
-	Source      string
-	BaseURL     string
-	APIType     string
+	DisplayName  string
+	Tags         string
+	Source       string
+	BaseURL      string
+	APIType      string
+	Provider     string
+	APIModelName string
 }
 
 func (m *Manager) GetModelInfo(modelID string) *ModelInfo {
@@ -702,7 +730,7 @@ func (m *Manager) GetModelInfo(modelID string) *ModelInfo {
 	if !ok {
 		return nil
 	}
-	return &ModelInfo{DisplayName: entry.displayName, Tags: entry.tags, Source: entry.source, BaseURL: entry.baseURL, APIType: string(entry.apiType)}
+	return &ModelInfo{DisplayName: entry.displayName, Tags: entry.tags, Source: entry.source, BaseURL: entry.baseURL, APIType: string(entry.apiType), Provider: string(entry.provider), APIModelName: entry.apiModel}
 }
 
 type reasoningMapping struct {
@@ -181,13 +181,14 @@ func Build(catalog []models.Model, sources []Source, httpc *http.Client, logger
 				}
 				seen[id] = true
 				out = append(out, models.Built{
-					ID:          id,
-					DisplayName: id,
-					Provider:    models.Provider(m.Provider),
-					Source:      src.label,
-					Service:     svc,
-					APIType:     apiType,
-					BaseURL:     src.integration.URL,
+					ID:           id,
+					DisplayName:  id,
+					Provider:     models.Provider(m.Provider),
+					Source:       src.label,
+					Service:      svc,
+					APIType:      apiType,
+					BaseURL:      src.integration.URL,
+					APIModelName: m.apiModelName(),
 				})
 				logger.Debug("Materialized integration model", "id", id, "source", src.label)
 			}
@@ -210,14 +211,15 @@ func Build(catalog []models.Model, sources []Source, httpc *http.Client, logger
 				baseURL = m.DefaultBaseURL
 			}
 			out = append(out, models.Built{
-				ID:          id,
-				DisplayName: id,
-				Provider:    m.Provider,
-				Tags:        m.Tags,
-				Source:      label,
-				Service:     svc,
-				APIType:     m.APIType,
-				BaseURL:     baseURL,
+				ID:           id,
+				DisplayName:  id,
+				Provider:     m.Provider,
+				Tags:         m.Tags,
+				Source:       label,
+				Service:      svc,
+				APIType:      m.APIType,
+				BaseURL:      baseURL,
+				APIModelName: m.APIModelName,
 			})
 			logger.Debug("Materialized model", "id", id, "source", label)
 		}
@@ -194,6 +194,9 @@ func TestLLMIntegrationEnrichesCompatibleCatalogModel(t *testing.T) {
 	if built.APIType != models.APITypeOpenAIResponses {
 		t.Errorf("APIType = %q, want %q", built.APIType, models.APITypeOpenAIResponses)
 	}
+	if built.APIModelName != "gpt-5.6-sol" {
+		t.Errorf("APIModelName = %q, want the native ID", built.APIModelName)
+	}
 	service, ok := built.Service.(*oai.ResponsesService)
 	if !ok {
 		t.Fatalf("service = %T, want *oai.ResponsesService", built.Service)
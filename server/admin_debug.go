@@ -0,0 +1,147 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strconv"
+	"strings"
+
+	"shelley.exe.dev/claudetool/browse"
+)
+
+// SetAdminToken sets the bearer token the /api/admin endpoints require.
+// Without one they only answer requests from localhost.
+func (s *Server) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// requireAdminToken lets through requests that carry the admin token in an
+// "Authorization: Bearer" header or, if no token is set, that come
+// straight from the loopback interface.
+func (s *Server) requireAdminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			if !isLoopbackRequest(r) {
+				http.Error(w, "admin endpoints are only served to localhost: no admin_token is configured", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			http.Error(w, "invalid admin token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackRequest reports whether r came from a loopback address and
+// not through a proxy, which could be forwarding anyone's request.
+func isLoopbackRequest(r *http.Request) bool {
+	if r.Header.Get("X-Forwarded-For") != "" || r.Header.Get("Forwarded") != "" {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// adminDebugMux serves /api/admin/debug with the /api/admin prefix
+// stripped, which leaves pprof at the /debug/pprof/ path it expects. The
+// caller checks the admin token.
+func (s *Server) adminDebugMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET /debug/goroutines", s.handleAdminGoroutines)
+	mux.HandleFunc("GET /debug/vars", s.handleAdminVars)
+	mux.HandleFunc("GET /debug/browsers", s.handleDebugBrowsers)
+	return mux
+}
+
+// handleAdminGoroutines handles GET /api/admin/debug/goroutines, a dump of
+// every goroutine's stack.
+func (s *Server) handleAdminGoroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// runtimeVars are the counters GET /api/admin/debug/vars reports.
+type runtimeVars struct {
+	Goroutines           int              `json:"goroutines"`
+	ActiveConversations  int              `json:"active_conversations"`
+	WorkingConversations int              `json:"working_conversations"`
+	Browsers             browse.PoolStats `json:"browsers"`
+	// ChildProcesses counts the server's direct children, such as MCP
+	// servers and bash commands. It is missing where /proc isn't.
+	ChildProcesses *int             `json:"child_processes,omitempty"`
+	MemStats       runtime.MemStats `json:"memstats"`
+}
+
+// handleAdminVars handles GET /api/admin/debug/vars.
+func (s *Server) handleAdminVars(w http.ResponseWriter, r *http.Request) {
+	vars := runtimeVars{Goroutines: runtime.NumGoroutine()}
+	s.mu.Lock()
+	managers := make([]*ConversationManager, 0, len(s.activeConversations))
+	for _, m := range s.activeConversations {
+		managers = append(managers, m)
+	}
+	s.mu.Unlock()
+	vars.ActiveConversations = len(managers)
+	for _, m := range managers {
+		if m.IsAgentWorking() {
+			vars.WorkingConversations++
+		}
+	}
+	if pool := s.currentToolSetConfig().BrowserPool; pool != nil {
+		vars.Browsers = pool.Stats()
+	}
+	if n, err := childProcessCount(); err == nil {
+		vars.ChildProcesses = &n
+	}
+	runtime.ReadMemStats(&vars.MemStats)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vars)
+}
+
+// childProcessCount counts the processes whose parent is this one.
+func childProcessCount() (int, error) {
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return 0, err
+	}
+	if len(stats) == 0 {
+		return 0, os.ErrNotExist
+	}
+	pid := strconv.Itoa(os.Getpid())
+	n := 0
+	for _, path := range stats {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // the process exited
+		}
+		// The state and parent pid follow the parenthesized command, which
+		// may itself contain spaces and parentheses.
+		stat := string(data)
+		fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+		if len(fields) > 1 && fields[1] == pid {
+			n++
+		}
+	}
+	return n, nil
+}
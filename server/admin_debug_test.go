@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminDebugEndpoints(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	request := func(path, token string) *http.Request {
+		r := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return r
+	}
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+	get := func(path, token string) *httptest.ResponseRecorder {
+		return serve(request(path, token))
+	}
+	local := func(path string) *http.Request {
+		r := request(path, "")
+		r.RemoteAddr = "127.0.0.1:40000"
+		return r
+	}
+
+	// Without an admin token, only direct requests from localhost get through.
+	for _, path := range []string{"/api/admin/debug/vars", "/debug/pprof/heap?debug=1", "/debug/browsers"} {
+		if w := get(path, ""); w.Code != http.StatusForbidden {
+			t.Errorf("%s from elsewhere without an admin token: got %d, want 403", path, w.Code)
+		}
+		if w := serve(local(path)); w.Code != http.StatusOK {
+			t.Errorf("%s from localhost without an admin token: got %d %.200s", path, w.Code, w.Body)
+		}
+		proxied := local(path)
+		proxied.Header.Set("X-Forwarded-For", "192.0.2.1")
+		if w := serve(proxied); w.Code != http.StatusForbidden {
+			t.Errorf("%s proxied from localhost without an admin token: got %d, want 403", path, w.Code)
+		}
+	}
+
+	server.SetAdminToken("secret")
+	for _, path := range []string{"/api/admin/debug/vars", "/api/admin/notifications/deliveries", "/debug/pprof/heap?debug=1"} {
+		if w := get(path, "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s with a wrong token: got %d, want 401", path, w.Code)
+		}
+		if w := serve(local(path)); w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s from localhost without the token: got %d, want 401", path, w.Code)
+		}
+	}
+	if w := get("/debug/pprof/heap?debug=1", "secret"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "heap profile") {
+		t.Errorf("/debug/pprof heap profile: %d %.200s", w.Code, w.Body)
+	}
+
+	w := get("/api/admin/debug/vars", "secret")
+	var vars runtimeVars
+	if err := json.Unmarshal(w.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("vars: %v: %s", err, w.Body)
+	}
+	if vars.Goroutines == 0 || vars.MemStats.HeapAlloc == 0 {
+		t.Errorf("unexpected vars %+v", vars)
+	}
+	if w := get("/api/admin/debug/goroutines", "secret"); !strings.Contains(w.Body.String(), "goroutine ") {
+		t.Errorf("goroutine dump: %d %s", w.Code, w.Body)
+	}
+	if w := get("/api/admin/debug/browsers", "secret"); w.Code != http.StatusOK {
+		t.Errorf("browsers: %d %s", w.Code, w.Body)
+	}
+	if w := get("/api/admin/debug/pprof/heap?debug=1", "secret"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "heap profile") {
+		t.Errorf("heap profile: %d %.200s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/server/notifications"
+)
+
+// toolFailureStreakLength is how many tool calls in a row must fail before
+// EventToolFailureStreak fires.
+const toolFailureStreakLength = 3
+
+// defaultAgentWaitingDelay is how long a turn that ended waiting on the user
+// may go unanswered before EventAgentWaiting fires.
+const defaultAgentWaitingDelay = 10 * time.Minute
+
+// notificationsSuppressed reports whether conv gets no notifications or
+// hooks. Subagents never do — they're internal and would just be noise.
+// Otherwise a conversation's own subscription choice wins over the
+// notify_by_default setting; disable_notifications mutes it.
+func (s *Server) notificationsSuppressed(ctx context.Context, conv *generated.Conversation) bool {
+	if conv.ParentConversationID != nil {
+		return true
+	}
+	opts := db.ParseConversationOptions(conv.ConversationOptions)
+	switch {
+	case opts.Notifications == db.NotificationsSubscribed:
+		return false
+	case opts.Notifications == db.NotificationsMuted, opts.DisableNotifications:
+		return true
+	}
+	val, err := s.db.GetSetting(ctx, notifyByDefaultSettingKey)
+	return err == nil && val == "false"
+}
+
+// alertConversation dispatches an alert event about a conversation to the
+// notification channels.
+func (s *Server) alertConversation(ctx context.Context, conversationID string, eventType notifications.EventType, message string) {
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		s.logger.Warn("failed to load conversation for alert", "conversationID", conversationID, "event", string(eventType), "error", err)
+		return
+	}
+	if s.notificationsSuppressed(ctx, conv) {
+		return
+	}
+	slug := derefString(conv.Slug)
+	payload := notifications.AlertPayload{
+		Hostname:          publicHostname(),
+		ConversationTitle: slug,
+		ConversationURL:   s.conversationURL(slug),
+		Message:           message,
+	}
+	// These fire mid-run, so the run is still there to cancel.
+	if eventType == notifications.EventToolFailureStreak || eventType == notifications.EventBudgetExceeded {
+		payload.CancelURL = s.conversationCancelURL(conversationID)
+	}
+	s.notifScheduler.Dispatch(ctx, notifications.Event{
+		Type:           eventType,
+		ConversationID: conversationID,
+		Timestamp:      time.Now(),
+		Payload:        payload,
+	})
+}
+
+// alertBudgetExceeded sends EventBudgetExceeded for the top-level
+// conversation rootID, once per conversation.
+func (s *Server) alertBudgetExceeded(rootID string, budgetErr error) {
+	s.mu.Lock()
+	alerted := s.budgetAlerted[rootID]
+	s.budgetAlerted[rootID] = true
+	s.mu.Unlock()
+	if !alerted {
+		go s.alertConversation(context.Background(), rootID, notifications.EventBudgetExceeded, budgetErr.Error())
+	}
+}
+
+// trackToolFailures extends the conversation's run of failed tool calls with
+// the tool results in msg. It reports whether the run just reached
+// toolFailureStreakLength, and the text of the latest failure.
+func (cm *ConversationManager) trackToolFailures(msg llm.Message) (reached bool, lastError string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	before := cm.toolFailureStreak
+	for _, c := range msg.Content {
+		if c.Type != llm.ContentTypeToolResult {
+			continue
+		}
+		if !c.ToolError {
+			cm.toolFailureStreak = 0
+			continue
+		}
+		cm.toolFailureStreak++
+		lastError = ""
+		for _, r := range c.ToolResult {
+			if r.Text != "" {
+				lastError = r.Text
+				break
+			}
+		}
+	}
+	return before < toolFailureStreakLength && cm.toolFailureStreak >= toolFailureStreakLength, lastError
+}
+
+// alertToolFailures sends EventToolFailureStreak when msg's tool results
+// bring conversationID's run of failed tool calls to toolFailureStreakLength.
+func (s *Server) alertToolFailures(cm *ConversationManager, conversationID string, msg llm.Message) {
+	reached, lastError := cm.trackToolFailures(msg)
+	if !reached {
+		return
+	}
+	message := fmt.Sprintf("%d tool calls in a row have failed.", toolFailureStreakLength)
+	if lastError != "" {
+		message += " Last error: " + truncateBody(lastError, 1000)
+	}
+	go s.alertConversation(context.Background(), conversationID, notifications.EventToolFailureStreak, message)
+}
+
+// agentWaitingMarker, anywhere in the last line of a turn, explicitly marks
+// the agent as blocked on the user.
+const agentWaitingMarker = "awaiting input"
+
+// waitingOnUser reports whether a turn's final response asks the user
+// something: it ends with a question or its last line has agentWaitingMarker.
+func waitingOnUser(finalResponse string) bool {
+	text := strings.TrimSpace(finalResponse)
+	if strings.HasSuffix(text, "?") {
+		return true
+	}
+	lastLine := text[strings.LastIndex(text, "\n")+1:]
+	return strings.Contains(strings.ToLower(lastLine), agentWaitingMarker)
+}
+
+// armAgentWaiting schedules EventAgentWaiting when a turn ends waiting on
+// the user. The next turn disarms it (see setAgentWorking).
+func (s *Server) armAgentWaiting(cm *ConversationManager, conversationID, finalResponse string) {
+	if !waitingOnUser(finalResponse) {
+		return
+	}
+	question := strings.TrimSpace(finalResponse)
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.agentWaiting != nil {
+		cm.agentWaiting.Stop()
+	}
+	cm.agentWaiting = time.AfterFunc(s.agentWaitingDelay, func() {
+		if cm.IsAgentWorking() {
+			return
+		}
+		s.alertConversation(context.Background(), conversationID, notifications.EventAgentWaiting, "Waiting for your reply:\n\n"+question)
+	})
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/server/notifications"
+)
+
+// alerts returns the alert payloads recorded for eventType.
+func (c *recordingChannel) alerts(eventType notifications.EventType) []notifications.AlertPayload {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []notifications.AlertPayload
+	for _, e := range c.events {
+		if e.Type == eventType {
+			out = append(out, e.Payload.(notifications.AlertPayload))
+		}
+	}
+	return out
+}
+
+func TestToolFailureStreakAlert(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	ch := &recordingChannel{}
+	server.RegisterNotificationChannel(ch)
+	ctx := context.Background()
+
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.getOrCreateConversationManager(ctx, conv.ConversationID, ""); err != nil {
+		t.Fatal(err)
+	}
+	result := func(failed bool, text string) llm.Message {
+		return llm.Message{Role: llm.MessageRoleUser, Content: []llm.Content{{
+			Type:       llm.ContentTypeToolResult,
+			ToolUseID:  "t",
+			ToolError:  failed,
+			ToolResult: []llm.Content{llm.StringContent(text)},
+		}}}
+	}
+	// A success resets the streak, so only the second run of failures
+	// reaches the threshold, and only once.
+	for _, failed := range []bool{true, true, false, true, true, true, true} {
+		text := "ok"
+		if failed {
+			text = "exit status 1"
+		}
+		if err := server.recordMessage(ctx, conv.ConversationID, result(failed, text), llm.Usage{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(t, 5*time.Second, func() bool { return len(ch.alerts(notifications.EventToolFailureStreak)) > 0 })
+	got := ch.alerts(notifications.EventToolFailureStreak)
+	if len(got) != 1 || got[0].Message != "3 tool calls in a row have failed. Last error: exit status 1" {
+		t.Fatalf("unexpected alerts: %+v", got)
+	}
+}
+
+func TestAgentWaitingAlert(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	server.agentWaitingDelay = 10 * time.Millisecond
+	ch := &recordingChannel{}
+	server.RegisterNotificationChannel(ch)
+	ctx := context.Background()
+
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, err := server.getOrCreateConversationManager(ctx, conv.ConversationID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	question := llm.Message{Role: llm.MessageRoleAssistant, Content: []llm.Content{llm.StringContent("Which branch should I use?")}}
+	if err := server.recordMessage(ctx, conv.ConversationID, question, llm.Usage{}); err != nil {
+		t.Fatal(err)
+	}
+	server.publishConversationState(ConversationState{ConversationID: conv.ConversationID, Model: "predictable"})
+
+	waitFor(t, 5*time.Second, func() bool { return len(ch.alerts(notifications.EventAgentWaiting)) > 0 })
+	if got := ch.alerts(notifications.EventAgentWaiting)[0].Message; !strings.HasSuffix(got, "Which branch should I use?") {
+		t.Fatalf("unexpected message: %q", got)
+	}
+
+	// A new turn disarms a pending alert.
+	server.agentWaitingDelay = time.Hour
+	server.publishConversationState(ConversationState{ConversationID: conv.ConversationID, Model: "predictable"})
+	manager.SetAgentWorking(true)
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	if manager.agentWaiting != nil {
+		t.Fatal("agent-waiting alert still armed after the agent started working")
+	}
+}
+
+func TestWaitingOnUser(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want bool
+	}{
+		{"Which branch should I use?\n", true},
+		{"I pushed the fix.\n\n[Awaiting input] before deploying.", true},
+		{"Done. Is anything else needed? I also ran the tests.", false},
+		{"Awaiting input from the API was the bug.\nFixed it.", false},
+		{"", false},
+	} {
+		if got := waitingOnUser(tc.text); got != tc.want {
+			t.Errorf("waitingOnUser(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestAgentDonePayloadTurnStats(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	ch := &recordingChannel{}
+	server.RegisterNotificationChannel(ch)
+	ctx := context.Background()
+
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, err := server.getOrCreateConversationManager(ctx, conv.ConversationID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.SetAgentWorking(true)
+	// An unpriced model falls back to the reported cost.
+	reply := llm.Message{Role: llm.MessageRoleAssistant, Content: []llm.Content{llm.StringContent("Done.")}, EndOfTurn: true}
+	if err := server.recordMessage(ctx, conv.ConversationID, reply, llm.Usage{Model: "unpriced", CostUSD: 0.25}); err != nil {
+		t.Fatal(err)
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(ch.events) == 0 {
+		t.Fatal("no end-of-turn notification")
+	}
+	p := ch.events[0].Payload.(notifications.AgentDonePayload)
+	if p.CostUSD != 0.25 || p.DurationSeconds <= 0 {
+		t.Fatalf("unexpected turn stats: cost %v, duration %v", p.CostUSD, p.DurationSeconds)
+	}
+}
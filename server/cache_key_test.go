@@ -22,7 +22,7 @@ func newCacheKeyTestServer(t *testing.T, requireHeader string) *Server {
 	t.Cleanup(cleanup)
 	ps := loop.NewPredictableService()
 	svr := NewServer(database, &testLLMManager{service: ps},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		true, "predictable", requireHeader)
 	return svr
@@ -76,8 +76,7 @@ func NewClaudeTestHarness(t *testing.T) *ClaudeTestHarness {
 
 	// Set up tools - bash for testing tool cancellation
 	toolSetConfig := claudetool.ToolSetConfig{
-		WorkingDir:    t.TempDir(),
-		EnableBrowser: false,
+		WorkingDir: t.TempDir(),
 	}
 
 	server := NewServer(database, llmManager, toolSetConfig, logger, true, "claude", "")
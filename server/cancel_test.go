@@ -47,7 +47,7 @@ func newTestServer(t *testing.T) (*Server, *db.DB, *loop.PredictableService) {
 	t.Cleanup(cleanup)
 	ps := loop.NewPredictableService()
 	svr := NewServer(database, &testLLMManager{service: ps},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		true, "predictable", "")
 	// Isolate tests from any user hooks installed on the dev machine
@@ -441,7 +441,7 @@ func TestRetryAfterLLMFailure(t *testing.T) {
 	switchable := &switchableTestLLM{inner: ps, err: fmt.Errorf("connection error: EOF")}
 
 	svr := NewServer(database, &testLLMManager{service: switchable},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		true, "predictable", "")
 	if svr.terminals != nil {
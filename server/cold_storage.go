@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/coldstore"
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+)
+
+// coldStorageBatch is how many conversations a sweep moves at a time.
+const coldStorageBatch = 100
+
+// coldBlob is what a frozen conversation's blob holds, gzipped.
+type coldBlob struct {
+	ConversationID string              `json:"conversation_id"`
+	Messages       []generated.Message `json:"messages"`
+}
+
+// SetColdStorage makes the server move the messages of conversations
+// archived for longer than after out of the database into store, and bring
+// them back when the conversation is opened.
+func (s *Server) SetColdStorage(store coldstore.Store, after time.Duration) {
+	s.coldStore = store
+	s.coldAfter = after
+}
+
+func coldStorageKey(conversationID string) string {
+	return "conversations/" + conversationID + ".json.gz"
+}
+
+// coldStorageRoutine freezes old archived conversations hourly.
+func (s *Server) coldStorageRoutine() {
+	if s.coldStore == nil {
+		return
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		n, err := s.freezeArchivedConversations(context.Background())
+		if err != nil {
+			s.logger.Error("Failed to move conversations to cold storage", "error", err)
+		}
+		if n > 0 {
+			s.logger.Info("Moved conversations to cold storage", "count", n)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.shutdownCh:
+			return
+		}
+	}
+}
+
+// freezeArchivedConversations moves every conversation that has been
+// archived for long enough to cold storage, returning how many it moved.
+func (s *Server) freezeArchivedConversations(ctx context.Context) (int, error) {
+	moved := 0
+	for {
+		ids, err := s.db.ColdStorageCandidates(ctx, s.coldAfter, coldStorageBatch)
+		if err != nil {
+			return moved, err
+		}
+		var errs []error
+		batchMoved := 0
+		for _, id := range ids {
+			ok, err := s.freezeConversation(ctx, id)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			}
+			if ok {
+				batchMoved++
+			}
+		}
+		moved += batchMoved
+		// Conversations that failed or were skipped would come up again,
+		// so stop after any batch that didn't move them all.
+		if len(ids) < coldStorageBatch || batchMoved < len(ids) {
+			return moved, errors.Join(errs...)
+		}
+	}
+}
+
+// freezeConversation moves a conversation's messages to cold storage,
+// reporting whether it did. Loaded conversations are left alone.
+func (s *Server) freezeConversation(ctx context.Context, conversationID string) (bool, error) {
+	s.coldMu.Lock()
+	defer s.coldMu.Unlock()
+	s.mu.Lock()
+	_, active := s.activeConversations[conversationID]
+	s.mu.Unlock()
+	if active {
+		return false, nil
+	}
+
+	messages, err := s.db.ListMessages(ctx, conversationID)
+	if err != nil {
+		return false, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(coldBlob{ConversationID: conversationID, Messages: messages}); err != nil {
+		return false, err
+	}
+	if err := zw.Close(); err != nil {
+		return false, err
+	}
+	key := coldStorageKey(conversationID)
+	if err := s.coldStore.Put(ctx, key, buf.Bytes()); err != nil {
+		return false, err
+	}
+	err = s.db.FreezeConversation(ctx, conversationID, key, int64(len(messages)), int64(buf.Len()))
+	if errors.Is(err, db.ErrConversationChanged) {
+		return false, s.coldStore.Delete(ctx, key)
+	}
+	if err != nil {
+		return false, err
+	}
+	s.logger.Debug("Moved conversation to cold storage", "conversationID", conversationID, "messages", len(messages), "bytes", buf.Len())
+	return true, nil
+}
+
+// thawConversation brings a frozen conversation's messages back into the
+// database. It does nothing for conversations that aren't frozen.
+func (s *Server) thawConversation(ctx context.Context, conversationID string) error {
+	if cold, err := s.db.FrozenConversation(ctx, conversationID); cold == nil {
+		return err
+	}
+	s.coldMu.Lock()
+	defer s.coldMu.Unlock()
+	// Another request may have thawed it while this one waited.
+	cold, err := s.db.FrozenConversation(ctx, conversationID)
+	if cold == nil {
+		return err
+	}
+	if s.coldStore == nil {
+		return fmt.Errorf("conversation %s is in cold storage, but cold_storage is not configured", conversationID)
+	}
+
+	data, err := s.coldStore.Get(ctx, cold.StorageKey)
+	if err != nil {
+		return err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", cold.StorageKey, err)
+	}
+	var blob coldBlob
+	if err := json.NewDecoder(io.LimitReader(zr, 1<<30)).Decode(&blob); err != nil {
+		return fmt.Errorf("%s: %w", cold.StorageKey, err)
+	}
+	if blob.ConversationID != conversationID || int64(len(blob.Messages)) != cold.MessageCount {
+		return fmt.Errorf("%s holds %d messages of conversation %s, not %d of %s", cold.StorageKey, len(blob.Messages), blob.ConversationID, cold.MessageCount, conversationID)
+	}
+	if err := s.db.ThawConversation(ctx, conversationID, blob.Messages); err != nil {
+		return err
+	}
+	if err := s.coldStore.Delete(ctx, cold.StorageKey); err != nil {
+		s.logger.Warn("Failed to delete thawed conversation from cold storage", "conversationID", conversationID, "key", cold.StorageKey, "error", err)
+	}
+	s.logger.Info("Restored conversation from cold storage", "conversationID", conversationID, "messages", len(blob.Messages))
+	return nil
+}
+
+// thawConversations thaws the conversation a /api/conversation/<id>/...
+// request is for before handling it. Deleting a frozen conversation only
+// deletes its blob.
+func (s *Server) thawConversations(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if action == "delete" {
+			if err := s.deleteColdConversation(r.Context(), id); err != nil {
+				s.logger.Warn("Failed to delete conversation from cold storage", "conversationID", id, "error", err)
+			}
+		} else if err := s.thawConversation(r.Context(), id); err != nil {
+			s.logger.Error("Failed to restore conversation from cold storage", "conversationID", id, "error", err)
+			http.Error(w, "Failed to restore conversation from cold storage", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// deleteColdConversation deletes a frozen conversation's blob.
+func (s *Server) deleteColdConversation(ctx context.Context, conversationID string) error {
+	cold, err := s.db.FrozenConversation(ctx, conversationID)
+	if cold == nil || s.coldStore == nil {
+		return err
+	}
+	return s.coldStore.Delete(ctx, cold.StorageKey)
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/coldstore"
+)
+
+func TestColdStorage(t *testing.T) {
+	h := NewTestHarness(t)
+	h.NewConversation("echo: pelican", t.TempDir())
+	h.WaitResponse()
+	ctx := context.Background()
+	id := h.ConversationID()
+	store := coldstore.Dir(t.TempDir())
+	h.server.SetColdStorage(store, 30*24*time.Hour)
+
+	if _, err := h.db.ArchiveConversation(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	stopActiveConversationLoops(h.server)
+	h.server.mu.Lock()
+	delete(h.server.activeConversations, id)
+	h.server.mu.Unlock()
+	if n, err := h.server.freezeArchivedConversations(ctx); err != nil || n != 0 {
+		t.Fatalf("froze a recently archived conversation: %d, %v", n, err)
+	}
+	if err := h.db.Pool().Exec(ctx, "UPDATE conversations SET updated_at = datetime('now', '-40 days') WHERE conversation_id = ?", id); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := h.server.freezeArchivedConversations(ctx); err != nil || n != 1 {
+		t.Fatalf("freezeArchivedConversations = %d, %v", n, err)
+	}
+	if msgs, _ := h.db.ListMessages(ctx, id); len(msgs) != 0 {
+		t.Fatalf("%d messages left in the database", len(msgs))
+	}
+	if _, err := store.Get(ctx, coldStorageKey(id)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening the conversation brings its messages back.
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/conversation/"+id, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "pelican") {
+		t.Fatalf("GET conversation: %d %s", w.Code, w.Body.String())
+	}
+	if _, err := store.Get(ctx, coldStorageKey(id)); !errors.Is(err, coldstore.ErrNotFound) {
+		t.Errorf("blob of a thawed conversation: err = %v", err)
+	}
+	if n, err := h.server.freezeArchivedConversations(ctx); err != nil || n != 0 {
+		t.Errorf("refroze a just-opened conversation: %d, %v", n, err)
+	}
+}
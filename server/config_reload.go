@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"shelley.exe.dev/claudetool"
+)
+
+// ConfigLoader re-reads the server's configuration file. It applies the
+// tool settings to tc, a copy of the server's tool set config, and returns
+// the default model.
+type ConfigLoader func(tc *claudetool.ToolSetConfig) (defaultModel string, err error)
+
+// SetConfigLoader configures how ReloadConfig re-reads the configuration.
+func (s *Server) SetConfigLoader(load ConfigLoader) {
+	s.loadConfig = load
+}
+
+// currentToolSetConfig returns the tool set config for new conversation
+// managers.
+func (s *Server) currentToolSetConfig() claudetool.ToolSetConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.toolSetConfig
+}
+
+// currentDefaultModel returns the configured default model.
+func (s *Server) currentDefaultModel() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.defaultModel
+}
+
+// ReloadConfig re-reads the configuration and applies it without
+// interrupting running conversations: it rebuilds the model catalog,
+// reloads notification channels, and updates the default model and tool
+// settings. Subagent budgets apply at once; other tool settings apply to
+// conversations loaded after the reload. MCP servers are not reconnected.
+func (s *Server) ReloadConfig(ctx context.Context) error {
+	if s.loadConfig == nil {
+		return errors.New("config reload is not configured")
+	}
+	tc := s.currentToolSetConfig()
+	defaultModel, err := s.loadConfig(&tc)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	applyToolSetDefaults(&tc)
+	if s.refreshBuiltModels != nil {
+		if err := s.refreshModels(ctx); err != nil {
+			return fmt.Errorf("failed to refresh models: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.toolSetConfig = tc
+	s.defaultModel = defaultModel
+	s.mu.Unlock()
+	s.ReloadNotificationChannels()
+	s.logger.Info("Reloaded configuration", "defaultModel", defaultModel)
+	return nil
+}
+
+// handleAdminReload handles POST /api/admin/reload, which reloads the
+// configuration like SIGHUP does.
+func (s *Server) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	if err := s.ReloadConfig(r.Context()); err != nil {
+		s.logger.Error("Failed to reload configuration", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "reloaded"})
+}
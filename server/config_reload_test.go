@@ -0,0 +1,53 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shelley.exe.dev/claudetool"
+)
+
+func TestAdminReload(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	server.SetAdminToken("secret")
+	reload := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/admin/reload", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := reload(); w.Code != http.StatusInternalServerError {
+		t.Fatalf("reload without a loader: got %d, want 500", w.Code)
+	}
+
+	loadErr := errors.New("bad config")
+	server.SetConfigLoader(func(tc *claudetool.ToolSetConfig) (string, error) {
+		return "", loadErr
+	})
+	if w := reload(); w.Code != http.StatusInternalServerError {
+		t.Fatalf("reload of a bad config: got %d, want 500", w.Code)
+	}
+
+	server.SetConfigLoader(func(tc *claudetool.ToolSetConfig) (string, error) {
+		tc.SubagentBudget = claudetool.SubagentBudget{MaxTokens: 1000}
+		tc.MaxSubagentDepth = 0
+		return "predictable", nil
+	})
+	if w := reload(); w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	tc := server.currentToolSetConfig()
+	if tc.SubagentBudget.MaxTokens != 1000 || tc.MaxSubagentDepth != 1 || tc.SubagentRunner == nil {
+		t.Errorf("unexpected tool set config after reload: budget %+v, depth %d", tc.SubagentBudget, tc.MaxSubagentDepth)
+	}
+	if got := server.currentDefaultModel(); got != "predictable" {
+		t.Errorf("default model = %q, want predictable", got)
+	}
+}
@@ -100,7 +100,7 @@ func TestContinueAfterRefusalSwitchesModelAndResumes(t *testing.T) {
 	t.Cleanup(cleanup)
 	svc := &refuseThenOKService{inner: loop.NewPredictableService()}
 	svr := NewServer(database, &twoModelLLMManager{service: svc},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		false, "model-a", "")
 	svr.hooksDir = t.TempDir()
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db"
+)
+
+func TestConversationInstructions(t *testing.T) {
+	t.Parallel()
+	server, database, ps := newTestServer(t)
+	ctx := context.Background()
+	conversation, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{ThinkingLevel: "high"})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	id := conversation.ConversationID
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/conversation/"+id+path, strings.NewReader(body)))
+		return w
+	}
+	if w := post("/instructions", `{"instructions":"`+strings.Repeat("x", maxInstructionsBytes+1)+`"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("oversized instructions: got %d, want 400", w.Code)
+	}
+	if w := post("/instructions", `{"instructions":"Never edit the migrations directory."}`); w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+
+	conv, err := database.GetConversationByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := db.ParseConversationOptions(conv.ConversationOptions)
+	if opts.Instructions != "Never edit the migrations directory." || opts.ThinkingLevel != "high" {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	if w := post("/chat", `{"message":"echo: hi","model":"predictable"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("chat: got %d: %s", w.Code, w.Body)
+	}
+	// The conversation's other model requests, such as for its title, have
+	// their own system prompts; look for the agent's.
+	want := formatInstructions("Never edit the migrations directory.")
+	waitFor(t, 5*time.Second, func() bool {
+		for _, req := range ps.GetRecentRequests() {
+			for _, s := range req.System {
+				if strings.Contains(s.Text, want) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
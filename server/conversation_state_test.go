@@ -130,7 +130,7 @@ func TestConversationStateAfterServerRestart(t *testing.T) {
 	// Create a NEW server (simulating server restart - no active managers)
 	ps := loop.NewPredictableService()
 	server := NewServer(database, &testLLMManager{service: ps},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		true, "predictable", "")
 
@@ -240,7 +240,7 @@ func TestModelRestorationAfterServerRestart(t *testing.T) {
 	// Create a NEW server (simulating server restart - no active managers)
 	ps := loop.NewPredictableService()
 	server := NewServer(database, &testLLMManager{service: ps},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		true, "predictable", "")
 
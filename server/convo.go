@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/google/uuid"
 	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/claudetool/onstart"
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/db/generated"
 	"shelley.exe.dev/gitstate"
@@ -61,6 +63,12 @@ type pendingBatch struct {
 	// value can't be read from the request there). Empty for other kinds and
 	// for requests without the X-ExeDev-Email header.
 	UserEmail string
+	// HandoffFrom is the conversation that posted a queued user message via
+	// handoff (Kind=pendingBatchUser). Empty otherwise.
+	HandoffFrom string
+	// QueuedAt is when a queued user message (Kind=pendingBatchUser) was
+	// queued, for the queue_wait_ms in its row's user_data.
+	QueuedAt time.Time
 	// SubagentConversationID is set only for Kind=pendingBatchSubagentDone.
 	// It identifies the child subagent whose completion this batch notifies
 	// the parent about. Used to coalesce stale notifications: if a subagent
@@ -89,10 +97,13 @@ type ConversationManager struct {
 	// (see Server.recordTurnStartMessage). Falls back to recordMessage when nil.
 	recordTurnStartMessage loop.MessageRecordFunc
 	logger                 *slog.Logger
+	debugLog               *debugLog // everything logger records, for GET /debug
 	toolSetConfig          claudetool.ToolSetConfig
 	toolSet                *claudetool.ToolSet // created per-conversation when loop starts
 
-	subpub *subpub.SubPub[StreamResponse]
+	// conversationPub carries events to legacy per-conversation stream
+	// subscribers, under the manager's ConversationID.
+	conversationPub *subpub.Topics[string, StreamResponse]
 	// streamPub mirrors per-conversation events to the server-wide /api/stream2
 	// subscribers. Each event is tagged with the manager's ConversationID by
 	// the publish helpers below before fan-out.
@@ -117,11 +128,17 @@ type ConversationManager struct {
 	cwd                   string // working directory for tools
 	userEmail             string // exe.dev auth email, from X-ExeDev-Email header
 	serverPort            int    // TCP port the shelley server listens on, for SHELLEY_PORT/SHELLEY_URL
+	workers               *workerHub
 	slug                  string // conversation slug, for SHELLEY_CONVERSATION_SLUG
+	hooksDir              string // user hooks directory, whose TrustedReposFile gates project configs
 
 	// agentWorking tracks whether the agent is currently working.
 	// This is explicitly managed and broadcast to subscribers when it changes.
 	agentWorking bool
+	// status is what the conversation is doing, finer-grained than
+	// agentWorking; "" until the first change this manager sees. Guarded by
+	// mu.
+	status ConversationStatus
 
 	// distilling is true while a distillation goroutine is inserting content
 	// into this conversation. When true, queued messages should NOT be drained
@@ -203,15 +220,37 @@ type ConversationManager struct {
 	// is not a completion, so we suppress onDone for its working→idle
 	// transition. Guarded by cm.mu.
 	cancelling bool
+
+	// toolFailureStreak counts consecutive failed tool calls (see
+	// trackToolFailures). Guarded by mu.
+	toolFailureStreak int
+	// agentWaiting fires EventAgentWaiting after a turn that ended waiting
+	// on the user; the next turn stops it. Guarded by mu.
+	agentWaiting *time.Timer
+	// turnStart, turnCostUSD, turnUsage and turnFiles describe the current
+	// (or last) turn for its end-of-turn notification and TurnCompleteEvent.
+	// Guarded by mu.
+	turnStart   time.Time
+	turnCostUSD float64
+	turnUsage   llm.Usage
+	turnFiles   []string
+	// compact is offered requests over the context limit; see
+	// loop.Config.OnContextFull.
+	compact func(notice llm.Message) bool
+	// compactedWhenFull records that the conversation was compacted for a
+	// request over the context limit since the last user message, so that
+	// a request still over it ends the turn instead. Guarded by mu.
+	compactedWhenFull bool
 }
 
 // NewConversationManager constructs a manager with dependencies but defers hydration until needed.
-func NewConversationManager(conversationID string, database *db.DB, baseLogger *slog.Logger, toolSetConfig claudetool.ToolSetConfig, recordMessage, recordTurnStartMessage loop.MessageRecordFunc, onStateChange func(ConversationState), streamPub *subpub.SubPub[StreamResponse]) *ConversationManager {
+func NewConversationManager(conversationID string, database *db.DB, baseLogger *slog.Logger, toolSetConfig claudetool.ToolSetConfig, recordMessage, recordTurnStartMessage loop.MessageRecordFunc, onStateChange func(ConversationState), streamPub *subpub.SubPub[StreamResponse], conversationPub *subpub.Topics[string, StreamResponse]) *ConversationManager {
 	logger := baseLogger
 	if logger == nil {
 		logger = slog.Default()
 	}
-	logger = logger.With("conversationID", conversationID)
+	log := &debugLog{}
+	logger = slog.New(&debugLogHandler{next: logger.With("conversationID", conversationID).Handler(), log: log})
 
 	return &ConversationManager{
 		conversationID:         conversationID,
@@ -220,31 +259,32 @@ func NewConversationManager(conversationID string, database *db.DB, baseLogger *
 		recordMessage:          recordMessage,
 		recordTurnStartMessage: recordTurnStartMessage,
 		logger:                 logger,
+		debugLog:               log,
 		toolSetConfig:          toolSetConfig,
-		subpub:                 subpub.New[StreamResponse](),
+		conversationPub:        conversationPub,
 		streamPub:              streamPub,
 		onStateChange:          onStateChange,
 	}
 }
 
 // broadcastStream tags data with the conversation ID and fans it out to both
-// the per-conversation subpub (used by the legacy /api/conversation/<id>/stream
+// the conversation's topic (used by the legacy /api/conversation/<id>/stream
 // endpoint) and the server-wide stream (used by /api/stream2).
 func (cm *ConversationManager) broadcastStream(data StreamResponse) {
 	data.ConversationID = cm.conversationID
-	cm.subpub.Broadcast(data)
+	cm.conversationPub.Broadcast(cm.conversationID, data)
 	if cm.streamPub != nil {
 		cm.streamPub.Broadcast(data)
 	}
 }
 
 // publishStream tags data with the conversation ID and publishes to the
-// per-conversation subpub at the given sequence id, also broadcasting to the
+// conversation's topic at the given sequence id, also broadcasting to the
 // server-wide stream. Sequence ids are per-conversation and meaningless on
 // the global stream, so we Broadcast rather than Publish there.
 func (cm *ConversationManager) publishStream(seqID int64, data StreamResponse) {
 	data.ConversationID = cm.conversationID
-	cm.subpub.Publish(seqID, data)
+	cm.conversationPub.Publish(cm.conversationID, seqID, data)
 	if cm.streamPub != nil {
 		cm.streamPub.Broadcast(data)
 	}
@@ -265,6 +305,38 @@ func (cm *ConversationManager) RegisterEndOfTurnHook(ctx context.Context, hook d
 	return nil
 }
 
+// SetNotifications subscribes the conversation to notifications, mutes it, or
+// (with "") returns it to the server-wide default.
+func (cm *ConversationManager) SetNotifications(ctx context.Context, notifications string) error {
+	if err := cm.Hydrate(ctx); err != nil {
+		return err
+	}
+	opts, err := cm.db.SetConversationNotifications(ctx, cm.conversationID, notifications)
+	if err != nil {
+		return err
+	}
+	cm.mu.Lock()
+	cm.conversationOptions = opts
+	cm.mu.Unlock()
+	return nil
+}
+
+// SetInstructions replaces the conversation's instructions, which apply from
+// the next LLM request on, even mid-turn. "" removes them.
+func (cm *ConversationManager) SetInstructions(ctx context.Context, instructions string) error {
+	if err := cm.Hydrate(ctx); err != nil {
+		return err
+	}
+	opts, err := cm.db.SetConversationInstructions(ctx, cm.conversationID, instructions)
+	if err != nil {
+		return err
+	}
+	cm.mu.Lock()
+	cm.conversationOptions = opts
+	cm.mu.Unlock()
+	return nil
+}
+
 // SetThinkingLevel updates the conversation's reasoning/thinking level. It
 // persists the new level to the conversation's stored options and, if a loop
 // is already running, updates it live so the next turn uses the new level.
@@ -334,27 +406,44 @@ func (cm *ConversationManager) EndOfTurnHooks(ctx context.Context) ([]db.Convers
 // SetAgentWorking updates the agent working state, persists it to the
 // conversations table (so the conversation list patch stream picks it up via
 // the standard Pool.OnCommit hook), and notifies the server to broadcast.
+// The status becomes thinking or idle.
 func (cm *ConversationManager) SetAgentWorking(working bool) {
-	cm.setAgentWorking(working, true)
+	status := StatusIdle
+	if working {
+		status = StatusThinking
+	}
+	cm.setAgentWorking(working, status, true)
 }
 
-// syncAgentWorking flips the in-memory flag and fires the same notifications as
-// SetAgentWorking but WITHOUT writing conversations.agent_working. Use it when
-// the persisted value has already been written in another transaction — e.g.
-// folded into a message INSERT via CreateMessageParams.MarkAgentStart/
-// MarkAgentDone — so we don't pay a second commit (and a second full
-// conversation-list recompute) just to re-write a value the DB already holds.
-func (cm *ConversationManager) syncAgentWorking(working bool) {
-	cm.setAgentWorking(working, false)
+// syncAgentWorking flips the in-memory flag and status and fires the same
+// notifications as SetAgentWorking but WITHOUT writing
+// conversations.agent_working or the status. Use it when the persisted values
+// have already been written in another transaction — e.g. folded into a
+// message INSERT via CreateMessageParams.MarkAgentStart/MarkAgentDone and
+// Status — so we don't pay a second commit (and a second full
+// conversation-list recompute) just to re-write values the DB already holds.
+func (cm *ConversationManager) syncAgentWorking(working bool, status ConversationStatus) {
+	cm.setAgentWorking(working, status, false)
 }
 
-func (cm *ConversationManager) setAgentWorking(working, persist bool) {
+func (cm *ConversationManager) setAgentWorking(working bool, status ConversationStatus, persist bool) {
 	cm.mu.Lock()
 	if cm.agentWorking == working {
 		cm.mu.Unlock()
 		return
 	}
 	cm.agentWorking = working
+	cm.status = status
+	if working {
+		cm.turnStart = time.Now()
+		cm.turnCostUSD = 0
+		cm.turnUsage = llm.Usage{}
+		cm.turnFiles = nil
+		if cm.agentWaiting != nil {
+			cm.agentWaiting.Stop()
+			cm.agentWaiting = nil
+		}
+	}
 	onStateChange := cm.onStateChange
 	onDone := cm.onDone
 	convID := cm.conversationID
@@ -376,17 +465,21 @@ func (cm *ConversationManager) setAgentWorking(working, persist bool) {
 	}
 	cm.mu.Unlock()
 
-	cm.logger.Debug("agent working state changed", "working", working, "persist", persist)
+	cm.logger.Debug("agent working state changed", "working", working, "status", status, "persist", persist)
 	if persist {
 		if err := cm.db.SetConversationAgentWorking(context.Background(), convID, working); err != nil {
 			cm.logger.Error("failed to persist agent working state", "error", err, "working", working)
 		}
+		if err := cm.db.RecordConversationStatus(context.Background(), convID, string(status)); err != nil {
+			cm.logger.Error("failed to persist conversation status", "error", err, "status", status)
+		}
 	}
 	if onStateChange != nil {
 		onStateChange(ConversationState{
 			ConversationID: convID,
 			Working:        working,
 			Model:          modelID,
+			Status:         status,
 		})
 	}
 	if !working && onDone != nil && !suppressDone {
@@ -394,6 +487,46 @@ func (cm *ConversationManager) setAgentWorking(working, persist bool) {
 	}
 }
 
+// Status returns what the conversation is doing, or "" if this manager has
+// seen no change yet.
+func (cm *ConversationManager) Status() ConversationStatus {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.status
+}
+
+// SetStatus records and broadcasts a status change that doesn't start or
+// end a turn, such as queued or archived.
+func (cm *ConversationManager) SetStatus(status ConversationStatus) {
+	if !cm.syncStatus(status) {
+		return
+	}
+	if err := cm.db.RecordConversationStatus(context.Background(), cm.conversationID, string(status)); err != nil {
+		cm.logger.Error("failed to persist conversation status", "error", err, "status", status)
+	}
+}
+
+// syncStatus is SetStatus for a status that was already persisted, e.g.
+// with a message via CreateMessageParams.Status. It reports whether the
+// status changed.
+func (cm *ConversationManager) syncStatus(status ConversationStatus) bool {
+	cm.mu.Lock()
+	if status == "" || cm.status == status {
+		cm.mu.Unlock()
+		return false
+	}
+	cm.status = status
+	state := ConversationState{
+		ConversationID: cm.conversationID,
+		Working:        cm.agentWorking,
+		Model:          cm.modelID,
+		Status:         status,
+	}
+	cm.mu.Unlock()
+	cm.broadcastStream(StreamResponse{ConversationState: &state})
+	return true
+}
+
 // registerSubagentWaiter marks that a synchronous (wait=true) subagent tool
 // call is in flight against this (subagent) conversation. While at least one
 // waiter is registered, SetAgentWorking suppresses the async onDone
@@ -576,6 +709,14 @@ func (cm *ConversationManager) Hydrate(ctx context.Context) error {
 	// This is also set in ensureLoop, but must be set here for Hydrate's system prompt creation.
 	cm.toolSetConfig.ParentConversationID = cm.conversationID
 
+	// The subagent tool is only offered above MaxSubagentDepth, counted
+	// from the top-level conversation.
+	ancestry, err := cm.db.GetConversationAncestry(ctx, cm.conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversation ancestry: %w", err)
+	}
+	cm.toolSetConfig.SubagentDepth = int(ancestry.Depth)
+
 	// Generate system prompt if missing:
 	// - For user-initiated conversations: full system prompt
 	// - For subagent conversations (has parent): minimal subagent prompt
@@ -611,6 +752,8 @@ func (cm *ConversationManager) Hydrate(ctx context.Context) error {
 		msg   llm.Message
 		mdl   string
 		email string
+		from  string
+		at    time.Time
 	}
 	var restored []restoredQueued
 	for _, qm := range db.ParseQueuedMessages(conversation.QueuedMessages) {
@@ -619,7 +762,7 @@ func (cm *ConversationManager) Hydrate(ctx context.Context) error {
 			cm.logger.Error("Failed to parse persisted queued message; dropping", "queued_id", qm.ID, "error", err)
 			continue
 		}
-		restored = append(restored, restoredQueued{id: qm.ID, msg: msg, mdl: qm.Model, email: qm.UserEmail})
+		restored = append(restored, restoredQueued{id: qm.ID, msg: msg, mdl: qm.Model, email: qm.UserEmail, from: qm.HandoffFrom, at: qm.CreatedAt})
 	}
 
 	cm.mu.Lock()
@@ -648,11 +791,13 @@ func (cm *ConversationManager) Hydrate(ctx context.Context) error {
 			continue
 		}
 		restoredBatches = append(restoredBatches, pendingBatch{
-			Kind:       pendingBatchUser,
-			Messages:   []llm.Message{r.msg},
-			ModelID:    r.mdl,
-			MessageIDs: []string{r.id},
-			UserEmail:  r.email,
+			Kind:        pendingBatchUser,
+			Messages:    []llm.Message{r.msg},
+			ModelID:     r.mdl,
+			MessageIDs:  []string{r.id},
+			UserEmail:   r.email,
+			HandoffFrom: r.from,
+			QueuedAt:    r.at,
 		})
 	}
 	if len(restoredBatches) > 0 {
@@ -690,7 +835,9 @@ func (cm *ConversationManager) AcceptUserMessage(ctx context.Context, service ll
 	cm.mu.Lock()
 	isFirst := !cm.hasConversationEvents
 	cm.hasConversationEvents = true
+	cm.compactedWhenFull = false
 	loopInstance := cm.loop
+	toolSet := cm.toolSet
 	cm.lastActivity = time.Now()
 	recordMessage := cm.recordMessage
 	recordTurnStart := cm.recordTurnStartMessage
@@ -708,7 +855,7 @@ func (cm *ConversationManager) AcceptUserMessage(ctx context.Context, service ll
 	// and no separate working-flip commit. syncAgentWorking does the in-memory
 	// flip + broadcast without its own DB write.
 	if recordTurnStart != nil {
-		cm.syncAgentWorking(true)
+		cm.syncAgentWorking(true, StatusThinking)
 		if err := recordTurnStart(ctx, message, llm.Usage{}); err != nil {
 			cm.logger.Error("failed to record user message immediately", "error", err)
 			// Continue anyway - the loop will also try to record it.
@@ -728,6 +875,10 @@ func (cm *ConversationManager) AcceptUserMessage(ctx context.Context, service ll
 		}
 	}
 
+	if toolSet != nil {
+		toolSet.ClearCache()
+	}
+	loopInstance.TraceTurn(ctx)
 	loopInstance.QueueUserMessage(message)
 
 	return isFirst, nil
@@ -910,11 +1061,12 @@ func (cm *ConversationManager) QueueMessage(ctx context.Context, s *Server, mode
 		return fmt.Errorf("failed to marshal queued message: %w", err)
 	}
 	qm := db.QueuedMessage{
-		ID:        uuid.New().String(),
-		Llm:       llmJSON,
-		CreatedAt: time.Now().UTC(),
-		Model:     modelID,
-		UserEmail: userEmailFromContext(ctx),
+		ID:          uuid.New().String(),
+		Llm:         llmJSON,
+		CreatedAt:   time.Now().UTC(),
+		Model:       modelID,
+		UserEmail:   userEmailFromContext(ctx),
+		HandoffFrom: handoffFromContext(ctx),
 	}
 	if _, err := s.db.AppendQueuedMessage(ctx, cm.conversationID, qm); err != nil {
 		return fmt.Errorf("failed to append queued message: %w", err)
@@ -927,11 +1079,13 @@ func (cm *ConversationManager) QueueMessage(ctx context.Context, s *Server, mode
 
 	cm.logger.Info("Queued user message", "queued_id", qm.ID)
 	cm.enqueueBatch(s, pendingBatch{
-		Kind:       pendingBatchUser,
-		Messages:   []llm.Message{message},
-		ModelID:    modelID,
-		MessageIDs: []string{qm.ID},
-		UserEmail:  qm.UserEmail,
+		Kind:        pendingBatchUser,
+		Messages:    []llm.Message{message},
+		ModelID:     modelID,
+		MessageIDs:  []string{qm.ID},
+		UserEmail:   qm.UserEmail,
+		HandoffFrom: qm.HandoffFrom,
+		QueuedAt:    qm.CreatedAt,
 	})
 	return nil
 }
@@ -990,11 +1144,15 @@ func (cm *ConversationManager) enqueueBatch(s *Server, b pendingBatch) {
 	cm.pendingBatches = append(cm.pendingBatches, b)
 	cm.lastActivity = time.Now()
 	needsDrain := !cm.agentWorking && !cm.distilling
+	queued := !cm.agentWorking && cm.distilling
 	cm.mu.Unlock()
 
 	if needsDrain {
 		go cm.drainPendingMessages(s)
 	}
+	if queued {
+		cm.SetStatus(StatusQueued)
+	}
 }
 
 // CancelQueuedMessages removes all pending queued *user* messages: it drops
@@ -1014,7 +1172,11 @@ func (cm *ConversationManager) CancelQueuedMessages(ctx context.Context, s *Serv
 		}
 	}
 	cm.pendingBatches = keep
+	unqueued := len(keep) == 0 && cm.status == StatusQueued
 	cm.mu.Unlock()
+	if unqueued {
+		cm.SetStatus(StatusIdle)
+	}
 
 	// Clear the persistent array regardless of the in-memory count so a
 	// restart-orphaned queue (array populated but no in-memory batches) can
@@ -1050,7 +1212,11 @@ func (cm *ConversationManager) CancelQueuedMessage(ctx context.Context, s *Serve
 		keep = append(keep, b)
 	}
 	cm.pendingBatches = keep
+	unqueued := len(keep) == 0 && cm.status == StatusQueued
 	cm.mu.Unlock()
+	if unqueued {
+		cm.SetStatus(StatusIdle)
+	}
 
 	if _, err := s.db.RemoveQueuedMessages(ctx, cm.conversationID, queuedID); err != nil {
 		cm.logger.Error("Failed to remove queued message", "queued_id", queuedID, "error", err)
@@ -1082,7 +1248,7 @@ func (cm *ConversationManager) processBatch(ctx context.Context, s *Server, loop
 			if i < len(b.MessageIDs) {
 				queuedID = b.MessageIDs[i]
 			}
-			if err := s.recordDrainedQueuedMessage(ctx, cm.conversationID, queuedID, msg, b.UserEmail); err != nil {
+			if err := s.recordDrainedQueuedMessage(ctx, cm.conversationID, queuedID, msg, b.UserEmail, b.HandoffFrom, b.QueuedAt); err != nil {
 				cm.logger.Error("Failed to record drained queued message; will retry", "error", err)
 				return false
 			}
@@ -1090,6 +1256,12 @@ func (cm *ConversationManager) processBatch(ctx context.Context, s *Server, loop
 		// notifySubscribersNewMessage (fired by recordDrainedQueuedMessage)
 		// already carried the cleaned array, so the ghost clears live; no extra
 		// broadcast needed.
+		cm.mu.Lock()
+		toolSet := cm.toolSet
+		cm.mu.Unlock()
+		if toolSet != nil {
+			toolSet.ClearCache()
+		}
 		loopInstance.QueueMessages(b.Messages...)
 		return true
 	case pendingBatchSubagentDone:
@@ -1315,7 +1487,7 @@ func (cm *ConversationManager) recordWarning(ctx context.Context, text string) e
 	if result.Suppressed {
 		return nil
 	}
-	cm.subpub.Publish(result.Message.SequenceID, StreamResponse{
+	cm.conversationPub.Publish(cm.conversationID, result.Message.SequenceID, StreamResponse{
 		Messages:     toAPIMessages([]generated.Message{*result.Message}),
 		Conversation: &result.Conversation,
 	})
@@ -1329,6 +1501,41 @@ func (cm *ConversationManager) Touch() {
 	cm.mu.Unlock()
 }
 
+// addToTurn adds a recorded message's usage, and the files its tool results
+// patched, to the current turn.
+func (cm *ConversationManager) addToTurn(msg llm.Message, usage llm.Usage) {
+	cost := usageCostUSD(usage)
+	files := patchedFiles(msg)
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.turnCostUSD += cost
+	cm.turnUsage.Add(usage)
+	for _, f := range files {
+		if !slices.Contains(cm.turnFiles, f) {
+			cm.turnFiles = append(cm.turnFiles, f)
+		}
+	}
+}
+
+// turnStats returns how long the current turn has run and what it has cost.
+// The duration is zero if the turn started before the manager was created.
+func (cm *ConversationManager) turnStats() (time.Duration, float64) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if cm.turnStart.IsZero() {
+		return 0, cm.turnCostUSD
+	}
+	return time.Since(cm.turnStart), cm.turnCostUSD
+}
+
+// turnChanges returns the current turn's token usage and the files it
+// patched.
+func (cm *ConversationManager) turnChanges() (llm.Usage, []string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.turnUsage, slices.Clone(cm.turnFiles)
+}
+
 func hasSystemMessage(messages []generated.Message) bool {
 	for _, msg := range messages {
 		if msg.Type == string(db.MessageTypeSystem) {
@@ -1348,14 +1555,39 @@ func hasNonSystemMessages(messages []generated.Message) bool {
 }
 
 func (cm *ConversationManager) createSystemPrompt(ctx context.Context) (*generated.Message, error) {
-	var opts []SystemPromptOption
+	cfg, project, err := cm.systemPromptToolSetConfig()
+	if err != nil {
+		return nil, err
+	}
+	ts := claudetool.NewToolSet(context.Background(), cfg)
+	defer ts.Cleanup()
+	tools := ts.Tools()
+
+	opts := []SystemPromptOption{WithTools(tools)}
 	if cm.userEmail != "" {
 		opts = append(opts, WithUserEmail(cm.userEmail))
 	}
+	if cfg.VerifyCommands {
+		opts = append(opts, WithVerifiedCommands())
+	}
+	if cm.db != nil {
+		opts = append(opts, WithAnalysisCache(cm.db))
+	}
+	if cfg.AnalysisSampleThreshold > 0 {
+		opts = append(opts, WithAnalysisLimits(onstart.Limits{SampleThreshold: cfg.AnalysisSampleThreshold}))
+	}
+	budget := GuidanceBudget{FileBytes: cfg.GuidanceFileBytes, TotalBytes: cfg.GuidanceTotalBytes}
+	if cfg.SummarizeGuidance && cfg.LLMProvider != nil {
+		budget.Summarize = LLMGuidanceSummarizer(cfg.LLMProvider)
+	}
+	opts = append(opts, WithGuidanceBudget(budget))
+	opts = append(opts, WithGuidanceNames(GuidanceNames{Add: cfg.GuidanceFileNames, Exclude: cfg.ExcludedGuidanceFileNames}))
 	systemPrompt, err := GenerateSystemPrompt(cm.cwd, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate system prompt: %w", err)
 	}
+	systemPrompt += project.runStartupHook(ctx, cfg)
+	systemPrompt += formatHookContext(cm.conversationOptions.HookContext)
 
 	if systemPrompt == "" {
 		cm.logger.Info("Skipping empty system prompt generation")
@@ -1372,7 +1604,7 @@ func (cm *ConversationManager) createSystemPrompt(ctx context.Context) (*generat
 		Type:           db.MessageTypeSystem,
 		LLMData:        systemMessage,
 		UsageData:      llm.Usage{},
-		DisplayData:    cm.systemPromptDisplayData(),
+		DisplayData:    toolDisplayData(tools),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to store system prompt: %w", err)
@@ -1418,24 +1650,62 @@ func systemPromptDisplayData(cfg claudetool.ToolSetConfig) map[string]any {
 	return toolDisplayData(ts.Tools())
 }
 
-func (cm *ConversationManager) systemPromptDisplayData() map[string]any {
+func (cm *ConversationManager) systemPromptDisplayData() (map[string]any, error) {
+	cfg, _, err := cm.systemPromptToolSetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return systemPromptDisplayData(cfg), nil
+}
+
+// systemPromptToolSetConfig returns the tool set config with the
+// conversation's tool options and its project's config applied, and the
+// project's config.
+func (cm *ConversationManager) systemPromptToolSetConfig() (claudetool.ToolSetConfig, ProjectConfig, error) {
 	cfg := cm.toolSetConfig
-	cfg.ToolOverrides = cm.conversationOptions.ToolOverrides
-	cfg.DisableAllTools = cm.conversationOptions.DisableAllTools
-	return systemPromptDisplayData(cfg)
+	applyToolOptions(&cfg, cm.conversationOptions)
+	project, err := loadProjectConfig(cm.hooksDir, cm.cwd)
+	if err != nil {
+		return cfg, project, err
+	}
+	project.applyTools(&cfg, cm.conversationOptions)
+	return cfg, project, nil
+}
+
+// applyToolOptions selects cfg's tools from a conversation's options. A
+// subagent persona with a tool list limits the tools to that list.
+func applyToolOptions(cfg *claudetool.ToolSetConfig, opts db.ConversationOptions) {
+	cfg.ToolOverrides = opts.ToolOverrides
+	cfg.DisableAllTools = opts.DisableAllTools
+	if p := cfg.SubagentPersonas[opts.Persona]; len(p.Tools) > 0 {
+		overrides := make(map[string]string, len(p.Tools))
+		for _, name := range p.Tools {
+			overrides[name] = "on"
+		}
+		cfg.ToolOverrides = overrides
+		cfg.DisableAllTools = true
+	}
 }
 
 func (cm *ConversationManager) createSubagentSystemPrompt(ctx context.Context, parentConversationID string) (*generated.Message, error) {
-	systemPrompt, err := GenerateSubagentSystemPrompt(cm.cwd, parentConversationID)
+	persona := cm.toolSetConfig.SubagentPersonas[cm.conversationOptions.Persona]
+	systemPrompt, err := GenerateSubagentSystemPrompt(cm.cwd, parentConversationID, persona.SystemPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate subagent system prompt: %w", err)
 	}
+	if systemPrompt != "" {
+		systemPrompt += formatHookContext(cm.conversationOptions.HookContext)
+	}
 
 	if systemPrompt == "" {
 		cm.logger.Info("Skipping empty subagent system prompt generation")
 		return nil, nil
 	}
 
+	displayData, err := cm.systemPromptDisplayData()
+	if err != nil {
+		return nil, err
+	}
 	systemMessage := llm.Message{
 		Role:    llm.MessageRoleUser,
 		Content: []llm.Content{{Type: llm.ContentTypeText, Text: systemPrompt}},
@@ -1446,7 +1716,7 @@ func (cm *ConversationManager) createSubagentSystemPrompt(ctx context.Context, p
 		Type:           db.MessageTypeSystem,
 		LLMData:        systemMessage,
 		UsageData:      llm.Usage{},
-		DisplayData:    cm.systemPromptDisplayData(),
+		DisplayData:    displayData,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to store subagent system prompt: %w", err)
@@ -1456,6 +1726,12 @@ func (cm *ConversationManager) createSubagentSystemPrompt(ctx context.Context, p
 	return created, nil
 }
 
+// formatInstructions wraps a conversation's instructions for the system prompt.
+func formatInstructions(instructions string) string {
+	return "<conversation_instructions>\nThe user's instructions for this conversation, which take precedence over general guidance:\n" +
+		strings.TrimRight(instructions, "\n") + "\n</conversation_instructions>"
+}
+
 func (cm *ConversationManager) partitionMessages(messages []generated.Message) ([]llm.Message, []llm.SystemContent) {
 	var history []llm.Message
 	var system []llm.SystemContent
@@ -1609,13 +1885,29 @@ func (cm *ConversationManager) ensureLoop(service llm.Service, modelID string) e
 	baseCtx := llmhttp.WithConversationID(context.Background(), conversationID)
 	processCtx, cancel := context.WithTimeout(baseCtx, 12*time.Hour)
 
-	toolSetConfig.ToolOverrides = conversationOpts.ToolOverrides
-	toolSetConfig.DisableAllTools = conversationOpts.DisableAllTools
+	applyToolOptions(&toolSetConfig, conversationOpts)
+	project, err := loadProjectConfig(cm.hooksDir, cwd)
+	if err != nil {
+		cancel()
+		return err
+	}
+	project.applyTools(&toolSetConfig, conversationOpts)
 	toolSetConfig.ReasoningLevel = conversationOpts.ThinkingLevel
+	policies := make(map[string]claudetool.ToolPolicy, len(conversationOpts.ToolPolicies))
+	for name, p := range conversationOpts.ToolPolicies {
+		policies[name] = claudetool.ToolPolicy(p)
+	}
+	toolSetConfig.ToolPolicies = claudetool.MergeToolPolicies(toolSetConfig.ToolPolicies, policies)
+	if conversationOpts.Worker != "" {
+		toolSetConfig.Remote = &workerRemote{hub: cm.workers, name: conversationOpts.Worker}
+	}
+	if ws := conversationWorkspace(conversationOpts); ws != nil {
+		toolSetConfig.Remote = ws
+	}
 	toolSet := claudetool.NewToolSet(processCtx, toolSetConfig)
 
 	// streamFlusher batches LLM stream deltas and flushes them periodically
-	// to avoid overwhelming the subpub channel (buffer=10) with hundreds
+	// to avoid overwhelming the subpub buffer with hundreds
 	// of individual deltas per second from the Anthropic SSE stream.
 	sf := newStreamFlusher(cm, 50*time.Millisecond)
 
@@ -1640,8 +1932,28 @@ func (cm *ConversationManager) ensureLoop(service llm.Service, modelID string) e
 				ToolProgress: &progress,
 			})
 		},
-		OnStreamDelta: sf.Push,
-		OnStreamDone:  sf.Flush,
+		OnStreamDelta:   sf.Push,
+		OnStreamDone:    sf.Flush,
+		ToolParallelism: toolSetConfig.ToolParallelism,
+		ContextLimit:    toolSetConfig.ContextLimit,
+		OnContextFull:   cm.compact,
+		ExtraSystem: func(ctx context.Context) ([]llm.SystemContent, error) {
+			var extra []llm.SystemContent
+			cm.mu.Lock()
+			instructions := cm.conversationOptions.Instructions
+			cm.mu.Unlock()
+			if instructions != "" {
+				extra = append(extra, llm.SystemContent{Type: "text", Text: formatInstructions(instructions)})
+			}
+			notes, err := cm.db.ScratchpadNotes(ctx, conversationID)
+			if err != nil {
+				return nil, err
+			}
+			if len(notes) > 0 {
+				extra = append(extra, llm.SystemContent{Type: "text", Text: claudetool.FormatScratchpad(notes)})
+			}
+			return extra, nil
+		},
 	})
 
 	cm.mu.Lock()
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"shelley.exe.dev/claudetool"
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/db/generated"
 )
@@ -139,3 +140,46 @@ func TestHydrateSystemPromptDisplayDataRespectsToolOverrides(t *testing.T) {
 		t.Fatalf("display data should include enabled shell tool: %+v", displayData.Tools)
 	}
 }
+
+func TestSubagentPersonaShapesSystemPromptAndTools(t *testing.T) {
+	t.Parallel()
+	h := NewTestHarness(t)
+	ctx := context.Background()
+	h.server.toolSetConfig.SubagentPersonas = map[string]claudetool.SubagentPersona{
+		"reviewer": {SystemPrompt: "Look only for injection bugs.", Tools: []string{"keyword_search"}},
+	}
+
+	h.NewConversation("Hello", "")
+	adapter := &db.SubagentDBAdapter{DB: h.db}
+	subID, _, err := adapter.GetOrCreateSubagentConversation(ctx, "rev", h.ConversationID(), t.TempDir(), "reviewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.server.getOrCreateSubagentConversationManager(ctx, subID); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := db.WithTxRes(h.db, ctx, func(q *generated.Queries) ([]generated.Message, error) {
+		return q.ListMessages(ctx, subID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) == 0 || messages[0].Type != string(db.MessageTypeSystem) {
+		t.Fatal("system message not found")
+	}
+	if !strings.Contains(*messages[0].LlmData, "Look only for injection bugs.") {
+		t.Errorf("system prompt lacks persona instructions: %s", *messages[0].LlmData)
+	}
+	var displayData struct {
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal([]byte(*messages[0].DisplayData), &displayData); err != nil {
+		t.Fatal(err)
+	}
+	if len(displayData.Tools) != 1 || displayData.Tools[0].Name != "keyword_search" {
+		t.Errorf("expected only keyword_search, got %+v", displayData.Tools)
+	}
+}
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 
+	"shelley.exe.dev/claudetool/browse"
 	"shelley.exe.dev/ui"
 )
 
@@ -21,3 +23,13 @@ func (s *Server) handleDebugConversationsPage(w http.ResponseWriter, r *http.Req
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 	io.Copy(w, file)
 }
+
+// handleDebugBrowsers reports the shared headless browser pool's size and memory.
+func (s *Server) handleDebugBrowsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var stats browse.PoolStats
+	if pool := s.currentToolSetConfig().BrowserPool; pool != nil {
+		stats = pool.Stats()
+	}
+	json.NewEncoder(w).Encode(stats)
+}
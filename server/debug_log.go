@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// debugLogSize is how many events a conversation's debug log keeps.
+const debugLogSize = 500
+
+// DebugEvent is a log record kept in a conversation's debug log.
+type DebugEvent struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"message"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+}
+
+// debugLog is a ring buffer of a conversation's most recent log records,
+// including debug records the server log may not show: LLM requests and
+// retries, tool calls, and stream subscribers coming and going.
+type debugLog struct {
+	mu     sync.Mutex
+	events []DebugEvent
+	next   int // where the next event goes once events is full
+}
+
+func (d *debugLog) add(ev DebugEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.events) < debugLogSize {
+		d.events = append(d.events, ev)
+		return
+	}
+	d.events[d.next] = ev
+	d.next = (d.next + 1) % debugLogSize
+}
+
+// Events returns the events, oldest first.
+func (d *debugLog) Events() []DebugEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	events := make([]DebugEvent, 0, len(d.events))
+	events = append(events, d.events[d.next:]...)
+	return append(events, d.events[:d.next]...)
+}
+
+// debugLogHandler records every log record of debug level and above in a
+// debugLog, and passes those next is enabled for on to it.
+type debugLogHandler struct {
+	next   slog.Handler
+	log    *debugLog
+	attrs  []slog.Attr
+	prefix string // group names, each followed by a dot
+}
+
+func (h *debugLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelDebug || h.next.Enabled(ctx, level)
+}
+
+func (h *debugLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ev := DebugEvent{Time: r.Time, Level: r.Level.String(), Message: r.Message}
+	if len(h.attrs) > 0 || r.NumAttrs() > 0 {
+		ev.Attrs = make(map[string]any, len(h.attrs)+r.NumAttrs())
+		for _, a := range h.attrs {
+			addDebugAttr(ev.Attrs, "", a)
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			addDebugAttr(ev.Attrs, h.prefix, a)
+			return true
+		})
+	}
+	h.log.add(ev)
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *debugLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.next = h.next.WithAttrs(attrs)
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = append(h2.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return &h2
+}
+
+func (h *debugLogHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.next = h.next.WithGroup(name)
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// addDebugAttr adds a to attrs as a JSON-friendly value, flattening groups
+// into dotted keys.
+func addDebugAttr(attrs map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addDebugAttr(attrs, prefix, ga)
+		}
+	case slog.KindDuration:
+		attrs[prefix+a.Key] = v.Duration().String()
+	default:
+		if err, ok := v.Any().(error); ok {
+			attrs[prefix+a.Key] = err.Error()
+			return
+		}
+		attrs[prefix+a.Key] = v.Any()
+	}
+}
+
+// handleConversationDebugLog handles GET /api/conversation/<id>/debug. It
+// returns the conversation's debug log, which is empty unless the
+// conversation is loaded.
+func (s *Server) handleConversationDebugLog(w http.ResponseWriter, r *http.Request, conversationID string) {
+	s.mu.Lock()
+	manager, active := s.activeConversations[conversationID]
+	s.mu.Unlock()
+	resp := struct {
+		Active bool         `json:"active"`
+		Events []DebugEvent `json:"events"`
+	}{Active: active, Events: []DebugEvent{}}
+	if active {
+		resp.Events = manager.debugLog.Events()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
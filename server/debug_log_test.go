@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestConversationDebugLog(t *testing.T) {
+	h := NewTestHarness(t)
+	h.NewConversation("bash: echo hi", t.TempDir())
+	h.WaitToolResult()
+
+	var resp struct {
+		Active bool         `json:"active"`
+		Events []DebugEvent `json:"events"`
+	}
+	hasEvent := func(msg string) bool {
+		return slices.ContainsFunc(resp.Events, func(ev DebugEvent) bool { return ev.Message == msg })
+	}
+	waitFor(t, 10*time.Second, func() bool {
+		w := httptest.NewRecorder()
+		h.server.handleConversationDebugLog(w, httptest.NewRequest("GET", "/api/conversation/"+h.ConversationID()+"/debug", nil), h.ConversationID())
+		return json.Unmarshal(w.Body.Bytes(), &resp) == nil && hasEvent("tool executed successfully")
+	})
+	if !resp.Active || !hasEvent("sending LLM request") || !hasEvent("executing tool") {
+		t.Errorf("unexpected debug log: %+v", resp)
+	}
+}
+
+func TestDebugLogHandler(t *testing.T) {
+	log := &debugLog{}
+	logger := slog.New(&debugLogHandler{next: slog.DiscardHandler, log: log})
+	for i := range debugLogSize + 10 {
+		logger.Info(fmt.Sprint(i))
+	}
+
+	events := log.Events()
+	if len(events) != debugLogSize || events[0].Message != "10" || events[debugLogSize-1].Message != fmt.Sprint(debugLogSize+9) {
+		t.Fatalf("ring buffer holds %d events, %q to %q", len(events), events[0].Message, events[len(events)-1].Message)
+	}
+
+	log = &debugLog{}
+	logger = slog.New(&debugLogHandler{next: slog.DiscardHandler, log: log}).With("a", 1).WithGroup("g")
+	logger.Debug("first", "err", errors.New("boom"), "took", time.Second)
+	attrs := log.Events()[0].Attrs
+	if attrs["a"] != int64(1) || attrs["g.err"] != "boom" || attrs["g.took"] != "1s" {
+		t.Errorf("attrs = %v", attrs)
+	}
+}
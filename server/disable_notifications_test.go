@@ -2,6 +2,9 @@ package server
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 
@@ -35,12 +38,16 @@ func TestPublishConversationStateHonorsDisableNotifications(t *testing.T) {
 	t.Parallel()
 
 	cases := []struct {
-		name    string
-		opts    db.ConversationOptions
-		wantHit bool
+		name            string
+		opts            db.ConversationOptions
+		notifyByDefault string
+		wantHit         bool
 	}{
-		{"default notifies", db.ConversationOptions{}, true},
-		{"disabled suppresses", db.ConversationOptions{DisableNotifications: true}, false},
+		{"default notifies", db.ConversationOptions{}, "", true},
+		{"disabled suppresses", db.ConversationOptions{DisableNotifications: true}, "", false},
+		{"muted suppresses", db.ConversationOptions{Notifications: db.NotificationsMuted}, "", false},
+		{"default off suppresses", db.ConversationOptions{}, "false", false},
+		{"subscribed overrides default off", db.ConversationOptions{Notifications: db.NotificationsSubscribed}, "false", true},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -48,6 +55,11 @@ func TestPublishConversationStateHonorsDisableNotifications(t *testing.T) {
 			server, database, _ := newTestServer(t)
 			ch := &recordingChannel{}
 			server.RegisterNotificationChannel(ch)
+			if tc.notifyByDefault != "" {
+				if err := database.SetSetting(context.Background(), notifyByDefaultSettingKey, tc.notifyByDefault); err != nil {
+					t.Fatal(err)
+				}
+			}
 
 			conversation, err := database.CreateConversation(context.Background(), nil, true, nil, nil, tc.opts)
 			if err != nil {
@@ -68,3 +80,37 @@ func TestPublishConversationStateHonorsDisableNotifications(t *testing.T) {
 		})
 	}
 }
+
+func TestSetConversationNotifications(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	ctx := context.Background()
+	conversation, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{DisableNotifications: true, ThinkingLevel: "high"})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/conversation/"+conversation.ConversationID+"/notifications", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	if w := post(`{"notifications":"loud"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid value: got %d, want 400", w.Code)
+	}
+	if w := post(`{"notifications":"subscribed"}`); w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+
+	conv, err := database.GetConversationByID(ctx, conversation.ConversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := db.ParseConversationOptions(conv.ConversationOptions)
+	if opts.Notifications != db.NotificationsSubscribed || opts.DisableNotifications || opts.ThinkingLevel != "high" {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"unicode/utf8"
@@ -119,7 +120,7 @@ func steeringSection(instructions string) string {
 	return "\n\n## User Guidance\n\nThe user provided the following guidance on what to preserve or emphasize in this distillation. Follow it closely:\n\n" + instructions
 }
 
-func (s *Server) runDistillNewGeneration(ctx context.Context, conversationID, sourceSlug, modelID, instructions string, sourceGeneration int64, messages []generated.Message) {
+func (s *Server) runDistillNewGeneration(ctx context.Context, conversationID, sourceSlug, modelID, instructions string, sourceGeneration int64, messages []generated.Message, then func(compacted bool)) {
 	defer func() {
 		s.mu.Lock()
 		manager, ok := s.activeConversations[conversationID]
@@ -130,13 +131,16 @@ func (s *Server) runDistillNewGeneration(ctx context.Context, conversationID, so
 		}
 	}()
 
-	s.performPiDistillation(ctx, conversationID, sourceSlug, modelID, instructions, sourceGeneration, messages)
+	summary := s.performPiDistillation(ctx, conversationID, sourceSlug, modelID, instructions, sourceGeneration, messages)
 	// The new generation's messages carry no usage data yet, so the UI's
 	// context-usage bar would keep showing the pre-distillation size until the
 	// next agent turn. Broadcast an estimate of the new generation's context
 	// size so the bar resets immediately.
 	s.broadcastEstimatedContextSize(ctx, conversationID)
 	go s.notifySubscribers(ctx, conversationID)
+	if then != nil {
+		then(summary != "")
+	}
 }
 
 // broadcastEstimatedContextSize estimates the latest generation's context
@@ -244,7 +248,6 @@ func (s *Server) handleDistillNewGeneration(w http.ResponseWriter, r *http.Reque
 		http.Error(w, fmt.Sprintf("unknown distill method %q", req.Method), http.StatusBadRequest)
 		return
 	}
-	method := distillMethodCompact
 
 	sourceConv, err := s.db.GetConversationByID(ctx, req.SourceConversationID)
 	if err != nil {
@@ -252,9 +255,6 @@ func (s *Server) handleDistillNewGeneration(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "Source conversation not found", http.StatusNotFound)
 		return
 	}
-	// Capture the generation we are distilling FROM, before incrementing.
-	// The pi strategy needs it to select the right messages to copy/summarize.
-	sourceGeneration := sourceConv.CurrentGeneration
 	messages, err := s.db.ListMessages(ctx, req.SourceConversationID)
 	if err != nil {
 		s.logger.Error("Failed to get messages", "conversationID", req.SourceConversationID, "error", err)
@@ -277,17 +277,49 @@ func (s *Server) handleDistillNewGeneration(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	manager, err := s.getOrCreateConversationManager(ctx, req.SourceConversationID, "")
+	conversation, err := s.beginCompaction(ctx, sourceConv, messages, modelID, req.Cwd, req.Instructions, nil)
+	if errors.Is(err, errDistillInProgress) {
+		http.Error(w, "Distillation already in progress", http.StatusConflict)
+		return
+	}
 	if err != nil {
-		s.logger.Error("Failed to create conversation manager for distill-new-generation", "conversationID", req.SourceConversationID, "error", err)
+		s.logger.Error("Failed to start distill-new-generation", "conversationID", req.SourceConversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":             "created",
+		"conversation_id":    req.SourceConversationID,
+		"current_generation": conversation.CurrentGeneration,
+	})
+}
+
+// errDistillInProgress is returned by beginCompaction when the conversation
+// is already being compacted.
+var errDistillInProgress = errors.New("distillation already in progress")
+
+// beginCompaction moves sourceConv to its next generation and compacts
+// messages, its history, into it in the background with modelID, which must
+// be a known model. cwd, if set, becomes the conversation's working
+// directory. It returns once the new generation is set up; then, if not
+// nil, is called when compaction ends, before queued messages are sent.
+func (s *Server) beginCompaction(ctx context.Context, sourceConv *generated.Conversation, messages []generated.Message, modelID, cwd, instructions string, then func(compacted bool)) (generated.Conversation, error) {
+	conversationID := sourceConv.ConversationID
+	method := distillMethodCompact
+	// Capture the generation we are distilling FROM, before incrementing.
+	// The pi strategy needs it to select the right messages to copy/summarize.
+	sourceGeneration := sourceConv.CurrentGeneration
+	manager, err := s.getOrCreateConversationManager(ctx, conversationID, "")
+	if err != nil {
+		return generated.Conversation{}, fmt.Errorf("failed to create conversation manager: %w", err)
+	}
 	// Acquire the distilling state before any mutation so a rejected
 	// concurrent request has no side effects.
 	if !manager.BeginDistillingSetup() {
-		http.Error(w, "Distillation already in progress", http.StatusConflict)
-		return
+		return generated.Conversation{}, errDistillInProgress
 	}
 	setupComplete := false
 	defer func() {
@@ -296,28 +328,22 @@ func (s *Server) handleDistillNewGeneration(w http.ResponseWriter, r *http.Reque
 		}
 	}()
 
-	if req.Cwd != "" && (sourceConv.Cwd == nil || *sourceConv.Cwd != req.Cwd) {
-		if err := s.db.UpdateConversationCwd(ctx, req.SourceConversationID, req.Cwd); err != nil {
-			s.logger.Error("Failed to update cwd for new generation", "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
+	if cwd != "" && (sourceConv.Cwd == nil || *sourceConv.Cwd != cwd) {
+		if err := s.db.UpdateConversationCwd(ctx, conversationID, cwd); err != nil {
+			return generated.Conversation{}, fmt.Errorf("failed to update cwd for new generation: %w", err)
 		}
 	}
 	if sourceConv.Model == nil || *sourceConv.Model != modelID {
-		if err := s.db.ForceUpdateConversationModel(ctx, req.SourceConversationID, modelID); err != nil {
-			s.logger.Error("Failed to update model for new generation", "error", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
+		if err := s.db.ForceUpdateConversationModel(ctx, conversationID, modelID); err != nil {
+			return generated.Conversation{}, fmt.Errorf("failed to update model for new generation: %w", err)
 		}
 	}
 
 	conversation, err := db.WithTxRes(s.db, ctx, func(q *generated.Queries) (generated.Conversation, error) {
-		return q.IncrementConversationGeneration(ctx, req.SourceConversationID)
+		return q.IncrementConversationGeneration(ctx, conversationID)
 	})
 	if err != nil {
-		s.logger.Error("Failed to increment generation", "conversationID", req.SourceConversationID, "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		return generated.Conversation{}, fmt.Errorf("failed to increment generation: %w", err)
 	}
 	manager.ResetLoop()
 
@@ -326,7 +352,7 @@ func (s *Server) handleDistillNewGeneration(w http.ResponseWriter, r *http.Reque
 		sourceSlug = *sourceConv.Slug
 	}
 	statusMsg, err := s.db.CreateMessage(ctx, db.CreateMessageParams{
-		ConversationID: req.SourceConversationID,
+		ConversationID: conversationID,
 		Type:           db.MessageTypeAgent,
 		LLMData: llm.Message{
 			Role:    llm.MessageRoleAssistant,
@@ -341,48 +367,91 @@ func (s *Server) handleDistillNewGeneration(w http.ResponseWriter, r *http.Reque
 		ExcludedFromContext: true,
 	})
 	if err != nil {
-		s.logger.Error("Failed to create status message", "conversationID", req.SourceConversationID, "error", err)
 		// WithoutCancel: a client disconnect mid-setup must not strand the
 		// conversation on the just-created empty generation.
-		s.rollbackCompactionFailure(context.WithoutCancel(ctx), s.logger, req.SourceConversationID, "Compaction failed during setup", sourceGeneration)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		s.rollbackCompactionFailure(context.WithoutCancel(ctx), s.logger, conversationID, "Compaction failed during setup", sourceGeneration)
+		return generated.Conversation{}, fmt.Errorf("failed to create status message: %w", err)
 	}
-	go s.notifySubscribersNewMessage(context.WithoutCancel(ctx), req.SourceConversationID, statusMsg)
+	go s.notifySubscribersNewMessage(context.WithoutCancel(ctx), conversationID, statusMsg)
 
 	if err := manager.Hydrate(ctx); err != nil {
-		s.logger.Error("Failed to hydrate new generation", "conversationID", req.SourceConversationID, "error", err)
 		// WithoutCancel: a client disconnect mid-setup must not strand the
 		// conversation on the just-created empty generation.
-		s.rollbackCompactionFailure(context.WithoutCancel(ctx), s.logger, req.SourceConversationID, "Compaction failed during setup", sourceGeneration)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		s.rollbackCompactionFailure(context.WithoutCancel(ctx), s.logger, conversationID, "Compaction failed during setup", sourceGeneration)
+		return generated.Conversation{}, fmt.Errorf("failed to hydrate new generation: %w", err)
 	}
-	if fresh, ferr := s.db.GetConversationByID(ctx, req.SourceConversationID); ferr == nil {
+	if fresh, ferr := s.db.GetConversationByID(ctx, conversationID); ferr == nil {
 		conversation = *fresh
 	}
-	if currentMessages, merr := s.db.ListMessages(ctx, req.SourceConversationID); merr == nil {
+	if currentMessages, merr := s.db.ListMessages(ctx, conversationID); merr == nil {
 		for i := range currentMessages {
 			msg := &currentMessages[i]
 			if msg.Generation == conversation.CurrentGeneration && msg.Type == string(db.MessageTypeSystem) && msg.UserData == nil {
-				go s.notifySubscribersNewMessage(context.WithoutCancel(ctx), req.SourceConversationID, msg)
+				go s.notifySubscribersNewMessage(context.WithoutCancel(ctx), conversationID, msg)
 			}
 		}
 	}
-	go s.notifySubscribers(context.WithoutCancel(ctx), req.SourceConversationID)
+	go s.notifySubscribers(context.WithoutCancel(ctx), conversationID)
 	setupComplete = true
 	manager.FinishDistillingSetup()
 
 	ctxNoCancel := context.WithoutCancel(ctx)
 	go func() {
-		s.runDistillNewGeneration(ctxNoCancel, req.SourceConversationID, sourceSlug, modelID, req.Instructions, sourceGeneration, messages)
+		s.runDistillNewGeneration(ctxNoCancel, conversationID, sourceSlug, modelID, instructions, sourceGeneration, messages, then)
 	}()
+	return conversation, nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":             "created",
-		"conversation_id":    req.SourceConversationID,
-		"current_generation": conversation.CurrentGeneration,
-	})
+// compactedWhenFullPrompt continues a turn stopped by the context limit once
+// the conversation is compacted.
+const compactedWhenFullPrompt = "[The conversation was compacted because it was filling the context window. Continue where you left off.]"
+
+// compactWhenFull compacts cm's conversation, if the server is configured
+// to, when a request is over the context limit, and continues the turn
+// afterwards. It does so once per user message: it declines a request still
+// over the limit, so that the turn ends with notice.
+func (s *Server) compactWhenFull(cm *ConversationManager, notice llm.Message) bool {
+	cm.mu.Lock()
+	compact := cm.toolSetConfig.CompactWhenFull && !cm.compactedWhenFull
+	cm.compactedWhenFull = true
+	modelID := cm.modelID
+	cm.mu.Unlock()
+	if !compact {
+		return false
+	}
+	go s.runCompactWhenFull(cm, modelID, notice)
+	return true
+}
+
+func (s *Server) runCompactWhenFull(cm *ConversationManager, modelID string, notice llm.Message) {
+	ctx := context.Background()
+	id := cm.conversationID
+	fail := func(err error) {
+		s.logger.Error("Failed to compact a full conversation", "conversationID", id, "error", err)
+		if err := s.recordMessage(ctx, id, notice, llm.Usage{}); err != nil {
+			s.logger.Error("Failed to record context full message", "conversationID", id, "error", err)
+		}
+	}
+	conv, err := s.db.GetConversationByID(ctx, id)
+	if err != nil {
+		fail(err)
+		return
+	}
+	messages, err := s.db.ListMessages(ctx, id)
+	if err != nil {
+		fail(err)
+		return
+	}
+	then := func(compacted bool) {
+		if !compacted {
+			fail(fmt.Errorf("nothing was compacted"))
+			return
+		}
+		if err := cm.QueueMessage(ctx, s, modelID, llm.UserStringMessage(compactedWhenFullPrompt)); err != nil {
+			fail(err)
+		}
+	}
+	if _, err := s.beginCompaction(ctx, conv, messages, modelID, "", "", then); err != nil {
+		fail(err)
+	}
 }
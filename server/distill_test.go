@@ -350,3 +350,69 @@ func stopActiveConversationLoops(server *Server) {
 		manager.stopLoop()
 	}
 }
+
+// TestCompactWhenFull checks that a turn over the context limit compacts the
+// conversation and continues, and that it ends with a notice, instead of
+// compacting again, if the compacted conversation is still over the limit.
+func TestCompactWhenFull(t *testing.T) {
+	t.Parallel()
+	synctest.Test(t, func(t *testing.T) {
+		h := NewTestHarness(t)
+		defer stopActiveConversationLoops(h.server)
+		h.server.piDistillKeepRecentTokens = 1
+
+		h.NewConversation("echo: alpha", "")
+		h.WaitResponse()
+		synctest.Wait()
+		convID := h.convID
+		ctx := context.Background()
+		before, err := h.db.GetConversationByID(ctx, convID)
+		if err != nil {
+			t.Fatalf("GetConversationByID: %v", err)
+		}
+
+		// Any request is over a limit this small.
+		h.server.mu.Lock()
+		manager := h.server.activeConversations[convID]
+		h.server.mu.Unlock()
+		manager.mu.Lock()
+		manager.toolSetConfig.ContextLimit = 0.0001
+		manager.toolSetConfig.CompactWhenFull = true
+		manager.mu.Unlock()
+		manager.ResetLoop()
+
+		h.Chat("echo: beta")
+		synctest.Wait()
+
+		after, err := h.db.GetConversationByID(ctx, convID)
+		if err != nil {
+			t.Fatalf("GetConversationByID: %v", err)
+		}
+		if after.CurrentGeneration != before.CurrentGeneration+1 {
+			t.Fatalf("generation went from %d to %d, want one compaction", before.CurrentGeneration, after.CurrentGeneration)
+		}
+		msgs, err := h.db.ListMessages(ctx, convID)
+		if err != nil {
+			t.Fatalf("ListMessages: %v", err)
+		}
+		var continued bool
+		var last llm.Message
+		for _, m := range msgs {
+			if m.Generation != after.CurrentGeneration || m.LlmData == nil {
+				continue
+			}
+			if err := json.Unmarshal([]byte(*m.LlmData), &last); err != nil {
+				t.Fatal(err)
+			}
+			if len(last.Content) > 0 && last.Content[0].Text == compactedWhenFullPrompt {
+				continued = true
+			}
+		}
+		if !continued {
+			t.Error("the turn was not continued after compacting")
+		}
+		if last.ErrorType != llm.ErrorTypeContextFull || !last.EndOfTurn {
+			t.Errorf("last message %+v, want the context full notice", last)
+		}
+	})
+}
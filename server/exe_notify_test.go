@@ -25,7 +25,7 @@ func newExeNotifyTestServer(t *testing.T) *Server {
 	// predictable-only mode (see exeNotifyEnabled). A predictable LLM service is
 	// still fine to back the server.
 	return NewServer(database, &testLLMManager{service: ps},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		false, "predictable", "")
 }
@@ -77,7 +77,7 @@ func TestExeNotifyDisabledInPredictableMode(t *testing.T) {
 	t.Cleanup(cleanup)
 	ps := loop.NewPredictableService()
 	s := NewServer(database, &testLLMManager{service: ps},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		true /* predictableOnly */, "predictable", "")
 	if s.exeNotifyEnabled(context.Background()) {
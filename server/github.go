@@ -0,0 +1,379 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/github"
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/slug"
+)
+
+// githubProgressInterval is how often a working conversation's progress
+// comment is brought up to date.
+const githubProgressInterval = 30 * time.Second
+
+// githubMaxDelivery is the largest webhook delivery GitHub sends.
+const githubMaxDelivery = 25 << 20
+
+// githubJob is one mention's turn: the comment reporting its progress and
+// the checkout whose changes are pushed when it ends.
+type githubJob struct {
+	mention   *github.Mention
+	commentID int64
+	url       string
+	dir       string
+	branch    string
+	// start is the commit checked out when the turn started.
+	start string
+	done  chan struct{}
+}
+
+// SetGitHub makes the server act as app: a comment mentioning it on an
+// issue or pull request starts a conversation in a checkout of the
+// repository, or continues the one already started there.
+func (s *Server) SetGitHub(app *github.App) {
+	s.github = app
+}
+
+// handleGitHubWebhook handles POST /api/github/webhook, the GitHub App's
+// webhook deliveries.
+func (s *Server) handleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	app := s.github
+	if app == nil {
+		http.Error(w, "GitHub integration is disabled: no github app is configured", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, githubMaxDelivery))
+	if err != nil {
+		http.Error(w, "Failed to read delivery", http.StatusBadRequest)
+		return
+	}
+	if err := app.VerifySignature(body, r.Header.Get("X-Hub-Signature-256")); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	m, err := github.ParseMention(r.Header.Get("X-GitHub-Event"), body, app.Config.Bot)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if m == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	go s.runGitHubMention(m)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// runGitHubMention replies to m and starts the turn it asks for, turning
+// the reply into the error if that fails.
+func (s *Server) runGitHubMention(m *github.Mention) {
+	ctx := context.Background()
+	app := s.github
+	commentID, err := app.CreateComment(ctx, m.Installation, m.Repo, m.Number, "Working on it.")
+	if err != nil {
+		s.logger.Error("Failed to comment on GitHub", "repo", m.Repo, "number", m.Number, "error", err)
+		return
+	}
+	job := &githubJob{mention: m, commentID: commentID, done: make(chan struct{})}
+	if err := s.startGitHubJob(ctx, job); err != nil {
+		s.logger.Error("Failed to start GitHub job", "repo", m.Repo, "number", m.Number, "error", err)
+		if err := app.UpdateComment(ctx, m.Installation, m.Repo, commentID, fmt.Sprintf("Couldn't start: %v", err)); err != nil {
+			s.logger.Error("Failed to update GitHub comment", "repo", m.Repo, "number", m.Number, "error", err)
+		}
+	}
+}
+
+// startGitHubJob checks out job's branch, if it isn't already, and sends
+// the mention to the thread's conversation.
+func (s *Server) startGitHubJob(ctx context.Context, job *githubJob) error {
+	m := job.mention
+	token, err := s.github.Token(ctx, m.Installation)
+	if err != nil {
+		return err
+	}
+	job.dir = filepath.Join(s.github.Config.Dir, filepath.FromSlash(m.Repo), fmt.Sprint(m.Number))
+	job.branch = fmt.Sprintf("shelley/issue-%d", m.Number)
+	if m.PullRequest {
+		pr, err := s.github.GetPullRequest(ctx, m.Installation, m.Repo, m.Number)
+		if err != nil {
+			return err
+		}
+		job.branch = fmt.Sprintf("shelley/pr-%d", m.Number)
+		if pr.HeadRepo == m.Repo {
+			job.branch = pr.HeadRef
+		}
+	}
+	if _, err := os.Stat(job.dir); errors.Is(err, fs.ErrNotExist) {
+		if err := githubCheckout(ctx, job, token); err != nil {
+			os.RemoveAll(job.dir)
+			return err
+		}
+	}
+	if job.start, err = githubGit(ctx, job.dir, nil, "rev-parse", "HEAD"); err != nil {
+		return err
+	}
+
+	name := githubSlug(m)
+	job.url = s.conversationURL(name)
+	prompt := fmt.Sprintf("@%s commented on %s#%d:\n\n%s", m.Author, m.Repo, m.Number, m.Comment)
+	conv, err := s.db.GetConversationBySlug(ctx, name)
+	if err != nil {
+		// A new thread, or its conversation was deleted.
+		if conv, err = s.createGitHubConversation(ctx, job, name); err != nil {
+			return err
+		}
+		prompt = githubPrompt(job)
+	}
+	modelID := derefString(conv.Model)
+	service, err := s.llmManager.GetService(modelID)
+	if err != nil {
+		return err
+	}
+	manager, err := s.getOrCreateConversationManager(ctx, conv.ConversationID, "")
+	if err != nil {
+		return err
+	}
+
+	finish := func() {
+		close(job.done)
+		go s.finishGitHubJob(conv.ConversationID, job)
+	}
+	if manager.IsAgentWorking() || !s.awaitTurnEnd(conv.ConversationID, finish) {
+		return fmt.Errorf("still working on an earlier comment in [this conversation](%s)", job.url)
+	}
+
+	msg := llm.Message{Role: llm.MessageRoleUser, Content: []llm.Content{{Type: llm.ContentTypeText, Text: prompt}}}
+	if _, err := manager.AcceptUserMessage(ctx, service, modelID, msg); err != nil {
+		s.cancelTurnEnd(conv.ConversationID)
+		return err
+	}
+	go s.reportGitHubProgress(conv.ConversationID, job)
+	return nil
+}
+
+// createGitHubConversation creates the conversation for job's thread,
+// named name, in its checkout.
+func (s *Server) createGitHubConversation(ctx context.Context, job *githubJob, name string) (*generated.Conversation, error) {
+	modelID := s.github.Config.Model
+	if modelID == "" {
+		var err error
+		if modelID, err = s.defaultModelIn(job.dir, s.getModelList()); err != nil {
+			return nil, err
+		}
+	}
+	conv, err := s.db.CreateConversation(ctx, &name, true, &job.dir, &modelID, db.ConversationOptions{})
+	if err != nil {
+		return nil, err
+	}
+	go s.publishConversationListUpdate(ConversationListUpdate{Type: "update", Conversation: conv})
+	return conv, nil
+}
+
+// githubSlug names the conversation of m's thread.
+func githubSlug(m *github.Mention) string {
+	return slug.Sanitize(fmt.Sprintf("github %s %d", strings.ReplaceAll(m.Repo, "/", " "), m.Number))
+}
+
+// githubPrompt is the first message of a thread's conversation.
+func githubPrompt(job *githubJob) string {
+	m := job.mention
+	kind := "issue"
+	if m.PullRequest {
+		kind = "pull request"
+	}
+	return fmt.Sprintf(`@%s mentioned you on GitHub, on %s %s#%d, %q:
+
+%s
+
+The %s's description:
+
+%s
+
+You are in a checkout of %s on branch %s. When your turn ends, your changes are committed and pushed to %s, and your final message is posted as a reply on GitHub, so end with a summary for the people there.`,
+		m.Author, kind, m.Repo, m.Number, m.Title, m.Comment, kind, m.Body, m.Repo, job.branch, job.branch)
+}
+
+// githubCheckout clones job's repository into its directory and checks out
+// its branch: the existing one, a fork's pull request head, or a new one.
+func githubCheckout(ctx context.Context, job *githubJob, token string) error {
+	m := job.mention
+	env := githubGitEnv(token)
+	if err := os.MkdirAll(filepath.Dir(job.dir), 0o755); err != nil {
+		return err
+	}
+	if _, err := githubGit(ctx, "", env, "clone", "--quiet", "--", m.CloneURL, job.dir); err != nil {
+		return err
+	}
+	from := "origin/" + job.branch
+	if _, err := githubGit(ctx, job.dir, nil, "rev-parse", "--verify", "--quiet", from); err != nil {
+		from = "HEAD"
+		if m.PullRequest {
+			if _, err := githubGit(ctx, job.dir, env, "fetch", "--quiet", "origin", fmt.Sprintf("pull/%d/head", m.Number)); err != nil {
+				return err
+			}
+			from = "FETCH_HEAD"
+		}
+	}
+	_, err := githubGit(ctx, job.dir, nil, "checkout", "--quiet", "-B", job.branch, from)
+	return err
+}
+
+// githubGitEnv authenticates git as the installation token.
+func githubGitEnv(token string) []string {
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+	)
+}
+
+// githubGit runs git in dir, with env if it isn't nil, and returns its
+// trimmed output.
+func githubGit(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// reportGitHubProgress keeps job's comment listing what the turn is doing
+// until it ends.
+func (s *Server) reportGitHubProgress(conversationID string, job *githubJob) {
+	ticker := time.NewTicker(githubProgressInterval)
+	defer ticker.Stop()
+	m := job.mention
+	var last string
+	for {
+		select {
+		case <-job.done:
+			return
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+		}
+		msgs, err := s.db.ListAgentMessagesSinceLastUser(context.Background(), conversationID)
+		if err != nil {
+			s.logger.Warn("Failed to read GitHub job progress", "conversationID", conversationID, "error", err)
+			continue
+		}
+		body := githubProgressBody(msgs, job.url)
+		if body == last {
+			continue
+		}
+		last = body
+		if err := s.github.UpdateComment(context.Background(), m.Installation, m.Repo, job.commentID, body); err != nil {
+			s.logger.Warn("Failed to update GitHub comment", "repo", m.Repo, "number", m.Number, "error", err)
+		}
+	}
+}
+
+// githubProgressBody lists the turn's latest tool calls, given its agent
+// messages newest first.
+func githubProgressBody(msgs []generated.Message, url string) string {
+	const maxSteps = 5
+	var steps []string
+	for _, m := range msgs {
+		if len(steps) == maxSteps {
+			break
+		}
+		if m.LlmData == nil {
+			continue
+		}
+		var msg llm.Message
+		if err := json.Unmarshal([]byte(*m.LlmData), &msg); err != nil {
+			continue
+		}
+		if step := summarizeLastToolUse(msg); step != "" {
+			steps = append(steps, "- "+step)
+		}
+	}
+	slices.Reverse(steps)
+	body := fmt.Sprintf("Working on it in [this conversation](%s).", url)
+	if len(steps) > 0 {
+		body += "\n\n" + strings.Join(steps, "\n")
+	}
+	return body
+}
+
+// finishGitHubJob pushes what job's turn changed and replaces its comment
+// with the turn's final message.
+func (s *Server) finishGitHubJob(conversationID string, job *githubJob) {
+	ctx := context.Background()
+	m := job.mention
+	var reply string
+	if msgs, err := s.db.ListAgentMessagesSinceLastUser(ctx, conversationID); err == nil {
+		reply = finalResponseBody(msgs)
+	}
+	if reply == "" {
+		reply = "Done."
+	}
+	pushed, err := s.pushGitHubJob(ctx, job)
+	switch {
+	case err != nil:
+		s.logger.Error("Failed to push GitHub job", "repo", m.Repo, "number", m.Number, "error", err)
+		reply += fmt.Sprintf("\n\nCouldn't push `%s`: %v", job.branch, err)
+	case pushed:
+		reply += fmt.Sprintf("\n\nPushed to `%s`.", job.branch)
+	}
+	reply += fmt.Sprintf("\n\n[Conversation](%s)", job.url)
+	if err := s.github.UpdateComment(ctx, m.Installation, m.Repo, job.commentID, reply); err != nil {
+		s.logger.Error("Failed to update GitHub comment", "repo", m.Repo, "number", m.Number, "error", err)
+	}
+}
+
+// pushGitHubJob commits job's uncommitted changes and pushes its branch if
+// the turn changed it, reporting whether it did.
+func (s *Server) pushGitHubJob(ctx context.Context, job *githubJob) (bool, error) {
+	m := job.mention
+	status, err := githubGit(ctx, job.dir, nil, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	if status != "" {
+		bot := s.github.Config.Bot + "[bot]"
+		if _, err := githubGit(ctx, job.dir, nil, "add", "-A"); err != nil {
+			return false, err
+		}
+		msg := fmt.Sprintf("%s (#%d)", m.Title, m.Number)
+		if _, err := githubGit(ctx, job.dir, nil, "-c", "user.name="+bot, "-c", "user.email="+bot+"@users.noreply.github.com", "commit", "--quiet", "-m", msg); err != nil {
+			return false, err
+		}
+	}
+	head, err := githubGit(ctx, job.dir, nil, "rev-parse", "HEAD")
+	if err != nil || head == job.start {
+		return false, err
+	}
+	token, err := s.github.Token(ctx, m.Installation)
+	if err != nil {
+		return false, err
+	}
+	if _, err := githubGit(ctx, job.dir, githubGitEnv(token), "push", "--quiet", "origin", "HEAD:refs/heads/"+job.branch); err != nil {
+		return false, err
+	}
+	return true, nil
+}
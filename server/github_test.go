@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/github"
+)
+
+// fakeGitHub serves the REST API calls the GitHub integration makes,
+// recording the body of comment 99.
+type fakeGitHub struct {
+	mu      sync.Mutex
+	comment string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var in struct{ Body string }
+	json.NewDecoder(r.Body).Decode(&in)
+	switch r.Method + " " + r.URL.Path {
+	case "POST /app/installations/3/access_tokens":
+		json.NewEncoder(w).Encode(map[string]any{"token": "t", "expires_at": time.Now().Add(time.Hour)})
+	case "POST /repos/o/r/issues/7/comments", "PATCH /repos/o/r/issues/comments/99":
+		f.mu.Lock()
+		f.comment = in.Body
+		f.mu.Unlock()
+		w.Write([]byte(`{"id": 99}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeGitHub) Comment() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.comment
+}
+
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestGitHubMention(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	fake := &fakeGitHub{}
+	api := httptest.NewServer(fake)
+	t.Cleanup(api.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600)
+	dir := t.TempDir()
+	app, err := github.New(github.Config{AppID: 1, PrivateKeyFile: keyFile, WebhookSecret: "s3cret", Bot: "shelley", Dir: dir, Model: "predictable", APIURL: api.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetGitHub(app)
+
+	// The repository is a local bare one, which git clones and pushes to
+	// like GitHub's.
+	origin := filepath.Join(t.TempDir(), "r.git")
+	work := t.TempDir()
+	gitCmd(t, work, "init", "-q", "-b", "main")
+	gitCmd(t, work, "commit", "-q", "--allow-empty", "-m", "init")
+	gitCmd(t, work, "clone", "-q", "--bare", work, origin)
+
+	deliver := func(event string, body []byte, secret string) int {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/github/webhook", bytes.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		w := httptest.NewRecorder()
+		s.handleGitHubWebhook(w, req)
+		return w.Code
+	}
+	delivery := func(comment string) []byte {
+		body, _ := json.Marshal(map[string]any{
+			"action":       "created",
+			"comment":      map[string]any{"body": comment, "author_association": "OWNER", "user": map[string]string{"login": "alice", "type": "User"}},
+			"issue":        map[string]any{"number": 7, "title": "Add a README", "body": "We need one."},
+			"installation": map[string]int{"id": 3},
+			"repository":   map[string]string{"full_name": "o/r", "clone_url": origin},
+		})
+		return body
+	}
+
+	if code := deliver("issue_comment", delivery("@shelley hi"), "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("badly signed delivery: got %d", code)
+	}
+	if code := deliver("issue_comment", delivery("no mention"), "s3cret"); code != http.StatusNoContent {
+		t.Errorf("delivery without a mention: got %d", code)
+	}
+	if code := deliver("issue_comment", delivery("@shelley add a README"), "s3cret"); code != http.StatusAccepted {
+		t.Fatalf("mention: got %d", code)
+	}
+
+	// The turn's reply replaces the progress comment.
+	waitFor(t, 10*time.Second, func() bool {
+		return strings.Contains(fake.Comment(), "[Conversation](")
+	})
+	if c := fake.Comment(); !strings.Contains(c, "edit predictable.go") || strings.Contains(c, "Pushed") {
+		t.Errorf("final comment: %q", c)
+	}
+	checkout := filepath.Join(dir, "o", "r", "7")
+	if branch := gitCmd(t, checkout, "branch", "--show-current"); branch != "shelley/issue-7" {
+		t.Errorf("checkout is on %q", branch)
+	}
+	conv, err := s.db.GetConversationBySlug(context.Background(), "github-o-r-7")
+	if err != nil || derefString(conv.Cwd) != checkout {
+		t.Fatalf("conversation: %+v, %v", conv, err)
+	}
+
+	// Changes are committed and pushed to the branch.
+	os.WriteFile(filepath.Join(checkout, "README"), []byte("hi\n"), 0o644)
+	job := &githubJob{mention: &github.Mention{Installation: 3, Repo: "o/r", Number: 7, Title: "Add a README"}, dir: checkout, branch: "shelley/issue-7", start: gitCmd(t, checkout, "rev-parse", "HEAD")}
+	if pushed, err := s.pushGitHubJob(context.Background(), job); err != nil || !pushed {
+		t.Fatalf("pushGitHubJob = %v, %v", pushed, err)
+	}
+	if msg := gitCmd(t, origin, "log", "-1", "--format=%an: %s", "shelley/issue-7"); msg != "shelley[bot]: Add a README (#7)" {
+		t.Errorf("pushed commit: %q", msg)
+	}
+
+	// Another mention continues the conversation.
+	fake.mu.Lock()
+	fake.comment = ""
+	fake.mu.Unlock()
+	if code := deliver("issue_comment", delivery("@shelley thanks"), "s3cret"); code != http.StatusAccepted {
+		t.Fatalf("second mention: got %d", code)
+	}
+	waitFor(t, 10*time.Second, func() bool {
+		return strings.Contains(fake.Comment(), "[Conversation](")
+	})
+	msgs, err := s.db.ListMessages(context.Background(), conv.ConversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users int
+	for _, m := range msgs {
+		if m.Type == "user" {
+			users++
+		}
+	}
+	if users != 2 {
+		t.Errorf("got %d user messages in the conversation, want 2", users)
+	}
+}
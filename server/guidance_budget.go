@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/llm"
+)
+
+const (
+	// DefaultGuidanceFileBytes is how much of one guidance file, such as
+	// AGENTS.md, the system prompt includes by default.
+	DefaultGuidanceFileBytes = 32 << 10
+	// DefaultGuidanceTotalBytes is how much of all guidance files together
+	// the system prompt includes by default.
+	DefaultGuidanceTotalBytes = 64 << 10
+)
+
+// GuidanceBudget bounds the guidance files injected into the system prompt,
+// so that a huge AGENTS.md does not take up the context window. Files are
+// budgeted in the order they appear in the prompt.
+type GuidanceBudget struct {
+	// FileBytes caps each file: 0 means DefaultGuidanceFileBytes, and a
+	// negative value means no cap.
+	FileBytes int
+	// TotalBytes caps all files together, like FileBytes.
+	TotalBytes int
+	// Summarize, if set, shortens a file that is over budget to at most
+	// maxBytes. If it fails, the file is truncated instead.
+	Summarize func(ctx context.Context, path, content string, maxBytes int) (string, error)
+}
+
+// WithGuidanceBudget bounds the guidance files in the system prompt. Without
+// it, the default caps apply and oversized files are truncated.
+func WithGuidanceBudget(budget GuidanceBudget) SystemPromptOption {
+	return func(d *SystemPromptData) {
+		d.guidanceBudget = budget
+	}
+}
+
+// guidanceLimit resolves a GuidanceBudget cap.
+func guidanceLimit(limit, def int) int {
+	switch {
+	case limit == 0:
+		return def
+	case limit < 0:
+		return math.MaxInt
+	}
+	return limit
+}
+
+// apply shortens the contents of info's guidance files to fit the budget.
+func (b GuidanceBudget) apply(info *CodebaseInfo) {
+	fileBytes := guidanceLimit(b.FileBytes, DefaultGuidanceFileBytes)
+	remaining := guidanceLimit(b.TotalBytes, DefaultGuidanceTotalBytes)
+	for _, file := range info.InjectFiles {
+		content := info.InjectFileContents[file]
+		if maxBytes := min(fileBytes, remaining); len(content) > maxBytes {
+			content = b.shorten(file, content, maxBytes)
+			info.InjectFileContents[file] = content
+		}
+		remaining = max(remaining-len(content), 0)
+	}
+}
+
+// shorten summarizes or truncates content to about maxBytes.
+func (b GuidanceBudget) shorten(path, content string, maxBytes int) string {
+	if b.Summarize != nil && maxBytes >= minGuidanceSummaryBytes {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		summary, err := b.Summarize(ctx, path, content, maxBytes)
+		if err == nil && strings.TrimSpace(summary) != "" && len(summary) <= maxBytes {
+			return fmt.Sprintf("[Summarized from %d bytes; read %s for details.]\n\n%s", len(content), path, strings.TrimSpace(summary))
+		}
+		slog.Warn("failed to summarize guidance file; truncating it", "file", path, "error", err, "summaryLen", len(summary))
+	}
+	return truncateGuidance(path, content, maxBytes)
+}
+
+// minGuidanceSummaryBytes is the smallest budget worth summarizing a file to.
+const minGuidanceSummaryBytes = 1 << 10
+
+// truncateGuidance cuts content to at most maxBytes, preferably just before a
+// markdown heading, else at a paragraph or line break, and notes the cut.
+func truncateGuidance(path, content string, maxBytes int) string {
+	if len(content) <= maxBytes {
+		return content
+	}
+	cut := strings.ToValidUTF8(content[:maxBytes], "")
+	// Only back up to a boundary that keeps at least half of what fits.
+	for _, sep := range []string{"\n#", "\n\n", "\n"} {
+		if i := strings.LastIndex(cut, sep); i >= maxBytes/2 {
+			cut = cut[:i]
+			break
+		}
+	}
+	if strings.TrimSpace(cut) == "" {
+		return fmt.Sprintf("[Not shown: %d bytes, over the guidance budget. Read %s if it is relevant.]", len(content), path)
+	}
+	return fmt.Sprintf("%s\n\n[Truncated: %d of %d bytes shown. Read %s for the rest.]", strings.TrimRight(cut, "\n"), len(cut), len(content), path)
+}
+
+// guidanceSummaries caches summaries by file content and budget, since
+// every new conversation in a repository injects the same files.
+var guidanceSummaries sync.Map // [sha256.Size + 8]byte -> string
+
+// LLMGuidanceSummarizer returns a GuidanceBudget.Summarize that condenses
+// files with the first of claudetool.PreferredToolModels that provider has.
+func LLMGuidanceSummarizer(provider claudetool.LLMServiceProvider) func(ctx context.Context, path, content string, maxBytes int) (string, error) {
+	return func(ctx context.Context, path, content string, maxBytes int) (string, error) {
+		var key [sha256.Size + 8]byte
+		sum := sha256.Sum256([]byte(content))
+		copy(key[:], sum[:])
+		for i := range 8 {
+			key[sha256.Size+i] = byte(maxBytes >> (8 * i))
+		}
+		if summary, ok := guidanceSummaries.Load(key); ok {
+			return summary.(string), nil
+		}
+
+		var svc llm.Service
+		for _, model := range claudetool.PreferredToolModels {
+			if s, err := provider.GetService(model); err == nil {
+				svc = s
+				break
+			}
+		}
+		if svc == nil {
+			return "", fmt.Errorf("no model available to summarize guidance")
+		}
+		prompt := fmt.Sprintf(`Condense the project guidance file %s below for a coding agent, to at most %d bytes of markdown.
+Keep every instruction, rule, command, path, and convention, verbatim where possible. Drop background, prose, and examples that the instructions don't need.
+Respond with only the condensed file.
+
+%s`, path, maxBytes*9/10, content)
+		resp, err := svc.Do(ctx, &llm.Request{Messages: []llm.Message{{
+			Role:    llm.MessageRoleUser,
+			Content: []llm.Content{{Type: llm.ContentTypeText, Text: prompt}},
+		}}})
+		if err != nil {
+			return "", err
+		}
+		var summary strings.Builder
+		for _, c := range resp.Content {
+			if c.Type == llm.ContentTypeText {
+				summary.WriteString(c.Text)
+			}
+		}
+		guidanceSummaries.Store(key, summary.String())
+		return summary.String(), nil
+	}
+}
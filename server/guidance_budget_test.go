@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTruncateGuidance(t *testing.T) {
+	content := "# Setup\n\nRun make.\n\n# Style\n\n" + strings.Repeat("Use tabs. ", 20)
+	got := truncateGuidance("/repo/AGENTS.md", content, 36)
+	if !strings.HasPrefix(got, "# Setup\n\nRun make.\n\n[Truncated: 19 of ") {
+		t.Errorf("did not cut before the heading:\n%s", got)
+	}
+	if !strings.Contains(got, "Read /repo/AGENTS.md for the rest.") {
+		t.Errorf("truncation note does not name the file:\n%s", got)
+	}
+	if got := truncateGuidance("/repo/AGENTS.md", content, 0); !strings.HasPrefix(got, "[Not shown:") {
+		t.Errorf("zero budget = %q", got)
+	}
+	if got := truncateGuidance("/repo/AGENTS.md", "short", 40); got != "short" {
+		t.Errorf("short file changed to %q", got)
+	}
+}
+
+func TestGuidanceBudget(t *testing.T) {
+	big := strings.Repeat("Always run the tests.\n", 100) // 2200 bytes
+	newInfo := func() *CodebaseInfo {
+		return &CodebaseInfo{
+			InjectFiles:        []string{"a.md", "b.md", "c.md"},
+			InjectFileContents: map[string]string{"a.md": big, "b.md": big, "c.md": "tiny"},
+		}
+	}
+
+	info := newInfo()
+	GuidanceBudget{FileBytes: 1500, TotalBytes: 2000}.apply(info)
+	if a := info.InjectFileContents["a.md"]; len(a) > 1600 || !strings.Contains(a, "[Truncated:") {
+		t.Errorf("a.md was not truncated to its file budget: %d bytes", len(a))
+	}
+	if b := info.InjectFileContents["b.md"]; len(b) >= 1000 || !strings.Contains(b, "[Truncated:") {
+		t.Errorf("b.md was not truncated to what is left of the total: %d bytes", len(b))
+	}
+	if c := info.InjectFileContents["c.md"]; !strings.HasPrefix(c, "[Not shown:") {
+		t.Errorf("c.md past the total budget = %q", c)
+	}
+
+	info = newInfo()
+	GuidanceBudget{FileBytes: -1, TotalBytes: -1}.apply(info)
+	if info.InjectFileContents["a.md"] != big || info.InjectFileContents["c.md"] != "tiny" {
+		t.Error("files changed without a budget")
+	}
+
+	info = newInfo()
+	summarize := func(ctx context.Context, path, content string, maxBytes int) (string, error) {
+		if path == "b.md" {
+			return "", errors.New("model unavailable")
+		}
+		return "Run the tests.", nil
+	}
+	GuidanceBudget{FileBytes: 1200, TotalBytes: -1, Summarize: summarize}.apply(info)
+	if a := info.InjectFileContents["a.md"]; a != "[Summarized from 2200 bytes; read a.md for details.]\n\nRun the tests." {
+		t.Errorf("a.md summary = %q", a)
+	}
+	if b := info.InjectFileContents["b.md"]; !strings.Contains(b, "[Truncated:") {
+		t.Errorf("b.md was not truncated after summarizing failed: %q", b)
+	}
+}
+
+func TestSystemPromptBudgetsGuidanceFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	content := "UNIQUE_HEAD_MARKER\n" + strings.Repeat("filler line\n", 200) + "UNIQUE_TAIL_MARKER\n"
+	if err := os.WriteFile(filepath.Join(dir, "AGENTS.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prompt, err := GenerateSystemPrompt(dir, WithGuidanceBudget(GuidanceBudget{FileBytes: 1000}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "UNIQUE_HEAD_MARKER") || strings.Contains(prompt, "UNIQUE_TAIL_MARKER") {
+		t.Error("AGENTS.md was not truncated to the budget")
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"cmp"
 	"compress/gzip"
 	"context"
 	"crypto/rand"
@@ -17,6 +18,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -30,7 +32,10 @@ import (
 	"shelley.exe.dev/gitstate"
 	"shelley.exe.dev/llm"
 	"shelley.exe.dev/models"
+	"shelley.exe.dev/models/modelsdev"
+	"shelley.exe.dev/server/notifications"
 	"shelley.exe.dev/slug"
+	"shelley.exe.dev/subpub"
 	"shelley.exe.dev/ui"
 	"shelley.exe.dev/version"
 )
@@ -96,6 +101,7 @@ func isReadableUIFile(path string) bool {
 		strings.HasPrefix(path, browse.ConsoleLogsDir+"/") ||
 		strings.HasPrefix(path, browse.ScreencastDir+"/") ||
 		strings.HasPrefix(path, claudetool.OneShotImageDir+"/") ||
+		strings.HasPrefix(path, claudetool.ToolOutputDir+"/") ||
 		isDistillationTempFile(path)
 }
 
@@ -864,6 +870,10 @@ func (s *Server) conversationMux() *http.ServeMux {
 	mux.HandleFunc("GET /{id}/subagent-usage", func(w http.ResponseWriter, r *http.Request) {
 		s.handleSubagentUsage(w, r, r.PathValue("id"))
 	})
+	// GET /api/conversation/<id>/debug - the conversation's recent log records
+	mux.HandleFunc("GET /{id}/debug", func(w http.ResponseWriter, r *http.Request) {
+		s.handleConversationDebugLog(w, r, r.PathValue("id"))
+	})
 	// GET /api/conversation/<id>/stream - legacy SSE stream. Compression is
 	// negotiated inside the handler (zstd/gzip per Accept-Encoding) with a
 	// compressor flush after every event so messages stream promptly.
@@ -877,6 +887,12 @@ func (s *Server) conversationMux() *http.ServeMux {
 	mux.HandleFunc("POST /{id}/hooks", func(w http.ResponseWriter, r *http.Request) {
 		s.handleRegisterConversationHook(w, r, r.PathValue("id"))
 	})
+	mux.HandleFunc("POST /{id}/notifications", func(w http.ResponseWriter, r *http.Request) {
+		s.handleSetConversationNotifications(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /{id}/instructions", func(w http.ResponseWriter, r *http.Request) {
+		s.handleSetConversationInstructions(w, r, r.PathValue("id"))
+	})
 	mux.HandleFunc("POST /{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
 		s.handleCancelConversation(w, r, r.PathValue("id"))
 	})
@@ -886,6 +902,9 @@ func (s *Server) conversationMux() *http.ServeMux {
 	mux.HandleFunc("POST /{id}/continue", func(w http.ResponseWriter, r *http.Request) {
 		s.handleContinueConversation(w, r, r.PathValue("id"))
 	})
+	mux.HandleFunc("GET /{id}/status", func(w http.ResponseWriter, r *http.Request) {
+		s.handleConversationStatus(w, r, r.PathValue("id"))
+	})
 	mux.HandleFunc("POST /{id}/archive", func(w http.ResponseWriter, r *http.Request) {
 		s.handleArchiveConversation(w, r, r.PathValue("id"))
 	})
@@ -895,6 +914,9 @@ func (s *Server) conversationMux() *http.ServeMux {
 	mux.HandleFunc("POST /{id}/delete", func(w http.ResponseWriter, r *http.Request) {
 		s.handleDeleteConversation(w, r, r.PathValue("id"))
 	})
+	mux.HandleFunc("GET /{id}/workspace/diff", func(w http.ResponseWriter, r *http.Request) {
+		s.handleWorkspaceDiff(w, r, r.PathValue("id"))
+	})
 	mux.HandleFunc("POST /{id}/rename", func(w http.ResponseWriter, r *http.Request) {
 		s.handleRenameConversation(w, r, r.PathValue("id"))
 	})
@@ -904,6 +926,15 @@ func (s *Server) conversationMux() *http.ServeMux {
 	mux.HandleFunc("GET /{id}/subagents", func(w http.ResponseWriter, r *http.Request) {
 		s.handleGetSubagents(w, r, r.PathValue("id"))
 	})
+	mux.HandleFunc("GET /{id}/subagent-status", func(w http.ResponseWriter, r *http.Request) {
+		s.handleGetSubagentStatus(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /{id}/reattach", func(w http.ResponseWriter, r *http.Request) {
+		s.handleReattachSubagent(w, r, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /{id}/handoff", func(w http.ResponseWriter, r *http.Request) {
+		s.handleHandoff(w, r, r.PathValue("id"))
+	})
 	mux.HandleFunc("POST /{id}/cancel-queued", func(w http.ResponseWriter, r *http.Request) {
 		s.handleCancelQueued(w, r, r.PathValue("id"))
 	})
@@ -1003,6 +1034,11 @@ type ChatRequest struct {
 	Cwd                 string                  `json:"cwd,omitempty"`
 	ConversationOptions *db.ConversationOptions `json:"conversation_options,omitempty"`
 	Queue               bool                    `json:"queue,omitempty"`
+	// RequireModel rejects the message when Model differs from an existing
+	// conversation's model, instead of quietly using the conversation's.
+	// Clients that send a model on purpose (the CLI) set it; the web UI
+	// doesn't, because its composer model goes stale mid-conversation.
+	RequireModel bool `json:"require_model,omitempty"`
 }
 
 // handleChatConversation handles POST /conversation/<id>/chat
@@ -1053,6 +1089,10 @@ func (s *Server) handleChatConversation(w http.ResponseWriter, r *http.Request,
 	// push "Reply" handler, which fires from a background launch with no
 	// loaded chat state — send an empty `model`; case 1 covers them too.
 	modelID := req.Model
+	if req.RequireModel && modelID != "" && !existing.IsDraft && existing.Model != nil && *existing.Model != "" && *existing.Model != modelID {
+		http.Error(w, fmt.Sprintf("%v: conversation already uses model %s; requested %s (send \"/model %s\" to switch)", errConversationModelMismatch, *existing.Model, modelID, modelID), http.StatusBadRequest)
+		return
+	}
 	if existing.Model != nil && *existing.Model != "" && (!existing.IsDraft || modelID == "") {
 		// Non-draft: persisted model wins outright (stale req.Model ignored).
 		// Draft: persisted model is only the fallback when the request omits
@@ -1097,6 +1137,10 @@ func (s *Server) handleChatConversation(w http.ResponseWriter, r *http.Request,
 				http.Error(w, msg, http.StatusBadRequest)
 				return
 			}
+			if req.ConversationOptions.Workspace != nil {
+				http.Error(w, errDraftWorkspace, http.StatusBadRequest)
+				return
+			}
 			if msg := validateModelReasoningLevel(findModelInfo(modelID, s.getModelList()), req.ConversationOptions.ThinkingLevel); msg != "" {
 				http.Error(w, msg, http.StatusBadRequest)
 				return
@@ -1285,7 +1329,12 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 	// Get LLM service for the requested model
 	modelID := req.Model
 	if modelID == "" {
-		modelID = s.effectiveDefaultModel(s.getModelList())
+		var err error
+		modelID, err = s.defaultModelIn(req.Cwd, s.getModelList())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
 	llmService, err := s.llmManager.GetService(modelID)
@@ -1311,8 +1360,16 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, msg, http.StatusBadRequest)
 			return
 		}
+		if convOpts.Workspace != nil && !s.currentToolSetConfig().Workspaces.Enabled() {
+			http.Error(w, "Workspaces are disabled: no workspaces.image is configured", http.StatusBadRequest)
+			return
+		}
 	}
 
+	// Look for a duplicate before creating the conversation, which would
+	// match itself.
+	similar := s.findSimilarConversation(ctx, req.Cwd, req.Message)
+
 	conversation, err := s.db.CreateConversation(ctx, nil, true, cwdPtr, &modelID, convOpts)
 	if err != nil {
 		s.logger.Error("Failed to create conversation", "error", err)
@@ -1332,11 +1389,24 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 			Headers:        HookHeaders(r.Header),
 		},
 	})
+	var abort *HookAbortError
+	if errors.As(hookErr, &abort) {
+		if err := s.db.DeleteConversation(ctx, conversationID); err != nil {
+			s.logger.Error("Failed to delete conversation aborted by hook", "conversationID", conversationID, "error", err)
+		}
+		http.Error(w, abort.Message, http.StatusForbidden)
+		return
+	}
 	if hookErr != nil {
 		s.logger.Error("new-conversation hook failed", "conversationID", conversationID, "error", hookErr)
 		http.Error(w, "new-conversation hook failed", http.StatusInternalServerError)
 		return
 	}
+	if err := s.saveHookOptions(ctx, conversationID, convOpts, hookResult); err != nil {
+		s.logger.Error("Failed to save new-conversation hook options", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 	if hookResult.Cwd != derefString(cwdPtr) {
 		if err := s.db.UpdateConversationCwd(ctx, conversationID, hookResult.Cwd); err != nil {
 			s.logger.Error("Failed to update cwd from hook", "error", err)
@@ -1344,6 +1414,17 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 			conversation.Cwd = &hookResult.Cwd
 		}
 	}
+	if convOpts.Workspace != nil {
+		conversation, err = s.createWorkspace(ctx, conversationID)
+		if err != nil {
+			s.logger.Error("Failed to create workspace", "conversationID", conversationID, "error", err)
+			if err := s.db.DeleteConversation(ctx, conversationID); err != nil {
+				s.logger.Error("Failed to delete conversation without a workspace", "conversationID", conversationID, "error", err)
+			}
+			http.Error(w, fmt.Sprintf("Failed to create workspace: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
 	if hookResult.Model != modelID {
 		newService, svcErr := s.llmManager.GetService(hookResult.Model)
 		if svcErr != nil {
@@ -1448,12 +1529,17 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 		}()
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	resp := map[string]interface{}{
 		"status":          "accepted",
 		"conversation_id": conversationID,
-	})
+	}
+	if similar != nil {
+		resp["similar_conversation"] = similar
+		manager.broadcastStream(StreamResponse{SimilarConversation: similar})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
 }
 
 // handleCancelConversation handles POST /conversation/<id>/cancel
@@ -1714,6 +1800,11 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if err := s.thawConversation(r.Context(), conversationID); err != nil {
+		s.logger.Error("Failed to restore conversation from cold storage", "conversationID", conversationID, "error", err)
+		http.Error(w, "Failed to restore conversation from cold storage", http.StatusInternalServerError)
+		return
+	}
 
 	ctx, cancelStream := context.WithCancel(r.Context())
 	defer cancelStream()
@@ -1918,20 +2009,7 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 	// ?conversation= parameter governs only backfill of that conversation's
 	// initial history (handled below).
 	if includeConversationListPatches && s.streamPub != nil {
-		next := s.streamPub.Subscribe(ctx, -1)
-		go func() {
-			for {
-				streamData, cont := next()
-				if !cont {
-					return
-				}
-				select {
-				case updates <- streamData:
-				case <-ctx.Done():
-					return
-				}
-			}
-		}()
+		go forwardStream(ctx, s.streamPub.Join(ctx, -1), updates)
 	}
 
 	if conversationID == "" {
@@ -1960,6 +2038,7 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 	// This is important because getOrCreateConversationManager may create a system prompt
 	// message during hydration, and we want to return the messages as they were before.
 	var messages []generated.Message
+	var replayed []APIMessage // instead of messages, when resuming from memory
 	var conversation generated.Conversation
 	// resuming: client is not asking for the full history, so skip the
 	// context_window_size calculation (which only makes sense over it).
@@ -2007,14 +2086,29 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 			lastSeqID = messages[len(messages)-1].SequenceID
 		}
 	default:
+		// An active conversation can usually replay what the client missed
+		// from memory, if the database has nothing newer; otherwise it is
+		// read from the database.
+		var replayOK bool
+		replayed, replayOK = s.replayMessages(conversationID, lastSeqID)
 		err := s.db.Queries(ctx, func(q *generated.Queries) error {
 			var err error
-			messages, err = q.ListMessagesSince(ctx, generated.ListMessagesSinceParams{
-				ConversationID: conversationID,
-				SequenceID:     lastSeqID,
-			})
-			if err != nil {
-				return err
+			if replayOK {
+				nextSeq, err := q.GetNextSequenceID(ctx, conversationID)
+				if err != nil {
+					return err
+				}
+				replayOK = nextSeq-1 == lastSeqID+int64(len(replayed))
+			}
+			if !replayOK {
+				replayed = nil
+				messages, err = q.ListMessagesSince(ctx, generated.ListMessagesSinceParams{
+					ConversationID: conversationID,
+					SequenceID:     lastSeqID,
+				})
+				if err != nil {
+					return err
+				}
 			}
 			conversation, err = q.GetConversation(ctx, conversationID)
 			return err
@@ -2027,6 +2121,9 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 		if len(messages) > 0 {
 			lastSeqID = messages[len(messages)-1].SequenceID
 		}
+		if len(replayed) > 0 {
+			lastSeqID = replayed[len(replayed)-1].SequenceID
+		}
 	}
 
 	manager, err := s.getOrCreateConversationManager(ctx, conversationID, "")
@@ -2035,22 +2132,31 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 		errAfterStreamStart(w, "Internal server error")
 		return
 	}
+	manager.logger.Debug("stream subscriber connected", "unified", includeConversationListPatches, "last_sequence_id", lastSeqID)
+	status, err := s.conversationStatus(ctx, conversationID, conversation.Archived)
+	if err != nil {
+		manager.logger.Warn("Failed to get conversation status", "error", err)
+	}
+	defer manager.logger.Debug("stream subscriber disconnected", "unified", includeConversationListPatches)
 
 	// On /api/stream2, live events arrive via the server-wide streamPub
-	// subscription set up above. The per-conversation subpub is used only by
+	// subscription set up above. The per-conversation topics are used only by
 	// the legacy /api/conversation/<id>/stream endpoint.
 	//
 	// Subscribe BEFORE sending initial data so we don't miss broadcasts that
-	// happen between the DB query and the start of the event loop. The subpub
-	// channel is buffered (10), so events arriving while we write the initial
-	// response are queued rather than lost.
-	var next func() (StreamResponse, bool)
+	// happen between the DB query and the start of the event loop. The
+	// subscription is buffered (streamBufferSize), so events arriving while we write the
+	// initial response are queued rather than lost.
+	var sub *subpub.Subscription[StreamResponse]
 	if !includeConversationListPatches {
-		next = manager.subpub.Subscribe(ctx, lastSeqID)
+		sub = s.conversationPub.Subscribe(ctx, conversationID, lastSeqID)
 	}
 
+	apiMessages := replayed
 	if len(messages) > 0 {
-		apiMessages := toAPIMessages(messages)
+		apiMessages = toAPIMessages(messages)
+	}
+	if len(apiMessages) > 0 {
 		// Only send context_window_size for fresh connections where we have all messages.
 		// On resume we only have the missed messages, so the calculation would be wrong.
 		// The client keeps its previous value and gets updates from subsequent stream events.
@@ -2066,6 +2172,7 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 				ConversationID: conversationID,
 				Working:        conversation.AgentWorking,
 				Model:          manager.GetModel(),
+				Status:         status,
 			},
 			ContextWindowSize: ctxSize,
 		}
@@ -2081,6 +2188,7 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 				ConversationID: conversationID,
 				Working:        conversation.AgentWorking,
 				Model:          manager.GetModel(),
+				Status:         status,
 			},
 			Heartbeat: true,
 		}
@@ -2117,6 +2225,10 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 				if err != nil {
 					continue // Skip heartbeat on error
 				}
+				status, err := s.conversationStatus(ctx, conversationID, conv.Archived)
+				if err != nil {
+					continue
+				}
 
 				heartbeat := StreamResponse{
 					Conversation: &conv,
@@ -2124,6 +2236,7 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 						ConversationID: conversationID,
 						Working:        conv.AgentWorking,
 						Model:          manager.GetModel(),
+						Status:         status,
 					},
 					Heartbeat: true,
 				}
@@ -2133,20 +2246,8 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 	}()
 	defer close(heartbeatDone)
 
-	if next != nil {
-		go func() {
-			for {
-				streamData, cont := next()
-				if !cont {
-					return
-				}
-				select {
-				case updates <- streamData:
-				case <-ctx.Done():
-					return
-				}
-			}
-		}()
+	if sub != nil {
+		go forwardStream(ctx, sub, updates)
 	}
 
 	ticker := time.NewTicker(30 * time.Second)
@@ -2171,6 +2272,58 @@ func (s *Server) runStream(w http.ResponseWriter, r *http.Request, conversationI
 	}
 }
 
+// replayMessages returns the messages after lastSeqID that conversationID's
+// topic still holds in memory, in order. It returns false if the client is
+// too far behind, or some of the messages were not published, so they must
+// be read from the database.
+func (s *Server) replayMessages(conversationID string, lastSeqID int64) ([]APIMessage, bool) {
+	events, ok := s.conversationPub.Replay(conversationID, lastSeqID)
+	if !ok {
+		return nil, false
+	}
+	var msgs []APIMessage
+	for _, ev := range events {
+		for _, m := range ev.Messages {
+			if m.SequenceID > lastSeqID {
+				msgs = append(msgs, m)
+			}
+		}
+	}
+	slices.SortFunc(msgs, func(a, b APIMessage) int { return cmp.Compare(a.SequenceID, b.SequenceID) })
+	msgs = slices.CompactFunc(msgs, func(a, b APIMessage) bool { return a.SequenceID == b.SequenceID })
+	// Sequence ids are consecutive, so a gap is a message that was stored
+	// without being published.
+	for i, m := range msgs {
+		if m.SequenceID != lastSeqID+1+int64(i) {
+			return nil, false
+		}
+	}
+	return msgs, true
+}
+
+// forwardStream sends sub's events to updates until either is done. When
+// sub dropped events because the client fell behind, it sends a Resync first.
+func forwardStream(ctx context.Context, sub *subpub.Subscription[StreamResponse], updates chan<- StreamResponse) {
+	for {
+		streamData, ok := sub.Next()
+		if !ok {
+			return
+		}
+		if sub.Missed() > 0 {
+			select {
+			case updates <- StreamResponse{Resync: true}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case updates <- streamData:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 // handleVersion returns build information plus the capabilities list as
 // JSON. The capabilities slot lets clients negotiate optional, additive
 // features without reshaping the response. See version.Capabilities for
@@ -2201,6 +2354,7 @@ type ModelInfo struct {
 	Source           string `json:"source,omitempty"`   // Human-readable source (e.g., "exe.dev gateway", "$ANTHROPIC_API_KEY")
 	BaseURL          string `json:"base_url,omitempty"` // Upstream origin (e.g., "https://llm.int.exe.xyz")
 	APIType          string `json:"api_type,omitempty"` // Wire protocol (e.g., "anthropic-messages")
+	Provider         string `json:"provider,omitempty"` // Upstream API family (e.g., "anthropic")
 	Ready            bool   `json:"ready"`
 	MaxContextTokens int    `json:"max_context_tokens,omitempty"`
 	IsDefault        bool   `json:"is_default,omitempty"`
@@ -2218,6 +2372,9 @@ type ModelInfo struct {
 	// badge blank. Empty means the provider picks its own default and Shelley
 	// can't name it up front.
 	DefaultReasoningLevel string `json:"default_reasoning_level,omitempty"`
+	// Cost is the model's pricing in USD per million tokens, nil when the
+	// model catalog doesn't know it.
+	Cost *modelsdev.Cost `json:"cost,omitempty"`
 }
 
 // handleModelCommand intercepts the built-in "/model" slash command. It
@@ -2541,6 +2698,10 @@ func (s *Server) getModelList() []ModelInfo {
 				info.Source = modelInfo.Source
 				info.BaseURL = modelInfo.BaseURL
 				info.APIType = modelInfo.APIType
+				info.Provider = modelInfo.Provider
+				if c, found := modelsdev.LookupCost(modelInfo.BaseURL, modelInfo.APIModelName); found {
+					info.Cost = &c
+				}
 			}
 			modelList = append(modelList, info)
 		}
@@ -2581,7 +2742,7 @@ func (s *Server) effectiveDefaultModel(modelList []ModelInfo) string {
 	if len(modelList) == 0 {
 		return ""
 	}
-	candidates := []string{s.defaultModel, models.Default().ID}
+	candidates := []string{s.currentDefaultModel(), models.Default().ID}
 	for _, c := range candidates {
 		if c == "" {
 			continue
@@ -2627,17 +2788,7 @@ func (s *Server) handleModelRefresh(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "model refresh is not configured", http.StatusNotImplemented)
 		return
 	}
-	refresher, ok := s.llmManager.(builtModelRefresher)
-	if !ok {
-		http.Error(w, "model manager does not support refresh", http.StatusInternalServerError)
-		return
-	}
-	builtModels, err := s.refreshBuiltModels(r.Context())
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if err := refresher.RefreshBuiltModels(builtModels); err != nil {
+	if err := s.refreshModels(r.Context()); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -2647,6 +2798,20 @@ func (s *Server) handleModelRefresh(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(modelList)
 }
 
+// refreshModels rebuilds the non-custom model catalog with the function set
+// by SetModelRefresher.
+func (s *Server) refreshModels(ctx context.Context) error {
+	refresher, ok := s.llmManager.(builtModelRefresher)
+	if !ok {
+		return errors.New("model manager does not support refresh")
+	}
+	builtModels, err := s.refreshBuiltModels(ctx)
+	if err != nil {
+		return err
+	}
+	return refresher.RefreshBuiltModels(builtModels)
+}
+
 // markDefaultModel sets IsDefault=true on the entry matching defaultID.
 func markDefaultModel(modelList []ModelInfo, defaultID string) {
 	if defaultID == "" {
@@ -2770,6 +2935,7 @@ func (s *Server) handleArchiveConversation(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	s.setConversationStatus(ctx, conversationID, StatusArchived)
 
 	// Notify conversation list subscribers
 	go s.publishConversationListUpdate(ConversationListUpdate{
@@ -2795,6 +2961,7 @@ func (s *Server) handleUnarchiveConversation(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	s.setConversationStatus(ctx, conversationID, StatusIdle)
 
 	// Notify conversation list subscribers
 	go s.publishConversationListUpdate(ConversationListUpdate{
@@ -2814,11 +2981,16 @@ func (s *Server) handleDeleteConversation(w http.ResponseWriter, r *http.Request
 	}
 
 	ctx := r.Context()
+	// Read the conversation first for its workspace; nil if it's missing.
+	conv, _ := s.db.GetConversationByID(ctx, conversationID)
 	if err := s.db.DeleteConversation(ctx, conversationID); err != nil {
 		s.logger.Error("Failed to delete conversation", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	if conv != nil {
+		go s.removeWorkspace(conv)
+	}
 
 	// Notify conversation list subscribers about the deletion
 	go s.publishConversationListUpdate(ConversationListUpdate{
@@ -3226,20 +3398,37 @@ func (s *Server) handleSetSetting(w http.ResponseWriter, r *http.Request) {
 
 	// Only allow known setting keys
 	allowedKeys := map[string]bool{
-		"auto_upgrade":      true,
-		exeNotifySettingKey: true,
+		"auto_upgrade":            true,
+		exeNotifySettingKey:       true,
+		notifyByDefaultSettingKey: true,
+		quietHoursSettingKey:      true,
+		quietModeSettingKey:       true,
+		digestIntervalSettingKey:  true,
 	}
 	if !allowedKeys[req.Key] {
 		http.Error(w, fmt.Sprintf("Invalid setting key: %s", req.Key), http.StatusBadRequest)
 		return
 	}
 
+	isScheduleKey := slices.Contains(scheduleSettingKeys, req.Key)
+	var schedule notifications.Schedule
+	if isScheduleKey {
+		var err error
+		if schedule, err = s.notificationSchedule(r.Context(), req.Key, req.Value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	if err := s.db.SetSetting(r.Context(), req.Key, req.Value); err != nil {
 		s.logger.Error("Failed to set setting", "error", err, "key", req.Key)
 		http.Error(w, fmt.Sprintf("Failed to set setting: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	if isScheduleKey {
+		s.notifScheduler.SetSchedule(schedule)
+	}
 	// The exe_notify setting is read on each end-of-turn (see
 	// withExeNotifyHook), so no dispatcher reload is needed here.
 
@@ -3333,6 +3522,87 @@ func (s *Server) handleRegisterConversationHook(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(map[string]string{"status": "registered"})
 }
 
+// SetConversationNotificationsRequest is the body for
+// POST /conversation/<id>/notifications.
+type SetConversationNotificationsRequest struct {
+	// Notifications is "subscribed", "muted", or "" to follow the
+	// server-wide default.
+	Notifications string `json:"notifications"`
+}
+
+// handleSetConversationNotifications handles POST /conversation/<id>/notifications
+func (s *Server) handleSetConversationNotifications(w http.ResponseWriter, r *http.Request, conversationID string) {
+	var req SetConversationNotificationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	switch req.Notifications {
+	case "", db.NotificationsSubscribed, db.NotificationsMuted:
+	default:
+		http.Error(w, fmt.Sprintf("Invalid notifications value %q: want %q, %q, or \"\"", req.Notifications, db.NotificationsSubscribed, db.NotificationsMuted), http.StatusBadRequest)
+		return
+	}
+
+	manager, err := s.getOrCreateConversationManager(r.Context(), conversationID, r.Header.Get("X-ExeDev-Email"))
+	if errors.Is(err, errConversationModelMismatch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get conversation manager", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := manager.SetNotifications(r.Context(), req.Notifications); err != nil {
+		s.logger.Error("Failed to set conversation notifications", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"notifications": req.Notifications})
+}
+
+// SetConversationInstructionsRequest is the body for
+// POST /conversation/<id>/instructions.
+type SetConversationInstructionsRequest struct {
+	// Instructions replace the conversation's instructions; "" removes them.
+	Instructions string `json:"instructions"`
+}
+
+// handleSetConversationInstructions handles POST /conversation/<id>/instructions
+func (s *Server) handleSetConversationInstructions(w http.ResponseWriter, r *http.Request, conversationID string) {
+	var req SetConversationInstructionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if msg := validateConversationOptions(db.ConversationOptions{Instructions: req.Instructions}); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	manager, err := s.getOrCreateConversationManager(r.Context(), conversationID, r.Header.Get("X-ExeDev-Email"))
+	if errors.Is(err, errConversationModelMismatch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get conversation manager", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := manager.SetInstructions(r.Context(), req.Instructions); err != nil {
+		s.logger.Error("Failed to set conversation instructions", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"instructions": req.Instructions})
+}
+
 // ForkRequest is the body for POST /conversation/<id>/fork. The fork copies all
 // messages up to and including the message identified by MessageID (preferred)
 // or SequenceID into a new conversation.
@@ -3657,9 +3927,35 @@ func validateConversationOptions(opts db.ConversationOptions) string {
 			return fmt.Sprintf("Invalid thinking_level: %q; must be one of off, minimal, low, medium, high, xhigh", opts.ThinkingLevel)
 		}
 	}
+	for name, p := range opts.ToolPolicies {
+		if p.TimeoutSeconds < 0 || p.MaxOutputBytes < 0 || p.MaxConcurrent < 0 {
+			return fmt.Sprintf("Invalid tool_policies[%s]; limits must not be negative", name)
+		}
+	}
+	if len(opts.Instructions) > maxInstructionsBytes {
+		return fmt.Sprintf("Instructions are %d bytes; the limit is %d", len(opts.Instructions), maxInstructionsBytes)
+	}
+	if ws := opts.Workspace; ws != nil {
+		switch {
+		case ws.Repo == "":
+			return "workspace.repo is required"
+		case ws.Container != "" || ws.Dir != "" || ws.Base != "":
+			return "workspace.container, workspace.dir, and workspace.base are set by the server"
+		case opts.Worker != "":
+			return "A conversation can't have both a worker and a workspace"
+		}
+	}
 	return ""
 }
 
+// errDraftWorkspace rejects drafts that ask for a workspace, which is
+// created with the conversation.
+const errDraftWorkspace = "Workspaces can only be requested with /api/conversations/new"
+
+// maxInstructionsBytes caps a conversation's instructions, which are sent
+// with every request.
+const maxInstructionsBytes = 16 << 10
+
 // CreateDraftRequest is the body for POST /api/conversations/draft.
 type CreateDraftRequest struct {
 	Draft               string                  `json:"draft"`
@@ -3704,6 +4000,10 @@ func (s *Server) handleCreateDraft(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if convOpts.Workspace != nil {
+		http.Error(w, errDraftWorkspace, http.StatusBadRequest)
+		return
+	}
 	conv, err := s.db.CreateDraftConversation(ctx, cwdPtr, &modelID, convOpts, req.Draft)
 	if err != nil {
 		s.logger.Error("Failed to create draft", "error", err)
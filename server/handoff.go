@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+// HandoffMessenger implements claudetool.ConversationMessenger.
+type HandoffMessenger struct {
+	server *Server
+}
+
+// NewHandoffMessenger creates a new HandoffMessenger.
+func NewHandoffMessenger(s *Server) *HandoffMessenger {
+	return &HandoffMessenger{server: s}
+}
+
+// SendToConversation implements claudetool.ConversationMessenger. to is
+// resolved as a conversation id first, then as a slug.
+func (m *HandoffMessenger) SendToConversation(ctx context.Context, fromID, to, message string) (string, error) {
+	s := m.server
+	from, err := s.db.GetConversationByID(ctx, fromID)
+	if err != nil {
+		return "", err
+	}
+	target, err := s.db.GetConversationByID(ctx, to)
+	if err != nil {
+		target, err = s.db.GetConversationBySlug(ctx, to)
+		if err != nil {
+			return "", fmt.Errorf("no conversation with id or slug %q", to)
+		}
+	}
+	if err := s.postHandoff(ctx, from, target, message); err != nil {
+		return "", err
+	}
+	return target.ConversationID, nil
+}
+
+// postHandoff queues message as a user message in target, attributed to
+// from. Queueing never interrupts a turn in progress; an idle target starts
+// working right away. The sender is recorded both in the text (so the
+// recipient's model knows who is asking) and in the row's user_data (so the
+// UI can link back).
+func (s *Server) postHandoff(ctx context.Context, from, target *generated.Conversation, message string) error {
+	if from.ConversationID == target.ConversationID {
+		return fmt.Errorf("cannot hand off to the same conversation")
+	}
+	if target.Archived {
+		return fmt.Errorf("conversation %s is archived", target.ConversationID)
+	}
+	modelID := derefString(target.Model)
+	if modelID == "" {
+		modelID = s.effectiveDefaultModel(s.getModelList())
+	}
+	if _, err := s.llmManager.GetService(modelID); err != nil {
+		return fmt.Errorf("unsupported model %q: %w", modelID, err)
+	}
+	manager, err := s.getOrCreateConversationManager(ctx, target.ConversationID, "")
+	if err != nil {
+		return fmt.Errorf("failed to get conversation manager: %w", err)
+	}
+	name := from.ConversationID
+	if from.Slug != nil && *from.Slug != "" {
+		name = fmt.Sprintf("%s (%s)", *from.Slug, from.ConversationID)
+	}
+	userMessage := llm.Message{
+		Role:    llm.MessageRoleUser,
+		Content: []llm.Content{{Type: llm.ContentTypeText, Text: fmt.Sprintf("[Message from conversation %s]\n\n%s", name, message)}},
+	}
+	ctx = contextWithHandoffFrom(ctx, from.ConversationID)
+	if err := manager.QueueMessage(ctx, s, modelID, userMessage); err != nil {
+		return fmt.Errorf("failed to queue handoff message: %w", err)
+	}
+	return nil
+}
+
+// HandoffRequest is the body of POST /api/conversation/{id}/handoff.
+type HandoffRequest struct {
+	FromConversationID string `json:"from_conversation_id"`
+	Message            string `json:"message"`
+}
+
+// handleHandoff posts a message from one conversation into another.
+func (s *Server) handleHandoff(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+	var req HandoffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.FromConversationID == "" || strings.TrimSpace(req.Message) == "" {
+		http.Error(w, "from_conversation_id and message are required", http.StatusBadRequest)
+		return
+	}
+	target, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	from, err := s.db.GetConversationByID(ctx, req.FromConversationID)
+	if err != nil {
+		http.Error(w, "Source conversation not found", http.StatusNotFound)
+		return
+	}
+	if err := s.postHandoff(ctx, from, target, req.Message); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "queued"})
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db"
+)
+
+func TestHandoff(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	defer stopActiveConversationLoops(server)
+	ctx := context.Background()
+
+	triageSlug := "triage"
+	triage, err := database.CreateConversation(ctx, &triageSlug, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frontendSlug := "frontend"
+	frontend, err := database.CreateConversation(ctx, &frontendSlug, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.handleHandoff(w, httptest.NewRequest("POST", "/", strings.NewReader(body)), target)
+		return w
+	}
+	if w := post(frontend.ConversationID, `{"from_conversation_id":"`+frontend.ConversationID+`","message":"x"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("self handoff: status %d, want 400", w.Code)
+	}
+	if w := post("missing", `{"from_conversation_id":"`+triage.ConversationID+`","message":"x"}`); w.Code != http.StatusNotFound {
+		t.Errorf("missing target: status %d, want 404", w.Code)
+	}
+
+	// The tool path resolves the target by slug.
+	id, err := NewHandoffMessenger(server).SendToConversation(ctx, triage.ConversationID, "frontend", "fix the login button")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != frontend.ConversationID {
+		t.Fatalf("resolved %s, want %s", id, frontend.ConversationID)
+	}
+
+	var from string
+	waitFor(t, 5*time.Second, func() bool {
+		msgs, err := database.ListMessages(ctx, frontend.ConversationID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range msgs {
+			if m.Type != string(db.MessageTypeUser) || m.LlmData == nil || !strings.Contains(*m.LlmData, "fix the login button") {
+				continue
+			}
+			if !strings.Contains(*m.LlmData, "[Message from conversation triage ("+triage.ConversationID+")]") {
+				t.Fatalf("message lacks sender header: %s", *m.LlmData)
+			}
+			var ud map[string]string
+			if m.UserData != nil {
+				json.Unmarshal([]byte(*m.UserData), &ud)
+			}
+			from = ud["handoff_from"]
+			return true
+		}
+		return false
+	})
+	if from != triage.ConversationID {
+		t.Errorf("handoff_from = %q, want %q", from, triage.ConversationID)
+	}
+}
@@ -363,10 +363,15 @@ func TestLlmDataForAPISingleParse(t *testing.T) {
 		t.Errorf("unexpected image URL: %q", parsed.Content[1].DisplayImageURL)
 	}
 
-	// Non-agent message: no end_of_turn pointer, but still stripped.
+	// Error messages end turns too.
+	if _, errorEOT := llmDataForAPI(&s, string(db.MessageTypeError), "msg-eot"); errorEOT == nil || !*errorEOT {
+		t.Errorf("expected end_of_turn=true for error message, got %v", errorEOT)
+	}
+
+	// User message: no end_of_turn pointer, but still stripped.
 	_, userEOT := llmDataForAPI(&s, string(db.MessageTypeUser), "msg-eot")
 	if userEOT != nil {
-		t.Errorf("expected nil end_of_turn for non-agent message, got %v", *userEOT)
+		t.Errorf("expected nil end_of_turn for user message, got %v", *userEOT)
 	}
 
 	// nil input returns (nil, nil).
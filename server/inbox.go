@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/inbox"
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/slug"
+)
+
+// inboxMaxMessage is the largest email the gateway takes.
+const inboxMaxMessage = 25 << 20
+
+// emailMessageIDPattern matches the Message-IDs of replies, which carry
+// their conversation's ID so that answering one continues it.
+var emailMessageIDPattern = regexp.MustCompile(`^<shelley\.([A-Za-z0-9]+)\.[0-9]+@`)
+
+// SetInbox makes the server take email at POST /api/email/inbound as c
+// configures: a new message starts a conversation, an answer to one of the
+// server's replies continues it, and the final response of each turn is
+// emailed back.
+func (s *Server) SetInbox(c inbox.Config) {
+	s.inbox = c
+	s.sendEmail = c.SMTP.Send
+}
+
+// handleInboundEmail handles POST /api/email/inbound, a raw RFC 5322
+// message from the inbound mail service.
+func (s *Server) handleInboundEmail(w http.ResponseWriter, r *http.Request) {
+	if !s.inbox.Enabled() {
+		http.Error(w, "email gateway is disabled: no inbox.token is configured", http.StatusNotFound)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		_, token, _ = r.BasicAuth()
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.inbox.Token)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	m, err := inbox.Parse(io.LimitReader(r.Body, inboxMaxMessage))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid email: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !s.inbox.Allowed(m.From) {
+		s.logger.Warn("Rejected email from a sender not allowed", "from", m.From)
+		http.Error(w, "sender not allowed", http.StatusForbidden)
+		return
+	}
+	go s.runEmail(m)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// runEmail sends m to its conversation, emailing the error back if that
+// fails.
+func (s *Server) runEmail(m *inbox.Message) {
+	if err := s.startEmail(context.Background(), m); err != nil {
+		s.logger.Error("Failed to start emailed conversation", "from", m.From, "error", err)
+		reply := inbox.ReplyTo(m)
+		reply.Body = fmt.Sprintf("Couldn't start: %v", err)
+		if err := s.sendEmail(reply); err != nil {
+			s.logger.Error("Failed to send email reply", "to", reply.To, "error", err)
+		}
+	}
+}
+
+// startEmail starts a turn for m in the conversation it answers, or in a
+// new one.
+func (s *Server) startEmail(ctx context.Context, m *inbox.Message) error {
+	var paths []string
+	for _, a := range m.Attachments {
+		path, err := saveUploadFile(a.Name, bytes.NewReader(a.Data))
+		if err != nil {
+			return fmt.Errorf("failed to save %s: %w", a.Name, err)
+		}
+		paths = append(paths, path)
+	}
+	prompt := fmt.Sprintf("%s replied by email:\n\n%s", m.From, m.Text)
+	conv := s.emailConversation(ctx, m)
+	if conv == nil {
+		var err error
+		if conv, err = s.createEmailConversation(ctx, m); err != nil {
+			return err
+		}
+		prompt = fmt.Sprintf("%s emailed you, with the subject %q:\n\n%s\n\nYour final message is emailed back to them.", m.From, m.Subject, m.Text)
+	}
+	if len(paths) > 0 {
+		prompt += "\n\nThe attachments are saved as:\n- " + strings.Join(paths, "\n- ")
+	}
+
+	modelID := derefString(conv.Model)
+	service, err := s.llmManager.GetService(modelID)
+	if err != nil {
+		return err
+	}
+	manager, err := s.getOrCreateConversationManager(ctx, conv.ConversationID, "")
+	if err != nil {
+		return err
+	}
+	replied := func() { go s.replyToEmail(conv.ConversationID, m) }
+	if manager.IsAgentWorking() || !s.awaitTurnEnd(conv.ConversationID, replied) {
+		return fmt.Errorf("still working on your previous message; send this again once it's answered")
+	}
+	msg := llm.Message{Role: llm.MessageRoleUser, Content: []llm.Content{{Type: llm.ContentTypeText, Text: prompt}}}
+	if _, err := manager.AcceptUserMessage(ctx, service, modelID, msg); err != nil {
+		s.cancelTurnEnd(conv.ConversationID)
+		return err
+	}
+	return nil
+}
+
+// emailConversation returns the conversation one of the server's replies
+// that m answers belongs to, or nil. Only the sender whose email started
+// the conversation may continue it: anyone can put a conversation's ID in
+// a Message-ID.
+func (s *Server) emailConversation(ctx context.Context, m *inbox.Message) *generated.Conversation {
+	for _, ref := range m.References {
+		match := emailMessageIDPattern.FindStringSubmatch(ref)
+		if match == nil {
+			continue
+		}
+		conv, err := s.db.GetConversationByID(ctx, match[1])
+		if err != nil {
+			continue
+		}
+		if from := db.ParseConversationOptions(conv.ConversationOptions).EmailFrom; from == "" || !strings.EqualFold(from, m.From) {
+			s.logger.Warn("Ignoring email reference to a conversation the sender didn't start", "from", m.From, "conversationID", conv.ConversationID)
+			continue
+		}
+		return conv
+	}
+	return nil
+}
+
+// createEmailConversation creates the conversation for a new email, named
+// after its subject.
+func (s *Server) createEmailConversation(ctx context.Context, m *inbox.Message) (*generated.Conversation, error) {
+	modelID := s.inbox.Model
+	if modelID == "" {
+		var err error
+		if modelID, err = s.defaultModelIn(s.inbox.Cwd, s.getModelList()); err != nil {
+			return nil, err
+		}
+	}
+	var cwd *string
+	if s.inbox.Cwd != "" {
+		cwd = &s.inbox.Cwd
+	}
+	conv, err := s.db.CreateConversation(ctx, nil, true, cwd, &modelID, db.ConversationOptions{EmailFrom: m.From})
+	if err != nil {
+		return nil, err
+	}
+	if name := slug.Sanitize(m.Subject); name != "" {
+		if name, err = slug.Assign(ctx, s.db, s.logger, conv.ConversationID, name); err != nil {
+			s.logger.Warn("Failed to name emailed conversation", "conversationID", conv.ConversationID, "error", err)
+		} else {
+			conv.Slug = &name
+		}
+	}
+	go s.publishConversationListUpdate(ConversationListUpdate{Type: "update", Conversation: conv})
+	return conv, nil
+}
+
+// replyToEmail emails the final response of the turn m started back to
+// its sender.
+func (s *Server) replyToEmail(conversationID string, m *inbox.Message) {
+	ctx := context.Background()
+	reply := inbox.ReplyTo(m)
+	reply.MessageID = emailMessageID(conversationID, s.inbox.SMTP.From)
+	if msgs, err := s.db.ListAgentMessagesSinceLastUser(ctx, conversationID); err == nil {
+		reply.Body = finalResponseBody(msgs)
+	}
+	if reply.Body == "" {
+		reply.Body = "Done."
+	}
+	if conv, err := s.db.GetConversationByID(ctx, conversationID); err == nil {
+		reply.Body += "\n\n-- \n" + s.conversationURL(derefString(conv.Slug))
+	}
+	if err := s.sendEmail(reply); err != nil {
+		s.logger.Error("Failed to send email reply", "conversationID", conversationID, "to", reply.To, "error", err)
+	}
+}
+
+// emailMessageID returns a new Message-ID for a reply in conversationID,
+// at the domain of the from address.
+func emailMessageID(conversationID, from string) string {
+	domain := "shelley"
+	if _, d, ok := strings.Cut(strings.Trim(from, "<> "), "@"); ok {
+		domain = strings.TrimSuffix(d, ">")
+	}
+	return fmt.Sprintf("<shelley.%s.%d@%s>", conversationID, time.Now().UnixNano(), domain)
+}
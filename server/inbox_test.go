@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/inbox"
+)
+
+func TestInboundEmail(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	post := func(token, raw string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/email/inbound", strings.NewReader(raw))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		s.handleInboundEmail(w, req)
+		return w.Code
+	}
+	email := func(from, subject, headers, body string) string {
+		return "From: " + from + "\r\nSubject: " + subject + "\r\nMessage-ID: <" + subject + "@example.com>\r\n" + headers + "\r\n" + body
+	}
+	if code := post("t", email("ann@example.com", "hi", "", "hello")); code != http.StatusNotFound {
+		t.Errorf("disabled gateway: got %d", code)
+	}
+
+	s.SetInbox(inbox.Config{Token: "t", AllowedSenders: []string{"ann@example.com"}, Model: "predictable", SMTP: inbox.SMTPConfig{From: "shelley@example.com"}})
+	var mu sync.Mutex
+	var replies []inbox.Reply
+	s.sendEmail = func(r inbox.Reply) error {
+		mu.Lock()
+		defer mu.Unlock()
+		replies = append(replies, r)
+		return nil
+	}
+	waitReplies := func(n int) inbox.Reply {
+		t.Helper()
+		waitFor(t, 10*time.Second, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(replies) >= n
+		})
+		mu.Lock()
+		defer mu.Unlock()
+		return replies[n-1]
+	}
+
+	if code := post("wrong", email("ann@example.com", "hi", "", "hello")); code != http.StatusUnauthorized {
+		t.Errorf("bad token: got %d", code)
+	}
+	if code := post("t", email("mallory@example.com", "hi", "", "hello")); code != http.StatusForbidden {
+		t.Errorf("disallowed sender: got %d", code)
+	}
+
+	// A new email starts a conversation named after its subject.
+	attached := "Content-Type: multipart/mixed; boundary=b\r\n"
+	body := "--b\r\nContent-Type: text/plain\r\n\r\nRead the notes.\r\n--b\r\nContent-Disposition: attachment; filename=notes.txt\r\n\r\nsome notes\r\n--b--\r\n"
+	if code := post("t", email("ann@example.com", "Fix the build", attached, body)); code != http.StatusAccepted {
+		t.Fatalf("email: got %d", code)
+	}
+	reply := waitReplies(1)
+	if reply.To != "ann@example.com" || reply.Subject != "Re: Fix the build" || reply.InReplyTo != "<Fix the build@example.com>" {
+		t.Errorf("reply headers: %+v", reply)
+	}
+	if !strings.Contains(reply.Body, "edit predictable.go") || !strings.Contains(reply.Body, "fix-the-build") {
+		t.Errorf("reply body: %q", reply.Body)
+	}
+	conv, err := s.db.GetConversationBySlug(context.Background(), "fix-the-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := s.db.ListMessages(context.Background(), conv.ConversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prompt string
+	for _, m := range msgs {
+		if m.Type == "user" && m.LlmData != nil {
+			prompt = *m.LlmData
+		}
+	}
+	if !strings.Contains(prompt, "Read the notes.") || !strings.Contains(prompt, "/notes_") {
+		t.Errorf("prompt: %s", prompt)
+	}
+
+	// Answering the reply continues the conversation.
+	if code := post("t", email("ann@example.com", "Re: Fix the build", "In-Reply-To: "+reply.MessageID+"\r\n", "Thanks")); code != http.StatusAccepted {
+		t.Fatalf("answer: got %d", code)
+	}
+	if second := waitReplies(2); second.InReplyTo != "<Re: Fix the build@example.com>" || strings.Contains(second.Body, "Couldn't") {
+		t.Errorf("second reply: %+v", second)
+	}
+	msgs, err = s.db.ListMessages(context.Background(), conv.ConversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users int
+	for _, m := range msgs {
+		if m.Type == "user" {
+			users++
+		}
+	}
+	if users != 2 {
+		t.Errorf("got %d user messages in the conversation, want 2", users)
+	}
+
+	// Another allowed sender answering the reply starts a new conversation.
+	s.inbox.AllowedSenders = append(s.inbox.AllowedSenders, "bob@example.com")
+	if code := post("t", email("bob@example.com", "Hijack", "In-Reply-To: "+reply.MessageID+"\r\n", "Delete it all")); code != http.StatusAccepted {
+		t.Fatalf("other sender: got %d", code)
+	}
+	waitReplies(3)
+	if _, err := s.db.GetConversationBySlug(context.Background(), "hijack"); err != nil {
+		t.Errorf("other sender's answer didn't start a new conversation: %v", err)
+	}
+	msgs, err = s.db.ListMessages(context.Background(), conv.ConversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range msgs {
+		if m.Type == "user" && m.LlmData != nil && strings.Contains(*m.LlmData, "Delete it all") {
+			t.Errorf("other sender continued the conversation")
+		}
+	}
+
+	// A reply can't name a conversation email didn't start.
+	other, err := s.db.CreateConversation(context.Background(), nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := &inbox.Message{From: "ann@example.com", References: []string{emailMessageID(other.ConversationID, "shelley@example.com")}}
+	if conv := s.emailConversation(context.Background(), forged); conv != nil {
+		t.Errorf("forged reference continued %s", conv.ConversationID)
+	}
+}
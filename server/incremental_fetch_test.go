@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -52,7 +53,7 @@ func newTestStreamServer(t *testing.T, database *db.DB) (*http.ServeMux, *Server
 	t.Helper()
 	ps := loop.NewPredictableService()
 	srv := NewServer(database, &testLLMManager{service: ps},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		true, "predictable", "")
 	mux := http.NewServeMux()
@@ -221,3 +222,45 @@ func TestStreamTailAndLastSeqIsBadRequest(t *testing.T) {
 		t.Fatalf("want 400, got %d; body=%q", w.Code, w.Body.String())
 	}
 }
+
+// TestStreamResumeReplaysFromMemory: an active conversation serves a
+// resuming client from its replay buffer, and falls back to the database
+// for messages it didn't publish, such as the system prompt.
+func TestStreamResumeReplaysFromMemory(t *testing.T) {
+	t.Parallel()
+	database, cleanup := setupTestDB(t)
+	t.Cleanup(cleanup)
+	id := seedConversation(t, database, 2)
+	_, srv := newTestStreamServer(t, database)
+
+	manager, err := srv.getOrCreateConversationManager(context.Background(), id, "")
+	if err != nil {
+		t.Fatalf("getOrCreateConversationManager: %v", err)
+	}
+	// Hydration stored the system prompt as message 3. Store message 4 and
+	// publish it under a different id, to tell the replayed copy apart.
+	if _, err := database.CreateMessage(context.Background(), db.CreateMessageParams{
+		ConversationID: id,
+		Type:           db.MessageTypeAgent,
+		LLMData:        llm.Message{Role: llm.MessageRoleAssistant, Content: []llm.Content{{Type: llm.ContentTypeText, Text: "m4"}}},
+	}); err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	manager.publishStream(4, StreamResponse{Messages: []APIMessage{{MessageID: "replayed", SequenceID: 4}}})
+
+	messageIDs := func(query string) []string {
+		var ids []string
+		for _, f := range runStreamWithQuery(t, srv, id, query, 10) {
+			for _, m := range f.Messages {
+				ids = append(ids, m.MessageID)
+			}
+		}
+		return ids
+	}
+	if got := messageIDs("last_sequence_id=3"); len(got) != 1 || got[0] != "replayed" {
+		t.Errorf("resuming after the system prompt got messages %q, want [replayed]", got)
+	}
+	if got := messageIDs("last_sequence_id=2"); len(got) != 2 || slices.Contains(got, "replayed") {
+		t.Errorf("resuming before the system prompt got messages %q, want both from the database", got)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+func TestMessageTimings(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	defer stopActiveConversationLoops(server)
+	ctx := context.Background()
+	conversation, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	convID := conversation.ConversationID
+
+	sendChat(t, server, convID, "delay: 0.5", false)
+	sendChat(t, server, convID, "bash: echo hi", true)
+
+	var latency, toolDuration, queueWait *int64
+	waitFor(t, 10*time.Second, func() bool {
+		msgs, err := database.ListMessages(ctx, convID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range msgs {
+			switch {
+			case m.Type == string(db.MessageTypeAgent) && m.UsageData != nil && latency == nil:
+				var u llm.Usage
+				json.Unmarshal([]byte(*m.UsageData), &u)
+				latency = &u.LatencyMs
+			case m.Type == string(db.MessageTypeUser) && m.DisplayData != nil:
+				var items []struct {
+					DurationMs *int64 `json:"duration_ms"`
+				}
+				json.Unmarshal([]byte(*m.DisplayData), &items)
+				if len(items) == 1 {
+					toolDuration = items[0].DurationMs
+				}
+			case m.Type == string(db.MessageTypeUser) && m.UserData != nil:
+				queueWait = userDataInt(m, "queue_wait_ms")
+			}
+		}
+		return toolDuration != nil && queueWait != nil
+	})
+	if latency == nil || *latency < 500 {
+		t.Errorf("latency_ms of the delayed response = %v, want at least 500", latency)
+	}
+	if *queueWait < 200 {
+		t.Errorf("queue_wait_ms = %d, want most of the delayed turn", *queueWait)
+	}
+}
+
+// userDataInt returns the number at key in m's user_data, if there is one.
+func userDataInt(m generated.Message, key string) *int64 {
+	var ud map[string]any
+	json.Unmarshal([]byte(*m.UserData), &ud)
+	f, ok := ud[key].(float64)
+	if !ok {
+		return nil
+	}
+	n := int64(f)
+	return &n
+}
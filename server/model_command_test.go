@@ -85,7 +85,7 @@ func newTwoModelTestServer(t *testing.T) (*Server, *db.DB) {
 	t.Cleanup(cleanup)
 	ps := loop.NewPredictableService()
 	svr := NewServer(database, &twoModelLLMManager{service: ps},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		false, "model-a", "")
 	svr.hooksDir = t.TempDir()
@@ -516,7 +516,7 @@ func TestModelCommandAmbiguous(t *testing.T) {
 	t.Cleanup(cleanup)
 	ps := loop.NewPredictableService()
 	srv := NewServer(database, &levelNamedModelLLMManager{service: ps},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		false, "model-a", "")
 	srv.hooksDir = t.TempDir()
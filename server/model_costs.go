@@ -1,9 +1,14 @@
 package server
 
 import (
+	"cmp"
 	"encoding/json"
 	"net/http"
+	"slices"
+	"time"
 
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
 	"shelley.exe.dev/models/modelsdev"
 )
 
@@ -56,23 +61,139 @@ func (s *Server) handleSubagentUsage(w http.ResponseWriter, r *http.Request, con
 	for _, row := range rows {
 		resp.LLMCalls += row.LlmCalls
 		resp.ReportedUsd += row.CostUsd
-		model, url := "", ""
-		if row.ModelName != nil {
-			model = *row.ModelName
-		}
-		if row.LlmApiUrl != nil {
-			url = *row.LlmApiUrl
-		}
-		if c, found := modelsdev.LookupCost(url, model); found {
-			resp.EstimatedUsd += float64(row.InputTokens)*c.Input/1e6 +
-				float64(row.CacheCreationInputTokens)*c.CacheWrite/1e6 +
-				float64(row.CacheReadInputTokens)*c.CacheRead/1e6 +
-				float64(row.OutputTokens)*c.Output/1e6
+		if usd, found := estimateSubagentCost(row); found {
+			resp.EstimatedUsd += usd
 		} else {
-			resp.UnpricedModels = append(resp.UnpricedModels, model)
+			resp.UnpricedModels = append(resp.UnpricedModels, derefString(row.ModelName))
 			resp.UnpricedCalls += row.LlmCalls
 		}
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
+
+// estimateSubagentCost prices a subagent usage row from the model catalog,
+// reporting whether the model has pricing.
+func estimateSubagentCost(row generated.GetSubagentUsageRow) (float64, bool) {
+	c, found := modelsdev.LookupCost(derefString(row.LlmApiUrl), derefString(row.ModelName))
+	if !found {
+		return 0, false
+	}
+	return float64(row.InputTokens)*c.Input/1e6 +
+		float64(row.CacheCreationInputTokens)*c.CacheWrite/1e6 +
+		float64(row.CacheReadInputTokens)*c.CacheRead/1e6 +
+		float64(row.OutputTokens)*c.Output/1e6, true
+}
+
+// usageCostUSD prices one LLM call from the model catalog, falling back to
+// the cost the provider reported.
+func usageCostUSD(u llm.Usage) float64 {
+	c, found := modelsdev.LookupCost(u.URL, u.Model)
+	if !found {
+		return u.CostUSD
+	}
+	return float64(u.InputTokens)*c.Input/1e6 +
+		float64(u.CacheCreationInputTokens)*c.CacheWrite/1e6 +
+		float64(u.CacheReadInputTokens)*c.CacheRead/1e6 +
+		float64(u.OutputTokens)*c.Output/1e6
+}
+
+// UsageTotals is one line of the usage report: a model's or a
+// conversation's LLM usage, or the grand total.
+type UsageTotals struct {
+	Model          string  `json:"model,omitempty"`
+	ConversationID string  `json:"conversation_id,omitempty"`
+	Slug           string  `json:"slug,omitempty"`
+	LLMCalls       int64   `json:"llm_calls"`
+	InputTokens    int64   `json:"input_tokens"`
+	CacheWrite     int64   `json:"cache_creation_input_tokens"`
+	CacheRead      int64   `json:"cache_read_input_tokens"`
+	OutputTokens   int64   `json:"output_tokens"`
+	CostUSD        float64 `json:"cost_usd"`
+}
+
+func (t *UsageTotals) add(row generated.GetUsageSinceRow, costUSD float64) {
+	t.LLMCalls += row.LlmCalls
+	t.InputTokens += row.InputTokens
+	t.CacheWrite += row.CacheCreationInputTokens
+	t.CacheRead += row.CacheReadInputTokens
+	t.OutputTokens += row.OutputTokens
+	t.CostUSD += costUSD
+}
+
+// handleUsage totals LLM usage across all conversations since ?since=
+// (RFC 3339; all time if omitted), grouped by ?by=model (the default) or
+// ?by=conversation, most expensive first. Costs are priced from the model
+// catalog, falling back to what the provider reported.
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid since (want RFC 3339): "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = "model"
+	}
+	if by != "model" && by != "conversation" {
+		http.Error(w, `by must be "model" or "conversation"`, http.StatusBadRequest)
+		return
+	}
+
+	rows, err := s.db.GetUsageSince(r.Context(), since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	groups := make(map[string]*UsageTotals)
+	var total UsageTotals
+	for _, row := range rows {
+		cost, found := estimateSubagentCost(generated.GetSubagentUsageRow{
+			ModelName:                row.ModelName,
+			LlmApiUrl:                row.LlmApiUrl,
+			InputTokens:              row.InputTokens,
+			CacheCreationInputTokens: row.CacheCreationInputTokens,
+			CacheReadInputTokens:     row.CacheReadInputTokens,
+			OutputTokens:             row.OutputTokens,
+		})
+		if !found {
+			cost = row.CostUsd
+		}
+		key := derefString(row.ModelName)
+		if by == "conversation" {
+			key = row.ConversationID
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &UsageTotals{}
+			if by == "conversation" {
+				g.ConversationID, g.Slug = row.ConversationID, derefString(row.Slug)
+			} else {
+				g.Model = key
+			}
+			groups[key] = g
+		}
+		g.add(row, cost)
+		total.add(row, cost)
+	}
+	resp := struct {
+		By    string         `json:"by"`
+		Since *time.Time     `json:"since,omitempty"`
+		Rows  []*UsageTotals `json:"rows"`
+		Total UsageTotals    `json:"total"`
+	}{By: by, Rows: make([]*UsageTotals, 0, len(groups)), Total: total}
+	if !since.IsZero() {
+		resp.Since = &since
+	}
+	for _, g := range groups {
+		resp.Rows = append(resp.Rows, g)
+	}
+	slices.SortFunc(resp.Rows, func(a, b *UsageTotals) int {
+		return cmp.Or(cmp.Compare(b.CostUSD, a.CostUSD), cmp.Compare(a.Model+a.ConversationID, b.Model+b.ConversationID))
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
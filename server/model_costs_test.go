@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/models/modelsdev"
@@ -130,3 +131,86 @@ func TestSubagentUsageHandler(t *testing.T) {
 		t.Errorf("leaf llm_calls = %d, want 0", res2.LLMCalls)
 	}
 }
+
+func TestUsageHandler(t *testing.T) {
+	t.Parallel()
+	srv, database, _ := newTestServer(t)
+	ctx := t.Context()
+
+	a, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addUsage := func(convID, model, url string, in, out int64, costUsd float64) {
+		t.Helper()
+		_, err := database.CreateMessage(ctx, db.CreateMessageParams{
+			ConversationID: convID,
+			Type:           db.MessageTypeAgent,
+			UsageData: map[string]any{
+				"input_tokens": in, "output_tokens": out,
+				"model": model, "cost_usd": costUsd,
+			},
+			ModelName: model,
+			LLMAPIURL: url,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Priced from the catalog: 1M input @$5 + 1M output @$25 = $30 each.
+	addUsage(a.ConversationID, "claude-opus-4-6", "https://llm.int.exe.xyz/v1/messages", 1_000_000, 1_000_000, 1)
+	addUsage(b.ConversationID, "claude-opus-4-6", "https://llm.int.exe.xyz/v1/messages", 1_000_000, 1_000_000, 1)
+	// Unpriced: falls back to the reported cost.
+	addUsage(b.ConversationID, "mystery-model", "", 100, 100, 0.5)
+
+	type report struct {
+		By    string        `json:"by"`
+		Rows  []UsageTotals `json:"rows"`
+		Total UsageTotals   `json:"total"`
+	}
+	get := func(query string) (int, report) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.handleUsage(w, httptest.NewRequest("GET", "/api/usage?"+query, nil))
+		var res report
+		if w.Code == 200 {
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, res
+	}
+
+	_, byModel := get("")
+	if byModel.By != "model" || len(byModel.Rows) != 2 {
+		t.Fatalf("by model = %+v, want 2 model rows", byModel)
+	}
+	if r := byModel.Rows[0]; r.Model != "claude-opus-4-6" || r.LLMCalls != 2 || r.CostUSD < 59.99 || r.CostUSD > 60.01 {
+		t.Errorf("first row = %+v, want claude-opus-4-6 with 2 calls costing ~$60", r)
+	}
+	if r := byModel.Rows[1]; r.Model != "mystery-model" || r.CostUSD != 0.5 {
+		t.Errorf("second row = %+v, want mystery-model at the reported $0.50", r)
+	}
+	if byModel.Total.LLMCalls != 3 || byModel.Total.InputTokens != 2_000_100 {
+		t.Errorf("total = %+v, want 3 calls and 2000100 input tokens", byModel.Total)
+	}
+
+	_, byConv := get("by=conversation")
+	if len(byConv.Rows) != 2 || byConv.Rows[0].ConversationID != b.ConversationID || byConv.Rows[0].LLMCalls != 2 {
+		t.Errorf("by conversation = %+v, want %s (2 calls) first", byConv.Rows, b.ConversationID)
+	}
+
+	if _, future := get("since=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339)); len(future.Rows) != 0 || future.Total.LLMCalls != 0 {
+		t.Errorf("since an hour from now = %+v, want nothing", future)
+	}
+	if code, _ := get("by=day"); code != 400 {
+		t.Errorf("by=day: status %d, want 400", code)
+	}
+	if code, _ := get("since=7d"); code != 400 {
+		t.Errorf("since=7d: status %d, want 400", code)
+	}
+}
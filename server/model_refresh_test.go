@@ -97,6 +97,43 @@ func TestHandleModelsAssignsTiers(t *testing.T) {
 	}
 }
 
+func TestHandleModelsReportsProviderAndCost(t *testing.T) {
+	mgr, err := models.NewManager(&models.Config{
+		Models: []models.Built{
+			{ID: "claude-opus-4.7", Provider: models.ProviderAnthropic, BaseURL: "https://api.anthropic.com", APIModelName: "claude-opus-4-7", Service: loop.NewPredictableService()},
+			{ID: "unpriced", Provider: models.ProviderBuiltIn, Service: loop.NewPredictableService()},
+		},
+		Logger: slog.Default(),
+	})
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	s := &Server{llmManager: mgr, logger: slog.Default()}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/models", nil)
+	rec := httptest.NewRecorder()
+	s.handleModels(rec, req)
+
+	var got []ModelInfo
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	byID := map[string]ModelInfo{}
+	for _, m := range got {
+		byID[m.ID] = m
+	}
+	opus := byID["claude-opus-4.7"]
+	if opus.Provider != string(models.ProviderAnthropic) {
+		t.Errorf("provider = %q, want %q", opus.Provider, models.ProviderAnthropic)
+	}
+	if opus.Cost == nil || opus.Cost.Input <= 0 || opus.Cost.Output <= 0 {
+		t.Errorf("cost = %+v, want catalog pricing", opus.Cost)
+	}
+	if c := byID["unpriced"].Cost; c != nil {
+		t.Errorf("unpriced cost = %+v, want nil", c)
+	}
+}
+
 func TestAssignModelTiersKeepsCustomModelsProminent(t *testing.T) {
 	modelList := []ModelInfo{
 		{ID: "gpt-5.6-sol", Source: "llm.int.exe.xyz", Ready: true},
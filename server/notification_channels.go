@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +21,22 @@ import (
 // turns it off.
 const exeNotifySettingKey = "exe_notify"
 
+// notifyByDefaultSettingKey is the settings key controlling whether
+// conversations without their own subscription choice get notifications.
+// They do unless it is set to "false".
+const notifyByDefaultSettingKey = "notify_by_default"
+
+// The notification schedule settings, in notifications.ParseSchedule's
+// argument order: quiet hours ("22:00-07:00"), quiet mode ("suppress" or
+// "queue"), and digest interval ("1h").
+const (
+	quietHoursSettingKey     = "notification_quiet_hours"
+	quietModeSettingKey      = "notification_quiet_mode"
+	digestIntervalSettingKey = "notification_digest_interval"
+)
+
+var scheduleSettingKeys = []string{quietHoursSettingKey, quietModeSettingKey, digestIntervalSettingKey}
+
 // exeNotifyGatewayURL is the exe.dev push-notification gateway, reachable from
 // inside the VM via the built-in "notify" integration. It is the same URL the
 // iOS app registers as an end-of-turn hook, so auto-configuring it here
@@ -63,25 +81,34 @@ type ChannelTypeInfo struct {
 	ConfigFields []ConfigField `json:"config_fields"`
 }
 
+// templateConfigFields are the optional message templates shared by the
+// channels that format messages (see notifications.ParseTemplates).
+var templateConfigFields = []ConfigField{
+	{Name: "title_template", Label: "Title Template", Type: "string", Placeholder: "{{.Slug}} finished in {{.Duration}}", Description: "Optional. A Go text/template for the title, with .Type, .ConversationID, .Hostname, .Slug, .Model, .URL, .Text, .Duration, .CostUSD, and .Timestamp."},
+	{Name: "body_template", Label: "Body Template", Type: "string", Placeholder: "{{.Text}}", Description: "Optional. A Go text/template for the body, with the same fields as the title template."},
+}
+
 var channelTypeInfo = map[string]ChannelTypeInfo{
 	"discord": {
 		Type:  "discord",
 		Label: "Discord Webhook",
-		ConfigFields: []ConfigField{
+		ConfigFields: append([]ConfigField{
 			{Name: "webhook_url", Label: "Webhook URL", Type: "string", Required: true, Placeholder: "https://discord.com/api/webhooks/..."},
-		},
+			{Name: "username", Label: "Username", Type: "string", Placeholder: "Shelley", Description: "Optional. Overrides the webhook's name."},
+			{Name: "avatar_url", Label: "Avatar URL", Type: "string", Placeholder: "https://...", Description: "Optional. Overrides the webhook's avatar."},
+		}, templateConfigFields...),
 	},
 	"email": {
 		Type:  "email",
 		Label: "Email (exe.dev)",
-		ConfigFields: []ConfigField{
+		ConfigFields: append([]ConfigField{
 			{Name: "to", Label: "Recipient Email", Type: "string", Required: true, Placeholder: "you@example.com"},
-		},
+		}, templateConfigFields...),
 	},
 	"ntfy": {
 		Type:  "ntfy",
 		Label: "ntfy",
-		ConfigFields: []ConfigField{
+		ConfigFields: append([]ConfigField{
 			{Name: "server", Label: "Server URL", Type: "string", Required: true, Placeholder: "https://ntfy.sh", Default: "https://ntfy.sh"},
 			{Name: "topic", Label: "Topic", Type: "string", Required: true, Placeholder: "my-shelley-notifications"},
 			{Name: "token", Label: "Access Token", Type: "password", Placeholder: "tk_...", Description: "Optional. For private topics, provide either an access token or username and password."},
@@ -89,8 +116,36 @@ var channelTypeInfo = map[string]ChannelTypeInfo{
 			{Name: "password", Label: "Password", Type: "password", Description: "Optional. For private topics, use with username."},
 			{Name: "done_priority", Label: "Done Priority", Type: "string", Required: true, Default: "default", Options: []string{"min", "low", "default", "high", "max"}},
 			{Name: "error_priority", Label: "Error Priority", Type: "string", Required: true, Default: "high", Options: []string{"min", "low", "default", "high", "max"}},
+		}, templateConfigFields...),
+	},
+	"pushover": {
+		Type:  "pushover",
+		Label: "Pushover",
+		ConfigFields: append([]ConfigField{
+			{Name: "token", Label: "Application Token", Type: "password", Required: true, Placeholder: "azGDORePK8gMaC0QOYAMyEEuzJnyUi"},
+			{Name: "user", Label: "User Key", Type: "password", Required: true, Placeholder: "uQiRzpo4DXghDmr9QzzfQu27cmVRsG", Description: "Your user or group key."},
+			{Name: "done_priority", Label: "Done Priority", Type: "string", Required: true, Default: "normal", Options: []string{"lowest", "low", "normal", "high", "emergency"}},
+			{Name: "error_priority", Label: "Error Priority", Type: "string", Required: true, Default: "high", Options: []string{"lowest", "low", "normal", "high", "emergency"}, Description: "Also used for alerts. Emergency repeats every minute until acknowledged, for up to an hour."},
+		}, templateConfigFields...),
+	},
+	"webhook": {
+		Type:  "webhook",
+		Label: "Webhook",
+		ConfigFields: []ConfigField{
+			{Name: "url", Label: "URL", Type: "string", Required: true, Placeholder: "https://example.com/shelley-events"},
+			{Name: "secret", Label: "Signing Secret", Type: "password", Description: "Optional. Signs each request body with HMAC-SHA256 in the X-Shelley-Signature-256 header as sha256=<hex>."},
+			{Name: "headers", Label: "Headers", Type: "string", Placeholder: "Authorization: Bearer ...; X-Source: shelley", Description: "Optional. Extra headers as Name: value pairs separated by semicolons."},
 		},
 	},
+	"slack": {
+		Type:  "slack",
+		Label: "Slack",
+		ConfigFields: append([]ConfigField{
+			{Name: "webhook_url", Label: "Webhook URL", Type: "password", Placeholder: "https://hooks.slack.com/services/...", Description: "An incoming webhook. Alternatively, provide a bot token and channel."},
+			{Name: "bot_token", Label: "Bot Token", Type: "password", Placeholder: "xoxb-...", Description: "Optional. A bot token with chat:write, used instead of a webhook URL."},
+			{Name: "channel", Label: "Channel", Type: "string", Placeholder: "#shelley", Description: "Required with a bot token."},
+		}, templateConfigFields...),
+	},
 }
 
 func toNotificationChannelAPI(ch generated.NotificationChannel) NotificationChannelAPI {
@@ -317,21 +372,50 @@ func (s *Server) handleDeleteNotificationChannel(w http.ResponseWriter, r *http.
 }
 
 func (s *Server) handleTestNotificationChannel(w http.ResponseWriter, r *http.Request, channelID string) {
+	s.writeTestNotificationResult(w, r, channelID, notifications.EventAgentDone)
+}
+
+// TestNotificationRequest is the body for POST /api/admin/notifications/test.
+type TestNotificationRequest struct {
+	ChannelID string `json:"channel_id"`
+	// EventType is the kind of synthetic event to send; agent_done if empty.
+	EventType notifications.EventType `json:"event_type,omitempty"`
+}
+
+// handleAdminTestNotification handles POST /api/admin/notifications/test,
+// sending a synthetic event of any type through one configured channel.
+func (s *Server) handleAdminTestNotification(w http.ResponseWriter, r *http.Request) {
+	var req TestNotificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.ChannelID == "" {
+		http.Error(w, "channel_id is required", http.StatusBadRequest)
+		return
+	}
+	if req.EventType == "" {
+		req.EventType = notifications.EventAgentDone
+	}
+	s.writeTestNotificationResult(w, r, req.ChannelID, req.EventType)
+}
+
+// writeTestNotificationResult sends a synthetic event through a configured
+// channel, enabled or not, and reports the outcome as
+// {"success": bool, "message": string}.
+func (s *Server) writeTestNotificationResult(w http.ResponseWriter, r *http.Request, channelID string, eventType notifications.EventType) {
+	testEvent, err := testNotificationEvent(eventType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	dbCh, err := s.db.GetNotificationChannel(r.Context(), channelID)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Channel not found: %v", err), http.StatusNotFound)
 		return
 	}
 
-	config := map[string]any{"type": dbCh.ChannelType}
-	var extra map[string]any
-	if err := json.Unmarshal([]byte(dbCh.Config), &extra); err == nil {
-		for k, v := range extra {
-			config[k] = v
-		}
-	}
-
-	ch, err := notifications.CreateFromConfig(config, s.logger)
+	ch, err := newStoredChannel(*dbCh, s.logger)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]any{
@@ -344,17 +428,9 @@ func (s *Server) handleTestNotificationChannel(w http.ResponseWriter, r *http.Re
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	testEvent := notifications.Event{
-		Type:      notifications.EventAgentDone,
-		Timestamp: time.Now(),
-		Payload: notifications.AgentDonePayload{
-			Model:             "test",
-			ConversationTitle: "test conversation",
-			FinalResponse:     "This is a test notification to verify your channel is working.",
-		},
-	}
-
-	if err := ch.Send(ctx, testEvent); err != nil {
+	err = ch.Send(ctx, testEvent)
+	s.recordNotificationDelivery(context.WithoutCancel(ctx), notifications.Delivery{Channel: ch, Event: testEvent, Err: err})
+	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]any{
 			"success": false,
@@ -370,6 +446,95 @@ func (s *Server) handleTestNotificationChannel(w http.ResponseWriter, r *http.Re
 	})
 }
 
+// testNotificationEvent returns a synthetic event of the given type.
+func testNotificationEvent(eventType notifications.EventType) (notifications.Event, error) {
+	event := notifications.Event{Type: eventType, Timestamp: time.Now()}
+	switch eventType {
+	case notifications.EventAgentDone:
+		event.Payload = notifications.AgentDonePayload{
+			Model:             "test",
+			ConversationTitle: "test conversation",
+			FinalResponse:     "This is a test notification to verify your channel is working.",
+		}
+	case notifications.EventAgentError:
+		event.Payload = notifications.AgentErrorPayload{
+			ErrorMessage: "This is a test error notification to verify your channel is working.",
+		}
+	case notifications.EventBudgetExceeded, notifications.EventToolFailureStreak, notifications.EventAgentWaiting:
+		event.Payload = notifications.AlertPayload{
+			ConversationTitle: "test conversation",
+			Message:           "This is a test alert to verify your channel is working.",
+		}
+	default:
+		return notifications.Event{}, fmt.Errorf("unknown event type: %q", eventType)
+	}
+	return event, nil
+}
+
+// handleNotificationDeliveries handles GET /api/admin/notifications/deliveries,
+// listing recent delivery attempts newest first. ?limit= caps the count
+// (default 100).
+func (s *Server) handleNotificationDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit := int64(100)
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	deliveries, err := s.db.ListNotificationDeliveries(r.Context(), limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list notification deliveries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// storedChannel is a channel built from a notification_channels row,
+// remembering the row's ID for the delivery log.
+type storedChannel struct {
+	notifications.Channel
+	id string
+}
+
+func newStoredChannel(dbCh generated.NotificationChannel, logger *slog.Logger) (*storedChannel, error) {
+	config := map[string]any{"type": dbCh.ChannelType}
+	var extra map[string]any
+	if err := json.Unmarshal([]byte(dbCh.Config), &extra); err == nil {
+		for k, v := range extra {
+			config[k] = v
+		}
+	}
+	ch, err := notifications.CreateFromConfig(config, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &storedChannel{Channel: ch, id: dbCh.ChannelID}, nil
+}
+
+// recordNotificationDelivery adds a delivery attempt to the delivery log.
+func (s *Server) recordNotificationDelivery(ctx context.Context, d notifications.Delivery) {
+	params := generated.InsertNotificationDeliveryParams{
+		ChannelType:    d.Channel.Name(),
+		EventType:      string(d.Event.Type),
+		ConversationID: d.Event.ConversationID,
+		Status:         "sent",
+	}
+	if sc, ok := d.Channel.(*storedChannel); ok {
+		params.ChannelID = sc.id
+	}
+	if d.Err != nil {
+		params.Status = "failed"
+		params.Error = d.Err.Error()
+	}
+	if err := s.db.RecordNotificationDelivery(ctx, params); err != nil {
+		s.logger.Warn("failed to record notification delivery", "channel", params.ChannelType, "error", err)
+	}
+}
+
 func (s *Server) handleNotificationChannelTypes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -434,14 +599,7 @@ func (s *Server) ReloadNotificationChannels() {
 
 	var active []notifications.Channel
 	for _, dbCh := range channels {
-		config := map[string]any{"type": dbCh.ChannelType}
-		var extra map[string]any
-		if err := json.Unmarshal([]byte(dbCh.Config), &extra); err == nil {
-			for k, v := range extra {
-				config[k] = v
-			}
-		}
-		ch, err := notifications.CreateFromConfig(config, s.logger)
+		ch, err := newStoredChannel(dbCh, s.logger)
 		if err != nil {
 			s.logger.Warn("Failed to create notification channel", "id", dbCh.ChannelID, "error", err)
 			continue
@@ -451,4 +609,29 @@ func (s *Server) ReloadNotificationChannels() {
 
 	s.notifDispatcher.ReplaceChannels(active)
 	s.logger.Info("Reloaded notification channels", "count", len(active))
+
+	schedule, err := s.notificationSchedule(context.Background(), "", "")
+	if err != nil {
+		s.logger.Error("Failed to load notification schedule", "error", err)
+		return
+	}
+	s.notifScheduler.SetSchedule(schedule)
+}
+
+// notificationSchedule parses the schedule settings, with value standing in
+// for the stored value of key when key is set.
+func (s *Server) notificationSchedule(ctx context.Context, key, value string) (notifications.Schedule, error) {
+	var vals [3]string
+	for i, k := range scheduleSettingKeys {
+		if k == key {
+			vals[i] = value
+			continue
+		}
+		v, err := s.db.GetSetting(ctx, k)
+		if err != nil {
+			return notifications.Schedule{}, err
+		}
+		vals[i] = v
+	}
+	return notifications.ParseSchedule(vals[0], vals[1], vals[2])
 }
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/server/notifications"
+	_ "shelley.exe.dev/server/notifications/channels"
+)
+
+func TestAdminTestNotificationRecordsDelivery(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer ntfy.Close()
+
+	ch, err := database.CreateNotificationChannel(context.Background(), generated.CreateNotificationChannelParams{
+		ChannelID:   "notif-test",
+		ChannelType: "ntfy",
+		DisplayName: "phone",
+		Config:      `{"server":"` + ntfy.URL + `","topic":"t"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	server.SetAdminToken("secret")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/admin/notifications/test", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+	if w := post(`{"channel_id":"notif-test","event_type":"nope"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown event type: got %d, want 400", w.Code)
+	}
+	w := post(`{"channel_id":"notif-test","event_type":"tool_failure_streak"}`)
+	var result struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode %q: %v", w.Body, err)
+	}
+	if result.Success || !strings.Contains(result.Message, "401") {
+		t.Fatalf("expected a 401 failure, got %+v", result)
+	}
+
+	r := httptest.NewRequest("GET", "/api/admin/notifications/deliveries", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	var deliveries []generated.NotificationDelivery
+	if err := json.Unmarshal(w.Body.Bytes(), &deliveries); err != nil {
+		t.Fatalf("decode %q: %v", w.Body, err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	d := deliveries[0]
+	if d.ChannelID != ch.ChannelID || d.ChannelType != "ntfy" || d.EventType != "tool_failure_streak" || d.Status != "failed" || !strings.Contains(d.Error, "401") {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+}
+
+func TestNotificationScheduleSettings(t *testing.T) {
+	t.Parallel()
+	server, _, _ := newTestServer(t)
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+
+	set := func(key, value string) int {
+		w := httptest.NewRecorder()
+		body := `{"key":"` + key + `","value":"` + value + `"}`
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/settings", strings.NewReader(body)))
+		return w.Code
+	}
+	for _, tc := range []struct{ key, value string }{
+		{quietHoursSettingKey, "22:00"},
+		{quietModeSettingKey, "later"},
+		{digestIntervalSettingKey, "-1h"},
+	} {
+		if code := set(tc.key, tc.value); code != http.StatusBadRequest {
+			t.Errorf("%s=%q: got %d, want 400", tc.key, tc.value, code)
+		}
+	}
+	if code := set(quietHoursSettingKey, "22:00-07:00"); code != http.StatusOK {
+		t.Fatalf("valid quiet hours: got %d", code)
+	}
+	got, err := server.notificationSchedule(context.Background(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.QuietStart != 22*time.Hour || got.QuietEnd != 7*time.Hour || got.QueueQuiet {
+		t.Fatalf("unexpected schedule: %+v", got)
+	}
+}
+
+func TestNotificationQuietHoursAndDigest(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	ch := &recordingChannel{}
+	server.RegisterNotificationChannel(ch)
+	ctx := context.Background()
+
+	// A queued quiet window around now holds everything until it's lifted.
+	now := time.Now()
+	sinceMidnight := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	server.notifScheduler.SetSchedule(notifications.Schedule{
+		QuietStart: (sinceMidnight + 23*time.Hour) % (24 * time.Hour),
+		QuietEnd:   (sinceMidnight + time.Hour) % (24 * time.Hour),
+		QueueQuiet: true,
+	})
+	var convIDs []string
+	for range 2 {
+		conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		convIDs = append(convIDs, conv.ConversationID)
+		server.publishConversationState(ConversationState{ConversationID: conv.ConversationID, Model: "predictable"})
+	}
+	server.alertConversation(ctx, convIDs[0], notifications.EventToolFailureStreak, "failing")
+	ch.mu.Lock()
+	held := len(ch.events)
+	ch.mu.Unlock()
+	if held != 0 {
+		t.Fatalf("%d notifications sent during quiet hours", held)
+	}
+
+	// Lifting quiet hours with a digest on releases the alert at once and
+	// batches both finished turns into one digest.
+	server.notifScheduler.SetSchedule(notifications.Schedule{DigestInterval: 10 * time.Millisecond})
+	waitFor(t, 5*time.Second, func() bool {
+		ch.mu.Lock()
+		defer ch.mu.Unlock()
+		return len(ch.events) == 2
+	})
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.events[0].Type != notifications.EventToolFailureStreak {
+		t.Fatalf("first event: got %s, want the alert", ch.events[0].Type)
+	}
+	digest, ok := ch.events[1].Payload.(notifications.DigestPayload)
+	if ch.events[1].Type != notifications.EventDigest || !ok || len(digest.Turns) != 2 {
+		t.Fatalf("unexpected digest: %+v", ch.events[1])
+	}
+}
+
+func TestNtfyAlertActions(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+	ctx := context.Background()
+	bodies := make(chan []byte, 1)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer ntfy.Close()
+
+	if _, err := database.CreateNotificationChannel(ctx, generated.CreateNotificationChannelParams{
+		ChannelID:   "notif-ntfy",
+		ChannelType: "ntfy",
+		DisplayName: "phone",
+		Enabled:     1,
+		Config:      `{"server":"` + ntfy.URL + `","topic":"t"}`,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	server.ReloadNotificationChannels()
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.alertConversation(ctx, conv.ConversationID, notifications.EventToolFailureStreak, "failing")
+	var msg struct {
+		Actions []struct {
+			Action string `json:"action"`
+			URL    string `json:"url"`
+			Method string `json:"method"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal(<-bodies, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.Actions) != 2 || msg.Actions[0].Action != "view" {
+		t.Fatalf("unexpected actions: %+v", msg.Actions)
+	}
+	cancel := msg.Actions[1]
+	if cancel.Action != "http" || cancel.Method != "POST" || !strings.HasSuffix(cancel.URL, "/api/conversation/"+conv.ConversationID+"/cancel") {
+		t.Fatalf("unexpected cancel action: %+v", cancel)
+	}
+}
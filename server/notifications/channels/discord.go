@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"shelley.exe.dev/server/notifications"
@@ -19,13 +20,25 @@ func init() {
 		if !ok || url == "" {
 			return nil, fmt.Errorf("discord channel requires \"webhook_url\"")
 		}
-		return newDiscord(url), nil
+		templates, err := notifications.ParseTemplates(config)
+		if err != nil {
+			return nil, fmt.Errorf("discord channel: %w", err)
+		}
+		d := newDiscord(url)
+		d.username, _ = config["username"].(string)
+		d.avatarURL, _ = config["avatar_url"].(string)
+		d.templates = templates
+		return d, nil
 	})
 }
 
 type discord struct {
 	webhookURL string
-	client     *http.Client
+	// username and avatarURL override the webhook's defaults when set.
+	username  string
+	avatarURL string
+	templates notifications.Templates
+	client    *http.Client
 }
 
 func newDiscord(webhookURL string) *discord {
@@ -44,33 +57,86 @@ func (d *discord) Send(ctx context.Context, event notifications.Event) error {
 	if msg == nil {
 		return nil
 	}
+	msg.Username = d.username
+	msg.AvatarURL = d.avatarURL
+	embed := &msg.Embeds[0]
+	title, description, err := d.templates.Render(event, embed.Title, embed.Description)
+	if err != nil {
+		return err
+	}
+	embed.Title, embed.Description = title, truncate(description, discordMaxDescription)
 
 	body, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("marshal discord payload: %w", err)
 	}
 
+	for attempt := 1; ; attempt++ {
+		retryAfter, err := d.post(ctx, body)
+		if err == nil || retryAfter == 0 || retryAfter > discordMaxRetryWait || attempt == discordMaxAttempts {
+			return err
+		}
+		select {
+		case <-time.After(retryAfter):
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry aborted: %w)", err, ctx.Err())
+		}
+	}
+}
+
+// Retries are bounded because notifications are sent synchronously at the
+// end of each turn.
+const (
+	// discordMaxAttempts bounds how often a rate-limited or failed post is sent.
+	discordMaxAttempts = 3
+	// discordMaxRetryWait is the longest rate limit worth waiting out.
+	discordMaxRetryWait = 5 * time.Second
+)
+
+// post sends one webhook request. For rate limits and server errors it also
+// returns how long to wait before retrying; other errors are not retried.
+func (d *discord) post(ctx context.Context, body []byte) (retryAfter time.Duration, err error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.webhookURL, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("create discord request: %w", err)
+		return 0, fmt.Errorf("create discord request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := d.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("send discord webhook: %w", err)
+		return 0, fmt.Errorf("send discord webhook: %w", err)
 	}
 	defer resp.Body.Close()
-	io.Copy(io.Discard, resp.Body)
-
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("discord webhook returned %d", resp.StatusCode)
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// Discord gives the wait in seconds, in the body and the
+		// Retry-After header.
+		var limit struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		json.Unmarshal(b, &limit)
+		if limit.RetryAfter == 0 {
+			limit.RetryAfter, _ = strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+		}
+		retryAfter = time.Duration(limit.RetryAfter * float64(time.Second))
+		if retryAfter <= 0 {
+			retryAfter = time.Second
+		}
+		return retryAfter, fmt.Errorf("discord webhook rate limited for %s", retryAfter)
+	case resp.StatusCode >= 500:
+		return 2 * time.Second, fmt.Errorf("discord webhook returned %d", resp.StatusCode)
+	case resp.StatusCode >= 400:
+		return 0, fmt.Errorf("discord webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(b))
 	}
-	return nil
+	return 0, nil
 }
 
 type discordMessage struct {
-	Embeds []discordEmbed `json:"embeds"`
+	Username  string         `json:"username,omitempty"`
+	AvatarURL string         `json:"avatar_url,omitempty"`
+	Embeds    []discordEmbed `json:"embeds"`
 }
 
 type discordEmbed struct {
@@ -79,11 +145,25 @@ type discordEmbed struct {
 	Description string `json:"description,omitempty"`
 	Color       int    `json:"color"`
 	Timestamp   string `json:"timestamp,omitempty"`
+
+	Footer *discordEmbedFooter `json:"footer,omitempty"`
+}
+
+type discordEmbedFooter struct {
+	Text string `json:"text"`
 }
 
 // discordMaxDescription is Discord's embed description limit.
 const discordMaxDescription = 4096
 
+// truncate shortens s to at most max bytes, marking the cut with "...".
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
+
 func formatDiscordMessage(event notifications.Event) *discordMessage {
 	switch event.Type {
 	case notifications.EventAgentDone:
@@ -94,12 +174,10 @@ func formatDiscordMessage(event notifications.Event) *discordMessage {
 		if p, ok := event.Payload.(notifications.AgentDonePayload); ok {
 			embed.Title = notifications.Title(p.Hostname, p.ConversationTitle)
 			embed.URL = p.ConversationURL
-			if p.FinalResponse != "" {
-				embed.Description = p.FinalResponse
-				if len(embed.Description) > discordMaxDescription {
-					embed.Description = embed.Description[:discordMaxDescription-3] + "..."
-				}
+			if p.Model != "" {
+				embed.Footer = &discordEmbedFooter{Text: p.Model}
 			}
+			embed.Description = p.FinalResponse
 		} else {
 			embed.Title = "Agent finished"
 		}
@@ -121,6 +199,33 @@ func formatDiscordMessage(event notifications.Event) *discordMessage {
 		}
 		return &discordMessage{Embeds: []discordEmbed{embed}}
 
+	case notifications.EventBudgetExceeded, notifications.EventToolFailureStreak, notifications.EventAgentWaiting:
+		embed := discordEmbed{
+			Color:     0xf59e0b, // amber
+			Timestamp: event.Timestamp.Format(time.RFC3339),
+		}
+		if p, ok := event.Payload.(notifications.AlertPayload); ok {
+			embed.Title = notifications.Title(p.Hostname, p.ConversationTitle)
+			embed.URL = p.ConversationURL
+			embed.Description = p.Message
+		} else {
+			embed.Title = "Agent needs attention"
+		}
+		return &discordMessage{Embeds: []discordEmbed{embed}}
+
+	case notifications.EventDigest:
+		embed := discordEmbed{
+			Color:     0x22c55e, // green
+			Timestamp: event.Timestamp.Format(time.RFC3339),
+		}
+		if p, ok := event.Payload.(notifications.DigestPayload); ok {
+			embed.Title = p.Title()
+			embed.Description = p.Summary()
+		} else {
+			embed.Title = "Agent turns finished"
+		}
+		return &discordMessage{Embeds: []discordEmbed{embed}}
+
 	default:
 		return nil
 	}
@@ -22,13 +22,20 @@ func init() {
 		if !ok || to == "" {
 			return nil, fmt.Errorf("email channel requires \"to\"")
 		}
-		return newEmail(to), nil
+		templates, err := notifications.ParseTemplates(config)
+		if err != nil {
+			return nil, fmt.Errorf("email channel: %w", err)
+		}
+		e := newEmail(to)
+		e.templates = templates
+		return e, nil
 	})
 }
 
 type email struct {
-	to     string
-	client *http.Client
+	to        string
+	templates notifications.Templates
+	client    *http.Client
 }
 
 func newEmail(to string) *email {
@@ -47,6 +54,10 @@ func (e *email) Send(ctx context.Context, event notifications.Event) error {
 	if subject == "" {
 		return nil
 	}
+	subject, body, err := e.templates.Render(event, subject, body)
+	if err != nil {
+		return err
+	}
 
 	payload, err := json.Marshal(map[string]string{
 		"to":      e.to,
@@ -122,6 +133,26 @@ func formatEmailMessage(event notifications.Event) (subject, body string) {
 		}
 		return subject, body
 
+	case notifications.EventBudgetExceeded, notifications.EventToolFailureStreak, notifications.EventAgentWaiting:
+		if p, ok := event.Payload.(notifications.AlertPayload); ok {
+			subject = notifications.Title(p.Hostname, p.ConversationTitle)
+			var parts []string
+			if p.ConversationURL != "" {
+				parts = append(parts, p.ConversationURL, "")
+			}
+			parts = append(parts, p.Message)
+			body = strings.Join(parts, "\n")
+		} else {
+			subject = "Agent needs attention"
+		}
+		return subject, body
+
+	case notifications.EventDigest:
+		if p, ok := event.Payload.(notifications.DigestPayload); ok {
+			return p.Title(), p.Summary()
+		}
+		return "Agent turns finished", ""
+
 	default:
 		return "", ""
 	}
@@ -55,6 +55,11 @@ func init() {
 			return nil, fmt.Errorf("ntfy channel: invalid error_priority %q", errorPriorityStr)
 		}
 
+		templates, err := notifications.ParseTemplates(config)
+		if err != nil {
+			return nil, fmt.Errorf("ntfy channel: %w", err)
+		}
+
 		return &ntfy{
 			server:        server,
 			topic:         topic,
@@ -63,6 +68,7 @@ func init() {
 			password:      password,
 			donePriority:  donePriority,
 			errorPriority: errorPriority,
+			templates:     templates,
 			client: &http.Client{
 				Timeout: 10 * time.Second,
 			},
@@ -78,18 +84,43 @@ type ntfy struct {
 	password      string
 	donePriority  int
 	errorPriority int
+	templates     notifications.Templates
 	client        *http.Client
 }
 
 func (n *ntfy) Name() string { return "ntfy" }
 
 type ntfyMessage struct {
-	Topic    string   `json:"topic"`
-	Title    string   `json:"title"`
-	Message  string   `json:"message"`
-	Priority int      `json:"priority"`
-	Tags     []string `json:"tags"`
-	Click    string   `json:"click,omitempty"`
+	Topic    string       `json:"topic"`
+	Title    string       `json:"title"`
+	Message  string       `json:"message"`
+	Priority int          `json:"priority"`
+	Tags     []string     `json:"tags"`
+	Click    string       `json:"click,omitempty"`
+	Actions  []ntfyAction `json:"actions,omitempty"`
+}
+
+// ntfyAction is a notification button: "view" opens a URL, "http" sends a
+// request from the phone.
+type ntfyAction struct {
+	Action string `json:"action"`
+	Label  string `json:"label"`
+	URL    string `json:"url"`
+	Method string `json:"method,omitempty"`
+	Clear  bool   `json:"clear,omitempty"`
+}
+
+// ntfyActions returns buttons to open the conversation and, when cancelURL
+// is set, to cancel its run.
+func ntfyActions(viewURL, cancelURL string) []ntfyAction {
+	var actions []ntfyAction
+	if viewURL != "" {
+		actions = append(actions, ntfyAction{Action: "view", Label: "Open", URL: viewURL})
+	}
+	if cancelURL != "" {
+		actions = append(actions, ntfyAction{Action: "http", Label: "Cancel run", URL: cancelURL, Method: http.MethodPost, Clear: true})
+	}
+	return actions
 }
 
 func (n *ntfy) Send(ctx context.Context, event notifications.Event) error {
@@ -97,6 +128,11 @@ func (n *ntfy) Send(ctx context.Context, event notifications.Event) error {
 	if msg == nil {
 		return nil
 	}
+	title, message, err := n.templates.Render(event, msg.Title, msg.Message)
+	if err != nil {
+		return err
+	}
+	msg.Title, msg.Message = title, truncate(message, ntfyMaxMessage)
 
 	body, err := json.Marshal(msg)
 	if err != nil {
@@ -147,13 +183,8 @@ func (n *ntfy) formatMessage(event notifications.Event) *ntfyMessage {
 		if p, ok := event.Payload.(notifications.AgentDonePayload); ok {
 			msg.Title = notifications.Title(p.Hostname, p.ConversationTitle)
 			msg.Click = p.ConversationURL
-			if p.FinalResponse != "" {
-				body := p.FinalResponse
-				if len(body) > ntfyMaxMessage {
-					body = body[:ntfyMaxMessage-3] + "..."
-				}
-				msg.Message = body
-			}
+			msg.Actions = ntfyActions(p.ConversationURL, "")
+			msg.Message = p.FinalResponse
 		} else {
 			msg.Title = "Agent finished"
 		}
@@ -168,6 +199,7 @@ func (n *ntfy) formatMessage(event notifications.Event) *ntfyMessage {
 		if p, ok := event.Payload.(notifications.AgentErrorPayload); ok {
 			msg.Title = notifications.Title(p.Hostname, "error")
 			msg.Click = p.ConversationURL
+			msg.Actions = ntfyActions(p.ConversationURL, "")
 			if p.ErrorMessage != "" {
 				msg.Message = p.ErrorMessage
 			}
@@ -176,6 +208,36 @@ func (n *ntfy) formatMessage(event notifications.Event) *ntfyMessage {
 		}
 		return msg
 
+	case notifications.EventBudgetExceeded, notifications.EventToolFailureStreak, notifications.EventAgentWaiting:
+		msg := &ntfyMessage{
+			Topic:    n.topic,
+			Priority: n.errorPriority,
+			Tags:     []string{"warning"},
+		}
+		if p, ok := event.Payload.(notifications.AlertPayload); ok {
+			msg.Title = notifications.Title(p.Hostname, p.ConversationTitle)
+			msg.Click = p.ConversationURL
+			msg.Actions = ntfyActions(p.ConversationURL, p.CancelURL)
+			msg.Message = p.Message
+		} else {
+			msg.Title = "Agent needs attention"
+		}
+		return msg
+
+	case notifications.EventDigest:
+		msg := &ntfyMessage{
+			Topic:    n.topic,
+			Priority: n.donePriority,
+			Tags:     []string{"white_check_mark"},
+		}
+		if p, ok := event.Payload.(notifications.DigestPayload); ok {
+			msg.Title = p.Title()
+			msg.Message = p.Summary()
+		} else {
+			msg.Title = "Agent turns finished"
+		}
+		return msg
+
 	default:
 		return nil
 	}
@@ -0,0 +1,211 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"shelley.exe.dev/server/notifications"
+)
+
+const pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+
+var pushoverPriorities = map[string]int{
+	"lowest":    -2,
+	"low":       -1,
+	"normal":    0,
+	"high":      1,
+	"emergency": 2,
+}
+
+// Emergency-priority messages repeat every pushoverRetry seconds until
+// acknowledged, for at most pushoverExpire seconds.
+const (
+	pushoverRetry  = 60
+	pushoverExpire = 3600
+)
+
+func init() {
+	notifications.Register("pushover", func(config map[string]any, logger *slog.Logger) (notifications.Channel, error) {
+		token, _ := config["token"].(string)
+		if token == "" {
+			return nil, fmt.Errorf("pushover channel requires \"token\"")
+		}
+
+		user, _ := config["user"].(string)
+		if user == "" {
+			return nil, fmt.Errorf("pushover channel requires \"user\"")
+		}
+
+		donePriorityStr, _ := config["done_priority"].(string)
+		if donePriorityStr == "" {
+			donePriorityStr = "normal"
+		}
+		donePriority, ok := pushoverPriorities[donePriorityStr]
+		if !ok {
+			return nil, fmt.Errorf("pushover channel: invalid done_priority %q", donePriorityStr)
+		}
+
+		errorPriorityStr, _ := config["error_priority"].(string)
+		if errorPriorityStr == "" {
+			errorPriorityStr = "high"
+		}
+		errorPriority, ok := pushoverPriorities[errorPriorityStr]
+		if !ok {
+			return nil, fmt.Errorf("pushover channel: invalid error_priority %q", errorPriorityStr)
+		}
+
+		templates, err := notifications.ParseTemplates(config)
+		if err != nil {
+			return nil, fmt.Errorf("pushover channel: %w", err)
+		}
+
+		return &pushover{
+			token:         token,
+			user:          user,
+			donePriority:  donePriority,
+			errorPriority: errorPriority,
+			templates:     templates,
+			client: &http.Client{
+				Timeout: 10 * time.Second,
+			},
+		}, nil
+	})
+}
+
+type pushover struct {
+	token         string
+	user          string
+	donePriority  int
+	errorPriority int
+	templates     notifications.Templates
+	client        *http.Client
+}
+
+func (p *pushover) Name() string { return "pushover" }
+
+type pushoverMessage struct {
+	Token     string `json:"token"`
+	User      string `json:"user"`
+	Title     string `json:"title"`
+	Message   string `json:"message"`
+	Priority  int    `json:"priority"`
+	Retry     int    `json:"retry,omitempty"`
+	Expire    int    `json:"expire,omitempty"`
+	URL       string `json:"url,omitempty"`
+	URLTitle  string `json:"url_title,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Pushover's title and message limits.
+const (
+	pushoverMaxTitle   = 250
+	pushoverMaxMessage = 1024
+)
+
+func (p *pushover) Send(ctx context.Context, event notifications.Event) error {
+	msg := p.formatMessage(event)
+	if msg == nil {
+		return nil
+	}
+	title, message, err := p.templates.Render(event, msg.Title, msg.Message)
+	if err != nil {
+		return err
+	}
+	// Pushover rejects an empty message.
+	if message == "" {
+		message = title
+	}
+	msg.Title, msg.Message = truncate(title, pushoverMaxTitle), truncate(message, pushoverMaxMessage)
+	if msg.Priority == pushoverPriorities["emergency"] {
+		msg.Retry, msg.Expire = pushoverRetry, pushoverExpire
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal pushover payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pushoverAPIURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create pushover request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send pushover notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if len(b) > 0 {
+			return fmt.Errorf("pushover returned %s: %s", resp.Status, bytes.TrimSpace(b))
+		}
+		return fmt.Errorf("pushover returned %s", resp.Status)
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (p *pushover) formatMessage(event notifications.Event) *pushoverMessage {
+	msg := &pushoverMessage{
+		Token:     p.token,
+		User:      p.user,
+		Timestamp: event.Timestamp.Unix(),
+	}
+	switch event.Type {
+	case notifications.EventAgentDone:
+		msg.Priority = p.donePriority
+		if pl, ok := event.Payload.(notifications.AgentDonePayload); ok {
+			msg.Title = notifications.Title(pl.Hostname, pl.ConversationTitle)
+			msg.URL = pl.ConversationURL
+			msg.Message = pl.FinalResponse
+		} else {
+			msg.Title = "Agent finished"
+		}
+
+	case notifications.EventAgentError:
+		msg.Priority = p.errorPriority
+		if pl, ok := event.Payload.(notifications.AgentErrorPayload); ok {
+			msg.Title = notifications.Title(pl.Hostname, "error")
+			msg.URL = pl.ConversationURL
+			msg.Message = pl.ErrorMessage
+		} else {
+			msg.Title = "Agent error"
+		}
+
+	case notifications.EventBudgetExceeded, notifications.EventToolFailureStreak, notifications.EventAgentWaiting:
+		msg.Priority = p.errorPriority
+		if pl, ok := event.Payload.(notifications.AlertPayload); ok {
+			msg.Title = notifications.Title(pl.Hostname, pl.ConversationTitle)
+			msg.URL = pl.ConversationURL
+			msg.Message = pl.Message
+		} else {
+			msg.Title = "Agent needs attention"
+		}
+
+	case notifications.EventDigest:
+		msg.Priority = p.donePriority
+		if pl, ok := event.Payload.(notifications.DigestPayload); ok {
+			msg.Title = pl.Title()
+			msg.Message = pl.Summary()
+		} else {
+			msg.Title = "Agent turns finished"
+		}
+
+	default:
+		return nil
+	}
+	if msg.URL != "" {
+		msg.URLTitle = "Open conversation"
+	}
+	return msg
+}
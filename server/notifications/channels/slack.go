@@ -0,0 +1,203 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/server/notifications"
+)
+
+// slackPostMessageURL is the Web API method used with a bot token.
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+func init() {
+	notifications.Register("slack", func(config map[string]any, logger *slog.Logger) (notifications.Channel, error) {
+		webhookURL, _ := config["webhook_url"].(string)
+		botToken, _ := config["bot_token"].(string)
+		channel, _ := config["channel"].(string)
+		switch {
+		case webhookURL != "" && botToken != "":
+			return nil, fmt.Errorf("slack channel takes either \"webhook_url\" or \"bot_token\", not both")
+		case webhookURL == "" && botToken == "":
+			return nil, fmt.Errorf("slack channel requires \"webhook_url\" or \"bot_token\"")
+		case botToken != "" && channel == "":
+			return nil, fmt.Errorf("slack channel with \"bot_token\" requires \"channel\"")
+		}
+		templates, err := notifications.ParseTemplates(config)
+		if err != nil {
+			return nil, fmt.Errorf("slack channel: %w", err)
+		}
+		return &slack{
+			webhookURL: webhookURL,
+			botToken:   botToken,
+			channel:    channel,
+			templates:  templates,
+			client: &http.Client{
+				Timeout: 10 * time.Second,
+			},
+		}, nil
+	})
+}
+
+// slack posts to an incoming webhook, or with a bot token to a channel.
+type slack struct {
+	webhookURL string
+	botToken   string
+	channel    string
+	templates  notifications.Templates
+	client     *http.Client
+}
+
+func (s *slack) Name() string { return "slack" }
+
+func (s *slack) Send(ctx context.Context, event notifications.Event) error {
+	msg, err := formatSlackMessage(event, s.templates)
+	if msg == nil || err != nil {
+		return err
+	}
+	url := s.webhookURL
+	if s.botToken != "" {
+		url = slackPostMessageURL
+		msg.Channel = s.channel
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal slack payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if s.botToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.botToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send slack message: %w", err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("slack returned %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	// The Web API reports failures in the body with a 200 status.
+	if s.botToken != "" {
+		var result struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(b, &result); err != nil {
+			return fmt.Errorf("decode slack response: %w", err)
+		}
+		if !result.OK {
+			return fmt.Errorf("slack chat.postMessage failed: %s", result.Error)
+		}
+	}
+	return nil
+}
+
+type slackMessage struct {
+	Channel string `json:"channel,omitempty"`
+	// Text is the fallback shown in notifications and clients without
+	// Block Kit.
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackMaxSectionText is Slack's limit on a section block's text.
+const slackMaxSectionText = 3000
+
+// formatSlackMessage returns nil for events Slack doesn't announce.
+func formatSlackMessage(event notifications.Event, templates notifications.Templates) (*slackMessage, error) {
+	var icon, title, body, model, url string
+	switch event.Type {
+	case notifications.EventAgentDone:
+		icon, title = ":white_check_mark:", "Agent finished"
+		if p, ok := event.Payload.(notifications.AgentDonePayload); ok {
+			title = notifications.Title(p.Hostname, p.ConversationTitle)
+			body, model, url = p.FinalResponse, p.Model, p.ConversationURL
+		}
+
+	case notifications.EventAgentError:
+		icon, title = ":x:", "Agent error"
+		if p, ok := event.Payload.(notifications.AgentErrorPayload); ok {
+			title = notifications.Title(p.Hostname, "error")
+			body, url = p.ErrorMessage, p.ConversationURL
+		}
+
+	case notifications.EventBudgetExceeded, notifications.EventToolFailureStreak, notifications.EventAgentWaiting:
+		icon, title = ":warning:", "Agent needs attention"
+		if p, ok := event.Payload.(notifications.AlertPayload); ok {
+			title = notifications.Title(p.Hostname, p.ConversationTitle)
+			body, url = p.Message, p.ConversationURL
+		}
+
+	case notifications.EventDigest:
+		icon, title = ":white_check_mark:", "Agent turns finished"
+		if p, ok := event.Payload.(notifications.DigestPayload); ok {
+			title, body = p.Title(), p.Summary()
+		}
+
+	default:
+		return nil, nil
+	}
+	title, body, err := templates.Render(event, title, body)
+	if err != nil {
+		return nil, err
+	}
+	return &slackMessage{
+		Text:   slackEscape(title),
+		Blocks: slackBlocks(icon+" "+slackLink(title, url), body, model),
+	}, nil
+}
+
+// slackBlocks lays out a heading, an optional body, and an optional model
+// context line.
+func slackBlocks(heading, body, model string) []slackBlock {
+	blocks := []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*" + heading + "*"}}}
+	if body != "" {
+		body = truncate(slackEscape(body), slackMaxSectionText)
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: body}})
+	}
+	if model != "" {
+		blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: "Model: " + slackEscape(model)}}})
+	}
+	return blocks
+}
+
+// slackLink formats text as a link to url, or as plain text without one.
+func slackLink(text, url string) string {
+	if url == "" {
+		return slackEscape(text)
+	}
+	return "<" + url + "|" + slackEscape(text) + ">"
+}
+
+// slackEscape escapes the characters Slack's mrkdwn treats as control
+// sequences.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,135 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/server/notifications"
+)
+
+func init() {
+	notifications.Register("webhook", func(config map[string]any, logger *slog.Logger) (notifications.Channel, error) {
+		url, _ := config["url"].(string)
+		if url == "" {
+			return nil, fmt.Errorf("webhook channel requires \"url\"")
+		}
+		secret, _ := config["secret"].(string)
+		headersStr, _ := config["headers"].(string)
+		headers, err := parseWebhookHeaders(headersStr)
+		if err != nil {
+			return nil, fmt.Errorf("webhook channel: %w", err)
+		}
+		return &webhook{
+			url:     url,
+			secret:  secret,
+			headers: headers,
+			client: &http.Client{
+				Timeout: 10 * time.Second,
+			},
+		}, nil
+	})
+}
+
+// webhookSignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+// request body, keyed with the channel's secret.
+const webhookSignatureHeader = "X-Shelley-Signature-256"
+
+// webhook POSTs every event as JSON to a URL.
+type webhook struct {
+	url     string
+	secret  string
+	headers map[string]string
+	client  *http.Client
+}
+
+// parseWebhookHeaders parses "Name: value" pairs separated by semicolons.
+func parseWebhookHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for pair := range strings.SplitSeq(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q: want \"Name: value\"", strings.TrimSpace(pair))
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+func (w *webhook) Name() string { return "webhook" }
+
+// Retries back off exponentially but stay short, because notifications are
+// sent synchronously at the end of each turn.
+const (
+	webhookMaxAttempts  = 4
+	webhookInitialDelay = 500 * time.Millisecond
+)
+
+func (w *webhook) Send(ctx context.Context, event notifications.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal webhook payload: %w", err)
+	}
+
+	delay := webhookInitialDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := w.post(ctx, event, body)
+		if err == nil || !retry || attempt == webhookMaxAttempts {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry aborted: %w)", err, ctx.Err())
+		}
+		delay *= 2
+	}
+}
+
+// post sends one request, reporting whether a failure is worth retrying:
+// network errors, rate limits, and server errors are.
+func (w *webhook) post(ctx context.Context, event notifications.Event, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("create webhook request: %w", err)
+	}
+	for name, value := range w.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Shelley-Event", string(event.Type))
+	if w.secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.secret))
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 400 {
+		retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if len(b) > 0 {
+			return retry, fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(b))
+		}
+		return retry, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return false, nil
+}
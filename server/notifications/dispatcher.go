@@ -8,9 +8,17 @@ import (
 
 // Dispatcher routes notification events to registered backend channels.
 type Dispatcher struct {
-	mu       sync.RWMutex
-	channels []Channel
-	logger   *slog.Logger
+	mu         sync.RWMutex
+	channels   []Channel
+	onDelivery func(context.Context, Delivery)
+	logger     *slog.Logger
+}
+
+// Delivery is the outcome of sending one event through one channel.
+type Delivery struct {
+	Channel Channel
+	Event   Event
+	Err     error
 }
 
 // NewDispatcher creates a new notification dispatcher.
@@ -32,6 +40,13 @@ func (d *Dispatcher) ReplaceChannels(channels []Channel) {
 	d.channels = channels
 }
 
+// OnDelivery sets a function called after every send attempt.
+func (d *Dispatcher) OnDelivery(fn func(context.Context, Delivery)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onDelivery = fn
+}
+
 // Channels returns a snapshot of current registered channels.
 func (d *Dispatcher) Channels() []Channel {
 	d.mu.RLock()
@@ -46,10 +61,12 @@ func (d *Dispatcher) Channels() []Channel {
 func (d *Dispatcher) Dispatch(ctx context.Context, event Event) {
 	d.mu.RLock()
 	channels := d.channels
+	onDelivery := d.onDelivery
 	d.mu.RUnlock()
 
 	for _, ch := range channels {
-		if err := ch.Send(ctx, event); err != nil {
+		err := ch.Send(ctx, event)
+		if err != nil {
 			d.logger.Warn(
 				"notification channel failed",
 				"channel", ch.Name(),
@@ -57,5 +74,8 @@ func (d *Dispatcher) Dispatch(ctx context.Context, event Event) {
 				"error", err,
 			)
 		}
+		if onDelivery != nil {
+			onDelivery(ctx, Delivery{Channel: ch, Event: event, Err: err})
+		}
 	}
 }
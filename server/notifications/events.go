@@ -1,7 +1,9 @@
 package notifications
 
 import (
+	"cmp"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -25,6 +27,16 @@ type EventType string
 const (
 	EventAgentDone  EventType = "agent_done"
 	EventAgentError EventType = "agent_error"
+
+	// Alert events flag a conversation that needs attention. Their payload
+	// is an AlertPayload.
+	EventBudgetExceeded    EventType = "budget_exceeded"
+	EventToolFailureStreak EventType = "tool_failure_streak"
+	EventAgentWaiting      EventType = "agent_waiting"
+
+	// EventDigest batches agent_done events (see Scheduler). Its payload is
+	// a DigestPayload.
+	EventDigest EventType = "digest"
 )
 
 // Event is a notification event generated by the system.
@@ -43,6 +55,9 @@ type AgentDonePayload struct {
 	ConversationURL   string `json:"conversation_url,omitempty"`
 	VMName            string `json:"vm_name,omitempty"`
 	FinalResponse     string `json:"final_response,omitempty"`
+	// DurationSeconds and CostUSD cover the turn that just ended.
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	CostUSD         float64 `json:"cost_usd,omitempty"`
 }
 
 // AgentErrorPayload is the payload for EventAgentError.
@@ -51,3 +66,48 @@ type AgentErrorPayload struct {
 	ErrorMessage    string `json:"error_message"`
 	ConversationURL string `json:"conversation_url,omitempty"`
 }
+
+// AlertPayload is the payload for the alert events.
+type AlertPayload struct {
+	Hostname          string `json:"hostname,omitempty"`
+	ConversationTitle string `json:"conversation_title,omitempty"`
+	ConversationURL   string `json:"conversation_url,omitempty"`
+	// CancelURL, set while the agent is still running, is the API endpoint
+	// that stops the run (POST).
+	CancelURL string `json:"cancel_url,omitempty"`
+	Message   string `json:"message"`
+}
+
+// DigestPayload is the payload for EventDigest.
+type DigestPayload struct {
+	Hostname string             `json:"hostname,omitempty"`
+	Turns    []AgentDonePayload `json:"turns"`
+}
+
+// Title summarizes the digest in one line.
+func (p DigestPayload) Title() string {
+	if len(p.Turns) == 1 {
+		return Title(p.Hostname, "1 agent turn finished")
+	}
+	return Title(p.Hostname, fmt.Sprintf("%d agent turns finished", len(p.Turns)))
+}
+
+// Summary lists each turn as its conversation, the first line of its final
+// response, and its URL.
+func (p DigestPayload) Summary() string {
+	var sb strings.Builder
+	for i, t := range p.Turns {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("• ")
+		sb.WriteString(cmp.Or(t.ConversationTitle, "untitled"))
+		if line, _, _ := strings.Cut(strings.TrimSpace(t.FinalResponse), "\n"); line != "" {
+			sb.WriteString(": " + line)
+		}
+		if t.ConversationURL != "" {
+			sb.WriteString(" (" + t.ConversationURL + ")")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,231 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quiet modes say what happens to events raised during quiet hours.
+const (
+	QuietSuppress = "suppress"
+	QuietQueue    = "queue"
+)
+
+// Schedule controls when a Scheduler hands events to its Dispatcher.
+// The zero Schedule delivers everything immediately.
+type Schedule struct {
+	// QuietStart and QuietEnd bound the daily quiet window as offsets from
+	// local midnight. The window wraps past midnight when QuietStart is
+	// after QuietEnd; equal offsets mean no quiet hours.
+	QuietStart, QuietEnd time.Duration
+	// QueueQuiet holds events raised during quiet hours until the window
+	// ends. Otherwise they are dropped.
+	QueueQuiet bool
+	// DigestInterval, when set, batches agent_done events into one
+	// EventDigest sent at most once per interval.
+	DigestInterval time.Duration
+}
+
+// ParseSchedule builds a Schedule from its settings: quiet hours as
+// "HH:MM-HH:MM" in server local time, a quiet mode of "suppress" or
+// "queue", and a digest interval such as "1h". Empty values disable the
+// corresponding feature; the quiet mode defaults to "suppress".
+func ParseSchedule(quietHours, quietMode, digestInterval string) (Schedule, error) {
+	var s Schedule
+	if quietHours = strings.TrimSpace(quietHours); quietHours != "" {
+		start, end, ok := strings.Cut(quietHours, "-")
+		var err error
+		if ok {
+			if s.QuietStart, err = parseTimeOfDay(start); err == nil {
+				s.QuietEnd, err = parseTimeOfDay(end)
+			}
+		}
+		if !ok || err != nil {
+			return Schedule{}, fmt.Errorf("invalid quiet hours %q: want HH:MM-HH:MM", quietHours)
+		}
+	}
+	switch quietMode {
+	case "", QuietSuppress:
+	case QuietQueue:
+		s.QueueQuiet = true
+	default:
+		return Schedule{}, fmt.Errorf("invalid quiet mode %q: want %q or %q", quietMode, QuietSuppress, QuietQueue)
+	}
+	if digestInterval = strings.TrimSpace(digestInterval); digestInterval != "" {
+		d, err := time.ParseDuration(digestInterval)
+		if err != nil || d <= 0 {
+			return Schedule{}, fmt.Errorf("invalid digest interval %q: want a positive duration such as 1h", digestInterval)
+		}
+		s.DigestInterval = d
+	}
+	return s, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// quiet reports whether t falls within quiet hours.
+func (s Schedule) quiet(t time.Time) bool {
+	since := t.Sub(midnight(t))
+	switch {
+	case s.QuietStart == s.QuietEnd:
+		return false
+	case s.QuietStart < s.QuietEnd:
+		return since >= s.QuietStart && since < s.QuietEnd
+	default:
+		return since >= s.QuietStart || since < s.QuietEnd
+	}
+}
+
+// quietEnd returns when the quiet hours containing t end, or t itself
+// outside quiet hours.
+func (s Schedule) quietEnd(t time.Time) time.Time {
+	if !s.quiet(t) {
+		return t
+	}
+	end := midnight(t).Add(s.QuietEnd)
+	if !end.After(t) {
+		end = midnight(t).AddDate(0, 0, 1).Add(s.QuietEnd)
+	}
+	return end
+}
+
+// Scheduler applies a Schedule in front of a Dispatcher. Held events live
+// in memory only, so a restart drops them.
+type Scheduler struct {
+	dispatcher *Dispatcher
+	logger     *slog.Logger
+
+	mu       sync.Mutex
+	schedule Schedule
+	held     []Event // raised during quiet hours
+	digest   []Event // agent_done events for the next digest
+	digestAt time.Time
+	timer    *time.Timer
+}
+
+// NewScheduler creates a Scheduler that delivers through d.
+func NewScheduler(d *Dispatcher, logger *slog.Logger) *Scheduler {
+	return &Scheduler{dispatcher: d, logger: logger}
+}
+
+// SetSchedule replaces the schedule. Held events are re-evaluated against
+// it, so lifting quiet hours or the digest releases them.
+func (s *Scheduler) SetSchedule(schedule Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedule = schedule
+	if schedule.DigestInterval == 0 {
+		s.digestAt = time.Now()
+	}
+	s.armLocked(time.Now())
+}
+
+// Dispatch delivers event now, holds it, or drops it, per the schedule.
+func (s *Scheduler) Dispatch(ctx context.Context, event Event) {
+	s.mu.Lock()
+	now := time.Now()
+	switch {
+	case s.schedule.quiet(now):
+		if s.schedule.QueueQuiet {
+			s.held = append(s.held, event)
+			s.armLocked(now)
+		} else {
+			s.logger.Debug("notification suppressed during quiet hours", "event", string(event.Type), "conversationID", event.ConversationID)
+		}
+		s.mu.Unlock()
+		return
+	case s.digestible(event):
+		s.addToDigestLocked(event, now)
+		s.armLocked(now)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	s.dispatcher.Dispatch(ctx, event)
+}
+
+func (s *Scheduler) digestible(event Event) bool {
+	return s.schedule.DigestInterval > 0 && event.Type == EventAgentDone
+}
+
+func (s *Scheduler) addToDigestLocked(event Event, now time.Time) {
+	if len(s.digest) == 0 {
+		s.digestAt = now.Add(s.schedule.DigestInterval)
+	}
+	s.digest = append(s.digest, event)
+}
+
+// armLocked sets the timer for the next flush: right away for held
+// events, at digestAt for a pending digest, and never during quiet hours.
+func (s *Scheduler) armLocked(now time.Time) {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	var next time.Time
+	switch {
+	case len(s.held) > 0:
+		next = now
+	case len(s.digest) > 0:
+		next = s.digestAt
+	default:
+		return
+	}
+	if end := s.schedule.quietEnd(now); next.Before(end) {
+		next = end
+	}
+	s.timer = time.AfterFunc(next.Sub(now), s.flush)
+}
+
+// flush delivers whatever the schedule no longer holds back.
+func (s *Scheduler) flush() {
+	s.mu.Lock()
+	now := time.Now()
+	var ready []Event
+	if !s.schedule.quiet(now) {
+		for _, e := range s.held {
+			if s.digestible(e) {
+				s.addToDigestLocked(e, now)
+			} else {
+				ready = append(ready, e)
+			}
+		}
+		s.held = nil
+		if len(s.digest) > 0 && !now.Before(s.digestAt) {
+			ready = append(ready, digestEvent(s.digest, now))
+			s.digest = nil
+		}
+	}
+	s.armLocked(now)
+	s.mu.Unlock()
+
+	for _, e := range ready {
+		s.dispatcher.Dispatch(context.Background(), e)
+	}
+}
+
+func digestEvent(events []Event, now time.Time) Event {
+	var p DigestPayload
+	for _, e := range events {
+		if done, ok := e.Payload.(AgentDonePayload); ok {
+			p.Hostname = done.Hostname
+			p.Turns = append(p.Turns, done)
+		}
+	}
+	return Event{Type: EventDigest, Timestamp: now, Payload: p}
+}
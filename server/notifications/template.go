@@ -0,0 +1,111 @@
+package notifications
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData is what custom title and body templates render against.
+type TemplateData struct {
+	Type           EventType
+	ConversationID string
+	Hostname       string
+	Slug           string
+	Model          string
+	URL            string
+	// Text is the agent's final response, the error message, the alert
+	// message, or the digest summary.
+	Text string
+	// Duration and CostUSD cover the turn; they are zero for other events.
+	Duration  time.Duration
+	CostUSD   float64
+	Timestamp time.Time
+}
+
+func newTemplateData(event Event) TemplateData {
+	d := TemplateData{
+		Type:           event.Type,
+		ConversationID: event.ConversationID,
+		Timestamp:      event.Timestamp,
+	}
+	switch p := event.Payload.(type) {
+	case AgentDonePayload:
+		d.Hostname, d.Slug, d.Model, d.URL, d.Text = p.Hostname, p.ConversationTitle, p.Model, p.ConversationURL, p.FinalResponse
+		d.Duration = time.Duration(p.DurationSeconds * float64(time.Second)).Round(time.Second)
+		d.CostUSD = p.CostUSD
+	case AgentErrorPayload:
+		d.Hostname, d.URL, d.Text = p.Hostname, p.ConversationURL, p.ErrorMessage
+	case AlertPayload:
+		d.Hostname, d.Slug, d.URL, d.Text = p.Hostname, p.ConversationTitle, p.ConversationURL, p.Message
+	case DigestPayload:
+		d.Hostname, d.Text = p.Hostname, p.Summary()
+	}
+	return d
+}
+
+// Templates holds a channel's custom title and body templates. Either may
+// be unset, leaving the channel's own format in place.
+type Templates struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// ParseTemplates parses the "title_template" and "body_template" entries of
+// a channel config.
+func ParseTemplates(config map[string]any) (Templates, error) {
+	var t Templates
+	for _, f := range []struct {
+		key string
+		dst **template.Template
+	}{
+		{"title_template", &t.title},
+		{"body_template", &t.body},
+	} {
+		text, _ := config[f.key].(string)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		tmpl, err := template.New(f.key).Parse(text)
+		if err == nil {
+			// Catch references to unknown fields now rather than at send time.
+			err = tmpl.Execute(io.Discard, TemplateData{})
+		}
+		if err != nil {
+			return Templates{}, fmt.Errorf("invalid %s: %w", f.key, err)
+		}
+		*f.dst = tmpl
+	}
+	return t, nil
+}
+
+// Render returns the title and body for event, replacing the given defaults
+// with whichever templates are set.
+func (t Templates) Render(event Event, title, body string) (string, string, error) {
+	if t.title == nil && t.body == nil {
+		return title, body, nil
+	}
+	data := newTemplateData(event)
+	var err error
+	if t.title != nil {
+		if title, err = execute(t.title, data); err != nil {
+			return "", "", err
+		}
+	}
+	if t.body != nil {
+		if body, err = execute(t.body, data); err != nil {
+			return "", "", err
+		}
+	}
+	return title, body, nil
+}
+
+func execute(tmpl *template.Template, data TemplateData) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("render %s: %w", tmpl.Name(), err)
+	}
+	return sb.String(), nil
+}
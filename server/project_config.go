@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/db"
+)
+
+// ProjectConfigFile is where a repository keeps its Shelley settings,
+// relative to its root.
+const ProjectConfigFile = ".shelley/config.json"
+
+// ProjectConfig overlays the server's settings for conversations whose
+// working directory is inside a repository with a ProjectConfigFile, so
+// that each project gets its own agent policy.
+type ProjectConfig struct {
+	// DefaultModel is the model for new conversations that don't pick one,
+	// if it is available.
+	DefaultModel string `json:"default_model"`
+	// Tools, if not empty, are the only tools conversations get, unless a
+	// conversation turns others on itself.
+	Tools []string `json:"tools"`
+	// SubagentBudget, if set, replaces the server's subagent budget.
+	SubagentBudget *claudetool.SubagentBudget `json:"subagent_budget"`
+	// Env is set for the commands the bash and shell tools run, if the
+	// repository is trusted.
+	Env map[string]string `json:"env"`
+	// StartupHook is a shell command run with the bash tool in the
+	// repository root when a conversation starts, if the repository is
+	// trusted. Its output is added to the system prompt.
+	StartupHook string `json:"startup_hook"`
+
+	// root is the repository root the config was read from.
+	root string
+	// trusted reports whether root is listed in the user hooks directory's
+	// TrustedReposFile, like the repositories whose hooks may run.
+	trusted bool
+}
+
+// loadProjectConfig reads the project config of the git repository
+// containing dir, trusting it if hooksDir lists it. Outside a repository,
+// or without a config file, it returns the zero config.
+func loadProjectConfig(hooksDir, dir string) (ProjectConfig, error) {
+	var pc ProjectConfig
+	if dir == "" {
+		return pc, nil
+	}
+	root, err := getGitRoot(dir)
+	if err != nil {
+		return pc, nil
+	}
+	path := filepath.Join(root, ProjectConfigFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pc, nil
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &pc)
+	}
+	if err != nil {
+		return ProjectConfig{}, fmt.Errorf("invalid project config %s: %w", path, err)
+	}
+	pc.root = root
+	pc.trusted = hooksDir != "" && repoTrusted(hooksDir, root)
+	return pc, nil
+}
+
+// applyTools limits cfg's tools to those of the project's that are
+// already enabled, so that it never turns on a tool that a persona or the
+// model leaves off, keeping the tools the conversation turned on itself. It
+// also sets the environment of a trusted project overlaid with the
+// conversation's. It runs after applyToolOptions.
+func (pc ProjectConfig) applyTools(cfg *claudetool.ToolSetConfig, opts db.ConversationOptions) {
+	env := pc.Env
+	if !pc.trusted {
+		env = nil
+	}
+	if len(env) > 0 || len(opts.Env) > 0 {
+		vars := make(map[string]string, len(env)+len(opts.Env))
+		maps.Copy(vars, env)
+		maps.Copy(vars, opts.Env)
+		cfg.Env.Vars = vars
+	}
+	if len(pc.Tools) == 0 {
+		return
+	}
+	overrides := make(map[string]string, len(pc.Tools)+len(opts.ToolOverrides))
+	for _, name := range pc.Tools {
+		if cfg.ToolEnabled(name) {
+			overrides[name] = "on"
+		}
+	}
+	if len(cfg.SubagentPersonas[opts.Persona].Tools) == 0 {
+		maps.Copy(overrides, opts.ToolOverrides)
+	}
+	cfg.ToolOverrides = overrides
+	cfg.DisableAllTools = true
+}
+
+// runStartupHook runs the project's startup hook with cfg's bash tool and
+// returns the output, formatted for the system prompt. It runs nothing for
+// an untrusted project.
+func (pc ProjectConfig) runStartupHook(ctx context.Context, cfg claudetool.ToolSetConfig) string {
+	if pc.StartupHook == "" || !pc.trusted {
+		return ""
+	}
+	cfg.WorkingDir = pc.root
+	cfg.ToolOverrides = map[string]string{"bash": "on"}
+	cfg.DisableAllTools = true
+	ts := claudetool.NewToolSet(ctx, cfg)
+	defer ts.Cleanup()
+	var output string
+	for _, tool := range ts.Tools() {
+		if tool.Name != "bash" {
+			continue
+		}
+		input, _ := json.Marshal(map[string]string{"command": pc.StartupHook})
+		out := tool.Run(ctx, input)
+		if out.Error != nil {
+			output = "The hook failed: " + out.Error.Error()
+		}
+		for _, c := range out.LLMContent {
+			output += c.Text
+		}
+	}
+	return fmt.Sprintf("\n\n<project_startup_hook command=%q>\n%s\n</project_startup_hook>\n", pc.StartupHook, output)
+}
+
+// defaultModelIn returns the model for a new conversation in dir that
+// doesn't pick one: the project's default model if it is ready, else the
+// server's.
+func (s *Server) defaultModelIn(dir string, modelList []ModelInfo) (string, error) {
+	pc, err := loadProjectConfig(s.hooksDir, dir)
+	if err != nil {
+		return "", err
+	}
+	if pc.DefaultModel != "" {
+		if m := findModelInfo(pc.DefaultModel, modelList); m != nil && m.Ready {
+			return pc.DefaultModel, nil
+		}
+		s.logger.Warn("Project default model is not available", "model", pc.DefaultModel, "root", pc.root)
+	}
+	return s.effectiveDefaultModel(modelList), nil
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/db"
+)
+
+func TestProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	config := `{"tools": ["bash"], "env": {"PROJECT_VAR": "from-project"}, "startup_hook": "echo hook-ran"}`
+	if err := os.MkdirAll(filepath.Join(dir, ".shelley"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ProjectConfigFile), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewTestHarness(t)
+	if err := os.WriteFile(filepath.Join(h.server.hooksDir, TrustedReposFile), []byte(dir+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h.NewConversation("bash: echo $PROJECT_VAR", sub)
+	if got := h.WaitToolResult(); !strings.Contains(got, "from-project") {
+		t.Errorf("project env var not set: %q", got)
+	}
+	req := h.llm.GetLastRequest()
+	var tools []string
+	for _, tool := range req.Tools {
+		tools = append(tools, tool.Name)
+	}
+	if len(tools) != 1 || tools[0] != "bash" {
+		t.Errorf("tools = %v, want only bash", tools)
+	}
+	var system strings.Builder
+	for _, s := range req.System {
+		system.WriteString(s.Text)
+	}
+	if !strings.Contains(system.String(), "hook-ran") {
+		t.Errorf("system prompt lacks the startup hook output:\n%s", system.String())
+	}
+}
+
+func TestUntrustedProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	config := `{"tools": ["bash"], "env": {"PROJECT_VAR": "from-project"}, "startup_hook": "echo hook-ran"}`
+	if err := os.MkdirAll(filepath.Join(dir, ".shelley"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ProjectConfigFile), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewTestHarness(t)
+	h.NewConversation("bash: echo project-var=$PROJECT_VAR", dir)
+	if got := h.WaitToolResult(); strings.Contains(got, "from-project") {
+		t.Errorf("untrusted project env var set: %q", got)
+	}
+	req := h.llm.GetLastRequest()
+	if len(req.Tools) != 1 || req.Tools[0].Name != "bash" {
+		t.Errorf("untrusted project tools not applied: %d tools", len(req.Tools))
+	}
+	var system strings.Builder
+	for _, s := range req.System {
+		system.WriteString(s.Text)
+	}
+	if strings.Contains(system.String(), "hook-ran") {
+		t.Errorf("untrusted project startup hook ran:\n%s", system.String())
+	}
+}
+
+func TestProjectToolsIntersectEnabledTools(t *testing.T) {
+	pc := ProjectConfig{Tools: []string{"bash", "patch"}}
+	enabled := func(cfg claudetool.ToolSetConfig) []string {
+		var names []string
+		for _, name := range []string{"bash", "patch", "keyword_search"} {
+			if cfg.ToolEnabled(name) {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	persona := db.ConversationOptions{Persona: "reviewer"}
+	cfg := claudetool.ToolSetConfig{SubagentPersonas: map[string]claudetool.SubagentPersona{
+		"reviewer": {Tools: []string{"bash", "keyword_search"}},
+	}}
+	applyToolOptions(&cfg, persona)
+	pc.applyTools(&cfg, persona)
+	if got := enabled(cfg); !slices.Equal(got, []string{"bash"}) {
+		t.Errorf("persona and project: enabled %v, want [bash]", got)
+	}
+
+	cfg = claudetool.ToolSetConfig{
+		ModelID:            "reviewer-model",
+		ModelToolOverrides: map[string]map[string]string{"reviewer-model": {"bash": "off"}},
+	}
+	applyToolOptions(&cfg, db.ConversationOptions{})
+	pc.applyTools(&cfg, db.ConversationOptions{})
+	if got := enabled(cfg); !slices.Equal(got, []string{"patch"}) {
+		t.Errorf("model off and project: enabled %v, want [patch]", got)
+	}
+}
+
+func TestInvalidProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".shelley"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ProjectConfigFile), []byte(`{"tools": "bash"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadProjectConfig("", dir); err == nil || !strings.Contains(err.Error(), ProjectConfigFile) {
+		t.Errorf("invalid config: err = %v", err)
+	}
+	if pc, err := loadProjectConfig("", t.TempDir()); err != nil || pc.root != "" {
+		t.Errorf("outside a repository: %+v, %v", pc, err)
+	}
+}
@@ -97,3 +97,31 @@ func TestChatEmptyModelUsesDraftModel(t *testing.T) {
 		t.Fatalf("reply with empty model: expected 202, got %d: %s", w.Code, w.Body.String())
 	}
 }
+
+// With require_model, a send naming a different model is rejected with an
+// error naming both, rather than quietly running on the conversation's.
+func TestChatRequireModelRejectsMismatch(t *testing.T) {
+	t.Parallel()
+	server, database, _ := newTestServer(t)
+
+	conversation, err := database.CreateConversation(context.Background(), nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	conversationID := conversation.ConversationID
+	if w := chatPost(t, server, conversationID, ChatRequest{Message: "echo: hi", Model: "some-other-model"}); w.Code != http.StatusAccepted {
+		t.Fatalf("first send: expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := chatPost(t, server, conversationID, ChatRequest{Message: "echo: again", Model: "predictable", RequireModel: true})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("mismatched send: expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); !strings.Contains(body, "some-other-model") || !strings.Contains(body, "predictable") {
+		t.Errorf("error should name both models, got %q", body)
+	}
+
+	if w := chatPost(t, server, conversationID, ChatRequest{Message: "echo: same", Model: "some-other-model", RequireModel: true}); w.Code != http.StatusAccepted {
+		t.Fatalf("matching send: expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+}
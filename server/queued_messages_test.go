@@ -365,7 +365,7 @@ func TestHydrateDedupesQueuedAgainstInMemory(t *testing.T) {
 	mgr := NewConversationManager(convID, database, server.logger, server.toolSetConfig,
 		func(context.Context, llm.Message, llm.Usage) error { return nil },
 		func(context.Context, llm.Message, llm.Usage) error { return nil },
-		func(ConversationState) {}, server.streamPub)
+		func(ConversationState) {}, server.streamPub, server.conversationPub)
 	mgr.mu.Lock()
 	mgr.pendingBatches = []pendingBatch{{
 		Kind: pendingBatchUser, Messages: []llm.Message{{}}, ModelID: "predictable",
@@ -61,7 +61,7 @@ func TestRetryDoubleClickDeduped(t *testing.T) {
 	gllm := &gatingTestLLM{inner: ps, err: fmt.Errorf("connection error: EOF"), gate: gate}
 
 	svr := NewServer(database, &testLLMManager{service: gllm},
-		claudetool.ToolSetConfig{EnableBrowser: false},
+		claudetool.ToolSetConfig{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
 		true, "predictable", "")
 	if svr.terminals != nil {
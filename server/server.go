@@ -8,7 +8,6 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
-	"net/http/pprof"
 	"net/url"
 	"os"
 	"os/exec"
@@ -23,11 +22,15 @@ import (
 	"tailscale.com/util/singleflight"
 
 	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/coldstore"
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/github"
+	"shelley.exe.dev/inbox"
 	"shelley.exe.dev/llm"
 	"shelley.exe.dev/models"
 	"shelley.exe.dev/server/notifications"
+	"shelley.exe.dev/slackbot"
 	"shelley.exe.dev/subpub"
 	"shelley.exe.dev/ui"
 )
@@ -63,9 +66,10 @@ type APIMessage struct {
 // ConversationState represents the current state of a conversation.
 // This is broadcast to all subscribers whenever the state changes.
 type ConversationState struct {
-	ConversationID string `json:"conversation_id"`
-	Working        bool   `json:"working"`
-	Model          string `json:"model,omitempty"`
+	ConversationID string             `json:"conversation_id"`
+	Working        bool               `json:"working"`
+	Model          string             `json:"model,omitempty"`
+	Status         ConversationStatus `json:"status,omitempty"`
 }
 
 // ConversationWithState combines a conversation with its working state.
@@ -112,6 +116,13 @@ type StreamResponse struct {
 	Heartbeat bool `json:"heartbeat,omitempty"`
 	// NotificationEvent is set when a notification-worthy event occurs (e.g. agent finished).
 	NotificationEvent *notifications.Event `json:"notification_event,omitempty"`
+	// TurnComplete is set when an agent turn ends, including subagent and
+	// muted conversations' turns.
+	TurnComplete *TurnCompleteEvent `json:"turn_complete,omitempty"`
+	// SimilarConversation is set, once, when a new conversation's first
+	// prompt is much like that of a recent conversation in its working
+	// directory, so the UI can offer to continue that one instead.
+	SimilarConversation *SimilarConversation `json:"similar_conversation,omitempty"`
 	// ToolProgress is set when a running tool reports partial output.
 	ToolProgress *llm.ToolProgress `json:"tool_progress,omitempty"`
 	// StreamDelta is set when the LLM streams partial text content.
@@ -128,6 +139,24 @@ type StreamResponse struct {
 	// it to hide a loading spinner, or — for "peek and disconnect" use
 	// cases like notification previews — to close the connection.
 	SnapshotComplete bool `json:"snapshot_complete,omitempty"`
+	// Resync tells the client that events were dropped because it fell
+	// behind, so it should re-fetch the state it shows.
+	Resync bool `json:"resync,omitempty"`
+}
+
+const (
+	// streamBufferSize is how many events an SSE stream buffers for a client.
+	streamBufferSize = 256
+	// streamReplaySize is how many published message events a conversation
+	// keeps, so that reconnecting clients can catch up without the database.
+	streamReplaySize = 256
+)
+
+// streamOptions configure SSE stream subscriptions. A client that falls
+// behind loses its oldest buffered events, not its connection, and is sent
+// a Resync.
+func streamOptions() []subpub.Option {
+	return []subpub.Option{subpub.WithBufferSize(streamBufferSize), subpub.WithPolicy(subpub.DropOldest)}
 }
 
 // LLMProvider is an interface for getting LLM services
@@ -189,7 +218,7 @@ func toAPIMessages(messages []generated.Message) []APIMessage {
 
 // llmDataForAPI prepares a message's llm_data for the API in a single JSON
 // parse, returning client-safe data plus the end-of-turn flag for agent
-// messages. It combines what stripImageDataFromLLMData and
+// and error messages. It combines what stripImageDataFromLLMData and
 // extractEndOfTurn did separately, each of which unmarshalled the full
 // message; doing it once halves the JSON work in the per-conversation
 // backfill, which dominates stream connect time for long conversations.
@@ -206,7 +235,7 @@ func llmDataForAPI(llmData *string, msgType, messageID string) (*string, *bool)
 	}
 
 	var endOfTurnPtr *bool
-	if msgType == string(db.MessageTypeAgent) {
+	if msgType == string(db.MessageTypeAgent) || msgType == string(db.MessageTypeError) {
 		eot := msg.EndOfTurn
 		endOfTurnPtr = &eot
 	}
@@ -332,10 +361,21 @@ type Server struct {
 	predictableOnly          bool
 	defaultModel             string
 	requireHeader            string
+	adminToken               string // for /api/admin/debug; see SetAdminToken
+	coldStore                coldstore.Store
+	coldAfter                time.Duration
+	coldMu                   sync.Mutex
+	workers                  *workerHub
+	github                   *github.App
+	inbox                    inbox.Config
+	sendEmail                func(inbox.Reply) error
+	slack                    *slackbot.App
 	refreshBuiltModels       func(context.Context) ([]models.Built, error)
+	loadConfig               ConfigLoader
 	conversationGroup        singleflight.Group[string, *ConversationManager]
 	versionChecker           *VersionChecker
 	notifDispatcher          *notifications.Dispatcher
+	notifScheduler           *notifications.Scheduler
 	conversationListStream   *conversationListStream
 	conversationListGitCache *conversationListGitCache
 	// exeNotifyOnce guards lazy detection of the exe.dev "notify" integration
@@ -345,10 +385,13 @@ type Server struct {
 	// streamPub is the server-wide subpub that fans out per-conversation
 	// events to every /api/stream2 subscriber. Events are tagged with their
 	// ConversationID so clients can route them.
-	streamPub  *subpub.SubPub[StreamResponse]
-	shutdownCh chan struct{} // Signals background routines to stop
-	listenPort int           // TCP port the server is listening on
-	terminals  *TerminalSessions
+	streamPub *subpub.SubPub[StreamResponse]
+	// conversationPub carries each conversation's events, by conversation
+	// ID, to legacy /api/conversation/<id>/stream subscribers.
+	conversationPub *subpub.Topics[string, StreamResponse]
+	shutdownCh      chan struct{} // Signals background routines to stop
+	listenPort      int           // TCP port the server is listening on
+	terminals       *TerminalSessions
 
 	// Banner, when non-empty, is shown in a full-width bar at the top of
 	// the UI. Useful for marking demo instances so they're not confused
@@ -367,6 +410,16 @@ type Server struct {
 	// to force summarization without a giant transcript.
 	piDistillKeepRecentTokens int
 
+	// agentWaitingDelay is how long a turn waiting on the user may go
+	// unanswered before EventAgentWaiting fires. Tests shorten it.
+	agentWaitingDelay time.Duration
+	// budgetAlerted records the top-level conversations already alerted
+	// for an exhausted subagent budget. Guarded by mu.
+	budgetAlerted map[string]bool
+	// turnWaiters run when a conversation's turn ends; see awaitTurnEnd.
+	// Guarded by mu.
+	turnWaiters map[string]*turnWaiter
+
 	// IndexedDB cache encryption master secret — see cache_key.go.
 	// Lives on the Server (not a package-global) so tests with
 	// independent DBs don't share state.
@@ -389,10 +442,16 @@ func NewServer(database *db.DB, llmManager LLMProvider, toolSetConfig claudetool
 		notifDispatcher:     notifications.NewDispatcher(logger),
 		shutdownCh:          make(chan struct{}),
 		hooksDir:            defaultHooksDir(),
+		agentWaitingDelay:   defaultAgentWaitingDelay,
+		budgetAlerted:       make(map[string]bool),
+		turnWaiters:         make(map[string]*turnWaiter),
 	}
 
+	s.notifDispatcher.OnDelivery(s.recordNotificationDelivery)
+	s.notifScheduler = notifications.NewScheduler(s.notifDispatcher, logger)
 	s.conversationListStream = newConversationListStream(s)
-	s.streamPub = subpub.New[StreamResponse]()
+	s.streamPub = subpub.New[StreamResponse](streamOptions()...)
+	s.conversationPub = subpub.NewTopics[string, StreamResponse](append(streamOptions(), subpub.WithReplay(streamReplaySize))...)
 	s.conversationListGitCache = newConversationListGitCache()
 
 	// Persistent terminal sessions live alongside the database so that they
@@ -423,11 +482,20 @@ func NewServer(database *db.DB, llmManager LLMProvider, toolSetConfig claudetool
 	// Set up subagent support
 	s.toolSetConfig.SubagentRunner = NewSubagentRunner(s)
 	s.toolSetConfig.SubagentDB = &db.SubagentDBAdapter{DB: database}
-	s.toolSetConfig.MaxSubagentDepth = 1 // Only top-level conversations can spawn subagents
+	s.toolSetConfig.Scratchpad = database
+	s.toolSetConfig.Messenger = NewHandoffMessenger(s)
+	applyToolSetDefaults(&s.toolSetConfig)
 
 	return s
 }
 
+// applyToolSetDefaults fills in the defaults for tool settings left unset.
+func applyToolSetDefaults(tc *claudetool.ToolSetConfig) {
+	if tc.MaxSubagentDepth == 0 {
+		tc.MaxSubagentDepth = 1 // Only top-level conversations can spawn subagents
+	}
+}
+
 // SetModelRefresher configures the user-triggered model catalog refresh.
 func (s *Server) SetModelRefresher(refresh func(context.Context) ([]models.Built, error)) {
 	s.refreshBuiltModels = refresh
@@ -450,10 +518,11 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("/api/conversations/new", http.HandlerFunc(s.handleNewConversation))                         // Small response
 	mux.Handle("POST /api/conversations/draft", http.HandlerFunc(s.handleCreateDraft))                      // Small response
 	mux.Handle("/api/conversations/distill-new-generation", http.HandlerFunc(s.handleDistillNewGeneration)) // Small response
-	mux.Handle("/api/conversation/", http.StripPrefix("/api/conversation", s.conversationMux()))
+	mux.Handle("/api/conversation/", http.StripPrefix("/api/conversation", s.thawConversations(s.conversationMux())))
 	mux.Handle("/api/conversation-by-slug/", compressionHandler(http.HandlerFunc(s.handleConversationBySlug)))
 	mux.Handle("/api/validate-cwd", http.HandlerFunc(s.handleValidateCwd)) // Small response
 	mux.Handle("POST /api/model-costs", http.HandlerFunc(s.handleModelCosts))
+	mux.Handle("GET /api/usage", http.HandlerFunc(s.handleUsage))
 	mux.Handle("/api/list-directory", compressionHandler(http.HandlerFunc(s.handleListDirectory)))
 	mux.Handle("/api/create-directory", http.HandlerFunc(s.handleCreateDirectory))
 	mux.Handle("/api/git/repos", compressionHandler(http.HandlerFunc(s.handleGitRepos)))
@@ -474,6 +543,11 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("/api/write-file", http.HandlerFunc(s.handleWriteFile))                                             // Small response
 	mux.Handle("/api/user-agents-md", http.HandlerFunc(s.handleUserAgentsMd))                                      // Small response
 	mux.HandleFunc("/api/exec-ws", s.handleExecWS)                                                                 // Websocket for shell commands
+	mux.Handle("GET /api/workers", s.requireAdminToken(http.HandlerFunc(s.handleWorkers)))                         // Connected workers
+	mux.HandleFunc("GET /api/workers/connect", s.handleWorkerConnect)                                              // Websocket for shelley worker
+	mux.HandleFunc("POST /api/github/webhook", s.handleGitHubWebhook)                                              // GitHub App deliveries
+	mux.HandleFunc("POST /api/slack/events", s.handleSlackEvents)                                                  // Slack app events
+	mux.HandleFunc("POST /api/email/inbound", s.handleInboundEmail)                                                // Email gateway
 	mux.HandleFunc("GET /api/terminals", s.handleTerminalsList)                                                    // List persistent dtach sessions
 	mux.HandleFunc("DELETE /api/terminals/{id}", s.handleTerminalDelete)
 	mux.HandleFunc("POST /api/terminals/{id}/kill", s.handleTerminalDelete)
@@ -488,6 +562,15 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("/api/notification-channels/", http.HandlerFunc(s.handleNotificationChannel))
 	mux.Handle("/api/notification-channel-types", http.HandlerFunc(s.handleNotificationChannelTypes))
 
+	// Admin API: every /api/admin route needs the admin token, or, without
+	// one, a request from localhost.
+	admin := http.NewServeMux()
+	admin.Handle("POST /api/admin/notifications/test", http.HandlerFunc(s.handleAdminTestNotification))
+	admin.Handle("GET /api/admin/notifications/deliveries", http.HandlerFunc(s.handleNotificationDeliveries))
+	admin.Handle("POST /api/admin/reload", http.HandlerFunc(s.handleAdminReload))
+	admin.Handle("/api/admin/debug/", http.StripPrefix("/api/admin", s.adminDebugMux()))
+	mux.Handle("/api/admin/", s.requireAdminToken(admin))
+
 	// Models API (dynamic list refresh)
 	mux.Handle("POST /api/models/refresh", compressionHandler(http.HandlerFunc(s.handleModelRefresh)))
 	mux.Handle("/api/models", compressionHandler(http.HandlerFunc(s.handleModels)))
@@ -516,13 +599,9 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("GET /debug/conversation-stream", http.HandlerFunc(s.handleDebugConversationStreamPage))
 	mux.Handle("GET /debug/conversation-stream/history", http.HandlerFunc(s.handleDebugConversationStreamHistory))
 	mux.Handle("GET /debug/stylebook", http.HandlerFunc(s.handleDebugStylebook))
-
-	// pprof endpoints
-	mux.Handle("GET /debug/pprof/", http.HandlerFunc(pprof.Index))
-	mux.Handle("GET /debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
-	mux.Handle("GET /debug/pprof/profile", http.HandlerFunc(pprof.Profile))
-	mux.Handle("GET /debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
-	mux.Handle("GET /debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+	// The original profiling paths, gated like /api/admin/debug.
+	mux.Handle("GET /debug/pprof/", s.requireAdminToken(s.adminDebugMux()))
+	mux.Handle("GET /debug/browsers", s.requireAdminToken(s.adminDebugMux()))
 
 	// Serve embedded UI assets
 	mux.Handle("/", s.staticHandler(ui.Assets()))
@@ -883,6 +962,10 @@ func (s *Server) getOrCreateConversationManager(ctx context.Context, conversatio
 		}
 		s.mu.Unlock()
 
+		if err := s.thawConversation(ctx, conversationID); err != nil {
+			return nil, err
+		}
+
 		recordMessage := func(ctx context.Context, message llm.Message, usage llm.Usage) error {
 			return s.recordMessage(ctx, conversationID, message, usage)
 		}
@@ -894,9 +977,12 @@ func (s *Server) getOrCreateConversationManager(ctx context.Context, conversatio
 			s.publishConversationState(state)
 		}
 
-		manager := NewConversationManager(conversationID, s.db, s.logger, s.toolSetConfig, recordMessage, recordTurnStart, onStateChange, s.streamPub)
+		manager := NewConversationManager(conversationID, s.db, s.logger, s.conversationToolSetConfig(), recordMessage, recordTurnStart, onStateChange, s.streamPub, s.conversationPub)
 		manager.userEmail = userEmail
 		manager.serverPort = s.listenPort
+		manager.hooksDir = s.hooksDir
+		manager.workers = s.workers
+		manager.compact = func(notice llm.Message) bool { return s.compactWhenFull(manager, notice) }
 		// Hydrate runs DB transactions, which fire OnCommit hooks. Those hooks
 		// (e.g. notify on the conversation list patch stream) acquire s.mu, so
 		// we must not hold it here.
@@ -921,8 +1007,8 @@ func (s *Server) getOrCreateConversationManager(ctx context.Context, conversatio
 }
 
 // getOrCreateSubagentConversationManager is like getOrCreateConversationManager but
-// uses a toolSetConfig with SubagentDepth incremented by 1, preventing subagents
-// from spawning their own subagents (when MaxSubagentDepth is 1).
+// notifies the parent conversation when the subagent finishes. Hydrate sets
+// the subagent's depth from its ancestry.
 func (s *Server) getOrCreateSubagentConversationManager(ctx context.Context, conversationID string) (*ConversationManager, error) {
 	manager, err, _ := s.conversationGroup.Do(conversationID, func() (*ConversationManager, error) {
 		s.mu.Lock()
@@ -944,12 +1030,10 @@ func (s *Server) getOrCreateSubagentConversationManager(ctx context.Context, con
 			s.publishConversationState(state)
 		}
 
-		// Use a modified toolSetConfig with incremented depth for subagents
-		subagentConfig := s.toolSetConfig
-		subagentConfig.SubagentDepth = s.toolSetConfig.SubagentDepth + 1
-
-		manager := NewConversationManager(conversationID, s.db, s.logger, subagentConfig, recordMessage, recordTurnStart, onStateChange, s.streamPub)
+		manager := NewConversationManager(conversationID, s.db, s.logger, s.conversationToolSetConfig(), recordMessage, recordTurnStart, onStateChange, s.streamPub, s.conversationPub)
 		manager.serverPort = s.listenPort
+		manager.hooksDir = s.hooksDir
+		manager.compact = func(notice llm.Message) bool { return s.compactWhenFull(manager, notice) }
 		// Wire up done notification: when this subagent finishes, notify the parent
 		// by injecting a user message into the parent's loop so the LLM sees it.
 		manager.onDone = func() {
@@ -988,15 +1072,24 @@ func ExtractDisplayData(message llm.Message) interface{} {
 
 	var displayData []any
 	for _, content := range message.Content {
-		if content.Type == llm.ContentTypeToolResult && content.Display != nil {
-			// Include tool name if we can find it
-			toolName := toolNameMap[content.ToolUseID]
-			displayData = append(displayData, map[string]any{
-				"tool_use_id": content.ToolUseID,
-				"tool_name":   toolName,
-				"display":     content.Display,
-			})
+		if content.Type != llm.ContentTypeToolResult {
+			continue
+		}
+		timed := content.ToolUseStartTime != nil && content.ToolUseEndTime != nil
+		if content.Display == nil && !timed {
+			continue
+		}
+		// Include tool name if we can find it
+		toolName := toolNameMap[content.ToolUseID]
+		item := map[string]any{
+			"tool_use_id": content.ToolUseID,
+			"tool_name":   toolName,
+			"display":     content.Display,
 		}
+		if timed {
+			item["duration_ms"] = content.ToolUseEndTime.Sub(*content.ToolUseStartTime).Milliseconds()
+		}
+		displayData = append(displayData, item)
 	}
 
 	if len(displayData) > 0 {
@@ -1065,6 +1158,7 @@ func (s *Server) buildCreateMessageParams(conversationID string, message llm.Mes
 		DisplayData:         ExtractDisplayData(message),
 		ExcludedFromContext: message.ExcludedFromContext,
 		MarkAgentDone:       markAgentDone,
+		Status:              string(messageStatus(message)),
 	}, nil
 }
 
@@ -1079,30 +1173,32 @@ func (s *Server) recordMessage(ctx context.Context, conversationID string, messa
 	// recompute on its own commit hook).
 	params.BumpTimestamp = true
 	markAgentDone := params.MarkAgentDone
+	status := ConversationStatus(params.Status)
 	createdMsg, err := s.db.CreateMessage(ctx, params)
 	if err != nil {
 		return fmt.Errorf("failed to create message: %w", err)
 	}
-	// Sync the conversation manager's in-memory agentWorking flag and fire
-	// onStateChange / onDone now that the DB has committed. The persisted
-	// agent_working=false was already written in the message-INSERT Tx above
-	// (via MarkAgentDone), so syncAgentWorking deliberately skips the DB write
-	// — re-writing it would only cost an extra commit + full-list recompute.
-	if markAgentDone {
-		s.mu.Lock()
-		mgr := s.activeConversations[conversationID]
-		s.mu.Unlock()
-		if mgr != nil {
-			mgr.syncAgentWorking(false)
-		}
-	}
-
-	// Touch active manager activity time if present and bump its max sequence ID.
+	// Touch active manager activity time if present, and count the message
+	// toward the turn's stats and tool-failure streak.
 	s.mu.Lock()
 	mgr, ok := s.activeConversations[conversationID]
 	s.mu.Unlock()
 	if ok {
 		mgr.Touch()
+		mgr.addToTurn(message, usage)
+		s.alertToolFailures(mgr, conversationID, message)
+	}
+
+	// Sync the conversation manager's in-memory agentWorking flag and status
+	// and fire onStateChange / onDone now that the DB has committed. The
+	// persisted agent_working=false and status were already written in the
+	// message-INSERT Tx above (via MarkAgentDone and Status), so
+	// syncAgentWorking deliberately skips the DB write — re-writing them would
+	// only cost an extra commit + full-list recompute.
+	if markAgentDone && ok {
+		mgr.syncAgentWorking(false, status)
+	} else if ok {
+		mgr.syncStatus(status)
 	}
 
 	// Notify subscribers with only the new message - use WithoutCancel because
@@ -1124,8 +1220,20 @@ func (s *Server) recordMessage(ctx context.Context, conversationID string, messa
 // userEmail is the exe.dev author captured at queue time (drain runs on a
 // background context, so it can't be read from the request here); it is
 // stamped onto the new row. Empty when the queuing request carried no header.
-func (s *Server) recordDrainedQueuedMessage(ctx context.Context, conversationID, queuedID string, message llm.Message, userEmail string) error {
-	params, err := s.buildCreateMessageParams(conversationID, message, llm.Usage{})
+// handoffFrom, when set, is recorded in user_data as the sending conversation.
+func (s *Server) recordDrainedQueuedMessage(ctx context.Context, conversationID, queuedID string, message llm.Message, userEmail, handoffFrom string, queuedAt time.Time) error {
+	ud := map[string]any{}
+	if handoffFrom != "" {
+		ud["handoff_from"] = handoffFrom
+	}
+	if !queuedAt.IsZero() {
+		ud["queue_wait_ms"] = time.Since(queuedAt).Milliseconds()
+	}
+	var userData []interface{}
+	if len(ud) > 0 {
+		userData = append(userData, ud)
+	}
+	params, err := s.buildCreateMessageParams(conversationID, message, llm.Usage{}, userData...)
 	if err != nil {
 		return err
 	}
@@ -1168,6 +1276,23 @@ func userEmailFromContext(ctx context.Context) string {
 	return email
 }
 
+// handoffFromContextKey carries the conversation that posted a message via
+// handoff down to QueueMessage, which persists it in the QueuedMessage entry.
+type handoffFromContextKey struct{}
+
+// contextWithHandoffFrom returns a child context carrying the sending
+// conversation's id.
+func contextWithHandoffFrom(ctx context.Context, conversationID string) context.Context {
+	return context.WithValue(ctx, handoffFromContextKey{}, conversationID)
+}
+
+// handoffFromContext returns the conversation id stamped by
+// contextWithHandoffFrom, or "" if none was set.
+func handoffFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(handoffFromContextKey{}).(string)
+	return id
+}
+
 // recordTurnStartMessage records the user message that starts an agent turn,
 // folding the agent_working=true flip and the updated_at bump into the same Tx
 // as the message INSERT. This replaces a separate SetAgentWorking(true) commit
@@ -1182,6 +1307,7 @@ func (s *Server) recordTurnStartMessage(ctx context.Context, conversationID stri
 	}
 	params.MarkAgentStart = true
 	params.BumpTimestamp = true
+	params.Status = string(StatusThinking)
 	// Attribute the turn-start user row to its author. recordTurnStartMessage
 	// is only ever called with a genuine user message (AcceptUserMessage's
 	// turn-start recorder), so unlike buildCreateMessageParams — which also
@@ -1219,6 +1345,9 @@ func (s *Server) recordMessages(ctx context.Context, conversationID string, msgs
 		if err != nil {
 			return err
 		}
+		// These are copied or synthetic messages, not steps of a turn, so
+		// they don't change the status.
+		params.Status = ""
 		paramsList = append(paramsList, params)
 	}
 	// Whether any message ends the turn — used to sync the manager's in-memory
@@ -1501,10 +1630,8 @@ func (s *Server) publishConversationListUpdate(update ConversationListUpdate) {
 		s.streamPub.Broadcast(streamData)
 	}
 	// Legacy /api/conversation/<id>/stream subscribers (iOS, CLI) still
-	// receive list updates via the per-conversation subpub.
-	for _, manager := range s.activeConversations {
-		manager.subpub.Broadcast(streamData)
-	}
+	// receive list updates via the per-conversation topics.
+	s.conversationPub.BroadcastAll(streamData)
 }
 
 // publicHostname returns the server's public hostname.
@@ -1520,11 +1647,20 @@ func publicHostname() string {
 
 // conversationURL returns the full URL for a conversation, using slug if available.
 func (s *Server) conversationURL(slug string) string {
-	hostname := publicHostname()
-	path := "/"
-	if slug != "" {
-		path = "/c/" + slug
+	if slug == "" {
+		return s.publicURL("/")
 	}
+	return s.publicURL("/c/" + slug)
+}
+
+// conversationCancelURL is the API endpoint that cancels conversationID's run.
+func (s *Server) conversationCancelURL(conversationID string) string {
+	return s.publicURL("/api/conversation/" + conversationID + "/cancel")
+}
+
+// publicURL is path on this server as reached from outside the VM.
+func (s *Server) publicURL(path string) string {
+	hostname := publicHostname()
 	if s.listenPort == 443 || s.listenPort == 0 {
 		return fmt.Sprintf("https://%s%s", hostname, path)
 	}
@@ -1540,22 +1676,26 @@ func (s *Server) publishConversationState(state ConversationState) {
 	// per-conversation SSE broadcast and end-of-turn notifications.
 
 	// When the agent finishes working, emit a notification event.
-	// Skip notifications for subagent conversations — they're internal
-	// and would just be noise for the user.
+	// Skip notifications for subagent conversations and muted or
+	// unsubscribed ones (see notificationsSuppressed).
+	s.updateTurnWaiters(state)
 	var notifEvent *notifications.Event
+	var turnEvent *TurnCompleteEvent
 	if !state.Working {
 		conv, convErr := s.db.GetConversationByID(context.Background(), state.ConversationID)
-		isSubagent := convErr == nil && conv.ParentConversationID != nil
-		// Honor an explicit per-conversation opt-out: conversations created
-		// with disable_notifications suppress all end-of-turn notifications
-		// (push, email, discord, ntfy) and hooks, same as subagents.
-		notifyDisabled := convErr == nil && db.ParseConversationOptions(conv.ConversationOptions).DisableNotifications
-		suppressNotify := isSubagent || notifyDisabled
+		suppressNotify := convErr == nil && s.notificationsSuppressed(context.Background(), conv)
+		s.mu.Lock()
+		manager := s.activeConversations[state.ConversationID]
+		s.mu.Unlock()
+		if manager != nil {
+			var cwd string
+			if convErr == nil && conv.Cwd != nil {
+				cwd = *conv.Cwd
+			}
+			turnEvent = s.completeTurn(manager, state, cwd)
+		}
 		var hooks []db.ConversationHook
 		if !suppressNotify {
-			s.mu.Lock()
-			manager := s.activeConversations[state.ConversationID]
-			s.mu.Unlock()
 			if manager != nil {
 				var err error
 				hooks, err = manager.EndOfTurnHooks(context.Background())
@@ -1584,6 +1724,10 @@ func (s *Server) publishConversationState(state ConversationState) {
 			ConversationURL:   s.conversationURL(slug),
 			VMName:            strings.TrimSuffix(hostname, ".exe.xyz"),
 		}
+		if manager != nil {
+			duration, cost := manager.turnStats()
+			payload.DurationSeconds, payload.CostUSD = duration.Seconds(), cost
+		}
 		// The literal latest agent message is often a tool-only turn (e.g.
 		// agent ended on `git status`), which produces a useless "Agent
 		// finished" notification. Walk back through every agent message
@@ -1599,7 +1743,10 @@ func (s *Server) publishConversationState(state ConversationState) {
 			Payload:        payload,
 		}
 		if !suppressNotify {
-			s.notifDispatcher.Dispatch(context.Background(), event)
+			s.notifScheduler.Dispatch(context.Background(), event)
+			if manager != nil {
+				s.armAgentWaiting(manager, state.ConversationID, payload.FinalResponse)
+			}
 			for _, hook := range hooks {
 				go s.sendEndOfTurnHook(context.Background(), hook, event)
 			}
@@ -1636,15 +1783,14 @@ func (s *Server) publishConversationState(state ConversationState) {
 		ConversationID:    state.ConversationID,
 		ConversationState: &state,
 		NotificationEvent: notifEvent,
+		TurnComplete:      turnEvent,
 	}
 	if s.streamPub != nil {
 		s.streamPub.Broadcast(streamData)
 	}
 	// Legacy /api/conversation/<id>/stream subscribers (iOS, CLI) still
-	// receive state updates via the per-conversation subpub.
-	for _, manager := range s.activeConversations {
-		manager.subpub.Broadcast(streamData)
-	}
+	// receive state updates via the per-conversation topics.
+	s.conversationPub.BroadcastAll(streamData)
 }
 
 // IsAgentWorking returns whether the agent is currently working on the given conversation.
@@ -1728,6 +1874,7 @@ func (s *Server) Cleanup() {
 			toCleanup = append(toCleanup, manager)
 			toCleanupIDs = append(toCleanupIDs, id)
 			delete(s.activeConversations, id)
+			s.conversationPub.Forget(id)
 		}
 	}
 	s.mu.Unlock()
@@ -1788,6 +1935,8 @@ func (s *Server) StartWithListeners(tcpListener net.Listener, socketPath string)
 	// Start auto-upgrade routine
 	go s.autoUpgradeRoutine()
 
+	go s.coldStorageRoutine()
+
 	// Get actual port from listener
 	actualPort := tcpListener.Addr().(*net.TCPAddr).Port
 	s.listenPort = actualPort
@@ -1845,6 +1994,23 @@ func (s *Server) StartWithListeners(tcpListener net.Listener, socketPath string)
 		}()
 	}
 
+	// Reload the configuration on SIGHUP.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-hup:
+				if err := s.ReloadConfig(context.Background()); err != nil {
+					s.logger.Error("Failed to reload configuration", "error", err)
+				}
+			case <-s.shutdownCh:
+				return
+			}
+		}
+	}()
+
 	// Wait for shutdown signal or server error
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -1872,6 +2038,11 @@ func (s *Server) StartWithListeners(tcpListener net.Listener, socketPath string)
 	// it by the shutdown context so a hung stopLoop can't starve HTTP
 	// shutdown's deadline.
 	s.stopAllConversations(ctx)
+	// Closing tabs leaves pooled browsers waiting out their idle timeout;
+	// kill them now.
+	if pool := s.currentToolSetConfig().BrowserPool; pool != nil {
+		pool.Close()
+	}
 
 	if err := tcpServer.Shutdown(ctx); err != nil {
 		s.logger.Error("TCP server forced to shutdown", "error", err)
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// similarWindow is how recently a conversation must have been updated
+	// for a new conversation's prompt to be compared with its first.
+	similarWindow = 24 * time.Hour
+	// similarCandidates caps the conversations compared.
+	similarCandidates = 50
+	// similarThreshold is the similarity of two prompts' words at which
+	// they count as the same request.
+	similarThreshold = 0.8
+	// similarMinWords is the fewest distinct words a prompt needs to be
+	// compared; short prompts like "hi" are alike by accident.
+	similarMinWords = 3
+)
+
+// SimilarConversation is a recent conversation whose first prompt is much
+// like a new conversation's, so the new one may be an accidental duplicate.
+type SimilarConversation struct {
+	ConversationID string  `json:"conversation_id"`
+	Slug           string  `json:"slug,omitempty"`
+	Similarity     float64 `json:"similarity"`
+}
+
+// findSimilarConversation returns the recent conversation in cwd whose
+// first prompt is most like prompt, if any is over similarThreshold.
+func (s *Server) findSimilarConversation(ctx context.Context, cwd, prompt string) *SimilarConversation {
+	words := promptWords(prompt)
+	if cwd == "" || len(words) < similarMinWords {
+		return nil
+	}
+	rows, err := s.db.ListRecentPrompts(ctx, cwd, time.Now().Add(-similarWindow), similarCandidates)
+	if err != nil {
+		s.logger.Warn("Failed to list recent prompts", "cwd", cwd, "error", err)
+		return nil
+	}
+	var best *SimilarConversation
+	for _, r := range rows {
+		similarity := promptSimilarity(words, promptWords(r.Prompt))
+		if similarity >= similarThreshold && (best == nil || similarity > best.Similarity) {
+			best = &SimilarConversation{ConversationID: r.ConversationID, Slug: derefString(r.Slug), Similarity: similarity}
+		}
+	}
+	return best
+}
+
+// promptWords returns the set of lowercased words in prompt.
+func promptWords(prompt string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[w] = true
+	}
+	return words
+}
+
+// promptSimilarity returns the Jaccard similarity of two word sets.
+func promptSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
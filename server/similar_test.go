@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewConversationSuggestsSimilar(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	cwd := t.TempDir()
+	create := func(message, cwd string) (id string, similar *SimilarConversation) {
+		body, _ := json.Marshal(ChatRequest{Message: message, Model: "predictable", Cwd: cwd})
+		w := httptest.NewRecorder()
+		s.handleNewConversation(w, httptest.NewRequest(http.MethodPost, "/api/conversations/new", strings.NewReader(string(body))))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("%q: got %d: %s", message, w.Code, w.Body)
+		}
+		var resp struct {
+			ConversationID      string               `json:"conversation_id"`
+			SimilarConversation *SimilarConversation `json:"similar_conversation"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.ConversationID, resp.SimilarConversation
+	}
+
+	first, similar := create("echo: fix the flaky login test", cwd)
+	if similar != nil {
+		t.Errorf("first conversation: got similar %+v", similar)
+	}
+	// The first prompt is recorded as the turn starts.
+	waitFor(t, 5*time.Second, func() bool {
+		s.mu.Lock()
+		manager := s.activeConversations[first]
+		s.mu.Unlock()
+		return manager != nil && !manager.IsAgentWorking()
+	})
+
+	if _, similar := create("Echo: fix the flaky login test!", cwd); similar == nil || similar.ConversationID != first {
+		t.Errorf("repeated prompt: got similar %+v, want %s", similar, first)
+	}
+	if _, similar := create("echo: fix the flaky login test", t.TempDir()); similar != nil {
+		t.Errorf("another directory: got similar %+v", similar)
+	}
+	if _, similar := create("echo: add a logout button", cwd); similar != nil {
+		t.Errorf("different prompt: got similar %+v", similar)
+	}
+}
+
+func TestPromptSimilarity(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want float64
+	}{
+		{"Fix the build", "fix the BUILD.", 1},
+		{"fix the build", "fix the tests", 0.5},
+		{"fix the build", "", 0},
+	} {
+		if got := promptSimilarity(promptWords(c.a), promptWords(c.b)); got != c.want {
+			t.Errorf("promptSimilarity(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/slackbot"
+	"shelley.exe.dev/slug"
+)
+
+// slackProgressInterval is how often a working conversation's progress
+// message is brought up to date.
+const slackProgressInterval = 10 * time.Second
+
+// slackMaxEvent is the largest Events API request read.
+const slackMaxEvent = 1 << 20
+
+// slackJob is one message's turn and the message in its thread that
+// reports the turn's progress.
+type slackJob struct {
+	msg        *slackbot.Message
+	progressTS string
+	url        string
+	done       chan struct{}
+}
+
+// SetSlack makes the server act as app: a message mentioning it starts a
+// conversation, mirrored in the message's thread, and replies in the
+// thread continue it.
+func (s *Server) SetSlack(app *slackbot.App) {
+	s.slack = app
+}
+
+// handleSlackEvents handles POST /api/slack/events, the Slack app's Events
+// API requests.
+func (s *Server) handleSlackEvents(w http.ResponseWriter, r *http.Request) {
+	app := s.slack
+	if app == nil {
+		http.Error(w, "Slack app is disabled: no slack.bot_token is configured", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, slackMaxEvent))
+	if err != nil {
+		http.Error(w, "Failed to read event", http.StatusBadRequest)
+		return
+	}
+	if err := app.VerifySignature(body, r.Header.Get("X-Slack-Request-Timestamp"), r.Header.Get("X-Slack-Signature"), time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	// Slack retries events not acknowledged within three seconds; every
+	// event is acknowledged at once, so a retry is a duplicate.
+	if r.Header.Get("X-Slack-Retry-Num") != "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	challenge, m, err := slackbot.Parse(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if challenge != "" {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, challenge)
+		return
+	}
+	if m != nil {
+		go s.runSlackMessage(m)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// runSlackMessage starts the turn m asks for, if it's for the app and
+// from an allowed user in an allowed channel, and reports an error
+// starting it in the thread.
+func (s *Server) runSlackMessage(m *slackbot.Message) {
+	if !s.slack.Config.Allowed(m) {
+		s.logger.Warn("Ignoring Slack message from a user or channel not allowed", "user", m.User, "channel", m.Channel)
+		return
+	}
+	ctx := context.Background()
+	conv, err := s.db.GetConversationBySlug(ctx, slackSlug(m))
+	if err != nil {
+		if !m.Mention {
+			// A reply in a thread without a conversation.
+			return
+		}
+		conv = nil
+	}
+	progressTS, err := s.slack.PostMessage(ctx, m.Channel, m.Thread, "Working on it.")
+	if err != nil {
+		s.logger.Error("Failed to post to Slack", "channel", m.Channel, "error", err)
+		return
+	}
+	job := &slackJob{msg: m, progressTS: progressTS, done: make(chan struct{})}
+	if err := s.startSlackJob(ctx, job, conv); err != nil {
+		s.logger.Error("Failed to start Slack job", "channel", m.Channel, "thread", m.Thread, "error", err)
+		if err := s.slack.UpdateMessage(ctx, m.Channel, progressTS, fmt.Sprintf("Couldn't start: %v", err)); err != nil {
+			s.logger.Error("Failed to update Slack message", "channel", m.Channel, "error", err)
+		}
+	}
+}
+
+// startSlackJob sends job's message to conv, the thread's conversation,
+// creating it if conv is nil.
+func (s *Server) startSlackJob(ctx context.Context, job *slackJob, conv *generated.Conversation) error {
+	m := job.msg
+	prompt := fmt.Sprintf("<@%s> replied on Slack:\n\n%s", m.User, m.Text)
+	if conv == nil {
+		var err error
+		if conv, err = s.createSlackConversation(ctx, slackSlug(m)); err != nil {
+			return err
+		}
+		prompt = fmt.Sprintf("<@%s> mentioned you on Slack:\n\n%s\n\nYour progress is mirrored in the Slack thread, and your final message is posted there, so end with a summary for the people there.", m.User, m.Text)
+	}
+	job.url = s.conversationURL(derefString(conv.Slug))
+	modelID := derefString(conv.Model)
+	service, err := s.llmManager.GetService(modelID)
+	if err != nil {
+		return err
+	}
+	manager, err := s.getOrCreateConversationManager(ctx, conv.ConversationID, "")
+	if err != nil {
+		return err
+	}
+
+	finish := func() {
+		close(job.done)
+		go s.finishSlackJob(conv.ConversationID, job)
+	}
+	if manager.IsAgentWorking() || !s.awaitTurnEnd(conv.ConversationID, finish) {
+		return fmt.Errorf("still working on an earlier message in <%s|this conversation>", job.url)
+	}
+
+	msg := llm.Message{Role: llm.MessageRoleUser, Content: []llm.Content{{Type: llm.ContentTypeText, Text: prompt}}}
+	if _, err := manager.AcceptUserMessage(ctx, service, modelID, msg); err != nil {
+		s.cancelTurnEnd(conv.ConversationID)
+		return err
+	}
+	go s.reportSlackProgress(conv.ConversationID, job)
+	return nil
+}
+
+// createSlackConversation creates a thread's conversation, named name.
+func (s *Server) createSlackConversation(ctx context.Context, name string) (*generated.Conversation, error) {
+	cfg := s.slack.Config
+	modelID := cfg.Model
+	if modelID == "" {
+		var err error
+		if modelID, err = s.defaultModelIn(cfg.Cwd, s.getModelList()); err != nil {
+			return nil, err
+		}
+	}
+	var cwd *string
+	if cfg.Cwd != "" {
+		cwd = &cfg.Cwd
+	}
+	conv, err := s.db.CreateConversation(ctx, &name, true, cwd, &modelID, db.ConversationOptions{})
+	if err != nil {
+		return nil, err
+	}
+	go s.publishConversationListUpdate(ConversationListUpdate{Type: "update", Conversation: conv})
+	return conv, nil
+}
+
+// slackSlug names the conversation of m's thread.
+func slackSlug(m *slackbot.Message) string {
+	return slug.Sanitize(fmt.Sprintf("slack %s %s", m.Channel, strings.ReplaceAll(m.Thread, ".", " ")))
+}
+
+// reportSlackProgress keeps job's progress message up to date until the
+// turn ends.
+func (s *Server) reportSlackProgress(conversationID string, job *slackJob) {
+	ticker := time.NewTicker(slackProgressInterval)
+	defer ticker.Stop()
+	m := job.msg
+	var last string
+	for {
+		select {
+		case <-job.done:
+			return
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+		}
+		msgs, err := s.db.ListAgentMessagesSinceLastUser(context.Background(), conversationID)
+		if err != nil {
+			s.logger.Warn("Failed to read Slack job progress", "conversationID", conversationID, "error", err)
+			continue
+		}
+		text := slackProgressText(msgs, job.url, true)
+		if text == last {
+			continue
+		}
+		last = text
+		if err := s.slack.UpdateMessage(context.Background(), m.Channel, job.progressTS, text); err != nil {
+			s.logger.Warn("Failed to update Slack message", "channel", m.Channel, "error", err)
+		}
+	}
+}
+
+// slackProgressText collapses the turn's tool calls, given its agent
+// messages newest first, into a count and the latest call.
+func slackProgressText(msgs []generated.Message, url string, working bool) string {
+	var calls int
+	var latest string
+	for _, m := range msgs {
+		if m.LlmData == nil {
+			continue
+		}
+		var msg llm.Message
+		if err := json.Unmarshal([]byte(*m.LlmData), &msg); err != nil {
+			continue
+		}
+		for _, c := range msg.Content {
+			if c.Type == llm.ContentTypeToolUse {
+				calls++
+			}
+		}
+		if latest == "" {
+			latest = summarizeLastToolUse(msg)
+		}
+	}
+	plural := "s"
+	if calls == 1 {
+		plural = ""
+	}
+	if !working {
+		return fmt.Sprintf("Made %d tool call%s in <%s|this conversation>.", calls, plural, url)
+	}
+	text := fmt.Sprintf("Working on it in <%s|this conversation>: %d tool call%s so far.", url, calls, plural)
+	if latest != "" {
+		text += "\n> " + latest
+	}
+	return text
+}
+
+// finishSlackJob collapses job's progress message and posts the turn's
+// final message in the thread.
+func (s *Server) finishSlackJob(conversationID string, job *slackJob) {
+	ctx := context.Background()
+	m := job.msg
+	msgs, err := s.db.ListAgentMessagesSinceLastUser(ctx, conversationID)
+	if err != nil {
+		s.logger.Error("Failed to read Slack job result", "conversationID", conversationID, "error", err)
+	}
+	if err := s.slack.UpdateMessage(ctx, m.Channel, job.progressTS, slackProgressText(msgs, job.url, false)); err != nil {
+		s.logger.Error("Failed to update Slack message", "channel", m.Channel, "error", err)
+	}
+	reply := finalResponseBody(msgs)
+	if reply == "" {
+		reply = "Done."
+	}
+	if _, err := s.slack.PostMessage(ctx, m.Channel, m.Thread, reply); err != nil {
+		s.logger.Error("Failed to post to Slack", "channel", m.Channel, "error", err)
+	}
+}
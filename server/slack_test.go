@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/slackbot"
+)
+
+// fakeSlack serves the Web API calls the Slack app makes, recording the
+// text of each message by its timestamp.
+type fakeSlack struct {
+	mu       sync.Mutex
+	messages map[string]string
+	posted   []string
+}
+
+func (f *fakeSlack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var in struct{ Channel, TS, Text string }
+	json.NewDecoder(r.Body).Decode(&in)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/chat.postMessage":
+		ts := fmt.Sprintf("2.%d", len(f.posted))
+		f.posted = append(f.posted, ts)
+		f.messages[ts] = in.Text
+		fmt.Fprintf(w, `{"ok":true,"ts":%q}`, ts)
+	case "/chat.update":
+		f.messages[in.TS] = in.Text
+		w.Write([]byte(`{"ok":true}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Message returns the text of the nth message posted, or "".
+func (f *fakeSlack) Message(n int) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if n >= len(f.posted) {
+		return ""
+	}
+	return f.messages[f.posted[n]]
+}
+
+func TestSlackEvents(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	fake := &fakeSlack{messages: map[string]string{}}
+	api := httptest.NewServer(fake)
+	t.Cleanup(api.Close)
+	app, err := slackbot.New(slackbot.Config{BotToken: "xoxb-1", SigningSecret: "s3cret", AllowedUsers: []string{"U1"}, AllowedChannels: []string{"C1"}, Model: "predictable", APIURL: api.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetSlack(app)
+
+	send := func(body, secret string) *httptest.ResponseRecorder {
+		ts := fmt.Sprint(time.Now().Unix())
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + ts + ":" + body))
+		req := httptest.NewRequest(http.MethodPost, "/api/slack/events", bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Slack-Request-Timestamp", ts)
+		req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+		w := httptest.NewRecorder()
+		s.handleSlackEvents(w, req)
+		return w
+	}
+	event := func(e string) string {
+		return `{"type":"event_callback","authorizations":[{"user_id":"UBOT"}],"event":` + e + `}`
+	}
+
+	if w := send(`{"type":"url_verification","challenge":"abc"}`, "s3cret"); w.Code != http.StatusOK || w.Body.String() != "abc" {
+		t.Errorf("url_verification: got %d %q", w.Code, w.Body)
+	}
+	if w := send(event(`{"type":"app_mention","user":"U1","channel":"C1","text":"<@UBOT> hi","ts":"1.1"}`), "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("badly signed event: got %d", w.Code)
+	}
+
+	// Messages from other users or channels are ignored.
+	send(event(`{"type":"app_mention","user":"U2","channel":"C1","text":"<@UBOT> hi","ts":"1.6"}`), "s3cret")
+	send(event(`{"type":"app_mention","user":"U1","channel":"C2","text":"<@UBOT> hi","ts":"1.7"}`), "s3cret")
+
+	// A reply in a thread without a conversation is ignored.
+	send(event(`{"type":"message","user":"U1","channel":"C1","text":"unrelated","ts":"1.5","thread_ts":"1.4"}`), "s3cret")
+
+	// A mention starts a conversation; the thread gets a collapsed
+	// progress message and then the final message.
+	if w := send(event(`{"type":"app_mention","user":"U1","channel":"C1","text":"<@UBOT> hello","ts":"1.1"}`), "s3cret"); w.Code != http.StatusOK {
+		t.Fatalf("mention: got %d", w.Code)
+	}
+	waitFor(t, 10*time.Second, func() bool { return fake.Message(1) != "" })
+	if got := fake.Message(0); !strings.HasPrefix(got, "Made 0 tool calls in <https://") {
+		t.Errorf("progress message: %q", got)
+	}
+	if got := fake.Message(1); !strings.Contains(got, "edit predictable.go") {
+		t.Errorf("final message: %q", got)
+	}
+	conv, err := s.db.GetConversationBySlug(context.Background(), "slack-c1-1-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A reply in the thread continues it.
+	send(event(`{"type":"message","user":"U1","channel":"C1","text":"thanks","ts":"1.2","thread_ts":"1.1"}`), "s3cret")
+	waitFor(t, 10*time.Second, func() bool { return fake.Message(3) != "" })
+	msgs, err := s.db.ListMessages(context.Background(), conv.ConversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users int
+	for _, m := range msgs {
+		if m.Type == "user" {
+			users++
+		}
+	}
+	if users != 2 {
+		t.Errorf("got %d user messages in the conversation, want 2", users)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.posted) != 4 {
+		t.Errorf("posted %d messages, want 4: those from other users or channels weren't ignored", len(fake.posted))
+	}
+}
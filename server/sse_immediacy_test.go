@@ -328,7 +328,7 @@ func TestSSEUserMessageWithExistingConnection(t *testing.T) {
 	// Subscribe to updates
 	subCtx, subCancel := context.WithCancel(context.Background())
 	defer subCancel()
-	next := manager.subpub.Subscribe(subCtx, -1)
+	next := server.conversationPub.Subscribe(subCtx, manager.conversationID, -1).Next
 
 	// Channel to receive updates
 	updates := make(chan StreamResponse, 10)
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/llm"
+)
+
+// ConversationStatus is what a conversation is doing. Every change is
+// recorded in the conversation's status history and broadcast in
+// ConversationState.
+type ConversationStatus string
+
+const (
+	StatusIdle     ConversationStatus = "idle"
+	StatusQueued   ConversationStatus = "queued" // a message waits for a compaction to finish
+	StatusThinking ConversationStatus = "thinking"
+	// StatusAwaitingUser is the status after a turn whose final response
+	// asks the user something (see waitingOnUser).
+	StatusAwaitingUser ConversationStatus = "awaiting-user"
+	StatusError        ConversationStatus = "error"
+	StatusArchived     ConversationStatus = "archived"
+)
+
+// runningToolStatusPrefix starts the status of a conversation whose agent is
+// running tools; their names follow, comma-separated.
+const runningToolStatusPrefix = "running-tool:"
+
+// statusHistoryLimit caps the transitions GET /api/conversation/<id>/status
+// returns.
+const statusHistoryLimit = 100
+
+// busy reports whether st is the status of a turn in progress.
+func (st ConversationStatus) busy() bool {
+	return st == StatusThinking || strings.HasPrefix(string(st), runningToolStatusPrefix)
+}
+
+// messageStatus returns the status a conversation moves to when message is
+// recorded, or "" if the message doesn't change it.
+func messageStatus(message llm.Message) ConversationStatus {
+	var tools, texts []string
+	hasResult := false
+	for _, c := range message.Content {
+		switch c.Type {
+		case llm.ContentTypeToolUse:
+			tools = append(tools, c.ToolName)
+		case llm.ContentTypeToolResult:
+			hasResult = true
+		case llm.ContentTypeText:
+			texts = append(texts, c.Text)
+		}
+	}
+	switch {
+	case message.ErrorType != llm.ErrorTypeNone:
+		if message.EndOfTurn {
+			return StatusError
+		}
+	case message.Role == llm.MessageRoleAssistant && message.EndOfTurn:
+		if waitingOnUser(strings.Join(texts, "\n")) {
+			return StatusAwaitingUser
+		}
+		return StatusIdle
+	case message.Role == llm.MessageRoleAssistant && len(tools) > 0:
+		return ConversationStatus(runningToolStatusPrefix + strings.Join(tools, ","))
+	case message.Role == llm.MessageRoleUser && hasResult:
+		return StatusThinking
+	}
+	return ""
+}
+
+// conversationStatus returns a conversation's current status: its active
+// manager's, else the newest recorded one. A recorded busy status outlived
+// the turn it belonged to (the server restarted mid-turn), so it reads as
+// idle.
+func (s *Server) conversationStatus(ctx context.Context, conversationID string, archived bool) (ConversationStatus, error) {
+	s.mu.Lock()
+	manager := s.activeConversations[conversationID]
+	s.mu.Unlock()
+	if manager != nil {
+		if status := manager.Status(); status != "" {
+			return status, nil
+		}
+	}
+	recorded, err := s.db.ConversationStatus(ctx, conversationID)
+	if err != nil {
+		return "", err
+	}
+	status := ConversationStatus(recorded)
+	switch {
+	case status == "" && archived:
+		return StatusArchived, nil
+	case status == "" || status.busy():
+		return StatusIdle, nil
+	}
+	return status, nil
+}
+
+// setConversationStatus records a conversation's new status, through its
+// active manager if it has one so that subscribers hear of it.
+func (s *Server) setConversationStatus(ctx context.Context, conversationID string, status ConversationStatus) {
+	s.mu.Lock()
+	manager := s.activeConversations[conversationID]
+	s.mu.Unlock()
+	if manager != nil {
+		manager.SetStatus(status)
+		return
+	}
+	if err := s.db.RecordConversationStatus(ctx, conversationID, string(status)); err != nil {
+		s.logger.Error("Failed to record conversation status", "conversationID", conversationID, "status", status, "error", err)
+	}
+}
+
+// StatusTransition is one entry of a conversation's status history.
+type StatusTransition struct {
+	Status    ConversationStatus `json:"status"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// handleConversationStatus handles GET /api/conversation/<id>/status,
+// returning the conversation's current status and its newest transitions,
+// newest first.
+func (s *Server) handleConversationStatus(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	status, err := s.conversationStatus(ctx, conversationID, conv.Archived)
+	if err != nil {
+		s.logger.Error("Failed to get conversation status", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	rows, err := s.db.ConversationStatusHistory(ctx, conversationID, statusHistoryLimit)
+	if err != nil {
+		s.logger.Error("Failed to get conversation status history", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	history := make([]StatusTransition, len(rows))
+	for i, row := range rows {
+		history[i] = StatusTransition{Status: ConversationStatus(row.Status), CreatedAt: row.CreatedAt}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  status,
+		"history": history,
+	})
+}
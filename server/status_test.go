@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/llm"
+)
+
+func TestConversationStatusTransitions(t *testing.T) {
+	s, database, _ := newTestServer(t)
+	ctx := context.Background()
+	conv, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := conv.ConversationID
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+
+	getStatus := func() (status ConversationStatus, history []ConversationStatus) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/conversation/"+id+"/status", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: got %d: %s", w.Code, w.Body)
+		}
+		var resp struct {
+			Status  ConversationStatus `json:"status"`
+			History []StatusTransition `json:"history"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range resp.History {
+			history = append(history, h.Status)
+		}
+		return resp.Status, history
+	}
+	chat := func(message string) {
+		body, _ := json.Marshal(ChatRequest{Message: message, Model: "predictable"})
+		w := httptest.NewRecorder()
+		s.handleChatConversation(w, httptest.NewRequest(http.MethodPost, "/api/conversation/"+id+"/chat", strings.NewReader(string(body))), id)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("chat: got %d: %s", w.Code, w.Body)
+		}
+		waitFor(t, 5*time.Second, func() bool { return !s.IsAgentWorking(id) })
+	}
+
+	if status, history := getStatus(); status != StatusIdle || len(history) != 0 {
+		t.Fatalf("new conversation: got %q %v", status, history)
+	}
+
+	chat("echo: hello")
+	if status, history := getStatus(); status != StatusIdle || !slices.Equal(history, []ConversationStatus{StatusIdle, StatusThinking}) {
+		t.Errorf("after a reply: got %q %v", status, history)
+	}
+
+	chat("echo: which file?")
+	if status, _ := getStatus(); status != StatusAwaitingUser {
+		t.Errorf("after a question: got %q, want %q", status, StatusAwaitingUser)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/conversation/"+id+"/archive", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("archive: got %d: %s", w.Code, w.Body)
+	}
+	if status, history := getStatus(); status != StatusArchived || history[0] != StatusArchived {
+		t.Errorf("after archiving: got %q %v", status, history)
+	}
+}
+
+func TestMessageStatus(t *testing.T) {
+	text := func(s string) llm.Content { return llm.Content{Type: llm.ContentTypeText, Text: s} }
+	for _, c := range []struct {
+		name string
+		msg  llm.Message
+		want ConversationStatus
+	}{
+		{"tool calls", llm.Message{Role: llm.MessageRoleAssistant, Content: []llm.Content{
+			{Type: llm.ContentTypeToolUse, ToolName: "bash"},
+			{Type: llm.ContentTypeToolUse, ToolName: "patch"},
+		}}, "running-tool:bash,patch"},
+		{"tool results", llm.Message{Role: llm.MessageRoleUser, Content: []llm.Content{{Type: llm.ContentTypeToolResult}}}, StatusThinking},
+		{"final answer", llm.Message{Role: llm.MessageRoleAssistant, EndOfTurn: true, Content: []llm.Content{text("Done.")}}, StatusIdle},
+		{"question", llm.Message{Role: llm.MessageRoleAssistant, EndOfTurn: true, Content: []llm.Content{text("Which one?")}}, StatusAwaitingUser},
+		{"error", llm.Message{Role: llm.MessageRoleAssistant, EndOfTurn: true, ErrorType: llm.ErrorTypeLLMRequest}, StatusError},
+		{"user prompt", llm.Message{Role: llm.MessageRoleUser, Content: []llm.Content{text("hi")}}, ""},
+	} {
+		if got := messageStatus(c.msg); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
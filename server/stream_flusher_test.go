@@ -29,7 +29,7 @@ func TestStreamFlusherAssignsMonotonicSeq(t *testing.T) {
 
 	subCtx, subCancel := context.WithCancel(context.Background())
 	defer subCancel()
-	next := manager.subpub.Subscribe(subCtx, -1)
+	next := server.conversationPub.Subscribe(subCtx, manager.conversationID, -1).Next
 
 	seqs := make(chan int64, 16)
 	go func() {
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"shelley.exe.dev/subpub"
+)
+
+// TestForwardStreamResyncsAfterDrops verifies that a client that falls behind
+// the stream keeps its connection and is told to re-fetch what it missed.
+func TestForwardStreamResyncsAfterDrops(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pub := subpub.New[StreamResponse](streamOptions()...)
+	sub := pub.Join(ctx, -1)
+	for i := range streamBufferSize + 2 {
+		pub.Broadcast(StreamResponse{ConversationID: "c", MaxSequenceID: int64(i)})
+	}
+
+	updates := make(chan StreamResponse, streamBufferSize+1)
+	go forwardStream(ctx, sub, updates)
+
+	if first := <-updates; !first.Resync {
+		t.Fatalf("first update = %+v, want a resync", first)
+	}
+	// The two oldest events were dropped.
+	for want := int64(2); want < streamBufferSize+2; want++ {
+		if got := <-updates; got.Resync || got.MaxSequenceID != want {
+			t.Fatalf("update = %+v, want event %d", got, want)
+		}
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"shelley.exe.dev/claudetool"
 	"shelley.exe.dev/db"
@@ -29,6 +31,11 @@ func NewSubagentRunner(s *Server) *SubagentRunner {
 // RunSubagent implements claudetool.SubagentRunner.
 func (r *SubagentRunner) RunSubagent(ctx context.Context, conversationID, prompt string, wait bool, timeout time.Duration, modelID, reasoning string) (string, error) {
 	s := r.server
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("shelley.subagent.conversation_id", conversationID))
+
+	if err := s.checkSubagentBudget(ctx, conversationID); err != nil {
+		return "", err
+	}
 
 	// Notify the UI about the subagent conversation.
 	// This ensures the sidebar shows the subagent even if it's a newly created conversation.
@@ -57,6 +64,9 @@ func (r *SubagentRunner) RunSubagent(ctx context.Context, conversationID, prompt
 			if hookErr != nil {
 				return "", fmt.Errorf("new-conversation hook: %w", hookErr)
 			}
+			if err := s.saveHookOptions(ctx, conversationID, db.ParseConversationOptions(conv.ConversationOptions), hookResult); err != nil {
+				return "", fmt.Errorf("failed to save new-conversation hook options: %w", err)
+			}
 			if hookResult.Cwd != derefString(conv.Cwd) {
 				if err := s.db.UpdateConversationCwd(ctx, conversationID, hookResult.Cwd); err != nil {
 					s.logger.Error("Failed to update subagent cwd from hook", "error", err)
@@ -454,6 +464,64 @@ func (r *SubagentRunner) buildConversationSummary(messages []generated.Message)
 	return result
 }
 
+// checkSubagentBudget returns an error once the subagents under
+// conversationID's top-level conversation have used up the configured
+// budget, alerting that conversation's notification channels the first time.
+func (s *Server) checkSubagentBudget(ctx context.Context, conversationID string) error {
+	ancestry, err := s.db.GetConversationAncestry(ctx, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversation ancestry: %w", err)
+	}
+	budget, err := s.subagentBudget(ctx, ancestry.RootConversationID)
+	if err != nil {
+		return err
+	}
+	if budget == (claudetool.SubagentBudget{}) {
+		return nil
+	}
+	rows, err := s.db.GetSubagentUsage(ctx, ancestry.RootConversationID)
+	if err != nil {
+		return fmt.Errorf("failed to get subagent usage: %w", err)
+	}
+	var tokens int64
+	var cost float64
+	for _, row := range rows {
+		tokens += row.InputTokens + row.CacheCreationInputTokens + row.CacheReadInputTokens + row.OutputTokens
+		if usd, found := estimateSubagentCost(row); found {
+			cost += usd
+		} else {
+			cost += row.CostUsd
+		}
+	}
+	var exhausted error
+	if budget.MaxTokens > 0 && tokens >= budget.MaxTokens {
+		exhausted = fmt.Errorf("subagent budget exhausted: subagents have used %d of %d tokens", tokens, budget.MaxTokens)
+	} else if budget.MaxCostUSD > 0 && cost >= budget.MaxCostUSD {
+		exhausted = fmt.Errorf("subagent budget exhausted: subagents have cost $%.2f of $%.2f", cost, budget.MaxCostUSD)
+	}
+	if exhausted != nil {
+		s.alertBudgetExceeded(ancestry.RootConversationID, exhausted)
+	}
+	return exhausted
+}
+
+// subagentBudget returns the subagent budget of the project the top-level
+// conversation rootID works in, or else the server's.
+func (s *Server) subagentBudget(ctx context.Context, rootID string) (claudetool.SubagentBudget, error) {
+	root, err := s.db.GetConversationByID(ctx, rootID)
+	if err != nil {
+		return claudetool.SubagentBudget{}, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	project, err := loadProjectConfig(s.hooksDir, derefString(root.Cwd))
+	if err != nil {
+		return claudetool.SubagentBudget{}, err
+	}
+	if project.SubagentBudget != nil {
+		return *project.SubagentBudget, nil
+	}
+	return s.currentToolSetConfig().SubagentBudget, nil
+}
+
 // notifySubagentConversation fetches the subagent conversation and publishes it
 // to all SSE streams so the UI can update the sidebar.
 func (r *SubagentRunner) notifySubagentConversation(ctx context.Context, conversationID string) {
@@ -610,7 +678,7 @@ func (s *Server) notifyParentSubagentDone(subagentConversationID string) {
 
 	modelID := parentModelID
 	if modelID == "" {
-		modelID = s.defaultModel
+		modelID = s.currentDefaultModel()
 	}
 
 	// Enqueue onto the parent's pending-batch queue. drainPendingMessages
@@ -727,3 +795,79 @@ func (s *Server) handleGetSubagents(w http.ResponseWriter, r *http.Request, conv
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(subagents)
 }
+
+// SubagentStatus is the live state of a subagent conversation.
+type SubagentStatus struct {
+	ConversationID       string    `json:"conversation_id"`
+	Slug                 string    `json:"slug"`
+	ParentConversationID string    `json:"parent_conversation_id"`
+	Working              bool      `json:"working"`
+	LLMCalls             int64     `json:"llm_calls"`
+	Tokens               int64     `json:"tokens"`
+	EstimatedUsd         float64   `json:"estimated_usd"`
+	LastActivity         time.Time `json:"last_activity"`
+}
+
+// handleGetSubagentStatus handles GET /api/conversation/<id>/subagent-status.
+// Usage includes the subagent's own subagents.
+func (s *Server) handleGetSubagentStatus(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if conv.ParentConversationID == nil {
+		http.Error(w, "Not a subagent conversation", http.StatusBadRequest)
+		return
+	}
+	rows, err := s.db.GetConversationTreeUsage(ctx, conversationID)
+	if err != nil {
+		s.logger.Error("Failed to get subagent usage", "conversationID", conversationID, "error", err)
+		http.Error(w, "Failed to get subagent usage", http.StatusInternalServerError)
+		return
+	}
+	working, _ := NewSubagentRunner(s).isAgentWorking(ctx, conversationID)
+	status := SubagentStatus{
+		ConversationID:       conversationID,
+		Slug:                 derefString(conv.Slug),
+		ParentConversationID: *conv.ParentConversationID,
+		Working:              working,
+		LastActivity:         conv.UpdatedAt,
+	}
+	for _, row := range rows {
+		status.LLMCalls += row.LlmCalls
+		status.Tokens += row.InputTokens + row.CacheCreationInputTokens + row.CacheReadInputTokens + row.OutputTokens
+		if usd, found := estimateSubagentCost(generated.GetSubagentUsageRow(row)); found {
+			status.EstimatedUsd += usd
+		} else {
+			status.EstimatedUsd += row.CostUsd
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleReattachSubagent handles POST /api/conversation/<id>/reattach. It
+// delivers an idle subagent's latest response to its parent as a late
+// subagent tool result, e.g. after the parent's call timed out or the
+// subagent was cancelled on its own.
+func (s *Server) handleReattachSubagent(w http.ResponseWriter, r *http.Request, conversationID string) {
+	ctx := r.Context()
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if conv.ParentConversationID == nil {
+		http.Error(w, "Not a subagent conversation", http.StatusBadRequest)
+		return
+	}
+	if working, _ := NewSubagentRunner(s).isAgentWorking(ctx, conversationID); working {
+		http.Error(w, "Subagent is still working", http.StatusConflict)
+		return
+	}
+	s.notifyParentSubagentDone(conversationID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "delivered"})
+}
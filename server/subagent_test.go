@@ -2,9 +2,13 @@ package server
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/db"
 	"shelley.exe.dev/db/generated"
 	"shelley.exe.dev/llm"
 )
@@ -239,3 +243,141 @@ func TestBuildConversationSummary_SkipsSystemMessages(t *testing.T) {
 		t.Error("Summary should include user messages")
 	}
 }
+
+func TestNestedSubagentDepth(t *testing.T) {
+	t.Parallel()
+	srv, database, _ := newTestServer(t)
+	srv.toolSetConfig.MaxSubagentDepth = 2
+	ctx := t.Context()
+
+	root, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := database.CreateSubagentConversation(ctx, "child", root.ConversationID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grandchild, err := database.CreateSubagentConversation(ctx, "grandchild", child.ConversationID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hasSubagentTool := func(conversationID string) bool {
+		t.Helper()
+		cm, err := srv.getOrCreateSubagentConversationManager(ctx, conversationID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, _, err := cm.systemPromptToolSetConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts := claudetool.NewToolSet(ctx, cfg)
+		defer ts.Cleanup()
+		for _, tool := range ts.Tools() {
+			if tool.Name == "subagent" {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasSubagentTool(child.ConversationID) {
+		t.Error("subagent at depth 1 should be able to spawn subagents with max depth 2")
+	}
+	if hasSubagentTool(grandchild.ConversationID) {
+		t.Error("subagent at depth 2 should not be able to spawn subagents with max depth 2")
+	}
+}
+
+func TestSubagentBudget(t *testing.T) {
+	t.Parallel()
+	srv, database, _ := newTestServer(t)
+	srv.toolSetConfig.SubagentBudget = claudetool.SubagentBudget{MaxTokens: 1000}
+	ctx := t.Context()
+
+	root, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := database.CreateSubagentConversation(ctx, "child", root.ConversationID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grandchild, err := database.CreateSubagentConversation(ctx, "grandchild", child.ConversationID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.checkSubagentBudget(ctx, grandchild.ConversationID); err != nil {
+		t.Fatalf("unused budget: %v", err)
+	}
+
+	// Usage anywhere under the root counts against every subagent in the tree.
+	_, err = database.CreateMessage(ctx, db.CreateMessageParams{
+		ConversationID: child.ConversationID,
+		Type:           db.MessageTypeAgent,
+		UsageData:      map[string]any{"input_tokens": 800, "output_tokens": 400},
+		ModelName:      "mystery-model",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = srv.checkSubagentBudget(ctx, grandchild.ConversationID)
+	if err == nil || !strings.Contains(err.Error(), "1200 of 1000 tokens") {
+		t.Fatalf("expected budget error, got %v", err)
+	}
+}
+
+func TestSubagentStatusHandler(t *testing.T) {
+	t.Parallel()
+	srv, database, _ := newTestServer(t)
+	ctx := t.Context()
+
+	root, err := database.CreateConversation(ctx, nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := database.CreateSubagentConversation(ctx, "child", root.ConversationID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grandchild, err := database.CreateSubagentConversation(ctx, "grandchild", child.ConversationID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{child.ConversationID, grandchild.ConversationID} {
+		_, err := database.CreateMessage(ctx, db.CreateMessageParams{
+			ConversationID: id,
+			Type:           db.MessageTypeAgent,
+			UsageData:      map[string]any{"input_tokens": 100, "output_tokens": 50, "cost_usd": 0.5},
+			ModelName:      "mystery-model",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	srv.handleGetSubagentStatus(w, httptest.NewRequest("GET", "/", nil), child.ConversationID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var status SubagentStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Slug != "child" || status.ParentConversationID != root.ConversationID || status.Working {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if status.LLMCalls != 2 || status.Tokens != 300 || status.EstimatedUsd != 1 {
+		t.Errorf("usage should include nested subagents: %+v", status)
+	}
+
+	for _, handler := range []func(http.ResponseWriter, *http.Request, string){srv.handleGetSubagentStatus, srv.handleReattachSubagent} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/", nil), root.ConversationID)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("top-level conversation: status %d, want 400", w.Code)
+		}
+	}
+}
@@ -7,18 +7,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"text/template"
 	"time"
 
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/claudetool/onstart"
+	"shelley.exe.dev/db"
 	"shelley.exe.dev/exeenv"
+	"shelley.exe.dev/llm"
 	"shelley.exe.dev/skills"
 )
 
@@ -35,10 +42,24 @@ type SystemPromptData struct {
 	Codebase         *CodebaseInfo
 	IsExeDev         bool
 	IsSudoAvailable  bool
-	Hostname         string // For exe.dev, the public hostname (e.g., "vmname.exe.xyz")
-	DefaultPort      int    // For exe.dev, the auto-routed HTTP port, 0 if unknown
-	SkillsXML        string // XML block for available skills
-	UserEmail        string // The exe.dev auth email of the user, if known
+	Hostname         string          // For exe.dev, the public hostname (e.g., "vmname.exe.xyz")
+	DefaultPort      int             // For exe.dev, the auto-routed HTTP port, 0 if unknown
+	SkillsXML        string          // XML block for available skills
+	Memory           string          // The git repository's project memory file, if any
+	UserEmail        string          // The exe.dev auth email of the user, if known
+	Tools            map[string]bool // Names of the conversation's tools; nil if unknown
+
+	verifyCommands bool                  // check Codebase.Commands before listing them
+	analysisCache  onstart.AnalysisCache // reuses codebase analyses; nil to analyze afresh
+	analysisLimits onstart.Limits        // bounds the analysis of large codebases
+	guidanceBudget GuidanceBudget        // bounds the injected guidance files
+	guidanceNames  GuidanceNames         // which files are guidance files
+}
+
+// HasTool reports whether the conversation has the named tool. Without a
+// known tool list, every tool is assumed present.
+func (d *SystemPromptData) HasTool(name string) bool {
+	return d.Tools == nil || d.Tools[name]
 }
 
 // DBPath is the path to the shelley database, set at startup
@@ -52,6 +73,21 @@ type CodebaseInfo struct {
 	InjectFiles         []string
 	InjectFileContents  map[string]string
 	SubdirGuidanceFiles []string
+	// Toolchain describes the repository's projects, tool versions, and
+	// workspaces, as found in their manifests.
+	Toolchain string
+	// Commands are the build, test, and lint commands inferred from the
+	// repository's build files, to be run from Root.
+	Commands []onstart.Command
+	// CommandsVerified reports whether Commands were checked to run.
+	CommandsVerified bool
+	// Root is the directory analyzed for Toolchain and Commands.
+	Root string
+}
+
+// CommandsSummary lists the inferred commands, one per line.
+func (c *CodebaseInfo) CommandsSummary() string {
+	return onstart.FormatCommands(c.Commands)
 }
 
 // SubdirGuidanceSummary returns a prompt-friendly summary of subdirectory guidance files.
@@ -86,21 +122,65 @@ func WithUserEmail(email string) SystemPromptOption {
 	}
 }
 
-// GenerateSystemPrompt generates the system prompt using the embedded template.
-// If workingDir is empty, it uses the current working directory.
-func GenerateSystemPrompt(workingDir string, opts ...SystemPromptOption) (string, error) {
-	data, err := collectSystemData(workingDir)
-	if err != nil {
-		return "", fmt.Errorf("failed to collect system data: %w", err)
+// WithTools limits tool-specific guidance in the system prompt to tools.
+func WithTools(tools []*llm.Tool) SystemPromptOption {
+	return func(d *SystemPromptData) {
+		d.Tools = make(map[string]bool, len(tools))
+		for _, t := range tools {
+			d.Tools[t.Name] = true
+		}
 	}
+}
 
+// WithVerifiedCommands lists only the inferred build, test, and lint commands
+// that cheap probes, such as make -n, show can run.
+func WithVerifiedCommands() SystemPromptOption {
+	return func(d *SystemPromptData) {
+		d.verifyCommands = true
+	}
+}
+
+// WithAnalysisCache reuses codebase analyses stored in cache while the
+// repository is unchanged, and stores new ones there.
+func WithAnalysisCache(cache onstart.AnalysisCache) SystemPromptOption {
+	return func(d *SystemPromptData) {
+		d.analysisCache = cache
+	}
+}
+
+// WithAnalysisLimits bounds the codebase analysis of large repositories.
+func WithAnalysisLimits(limits onstart.Limits) SystemPromptOption {
+	return func(d *SystemPromptData) {
+		d.analysisLimits = limits
+	}
+}
+
+// GenerateSystemPrompt generates the system prompt using the embedded template,
+// or the SystemPromptFile overriding it. If workingDir is empty, it uses the
+// current working directory.
+func GenerateSystemPrompt(workingDir string, opts ...SystemPromptOption) (string, error) {
+	data := &SystemPromptData{}
 	for _, opt := range opts {
 		opt(data)
 	}
+	if err := collectSystemData(workingDir, data); err != nil {
+		return "", fmt.Errorf("failed to collect system data: %w", err)
+	}
+	if c := data.Codebase; data.verifyCommands && c != nil && len(c.Commands) > 0 {
+		c.Commands = onstart.VerifyCommands(context.Background(), c.Root, c.Commands)
+		c.CommandsVerified = true
+	}
+	if data.Codebase != nil {
+		data.guidanceBudget.apply(data.Codebase)
+	}
 
-	tmpl, err := template.New("system_prompt").Parse(systemPromptTemplate)
+	projectDir := data.WorkingDirectory
+	if data.GitInfo != nil {
+		projectDir = data.GitInfo.Root
+	}
+	tmpl, err := parseSystemPrompt(projectDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+		return "", err
 	}
 
 	var buf strings.Builder
@@ -128,6 +208,7 @@ const (
 	hookNewConversation = "new-conversation"
 	hookEndOfTurn       = "end-of-turn"
 	hookChatMessage     = "chat-message"
+	hookTurnComplete    = "turn-complete"
 )
 
 // HookHeaders converts an http.Header to a sorted list of [name, value]
@@ -216,6 +297,64 @@ type NewConversationHookResult struct {
 	Model  string
 	Cwd    string
 	Slug   string
+	// Env is set for the commands the conversation's bash and shell tools
+	// run.
+	Env map[string]string
+	// Context is added to the conversation's system prompt.
+	Context string
+}
+
+// RepoHooksDir is where a repository keeps its own hooks, relative to its
+// root. They run after the user's hooks of the same name, if the
+// repository is listed in TrustedReposFile.
+const RepoHooksDir = ".shelley/hooks"
+
+// TrustedReposFile, in the user hooks directory, lists the root
+// directories of the repositories whose RepoHooksDir hooks may run, one per
+// line. It lives outside any repository so that checking one out (a fork's
+// pull request branch, say) can't make the server run its code.
+const TrustedReposFile = "trusted-repos"
+
+// repoHooksDir returns the RepoHooksDir of the repository containing cwd if
+// it's listed in userDir's TrustedReposFile, or "" otherwise.
+func repoHooksDir(userDir, cwd string) string {
+	for d := cwd; d != ""; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			if repo := filepath.Join(d, RepoHooksDir); repo != userDir && repoTrusted(userDir, d) {
+				return repo
+			}
+			return ""
+		}
+		if d == filepath.Dir(d) {
+			break
+		}
+	}
+	return ""
+}
+
+// repoTrusted reports whether root is listed in userDir's TrustedReposFile.
+func repoTrusted(userDir, root string) bool {
+	data, err := os.ReadFile(filepath.Join(userDir, TrustedReposFile))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && filepath.Clean(line) == filepath.Clean(root) {
+			return true
+		}
+	}
+	return false
+}
+
+// HookAbortError reports that a hook refused the operation it belongs to.
+// Message is meant for the user.
+type HookAbortError struct {
+	Hook    string
+	Message string
+}
+
+func (e *HookAbortError) Error() string {
+	return fmt.Sprintf("%s hook aborted: %s", e.Hook, e.Message)
 }
 
 // RunNewConversationHook runs the new-conversation hook from the
@@ -229,61 +368,94 @@ func RunNewConversationHook(input NewConversationHookInput) (NewConversationHook
 }
 
 // RunNewConversationHookIn is the dir-explicit variant of
-// RunNewConversationHook. A non-nil error means the hook failed
-// (non-zero exit, invalid JSON, etc.) and the caller should abort
-// the operation. If no hook is installed, the input values are
-// returned with a nil error.
+// RunNewConversationHook. It runs the hook in hooksDir, then the one in
+// the RepoHooksDir of the repository containing the (possibly changed)
+// working directory, if it's trusted (see TrustedReposFile), which sees the
+// first hook's changes. A non-nil error
+// means a hook failed (non-zero exit, invalid JSON, etc.) or aborted, as a
+// *HookAbortError, and the caller should abort the operation. If no hook is
+// installed, the input values are returned with a nil error.
 func RunNewConversationHookIn(hooksDir string, input NewConversationHookInput) (NewConversationHookResult, error) {
-	original := NewConversationHookResult{
+	result := NewConversationHookResult{
 		Prompt: input.Prompt,
 		Model:  input.Model,
 		Cwd:    input.Cwd,
 	}
+	if err := runNewConversationHook(hooksDir, input, &result); err != nil {
+		return result, err
+	}
+	if result.Cwd == "" {
+		return result, nil
+	}
+	repo := repoHooksDir(hooksDir, result.Cwd)
+	if repo == "" {
+		return result, nil
+	}
+	input.Prompt, input.Model, input.Cwd = result.Prompt, result.Model, result.Cwd
+	err := runNewConversationHook(repo, input, &result)
+	return result, err
+}
 
-	hookPath, err := findHookIn(hooksDir, hookNewConversation)
+// runNewConversationHook runs the new-conversation hook in dir, if any, and
+// applies its output to result.
+func runNewConversationHook(dir string, input NewConversationHookInput, result *NewConversationHookResult) error {
+	hookPath, err := findHookIn(dir, hookNewConversation)
 	if err != nil {
-		return original, fmt.Errorf("new-conversation hook: %w", err)
+		return fmt.Errorf("new-conversation hook: %w", err)
 	}
 	if hookPath == "" {
-		return original, nil
+		return nil
 	}
 
 	inputJSON, err := json.Marshal(input)
 	if err != nil {
-		return original, fmt.Errorf("new-conversation hook: marshal input: %w", err)
+		return fmt.Errorf("new-conversation hook: marshal input: %w", err)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, hookPath)
+	// The conversation ID, working directory, and model are also passed as
+	// arguments and SHELLEY_* variables, for hooks that don't parse JSON.
+	cmd := exec.CommandContext(ctx, hookPath, input.Readonly.ConversationID, input.Cwd, input.Model)
+	cmd.Env = append(os.Environ(),
+		"SHELLEY_CONVERSATION_ID="+input.Readonly.ConversationID,
+		"SHELLEY_CWD="+input.Cwd,
+		"SHELLEY_MODEL="+input.Model,
+	)
 	cmd.Stdin = strings.NewReader(string(inputJSON))
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return original, fmt.Errorf("new-conversation hook %s failed: %w (stderr: %s)", hookPath, err, stderr.String())
+		return fmt.Errorf("new-conversation hook %s failed: %w (stderr: %s)", hookPath, err, stderr.String())
 	}
 
 	output := strings.TrimSpace(stdout.String())
 	if output == "" {
 		// Empty output is fine — hook ran but has no overrides.
-		return original, nil
+		return nil
 	}
 
 	// Parse only the mutable fields from the output.
 	var hookOut struct {
-		Prompt string `json:"prompt"`
-		Model  string `json:"model"`
-		Cwd    string `json:"cwd"`
-		Slug   string `json:"slug"`
+		Prompt  string            `json:"prompt"`
+		Model   string            `json:"model"`
+		Cwd     string            `json:"cwd"`
+		Slug    string            `json:"slug"`
+		Env     map[string]string `json:"env"`
+		Context string            `json:"context"`
+		Abort   string            `json:"abort"`
 	}
 	if err := json.Unmarshal([]byte(output), &hookOut); err != nil {
-		return original, fmt.Errorf("new-conversation hook %s: invalid JSON output %q: %w", hookPath, output, err)
+		return fmt.Errorf("new-conversation hook %s: invalid JSON output %q: %w", hookPath, output, err)
+	}
+	if hookOut.Abort != "" {
+		return &HookAbortError{Hook: hookNewConversation, Message: hookOut.Abort}
 	}
 
-	result := original
+	original := *result
 	if hookOut.Cwd != "" {
 		result.Cwd = hookOut.Cwd
 	}
@@ -296,18 +468,48 @@ func RunNewConversationHookIn(hooksDir string, input NewConversationHookInput) (
 	if hookOut.Slug != "" {
 		result.Slug = hookOut.Slug
 	}
+	if len(hookOut.Env) > 0 {
+		result.Env = maps.Clone(result.Env)
+		if result.Env == nil {
+			result.Env = make(map[string]string, len(hookOut.Env))
+		}
+		maps.Copy(result.Env, hookOut.Env)
+	}
+	if hookOut.Context != "" {
+		result.Context = strings.TrimSpace(result.Context + "\n\n" + hookOut.Context)
+	}
 
-	if result != original {
-		slog.Info(
-			"new-conversation hook applied overrides",
-			"cwdChanged", result.Cwd != original.Cwd,
-			"promptChanged", result.Prompt != original.Prompt,
-			"modelChanged", result.Model != original.Model,
-			"slugChanged", result.Slug != original.Slug,
-		)
+	slog.Info(
+		"new-conversation hook applied overrides",
+		"hook", hookPath,
+		"cwdChanged", result.Cwd != original.Cwd,
+		"promptChanged", result.Prompt != original.Prompt,
+		"modelChanged", result.Model != original.Model,
+		"slugChanged", result.Slug != original.Slug,
+		"env", len(hookOut.Env),
+		"contextAdded", hookOut.Context != "",
+	)
+	return nil
+}
+
+// saveHookOptions stores the env and context the new-conversation hook set
+// in the conversation's options, opts.
+func (s *Server) saveHookOptions(ctx context.Context, conversationID string, opts db.ConversationOptions, result NewConversationHookResult) error {
+	if len(result.Env) == 0 && result.Context == "" {
+		return nil
 	}
+	opts.Env = result.Env
+	opts.HookContext = result.Context
+	return s.db.UpdateConversationOptions(ctx, conversationID, opts)
+}
 
-	return result, nil
+// formatHookContext formats the context the new-conversation hook added
+// for the system prompt.
+func formatHookContext(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "\n\n<new_conversation_hook_context>\n" + text + "\n</new_conversation_hook_context>\n"
 }
 
 // EndOfTurnHookInput is the JSON data passed to the end-of-turn hook on stdin.
@@ -632,19 +834,17 @@ func runHook(name, prompt string) (string, error) {
 	return result, nil
 }
 
-func collectSystemData(workingDir string) (*SystemPromptData, error) {
+// collectSystemData fills in data, which options have already been applied to.
+func collectSystemData(workingDir string, data *SystemPromptData) error {
 	wd := workingDir
 	if wd == "" {
 		var err error
 		wd, err = os.Getwd()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get working directory: %w", err)
+			return fmt.Errorf("failed to get working directory: %w", err)
 		}
 	}
-
-	data := &SystemPromptData{
-		WorkingDirectory: wd,
-	}
+	data.WorkingDirectory = wd
 
 	// collectGitInfo shells out to `git rev-parse`; resolve it first so the
 	// codebase and skill walks below can scope to the git root.
@@ -655,6 +855,7 @@ func collectSystemData(workingDir string) (*SystemPromptData, error) {
 	var gitRoot string
 	if gitInfo != nil {
 		gitRoot = gitInfo.Root
+		data.Memory = claudetool.ReadProjectMemory(gitRoot)
 	}
 
 	// Check if running on exe.dev (cheap stat).
@@ -675,7 +876,7 @@ func collectSystemData(workingDir string) (*SystemPromptData, error) {
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		codebaseInfo, codebaseErr = collectCodebaseInfo(wd, gitInfo)
+		codebaseInfo, codebaseErr = collectCodebaseInfo(wd, gitInfo, data.guidanceNames, data.analysisCache, data.analysisLimits)
 	}()
 	go func() {
 		defer wg.Done()
@@ -701,7 +902,7 @@ func collectSystemData(workingDir string) (*SystemPromptData, error) {
 	}
 	data.SkillsXML = skillsXML
 
-	return data, nil
+	return nil
 }
 
 func collectGitInfo(dir string) (*GitInfo, error) {
@@ -721,7 +922,8 @@ func collectGitInfo(dir string) (*GitInfo, error) {
 	}, nil
 }
 
-func collectCodebaseInfo(wd string, gitInfo *GitInfo) (*CodebaseInfo, error) {
+func collectCodebaseInfo(wd string, gitInfo *GitInfo, names GuidanceNames, cache onstart.AnalysisCache, limits onstart.Limits) (*CodebaseInfo, error) {
+	patterns := names.patterns()
 	info := &CodebaseInfo{
 		InjectFiles:        []string{},
 		InjectFileContents: make(map[string]string),
@@ -764,7 +966,7 @@ func collectCodebaseInfo(wd string, gitInfo *GitInfo) (*CodebaseInfo, error) {
 	}
 
 	// Find root-level guidance files (case-insensitive)
-	rootGuidanceFiles := findGuidanceFilesInDir(searchRoot)
+	rootGuidanceFiles := findGuidanceFilesInDir(searchRoot, patterns)
 	for _, file := range rootGuidanceFiles {
 		canonical := resolveAndNormalize(file)
 		if seenFiles[canonical] {
@@ -785,9 +987,13 @@ func collectCodebaseInfo(wd string, gitInfo *GitInfo) (*CodebaseInfo, error) {
 	}
 
 	// If working directory is different from root, also check working directory
+	ignore := onstart.ReadShelleyIgnore(searchRoot)
 	if wd != searchRoot {
-		wdGuidanceFiles := findGuidanceFilesInDir(wd)
+		wdGuidanceFiles := findGuidanceFilesInDir(wd, patterns)
 		for _, file := range wdGuidanceFiles {
+			if rel, err := filepath.Rel(searchRoot, file); err == nil && ignore.Excludes(filepath.ToSlash(rel)) {
+				continue
+			}
 			canonical := resolveAndNormalize(file)
 			if seenFiles[canonical] {
 				continue
@@ -808,45 +1014,105 @@ func collectCodebaseInfo(wd string, gitInfo *GitInfo) (*CodebaseInfo, error) {
 	}
 
 	// Find subdirectory guidance files for the system prompt listing
-	info.SubdirGuidanceFiles = findSubdirGuidanceFiles(searchRoot)
-
-	return info, nil
-}
+	info.SubdirGuidanceFiles = findSubdirGuidanceFiles(searchRoot, patterns, ignore)
 
-func findGuidanceFilesInDir(dir string) []string {
-	// Read directory entries to handle case-insensitive file systems
-	entries, err := os.ReadDir(dir)
+	// Describe the projects in the repository so the agent need not guess
+	// how to build them.
+	cb, err := onstart.AnalyzeCodebaseCached(context.Background(), searchRoot, cache, limits)
 	if err != nil {
-		return nil
+		slog.Debug("codebase analysis failed", "root", searchRoot, "error", err)
+	} else {
+		info.Toolchain = cb.ToolchainSummary()
+		info.Commands = cb.Commands
+		info.Root = searchRoot
 	}
 
+	return info, nil
+}
+
+// findGuidanceFilesInDir returns the files in dir, or below it for patterns
+// with a slash, that match patterns, except READMEs, in the order of
+// patterns.
+func findGuidanceFilesInDir(dir string, patterns []string) []string {
 	var found []string
 	seen := make(map[string]bool)
+	entries := make(map[string][]os.DirEntry)
+	for _, pattern := range patterns {
+		sub := path.Dir(pattern)
+		if _, ok := entries[sub]; !ok {
+			// Read directory entries to handle case-insensitive file systems
+			entries[sub], _ = os.ReadDir(filepath.Join(dir, filepath.FromSlash(sub)))
+		}
+		for _, entry := range entries[sub] {
+			if entry.IsDir() {
+				continue
+			}
+			lowerPath := path.Join(sub, strings.ToLower(entry.Name()))
+			if ok, _ := path.Match(pattern, lowerPath); ok && lowerPath != "readme.md" && !seen[lowerPath] {
+				seen[lowerPath] = true
+				found = append(found, filepath.Join(dir, filepath.FromSlash(sub), entry.Name()))
+			}
+		}
+	}
+	return found
+}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
+// DefaultGuidanceNames are the names of the files treated as guidance files.
+// READMEs are listed for subdirectories but never injected.
+var DefaultGuidanceNames = []string{"agents.md", "agent.md", "claude.md", "dear_llm.md", "readme.md"}
+
+// GuidanceNames changes which files are guidance files, injected into the
+// system prompt from the repository root and working directory and listed
+// for subdirectories.
+type GuidanceNames struct {
+	// Add are more names, such as "CONTRIBUTING.md", or slash-separated
+	// paths, such as ".github/copilot-instructions.md". They are matched
+	// case-insensitively and may be path.Match patterns, such as
+	// ".cursor/rules/*.mdc". Paths are only injected, not listed for
+	// subdirectories.
+	Add []string
+	// Exclude removes names from DefaultGuidanceNames, such as "readme.md".
+	Exclude []string
+}
+
+// WithGuidanceNames changes which files are guidance files.
+func WithGuidanceNames(names GuidanceNames) SystemPromptOption {
+	return func(d *SystemPromptData) {
+		d.guidanceNames = names
+	}
+}
+
+// patterns returns the lowercased patterns that guidance file paths match.
+func (g GuidanceNames) patterns() []string {
+	var patterns []string
+	for _, name := range append(slices.Clone(DefaultGuidanceNames), g.Add...) {
+		name = path.Clean(strings.ToLower(filepath.ToSlash(name)))
+		if !filepath.IsLocal(name) {
 			continue
 		}
-		lowerName := strings.ToLower(entry.Name())
-		if isGuidanceFile(lowerName) && lowerName != "readme.md" && !seen[lowerName] {
-			seen[lowerName] = true
-			found = append(found, filepath.Join(dir, entry.Name()))
+		excluded := slices.ContainsFunc(g.Exclude, func(e string) bool { return strings.EqualFold(e, name) })
+		if !excluded && !slices.Contains(patterns, name) {
+			patterns = append(patterns, name)
 		}
 	}
-	return found
+	return patterns
 }
 
-// isGuidanceFile returns true if the lowercased filename is a recognized guidance file.
-func isGuidanceFile(lowerName string) bool {
-	switch lowerName {
-	case "agents.md", "agent.md", "claude.md", "dear_llm.md", "readme.md":
-		return true
+// matchesGuidanceName reports whether lowerName matches one of patterns
+// without a slash.
+func matchesGuidanceName(patterns []string, lowerName string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, lowerName); ok && !strings.Contains(pattern, "/") {
+			return true
+		}
 	}
 	return false
 }
 
-// findSubdirGuidanceFiles returns guidance files in subdirectories of root (not root itself).
-func findSubdirGuidanceFiles(root string) []string {
+// findSubdirGuidanceFiles returns the guidance files in subdirectories of
+// root (not root itself) whose names match patterns without a slash, leaving
+// out paths that ignore excludes.
+func findSubdirGuidanceFiles(root string, patterns []string, ignore onstart.IgnoreRules) []string {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
@@ -860,15 +1126,24 @@ func findSubdirGuidanceFiles(root string) []string {
 		if err != nil {
 			return nil // Continue on errors
 		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
 		if info.IsDir() {
 			// Skip hidden directories and common ignore patterns
-			if strings.HasPrefix(info.Name(), ".") || info.Name() == "node_modules" || info.Name() == "vendor" {
+			if strings.HasPrefix(info.Name(), ".") || info.Name() == "node_modules" || info.Name() == "vendor" ||
+				rel != "." && ignore.Match(rel, true) {
 				return filepath.SkipDir
 			}
 			return nil
 		}
+		if ignore.Match(rel, false) {
+			return nil
+		}
 		// Only count files in subdirectories, not root
-		if filepath.Dir(path) != root && isGuidanceFile(strings.ToLower(info.Name())) {
+		if filepath.Dir(path) != root && matchesGuidanceName(patterns, strings.ToLower(info.Name())) {
 			lowerPath := strings.ToLower(path)
 			if !seen[lowerPath] {
 				seen[lowerPath] = true
@@ -951,10 +1226,12 @@ type SubagentSystemPromptData struct {
 	ShelleyDBPath    string
 	ConversationID   string // Parent conversation ID for querying user messages
 	SkillsXML        string // XML block for available skills
+	Persona          string // Instructions from the subagent's persona
 }
 
-// GenerateSubagentSystemPrompt generates a minimal system prompt for subagent conversations.
-func GenerateSubagentSystemPrompt(workingDir, parentConversationID string) (string, error) {
+// GenerateSubagentSystemPrompt generates a minimal system prompt for subagent
+// conversations. persona holds the persona's instructions, if any.
+func GenerateSubagentSystemPrompt(workingDir, parentConversationID, persona string) (string, error) {
 	wd := workingDir
 	if wd == "" {
 		var err error
@@ -968,6 +1245,7 @@ func GenerateSubagentSystemPrompt(workingDir, parentConversationID string) (stri
 		WorkingDirectory: wd,
 		ShelleyDBPath:    DBPath,
 		ConversationID:   parentConversationID,
+		Persona:          persona,
 	}
 
 	// Try to collect git info
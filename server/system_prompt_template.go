@@ -0,0 +1,83 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// SystemPromptFile replaces the embedded system prompt template when it is
+// in ~/.config/shelley or, taking precedence, in a project's .shelley
+// directory. It is a text/template executed with SystemPromptData, and can
+// include the embedded template as {{template "default" .}}.
+const SystemPromptFile = "system_prompt.txt"
+
+// SystemPromptPartialsDir, next to where SystemPromptFile may be, holds
+// partial templates: each *.txt file in it defines a template named after
+// the file, for {{template "name" .}}. A project's partials replace the
+// user's of the same name.
+const SystemPromptPartialsDir = "system_prompt.d"
+
+// systemPromptDirs returns the directories that may hold SystemPromptFile
+// and SystemPromptPartialsDir, lowest precedence first: the user's
+// ~/.config/shelley and projectDir's .shelley.
+func systemPromptDirs(projectDir string) []string {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config", "shelley"))
+	}
+	if projectDir != "" {
+		dirs = append(dirs, filepath.Join(projectDir, ".shelley"))
+	}
+	return dirs
+}
+
+// SystemPromptFiles returns the override and partial template files that
+// the system prompt for projectDir, a git root or working directory, is
+// made from, in the order they are read. None means the embedded template.
+func SystemPromptFiles(projectDir string) []string {
+	var files []string
+	for _, dir := range systemPromptDirs(projectDir) {
+		partials, _ := filepath.Glob(filepath.Join(dir, SystemPromptPartialsDir, "*.txt"))
+		files = append(files, partials...)
+		if fi, err := os.Stat(filepath.Join(dir, SystemPromptFile)); err == nil && fi.Mode().IsRegular() {
+			files = append(files, filepath.Join(dir, SystemPromptFile))
+		}
+	}
+	return files
+}
+
+// parseSystemPrompt parses the system prompt template for projectDir from
+// SystemPromptFiles, falling back to the embedded template.
+func parseSystemPrompt(projectDir string) (*template.Template, error) {
+	tmpl := template.New("system_prompt")
+	if _, err := tmpl.New("default").Parse(systemPromptTemplate); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	main, mainFile := systemPromptTemplate, "embedded template"
+	for _, file := range SystemPromptFiles(projectDir) {
+		text, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read system prompt template: %w", err)
+		}
+		if filepath.Base(filepath.Dir(file)) != SystemPromptPartialsDir {
+			main, mainFile = string(text), file
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".txt")
+		if _, err := tmpl.New(name).Parse(string(text)); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", file, err)
+		}
+	}
+	if _, err := tmpl.Parse(main); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", mainFile, err)
+	}
+	return tmpl, nil
+}
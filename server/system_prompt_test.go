@@ -4,13 +4,17 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
+	"shelley.exe.dev/claudetool"
 	"shelley.exe.dev/exeenv"
+	"shelley.exe.dev/llm"
 )
 
 // TestSystemPromptIncludesCwdGuidanceFiles verifies that AGENTS.md from the working directory
@@ -123,6 +127,98 @@ func TestSystemPromptDetectsGitInWorkingDir(t *testing.T) {
 	}
 }
 
+func TestSystemPromptIncludesProjectMemory(t *testing.T) {
+	t.Parallel()
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	if err := os.MkdirAll(filepath.Join(repo, ".shelley"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, claudetool.ProjectMemoryFile), []byte("- Build with make, not go build\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prompt, err := GenerateSystemPrompt(repo)
+	if err != nil {
+		t.Fatalf("GenerateSystemPrompt failed: %v", err)
+	}
+	if !strings.Contains(prompt, "<project_memory>") || !strings.Contains(prompt, "- Build with make, not go build") {
+		t.Errorf("system prompt should include the project memory:\n%s", prompt)
+	}
+}
+
+func TestSystemPromptDescribesProjectManifests(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module example.com/app\n\ngo 1.24\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "go.mod"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	prompt, err := GenerateSystemPrompt(tmpDir)
+	if err != nil {
+		t.Fatalf("GenerateSystemPrompt failed: %v", err)
+	}
+	if want := `- go.mod: Go "example.com/app"; versions: go 1.24`; !strings.Contains(prompt, want) {
+		t.Errorf("system prompt should describe go.mod with %q", want)
+	}
+	if want := "- test: go test ./... (go.mod)"; !strings.Contains(prompt, want) {
+		t.Errorf("system prompt should list the inferred command %q", want)
+	}
+}
+
+func TestSystemPromptDescribesProjectsOutsideGit(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "Cargo.toml"), []byte("[package]\nname = \"app\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, err := GenerateSystemPrompt(tmpDir)
+	if err != nil {
+		t.Fatalf("GenerateSystemPrompt failed: %v", err)
+	}
+	if want := `- Cargo.toml: Rust "app"`; !strings.Contains(prompt, want) {
+		t.Errorf("system prompt should describe Cargo.toml with %q", want)
+	}
+}
+
+func TestSystemPromptHonorsShelleyIgnore(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"api/AGENTS.md":           "api notes",
+		"fixtures/repo/AGENTS.md": "fixture notes",
+		".shelleyignore":          "fixtures/\n",
+	} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prompt, err := GenerateSystemPrompt(tmpDir)
+	if err != nil {
+		t.Fatalf("GenerateSystemPrompt failed: %v", err)
+	}
+	if !strings.Contains(prompt, filepath.Join(tmpDir, "api", "AGENTS.md")) {
+		t.Errorf("system prompt should list api/AGENTS.md")
+	}
+	if strings.Contains(prompt, filepath.Join("fixtures", "repo", "AGENTS.md")) {
+		t.Errorf("system prompt lists a guidance file excluded by .shelleyignore")
+	}
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -767,7 +863,7 @@ This is a test skill.
 	}
 
 	// Generate subagent system prompt
-	prompt, err := GenerateSubagentSystemPrompt(tmpDir, "parent-conv-id")
+	prompt, err := GenerateSubagentSystemPrompt(tmpDir, "parent-conv-id", "")
 	if err != nil {
 		t.Fatalf("GenerateSubagentSystemPrompt failed: %v", err)
 	}
@@ -817,6 +913,65 @@ echo '{"slug": "Hello World!"}'`
 	}
 }
 
+func TestNewConversationHookEnvAndContext(t *testing.T) {
+	h := NewTestHarness(t)
+	script := `#!/bin/sh
+echo "{\"env\": {\"HOOK_VAR\": \"from-hook\"}, \"context\": \"args $2 $3, env $SHELLEY_MODEL\"}"`
+	if err := os.WriteFile(filepath.Join(h.server.hooksDir, "new-conversation"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	repoHooks := filepath.Join(repo, RepoHooksDir)
+	if err := os.MkdirAll(repoHooks, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	repoScript := "#!/bin/sh\necho '{\"context\": \"repo hook context\"}'\n"
+	if err := os.WriteFile(filepath.Join(repoHooks, "new-conversation"), []byte(repoScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(h.server.hooksDir, TrustedReposFile), []byte(repo+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h.NewConversation("bash: echo $HOOK_VAR", repo)
+	if got := h.WaitToolResult(); !strings.Contains(got, "from-hook") {
+		t.Errorf("hook env var not set: %q", got)
+	}
+	var system strings.Builder
+	for _, s := range h.llm.GetLastRequest().System {
+		system.WriteString(s.Text)
+	}
+	for _, want := range []string{"args " + repo + " predictable, env predictable", "repo hook context"} {
+		if !strings.Contains(system.String(), want) {
+			t.Errorf("system prompt lacks %q:\n%s", want, system.String())
+		}
+	}
+}
+
+func TestNewConversationHookAbort(t *testing.T) {
+	h := NewTestHarness(t)
+	script := `#!/bin/sh
+echo '{"abort": "Conversations are disabled during the freeze."}'`
+	if err := os.WriteFile(filepath.Join(h.server.hooksDir, "new-conversation"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.server.handleNewConversation(w, httptest.NewRequest("POST", "/api/conversations/new", strings.NewReader(`{"message":"hi","model":"predictable"}`)))
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "disabled during the freeze") {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	convs, err := h.db.ListConversations(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(convs) != 0 {
+		t.Errorf("aborted conversation was kept: %d conversations", len(convs))
+	}
+}
+
 func TestRunEndOfTurnHookNoHook(t *testing.T) {
 	// Should be a no-op and not panic.
 	_ = RunEndOfTurnHookIn(t.TempDir(), EndOfTurnHookInput{ConversationID: "abc"})
@@ -1102,3 +1257,122 @@ func TestHookHeadersEmptyReturnsNil(t *testing.T) {
 		t.Errorf("expected nil when only auth secrets present")
 	}
 }
+
+func TestSystemPromptOmitsGuidanceForDisabledTools(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	prompt, err := GenerateSystemPrompt(dir, WithTools([]*llm.Tool{{Name: "bash"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(prompt, "change_dir") {
+		t.Errorf("prompt mentions change_dir without the tool:\n%s", prompt)
+	}
+	prompt, err = GenerateSystemPrompt(dir, WithTools([]*llm.Tool{{Name: "change_dir"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "Use the change_dir tool") {
+		t.Errorf("prompt lacks change_dir guidance:\n%s", prompt)
+	}
+}
+
+func TestSystemPromptTemplateOverrides(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	project := t.TempDir()
+	write := func(path, text string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	userDir := filepath.Join(home, ".config", "shelley")
+	projectDir := filepath.Join(project, ".shelley")
+
+	// The user's override can include the embedded template and partials.
+	write(filepath.Join(userDir, SystemPromptFile), `{{template "default" .}}`+"\nUser rules for {{.WorkingDirectory}}.\n{{template \"extra\" .}}\n")
+	write(filepath.Join(userDir, SystemPromptPartialsDir, "extra.txt"), "User extra.")
+	prompt, err := GenerateSystemPrompt(project)
+	if err != nil {
+		t.Fatalf("GenerateSystemPrompt failed: %v", err)
+	}
+	if !strings.Contains(prompt, "User rules for "+project+".") || !strings.Contains(prompt, "User extra.") {
+		t.Errorf("prompt lacks the user's override:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "You are Shelley") {
+		t.Error("prompt lacks the embedded template")
+	}
+
+	// A project's partials replace the user's, and its override wins.
+	write(filepath.Join(projectDir, SystemPromptPartialsDir, "extra.txt"), "Project extra.")
+	prompt, err = GenerateSystemPrompt(project)
+	if err != nil {
+		t.Fatalf("GenerateSystemPrompt failed: %v", err)
+	}
+	if !strings.Contains(prompt, "Project extra.") || strings.Contains(prompt, "User extra.") {
+		t.Errorf("prompt doesn't use the project's partial:\n%s", prompt)
+	}
+	write(filepath.Join(projectDir, SystemPromptFile), "Project prompt.")
+	if prompt, err = GenerateSystemPrompt(project); err != nil || prompt != "Project prompt.\n" {
+		t.Errorf("GenerateSystemPrompt() = %q, %v; want the project's override", prompt, err)
+	}
+	want := []string{
+		filepath.Join(userDir, SystemPromptPartialsDir, "extra.txt"),
+		filepath.Join(userDir, SystemPromptFile),
+		filepath.Join(projectDir, SystemPromptPartialsDir, "extra.txt"),
+		filepath.Join(projectDir, SystemPromptFile),
+	}
+	if got := SystemPromptFiles(project); !slices.Equal(got, want) {
+		t.Errorf("SystemPromptFiles() = %q, want %q", got, want)
+	}
+
+	// Broken templates are reported with their file.
+	write(filepath.Join(projectDir, SystemPromptFile), "{{.Broken")
+	if _, err := GenerateSystemPrompt(project); err == nil || !strings.Contains(err.Error(), filepath.Join(projectDir, SystemPromptFile)) {
+		t.Errorf("broken template error = %v", err)
+	}
+}
+
+func TestSystemPromptCustomGuidanceNames(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"CONTRIBUTING.md":                 "CONTRIBUTING_MARKER",
+		".github/copilot-instructions.md": "COPILOT_MARKER",
+		".cursor/rules/style.mdc":         "CURSOR_MARKER",
+		"CLAUDE.md":                       "CLAUDE_MARKER",
+		"pkg/README.md":                   "pkg readme",
+		"pkg/CONTRIBUTING.md":             "pkg contributing",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prompt, err := GenerateSystemPrompt(dir, WithGuidanceNames(GuidanceNames{
+		Add:     []string{"CONTRIBUTING.md", ".github/copilot-instructions.md", ".cursor/rules/*.mdc"},
+		Exclude: []string{"README.md", "claude.md"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, marker := range []string{"CONTRIBUTING_MARKER", "COPILOT_MARKER", "CURSOR_MARKER"} {
+		if !strings.Contains(prompt, marker) {
+			t.Errorf("prompt lacks %s", marker)
+		}
+	}
+	if strings.Contains(prompt, "CLAUDE_MARKER") {
+		t.Error("prompt includes an excluded guidance file")
+	}
+	if !strings.Contains(prompt, filepath.Join(dir, "pkg", "CONTRIBUTING.md")) || strings.Contains(prompt, filepath.Join(dir, "pkg", "README.md")) {
+		t.Error("subdirectory guidance files do not follow the configured names")
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/llm"
+)
+
+const (
+	hookPreTool  = "pre-tool"
+	hookPostTool = "post-tool"
+)
+
+// ToolHookInput is the JSON data passed to the pre-tool/<tool> and
+// post-tool/<tool> hooks on stdin. Output and Error are only set for
+// post-tool hooks.
+type ToolHookInput struct {
+	claudetool.ToolHookCall
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// scriptToolHooks runs the pre-tool/<tool> and post-tool/<tool> hook
+// scripts in dir, then those in the RepoHooksDir of the trusted repository
+// containing the call's working directory.
+type scriptToolHooks struct {
+	dir string
+}
+
+// conversationToolSetConfig returns the tool set config for a new
+// conversation manager.
+func (s *Server) conversationToolSetConfig() claudetool.ToolSetConfig {
+	tc := s.currentToolSetConfig()
+	tc.ToolHooks = scriptToolHooks{dir: s.hooksDir}
+	return tc
+}
+
+func (h scriptToolHooks) BeforeTool(ctx context.Context, call claudetool.ToolHookCall) (string, error) {
+	for _, dir := range hookDirsFor(h.dir, call.Cwd) {
+		var out struct {
+			Deny string `json:"deny"`
+		}
+		if err := runToolHook(ctx, dir, hookPreTool, ToolHookInput{ToolHookCall: call}, &out); err != nil {
+			return "", err
+		}
+		if out.Deny != "" {
+			return out.Deny, nil
+		}
+	}
+	return "", nil
+}
+
+func (h scriptToolHooks) AfterTool(ctx context.Context, call claudetool.ToolHookCall, result llm.ToolOut) (string, error) {
+	input := ToolHookInput{ToolHookCall: call}
+	if result.Error != nil {
+		input.Error = result.Error.Error()
+	}
+	for _, c := range result.LLMContent {
+		if c.Type == llm.ContentTypeText {
+			input.Output += c.Text
+		}
+	}
+	var notes []string
+	for _, dir := range hookDirsFor(h.dir, call.Cwd) {
+		var out struct {
+			Annotation string `json:"annotation"`
+		}
+		if err := runToolHook(ctx, dir, hookPostTool, input, &out); err != nil {
+			return strings.Join(notes, "\n\n"), err
+		}
+		if out.Annotation != "" {
+			notes = append(notes, out.Annotation)
+		}
+	}
+	return strings.Join(notes, "\n\n"), nil
+}
+
+// hookDirsFor returns the user hooks directory followed by the RepoHooksDir
+// of the repository containing cwd, if that repository is trusted.
+func hookDirsFor(userDir, cwd string) []string {
+	dirs := []string{userDir}
+	if repo := repoHooksDir(userDir, cwd); repo != "" {
+		dirs = append(dirs, repo)
+	}
+	return dirs
+}
+
+// runToolHook runs the kind/<tool> hook in dir, if any, with input on stdin
+// and decodes its stdout, if not empty, into out.
+func runToolHook(ctx context.Context, dir, kind string, input ToolHookInput, out any) error {
+	name := kind + "/" + input.Tool
+	hookPath, err := findHookIn(dir, name)
+	if err != nil || hookPath == "" {
+		return err
+	}
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("%s hook: marshal input: %w", name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, hookPath, input.Tool)
+	cmd.Dir = input.Cwd
+	cmd.Stdin = bytes.NewReader(inputJSON)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"SHELLEY_TOOL="+input.Tool,
+		"SHELLEY_CONVERSATION_ID="+input.ConversationID,
+		"SHELLEY_CWD="+input.Cwd,
+	)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook %s failed: %w (stderr: %s)", name, hookPath, err, strings.TrimSpace(stderr.String()))
+	}
+	output := bytes.TrimSpace(stdout.Bytes())
+	if len(output) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(output, out); err != nil {
+		return fmt.Errorf("%s hook %s: invalid JSON output %q: %w", name, hookPath, output, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/llm"
+)
+
+func writeHook(t *testing.T, dir, name, script string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPreToolHookDenies(t *testing.T) {
+	h := NewTestHarness(t)
+	writeHook(t, h.server.hooksDir, "pre-tool/bash", `#!/bin/sh
+if grep -q 'rm -rf'; then
+  echo '{"deny": "rm -rf is not allowed"}'
+fi`)
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "keep")
+	if err := os.Mkdir(keep, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	h.NewConversation("bash: rm -rf keep", dir)
+	if got := h.WaitToolResult(); !strings.Contains(got, "denied by pre-tool hook: rm -rf is not allowed") {
+		t.Errorf("tool result = %q, want the hook's denial", got)
+	}
+	if _, err := os.Stat(keep); err != nil {
+		t.Errorf("denied command ran: %v", err)
+	}
+}
+
+func TestScriptToolHooks(t *testing.T) {
+	userHooks := t.TempDir()
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	writeHook(t, userHooks, "post-tool/patch", `#!/bin/sh
+echo "{\"annotation\": \"user saw $1 in $SHELLEY_CWD\"}"`)
+	writeHook(t, filepath.Join(repo, RepoHooksDir), "post-tool/patch", `#!/bin/sh
+grep -q '"output":"patched"' && echo '{"annotation": "formatted"}'`)
+	writeHook(t, filepath.Join(repo, RepoHooksDir), "pre-tool/patch", "#!/bin/sh\nexit 1\n")
+
+	hooks := scriptToolHooks{dir: userHooks}
+	call := claudetool.ToolHookCall{Tool: "patch", Input: []byte(`{}`), ConversationID: "c1", Cwd: repo}
+	if deny, err := hooks.BeforeTool(context.Background(), call); deny != "" || err != nil {
+		t.Errorf("untrusted repo: deny %q, err %v", deny, err)
+	}
+	if err := os.WriteFile(filepath.Join(userHooks, TrustedReposFile), []byte("/elsewhere\n"+repo+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	note, err := hooks.AfterTool(context.Background(), call, llm.ToolOut{LLMContent: llm.TextContent("patched")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "user saw patch in " + repo + "\n\nformatted"; note != want {
+		t.Errorf("note = %q, want %q", note, want)
+	}
+	if _, err := hooks.BeforeTool(context.Background(), call); err == nil {
+		t.Error("failing repo pre-tool hook returned no error")
+	}
+	call.Cwd = t.TempDir()
+	if deny, err := hooks.BeforeTool(context.Background(), call); deny != "" || err != nil {
+		t.Errorf("outside the repo: deny %q, err %v", deny, err)
+	}
+}
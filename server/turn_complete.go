@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/llm"
+)
+
+// TurnCompleteEvent describes an agent turn that just ended. It is passed
+// to the turn-complete hook on stdin and sent to stream subscribers.
+type TurnCompleteEvent struct {
+	Type           string    `json:"type"`
+	ConversationID string    `json:"conversation_id"`
+	Timestamp      time.Time `json:"timestamp"`
+	Model          string    `json:"model,omitempty"`
+	Cwd            string    `json:"cwd,omitempty"`
+	// DurationSeconds is zero if the turn started before the server did.
+	DurationSeconds float64   `json:"duration_seconds"`
+	Usage           llm.Usage `json:"usage"`
+	CostUSD         float64   `json:"cost_usd"`
+	// ChangedFiles are the files the turn's patch tool calls wrote.
+	ChangedFiles []string `json:"changed_files"`
+}
+
+// completeTurn builds the TurnCompleteEvent for cm's turn and runs the
+// turn-complete hooks in the background.
+func (s *Server) completeTurn(cm *ConversationManager, state ConversationState, cwd string) *TurnCompleteEvent {
+	duration, cost := cm.turnStats()
+	usage, files := cm.turnChanges()
+	event := &TurnCompleteEvent{
+		Type:            "turn_complete",
+		ConversationID:  state.ConversationID,
+		Timestamp:       time.Now(),
+		Model:           state.Model,
+		Cwd:             cwd,
+		DurationSeconds: duration.Seconds(),
+		Usage:           usage,
+		CostUSD:         cost,
+		ChangedFiles:    files,
+	}
+	if event.ChangedFiles == nil {
+		event.ChangedFiles = []string{}
+	}
+	// Like the end-of-turn hook, a slow hook must not delay the broadcast.
+	go func() {
+		if err := RunTurnCompleteHookIn(s.hooksDir, *event); err != nil {
+			s.logger.Error("turn-complete hook failed", "conversationID", event.ConversationID, "error", err)
+		}
+	}()
+	return event
+}
+
+// turnWaiter runs done when the turn a conversation is starting ends.
+type turnWaiter struct {
+	// working is set once the turn has started, so that only its end
+	// counts.
+	working bool
+	done    func()
+}
+
+// awaitTurnEnd arranges for done to run when conversationID's next turn
+// ends. Call it before starting the turn. It reports false, and does
+// nothing, if something already awaits the conversation.
+func (s *Server) awaitTurnEnd(conversationID string, done func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.turnWaiters[conversationID] != nil {
+		return false
+	}
+	s.turnWaiters[conversationID] = &turnWaiter{done: done}
+	return true
+}
+
+// cancelTurnEnd drops what awaits conversationID's turn, for a turn that
+// failed to start.
+func (s *Server) cancelTurnEnd(conversationID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.turnWaiters, conversationID)
+}
+
+// updateTurnWaiters runs the waiter of a conversation whose turn ended.
+func (s *Server) updateTurnWaiters(state ConversationState) {
+	s.mu.Lock()
+	w := s.turnWaiters[state.ConversationID]
+	if w != nil && state.Working {
+		w.working = true
+	}
+	ended := w != nil && w.working && !state.Working
+	if ended {
+		delete(s.turnWaiters, state.ConversationID)
+	}
+	s.mu.Unlock()
+	if ended {
+		w.done()
+	}
+}
+
+// patchedFiles returns the files that msg's successful patch tool results
+// wrote.
+func patchedFiles(msg llm.Message) []string {
+	var files []string
+	for _, c := range msg.Content {
+		if c.Type != llm.ContentTypeToolResult || c.ToolError {
+			continue
+		}
+		if d, ok := c.Display.(claudetool.PatchDisplayData); ok && !d.DryRun {
+			files = append(files, d.Paths...)
+		}
+	}
+	return files
+}
+
+// RunTurnCompleteHookIn runs the turn-complete hook from hooksDir, then the
+// one in the RepoHooksDir of the trusted repository containing the event's working
+// directory, with the event JSON on stdin. Stdout is ignored. The hooks run
+// in the working directory with SHELLEY_CONVERSATION_ID and SHELLEY_CWD set.
+func RunTurnCompleteHookIn(hooksDir string, event TurnCompleteEvent) error {
+	inputJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("turn-complete hook: marshal input: %w", err)
+	}
+	for _, dir := range hookDirsFor(hooksDir, event.Cwd) {
+		hookPath, err := findHookIn(dir, hookTurnComplete)
+		if err != nil {
+			return fmt.Errorf("turn-complete hook: %w", err)
+		}
+		if hookPath == "" {
+			continue
+		}
+		if err := runTurnCompleteHook(hookPath, event, inputJSON); err != nil {
+			return err
+		}
+		slog.Info("turn-complete hook applied", "hook", hookPath, "conversationID", event.ConversationID)
+	}
+	return nil
+}
+
+func runTurnCompleteHook(hookPath string, event TurnCompleteEvent, inputJSON []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, hookPath)
+	cmd.Dir = event.Cwd
+	cmd.Stdin = bytes.NewReader(inputJSON)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"SHELLEY_CONVERSATION_ID="+event.ConversationID,
+		"SHELLEY_CWD="+event.Cwd,
+	)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("turn-complete hook %s failed: %w (stderr: %s)", hookPath, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTurnCompleteHook(t *testing.T) {
+	h := NewTestHarness(t)
+	dumpFile := filepath.Join(t.TempDir(), "turn-complete.json")
+	writeHook(t, h.server.hooksDir, hookTurnComplete, "#!/bin/sh\ncat > "+dumpFile+".tmp && mv "+dumpFile+".tmp "+dumpFile+"\n")
+	cwd := t.TempDir()
+
+	h.NewConversation("patch success", cwd)
+	var event TurnCompleteEvent
+	waitFor(t, 10*time.Second, func() bool {
+		data, err := os.ReadFile(dumpFile)
+		return err == nil && json.Unmarshal(data, &event) == nil
+	})
+	if event.Type != "turn_complete" || event.ConversationID != h.ConversationID() || event.Cwd != cwd {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if !slices.Equal(event.ChangedFiles, []string{"/tmp/test-patch-success.txt"}) {
+		t.Errorf("changed files = %q", event.ChangedFiles)
+	}
+	if event.Usage.OutputTokens == 0 || event.DurationSeconds <= 0 {
+		t.Errorf("missing turn stats: usage %+v, duration %v", event.Usage, event.DurationSeconds)
+	}
+}
@@ -0,0 +1,394 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/worker"
+)
+
+// SetWorkerToken lets machines running "shelley worker" connect with
+// token, so that conversations can run their tools on them. Without a
+// token, workers are disabled.
+func (s *Server) SetWorkerToken(token string) error {
+	if token == "" {
+		return nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "shelley-workers-")
+	if err != nil {
+		return err
+	}
+	socket := filepath.Join(dir, "relay.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	s.workers = &workerHub{token: token, exe: exe, socket: socket, workers: make(map[string]*workerConn)}
+	go s.workers.serveRelays(ln)
+	go func() {
+		<-s.shutdownCh
+		ln.Close()
+		os.RemoveAll(dir)
+	}()
+	return nil
+}
+
+// workerHub tracks the connected workers and runs commands on them. Tools
+// run a command on a worker through a relay, "shelley worker exec", which
+// stands in for the command locally and connects to the hub's socket.
+type workerHub struct {
+	token  string
+	exe    string
+	socket string
+
+	mu      sync.Mutex
+	workers map[string]*workerConn
+}
+
+// workerConn is a connected worker.
+type workerConn struct {
+	name        string
+	remoteAddr  string
+	connectedAt time.Time
+	ws          *websocket.Conn
+	conn        worker.Conn
+
+	mu       sync.Mutex
+	nextID   int64
+	commands map[int64]func(worker.Frame) // delivers the command's frames
+	closed   bool
+}
+
+// WorkerInfo describes a connected worker for GET /api/workers.
+type WorkerInfo struct {
+	Name        string    `json:"name"`
+	RemoteAddr  string    `json:"remote_addr"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Commands    int       `json:"commands"`
+}
+
+// handleWorkers handles GET /api/workers, the connected workers.
+func (s *Server) handleWorkers(w http.ResponseWriter, r *http.Request) {
+	infos := []WorkerInfo{}
+	if s.workers != nil {
+		s.workers.mu.Lock()
+		for _, wc := range s.workers.workers {
+			wc.mu.Lock()
+			infos = append(infos, WorkerInfo{Name: wc.name, RemoteAddr: wc.remoteAddr, ConnectedAt: wc.connectedAt, Commands: len(wc.commands)})
+			wc.mu.Unlock()
+		}
+		s.workers.mu.Unlock()
+	}
+	slices.SortFunc(infos, func(a, b WorkerInfo) int { return cmp.Compare(a.Name, b.Name) })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+// handleWorkerConnect handles GET /api/workers/connect?name=..., the
+// websocket a worker keeps open. A worker that connects under the name of
+// a connected one replaces it.
+func (s *Server) handleWorkerConnect(w http.ResponseWriter, r *http.Request) {
+	h := s.workers
+	if h == nil {
+		http.Error(w, "workers are disabled: no worker_token is configured", http.StatusNotFound)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		http.Error(w, "invalid worker token", http.StatusUnauthorized)
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	ws, err := websocket.Accept(w, r, &websocket.AcceptOptions{CompressionMode: websocket.CompressionDisabled})
+	if err != nil {
+		s.logger.Error("Failed to upgrade worker websocket", "error", err)
+		return
+	}
+	wc := &workerConn{
+		name:        name,
+		remoteAddr:  r.RemoteAddr,
+		connectedAt: time.Now(),
+		ws:          ws,
+		conn:        worker.WebSocket(ws),
+		commands:    make(map[int64]func(worker.Frame)),
+	}
+	h.mu.Lock()
+	old := h.workers[name]
+	h.workers[name] = wc
+	h.mu.Unlock()
+	if old != nil {
+		old.ws.Close(websocket.StatusGoingAway, "replaced by a new connection")
+	}
+	s.logger.Info("Worker connected", "worker", name, "remoteAddr", r.RemoteAddr)
+
+	for {
+		f, err := wc.conn.ReadFrame(r.Context())
+		if err != nil {
+			s.logger.Info("Worker disconnected", "worker", name, "error", err)
+			break
+		}
+		wc.deliver(f)
+	}
+	h.mu.Lock()
+	if h.workers[name] == wc {
+		delete(h.workers, name)
+	}
+	h.mu.Unlock()
+	wc.fail("worker " + name + " disconnected")
+	ws.CloseNow()
+}
+
+// deliver passes a frame from the worker to its command.
+func (wc *workerConn) deliver(f worker.Frame) {
+	wc.mu.Lock()
+	fn := wc.commands[f.ID]
+	if f.Type == worker.FrameExit {
+		delete(wc.commands, f.ID)
+	}
+	wc.mu.Unlock()
+	if fn != nil {
+		fn(f)
+	}
+}
+
+// fail ends the worker's commands with msg.
+func (wc *workerConn) fail(msg string) {
+	wc.mu.Lock()
+	commands := wc.commands
+	wc.commands = nil
+	wc.closed = true
+	wc.mu.Unlock()
+	for id, fn := range commands {
+		fn(worker.Frame{ID: id, Type: worker.FrameExit, Code: -1, Error: msg})
+	}
+}
+
+// workerCommand is a command running on a worker.
+type workerCommand struct {
+	wc *workerConn
+	id int64
+}
+
+// start runs the command exec describes on the named worker. deliver is
+// called with the command's output frames, in order, and finally with its
+// exit frame.
+func (h *workerHub) start(ctx context.Context, name string, exec worker.Frame, deliver func(worker.Frame)) (*workerCommand, error) {
+	wc, err := h.worker(name)
+	if err != nil {
+		return nil, err
+	}
+	wc.mu.Lock()
+	if wc.closed {
+		wc.mu.Unlock()
+		return nil, fmt.Errorf("worker %s disconnected", name)
+	}
+	wc.nextID++
+	id := wc.nextID
+	wc.commands[id] = deliver
+	wc.mu.Unlock()
+	exec.ID, exec.Type, exec.Worker = id, worker.FrameExec, ""
+	c := &workerCommand{wc: wc, id: id}
+	if err := wc.conn.WriteFrame(ctx, exec); err != nil {
+		c.stop(ctx)
+		return nil, err
+	}
+	return c, nil
+}
+
+// worker returns the connected worker called name.
+func (h *workerHub) worker(name string) (*workerConn, error) {
+	if h == nil {
+		return nil, errors.New("workers are disabled: no worker_token is configured")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	wc := h.workers[name]
+	if wc == nil {
+		return nil, fmt.Errorf("worker %s is not connected", name)
+	}
+	return wc, nil
+}
+
+// send sends the command a stdin, eof, or kill frame.
+func (c *workerCommand) send(ctx context.Context, typ string, data []byte) error {
+	return c.wc.conn.WriteFrame(ctx, worker.Frame{ID: c.id, Type: typ, Data: data})
+}
+
+// stop kills the command unless it has exited.
+func (c *workerCommand) stop(ctx context.Context) {
+	c.wc.mu.Lock()
+	_, running := c.wc.commands[c.id]
+	delete(c.wc.commands, c.id)
+	c.wc.mu.Unlock()
+	if running {
+		c.send(ctx, worker.FrameKill, nil)
+	}
+}
+
+// serveRelays accepts the connections of "shelley worker exec" relays,
+// each of which runs one command.
+func (h *workerHub) serveRelays(ln net.Listener) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go h.relay(c)
+	}
+}
+
+func (h *workerHub) relay(c net.Conn) {
+	defer c.Close()
+	ctx := context.Background()
+	conn := worker.Stream(c)
+	exec, err := conn.ReadFrame(ctx)
+	if err != nil || exec.Type != worker.FrameExec {
+		return
+	}
+	cmd, err := h.start(ctx, exec.Worker, exec, func(f worker.Frame) {
+		f.ID = 0
+		conn.WriteFrame(ctx, f)
+	})
+	if err != nil {
+		conn.WriteFrame(ctx, worker.Frame{Type: worker.FrameExit, Code: -1, Error: err.Error()})
+		return
+	}
+	// The relay goes away when its command exits or is killed locally.
+	defer cmd.stop(ctx)
+	for {
+		f, err := conn.ReadFrame(ctx)
+		if err != nil {
+			return
+		}
+		switch f.Type {
+		case worker.FrameStdin, worker.FrameEOF, worker.FrameKill:
+			if err := cmd.send(ctx, f.Type, f.Data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// run runs args on the named worker with stdin and returns its output and
+// exit code.
+func (h *workerHub) run(ctx context.Context, name string, stdin []byte, args ...string) (stdout, stderr []byte, code int, err error) {
+	var out, errOut bytes.Buffer
+	done := make(chan worker.Frame, 1)
+	cmd, err := h.start(ctx, name, worker.Frame{Args: args}, func(f worker.Frame) {
+		switch f.Type {
+		case worker.FrameStdout:
+			out.Write(f.Data)
+		case worker.FrameStderr:
+			errOut.Write(f.Data)
+		case worker.FrameExit:
+			done <- f
+		}
+	})
+	if err != nil {
+		return nil, nil, -1, err
+	}
+	defer cmd.stop(ctx)
+	for len(stdin) > 0 {
+		n := min(len(stdin), worker.ChunkSize)
+		if err := cmd.send(ctx, worker.FrameStdin, stdin[:n]); err != nil {
+			return nil, nil, -1, err
+		}
+		stdin = stdin[n:]
+	}
+	if err := cmd.send(ctx, worker.FrameEOF, nil); err != nil {
+		return nil, nil, -1, err
+	}
+	select {
+	case f := <-done:
+		if f.Error != "" {
+			return nil, nil, -1, errors.New(f.Error)
+		}
+		return out.Bytes(), errOut.Bytes(), f.Code, nil
+	case <-ctx.Done():
+		return nil, nil, -1, ctx.Err()
+	}
+}
+
+// workerRemote runs a conversation's tools on a worker.
+type workerRemote struct {
+	hub  *workerHub
+	name string
+}
+
+// Wrap makes cmd run on the worker through a relay. The relay runs in the
+// server's working directory, since cmd.Dir is the worker's.
+func (r *workerRemote) Wrap(cmd *exec.Cmd) error {
+	if _, err := r.hub.worker(r.name); err != nil {
+		return err
+	}
+	args := []string{r.hub.exe, "worker", "exec", "-socket", r.hub.socket, "-name", r.name, "-dir", cmd.Dir}
+	// Pass the variables Shelley adds, not the host's PATH and friends.
+	host := os.Environ()
+	for _, kv := range cmd.Env {
+		if !slices.Contains(host, kv) {
+			args = append(args, "-e", kv)
+		}
+	}
+	cmd.Path = r.hub.exe
+	cmd.Args = append(append(args, "--"), cmd.Args...)
+	cmd.Dir = ""
+	return nil
+}
+
+func (r *workerRemote) Close() error { return nil }
+
+// readFileScript prints file $1, or exits 66 (EX_NOINPUT) if it is missing.
+const readFileScript = `[ -e "$1" ] || exit 66; exec cat -- "$1"`
+
+func (r *workerRemote) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	out, errOut, code, err := r.hub.run(ctx, r.name, nil, "sh", "-c", readFileScript, "sh", path)
+	switch {
+	case err != nil:
+		return nil, err
+	case code == 66:
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	case code != 0:
+		return nil, fmt.Errorf("reading %s on worker %s: %s", path, r.name, cmp.Or(string(bytes.TrimSpace(errOut)), fmt.Sprintf("exit status %d", code)))
+	}
+	return out, nil
+}
+
+func (r *workerRemote) WriteFile(ctx context.Context, path string, data []byte) error {
+	_, errOut, code, err := r.hub.run(ctx, r.name, data, "sh", "-c", `mkdir -p -- "$(dirname -- "$1")" && cat > "$1"`, "sh", path)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("writing %s on worker %s: %s", path, r.name, cmp.Or(string(bytes.TrimSpace(errOut)), fmt.Sprintf("exit status %d", code)))
+	}
+	return nil
+}
+
+var _ claudetool.Remote = (*workerRemote)(nil)
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/worker"
+)
+
+func TestWorkers(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	if err := s.SetWorkerToken("secret"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { close(s.shutdownCh) })
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := worker.Connect(ctx, ts.URL, "gpu", "wrong"); err == nil {
+		t.Fatal("worker connected with the wrong token")
+	}
+	go worker.Connect(ctx, ts.URL, "gpu", "secret")
+	waitFor(t, 5*time.Second, func() bool {
+		resp, err := http.Get(ts.URL + "/api/workers")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		var infos []WorkerInfo
+		json.NewDecoder(resp.Body).Decode(&infos)
+		return len(infos) == 1 && infos[0].Name == "gpu"
+	})
+	s.SetAdminToken("admin")
+	resp, err := http.Get(ts.URL + "/api/workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("workers without the admin token: got %d, want 401", resp.StatusCode)
+	}
+
+	dir := t.TempDir()
+	remote := &workerRemote{hub: s.workers, name: "gpu"}
+	path := filepath.Join(dir, "sub", "f.txt")
+	if err := remote.WriteFile(ctx, path, []byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := remote.ReadFile(ctx, path); err != nil || string(data) != "hello\n" {
+		t.Fatalf("ReadFile = %q, %v", data, err)
+	}
+	if _, err := remote.ReadFile(ctx, filepath.Join(dir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("ReadFile of a missing file: %v", err)
+	}
+
+	// A relay runs its command on the worker.
+	c, err := net.Dial("unix", s.workers.socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var stdout, stderr bytes.Buffer
+	exec := worker.Frame{Worker: "gpu", Args: []string{"sh", "-c", `cat sub/f.txt; echo "$X" >&2; exit 3`}, Dir: dir, Env: []string{"X=y"}}
+	code, err := worker.Relay(ctx, worker.Stream(c), exec, nil, &stdout, &stderr)
+	if err != nil || code != 3 || stdout.String() != "hello\n" || stderr.String() != "y\n" {
+		t.Fatalf("Relay = %d, %v; stdout %q, stderr %q", code, err, stdout.String(), stderr.String())
+	}
+}
+
+func TestWorkerRemoteWrap(t *testing.T) {
+	hub := &workerHub{exe: "/bin/shelley", socket: "/tmp/relay.sock", workers: map[string]*workerConn{"gpu": {}}}
+	cmd := exec.Command("bash", "-c", "ls")
+	cmd.Dir = "/srv/app"
+	cmd.Env = []string{"SHELLEY_CONVERSATION_ID=c1"}
+	if err := (&workerRemote{hub: hub, name: "gpu"}).Wrap(cmd); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/bin/shelley", "worker", "exec", "-socket", "/tmp/relay.sock", "-name", "gpu", "-dir", "/srv/app", "-e", "SHELLEY_CONVERSATION_ID=c1", "--", "bash", "-c", "ls"}
+	if !slices.Equal(cmd.Args, want) || cmd.Path != "/bin/shelley" || cmd.Dir != "" {
+		t.Errorf("Wrap made %q in %q", cmd.Args, cmd.Dir)
+	}
+
+	err := (&workerRemote{hub: hub, name: "vm"}).Wrap(exec.Command("true"))
+	if err == nil || !strings.Contains(err.Error(), "not connected") {
+		t.Errorf("Wrap for a disconnected worker: %v", err)
+	}
+	err = (&workerRemote{name: "gpu"}).Wrap(exec.Command("true"))
+	if err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("Wrap with workers disabled: %v", err)
+	}
+}
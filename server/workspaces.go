@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+)
+
+func workspaceContainer(conversationID string) string {
+	return "shelley-workspace-" + conversationID
+}
+
+// createWorkspace creates the docker workspace a new conversation's
+// options ask for, records it in the options, and moves the conversation
+// into it. It returns the updated conversation.
+func (s *Server) createWorkspace(ctx context.Context, conversationID string) (*generated.Conversation, error) {
+	cfg := s.currentToolSetConfig().Workspaces
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("workspaces are disabled: no workspaces.image is configured")
+	}
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	opts := db.ParseConversationOptions(conv.ConversationOptions)
+	ws, base, err := claudetool.CreateWorkspace(ctx, cfg, workspaceContainer(conversationID), opts.Workspace.Repo, opts.Workspace.Ref)
+	if err != nil {
+		return nil, err
+	}
+	opts.Workspace.Container, opts.Workspace.Dir, opts.Workspace.Base = ws.Container, ws.Dir, base
+	err = s.db.UpdateConversationOptions(ctx, conversationID, opts)
+	if err == nil {
+		err = s.db.UpdateConversationCwd(ctx, conversationID, ws.Dir)
+	}
+	if err == nil {
+		conv, err = s.db.GetConversationByID(ctx, conversationID)
+	}
+	if err != nil {
+		claudetool.RemoveWorkspace(context.WithoutCancel(ctx), ws.Container)
+		return nil, err
+	}
+	s.logger.Info("Created workspace", "conversationID", conversationID, "container", ws.Container, "repo", opts.Workspace.Repo, "base", base)
+	return conv, nil
+}
+
+// conversationWorkspace returns the workspace a conversation runs in, or
+// nil if it has none.
+func conversationWorkspace(opts db.ConversationOptions) *claudetool.Workspace {
+	if opts.Workspace == nil || opts.Workspace.Container == "" {
+		return nil
+	}
+	return &claudetool.Workspace{Container: opts.Workspace.Container, Dir: opts.Workspace.Dir}
+}
+
+// removeWorkspace removes the container of a deleted top-level
+// conversation's workspace. Subagents share their parent's.
+func (s *Server) removeWorkspace(conv *generated.Conversation) {
+	opts := db.ParseConversationOptions(conv.ConversationOptions)
+	ws := conversationWorkspace(opts)
+	if ws == nil || conv.ParentConversationID != nil {
+		return
+	}
+	if err := claudetool.RemoveWorkspace(context.Background(), ws.Container); err != nil {
+		s.logger.Error("Failed to remove workspace", "conversationID", conv.ConversationID, "container", ws.Container, "error", err)
+	}
+}
+
+// handleWorkspaceDiff handles GET /api/conversation/<id>/workspace/diff,
+// the changes made in the conversation's workspace since it was cloned, as
+// a patch for git apply.
+func (s *Server) handleWorkspaceDiff(w http.ResponseWriter, r *http.Request, conversationID string) {
+	conv, err := s.db.GetConversationByID(r.Context(), conversationID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	opts := db.ParseConversationOptions(conv.ConversationOptions)
+	ws := conversationWorkspace(opts)
+	if ws == nil {
+		http.Error(w, "Conversation has no workspace", http.StatusNotFound)
+		return
+	}
+	diff, err := ws.Diff(r.Context(), opts.Workspace.Base)
+	if err != nil {
+		s.logger.Error("Failed to diff workspace", "conversationID", conversationID, "error", err)
+		http.Error(w, fmt.Sprintf("Failed to diff workspace: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	w.Write(diff)
+}
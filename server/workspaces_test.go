@@ -0,0 +1,46 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db"
+)
+
+func TestWorkspaceOptionsRejected(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	cases := []struct {
+		opts db.ConversationOptions
+		want string
+	}{
+		{db.ConversationOptions{Workspace: &db.WorkspaceOptions{}}, "workspace.repo is required"},
+		{db.ConversationOptions{Workspace: &db.WorkspaceOptions{Repo: "r", Container: "c"}}, "set by the server"},
+		{db.ConversationOptions{Workspace: &db.WorkspaceOptions{Repo: "r"}, Worker: "gpu"}, "both a worker and a workspace"},
+		// No workspaces.image is configured.
+		{db.ConversationOptions{Workspace: &db.WorkspaceOptions{Repo: "r"}}, "Workspaces are disabled"},
+	}
+	for _, c := range cases {
+		body, _ := json.Marshal(ChatRequest{Message: "hi", Model: "predictable", ConversationOptions: &c.opts})
+		w := httptest.NewRecorder()
+		s.handleNewConversation(w, httptest.NewRequest(http.MethodPost, "/api/conversations/new", strings.NewReader(string(body))))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), c.want) {
+			t.Errorf("%+v: got %d %q, want 400 %q", c.opts.Workspace, w.Code, w.Body.String(), c.want)
+		}
+	}
+}
+
+func TestWorkspaceDiffWithoutWorkspace(t *testing.T) {
+	s, database, _ := newTestServer(t)
+	conv, err := database.CreateConversation(t.Context(), nil, true, nil, nil, db.ConversationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	s.handleWorkspaceDiff(w, httptest.NewRequest(http.MethodGet, "/", nil), conv.ConversationID)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "no workspace") {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+}
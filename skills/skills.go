@@ -3,9 +3,11 @@
 package skills
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"html"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -34,9 +36,11 @@ type Skill struct {
 
 // Discover finds all skills in the given directories.
 // It scans each directory for subdirectories containing SKILL.md files.
+// When two directories hold a skill of the same name, the earlier one wins.
 func Discover(dirs []string) []Skill {
 	var skills []Skill
 	seen := make(map[string]bool)
+	names := make(map[string]bool)
 
 	for _, dir := range dirs {
 		dir = expandPath(dir)
@@ -74,9 +78,10 @@ func Discover(dirs []string) []Skill {
 			}
 
 			// Validate name matches directory
-			if skill.Name != entry.Name() {
+			if skill.Name != entry.Name() || names[skill.Name] {
 				continue
 			}
+			names[skill.Name] = true
 
 			skills = append(skills, skill)
 		}
@@ -380,6 +385,25 @@ func DefaultDirs() []string {
 	return dirs
 }
 
+// RepoSkillsDir is where a repository keeps skills versioned with its code,
+// relative to its root.
+const RepoSkillsDir = ".shelley/skills"
+
+// searchDirs returns the skill directories for workingDir, highest
+// precedence first: the repository's RepoSkillsDir (the git root's, or
+// workingDir's outside a repository), DefaultDirs, then ProjectSkillsDirs.
+func searchDirs(workingDir, gitRoot string) []string {
+	var dirs []string
+	if root := cmp.Or(gitRoot, workingDir); root != "" {
+		repoDir := filepath.Join(root, RepoSkillsDir)
+		if info, err := os.Stat(repoDir); err == nil && info.IsDir() {
+			dirs = append(dirs, repoDir)
+		}
+	}
+	dirs = append(dirs, DefaultDirs()...)
+	return append(dirs, ProjectSkillsDirs(workingDir, gitRoot)...)
+}
+
 // expandPath expands ~ to the user's home directory.
 func expandPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
@@ -503,7 +527,8 @@ func DiscoverInTree(workingDir, gitRoot string) ([]Skill, map[string]bool) {
 
 // ListAll returns all available skills (built-in + filesystem), deduplicated by name.
 //
-// Filesystem skills take priority over built-in skills with the same name.
+// Filesystem skills take priority over built-in skills with the same name,
+// and a repository's RepoSkillsDir over the user's skills.
 // An empty SKILL.md on the filesystem suppresses the corresponding built-in
 // skill entirely — this is the mechanism for users to disable built-in skills.
 //
@@ -513,8 +538,7 @@ func ListAll(workingDir, gitRoot string) []Skill {
 		gitRoot = findGitRoot(workingDir)
 	}
 
-	dirs := DefaultDirs()
-	dirs = append(dirs, ProjectSkillsDirs(workingDir, gitRoot)...)
+	dirs := searchDirs(workingDir, gitRoot)
 
 	all := Discover(dirs)
 
@@ -586,34 +610,51 @@ func matchWhen(when string, env Env) bool {
 }
 
 // FindByName looks up a skill by name and returns its raw SKILL.md content.
+// See Find for precedence.
+func FindByName(name, workingDir string) (string, error) {
+	skill, err := Find(name, workingDir)
+	if err != nil {
+		return "", err
+	}
+	return Read(skill)
+}
+
+// Read returns a skill's raw SKILL.md content.
+func Read(skill Skill) (string, error) {
+	if skill.Path == "" {
+		data, err := builtinFS.ReadFile("builtin/" + skill.Name + "/SKILL.md")
+		if err != nil {
+			return "", fmt.Errorf("reading built-in skill %q: %w", skill.Name, err)
+		}
+		return string(data), nil
+	}
+	content, err := os.ReadFile(skill.Path)
+	if err != nil {
+		return "", fmt.Errorf("reading skill %q: %w", skill.Name, err)
+	}
+	return string(content), nil
+}
+
+// Find looks up a skill by name.
 //
 // Filesystem skills take priority: if a SKILL.md exists on the filesystem
 // for the given name it is returned, even if a built-in skill with the
 // same name exists. An empty filesystem SKILL.md suppresses the built-in
 // skill — this lets users delete built-in skills they don't want.
-func FindByName(name, workingDir string) (string, error) {
+func Find(name, workingDir string) (Skill, error) {
 	gitRoot := findGitRoot(workingDir)
-	dirs := DefaultDirs()
-	dirs = append(dirs, ProjectSkillsDirs(workingDir, gitRoot)...)
+	dirs := searchDirs(workingDir, gitRoot)
 
 	// Filesystem first: check directory-based discovery, then tree discovery.
 	for _, s := range Discover(dirs) {
 		if s.Name == name {
-			content, err := os.ReadFile(s.Path)
-			if err != nil {
-				return "", fmt.Errorf("reading skill %q: %w", name, err)
-			}
-			return string(content), nil
+			return s, nil
 		}
 	}
 	treeSkills, treeNames := DiscoverInTree(workingDir, gitRoot)
 	for _, s := range treeSkills {
 		if s.Name == name {
-			content, err := os.ReadFile(s.Path)
-			if err != nil {
-				return "", fmt.Errorf("reading skill %q: %w", name, err)
-			}
-			return string(content), nil
+			return s, nil
 		}
 	}
 
@@ -631,27 +672,80 @@ func FindByName(name, workingDir string) (string, error) {
 			if path := findSkillMD(filepath.Join(dir, name)); path != "" {
 				if data, err := os.ReadFile(path); err == nil && len(strings.TrimSpace(string(data))) > 0 {
 					if _, parseErr := Parse(path); parseErr != nil {
-						return "", fmt.Errorf("skill %q (%s): %w", name, path, parseErr)
+						return Skill{}, fmt.Errorf("skill %q (%s): %w", name, path, parseErr)
 					}
 				}
 				break
 			}
 		}
-		return "", fmt.Errorf("skill %q is disabled", name)
+		return Skill{}, fmt.Errorf("skill %q is disabled", name)
 	}
 
 	// Fall back to built-in skills.
 	for _, s := range BuiltinSkills() {
 		if s.Name == name {
-			data, err := builtinFS.ReadFile("builtin/" + name + "/SKILL.md")
-			if err != nil {
-				return "", fmt.Errorf("reading built-in skill %q: %w", name, err)
-			}
-			return string(data), nil
+			return s, nil
 		}
 	}
 
-	return "", fmt.Errorf("skill %q not found", name)
+	return Skill{}, fmt.Errorf("skill %q not found", name)
+}
+
+// Files returns the paths, relative to the skill's directory, of the files
+// bundled with a filesystem skill other than SKILL.md, such as reference
+// documents and scripts it points to. Hidden files are skipped. Built-in
+// skills have none.
+func Files(skill Skill) ([]string, error) {
+	if skill.Path == "" {
+		return nil, nil
+	}
+	dir := filepath.Dir(skill.Path)
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || path == skill.Path {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		if len(files) == maxSkillFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return files, err
+}
+
+// maxSkillFiles caps how many files Files lists, for skills that bundle a
+// whole tree.
+const maxSkillFiles = 200
+
+// ReadFile reads a file bundled with a filesystem skill, by its path relative
+// to the skill's directory as returned by Files.
+func ReadFile(skill Skill, name string) ([]byte, error) {
+	if skill.Path == "" {
+		return nil, fmt.Errorf("built-in skill %q has no files besides SKILL.md", skill.Name)
+	}
+	if !filepath.IsLocal(name) {
+		return nil, fmt.Errorf("%q is not a path inside the skill directory", name)
+	}
+	root, err := os.OpenRoot(filepath.Dir(skill.Path))
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	return root.ReadFile(name)
 }
 
 // dirSkillNames returns the set of skill names found in the given skill
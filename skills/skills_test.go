@@ -797,6 +797,52 @@ func TestFilesystemSkillOverridesBuiltin(t *testing.T) {
 	}
 }
 
+func TestRepoSkillsOverrideUserSkills(t *testing.T) {
+	tmpHome := t.TempDir()
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(dir, description string) {
+		t.Helper()
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		content := "---\nname: deploy\ndescription: " + description + "\n---\n\nInstructions: " + description + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(tmpHome, ".config", "shelley", "deploy"), "User deploy.")
+	write(filepath.Join(repo, ".shelley", "skills", "deploy"), "Repo deploy.")
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpHome)
+	t.Cleanup(func() { os.Setenv("HOME", oldHome) })
+
+	// From a subdirectory, the repository's skills are found at its root.
+	wd := filepath.Join(repo, "cmd")
+	if err := os.Mkdir(wd, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var descriptions []string
+	for _, s := range ListAll(wd, "") {
+		if s.Name == "deploy" {
+			descriptions = append(descriptions, s.Description)
+		}
+	}
+	if len(descriptions) != 1 || descriptions[0] != "Repo deploy." {
+		t.Errorf("ListAll deploy skills = %q, want just the repository's", descriptions)
+	}
+	content, err := FindByName("deploy", wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "Instructions: Repo deploy.") {
+		t.Errorf("FindByName returned the user's skill:\n%s", content)
+	}
+}
+
 func TestDiscoverFollowsSymlinks(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -0,0 +1,103 @@
+package slackbot
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Message is a message for the app: one mentioning it, or any reply in a
+// thread, which continues the thread's conversation if it has one.
+type Message struct {
+	Channel string
+	// User is the sender's user ID.
+	User string
+	// Text is the message, without the mention of the app.
+	Text string
+	// TS is the message's timestamp, and Thread the timestamp of the
+	// message starting its thread, which is TS if it isn't a reply.
+	TS     string
+	Thread string
+	// Mention reports whether the message mentions the app.
+	Mention bool
+}
+
+// event holds the fields of app_mention and message events that Parse uses.
+type event struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	BotID    string `json:"bot_id"`
+	User     string `json:"user"`
+	Channel  string `json:"channel"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+}
+
+// payload is an Events API request.
+type payload struct {
+	Type           string `json:"type"`
+	Challenge      string `json:"challenge"`
+	Event          event  `json:"event"`
+	Authorizations []struct {
+		UserID string `json:"user_id"`
+	} `json:"authorizations"`
+}
+
+// userMention matches a mention of a user, such as "<@U012AB3CD>".
+var userMention = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|[^>]*)?>`)
+
+// Parse parses an Events API request. It returns the challenge of a
+// url_verification request, which must be echoed back, and nil, and no
+// error, for events that aren't a Message, including the app's own posts.
+func Parse(body []byte) (challenge string, m *Message, err error) {
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return "", nil, fmt.Errorf("invalid event payload: %w", err)
+	}
+	switch p.Type {
+	case "url_verification":
+		return p.Challenge, nil, nil
+	case "event_callback":
+	default:
+		return "", nil, nil
+	}
+	e := p.Event
+	if e.Subtype != "" || e.BotID != "" || e.User == "" {
+		return "", nil, nil
+	}
+	var bot string
+	if len(p.Authorizations) > 0 {
+		bot = p.Authorizations[0].UserID
+	}
+	m = &Message{Channel: e.Channel, User: e.User, TS: e.TS, Thread: cmp.Or(e.ThreadTS, e.TS)}
+	switch e.Type {
+	case "app_mention":
+		m.Mention = true
+	case "message":
+		// Mentions arrive as app_mention events too.
+		if e.ThreadTS == "" || (bot != "" && mentions(e.Text, bot)) {
+			return "", nil, nil
+		}
+	default:
+		return "", nil, nil
+	}
+	m.Text = strings.TrimSpace(userMention.ReplaceAllStringFunc(e.Text, func(s string) string {
+		if mentions(s, bot) {
+			return ""
+		}
+		return s
+	}))
+	return "", m, nil
+}
+
+func mentions(text, user string) bool {
+	for _, match := range userMention.FindAllStringSubmatch(text, -1) {
+		if match[1] == user {
+			return true
+		}
+	}
+	return false
+}
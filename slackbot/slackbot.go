@@ -0,0 +1,188 @@
+// Package slackbot connects Shelley to Slack as a Slack app: it checks and
+// parses the app's Events API requests, and posts to threads with the Web
+// API as the app's bot user.
+package slackbot
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSkew is how old a request's timestamp may be, so that a captured
+// request can't be replayed later.
+const maxSkew = 5 * time.Minute
+
+// MaxText is the longest message text Slack accepts.
+const MaxText = 40000
+
+// Config configures the Slack app in shelley.json.
+type Config struct {
+	// BotToken is the app's bot user OAuth token, "xoxb-...". It needs the
+	// app_mentions:read, channels:history, and chat:write scopes.
+	BotToken string `json:"bot_token"`
+	// SigningSecret is the secret Slack signs requests with.
+	SigningSecret string `json:"signing_secret"`
+	// AllowedUsers are the IDs of the users whose messages the app acts on.
+	AllowedUsers []string `json:"allowed_users"`
+	// AllowedChannels are the IDs of the channels the app acts in.
+	AllowedChannels []string `json:"allowed_channels"`
+	// Cwd is the working directory of conversations. The default is the
+	// server's.
+	Cwd string `json:"cwd,omitempty"`
+	// Model is the model conversations use. The default is the server's.
+	Model string `json:"model,omitempty"`
+	// APIURL is the Web API's URL. The default is https://slack.com/api.
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// Enabled reports whether c configures an app.
+func (c Config) Enabled() bool {
+	return c.BotToken != ""
+}
+
+// Validate checks an enabled c.
+func (c Config) Validate() error {
+	switch {
+	case c.SigningSecret == "":
+		return fmt.Errorf("signing_secret is required")
+	case len(c.AllowedUsers) == 0:
+		return fmt.Errorf("allowed_users is required")
+	case len(c.AllowedChannels) == 0:
+		return fmt.Errorf("allowed_channels is required")
+	case c.Cwd != "" && !filepath.IsAbs(c.Cwd):
+		return fmt.Errorf("cwd %q is not absolute", c.Cwd)
+	}
+	return nil
+}
+
+// Allowed reports whether the app acts on m: whether its sender and
+// channel are allowed.
+func (c Config) Allowed(m *Message) bool {
+	return slices.Contains(c.AllowedUsers, m.User) && slices.Contains(c.AllowedChannels, m.Channel)
+}
+
+func (c Config) apiURL() string {
+	if c.APIURL == "" {
+		return "https://slack.com/api"
+	}
+	return strings.TrimSuffix(c.APIURL, "/")
+}
+
+// App is a Slack app, calling the Web API with its bot token.
+type App struct {
+	Config Config
+	Client *http.Client
+}
+
+// New returns the app c configures.
+func New(c Config) (*App, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &App{Config: c, Client: http.DefaultClient}, nil
+}
+
+// VerifySignature checks a request's X-Slack-Signature header against its
+// X-Slack-Request-Timestamp header and body, as of now.
+func (a *App) VerifySignature(body []byte, timestamp, signature string, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed timestamp")
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > maxSkew || d < -maxSkew {
+		return fmt.Errorf("stale timestamp")
+	}
+	sig, ok := strings.CutPrefix(signature, "v0=")
+	if !ok {
+		return fmt.Errorf("missing v0 signature")
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("malformed signature")
+	}
+	mac := hmac.New(sha256.New, []byte(a.Config.SigningSecret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// call calls a Web API method, which reports failures in the body with a
+// 200 status.
+func (a *App) call(ctx context.Context, method string, in, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Config.apiURL()+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.Config.BotToken)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("%s: %s", method, result.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// PostMessage posts text in the thread of channel started by thread, and
+// returns the message's timestamp, which identifies it.
+func (a *App) PostMessage(ctx context.Context, channel, thread, text string) (string, error) {
+	var out struct {
+		TS string `json:"ts"`
+	}
+	in := map[string]any{"channel": channel, "thread_ts": thread, "text": truncate(text)}
+	if err := a.call(ctx, "chat.postMessage", in, &out); err != nil {
+		return "", err
+	}
+	return out.TS, nil
+}
+
+// UpdateMessage replaces the text of the message at ts in channel.
+func (a *App) UpdateMessage(ctx context.Context, channel, ts, text string) error {
+	return a.call(ctx, "chat.update", map[string]any{"channel": channel, "ts": ts, "text": truncate(text)}, nil)
+}
+
+func truncate(text string) string {
+	if len(text) <= MaxText {
+		return text
+	}
+	return text[:MaxText-len("…")] + "…"
+}
@@ -0,0 +1,131 @@
+package slackbot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{BotToken: "xoxb-1", SigningSecret: "s", AllowedUsers: []string{"U1"}, AllowedChannels: []string{"C1"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+	for _, c := range []Config{
+		{BotToken: "xoxb-1", AllowedUsers: []string{"U1"}, AllowedChannels: []string{"C1"}},
+		{BotToken: "xoxb-1", SigningSecret: "s", AllowedUsers: []string{"U1"}, AllowedChannels: []string{"C1"}, Cwd: "work"},
+		{BotToken: "xoxb-1", SigningSecret: "s", AllowedChannels: []string{"C1"}},
+		{BotToken: "xoxb-1", SigningSecret: "s", AllowedUsers: []string{"U1"}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", c)
+		}
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	app, err := New(Config{BotToken: "xoxb-1", SigningSecret: "s3cret", AllowedUsers: []string{"U1"}, AllowedChannels: []string{"C1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"event_callback"}`)
+	sign := func(ts string) string {
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte("v0:" + ts + ":"))
+		mac.Write(body)
+		return "v0=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	if err := app.VerifySignature(body, "1700000000", sign("1700000000"), now); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+	stale := "1699999000"
+	for _, c := range []struct{ ts, sig string }{
+		{"1700000000", ""},
+		{"1700000000", "v0=zz"},
+		{"1700000000", "v0=" + strings.Repeat("0", 64)},
+		{"1700000001", sign("1700000000")},
+		{stale, sign(stale)},
+		{"x", sign("x")},
+	} {
+		if err := app.VerifySignature(body, c.ts, c.sig, now); err == nil {
+			t.Errorf("timestamp %q, signature %q accepted", c.ts, c.sig)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	challenge, m, err := Parse([]byte(`{"type":"url_verification","challenge":"abc"}`))
+	if challenge != "abc" || m != nil || err != nil {
+		t.Errorf("url_verification: Parse = %q, %v, %v", challenge, m, err)
+	}
+	callback := func(event string) []byte {
+		return []byte(`{"type":"event_callback","authorizations":[{"user_id":"UBOT"}],"event":` + event + `}`)
+	}
+	_, m, err = Parse(callback(`{"type":"app_mention","user":"U1","channel":"C1","text":"<@UBOT> fix the build <@U2>","ts":"1.2"}`))
+	if err != nil || m == nil {
+		t.Fatalf("app_mention: Parse = %v, %v", m, err)
+	}
+	want := Message{Channel: "C1", User: "U1", Text: "fix the build <@U2>", TS: "1.2", Thread: "1.2", Mention: true}
+	if *m != want {
+		t.Errorf("app_mention: Parse = %+v", *m)
+	}
+	_, m, err = Parse(callback(`{"type":"message","user":"U1","channel":"C1","text":"thanks","ts":"1.3","thread_ts":"1.2"}`))
+	if err != nil || m == nil || m.Mention || m.Thread != "1.2" || m.Text != "thanks" {
+		t.Errorf("thread reply: Parse = %+v, %v", m, err)
+	}
+	for name, event := range map[string]string{
+		"top-level message":  `{"type":"message","user":"U1","channel":"C1","text":"hi","ts":"1.3"}`,
+		"reply mentioning":   `{"type":"message","user":"U1","channel":"C1","text":"<@UBOT> hi","ts":"1.3","thread_ts":"1.2"}`,
+		"bot message":        `{"type":"message","bot_id":"B1","channel":"C1","text":"hi","ts":"1.3","thread_ts":"1.2"}`,
+		"edited message":     `{"type":"message","subtype":"message_changed","channel":"C1","ts":"1.3","thread_ts":"1.2"}`,
+		"unsubscribed event": `{"type":"reaction_added","user":"U1"}`,
+	} {
+		if _, m, err := Parse(callback(event)); m != nil || err != nil {
+			t.Errorf("%s: Parse = %+v, %v", name, m, err)
+		}
+	}
+}
+
+func TestAPI(t *testing.T) {
+	var got map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-1" {
+			w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		switch r.URL.Path {
+		case "/chat.postMessage":
+			w.Write([]byte(`{"ok":true,"ts":"1.5"}`))
+		case "/chat.update":
+			w.Write([]byte(`{"ok":false,"error":"message_not_found"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	app, err := New(Config{BotToken: "xoxb-1", SigningSecret: "s", AllowedUsers: []string{"U1"}, AllowedChannels: []string{"C1"}, APIURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	msgTS, err := app.PostMessage(ctx, "C1", "1.2", "hello")
+	if err != nil || msgTS != "1.5" || got["thread_ts"] != "1.2" || got["text"] != "hello" {
+		t.Errorf("PostMessage = %q, %v; sent %v", msgTS, err, got)
+	}
+	if err := app.UpdateMessage(ctx, "C1", "9.9", "x"); err == nil || !strings.Contains(err.Error(), "message_not_found") {
+		t.Errorf("UpdateMessage of a missing message: %v", err)
+	}
+	app.Config.BotToken = "xoxb-2"
+	if _, err := app.PostMessage(ctx, "C1", "1.2", "hello"); err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Errorf("PostMessage with a bad token: %v", err)
+	}
+}
@@ -36,10 +36,16 @@ func GenerateSlug(ctx context.Context, llmProvider LLMServiceProvider, database
 		return "", err
 	}
 
+	return Assign(ctx, database, logger, conversationID, baseSlug)
+}
+
+// Assign gives a conversation baseSlug, or baseSlug with the first numeric
+// suffix not taken by another conversation, and returns the slug it set.
+func Assign(ctx context.Context, database *db.DB, logger *slog.Logger, conversationID, baseSlug string) (string, error) {
 	// Try to update with the base slug first, then with numeric suffixes if needed
 	slug := baseSlug
 	for attempt := 0; attempt < 100; attempt++ {
-		_, err = database.UpdateConversationSlug(ctx, conversationID, slug)
+		_, err := database.UpdateConversationSlug(ctx, conversationID, slug)
 		if err == nil {
 			// Success!
 			logger.Info("Generated slug for conversation", "conversationID", conversationID, "slug", slug)
@@ -2,102 +2,263 @@ package subpub
 
 import (
 	"context"
+	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type SubPub[K any] struct {
 	mu          sync.Mutex
-	subscribers []*subscriber[K]
+	subscribers []*Subscription[K]
+	defaults    []Option
+	replay      ring[K]
 }
 
-type subscriber[K any] struct {
-	idx    int64
-	ch     chan K
+// A ring holds the last published messages for replay.
+type ring[K any] struct {
+	buf     []indexed[K] // oldest at buf[next] once full
+	next    int
+	evicted int64 // highest index to leave buf
+}
+
+type indexed[K any] struct {
+	idx int64
+	msg K
+}
+
+func newRing[K any](n int) ring[K] {
+	return ring[K]{buf: make([]indexed[K], 0, max(n, 0)), evicted: math.MinInt64}
+}
+
+// add adds a published message, evicting the oldest if r is full.
+func (r *ring[K]) add(idx int64, message K) {
+	switch {
+	case cap(r.buf) == 0:
+		r.evicted = max(r.evicted, idx)
+	case len(r.buf) < cap(r.buf):
+		r.buf = append(r.buf, indexed[K]{idx, message})
+	default:
+		r.evicted = max(r.evicted, r.buf[r.next].idx)
+		r.buf[r.next] = indexed[K]{idx, message}
+		r.next = (r.next + 1) % len(r.buf)
+	}
+}
+
+// after returns the messages published after idx, oldest first, or false if
+// some were evicted.
+func (r *ring[K]) after(idx int64) ([]K, bool) {
+	if idx < r.evicted {
+		return nil, false
+	}
+	var msgs []K
+	for i := range r.buf {
+		e := r.buf[(r.next+i)%len(r.buf)]
+		if e.idx > idx {
+			msgs = append(msgs, e.msg)
+		}
+	}
+	return msgs, true
+}
+
+// A Subscription receives the messages published after its index.
+type Subscription[K any] struct {
+	idx    int64 // guarded by the publisher's mutex
+	ch     chan entry[K]
 	ctx    context.Context
 	cancel context.CancelFunc
+	opts   options
+
+	sent    int64        // messages buffered so far; guarded by the publisher's mutex
+	dropped atomic.Int64 // written only with the publisher's mutex held
+	seq     int64        // seq of the message Next last returned; used only by the reader
+	missed  int64        // drops just before the message Next last returned
+}
+
+// An entry is a buffered message. Its seq counts the subscription's
+// messages, so that gaps in it show where messages were dropped.
+type entry[K any] struct {
+	msg K
+	seq int64
 }
 
-func New[K any]() *SubPub[K] {
+// A Policy says what happens when a subscriber's buffer is full.
+type Policy int
+
+const (
+	// Disconnect ends the subscription. It is the default.
+	Disconnect Policy = iota
+	// DropOldest discards the oldest buffered message to make room. The
+	// subscription keeps going, and reports the gap through Missed.
+	DropOldest
+	// Block waits up to the block timeout for room, then disconnects.
+	// Publishing to every subscriber waits with it.
+	Block
+)
+
+const (
+	// DefaultBufferSize is how many messages a subscriber buffers by default.
+	DefaultBufferSize = 10
+	// DefaultBlockTimeout is how long the Block policy waits by default.
+	DefaultBlockTimeout = time.Second
+)
+
+// An Option configures a subscription, or, passed to New, every subscription.
+type Option func(*options)
+
+type options struct {
+	bufferSize   int
+	policy       Policy
+	blockTimeout time.Duration
+	replay       int
+}
+
+// WithBufferSize sets how many messages a subscriber buffers; at least one.
+func WithBufferSize(n int) Option {
+	return func(o *options) { o.bufferSize = max(n, 1) }
+}
+
+// WithPolicy sets what happens when a subscriber's buffer is full.
+func WithPolicy(p Policy) Option {
+	return func(o *options) { o.policy = p }
+}
+
+// WithBlockTimeout sets how long the Block policy waits for room.
+func WithBlockTimeout(d time.Duration) Option {
+	return func(o *options) { o.blockTimeout = d }
+}
+
+// WithReplay makes New keep the last n published messages for Replay.
+// Subscriptions ignore it.
+func WithReplay(n int) Option {
+	return func(o *options) { o.replay = n }
+}
+
+// New returns a SubPub whose subscriptions default to opts.
+func New[K any](opts ...Option) *SubPub[K] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 	return &SubPub[K]{
-		subscribers: make([]*subscriber[K], 0),
+		subscribers: make([]*Subscription[K], 0),
+		defaults:    opts,
+		replay:      newRing[K](o.replay),
 	}
 }
 
+// Replay returns the messages published after the given index, oldest first,
+// from the last ones kept per WithReplay. It returns false if some of them
+// are no longer kept: the caller is too far behind and must fetch what it
+// missed elsewhere. Messages published before sp was created and messages
+// sent with Broadcast are never replayed.
+func (sp *SubPub[K]) Replay(idx int64) ([]K, bool) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.replay.after(idx)
+}
+
 // Subscribe registers an interest in messages after the given index, subject to the
 // expiration/cancellation of the provided context. The returned function blocks
 // until a new message, and can return false as the second arguent if the subscription
 // is done for.
-func (sp *SubPub[K]) Subscribe(ctx context.Context, idx int64) func() (K, bool) {
+func (sp *SubPub[K]) Subscribe(ctx context.Context, idx int64, opts ...Option) func() (K, bool) {
+	return sp.Join(ctx, idx, opts...).Next
+}
+
+// Join is like Subscribe, but returns the Subscription, which also reports
+// the messages it dropped. opts override the SubPub's defaults.
+func (sp *SubPub[K]) Join(ctx context.Context, idx int64, opts ...Option) *Subscription[K] {
+	sub := newSubscription[K](ctx, sp.defaults, opts)
+	sub.idx = idx
+
+	sp.mu.Lock()
+	sp.subscribers = append(sp.subscribers, sub)
+	sp.mu.Unlock()
+	return sub
+}
+
+// newSubscription returns a subscription configured by defaults, then opts.
+func newSubscription[K any](ctx context.Context, defaults, opts []Option) *Subscription[K] {
+	o := options{bufferSize: DefaultBufferSize, blockTimeout: DefaultBlockTimeout}
+	for _, opt := range append(defaults[:len(defaults):len(defaults)], opts...) {
+		opt(&o)
+	}
+
 	// Create a child context so we can cancel the subscription independently
 	subCtx, cancel := context.WithCancel(ctx)
 
 	// Buffered channel to avoid blocking publishers
-	ch := make(chan K, 10)
-	sub := &subscriber[K]{
-		idx:    idx,
-		ch:     ch,
+	return &Subscription[K]{
+		ch:     make(chan entry[K], o.bufferSize),
 		ctx:    subCtx,
 		cancel: cancel,
+		opts:   o,
 	}
+}
 
-	sp.mu.Lock()
-	sp.subscribers = append(sp.subscribers, sub)
-	sp.mu.Unlock()
-
-	// Return a function that blocks until the next message
-	return func() (K, bool) {
+// Next blocks until the next message, and returns false as the second
+// argument if the subscription is done for.
+func (s *Subscription[K]) Next() (K, bool) {
+	select {
+	case e, ok := <-s.ch:
+		if ok {
+			return s.received(e), true
+		}
+	case <-s.ctx.Done():
+		// Context cancelled, but drain any buffered messages first
 		select {
-		case msg, ok := <-ch:
-			if !ok {
-				var zero K
-				return zero, false
+		case e, ok := <-s.ch:
+			if ok {
+				return s.received(e), true
 			}
-			return msg, true
-		case <-subCtx.Done():
-			// Context cancelled, but drain any buffered messages first
-			select {
-			case msg, ok := <-ch:
-				if ok {
-					return msg, true
-				}
-			default:
-			}
-			var zero K
-			return zero, false
+		default:
 		}
 	}
+	var zero K
+	return zero, false
+}
+
+func (s *Subscription[K]) received(e entry[K]) K {
+	s.missed = e.seq - s.seq - 1
+	s.seq = e.seq
+	return e.msg
+}
+
+// Missed returns how many messages were dropped just before the one Next
+// last returned. It is nonzero only under the DropOldest policy, and must be
+// called from the goroutine calling Next.
+func (s *Subscription[K]) Missed() int64 {
+	return s.missed
+}
+
+// Dropped returns how many messages the subscription has dropped in all.
+func (s *Subscription[K]) Dropped() int64 {
+	return s.dropped.Load()
 }
 
 // Publish sends a message to all subscribers waiting for messages after the given index.
-// Subscribers that are "behind" should get a disconnection message.
+// Subscribers that are "behind" are handled according to their Policy.
 func (sp *SubPub[K]) Publish(idx int64, message K) {
 	sp.mu.Lock()
 	defer sp.mu.Unlock()
+	sp.replay.add(idx, message)
 
 	// Notify subscribers and filter out disconnected ones
 	remaining := sp.subscribers[:0]
 	for _, sub := range sp.subscribers {
 		// Check if context is still valid
-		select {
-		case <-sub.ctx.Done():
+		if sub.ctx.Err() != nil {
 			// Context cancelled, close channel and don't keep subscriber
 			close(sub.ch)
 			continue
-		default:
 		}
 
 		// Only send to subscribers waiting for messages after an index < idx
 		if sub.idx < idx {
-			// Try to send the message
-			select {
-			case sub.ch <- message:
-				// Success, update subscriber's index and keep them
+			if sub.deliver(message) {
 				sub.idx = idx
 				remaining = append(remaining, sub)
-			default:
-				// Channel full, subscriber is behind - disconnect them
-				close(sub.ch)
-				sub.cancel()
 			}
 		} else {
 			// This subscriber is not interested yet (already has this index or beyond)
@@ -115,21 +276,58 @@ func (sp *SubPub[K]) Broadcast(message K) {
 
 	remaining := sp.subscribers[:0]
 	for _, sub := range sp.subscribers {
-		select {
-		case <-sub.ctx.Done():
+		if sub.ctx.Err() != nil {
 			close(sub.ch)
 			continue
-		default:
 		}
+		if sub.deliver(message) {
+			remaining = append(remaining, sub)
+		}
+	}
+	sp.subscribers = remaining
+}
 
+// deliver buffers message for s according to its policy, and reports whether
+// s is still subscribed. If not, it has closed s's channel. The caller holds the
+// publisher's mutex, so nothing else sends on s.ch.
+func (s *Subscription[K]) deliver(message K) bool {
+	e := entry[K]{message, s.sent + 1}
+	switch s.opts.policy {
+	case DropOldest:
+		if len(s.ch) == cap(s.ch) {
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+				// The reader made room meanwhile.
+			}
+		}
+	case Block:
 		select {
-		case sub.ch <- message:
-			remaining = append(remaining, sub)
+		case s.ch <- e:
+			s.sent++
+			return true
 		default:
-			// Channel full, disconnect
-			close(sub.ch)
-			sub.cancel()
+		}
+		timer := time.NewTimer(s.opts.blockTimeout)
+		defer timer.Stop()
+		select {
+		case s.ch <- e:
+			s.sent++
+			return true
+		case <-timer.C:
+		case <-s.ctx.Done():
 		}
 	}
-	sp.subscribers = remaining
+
+	select {
+	case s.ch <- e:
+		s.sent++
+		return true
+	default:
+		// Channel full, subscriber is behind - disconnect them
+		close(s.ch)
+		s.cancel()
+		return false
+	}
 }
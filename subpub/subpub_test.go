@@ -3,6 +3,7 @@ package subpub
 import (
 	"context"
 	"fmt"
+	"slices"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -365,3 +366,132 @@ func TestSubPubSubscriberContextDoneDuringPublish(t *testing.T) {
 		t.Error("Expected closed channel after context cancellation")
 	}
 }
+
+func TestSubPubBufferSize(t *testing.T) {
+	sp := New[int](WithBufferSize(3))
+	ctx := context.Background()
+
+	next := sp.Subscribe(ctx, 0)
+	bigger := sp.Subscribe(ctx, 0, WithBufferSize(5))
+	for i := 1; i <= 4; i++ {
+		sp.Publish(int64(i), i)
+	}
+
+	// The first subscriber overflowed on the fourth message.
+	for want := 1; want <= 3; want++ {
+		if msg, ok := next(); !ok || msg != want {
+			t.Fatalf("next() = %d, %v; want %d, true", msg, ok, want)
+		}
+	}
+	if _, ok := next(); ok {
+		t.Error("Expected subscriber with a 3-message buffer to be disconnected")
+	}
+	for want := 1; want <= 4; want++ {
+		if msg, ok := bigger(); !ok || msg != want {
+			t.Fatalf("bigger() = %d, %v; want %d, true", msg, ok, want)
+		}
+	}
+}
+
+func TestSubPubDropOldest(t *testing.T) {
+	sp := New[int](WithBufferSize(3), WithPolicy(DropOldest))
+	sub := sp.Join(context.Background(), 0)
+
+	for i := 1; i <= 5; i++ {
+		sp.Publish(int64(i), i)
+	}
+	if got := sub.Dropped(); got != 2 {
+		t.Errorf("Dropped() = %d, want 2", got)
+	}
+
+	// Messages 1 and 2 were dropped; the gap shows before message 3.
+	for i, want := range []struct{ msg, missed int64 }{{3, 2}, {4, 0}, {5, 0}} {
+		msg, ok := sub.Next()
+		if !ok || int64(msg) != want.msg || sub.Missed() != want.missed {
+			t.Errorf("message %d: got %d, %v, missed %d; want %d, true, missed %d", i, msg, ok, sub.Missed(), want.msg, want.missed)
+		}
+	}
+
+	sp.Broadcast(6)
+	if msg, ok := sub.Next(); !ok || msg != 6 || sub.Missed() != 0 {
+		t.Errorf("got %d, %v, missed %d; want 6, true, missed 0", msg, ok, sub.Missed())
+	}
+}
+
+func TestSubPubBlock(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sp := New[int](WithBufferSize(1), WithPolicy(Block), WithBlockTimeout(time.Minute))
+		next := sp.Subscribe(context.Background(), 0)
+
+		sp.Publish(1, 1)
+		published := make(chan struct{})
+		go func() {
+			sp.Publish(2, 2)
+			close(published)
+		}()
+		synctest.Wait()
+		select {
+		case <-published:
+			t.Fatal("Publish did not wait for room in the buffer")
+		default:
+		}
+
+		// Reading makes room, so the blocked Publish delivers.
+		for want := 1; want <= 2; want++ {
+			if msg, ok := next(); !ok || msg != want {
+				t.Fatalf("next() = %d, %v; want %d, true", msg, ok, want)
+			}
+		}
+		<-published
+
+		// A reader that stays behind past the timeout is disconnected.
+		sp.Publish(3, 3)
+		start := time.Now()
+		sp.Publish(4, 4)
+		if waited := time.Since(start); waited != time.Minute {
+			t.Errorf("Publish waited %v, want %v", waited, time.Minute)
+		}
+		if msg, ok := next(); !ok || msg != 3 {
+			t.Fatalf("next() = %d, %v; want 3, true", msg, ok)
+		}
+		if _, ok := next(); ok {
+			t.Error("Expected blocked subscriber to be disconnected after the timeout")
+		}
+	})
+}
+
+func TestSubPubReplay(t *testing.T) {
+	sp := New[int](WithReplay(3))
+	sp.Publish(1, 1)
+	sp.Publish(2, 2)
+	sp.Broadcast(-1)
+
+	if msgs, ok := sp.Replay(0); !ok || !slices.Equal(msgs, []int{1, 2}) {
+		t.Errorf("Replay(0) = %v, %v; want [1 2], true", msgs, ok)
+	}
+
+	sp.Publish(4, 4) // a batch of 3 and 4
+	sp.Publish(5, 5)
+	tests := []struct {
+		idx  int64
+		want []int
+		ok   bool
+	}{
+		{0, nil, false}, // 1 was evicted
+		{1, []int{2, 4, 5}, true},
+		{3, []int{4, 5}, true},
+		{5, nil, true},
+	}
+	for _, tt := range tests {
+		msgs, ok := sp.Replay(tt.idx)
+		if ok != tt.ok || !slices.Equal(msgs, tt.want) {
+			t.Errorf("Replay(%d) = %v, %v; want %v, %v", tt.idx, msgs, ok, tt.want, tt.ok)
+		}
+	}
+
+	unbuffered := New[int]()
+	unbuffered.Publish(1, 1)
+	if msgs, ok := unbuffered.Replay(0); ok {
+		t.Errorf("Replay without a buffer = %v, %v; want false", msgs, ok)
+	}
+}
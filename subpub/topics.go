@@ -0,0 +1,195 @@
+package subpub
+
+import (
+	"context"
+	"sync"
+)
+
+// Topics is a SubPub per topic, such as a conversation ID, sharing
+// subscriptions: one subscription can follow many topics, receiving their
+// messages on one channel. Each topic has its own indices and replay buffer.
+// Messages don't carry their topic, so callers that follow several topics
+// put it in K.
+type Topics[T comparable, K any] struct {
+	mu       sync.Mutex
+	topics   map[T]*topic[K]
+	defaults []Option
+	replay   int
+}
+
+type topic[K any] struct {
+	followers map[*Subscription[K]]int64 // the index each follower has
+	replay    ring[K]
+}
+
+// NewTopics returns a Topics whose subscriptions default to opts.
+// WithReplay sets the replay buffer of each topic.
+func NewTopics[T comparable, K any](opts ...Option) *Topics[T, K] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Topics[T, K]{
+		topics:   make(map[T]*topic[K]),
+		defaults: opts,
+		replay:   o.replay,
+	}
+}
+
+// Join returns a subscription that follows no topics yet, subject to the
+// expiration/cancellation of ctx. opts override the Topics' defaults.
+func (tp *Topics[T, K]) Join(ctx context.Context, opts ...Option) *Subscription[K] {
+	return newSubscription[K](ctx, tp.defaults, opts)
+}
+
+// Subscribe returns a subscription following topic after idx.
+func (tp *Topics[T, K]) Subscribe(ctx context.Context, t T, idx int64, opts ...Option) *Subscription[K] {
+	sub := tp.Join(ctx, opts...)
+	tp.Follow(sub, t, idx)
+	return sub
+}
+
+// Follow makes sub also receive the messages published to topic t after idx.
+// Following a topic again resets its index.
+func (tp *Topics[T, K]) Follow(sub *Subscription[K], t T, idx int64) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.topic(t).followers[sub] = idx
+}
+
+// Unfollow stops sub receiving the messages published to topic t.
+func (tp *Topics[T, K]) Unfollow(sub *Subscription[K], t T) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if top, ok := tp.topics[t]; ok {
+		delete(top.followers, sub)
+		tp.prune(t, top)
+	}
+}
+
+// Publish sends a message to the followers of topic t waiting for messages
+// after the given index, like SubPub.Publish.
+func (tp *Topics[T, K]) Publish(t T, idx int64, message K) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	top := tp.topic(t)
+	top.replay.add(idx, message)
+	for sub, subIdx := range top.followers {
+		if sub.ctx.Err() != nil {
+			// Context cancelled, close channel and don't keep subscriber
+			tp.drop(sub)
+			close(sub.ch)
+			continue
+		}
+		if subIdx >= idx {
+			continue
+		}
+		if sub.deliver(message) {
+			top.followers[sub] = idx
+		} else {
+			tp.drop(sub)
+		}
+	}
+	tp.prune(t, top)
+}
+
+// Broadcast sends a message to all the followers of topic t regardless of
+// their index, like SubPub.Broadcast.
+func (tp *Topics[T, K]) Broadcast(t T, message K) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	top, ok := tp.topics[t]
+	if !ok {
+		return
+	}
+	for sub := range top.followers {
+		if sub.ctx.Err() != nil {
+			tp.drop(sub)
+			close(sub.ch)
+			continue
+		}
+		if !sub.deliver(message) {
+			tp.drop(sub)
+		}
+	}
+	tp.prune(t, top)
+}
+
+// BroadcastAll sends a message once to every subscription following any
+// topic, for out-of-band notifications.
+func (tp *Topics[T, K]) BroadcastAll(message K) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	seen := make(map[*Subscription[K]]bool)
+	for _, top := range tp.topics {
+		for sub := range top.followers {
+			seen[sub] = true
+		}
+	}
+	for sub := range seen {
+		if sub.ctx.Err() != nil {
+			tp.drop(sub)
+			close(sub.ch)
+			continue
+		}
+		if !sub.deliver(message) {
+			tp.drop(sub)
+		}
+	}
+}
+
+// Replay returns the messages published to topic t after the given index,
+// like SubPub.Replay. A topic that nothing was published to is like a new
+// SubPub.
+func (tp *Topics[T, K]) Replay(t T, idx int64) ([]K, bool) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if top, ok := tp.topics[t]; ok {
+		return top.replay.after(idx)
+	}
+	return nil, tp.replay > 0
+}
+
+// Forget discards topic t's replay buffer, for when nothing more will be
+// published to it for a while. Its followers keep following it.
+func (tp *Topics[T, K]) Forget(t T) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if top, ok := tp.topics[t]; ok {
+		top.replay = newRing[K](tp.replay)
+		if len(top.followers) == 0 {
+			delete(tp.topics, t)
+		}
+	}
+}
+
+// topic returns topic t's state, creating it if need be.
+// The caller holds tp.mu.
+func (tp *Topics[T, K]) topic(t T) *topic[K] {
+	top, ok := tp.topics[t]
+	if !ok {
+		top = &topic[K]{followers: make(map[*Subscription[K]]int64), replay: newRing[K](tp.replay)}
+		tp.topics[t] = top
+	}
+	return top
+}
+
+// prune deletes topic t's state if it holds nothing: no followers, and no
+// replay buffer to remember what was published.
+// The caller holds tp.mu.
+func (tp *Topics[T, K]) prune(t T, top *topic[K]) {
+	if len(top.followers) == 0 && tp.replay == 0 {
+		delete(tp.topics, t)
+	}
+}
+
+// drop makes sub, which is done for, stop following every topic.
+// The caller holds tp.mu.
+func (tp *Topics[T, K]) drop(sub *Subscription[K]) {
+	for t, top := range tp.topics {
+		if _, ok := top.followers[sub]; ok {
+			delete(top.followers, sub)
+			tp.prune(t, top)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package subpub
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestTopicsFollowMany(t *testing.T) {
+	tp := NewTopics[string, string]()
+	ctx := context.Background()
+
+	sub := tp.Subscribe(ctx, "parent", 0)
+	tp.Follow(sub, "child", 5)
+	other := tp.Subscribe(ctx, "other", 0)
+
+	tp.Publish("parent", 1, "p1")
+	tp.Publish("child", 5, "c5") // already seen
+	tp.Publish("child", 6, "c6")
+	tp.Publish("other", 1, "o1")
+	tp.Broadcast("parent", "p-heartbeat")
+	tp.Unfollow(sub, "child")
+	tp.Publish("child", 7, "c7")
+	tp.Publish("parent", 2, "p2")
+
+	var got []string
+	for range 4 {
+		msg, ok := sub.Next()
+		if !ok {
+			t.Fatal("Expected to receive message, got closed channel")
+		}
+		got = append(got, msg)
+	}
+	if want := []string{"p1", "c6", "p-heartbeat", "p2"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if msg, ok := other.Next(); !ok || msg != "o1" {
+		t.Errorf("other got %q, %v; want o1, true", msg, ok)
+	}
+
+	tp.Follow(sub, "child", 7)
+	tp.BroadcastAll("all")
+	tp.Publish("other", 2, "o2")
+	if msg, ok := sub.Next(); !ok || msg != "all" {
+		t.Errorf("got %q, %v; want all, true", msg, ok)
+	}
+	for _, want := range []string{"all", "o2"} {
+		if msg, ok := other.Next(); !ok || msg != want {
+			t.Errorf("other got %q, %v; want %s, true", msg, ok, want)
+		}
+	}
+}
+
+func TestTopicsDisconnect(t *testing.T) {
+	tp := NewTopics[int, int](WithBufferSize(1))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sub := tp.Subscribe(context.Background(), 1, 0)
+	tp.Follow(sub, 2, 0)
+	tp.Publish(1, 1, 1)
+	tp.Publish(2, 1, 2) // overflows, disconnecting sub from both topics
+
+	if msg, ok := sub.Next(); !ok || msg != 1 {
+		t.Fatalf("Next() = %d, %v; want 1, true", msg, ok)
+	}
+	if _, ok := sub.Next(); ok {
+		t.Error("Expected subscriber to be disconnected")
+	}
+	tp.Publish(1, 2, 3) // must not send on the closed channel
+
+	cancelled := tp.Subscribe(ctx, 1, 0)
+	cancel()
+	tp.Publish(1, 3, 4)
+	if _, ok := cancelled.Next(); ok {
+		t.Error("Expected closed channel after context cancellation")
+	}
+	if len(tp.topics) != 0 {
+		t.Errorf("topics without followers were kept: %v", tp.topics)
+	}
+}
+
+func TestTopicsReplay(t *testing.T) {
+	tp := NewTopics[string, int](WithReplay(2))
+	tp.Publish("a", 1, 1)
+	tp.Publish("a", 2, 2)
+	tp.Publish("a", 3, 3)
+	tp.Publish("b", 1, 10)
+
+	tests := []struct {
+		topic string
+		idx   int64
+		want  []int
+		ok    bool
+	}{
+		{"a", 0, nil, false},
+		{"a", 1, []int{2, 3}, true},
+		{"b", 0, []int{10}, true},
+		{"c", 0, nil, true},
+	}
+	for _, tt := range tests {
+		msgs, ok := tp.Replay(tt.topic, tt.idx)
+		if ok != tt.ok || !slices.Equal(msgs, tt.want) {
+			t.Errorf("Replay(%q, %d) = %v, %v; want %v, %v", tt.topic, tt.idx, msgs, ok, tt.want, tt.ok)
+		}
+	}
+
+	tp.Forget("a")
+	if msgs, ok := tp.Replay("a", 3); !ok || msgs != nil {
+		t.Errorf("Replay after Forget = %v, %v; want nil, true", msgs, ok)
+	}
+}
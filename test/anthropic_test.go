@@ -59,9 +59,8 @@ func TestWithAnthropicAPI(t *testing.T) {
 
 	// Set up tools config
 	toolSetConfig := claudetool.ToolSetConfig{
-		WorkingDir:    t.TempDir(),
-		LLMProvider:   llmManager,
-		EnableBrowser: false,
+		WorkingDir:  t.TempDir(),
+		LLMProvider: llmManager,
 	}
 
 	// Create server
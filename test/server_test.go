@@ -67,8 +67,7 @@ func TestServerEndToEnd(t *testing.T) {
 	// Set up tools
 	// Set up tools config
 	toolSetConfig := claudetool.ToolSetConfig{
-		WorkingDir:    t.TempDir(),
-		EnableBrowser: false,
+		WorkingDir: t.TempDir(),
 	}
 
 	// Create server
@@ -1092,8 +1091,7 @@ func TestGitStateChangeCreatesGitInfoMessage(t *testing.T) {
 
 	// Create server with git repo as working directory
 	toolConfig := claudetool.ToolSetConfig{
-		WorkingDir:    workDir,
-		EnableBrowser: false,
+		WorkingDir: workDir,
 	}
 	svr := server.NewServer(database, customLLMManager, toolConfig, logger, false, "", "")
 
@@ -1180,8 +1178,7 @@ func TestSubagentEndToEnd(t *testing.T) {
 
 	// Set up tools config
 	toolSetConfig := claudetool.ToolSetConfig{
-		WorkingDir:    t.TempDir(),
-		EnableBrowser: false,
+		WorkingDir: t.TempDir(),
 	}
 
 	// Create server (predictable-only mode)
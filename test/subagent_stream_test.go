@@ -120,8 +120,7 @@ func setupTestServerForSubagent(t *testing.T) (*server.Server, *db.DB, *httptest
 	llmManager := &fakeLLMManager{service: predictableService}
 
 	toolSetConfig := claudetool.ToolSetConfig{
-		WorkingDir:    t.TempDir(),
-		EnableBrowser: false,
+		WorkingDir: t.TempDir(),
 	}
 
 	svr := server.NewServer(database, llmManager, toolSetConfig, logger, true, "predictable", "")
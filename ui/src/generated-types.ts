@@ -32,6 +32,7 @@ export interface Usage {
   url?: string;
   start_time?: string | null;
   end_time?: string | null;
+  latency_ms?: number;
 }
 
 export interface ApiMessageForTS {
@@ -56,6 +57,7 @@ export interface ConversationStateForTS {
   conversation_id: string;
   working: boolean;
   model?: string;
+  status?: string;
 }
 
 export interface NotificationEventForTS {
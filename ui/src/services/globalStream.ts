@@ -165,6 +165,13 @@ export function connectGlobalStream({
   };
 
   const handleEvent = (data: StreamResponse) => {
+    if (data.resync) {
+      // The server dropped events because we fell behind. Backfill exactly
+      // as after a reconnect.
+      messageStore.markAllStale();
+      onReconnect?.();
+      return;
+    }
     if (data.conversation_list_patch) {
       onListPatch(data.conversation_list_patch);
     }
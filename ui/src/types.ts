@@ -89,6 +89,8 @@ export interface ChatRequest {
     disable_all_tools?: boolean;
     thinking_level?: "off" | "minimal" | "low" | "medium" | "high" | "xhigh";
     disable_notifications?: boolean;
+    notifications?: "subscribed" | "muted";
+    instructions?: string;
   };
   queue?: boolean;
 }
@@ -126,6 +128,9 @@ export interface StreamResponse extends Omit<StreamResponseForTS, "messages"> {
   notification_event?: NotificationEvent;
   tool_progress?: ToolProgress;
   stream_delta?: StreamDelta;
+  // resync is set when the server dropped events because this client fell
+  // behind; the client should re-fetch what it shows.
+  resync?: boolean;
 }
 
 // Link represents a custom link that can be added to the UI
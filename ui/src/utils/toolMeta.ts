@@ -78,6 +78,10 @@ export function toolEmoji(name: string | undefined | null, input?: unknown): str
       return "🎬";
     case "change_dir":
       return "📂";
+    case "plan":
+      return "📋";
+    case "handoff":
+      return "📨";
     case "llm_one_shot":
       return "🤖";
     case "output_iframe":
@@ -104,6 +108,8 @@ const TOOL_DISPLAY_NAMES: Record<string, string> = {
   shell: "Shell command",
   patch: "File edit",
   change_dir: "Change directory",
+  plan: "Plan",
+  handoff: "Handoff",
   read_image: "Read image",
   keyword_search: "Keyword search",
   web_search: "Web search",
@@ -405,6 +411,8 @@ function inputSummary(name: string | undefined | null, input: unknown): string {
       return pick("query");
     case "subagent":
       return pick("slug", "prompt");
+    case "handoff":
+      return pick("conversation");
     case "llm_one_shot": {
       const files = o.prompt_files;
       if (Array.isArray(files) && files.length > 0) return files.join(", ");
@@ -413,6 +421,14 @@ function inputSummary(name: string | undefined | null, input: unknown): string {
     }
     case "output_iframe":
       return pick("title", "path");
+    case "plan": {
+      const items = o.items;
+      if (!Array.isArray(items)) return "";
+      const done = items.filter(
+        (i) => typeof i === "object" && i !== null && i.status === "completed",
+      ).length;
+      return `${done}/${items.length} done`;
+    }
     case "browser_eval":
       return pick("expression");
     case "browser_emulate":
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// pingInterval keeps idle connections from being dropped by proxies.
+const pingInterval = 30 * time.Second
+
+// Connect registers as the worker called name with the Shelley server at
+// serverURL and runs the commands it sends until the connection fails or
+// ctx is done.
+func Connect(ctx context.Context, serverURL, name, token string) error {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/workers/connect"
+	u.RawQuery = url.Values{"name": {name}}.Encode()
+	c, _, err := websocket.Dial(ctx, u.String(), &websocket.DialOptions{
+		HTTPHeader: http.Header{"Authorization": {"Bearer " + token}},
+	})
+	if err != nil {
+		return err
+	}
+	defer c.CloseNow()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.Ping(ctx); err != nil {
+					c.CloseNow()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return Serve(ctx, WebSocket(c))
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Relay runs the command exec describes over conn, copying stdin to it and
+// its output to stdout and stderr, and returns its exit code.
+func Relay(ctx context.Context, conn Conn, exec Frame, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	exec.Type = FrameExec
+	if err := conn.WriteFrame(ctx, exec); err != nil {
+		return -1, err
+	}
+	go func() {
+		buf := make([]byte, ChunkSize)
+		for stdin != nil {
+			n, err := stdin.Read(buf)
+			if n > 0 {
+				if conn.WriteFrame(ctx, Frame{ID: exec.ID, Type: FrameStdin, Data: buf[:n]}) != nil {
+					return
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		conn.WriteFrame(ctx, Frame{ID: exec.ID, Type: FrameEOF})
+	}()
+	for {
+		f, err := conn.ReadFrame(ctx)
+		if err != nil {
+			return -1, err
+		}
+		switch f.Type {
+		case FrameStdout:
+			stdout.Write(f.Data)
+		case FrameStderr:
+			stderr.Write(f.Data)
+		case FrameExit:
+			if f.Error != "" {
+				return -1, errors.New(f.Error)
+			}
+			return f.Code, nil
+		}
+	}
+}
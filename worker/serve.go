@@ -0,0 +1,165 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// waitDelay bounds how long the output of a finished command is waited
+// for when processes it left in the background hold its stdout open.
+const waitDelay = 2 * time.Second
+
+// Serve runs the commands that conn's exec frames ask for until reading
+// from conn fails, and then kills the ones still running.
+func Serve(ctx context.Context, conn Conn) error {
+	var mu sync.Mutex
+	procs := make(map[int64]*process)
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range procs {
+			p.kill()
+		}
+	}()
+	for {
+		f, err := conn.ReadFrame(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		p := procs[f.ID]
+		mu.Unlock()
+		switch f.Type {
+		case FrameExec:
+			if p != nil {
+				continue
+			}
+			p, err := start(ctx, conn, f)
+			if err != nil {
+				if err := conn.WriteFrame(ctx, Frame{ID: f.ID, Type: FrameExit, Code: -1, Error: err.Error()}); err != nil {
+					return err
+				}
+				continue
+			}
+			mu.Lock()
+			procs[f.ID] = p
+			mu.Unlock()
+			go func() {
+				code := p.wait()
+				mu.Lock()
+				delete(procs, f.ID)
+				mu.Unlock()
+				conn.WriteFrame(ctx, Frame{ID: f.ID, Type: FrameExit, Code: code})
+			}()
+		case FrameStdin:
+			if p != nil && f.Data != nil {
+				p.write(f.Data)
+			}
+		case FrameEOF:
+			if p != nil {
+				p.write(nil)
+			}
+		case FrameKill:
+			if p != nil {
+				p.kill()
+			}
+		}
+	}
+}
+
+// process is a command started for an exec frame.
+type process struct {
+	cmd   *exec.Cmd
+	stdin chan []byte // nil closes stdin
+	done  chan struct{}
+}
+
+func start(ctx context.Context, conn Conn, f Frame) (*process, error) {
+	if len(f.Args) == 0 {
+		return nil, errors.New("no command")
+	}
+	cmd := exec.Command(f.Args[0], f.Args[1:]...)
+	cmd.Dir = f.Dir
+	cmd.Env = append(os.Environ(), f.Env...)
+	cmd.Stdout = &frameWriter{ctx: ctx, conn: conn, id: f.ID, typ: FrameStdout}
+	cmd.Stderr = &frameWriter{ctx: ctx, conn: conn, id: f.ID, typ: FrameStderr}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.WaitDelay = waitDelay
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &process{cmd: cmd, stdin: make(chan []byte, 16), done: make(chan struct{})}
+	go p.copyStdin(stdin)
+	return p, nil
+}
+
+// write queues data for the process's stdin, or closes it if data is nil.
+func (p *process) write(data []byte) {
+	select {
+	case p.stdin <- data:
+	case <-p.done:
+	}
+}
+
+func (p *process) copyStdin(w io.WriteCloser) {
+	defer w.Close()
+	for {
+		select {
+		case data := <-p.stdin:
+			if data == nil {
+				w.Close()
+				continue
+			}
+			// Keep draining after a failed write so that write never blocks.
+			w.Write(data)
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// wait waits for the process to exit and returns its exit code, or 128
+// plus the signal number if a signal ended it, as shells report it.
+func (p *process) wait() int {
+	// Wait's error is the exit status, or ErrWaitDelay, or a failure to
+	// send output, which the closing connection reports.
+	p.cmd.Wait()
+	close(p.done)
+	if ws, ok := p.cmd.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return p.cmd.ProcessState.ExitCode()
+}
+
+func (p *process) kill() {
+	syscall.Kill(-p.cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// frameWriter sends what a command writes as frames of type typ.
+type frameWriter struct {
+	ctx  context.Context
+	conn Conn
+	id   int64
+	typ  string
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	for n := 0; n < len(p); {
+		chunk := p[n:min(n+ChunkSize, len(p))]
+		if err := w.conn.WriteFrame(w.ctx, Frame{ID: w.id, Type: w.typ, Data: chunk}); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,107 @@
+// Package worker runs a conversation's commands on another machine.
+//
+// A worker ("shelley worker") keeps a websocket open to the server. The
+// server sends it exec frames, each naming a command, and then the
+// command's stdin; the worker answers with its output and exit status.
+// Frames for different commands are told apart by ID.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// Frame types.
+const (
+	// FrameExec starts Args in Dir with Env added to the worker's environment.
+	FrameExec = "exec"
+	// FrameStdin carries Data for the command's stdin.
+	FrameStdin = "stdin"
+	// FrameEOF closes the command's stdin.
+	FrameEOF = "eof"
+	// FrameKill kills the command and its process group.
+	FrameKill = "kill"
+	// FrameStdout and FrameStderr carry Data the command wrote.
+	FrameStdout = "stdout"
+	FrameStderr = "stderr"
+	// FrameExit ends a command with its exit Code, or with Error if it
+	// could not be run.
+	FrameExit = "exit"
+)
+
+// ReadLimit is the largest frame a Conn accepts. Data is sent in chunks
+// of at most ChunkSize bytes so that frames stay well under it.
+const (
+	ReadLimit = 1 << 20
+	ChunkSize = 32 << 10
+)
+
+// Frame is one message between the server and a worker.
+type Frame struct {
+	ID   int64  `json:"id,omitempty"`
+	Type string `json:"type"`
+	// Worker names the worker in the exec frame a relay sends the server.
+	Worker string   `json:"worker,omitempty"`
+	Args   []string `json:"args,omitempty"`
+	Dir    string   `json:"dir,omitempty"`
+	Env    []string `json:"env,omitempty"`
+	Data   []byte   `json:"data,omitempty"`
+	Code   int      `json:"code,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// Conn sends and receives frames. WriteFrame may be called concurrently.
+type Conn interface {
+	ReadFrame(ctx context.Context) (Frame, error)
+	WriteFrame(ctx context.Context, f Frame) error
+}
+
+// WebSocket returns a Conn that exchanges frames as JSON messages on c.
+func WebSocket(c *websocket.Conn) Conn {
+	c.SetReadLimit(ReadLimit)
+	return wsConn{c}
+}
+
+type wsConn struct {
+	c *websocket.Conn
+}
+
+func (w wsConn) ReadFrame(ctx context.Context) (Frame, error) {
+	var f Frame
+	err := wsjson.Read(ctx, w.c, &f)
+	return f, err
+}
+
+func (w wsConn) WriteFrame(ctx context.Context, f Frame) error {
+	return wsjson.Write(ctx, w.c, f)
+}
+
+// Stream returns a Conn that exchanges frames as a stream of JSON values
+// on rw. Its ReadFrame ignores ctx; close rw to interrupt it.
+func Stream(rw io.ReadWriter) Conn {
+	return &streamConn{dec: json.NewDecoder(rw), enc: json.NewEncoder(rw)}
+}
+
+type streamConn struct {
+	dec *json.Decoder
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (s *streamConn) ReadFrame(ctx context.Context) (Frame, error) {
+	var f Frame
+	err := s.dec.Decode(&f)
+	return f, err
+}
+
+func (s *streamConn) WriteFrame(ctx context.Context, f Frame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(f)
+}
@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+func serve(t *testing.T) Conn {
+	t.Helper()
+	a, b := net.Pipe()
+	t.Cleanup(func() { a.Close() })
+	go Serve(context.Background(), Stream(b))
+	return Stream(a)
+}
+
+func TestRelay(t *testing.T) {
+	conn := serve(t)
+	var stdout, stderr bytes.Buffer
+	exec := Frame{Args: []string{"sh", "-c", `tr a-z A-Z; pwd >&2; exit 3`}, Dir: "/", Env: []string{"X=y"}}
+	code, err := Relay(context.Background(), conn, exec, strings.NewReader("hello"), &stdout, &stderr)
+	if err != nil || code != 3 {
+		t.Fatalf("Relay = %d, %v", code, err)
+	}
+	if stdout.String() != "HELLO" || stderr.String() != "/\n" {
+		t.Errorf("stdout %q, stderr %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestRelayLargeOutput(t *testing.T) {
+	conn := serve(t)
+	var stdout bytes.Buffer
+	input := strings.Repeat("0123456789", 50_000)
+	code, err := Relay(context.Background(), conn, Frame{Args: []string{"cat"}}, strings.NewReader(input), &stdout, &stdout)
+	if err != nil || code != 0 || stdout.String() != input {
+		t.Fatalf("Relay = %d, %v; %d bytes out", code, err, stdout.Len())
+	}
+}
+
+func TestRelayKilled(t *testing.T) {
+	conn := serve(t)
+	var out bytes.Buffer
+	code, err := Relay(context.Background(), conn, Frame{Args: []string{"sh", "-c", "kill -9 $$"}}, nil, &out, &out)
+	if err != nil || code != 128+9 {
+		t.Fatalf("Relay = %d, %v", code, err)
+	}
+}
+
+func TestRelayBadCommand(t *testing.T) {
+	conn := serve(t)
+	var out bytes.Buffer
+	if _, err := Relay(context.Background(), conn, Frame{Args: []string{"/nonexistent/command"}}, nil, &out, &out); err == nil {
+		t.Fatal("Relay of a missing command succeeded")
+	}
+}